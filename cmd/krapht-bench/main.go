@@ -0,0 +1,120 @@
+// Command krapht-bench benchmarks registered flows between a synthetic generator source and a blackhole
+// sink, and prints a report that can be compared to a baseline.
+//
+// Usage:
+//
+//	krapht-bench [flags] [-flow name[=json config]]...
+//
+// For example, to save a baseline and compare a CEL filter to it:
+//
+//	krapht-bench -json -flow passthrough > base.json
+//	krapht-bench -baseline base.json -flow 'cel_filter={"expression":"record.level != \"debug\""}'
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/witfoo/krapht/pkg/pipeline/bench"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// flowFlags are the repeated -flow flags
+type flowFlags []string
+
+func (f *flowFlags) String() string { return strings.Join(*f, ",") }
+
+func (f *flowFlags) Set(v string) error {
+	*f = append(*f, v)
+	return nil
+}
+
+func main() {
+	var flows flowFlags
+	conf := bench.Config{}
+	flag.StringVar(&conf.Name, "name", "bench", "name of the report")
+	flag.IntVar(&conf.Records, "records", 100000, "records generated")
+	flag.IntVar(&conf.Size, "size", 256, "minimum payload size of the records in bytes")
+	flag.Float64Var(&conf.Rate, "rate", 0, "max records generated per second, 0 for unlimited")
+	flag.Float64Var(&conf.SinkRate, "sink-rate", 0, "max records consumed per second, 0 for unlimited")
+	flag.Uint64Var(&conf.Seed, "seed", 1, "seed of the generated records")
+	flag.IntVar(&conf.LinkBuffer, "buffer", 0, "buffer size of the channels between stages")
+	flag.IntVar(&conf.ChunkSize, "chunk", 0, "items moved between stages at a time")
+	flag.Var(&flows, "flow", "registered flow `name[=json config]` to benchmark, repeated in pipeline order")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	baseline := flag.String("baseline", "", "JSON report `file` to compare the report to")
+	list := flag.Bool("list", false, "list the registered flows with their options")
+	flag.Parse()
+
+	if *list {
+		for _, c := range flow.Components() {
+			fmt.Println(c.Name)
+			for _, o := range c.Options {
+				fmt.Printf("\t%s %s", o.Name, o.Type)
+				if o.Required {
+					fmt.Print(", required")
+				}
+				if o.Default != nil {
+					fmt.Printf(", default %v", o.Default)
+				}
+				if o.Description != "" {
+					fmt.Printf(": %s", o.Description)
+				}
+				fmt.Println()
+			}
+		}
+		return
+	}
+
+	if err := run(conf, flows, *asJSON, *baseline); err != nil {
+		fmt.Fprintln(os.Stderr, "krapht-bench:", err)
+		os.Exit(1)
+	}
+}
+
+func run(conf bench.Config, flows []string, asJSON bool, baseline string) error {
+	for i, f := range flows {
+		name, raw, _ := strings.Cut(f, "=")
+		fl, err := flow.NewRegistered(name, json.RawMessage(raw))
+		if err != nil {
+			return err
+		}
+		conf.Flows = append(conf.Flows, bench.Stage{Name: fmt.Sprintf("%d-%s", i+1, name), Flow: fl})
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	report, err := bench.Run(ctx, conf)
+	if err != nil {
+		return err
+	}
+
+	if baseline != "" {
+		f, err := os.Open(baseline)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		base, err := bench.ReadJSON(f)
+		if err != nil {
+			return fmt.Errorf("could not read baseline %s: %w", baseline, err)
+		}
+		if err := report.WriteText(os.Stdout); err != nil {
+			return err
+		}
+		fmt.Println()
+		return bench.WriteComparison(os.Stdout, base, report)
+	}
+
+	if asJSON {
+		return report.WriteJSON(os.Stdout)
+	}
+	return report.WriteText(os.Stdout)
+}
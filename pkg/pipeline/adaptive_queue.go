@@ -0,0 +1,172 @@
+package pipeline
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// MetricBufferSize is the gauge of the size of an adaptive queue, sent when it is resized.
+const MetricBufferSize = "krapht_buffer_size"
+
+// AdaptiveConfig is the configuration of an adaptive queue.
+type AdaptiveConfig struct {
+	// Min is the initial and minimum size of the queue, it must be positive.
+	Min int
+	// Max is the maximum size of the queue, it defaults to Min.
+	Max int
+	// Interval is the interval at which the fill of the queue is checked, it defaults to 1 second.
+	Interval time.Duration
+	// GrowRatio is the peak fill ratio of an interval at or above which the queue doubles in size,
+	// it defaults to 0.9.
+	GrowRatio float64
+	// ShrinkRatio is the peak fill ratio of an interval at or below which the queue halves in size,
+	// it defaults to 0.25.
+	ShrinkRatio float64
+}
+
+// AdaptiveQueue relays the items of a channel through a queue resized within bounds to the peak fill of
+// the queue, so its size does not have to be guessed up front. The queue doubles when it was nearly full
+// over an interval and halves when it stayed mostly empty, by swapping to a new backing queue.
+type AdaptiveQueue[T any] struct {
+	conf   AdaptiveConfig
+	size   atomic.Int64
+	queued atomic.Int64
+}
+
+// NewAdaptiveQueue creates a new adaptive queue with the given configuration.
+func NewAdaptiveQueue[T any](conf AdaptiveConfig) (*AdaptiveQueue[T], error) {
+	if conf.Min <= 0 {
+		return nil, errors.New("adaptive queue min is not positive")
+	}
+	if conf.Max == 0 {
+		conf.Max = conf.Min
+	}
+	if conf.Max < conf.Min {
+		return nil, errors.New("adaptive queue max is less than min")
+	}
+	if conf.Interval <= 0 {
+		conf.Interval = time.Second
+	}
+	if conf.GrowRatio <= 0 {
+		conf.GrowRatio = 0.9
+	}
+	if conf.ShrinkRatio <= 0 {
+		conf.ShrinkRatio = 0.25
+	}
+	if conf.ShrinkRatio >= conf.GrowRatio {
+		return nil, errors.New("adaptive queue shrink ratio is not less than grow ratio")
+	}
+
+	q := &AdaptiveQueue[T]{conf: conf}
+	q.size.Store(int64(conf.Min))
+	return q, nil
+}
+
+// Size returns the current size of the queue.
+func (q *AdaptiveQueue[T]) Size() int {
+	return int(q.size.Load())
+}
+
+// Queued returns the number of items held by the queue.
+func (q *AdaptiveQueue[T]) Queued() int {
+	return int(q.queued.Load())
+}
+
+// Relay returns a channel of the items of the input channel, in order, holding up to the size of the
+// queue while the receiver is not ready. The input channel is not read while the queue is full. The
+// resize function, if not nil, is called with the old and new size of the queue when it is resized.
+// The output channel is closed once the input channel is closed and the queue is drained.
+func (q *AdaptiveQueue[T]) Relay(in <-chan T, resize func(from, to int)) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(q.conf.Interval)
+		defer ticker.Stop()
+
+		r := ring[T]{buf: make([]T, q.conf.Min)}
+		q.size.Store(int64(q.conf.Min))
+		defer q.queued.Store(0)
+
+		peak := 0
+		for in != nil || r.n > 0 {
+			recv := in
+			if r.n == len(r.buf) {
+				recv = nil
+			}
+			var send chan<- T
+			var next T
+			if r.n > 0 {
+				send = out
+				next = r.buf[r.head]
+			}
+
+			select {
+			case v, ok := <-recv:
+				if !ok {
+					in = nil
+					continue
+				}
+				r.push(v)
+				peak = max(peak, r.n)
+			case send <- next:
+				r.pop()
+			case <-ticker.C:
+				from := len(r.buf)
+				if to := q.adapt(from, peak, r.n); to != from {
+					r.resize(to)
+					q.size.Store(int64(to))
+					if resize != nil {
+						resize(from, to)
+					}
+				}
+				peak = r.n
+			}
+			q.queued.Store(int64(r.n))
+		}
+	}()
+	return out
+}
+
+// adapt returns the size of the queue for the peak number of items held over an interval
+func (q *AdaptiveQueue[T]) adapt(size, peak, n int) int {
+	fill := float64(peak) / float64(size)
+	switch {
+	case fill >= q.conf.GrowRatio && size < q.conf.Max:
+		return min(size*2, q.conf.Max)
+	case fill <= q.conf.ShrinkRatio && size > q.conf.Min:
+		return max(size/2, q.conf.Min, n)
+	}
+	return size
+}
+
+// ring is a FIFO queue of a fixed size
+type ring[T any] struct {
+	buf     []T
+	head, n int
+}
+
+// push adds an item to the back of the queue, which must not be full
+func (r *ring[T]) push(v T) {
+	r.buf[(r.head+r.n)%len(r.buf)] = v
+	r.n++
+}
+
+// pop removes the item at the front of the queue, which must not be empty
+func (r *ring[T]) pop() {
+	var zero T
+	r.buf[r.head] = zero
+	r.head = (r.head + 1) % len(r.buf)
+	r.n--
+}
+
+// resize swaps the queue to a new backing slice of the size, which must hold its items
+func (r *ring[T]) resize(size int) {
+	buf := make([]T, size)
+	for i := range r.n {
+		buf[i] = r.buf[(r.head+i)%len(r.buf)]
+	}
+	r.buf = buf
+	r.head = 0
+}
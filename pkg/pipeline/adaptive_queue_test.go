@@ -0,0 +1,70 @@
+package pipeline_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+func TestNewAdaptiveQueue(t *testing.T) {
+	for name, conf := range map[string]pipeline.AdaptiveConfig{
+		"min not positive": {Min: 0},
+		"max below min":    {Min: 4, Max: 2},
+		"ratios inverted":  {Min: 1, GrowRatio: 0.5, ShrinkRatio: 0.6},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := pipeline.NewAdaptiveQueue[int](conf)
+			assert.Error(t, err)
+		})
+	}
+
+	q, err := pipeline.NewAdaptiveQueue[int](pipeline.AdaptiveConfig{Min: 4})
+	require.NoError(t, err)
+	assert.Equal(t, 4, q.Size())
+}
+
+func TestAdaptiveQueue_Relay(t *testing.T) {
+	q, err := pipeline.NewAdaptiveQueue[int](pipeline.AdaptiveConfig{
+		Min:      2,
+		Max:      8,
+		Interval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var sizes []int
+	in := make(chan int)
+	out := q.Relay(in, func(from, to int) {
+		mu.Lock()
+		defer mu.Unlock()
+		sizes = append(sizes, to)
+	})
+
+	// the queue grows while the receiver is not ready, up to its max
+	go func() {
+		for i := range 8 {
+			in <- i
+		}
+	}()
+	assert.Eventually(t, func() bool { return q.Queued() == 8 }, time.Second, time.Millisecond)
+	assert.Equal(t, 8, q.Size())
+
+	for i := range 8 {
+		assert.Equal(t, i, <-out)
+	}
+
+	// and shrinks back to its min once it stays empty
+	assert.Eventually(t, func() bool { return q.Size() == 2 }, time.Second, time.Millisecond)
+	close(in)
+	_, ok := <-out
+	assert.False(t, ok)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{4, 8, 4, 2}, sizes)
+}
@@ -0,0 +1,202 @@
+// Package bench measures the throughput, latency and allocations of flows in a standard pipeline, so
+// changes to flows can be quantified and compared.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+	"github.com/witfoo/krapht/pkg/pipeline/source"
+)
+
+// ErrBench is returned when a benchmark cannot be run.
+var ErrBench = errors.New("bench error")
+
+// Stage is a named flow of a benchmark pipeline. Typed flows are adapted with flow.NewAnyFlow.
+type Stage struct {
+	Name string
+	Flow pipeline.Flow[any, any]
+}
+
+// Config is the configuration of a benchmark.
+type Config struct {
+	Name       string  // name of the report, defaults to "bench"
+	Records    int     // records generated, defaults to 100000
+	Size       int     // minimum payload size of the records in bytes, defaults to 256
+	Rate       float64 // max records generated per second, 0 generates as fast as possible
+	SinkRate   float64 // max records consumed per second by the sink, 0 consumes as fast as possible
+	Seed       uint64  // seed of the generated records
+	LinkBuffer int     // buffer size of the channels between stages
+	ChunkSize  int     // items moved between stages at a time, 0 or 1 moves them one at a time
+
+	Flows []Stage // flows between the generator source and the blackhole sink, in order
+}
+
+// StageReport is the report of a stage of a benchmark pipeline.
+type StageReport struct {
+	Name     string        `json:"name"`
+	ItemsIn  uint64        `json:"items_in"`
+	ItemsOut uint64        `json:"items_out"`
+	Errors   uint64        `json:"errors"`
+	P50      time.Duration `json:"p50_ns"`
+	P99      time.Duration `json:"p99_ns"`
+}
+
+// Report is the result of a benchmark. Allocations are those of the whole process during the run, the
+// generator and sink included, so they are best compared to a run of the same configuration.
+type Report struct {
+	Name             string        `json:"name"`
+	Records          uint64        `json:"records"` // records loaded by the sink
+	Duration         time.Duration `json:"duration_ns"`
+	RecordsPerSecond float64       `json:"records_per_second"`
+	AllocsPerRecord  float64       `json:"allocs_per_record"`
+	BytesPerRecord   float64       `json:"bytes_per_record"` // bytes allocated per record
+	Errors           uint64        `json:"errors"`
+	Stages           []StageReport `json:"stages"`
+}
+
+// Run runs the flows of the configuration between a generator source and a blackhole sink until the
+// records are loaded or the context is done, and reports the measurements.
+func Run(ctx context.Context, conf Config) (Report, error) {
+	if conf.Name == "" {
+		conf.Name = "bench"
+	}
+
+	if conf.Records <= 0 {
+		conf.Records = 100000
+	}
+
+	if conf.Size <= 0 {
+		conf.Size = 256
+	}
+
+	gen, err := source.NewGenerator(source.GeneratorConfig{Count: conf.Records, Rate: conf.Rate, Size: conf.Size, Seed: conf.Seed})
+	if err != nil {
+		return Report{}, fmt.Errorf("%w: %w", ErrBench, err)
+	}
+
+	hole, err := sink.NewBlackhole[any](sink.BlackholeConfig{Rate: conf.SinkRate, Seed: conf.Seed, Release: true})
+	if err != nil {
+		return Report{}, fmt.Errorf("%w: %w", ErrBench, err)
+	}
+
+	r := pipeline.NewRunner(conf.Name, gen, pipeline.WithLinkBuffer(conf.LinkBuffer), pipeline.WithChunkSize(conf.ChunkSize))
+	for _, st := range conf.Flows {
+		if st.Flow == nil {
+			return Report{}, fmt.Errorf("%w: flow %s is nil", ErrBench, st.Name)
+		}
+		if err := pipeline.AddFlow(r, st.Name, st.Flow); err != nil {
+			return Report{}, fmt.Errorf("%w: %w", ErrBench, err)
+		}
+	}
+	if err := pipeline.SetSink[any](r, "blackhole", hole); err != nil {
+		return Report{}, fmt.Errorf("%w: %w", ErrBench, err)
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+
+	report := Report{Name: conf.Name}
+	for e := range r.Run(ctx) {
+		if e.Type() == pipeline.EventError {
+			report.Errors++
+		}
+	}
+
+	report.Duration = time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	stats := r.Stats()
+	report.Records = stats[len(stats)-1].ItemsIn
+	if report.Records > 0 {
+		report.RecordsPerSecond = float64(report.Records) / report.Duration.Seconds()
+		report.AllocsPerRecord = float64(after.Mallocs-before.Mallocs) / float64(report.Records)
+		report.BytesPerRecord = float64(after.TotalAlloc-before.TotalAlloc) / float64(report.Records)
+	}
+	for _, st := range stats {
+		report.Stages = append(report.Stages, StageReport{
+			Name:     st.Name,
+			ItemsIn:  st.ItemsIn,
+			ItemsOut: st.ItemsOut,
+			Errors:   st.Errors,
+			P50:      st.Latency.P50,
+			P99:      st.Latency.P99,
+		})
+	}
+	return report, nil
+}
+
+// WriteText writes the report as an aligned table.
+func (r Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "benchmark\t%s\n", r.Name)
+	fmt.Fprintf(tw, "records\t%d\n", r.Records)
+	fmt.Fprintf(tw, "duration\t%s\n", r.Duration.Round(time.Microsecond))
+	fmt.Fprintf(tw, "records/s\t%.0f\n", r.RecordsPerSecond)
+	fmt.Fprintf(tw, "allocs/record\t%.2f\n", r.AllocsPerRecord)
+	fmt.Fprintf(tw, "bytes/record\t%.0f\n", r.BytesPerRecord)
+	fmt.Fprintf(tw, "errors\t%d\n", r.Errors)
+	fmt.Fprintf(tw, "\nstage\tin\tout\terrors\tp50\tp99\n")
+	for _, st := range r.Stages {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%s\n", st.Name, st.ItemsIn, st.ItemsOut, st.Errors, st.P50, st.P99)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the report as JSON, to be read back with ReadJSON as the baseline of a comparison.
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// ReadJSON reads a report written with WriteJSON.
+func ReadJSON(rd io.Reader) (Report, error) {
+	var r Report
+	err := json.NewDecoder(rd).Decode(&r)
+	return r, err
+}
+
+// WriteComparison writes the measurements of the report next to those of the baseline, with the change
+// relative to the baseline.
+func WriteComparison(w io.Writer, base, r Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\t%s\t%s\tdelta\n", base.Name, r.Name)
+	row := func(name string, b, v float64, format string) {
+		fmt.Fprintf(tw, "%s\t"+format+"\t"+format+"\t%s\n", name, b, v, delta(b, v))
+	}
+	row("records/s", base.RecordsPerSecond, r.RecordsPerSecond, "%.0f")
+	row("allocs/record", base.AllocsPerRecord, r.AllocsPerRecord, "%.2f")
+	row("bytes/record", base.BytesPerRecord, r.BytesPerRecord, "%.0f")
+
+	baseStages := make(map[string]StageReport, len(base.Stages))
+	for _, st := range base.Stages {
+		baseStages[st.Name] = st
+	}
+	for _, st := range r.Stages {
+		b, ok := baseStages[st.Name]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(tw, "%s p99\t%s\t%s\t%s\n", st.Name, b.P99, st.P99, delta(float64(b.P99), float64(st.P99)))
+	}
+	return tw.Flush()
+}
+
+// delta formats the relative change from b to v
+func delta(b, v float64) string {
+	if b == 0 {
+		return "~"
+	}
+	return fmt.Sprintf("%+.1f%%", (v-b)/b*100)
+}
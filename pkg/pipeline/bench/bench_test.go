@@ -0,0 +1,72 @@
+package bench_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/bench"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestRun(t *testing.T) {
+	filter, err := flow.NewCELFilter[any](`record.level != "debug"`, nil)
+	assert.NoError(t, err)
+
+	failing, err := flow.NewMap(func(in any) (any, error) {
+		if b, _ := in.(pipeline.Readable).Read(); len(b)%7 == 0 {
+			return nil, errors.New("unlucky length")
+		}
+		return in, nil
+	})
+	assert.NoError(t, err)
+
+	report, err := bench.Run(context.Background(), bench.Config{
+		Name:    "cel",
+		Records: 1000,
+		Size:    128,
+		Flows: []bench.Stage{
+			{Name: "filter", Flow: filter},
+			{Name: "failing", Flow: failing},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "cel", report.Name)
+	assert.Len(t, report.Stages, 4)
+	assert.Equal(t, uint64(1000), report.Stages[0].ItemsOut)
+	assert.Less(t, report.Stages[1].ItemsOut, uint64(1000), "debug records are filtered")
+	assert.Equal(t, report.Stages[2].ItemsIn, report.Stages[2].ItemsOut+report.Stages[2].Errors)
+	assert.Equal(t, report.Stages[2].Errors, report.Errors)
+	assert.Equal(t, report.Stages[3].ItemsIn, report.Records)
+	assert.Positive(t, report.RecordsPerSecond)
+	assert.Positive(t, report.AllocsPerRecord)
+
+	var text bytes.Buffer
+	assert.NoError(t, report.WriteText(&text))
+	assert.Contains(t, text.String(), "records/s")
+	assert.Contains(t, text.String(), "filter")
+
+	var js bytes.Buffer
+	assert.NoError(t, report.WriteJSON(&js))
+	base, err := bench.ReadJSON(&js)
+	assert.NoError(t, err)
+	assert.Equal(t, report, base)
+
+	var cmp bytes.Buffer
+	assert.NoError(t, bench.WriteComparison(&cmp, base, report))
+	assert.Contains(t, cmp.String(), "+0.0%")
+	assert.Contains(t, cmp.String(), "filter p99")
+}
+
+func TestRunErrors(t *testing.T) {
+	_, err := bench.Run(context.Background(), bench.Config{Flows: []bench.Stage{{Name: "nil"}}})
+	assert.ErrorIs(t, err, bench.ErrBench)
+
+	_, err = bench.Run(context.Background(), bench.Config{Rate: -1})
+	assert.ErrorIs(t, err, bench.ErrBench)
+}
@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrReleased is the error of reading a pooled payload after it was released
+var ErrReleased = errors.New("payload was released")
+
+// Releasable is implemented by items holding pooled buffers, which return the buffers to their pool when
+// the item is released. Only the last consumer of an item may release it, such as a sink that has
+// written it, and the item and the bytes read from it must not be used after.
+type Releasable interface {
+	Release()
+}
+
+// Release releases the item if it is Releasable.
+func Release(v any) {
+	if r, ok := v.(Releasable); ok {
+		r.Release()
+	}
+}
+
+// DefaultBufferPool is the buffer pool of the payloads of the sources and flows, retaining buffers up to 1MiB.
+var DefaultBufferPool = NewBufferPool(1 << 20)
+
+// BufferPool is a pool of byte buffers for payloads, so the buffers of released items are reused rather
+// than allocated for each item.
+type BufferPool struct {
+	pool        sync.Pool // of *bytes.Buffer
+	maxRetained int
+}
+
+// NewBufferPool creates a new buffer pool retaining buffers up to maxRetained bytes, larger buffers are
+// left to the garbage collector so a few large payloads do not hold on to memory.
+func NewBufferPool(maxRetained int) *BufferPool {
+	return &BufferPool{
+		pool: sync.Pool{New: func() any {
+			return new(bytes.Buffer)
+		}},
+		maxRetained: maxRetained,
+	}
+}
+
+// Get returns an empty buffer from the pool.
+func (p *BufferPool) Get() *bytes.Buffer {
+	return p.pool.Get().(*bytes.Buffer)
+}
+
+// Put returns a buffer to the pool, the buffer must not be used after.
+func (p *BufferPool) Put(buf *bytes.Buffer) {
+	if buf.Cap() > p.maxRetained {
+		return
+	}
+	buf.Reset()
+	p.pool.Put(buf)
+}
+
+// Bytes returns a releasable payload of the contents of the buffer, which returns it to the pool when
+// released.
+func (p *BufferPool) Bytes(buf *bytes.Buffer) *PooledBytes {
+	return &PooledBytes{
+		buf:  buf,
+		pool: p,
+	}
+}
+
+// Ensure that PooledBytes implements the Readable and Releasable interfaces.
+var (
+	_ Readable   = (*PooledBytes)(nil)
+	_ Releasable = (*PooledBytes)(nil)
+)
+
+// PooledBytes is a Readable over a pooled buffer, which is returned to its pool when released.
+type PooledBytes struct {
+	buf      *bytes.Buffer
+	pool     *BufferPool
+	released atomic.Bool
+}
+
+// Read returns the bytes of the buffer, or ErrReleased after the payload was released.
+func (b *PooledBytes) Read() ([]byte, error) {
+	if b.released.Load() {
+		return nil, ErrReleased
+	}
+	return b.buf.Bytes(), nil
+}
+
+// Release returns the buffer to its pool, releasing it more than once has no effect.
+func (b *PooledBytes) Release() {
+	if b.released.CompareAndSwap(false, true) {
+		b.pool.Put(b.buf)
+	}
+}
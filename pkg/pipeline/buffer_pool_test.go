@@ -0,0 +1,48 @@
+package pipeline_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+func TestBufferPool(t *testing.T) {
+	pool := pipeline.NewBufferPool(1 << 10)
+
+	buf := pool.Get()
+	assert.Zero(t, buf.Len())
+	buf.WriteString("payload")
+
+	pooled := pool.Bytes(buf)
+	b, err := pooled.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("payload"), b)
+
+	pooled.Release()
+	pooled.Release()
+	_, err = pooled.Read()
+	assert.ErrorIs(t, err, pipeline.ErrReleased)
+
+	assert.Zero(t, pool.Get().Len(), "buffers are reset when they are pooled")
+}
+
+func TestRecordRelease(t *testing.T) {
+	pool := pipeline.NewBufferPool(1 << 10)
+	data, raw := pool.Get(), pool.Get()
+	data.WriteString(`{"msg":"one"}`)
+	raw.WriteString("one")
+
+	record := pipeline.NewRecord(pool.Bytes(data), pool.Bytes(raw))
+	pipeline.Release(record)
+
+	_, err := record.Data().Read()
+	assert.ErrorIs(t, err, pipeline.ErrReleased)
+	_, err = record.Raw().Read()
+	assert.ErrorIs(t, err, pipeline.ErrReleased)
+
+	// items that are not releasable are ignored
+	pipeline.Release(pipeline.Bytes("one"))
+	pipeline.Release(1)
+}
@@ -0,0 +1,109 @@
+package pipeline
+
+import (
+	"bytes"
+	"sync/atomic"
+)
+
+// SharedBuffer is a pooled buffer of a block read by a source, such as from a TCP connection, a file or
+// an object, shared by the views of the records in it, so the records are not copied out of the block.
+// It is returned to its pool once it and all of its views are released.
+type SharedBuffer struct {
+	buf  *bytes.Buffer
+	pool *BufferPool
+	refs atomic.Int64
+}
+
+// Shared returns a shared buffer of the contents of the buffer, holding a reference released by Release.
+func (p *BufferPool) Shared(buf *bytes.Buffer) *SharedBuffer {
+	b := &SharedBuffer{
+		buf:  buf,
+		pool: p,
+	}
+	b.refs.Store(1)
+	return b
+}
+
+// Bytes returns the contents of the buffer, which must not be used after the buffer is released.
+func (b *SharedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+// View returns a view of the n bytes of the buffer at the offset, holding a reference to the buffer. It
+// panics if the bytes are out of the range of the buffer, like slicing them would.
+func (b *SharedBuffer) View(off, n int) *BufferView {
+	_ = b.buf.Bytes()[off : off+n]
+	b.refs.Add(1)
+	return &BufferView{
+		shared: b,
+		off:    off,
+		n:      n,
+	}
+}
+
+// Split returns views of the records of the buffer terminated by the separator, without it, and the
+// bytes after the last separator, such as a partial record to be completed by the next block, which must
+// be copied before the buffer is released. Empty records are skipped.
+func (b *SharedBuffer) Split(sep byte) ([]*BufferView, []byte) {
+	p := b.buf.Bytes()
+	var views []*BufferView
+	off := 0
+	for {
+		i := bytes.IndexByte(p[off:], sep)
+		if i < 0 {
+			return views, p[off:]
+		}
+		if i > 0 {
+			views = append(views, b.View(off, i))
+		}
+		off += i + 1
+	}
+}
+
+// Release releases the reference of the reader of the buffer, the views of the buffer hold theirs.
+func (b *SharedBuffer) Release() {
+	b.release()
+}
+
+// release releases a reference, returning the buffer to its pool after the last one
+func (b *SharedBuffer) release() {
+	if b.refs.Add(-1) == 0 {
+		b.pool.Put(b.buf)
+	}
+}
+
+// Ensure that BufferView implements the Readable and Releasable interfaces.
+var (
+	_ Readable   = (*BufferView)(nil)
+	_ Releasable = (*BufferView)(nil)
+)
+
+// BufferView is a Readable over a sub-slice of a shared buffer. It is released when it is acked by a
+// sink, like upstream messages are, or released.
+type BufferView struct {
+	shared   *SharedBuffer
+	off, n   int
+	released atomic.Bool
+}
+
+// Read returns the bytes of the view, whose capacity ends with them so appending to them does not
+// overwrite the bytes after, or ErrReleased after the view was released.
+func (v *BufferView) Read() ([]byte, error) {
+	if v.released.Load() {
+		return nil, ErrReleased
+	}
+	return v.shared.buf.Bytes()[v.off : v.off+v.n : v.off+v.n], nil
+}
+
+// Release releases the reference of the view to its buffer, releasing it more than once has no effect.
+func (v *BufferView) Release() {
+	if v.released.CompareAndSwap(false, true) {
+		v.shared.release()
+	}
+}
+
+// Ack releases the view, as the sink is done with it.
+func (v *BufferView) Ack() error {
+	v.Release()
+	return nil
+}
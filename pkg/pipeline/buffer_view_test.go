@@ -0,0 +1,56 @@
+package pipeline_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+func TestSharedBuffer(t *testing.T) {
+	pool := pipeline.NewBufferPool(1 << 10)
+	buf := pool.Get()
+	buf.WriteString("one\ntwo\n\nthr")
+
+	shared := pool.Shared(buf)
+	views, rest := shared.Split('\n')
+	assert.Equal(t, []byte("thr"), rest)
+	assert.Len(t, views, 2)
+
+	one, err := views[0].Read()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("one"), one)
+	assert.Equal(t, 3, cap(one), "appending to a view does not overwrite the next record")
+
+	// the buffer is only pooled after the reader and all views are released
+	shared.Release()
+	assert.NoError(t, views[0].Ack())
+	two, err := views[1].Read()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("two"), two)
+
+	views[1].Release()
+	views[1].Release()
+	_, err = views[1].Read()
+	assert.ErrorIs(t, err, pipeline.ErrReleased)
+	assert.Zero(t, pool.Get().Len())
+
+	assert.Panics(t, func() { pool.Shared(pool.Get()).View(0, 1) })
+}
+
+func TestBufferViewRecord(t *testing.T) {
+	pool := pipeline.NewBufferPool(1 << 10)
+	buf := pool.Get()
+	buf.WriteString(`{"msg":"one"}`)
+	shared := pool.Shared(buf)
+
+	view := shared.View(0, buf.Len())
+	shared.Release()
+
+	// releasing a record releases its raw view
+	record := pipeline.NewRecord(pipeline.Bytes(`{"msg":"one"}`), view)
+	pipeline.Release(record)
+	_, err := view.Read()
+	assert.ErrorIs(t, err, pipeline.ErrReleased)
+}
@@ -0,0 +1,110 @@
+package pipeline
+
+import (
+	"fmt"
+)
+
+// Builder builds a Runner from a source, flows and a sink, checking at compile time that each stage
+// takes the items of the stage before it, where AddFlow and SetSink check it when the stage is added.
+// T is the item type of the last stage. A builder and the builders returned by its methods share the
+// runner they build, so a pipeline is built by a single chain of calls:
+//
+//	r, err := pipeline.Via(pipeline.From(src).Named("ingest"), parser).Via(filter).To(snk).Build()
+type Builder[T any] struct {
+	r     *Runner
+	flows int // number of flows added
+	err   error
+}
+
+// From starts building a pipeline reading from the source, named "pipeline" unless it is named with
+// Named. It accepts optional RunnerOption functions to configure the runner.
+func From[T any](src Source[T], opts ...RunnerOption) *Builder[T] {
+	b := &Builder[T]{r: NewRunner("pipeline", src, opts...)}
+	if src == nil {
+		b.err = fmt.Errorf("%w: source is nil", ErrRunner)
+	}
+	return b
+}
+
+// Named names the pipeline.
+func (b *Builder[T]) Named(name string) *Builder[T] {
+	b.r.name = name
+	return b
+}
+
+// As names the last stage added, which are named source, flow1, flow2 and so on, and sink otherwise.
+// Building fails when another stage has the name.
+func (b *Builder[T]) As(name string) *Builder[T] {
+	if b.err != nil {
+		return b
+	}
+
+	b.r.mu.Lock()
+	defer b.r.mu.Unlock()
+	last := b.r.stages[len(b.r.stages)-1]
+	if last.name != name {
+		if b.err = b.r.unique(name); b.err != nil {
+			return b
+		}
+	}
+	last.name = name
+	return b
+}
+
+// Via appends a flow keeping the item type. Methods cannot have type parameters of their own, so flows
+// changing the item type are appended with the Via function.
+func (b *Builder[T]) Via(f Flow[T, T]) *Builder[T] {
+	return Via(b, f)
+}
+
+// Via appends a flow to the pipeline of the builder, returning the builder of the output items of the flow.
+func Via[I, O any](b *Builder[I], f Flow[I, O]) *Builder[O] {
+	next := &Builder[O]{r: b.r, flows: b.flows + 1, err: b.err}
+	if next.err != nil {
+		return next
+	}
+
+	name := fmt.Sprintf("flow%d", next.flows)
+	if f == nil {
+		next.err = fmt.Errorf("%w: flow %s is nil", ErrRunner, name)
+		return next
+	}
+	next.err = AddFlow(b.r, name, f)
+	return next
+}
+
+// To sets the sink of the pipeline.
+func (b *Builder[T]) To(snk Sink[T]) *Builder[T] {
+	if b.err != nil {
+		return b
+	}
+	if snk == nil {
+		b.err = fmt.Errorf("%w: sink is nil", ErrRunner)
+		return b
+	}
+	b.err = SetSink(b.r, "sink", snk)
+	return b
+}
+
+// Build returns the runner of the pipeline, or the error of the first stage that could not be added.
+// Running the runner wires the channels of the stages and plumbs their events into its event channel.
+func (b *Builder[T]) Build() (*Runner, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if !b.r.sunk {
+		return nil, fmt.Errorf("%w: %s has no sink", ErrRunner, b.r.name)
+	}
+	return b.r, nil
+}
+
+// NewPipeline builds the runner of a pipeline reading from the source, passing items through the flows
+// in order and loading them into the sink, for pipelines whose flows keep the item type. It accepts
+// optional RunnerOption functions to configure the runner.
+func NewPipeline[T any](name string, src Source[T], flows []Flow[T, T], snk Sink[T], opts ...RunnerOption) (*Runner, error) {
+	b := From(src, opts...).Named(name)
+	for _, f := range flows {
+		b = b.Via(f)
+	}
+	return b.To(snk).Build()
+}
@@ -0,0 +1,73 @@
+package pipeline_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+)
+
+func TestBuilder(t *testing.T) {
+	double, err := flow.NewMap(func(in int) (int, error) { return in * 2, nil })
+	assert.NoError(t, err)
+	format, err := flow.NewMap(func(in int) (string, error) { return strconv.Itoa(in), nil })
+	assert.NoError(t, err)
+	out := mock.NewSink[string]()
+
+	r, err := pipeline.Via(pipeline.From(mock.NewChannelSourceOf(1, 2, 3)).Named("ingest").Via(double).As("double"), format).
+		To(out).
+		Build()
+	assert.NoError(t, err)
+	assert.Equal(t, "ingest", r.Name())
+
+	for range r.Run(context.Background()) {
+	}
+	out.AssertItems(t, []string{"2", "4", "6"})
+
+	var names []string
+	for _, s := range r.Stats() {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"source", "double", "flow2", "sink"}, names)
+}
+
+func TestBuilderErrors(t *testing.T) {
+	_, err := pipeline.From(mock.NewChannelSourceOf(1)).Build()
+	assert.ErrorIs(t, err, pipeline.ErrRunner, "no sink")
+
+	_, err = pipeline.From(mock.NewChannelSourceOf(1)).Via(nil).To(mock.NewSink[int]()).Build()
+	assert.ErrorIs(t, err, pipeline.ErrRunner, "nil flow")
+
+	_, err = pipeline.From(mock.NewChannelSourceOf(1)).To(nil).Build()
+	assert.ErrorIs(t, err, pipeline.ErrRunner, "nil sink")
+
+	_, err = pipeline.From[int](nil).To(mock.NewSink[int]()).Build()
+	assert.ErrorIs(t, err, pipeline.ErrRunner, "nil source")
+
+	_, err = pipeline.From(mock.NewChannelSourceOf(1)).Via(flow.NewPassthrough[int]()).As("source").To(mock.NewSink[int]()).Build()
+	assert.ErrorIs(t, err, pipeline.ErrRunner, "duplicate name")
+}
+
+func TestNewPipeline(t *testing.T) {
+	double, err := flow.NewMap(func(in int) (int, error) { return in * 2, nil })
+	assert.NoError(t, err)
+	inc, err := flow.NewMap(func(in int) (int, error) { return in + 1, nil })
+	assert.NoError(t, err)
+	out := mock.NewSink[int]()
+
+	r, err := pipeline.NewPipeline("ingest", mock.NewChannelSourceOf(1, 2, 3), []pipeline.Flow[int, int]{double, inc}, out)
+	assert.NoError(t, err)
+	assert.Equal(t, "ingest", r.Name())
+
+	for range r.Run(context.Background()) {
+	}
+	out.AssertItems(t, []int{3, 5, 7})
+
+	_, err = pipeline.NewPipeline[int]("ingest", mock.NewChannelSourceOf(1), nil, nil)
+	assert.ErrorIs(t, err, pipeline.ErrRunner)
+}
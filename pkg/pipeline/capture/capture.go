@@ -0,0 +1,183 @@
+// Package capture records the items passing through a point of a pipeline to a capture file, with the
+// time each item passed, so the traffic of one environment can be replayed in another by the replay
+// source.
+//
+// A capture file is newline delimited JSON: a header line with the format version and the time the
+// capture started, followed by a line per item with its offset from the start in nanoseconds and its
+// payload and raw message encoded as base64:
+//
+//	{"krapht_capture":1,"started":"2025-01-02T03:04:05Z"}
+//	{"offset":1500000,"data":"eyJob3N0IjoiZGIxIn0=","raw":"PDEzPmRiMQ=="}
+package capture
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Version is the version of the capture file format written by the Writer
+const Version = 1
+
+// ErrCapture is the error returned for invalid capture files and configurations
+var ErrCapture = errors.New("capture error")
+
+// maxLine is the longest line of a capture file the Reader accepts
+const maxLine = 64 << 20
+
+// Entry is an item of a capture file.
+type Entry struct {
+	Offset time.Duration `json:"offset"`        // time from the start of the capture until the item passed
+	Data   []byte        `json:"data"`          // payload of the item
+	Raw    []byte        `json:"raw,omitempty"` // raw message of the item, if it had one
+}
+
+// header is the first line of a capture file
+type header struct {
+	Version int       `json:"krapht_capture"`
+	Started time.Time `json:"started"`
+}
+
+// NewEntry creates the entry of an item passing at the offset. The payload of an item is its structured
+// data for DataReadable items, the bytes read for Readable items, the value itself for []byte and the
+// JSON encoding of any other value; the raw message is kept for RawReadable items.
+func NewEntry(offset time.Duration, item any) (Entry, error) {
+	e := Entry{Offset: offset}
+
+	var err error
+	switch r := item.(type) {
+	case pipeline.DataReadable:
+		e.Data, err = r.Data().Read()
+	case pipeline.Readable:
+		e.Data, err = r.Read()
+	case []byte:
+		e.Data = r
+	default:
+		e.Data, err = json.Marshal(r)
+	}
+	if err != nil {
+		return e, err
+	}
+
+	if raw, ok := item.(pipeline.RawReadable); ok && raw.Raw() != nil {
+		if e.Raw, err = raw.Raw().Read(); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// Readable returns the item of the entry, a Record when the entry has a raw message and Bytes otherwise
+func (e Entry) Readable() pipeline.Readable {
+	if e.Raw != nil {
+		return recordReadable{pipeline.NewRecord(pipeline.Bytes(e.Data), pipeline.Bytes(e.Raw))}
+	}
+	return pipeline.Bytes(e.Data)
+}
+
+// recordReadable is a Record that reads as its structured data
+type recordReadable struct {
+	pipeline.Record
+}
+
+// Read returns the structured data of the record
+func (r recordReadable) Read() ([]byte, error) {
+	return r.Data().Read()
+}
+
+// Writer writes a capture file.
+type Writer struct {
+	w       *bufio.Writer
+	enc     *json.Encoder
+	started time.Time
+}
+
+// NewWriter creates a new Writer of a capture started at the time, writing the header to w
+func NewWriter(w io.Writer, started time.Time) (*Writer, error) {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(header{Version: Version, Started: started.UTC()}); err != nil {
+		return nil, fmt.Errorf("%w: write header: %w", ErrCapture, err)
+	}
+
+	return &Writer{
+		w:       bw,
+		enc:     enc,
+		started: started,
+	}, nil
+}
+
+// Started returns the time the capture started
+func (w *Writer) Started() time.Time {
+	return w.started
+}
+
+// Write writes an entry to the capture file
+func (w *Writer) Write(e Entry) error {
+	return w.enc.Encode(e)
+}
+
+// Flush writes the buffered entries to the underlying writer
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Reader reads a capture file.
+type Reader struct {
+	s       *bufio.Scanner
+	started time.Time
+}
+
+// NewReader creates a new Reader of the capture file of r, reading its header
+func NewReader(r io.Reader) (*Reader, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxLine)
+	if !s.Scan() {
+		if err := s.Err(); err != nil {
+			return nil, fmt.Errorf("%w: read header: %w", ErrCapture, err)
+		}
+		return nil, fmt.Errorf("%w: missing header", ErrCapture)
+	}
+
+	var h header
+	if err := json.Unmarshal(s.Bytes(), &h); err != nil {
+		return nil, fmt.Errorf("%w: invalid header: %w", ErrCapture, err)
+	}
+	if h.Version != Version {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrCapture, h.Version)
+	}
+
+	return &Reader{
+		s:       s,
+		started: h.Started,
+	}, nil
+}
+
+// Started returns the time the capture started
+func (r *Reader) Started() time.Time {
+	return r.started
+}
+
+// Next returns the next entry of the capture file, or io.EOF after the last entry
+func (r *Reader) Next() (Entry, error) {
+	var e Entry
+	for r.s.Scan() {
+		if len(r.s.Bytes()) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(r.s.Bytes(), &e); err != nil {
+			return e, fmt.Errorf("%w: invalid entry: %w", ErrCapture, err)
+		}
+		return e, nil
+	}
+
+	if err := r.s.Err(); err != nil {
+		return e, fmt.Errorf("%w: read entry: %w", ErrCapture, err)
+	}
+	return e, io.EOF
+}
@@ -0,0 +1,115 @@
+package capture_test
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/capture"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+)
+
+func TestTap(t *testing.T) {
+	var buf bytes.Buffer
+	tap, err := capture.NewTap[any](capture.TapConfig{Writer: &buf})
+	assert.NoError(t, err)
+
+	items := []any{
+		pipeline.Bytes("one"),
+		pipeline.NewRecord(pipeline.Bytes(`{"n":2}`), pipeline.Bytes("two")),
+		map[string]int{"n": 3},
+		mock.ReadableBad{},
+	}
+	in := make(chan any)
+	go func() {
+		defer close(in)
+		for i, v := range items {
+			if i == 1 {
+				time.Sleep(10 * time.Millisecond)
+			}
+			in <- v
+		}
+	}()
+
+	eventC := make(chan pipeline.Event, 10)
+	var got []any
+	for v := range tap.Transform(in, eventC) {
+		got = append(got, v)
+	}
+	assert.Equal(t, items, got, "items are passed on unchanged")
+	assert.Len(t, eventC, 1, "the bad item is reported")
+
+	r, err := capture.NewReader(&buf)
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), r.Started(), time.Second)
+
+	first, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("one"), first.Data)
+	assert.Nil(t, first.Raw)
+
+	second, err := r.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(`{"n":2}`), second.Data)
+	assert.Equal(t, []byte("two"), second.Raw)
+	assert.GreaterOrEqual(t, second.Offset-first.Offset, 10*time.Millisecond)
+
+	replayed, ok := second.Readable().(pipeline.DataRawReadable)
+	assert.True(t, ok)
+	raw, _ := replayed.Raw().Read()
+	assert.Equal(t, []byte("two"), raw)
+
+	third, err := r.Next()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"n":3}`, string(third.Data))
+
+	_, err = r.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestTapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.ndjson")
+	tap, err := capture.NewTap[pipeline.Bytes](capture.TapConfig{Path: path})
+	assert.NoError(t, err)
+
+	in := make(chan pipeline.Bytes, 1)
+	in <- pipeline.Bytes("one")
+	close(in)
+	for range tap.Transform(in, nil) {
+	}
+
+	w, err := capture.NewTap[pipeline.Bytes](capture.TapConfig{Path: filepath.Join(path, "missing", "capture.ndjson")})
+	assert.NoError(t, err)
+	eventC := make(chan pipeline.Event, 1)
+	in = make(chan pipeline.Bytes, 1)
+	in <- pipeline.Bytes("unrecorded")
+	close(in)
+	var passed int
+	for range w.Transform(in, eventC) {
+		passed++
+	}
+	assert.Equal(t, 1, passed)
+	assert.Equal(t, pipeline.EventError, (<-eventC).Type())
+}
+
+func TestReaderErrors(t *testing.T) {
+	_, err := capture.NewReader(strings.NewReader(""))
+	assert.ErrorIs(t, err, capture.ErrCapture)
+
+	_, err = capture.NewReader(strings.NewReader(`{"krapht_capture":2}`))
+	assert.ErrorIs(t, err, capture.ErrCapture)
+
+	r, err := capture.NewReader(strings.NewReader("{\"krapht_capture\":1}\nnot json\n"))
+	assert.NoError(t, err)
+	_, err = r.Next()
+	assert.ErrorIs(t, err, capture.ErrCapture)
+
+	_, err = capture.NewTap[any](capture.TapConfig{})
+	assert.ErrorIs(t, err, capture.ErrCapture)
+}
@@ -0,0 +1,115 @@
+package capture
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Tap implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*Tap[any])(nil)
+
+// TapConfig is the configuration of a tap.
+type TapConfig struct {
+	Path   string    // path of the capture file, created or truncated when the tap starts
+	Writer io.Writer // writer of the capture, used instead of a file when the path is empty
+}
+
+// Tap is a flow that passes items unchanged while recording them, and the time they passed, to a
+// capture file.
+type Tap[T any] struct {
+	conf TapConfig
+}
+
+// NewTap creates a new tap with the given configuration.
+func NewTap[T any](conf TapConfig) (*Tap[T], error) {
+	if conf.Path == "" && conf.Writer == nil {
+		return nil, fmt.Errorf("%w: path is empty and writer is nil", ErrCapture)
+	}
+
+	return &Tap[T]{
+		conf: conf,
+	}, nil
+}
+
+// Transform passes the items of the input channel to the output channel and records them, starting the
+// capture when it is called and flushing it, and closing the file, when the input is closed. Items that
+// cannot be recorded are still passed on and reported with an error event; if the capture cannot be
+// started the items are passed on unrecorded.
+func (t Tap[T]) Transform(in <-chan T, eventC chan<- pipeline.Event) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+
+		w, closeFn, err := t.open()
+		if err != nil {
+			pipeline.SendEvent(eventC, pipeline.NewErrorEvent("tap capture error", err, false))
+			for v := range in {
+				out <- v
+			}
+			return
+		}
+		defer func() {
+			if err := closeFn(); err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewErrorEvent("tap capture error", err, false))
+			}
+		}()
+
+		for v := range in {
+			if err := t.record(w, v); err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("tap capture error", err, true, v))
+			}
+			out <- v
+		}
+	}()
+	return out
+}
+
+// open starts the capture, returning its writer and the func flushing and closing it
+func (t Tap[T]) open() (*Writer, func() error, error) {
+	dst := t.conf.Writer
+	var file *os.File
+	if t.conf.Path != "" {
+		var err error
+		if file, err = os.Create(t.conf.Path); err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", ErrCapture, err)
+		}
+		dst = file
+	}
+
+	w, err := NewWriter(dst, time.Now())
+	if err != nil {
+		if file != nil {
+			_ = file.Close()
+		}
+		return nil, nil, err
+	}
+
+	return w, func() error {
+		err := w.Flush()
+		if file != nil {
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCapture, err)
+		}
+		return nil
+	}, nil
+}
+
+// record writes the entry of an item to the capture
+func (t Tap[T]) record(w *Writer, v T) error {
+	e, err := NewEntry(time.Since(w.Started()), v)
+	if err != nil {
+		return fmt.Errorf("%w: read item: %w", ErrCapture, err)
+	}
+	if err := w.Write(e); err != nil {
+		return fmt.Errorf("%w: write entry: %w", ErrCapture, err)
+	}
+	return nil
+}
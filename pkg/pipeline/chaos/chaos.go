@@ -0,0 +1,125 @@
+// Package chaos provides decorators for sources, flows and sinks that inject the misbehavior of real
+// systems, such as delays, lost and duplicated items and errors, to verify pipelines survive them.
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// ErrChaos is the error of the injected failures
+var ErrChaos = errors.New("chaos error")
+
+// Config is the configuration of a chaos decorator. The rates are the probabilities, from 0 to 1, of the
+// faults for each item, which are decided in the order failure, error, drop and duplicate; delays are
+// independent of them.
+type Config struct {
+	Name          string        // name of the wrapped stage in the error events
+	Seed          uint64        // seed of the faults, so runs with the same seed and items inject the same faults
+	DelayRate     float64       // rate of items delayed before they are passed on
+	MaxDelay      time.Duration // delays are uniform up to it, defaults to 100ms
+	DropRate      float64       // rate of items silently dropped
+	DuplicateRate float64       // rate of items passed on twice
+	ErrorRate     float64       // rate of items dropped with a temporary error event
+	FailRate      float64       // rate of items failing the stage with a permanent error event, after which it passes no items
+}
+
+// withDefaults validates the configuration and returns it with the defaults applied
+func (c Config) withDefaults() (Config, error) {
+	if c.Name == "" {
+		return c, fmt.Errorf("%w: name is empty", ErrChaos)
+	}
+
+	for name, rate := range map[string]float64{
+		"delay":     c.DelayRate,
+		"drop":      c.DropRate,
+		"duplicate": c.DuplicateRate,
+		"error":     c.ErrorRate,
+		"fail":      c.FailRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return c, fmt.Errorf("%w: %s rate %v is not between 0 and 1", ErrChaos, name, rate)
+		}
+	}
+
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = 100 * time.Millisecond
+	}
+	return c, nil
+}
+
+// injector decides the faults of the items of a stage
+type injector struct {
+	conf Config
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// newInjector creates an injector with the given configuration
+func newInjector(conf Config) (*injector, error) {
+	conf, err := conf.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	return &injector{
+		conf: conf,
+		rng:  rand.New(rand.NewPCG(conf.Seed, conf.Seed)),
+	}, nil
+}
+
+// roll reports whether a fault of the rate happens
+func (j *injector) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.rng.Float64() < rate
+}
+
+// delay returns a random delay up to the max delay
+func (j *injector) delay() time.Duration {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return time.Duration(j.rng.Int64N(int64(j.conf.MaxDelay)))
+}
+
+// inject passes the items of the input channel to the returned channel with the faults injected. After a
+// failure the input is drained, so the stages before are not blocked.
+func inject[T any](j *injector, in <-chan T, eventC chan<- pipeline.Event) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for v := range in {
+			if j.roll(j.conf.DelayRate) {
+				time.Sleep(j.delay())
+			}
+
+			switch {
+			case j.roll(j.conf.FailRate):
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent(j.conf.Name+" chaos failure",
+					fmt.Errorf("%w: injected permanent failure", ErrChaos), false, v))
+				for range in {
+				}
+				return
+			case j.roll(j.conf.ErrorRate):
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent(j.conf.Name+" chaos error",
+					fmt.Errorf("%w: injected transient error", ErrChaos), true, v))
+			case j.roll(j.conf.DropRate):
+			case j.roll(j.conf.DuplicateRate):
+				out <- v
+				out <- v
+			default:
+				out <- v
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,133 @@
+package chaos_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/chaos"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+)
+
+func ints(n int) <-chan int {
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 1; i <= n; i++ {
+			in <- i
+		}
+	}()
+	return in
+}
+
+func drain(eventC chan pipeline.Event) []pipeline.ErrorEvent {
+	close(eventC)
+	var errs []pipeline.ErrorEvent
+	for e := range eventC {
+		if errEvent, ok := e.(pipeline.ErrorEvent); ok {
+			errs = append(errs, errEvent)
+		}
+	}
+	return errs
+}
+
+func TestFlow(t *testing.T) {
+	tests := []struct {
+		name   string
+		conf   chaos.Config
+		want   int
+		errors int
+	}{
+		{name: "passes items without faults", conf: chaos.Config{}, want: 100},
+		{name: "drops items", conf: chaos.Config{DropRate: 1}, want: 0},
+		{name: "duplicates items", conf: chaos.Config{DuplicateRate: 1}, want: 200},
+		{name: "errors items", conf: chaos.Config{ErrorRate: 1}, want: 0, errors: 100},
+		{name: "fails on the first item", conf: chaos.Config{FailRate: 1}, want: 0, errors: 1},
+		{name: "delays items", conf: chaos.Config{DelayRate: 1, MaxDelay: time.Millisecond}, want: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conf.Name = "passthrough"
+			f, err := chaos.NewFlow[int, int](flow.NewPassthrough[int](), tt.conf)
+			assert.NoError(t, err)
+
+			eventC := make(chan pipeline.Event, 200)
+			var got int
+			for range f.Transform(ints(100), eventC) {
+				got++
+			}
+			errs := drain(eventC)
+			assert.Equal(t, tt.want, got)
+			assert.Len(t, errs, tt.errors)
+			for _, e := range errs {
+				assert.ErrorIs(t, e.Unwrap(), chaos.ErrChaos)
+			}
+		})
+	}
+}
+
+func TestFlowSeed(t *testing.T) {
+	run := func(seed uint64) []int {
+		f, err := chaos.NewFlow[int, int](flow.NewPassthrough[int](), chaos.Config{
+			Name: "passthrough", Seed: seed, DropRate: 0.2, DuplicateRate: 0.2, ErrorRate: 0.1,
+		})
+		assert.NoError(t, err)
+
+		var got []int
+		for v := range f.Transform(ints(100), nil) {
+			got = append(got, v)
+		}
+		return got
+	}
+
+	first := run(7)
+	assert.NotEqual(t, 100, len(first))
+	assert.Equal(t, first, run(7), "same seed injects the same faults")
+	assert.NotEqual(t, first, run(8))
+}
+
+func TestSource(t *testing.T) {
+	src, err := chaos.NewSource[mock.ReadableImpl](mock.NewSourceImpl([]mock.ReadableImpl{
+		mock.NewReadableImpl([]byte("a")),
+		mock.NewReadableImpl([]byte("b")),
+	}), chaos.Config{Name: "source", FailRate: 1})
+	assert.NoError(t, err)
+
+	eventC := make(chan pipeline.Event, 10)
+	var got int
+	for range src.Extract(context.Background(), eventC) {
+		got++
+	}
+	assert.Equal(t, 0, got)
+
+	errs := drain(eventC)
+	assert.Len(t, errs, 1)
+	assert.False(t, errs[0].IsTemporary())
+	assert.Equal(t, mock.NewReadableImpl([]byte("a")), errs[0].Record())
+}
+
+func TestSink(t *testing.T) {
+	captured := mock.NewSink[int]()
+	s, err := chaos.NewSink[int](captured, chaos.Config{Name: "capture", DuplicateRate: 1})
+	assert.NoError(t, err)
+
+	s.Load(ints(3), nil)
+	captured.AssertItems(t, []int{1, 1, 2, 2, 3, 3})
+	assert.True(t, captured.Closed())
+}
+
+func TestConfigErrors(t *testing.T) {
+	_, err := chaos.NewFlow[int, int](flow.NewPassthrough[int](), chaos.Config{})
+	assert.ErrorIs(t, err, chaos.ErrChaos)
+
+	_, err = chaos.NewFlow[int, int](flow.NewPassthrough[int](), chaos.Config{Name: "rate", DropRate: 1.5})
+	assert.ErrorIs(t, err, chaos.ErrChaos)
+
+	_, err = chaos.NewSink[int](nil, chaos.Config{Name: "nil"})
+	assert.ErrorIs(t, err, chaos.ErrChaos)
+}
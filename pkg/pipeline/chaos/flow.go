@@ -0,0 +1,39 @@
+package chaos
+
+import (
+	"fmt"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Flow implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*Flow[any, any])(nil)
+
+// Flow is a flow decorator that injects faults into the output of the wrapped flow.
+type Flow[I, O any] struct {
+	flow pipeline.Flow[I, O]
+	inj  *injector
+}
+
+// NewFlow creates a new chaos flow wrapping f.
+func NewFlow[I, O any](f pipeline.Flow[I, O], conf Config) (*Flow[I, O], error) {
+	if f == nil {
+		return nil, fmt.Errorf("%w: flow is nil", ErrChaos)
+	}
+
+	inj, err := newInjector(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Flow[I, O]{
+		flow: f,
+		inj:  inj,
+	}, nil
+}
+
+// Transform transforms the items of the input channel with the wrapped flow and returns the output
+// channel of its items with the faults injected. After a failure the output is closed.
+func (f Flow[I, O]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan O {
+	return inject(f.inj, f.flow.Transform(in, eventC), eventC)
+}
@@ -0,0 +1,39 @@
+package chaos
+
+import (
+	"fmt"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Sink implements the Sink interface.
+var _ pipeline.Sink[any] = (*Sink[any])(nil)
+
+// Sink is a sink decorator that injects faults into the items loaded by the wrapped sink.
+type Sink[I any] struct {
+	sink pipeline.Sink[I]
+	inj  *injector
+}
+
+// NewSink creates a new chaos sink wrapping s.
+func NewSink[I any](s pipeline.Sink[I], conf Config) (*Sink[I], error) {
+	if s == nil {
+		return nil, fmt.Errorf("%w: sink is nil", ErrChaos)
+	}
+
+	inj, err := newInjector(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sink[I]{
+		sink: s,
+		inj:  inj,
+	}, nil
+}
+
+// Load loads the items of the input channel with the faults injected into the wrapped sink. After a
+// failure the wrapped sink receives no more items and the input is drained.
+func (s Sink[I]) Load(in <-chan I, eventC chan<- pipeline.Event) {
+	s.sink.Load(inject(s.inj, in, eventC), eventC)
+}
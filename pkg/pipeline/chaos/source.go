@@ -0,0 +1,40 @@
+package chaos
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Source implements the Source interface.
+var _ pipeline.Source[any] = (*Source[any])(nil)
+
+// Source is a source decorator that injects faults into the items extracted by the wrapped source.
+type Source[T any] struct {
+	src pipeline.Source[T]
+	inj *injector
+}
+
+// NewSource creates a new chaos source wrapping src.
+func NewSource[T any](src pipeline.Source[T], conf Config) (*Source[T], error) {
+	if src == nil {
+		return nil, fmt.Errorf("%w: source is nil", ErrChaos)
+	}
+
+	inj, err := newInjector(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Source[T]{
+		src: src,
+		inj: inj,
+	}, nil
+}
+
+// Extract extracts the items of the wrapped source and returns the output channel of the items with the
+// faults injected. After a failure the output is closed.
+func (s Source[T]) Extract(ctx context.Context, eventC chan<- pipeline.Event) <-chan T {
+	return inject(s.inj, s.src.Extract(ctx, eventC), eventC)
+}
@@ -0,0 +1,55 @@
+package pipeline
+
+// Chunk returns a channel of chunks of up to n items of the input channel. A chunk is sent when it is
+// full or when no item is ready on the input channel, so items are not held back waiting for a chunk to
+// fill. The chunks are owned by the receiver.
+func Chunk[T any](in <-chan T, n int) <-chan []T {
+	out := make(chan []T)
+	go func() {
+		defer close(out)
+		for chunk := range chunks(in, n) {
+			out <- chunk
+		}
+	}()
+	return out
+}
+
+// Unchunk returns a channel of the items of the chunks of the input channel, in order.
+func Unchunk[T any](in <-chan []T) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			for _, v := range chunk {
+				out <- v
+			}
+		}
+	}()
+	return out
+}
+
+// chunks yields chunks of up to n items of the input channel, taking the items that are ready without
+// waiting after the first item of a chunk
+func chunks[T any](in <-chan T, n int) func(yield func([]T) bool) {
+	return func(yield func([]T) bool) {
+		for v := range in {
+			chunk := make([]T, 1, n)
+			chunk[0] = v
+		fill:
+			for len(chunk) < n {
+				select {
+				case v, ok := <-in:
+					if !ok {
+						break fill
+					}
+					chunk = append(chunk, v)
+				default:
+					break fill
+				}
+			}
+			if !yield(chunk) {
+				return
+			}
+		}
+	}
+}
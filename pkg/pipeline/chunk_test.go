@@ -0,0 +1,47 @@
+package pipeline_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+func TestChunk(t *testing.T) {
+	in := make(chan int, 10)
+	for i := range 10 {
+		in <- i
+	}
+	close(in)
+
+	var chunks [][]int
+	for chunk := range pipeline.Chunk(in, 4) {
+		chunks = append(chunks, chunk)
+	}
+	// the buffered items are ready, so the chunks are full
+	assert.Equal(t, [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}, {8, 9}}, chunks)
+
+	c := make(chan []int, len(chunks))
+	for _, chunk := range chunks {
+		c <- chunk
+	}
+	close(c)
+
+	var items []int
+	for v := range pipeline.Unchunk(c) {
+		items = append(items, v)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, items)
+}
+
+func TestChunkNotHeldBack(t *testing.T) {
+	in := make(chan int)
+	chunks := pipeline.Chunk(in, 64)
+
+	in <- 1
+	assert.Equal(t, []int{1}, <-chunks, "a partial chunk is sent when no item is ready")
+	close(in)
+	_, ok := <-chunks
+	assert.False(t, ok)
+}
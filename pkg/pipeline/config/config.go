@@ -0,0 +1,279 @@
+// Package config builds pipelines from declarative YAML or JSON documents naming a source, flows and a
+// sink of the component registries with their options, so pipelines are defined without recompiling:
+//
+//	name: ingest
+//	drain_timeout: 30s
+//	source:
+//	  type: http
+//	  options: {addr: ":8008"}
+//	flows:
+//	  - name: errors
+//	    type: cel_filter
+//	    options: {expression: 'record.level == "error"'}
+//	sink:
+//	  type: nats_stream
+//	  options: {url: "nats://krapht:${secret:env:nats_password}@nats:4222", subject: logs}
+//
+// The options of each component are checked against the options it registered, and the secret
+// references in them are resolved when the pipeline is built with a secret provider. Components of
+// other packages are available once they registered, linked in by a blank import or in the Go plugins
+// listed under plugins.
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+	"github.com/witfoo/krapht/pkg/pipeline/secret"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+	"github.com/witfoo/krapht/pkg/pipeline/source"
+)
+
+// ErrConfig is returned for invalid pipeline configurations.
+var ErrConfig = errors.New("config error")
+
+// Component is a stage of a pipeline configuration: the registered type of the component, the name of
+// the stage and the options of the component. Flows are named by their type, followed by a count from
+// the second unnamed flow of a type on, such as cel_filter and cel_filter2, and the source and the sink
+// source and sink, unless they are named.
+type Component struct {
+	Name    string          `json:"name,omitempty"`
+	Type    string          `json:"type"`
+	Options json.RawMessage `json:"options,omitempty"`
+}
+
+// Config is a pipeline configuration, with the runner options left zero defaulting to those of the runner.
+type Config struct {
+	Name         string            `json:"name"`
+	Plugins      []string          `json:"plugins,omitempty"` // paths of Go plugins registering components
+	LinkBuffer   int               `json:"link_buffer,omitempty"`
+	EventBuffer  int               `json:"event_buffer,omitempty"`
+	ChunkSize    int               `json:"chunk_size,omitempty"`
+	DrainTimeout pipeline.Duration `json:"drain_timeout,omitempty"`
+	Source       Component         `json:"source"`
+	Flows        []Component       `json:"flows,omitempty"`
+	Sink         Component         `json:"sink"`
+}
+
+// Parse parses a YAML or JSON pipeline configuration. Fields that are not part of the configuration
+// are rejected, so misspelled fields are not silently ignored.
+func Parse(data []byte) (*Config, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+	doc, err := jsonValue(doc)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	var c Config
+	if err := d.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Load reads and parses the pipeline configuration file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+	return Parse(data)
+}
+
+// jsonValue converts a decoded YAML value to one encoding to JSON, whose object keys are strings
+func jsonValue(v any) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			j, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = j
+		}
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: key %v is not a string", ErrConfig, k)
+			}
+			j, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = j
+		}
+		return m, nil
+	case []any:
+		for i, e := range v {
+			j, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = j
+		}
+	}
+	return v, nil
+}
+
+// Components returns the descriptions of the registered sources, flows and sinks, in that order and
+// sorted by name, which configurations can name.
+func Components() []pipeline.Component {
+	components := source.Components()
+	components = append(components, flow.Components()...)
+	return append(components, sink.Components()...)
+}
+
+// Validate checks that the configuration names its pipeline and the type of each component.
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("%w: pipeline name is empty", ErrConfig)
+	}
+	if c.Source.Type == "" {
+		return fmt.Errorf("%w: %s: source type is empty", ErrConfig, c.Name)
+	}
+	for i, f := range c.Flows {
+		if f.Type == "" {
+			return fmt.Errorf("%w: %s: type of flow %d is empty", ErrConfig, c.Name, i+1)
+		}
+	}
+	if c.Sink.Type == "" {
+		return fmt.Errorf("%w: %s: sink type is empty", ErrConfig, c.Name)
+	}
+	return nil
+}
+
+// Option represents a functional option for configuring Build.
+type Option func(*build)
+
+// build holds the options of Build
+type build struct {
+	secrets    secret.Provider
+	runnerOpts []pipeline.RunnerOption
+}
+
+// WithSecrets configures the provider of the secrets referenced by the options of the components.
+// Without it the references are passed to the components as they are.
+func WithSecrets(p secret.Provider) Option {
+	return func(b *build) {
+		if p != nil {
+			b.secrets = p
+		}
+	}
+}
+
+// WithRunnerOptions configures options of the runner applied after those of the configuration.
+func WithRunnerOptions(opts ...pipeline.RunnerOption) Option {
+	return func(b *build) {
+		b.runnerOpts = append(b.runnerOpts, opts...)
+	}
+}
+
+// Build loads the plugins of the configuration, creates its components from the source, flow and sink
+// registries and connects them in a runner.
+func (c *Config) Build(ctx context.Context, opts ...Option) (*pipeline.Runner, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	var b build
+	for _, opt := range opts {
+		opt(&b)
+	}
+
+	for _, path := range c.Plugins {
+		if err := flow.LoadPlugin(path); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrConfig, c.Name, err)
+		}
+	}
+
+	conf, err := b.options(ctx, c.Source)
+	if err != nil {
+		return nil, err
+	}
+	src, err := source.NewRegistered(c.Source.Type, conf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrConfig, c.Name, err)
+	}
+
+	runnerOpts := []pipeline.RunnerOption{
+		pipeline.WithLinkBuffer(c.LinkBuffer),
+		pipeline.WithEventBuffer(c.EventBuffer),
+		pipeline.WithChunkSize(c.ChunkSize),
+		pipeline.WithDrainTimeout(time.Duration(c.DrainTimeout)),
+	}
+	p := pipeline.From(src, append(runnerOpts, b.runnerOpts...)...).Named(c.Name)
+	if c.Source.Name != "" {
+		p.As(c.Source.Name)
+	}
+
+	types := make(map[string]int, len(c.Flows))
+	for _, fc := range c.Flows {
+		conf, err := b.options(ctx, fc)
+		if err != nil {
+			return nil, err
+		}
+		f, err := flow.NewRegistered(fc.Type, conf)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrConfig, c.Name, err)
+		}
+		name := fc.Name
+		if name == "" {
+			types[fc.Type]++
+			name = fc.Type
+			if n := types[fc.Type]; n > 1 {
+				name += strconv.Itoa(n)
+			}
+		}
+		p = p.Via(f).As(name)
+	}
+
+	conf, err = b.options(ctx, c.Sink)
+	if err != nil {
+		return nil, err
+	}
+	snk, err := sink.NewRegistered(c.Sink.Type, conf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrConfig, c.Name, err)
+	}
+	p = p.To(snk)
+	if c.Sink.Name != "" {
+		p.As(c.Sink.Name)
+	}
+	return p.Build()
+}
+
+// options returns the options of a component with their secret references resolved
+func (b *build) options(ctx context.Context, c Component) (json.RawMessage, error) {
+	if b.secrets == nil {
+		return c.Options, nil
+	}
+	conf, err := secret.Resolve(ctx, b.secrets, c.Options)
+	if err != nil {
+		return nil, fmt.Errorf("%w: options of %s: %w", ErrConfig, c.Type, err)
+	}
+	return conf, nil
+}
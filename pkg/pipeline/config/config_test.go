@@ -0,0 +1,169 @@
+package config_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/config"
+	"github.com/witfoo/krapht/pkg/pipeline/secret"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+// counted is the number of items loaded into the config_test_count sinks, and token the token option
+// of the last one created
+var (
+	counted atomic.Int64
+	token   atomic.Value
+)
+
+func init() {
+	sink.Register("config_test_count", func(conf json.RawMessage) (pipeline.Sink[any], error) {
+		var c struct {
+			Token string `json:"token"`
+		}
+		if err := pipeline.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		token.Store(c.Token)
+		return countSink{}, nil
+	}, pipeline.Option{Name: "token", Type: pipeline.OptionString})
+}
+
+type countSink struct{}
+
+func (countSink) Load(in <-chan any, _ chan<- pipeline.Event) {
+	for range in {
+		counted.Add(1)
+	}
+}
+
+const ingest = `
+name: ingest
+link_buffer: 8
+drain_timeout: 5s
+source:
+  type: generator
+  options:
+    count: 5
+flows:
+  - type: passthrough
+  - name: again
+    type: passthrough
+  - type: passthrough
+sink:
+  name: count
+  type: config_test_count
+  options:
+    token: ${secret:token}
+`
+
+func TestParse(t *testing.T) {
+	c, err := config.Parse([]byte(ingest))
+	require.NoError(t, err)
+
+	assert.Equal(t, "ingest", c.Name)
+	assert.Equal(t, 8, c.LinkBuffer)
+	assert.Equal(t, pipeline.Duration(5*time.Second), c.DrainTimeout)
+	assert.Equal(t, "generator", c.Source.Type)
+	assert.JSONEq(t, `{"count": 5}`, string(c.Source.Options))
+	assert.Len(t, c.Flows, 3)
+	assert.Equal(t, "again", c.Flows[1].Name)
+	assert.Equal(t, "config_test_count", c.Sink.Type)
+
+	j, err := config.Parse([]byte(`{"name": "ingest", "source": {"type": "generator"}, "sink": {"type": "noop"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "noop", j.Sink.Type)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for name, doc := range map[string]string{
+		"syntax":       "name: [ingest",
+		"unknown":      "name: ingest\nsorce: {type: generator}\nsink: {type: noop}",
+		"no name":      "source: {type: generator}\nsink: {type: noop}",
+		"no source":    "name: ingest\nsink: {type: noop}",
+		"no flow type": "name: ingest\nsource: {type: generator}\nflows: [{name: parse}]\nsink: {type: noop}",
+		"no sink":      "name: ingest\nsource: {type: generator}",
+		"key":          "name: ingest\nsource: {type: generator, options: {1: one}}\nsink: {type: noop}",
+	} {
+		_, err := config.Parse([]byte(doc))
+		assert.ErrorIs(t, err, config.ErrConfig, name)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ingest.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(ingest), 0o600))
+	c, err := config.Load(path)
+	require.NoError(t, err)
+
+	secrets := secret.ProviderFunc(func(_ context.Context, name string) (string, error) {
+		return "s3cr3t-" + name, nil
+	})
+	r, err := c.Build(context.Background(), config.WithSecrets(secrets))
+	require.NoError(t, err)
+	assert.Equal(t, "s3cr3t-token", token.Load())
+
+	counted.Store(0)
+	for e := range r.Run(context.Background()) {
+		assert.NotEqual(t, pipeline.EventError, e.Type(), e.String())
+	}
+	assert.Equal(t, int64(5), counted.Load())
+
+	var names []string
+	for _, s := range r.Stats() {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"source", "passthrough", "again", "passthrough2", "count"}, names)
+}
+
+func TestBuildInvalid(t *testing.T) {
+	for name, doc := range map[string]string{
+		"source":  "name: ingest\nsource: {type: nope}\nsink: {type: noop}",
+		"flow":    "name: ingest\nsource: {type: generator}\nflows: [{type: nope}]\nsink: {type: noop}",
+		"sink":    "name: ingest\nsource: {type: generator}\nsink: {type: nope}",
+		"options": "name: ingest\nsource: {type: generator, options: {count: many}}\nsink: {type: noop}",
+	} {
+		c, err := config.Parse([]byte(doc))
+		require.NoError(t, err, name)
+		_, err = c.Build(context.Background())
+		assert.ErrorIs(t, err, config.ErrConfig, name)
+	}
+
+	c, err := config.Parse([]byte(ingest))
+	require.NoError(t, err)
+	_, err = c.Build(context.Background(), config.WithSecrets(secret.NewEnv("KRAPHT_CONFIG_TEST_")))
+	assert.ErrorIs(t, err, secret.ErrNotFound)
+}
+
+func TestLoadMissing(t *testing.T) {
+	_, err := config.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorIs(t, err, config.ErrConfig)
+}
+
+func TestComponents(t *testing.T) {
+	kinds := map[string]pipeline.StageKind{}
+	for _, c := range config.Components() {
+		kinds[c.Name] = c.Kind
+	}
+	assert.Equal(t, pipeline.StageSource, kinds["generator"])
+	assert.Equal(t, pipeline.StageFlow, kinds["passthrough"])
+	assert.Equal(t, pipeline.StageSink, kinds["config_test_count"])
+}
+
+func TestBuildPlugins(t *testing.T) {
+	c, err := config.Parse([]byte("name: ingest\nplugins: [" + filepath.Join(t.TempDir(), "missing.so") + "]\nsource: {type: generator}\nsink: {type: noop}"))
+	require.NoError(t, err)
+	assert.Len(t, c.Plugins, 1)
+
+	_, err = c.Build(context.Background())
+	assert.ErrorIs(t, err, config.ErrConfig)
+}
@@ -0,0 +1,231 @@
+// Package diagnostics provides an opt-in server for profiling pipelines in production.
+package diagnostics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	runpprof "runtime/pprof"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Server observes runners.
+var _ pipeline.Observer = (*Server)(nil)
+
+// ServerConfig is the configuration of a diagnostics server.
+type ServerConfig struct {
+	Addr string // listen address, defaults to "localhost:6060" so profiles are not exposed remotely
+}
+
+// Server is a diagnostics server exposing the pprof endpoints under /debug/pprof/ and dumps of the
+// runners it is attached to under /debug/krapht/: the goroutines of each stage and the length of the
+// channel after it.
+type Server struct {
+	conf ServerConfig
+
+	mu      sync.Mutex
+	runners map[string]*pipeline.Runner
+	srv     *http.Server
+}
+
+// StageDump is the state of a stage of a pipeline.
+type StageDump struct {
+	Name       string             `json:"name"`
+	Kind       pipeline.StageKind `json:"kind"`
+	ItemsIn    uint64             `json:"items_in"`
+	ItemsOut   uint64             `json:"items_out"`
+	Errors     uint64             `json:"errors"`
+	LatencyP50 time.Duration      `json:"latency_p50_ns"`
+	LatencyP99 time.Duration      `json:"latency_p99_ns"`
+	Queued     int                `json:"queued"`
+	Capacity   int                `json:"capacity"`
+	Goroutines int                `json:"goroutines"`
+}
+
+// PipelineDump is the state of the stages of a pipeline.
+type PipelineDump struct {
+	Name   string      `json:"name"`
+	Stages []StageDump `json:"stages"`
+}
+
+// Dump is the state of the pipelines of the runners the server is attached to.
+type Dump struct {
+	Goroutines int            `json:"goroutines"` // goroutines of the process, including those of no stage
+	Pipelines  []PipelineDump `json:"pipelines"`
+}
+
+// NewServer creates a new diagnostics server with the given configuration.
+func NewServer(conf ServerConfig) *Server {
+	if conf.Addr == "" {
+		conf.Addr = "localhost:6060"
+	}
+
+	return &Server{
+		conf:    conf,
+		runners: make(map[string]*pipeline.Runner),
+	}
+}
+
+// Handler returns the handler of the diagnostics endpoints, to serve them on another server.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/krapht/stages", s.serveStages)
+	return mux
+}
+
+// Listen starts serving the diagnostics endpoints on the configured address. It returns once the address
+// is bound, serving in the background until Close.
+func (s *Server) Listen() error {
+	ln, err := net.Listen("tcp", s.conf.Addr)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	// profiles take as long as requested, so only the headers are bounded
+	s.srv = &http.Server{Handler: s.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	srv := s.srv
+	s.mu.Unlock()
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			_ = ln.Close()
+		}
+	}()
+	return nil
+}
+
+// Close stops serving the diagnostics endpoints, waiting for requests in progress until the context is done.
+func (s *Server) Close(ctx context.Context) error {
+	s.mu.Lock()
+	srv := s.srv
+	s.srv = nil
+	s.mu.Unlock()
+
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
+
+// Start adds the runner to the dumps when a run starts, replacing a runner of the same name.
+func (s *Server) Start(_ context.Context, r *pipeline.Runner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runners[r.Name()] = r
+}
+
+// Observe ignores the events of a run.
+func (s *Server) Observe(*pipeline.Runner, pipeline.Event) {}
+
+// Stop keeps the runner in the dumps, showing the state its last run ended with.
+func (s *Server) Stop(*pipeline.Runner) {}
+
+// Dump returns the state of the pipelines, sorted by name.
+func (s *Server) Dump() (Dump, error) {
+	total, counts, err := stageGoroutines()
+	if err != nil {
+		return Dump{}, err
+	}
+
+	s.mu.Lock()
+	runners := make([]*pipeline.Runner, 0, len(s.runners))
+	for _, r := range s.runners {
+		runners = append(runners, r)
+	}
+	s.mu.Unlock()
+	slices.SortFunc(runners, func(a, b *pipeline.Runner) int { return strings.Compare(a.Name(), b.Name()) })
+
+	d := Dump{Goroutines: total, Pipelines: make([]PipelineDump, 0, len(runners))}
+	for _, r := range runners {
+		name := r.Name()
+		p := PipelineDump{Name: name}
+		for _, st := range r.Stats() {
+			p.Stages = append(p.Stages, StageDump{
+				Name:       st.Name,
+				Kind:       st.Kind,
+				ItemsIn:    st.ItemsIn,
+				ItemsOut:   st.ItemsOut,
+				Errors:     st.Errors,
+				LatencyP50: st.Latency.P50,
+				LatencyP99: st.Latency.P99,
+				Queued:     st.Queued,
+				Capacity:   st.Capacity,
+				Goroutines: counts[stageKey{pipeline: name, stage: st.Name}],
+			})
+		}
+		d.Pipelines = append(d.Pipelines, p)
+	}
+	return d, nil
+}
+
+// serveStages writes the dump as JSON
+func (s *Server) serveStages(w http.ResponseWriter, _ *http.Request) {
+	d, err := s.Dump()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(d)
+}
+
+// stageKey identifies a stage of a pipeline by the profiler labels of its goroutines
+type stageKey struct {
+	pipeline string
+	stage    string
+}
+
+// stageGoroutines returns the number of goroutines of the process and of each stage, from the goroutine
+// profile whose stacks are grouped by labels as well
+func stageGoroutines() (int, map[stageKey]int, error) {
+	var buf bytes.Buffer
+	if err := runpprof.Lookup("goroutine").WriteTo(&buf, 1); err != nil {
+		return 0, nil, err
+	}
+
+	var total, count int
+	counts := make(map[stageKey]int)
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "goroutine profile: total "):
+			total, _ = strconv.Atoi(strings.TrimPrefix(line, "goroutine profile: total "))
+		case strings.HasPrefix(line, "# labels: "):
+			var labels map[string]string
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "# labels: ")), &labels); err != nil {
+				continue
+			}
+			if name, ok := labels[pipeline.ProfileLabelPipeline]; ok {
+				counts[stageKey{pipeline: name, stage: labels[pipeline.ProfileLabelStage]}] += count
+			}
+		default:
+			// a group of goroutines with the same stack starts with their count
+			if n, _, ok := strings.Cut(line, " @ "); ok {
+				count, _ = strconv.Atoi(n)
+			}
+		}
+	}
+	return total, counts, scanner.Err()
+}
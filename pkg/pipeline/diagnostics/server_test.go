@@ -0,0 +1,76 @@
+package diagnostics_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/diagnostics"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// chanSource emits the items of a channel
+type chanSource chan int
+
+func (c chanSource) Extract(_ context.Context, _ chan<- pipeline.Event) <-chan int {
+	return c
+}
+
+type discard struct{}
+
+func (discard) Load(in <-chan int, _ chan<- pipeline.Event) {
+	for range in {
+	}
+}
+
+func TestServerStages(t *testing.T) {
+	s := diagnostics.NewServer(diagnostics.ServerConfig{})
+
+	src := make(chanSource)
+	r := pipeline.NewRunner("diag", src, pipeline.WithLinkBuffer(8))
+	assert.NoError(t, pipeline.AddFlow[int, int](r, "pass", flow.NewPassthrough[int]()))
+	assert.NoError(t, pipeline.SetSink[int](r, "discard", discard{}))
+	r.Attach(s)
+
+	events := r.Run(context.Background())
+	src <- 1
+
+	var d diagnostics.Dump
+	assert.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/krapht/stages", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NoError(t, json.NewDecoder(rec.Body).Decode(&d))
+		return len(d.Pipelines) == 1 && d.Pipelines[0].Stages[2].ItemsIn == 1
+	}, time.Second, 10*time.Millisecond)
+
+	p := d.Pipelines[0]
+	assert.Equal(t, "diag", p.Name)
+	assert.Positive(t, d.Goroutines)
+	assert.Equal(t, 8, p.Stages[0].Capacity)
+	for _, st := range p.Stages {
+		assert.Positive(t, st.Goroutines, st.Name)
+	}
+
+	close(src)
+	for range events {
+	}
+}
+
+func TestServerPprof(t *testing.T) {
+	s := diagnostics.NewServer(diagnostics.ServerConfig{Addr: "127.0.0.1:0"})
+
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/pprof/goroutine?debug=1", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine profile")
+
+	assert.NoError(t, s.Listen())
+	assert.NoError(t, s.Close(context.Background()))
+}
@@ -1,5 +1,7 @@
 package pipeline
 
+import "sync/atomic"
+
 // EventType represents the type of pipeline event
 type EventType uint8
 
@@ -31,6 +33,16 @@ func SendEvent(eventC chan<- Event, event Event) bool {
 	case eventC <- event:
 		return true
 	default:
+		droppedEvents.Add(1)
 		return false
 	}
 }
+
+// droppedEvents counts the events SendEvent dropped because the channel was full
+var droppedEvents atomic.Uint64
+
+// DroppedEvents returns the number of events SendEvent dropped because the channel was full,
+// since the program started.
+func DroppedEvents() uint64 {
+	return droppedEvents.Load()
+}
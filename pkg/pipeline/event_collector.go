@@ -3,6 +3,7 @@ package pipeline
 import (
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 // EventCallback is a function called when an event is received.
@@ -35,6 +36,58 @@ func WithBufferSize(size int) EventCollectorOption {
 	}
 }
 
+// WithAdaptiveBuffer configures the queue of events behind the event channel to resize within the bounds
+// of the configuration as it fills and empties, the event channel is then buffered to its minimum size.
+// A metric event of the new size is processed when the queue is resized. An invalid configuration is
+// ignored.
+func WithAdaptiveBuffer(conf AdaptiveConfig) EventCollectorOption {
+	return func(c *EventCollector) {
+		if queue, err := NewAdaptiveQueue[Event](conf); err == nil {
+			c.adaptive = queue
+			c.bufferSize = conf.Min
+		}
+	}
+}
+
+// AutotuneConfig is the configuration of the worker autotuning of an event collector.
+type AutotuneConfig struct {
+	// Min is the minimum number of workers, it defaults to 1.
+	Min int
+	// Max is the maximum number of workers, it defaults to Min.
+	Max int
+	// Interval is the interval at which the worker count is adjusted, it defaults to 1 second.
+	Interval time.Duration
+	// TargetWait is the queue wait of the events above which a worker is added, it defaults to 10ms.
+	TargetWait time.Duration
+}
+
+// WithAutotune configures the collector to adjust its worker count within the bounds of the configuration.
+// At each interval the collector measures the latency of the callbacks and estimates the queue wait of
+// the events from the queued events and the rate they are processed at. A worker is added while the wait
+// exceeds the target, and one is removed when the wait is under half the target and the workers are busy
+// less than half the time. The worker count of WithWorkers is the initial count. An invalid
+// configuration is ignored.
+func WithAutotune(conf AutotuneConfig) EventCollectorOption {
+	return func(c *EventCollector) {
+		if conf.Min <= 0 {
+			conf.Min = 1
+		}
+		if conf.Max == 0 {
+			conf.Max = conf.Min
+		}
+		if conf.Max < conf.Min {
+			return
+		}
+		if conf.Interval <= 0 {
+			conf.Interval = time.Second
+		}
+		if conf.TargetWait <= 0 {
+			conf.TargetWait = 10 * time.Millisecond
+		}
+		c.autotune = &conf
+	}
+}
+
 // WithCallback adds a callback that will be called for all events.
 func WithCallback(callback EventCallback) EventCollectorOption {
 	return func(c *EventCollector) {
@@ -65,7 +118,15 @@ type EventCollector struct {
 	typedCallbacks []TypedEventCallback
 	wg             sync.WaitGroup
 	eventChan      chan Event
+	adaptive       *AdaptiveQueue[Event] // queue of events of an adaptive buffer
+	autotune       *AutotuneConfig       // worker autotuning, if enabled
 	isOpen         atomic.Bool
+	processed      atomic.Uint64
+	running        atomic.Int64  // number of running workers
+	busy           atomic.Int64  // time spent in the callbacks by the workers, in nanoseconds
+	retire         chan struct{} // signals a worker to stop when autotuning
+	stop           chan struct{} // stops the autotuning
+	tuned          sync.WaitGroup
 }
 
 // NewEventCollector creates a new event collector with default settings.
@@ -98,33 +159,116 @@ func (c *EventCollector) Collect() chan<- Event {
 
 	// Create a buffered channel for event collection
 	eventChan := make(chan Event, c.bufferSize)
-	// Mark the collector as open
-	c.isOpen.Store(true)
+	// Mark the collector as open once the channel is set
 	c.eventChan = eventChan
+	c.isOpen.Store(true)
+
+	// Relay the events through the adaptive queue, if any
+	var events <-chan Event = eventChan
+	if c.adaptive != nil {
+		events = c.adaptive.Relay(eventChan, c.resized)
+	}
+
+	workers := c.workers
+	if c.autotune != nil {
+		workers = min(max(workers, c.autotune.Min), c.autotune.Max)
+		c.retire = make(chan struct{}, 1)
+		c.stop = make(chan struct{})
+	}
 
 	// Start worker goroutines to process events
-	for range c.workers {
-		// Increment the wait group counter for each worker
-		c.wg.Add(1)
-
-		// Start a goroutine for each worker
-		go func() {
-			// Ensure the goroutine signals completion
-			defer c.wg.Done()
-
-			// Process events until context is done
-			// or the event channel is closed
-			for event := range eventChan {
-				// Process the event
-				c.processEvent(event)
-			}
+	for range workers {
+		c.startWorker(events)
+	}
 
-		}()
+	// Adjust the worker count while the collector is open
+	if c.autotune != nil {
+		c.tuned.Add(1)
+		go c.tune(events)
 	}
+
 	// Return the event channel for sending events
 	return eventChan
 }
 
+// startWorker starts a worker goroutine processing events until the event channel is closed, or until
+// it is retired by the autotuning
+func (c *EventCollector) startWorker(events <-chan Event) {
+	// Increment the wait group counter for each worker
+	c.wg.Add(1)
+	c.running.Add(1)
+
+	go func() {
+		// Ensure the goroutine signals completion
+		defer c.wg.Done()
+		defer c.running.Add(-1)
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				// Process the event, timing the callbacks when autotuning
+				if c.autotune != nil {
+					start := time.Now()
+					c.processEvent(event)
+					c.busy.Add(int64(time.Since(start)))
+				} else {
+					c.processEvent(event)
+				}
+				c.processed.Add(1)
+			case <-c.retire:
+				return
+			}
+		}
+	}()
+}
+
+// tune adjusts the worker count at each interval to the queue wait and the busy time of the workers
+func (c *EventCollector) tune(events <-chan Event) {
+	defer c.tuned.Done()
+	conf := c.autotune
+	ticker := time.NewTicker(conf.Interval)
+	defer ticker.Stop()
+
+	processed, busy := c.processed.Load(), c.busy.Load()
+	last := time.Now()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case now := <-ticker.C:
+			elapsed := now.Sub(last)
+			done := c.processed.Load() - processed
+			spent := time.Duration(c.busy.Load() - busy)
+			processed, busy, last = c.processed.Load(), c.busy.Load(), now
+
+			// estimate the queue wait from the queued events and the rate they were processed at
+			workers := int(c.running.Load())
+			queued := c.Queued()
+			wait := time.Duration(0)
+			if queued > 0 {
+				wait = time.Duration(1<<63 - 1)
+				if done > 0 {
+					wait = time.Duration(float64(queued) / float64(done) * float64(elapsed))
+				}
+			}
+			utilization := float64(spent) / (float64(elapsed) * float64(max(workers, 1)))
+
+			switch {
+			case wait > conf.TargetWait && workers < conf.Max:
+				c.startWorker(events)
+			case wait < conf.TargetWait/2 && utilization < 0.5 && workers > conf.Min:
+				select {
+				case c.retire <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
 // processEvent handles a single event by applying callbacks.
 // It's called for each event received by a worker goroutine.
 func (c *EventCollector) processEvent(event Event) {
@@ -142,6 +286,34 @@ func (c *EventCollector) processEvent(event Event) {
 	}
 }
 
+// resized processes the metric event of the new size of the adaptive queue
+func (c *EventCollector) resized(_, to int) {
+	c.processEvent(NewMetricEvent(
+		MetricBufferSize, float64(to), map[string]string{"buffer": "event_collector"}, MetricTypeGauge))
+}
+
+// Processed returns the number of events processed by the callbacks.
+func (c *EventCollector) Processed() uint64 {
+	return c.processed.Load()
+}
+
+// Workers returns the number of running workers, which changes as the worker count is autotuned.
+func (c *EventCollector) Workers() int {
+	return int(c.running.Load())
+}
+
+// Queued returns the number of events waiting in the event channel and the adaptive queue, or zero when
+// the collector is closed.
+func (c *EventCollector) Queued() int {
+	if !c.isOpen.Load() {
+		return 0
+	}
+	if c.adaptive != nil {
+		return len(c.eventChan) + c.adaptive.Queued()
+	}
+	return len(c.eventChan)
+}
+
 // Close the event channel to signal all workers to stop
 func (c *EventCollector) Close() {
 	// Check if the collector is already closed
@@ -150,6 +322,11 @@ func (c *EventCollector) Close() {
 	}
 	// Mark the collector as closed
 	c.isOpen.Store(false)
+	// Stop the autotuning before the workers
+	if c.stop != nil {
+		close(c.stop)
+		c.tuned.Wait()
+	}
 	// Close the event channel to signal all workers to stop
 	close(c.eventChan)
 	// Wait for all workers to finish processing
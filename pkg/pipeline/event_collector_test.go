@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/witfoo/krapht/pkg/pipeline"
 	"github.com/witfoo/krapht/pkg/pipeline/mock"
@@ -275,6 +276,80 @@ func TestEventCollector_Collect(t *testing.T) {
 }
 
 // waitWithTimeout waits for the WaitGroup with a timeout
+func TestEventCollector_AdaptiveBuffer(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []float64
+	// the worker is blocked until the queue has grown
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(6)
+	typ := pipeline.EventType(42)
+	collector := pipeline.NewEventCollector(
+		pipeline.WithAdaptiveBuffer(pipeline.AdaptiveConfig{Min: 1, Max: 4, Interval: 10 * time.Millisecond}),
+		pipeline.WithTypedCallback(typ, func(pipeline.Event) {
+			<-release
+			wg.Done()
+		}),
+		pipeline.WithTypedCallback(pipeline.EventMetric, func(e pipeline.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			if m, ok := e.(pipeline.Measurable); ok && m.Name() == pipeline.MetricBufferSize {
+				sizes = append(sizes, m.Value())
+			}
+		}),
+	)
+	eventC := collector.Collect()
+
+	go func() {
+		for range 6 {
+			eventC <- mock.NewEvent(typ, "queued")
+		}
+	}()
+	assert.Eventually(t, func() bool { return collector.Queued() == 5 }, time.Second, time.Millisecond)
+	close(release)
+	waitWithTimeout(t, &wg, 2*time.Second)
+	collector.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.GreaterOrEqual(t, len(sizes), 2)
+	assert.Equal(t, []float64{2, 4}, sizes[:2])
+}
+
+func TestEventCollector_Autotune(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(200)
+	collector := pipeline.NewEventCollector(
+		pipeline.WithBufferSize(200),
+		pipeline.WithAutotune(pipeline.AutotuneConfig{
+			Min:        1,
+			Max:        4,
+			Interval:   10 * time.Millisecond,
+			TargetWait: time.Millisecond,
+		}),
+		pipeline.WithCallback(func(pipeline.Event) {
+			time.Sleep(2 * time.Millisecond)
+			wg.Done()
+		}),
+	)
+	eventC := collector.Collect()
+	assert.Equal(t, 1, collector.Workers())
+
+	// workers are added while the events wait in the queue
+	for range 200 {
+		eventC <- mock.NewEvent(pipeline.EventLog, "queued")
+	}
+	assert.Eventually(t, func() bool { return collector.Workers() == 4 }, 2*time.Second, time.Millisecond)
+	waitWithTimeout(t, &wg, 5*time.Second)
+
+	// and removed once they are idle
+	assert.Eventually(t, func() bool { return collector.Workers() == 1 }, 2*time.Second, time.Millisecond)
+
+	collector.Close()
+	assert.Equal(t, 0, collector.Workers())
+	assert.Equal(t, uint64(200), collector.Processed())
+}
+
 func waitWithTimeout(t *testing.T, wg *sync.WaitGroup, timeout time.Duration) {
 	t.Helper()
 
@@ -11,6 +11,7 @@ type ErrorEvent struct {
 	msg       string
 	err       error
 	temporary bool
+	record    any
 }
 
 // NewErrorEvent creates a new pipeline ErrorEvent
@@ -22,6 +23,18 @@ func NewErrorEvent(msg string, err error, temporary bool) Event {
 	}
 }
 
+// NewRecordErrorEvent creates a new pipeline ErrorEvent for a failure to process a specific record.
+// The record, or the slice of records of a failed batch, is carried with the event so that
+// decorators such as dead-letter sinks can act on it.
+func NewRecordErrorEvent(msg string, err error, temporary bool, record any) Event {
+	return ErrorEvent{
+		msg:       msg,
+		err:       err,
+		temporary: temporary,
+		record:    record,
+	}
+}
+
 // Type returns the event type
 func (e ErrorEvent) Type() EventType {
 	return EventError
@@ -46,3 +59,8 @@ func (e ErrorEvent) IsTemporary() bool {
 func (e ErrorEvent) Unwrap() error {
 	return e.err
 }
+
+// Record returns the record the error relates to, or nil when it is not specific to a record
+func (e ErrorEvent) Record() any {
+	return e.record
+}
@@ -131,3 +131,23 @@ func TestErrorableInterface(t *testing.T) {
 		t.Error("Event does not implement Errorable interface")
 	}
 }
+
+func TestNewRecordErrorEvent(t *testing.T) {
+	originalErr := errors.New("underlying error")
+	event := pipeline.NewRecordErrorEvent("test error", originalErr, true, "record")
+
+	errEvent, ok := event.(pipeline.ErrorEvent)
+	if !ok {
+		t.Fatal("Expected NewRecordErrorEvent to return an ErrorEvent")
+	}
+	if errEvent.Record() != "record" {
+		t.Errorf("Expected record %q, got %v", "record", errEvent.Record())
+	}
+	if !errEvent.IsTemporary() {
+		t.Error("Expected IsTemporary() to return true")
+	}
+
+	if pipeline.NewErrorEvent("test error", originalErr, true).(pipeline.ErrorEvent).Record() != nil {
+		t.Error("Expected ErrorEvent without a record to return nil")
+	}
+}
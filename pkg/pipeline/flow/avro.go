@@ -0,0 +1,214 @@
+package flow
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that AvroDecoder implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*AvroDecoder[any])(nil)
+
+// ErrMalformedAvro is the error of a message that is not Confluent framed Avro matching its schema.
+var ErrMalformedAvro = errors.New("malformed avro message")
+
+// avroMagic is the first byte of a Confluent framed message, followed by the 4 byte schema ID
+const avroMagic = 0
+
+// SchemaRegistry is the interface for looking up Avro writer schemas by ID.
+type SchemaRegistry interface {
+	Schema(ctx context.Context, id int) (string, error)
+}
+
+// SchemaRegistryConfig is the configuration of a schema registry client.
+type SchemaRegistryConfig struct {
+	URL      string        // base URL of the registry
+	Username string        // optional basic auth user
+	Password string        // optional basic auth password
+	Timeout  time.Duration // request timeout, defaults to 10 seconds
+}
+
+// SchemaRegistryClient is a client of the Confluent schema registry REST API.
+type SchemaRegistryClient struct {
+	url      string
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewSchemaRegistryClient creates a new schema registry client with the given configuration.
+func NewSchemaRegistryClient(conf SchemaRegistryConfig) (*SchemaRegistryClient, error) {
+	if _, err := url.ParseRequestURI(conf.URL); err != nil {
+		return nil, fmt.Errorf("invalid schema registry url: %w", err)
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 10 * time.Second
+	}
+
+	return &SchemaRegistryClient{
+		url:      strings.TrimSuffix(conf.URL, "/"),
+		username: conf.Username,
+		password: conf.Password,
+		client:   &http.Client{Timeout: conf.Timeout},
+	}, nil
+}
+
+// Schema fetches the schema with the ID from the registry.
+func (s *SchemaRegistryClient) Schema(ctx context.Context, id int) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url+"/schemas/ids/"+strconv.Itoa(id), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// drain the body so the connection can be reused
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return "", fmt.Errorf("schema %d: registry returned status %d", id, resp.StatusCode)
+	}
+
+	var body struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"` // omitted for Avro
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("schema %d: %w", id, err)
+	}
+	if body.SchemaType != "" && body.SchemaType != "AVRO" {
+		return "", fmt.Errorf("schema %d is %s, not AVRO", id, body.SchemaType)
+	}
+	return body.Schema, nil
+}
+
+// AvroDecoder is a struct that represents decoding of Confluent framed Avro messages on a data stream into records.
+type AvroDecoder[I any] struct {
+	registry   SchemaRegistry
+	codecs     *avroCodecs
+	deadLetter DeadLetterFunc[I]
+}
+
+// avroCodecs caches the codecs of the writer schemas by ID, which never change in a registry
+type avroCodecs struct {
+	mu     sync.Mutex
+	codecs map[int]*goavro.Codec
+}
+
+// NewAvroDecoder creates a new AvroDecoder flow fetching writer schemas from the registry.
+// Schemas are fetched once per ID and cached for the life of the flow; failed fetches are retried
+// with the next message of the ID. Messages that fail to decode are passed to deadLetter when it is not nil.
+func NewAvroDecoder[I any](registry SchemaRegistry, deadLetter DeadLetterFunc[I]) (*AvroDecoder[I], error) {
+	if registry == nil {
+		return nil, errors.New("schema registry is nil")
+	}
+
+	return &AvroDecoder[I]{
+		registry:   registry,
+		codecs:     &avroCodecs{codecs: make(map[int]*goavro.Codec)},
+		deadLetter: deadLetter,
+	}, nil
+}
+
+// Transform decodes the messages from the input channel and returns the output channel of records
+// holding the decoded value as JSON, with union values as plain JSON values rather than Avro's
+// {"type": value} encoding. The message is the raw message of a RawReadable, or a Readable, []byte
+// or string itself, and is retained as the raw message of the record.
+// Messages that fail to decode are dropped and reported with an error event carrying them as the record.
+func (a AvroDecoder[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		for v := range in {
+			record, err := a.record(v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("avro decode error", err, false, v))
+				if a.deadLetter != nil {
+					a.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- record
+		}
+	}()
+	return out
+}
+
+// record decodes the raw message of the item into a record
+func (a AvroDecoder[I]) record(v I) (pipeline.DataRawReadable, error) {
+	raw, err := rawOf(v)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := raw.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(b) < 5 || b[0] != avroMagic {
+		return nil, fmt.Errorf("%w: missing schema id header", ErrMalformedAvro)
+	}
+	id := int(binary.BigEndian.Uint32(b[1:5]))
+
+	codec, err := a.codec(id)
+	if err != nil {
+		return nil, err
+	}
+
+	native, rest, err := codec.NativeFromBinary(b[5:])
+	if err != nil {
+		return nil, fmt.Errorf("%w: schema %d: %w", ErrMalformedAvro, id, err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("%w: schema %d: %d trailing bytes", ErrMalformedAvro, id, len(rest))
+	}
+
+	data, err := codec.TextualFromNative(nil, native)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.DeriveRecord(pipeline.Bytes(data), raw, v), nil
+}
+
+// codec returns the cached codec of the schema ID, fetching the schema when it is not cached
+func (a AvroDecoder[I]) codec(id int) (*goavro.Codec, error) {
+	a.codecs.mu.Lock()
+	defer a.codecs.mu.Unlock()
+
+	if codec, ok := a.codecs.codecs[id]; ok {
+		return codec, nil
+	}
+
+	schema, err := a.registry.Schema(context.Background(), id)
+	if err != nil {
+		return nil, err
+	}
+
+	codec, err := goavro.NewCodecForStandardJSONFull(schema)
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %w", id, err)
+	}
+	a.codecs.codecs[id] = codec
+	return codec, nil
+}
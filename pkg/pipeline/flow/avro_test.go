@@ -0,0 +1,97 @@
+package flow_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+const avroTestSchema = `{"type":"record","name":"Login","fields":[
+	{"name":"user","type":"string"},
+	{"name":"port","type":"int"},
+	{"name":"note","type":["null","string"],"default":null}
+]}`
+
+// avroMessage returns the Confluent framed encoding of the textual value
+func avroMessage(t *testing.T, id uint32, textual string) []byte {
+	t.Helper()
+
+	codec, err := goavro.NewCodecForStandardJSONFull(avroTestSchema)
+	assert.NoError(t, err)
+	native, _, err := codec.NativeFromTextual([]byte(textual))
+	assert.NoError(t, err)
+
+	msg := binary.BigEndian.AppendUint32([]byte{0}, id)
+	msg, err = codec.BinaryFromNative(msg, native)
+	assert.NoError(t, err)
+	return msg
+}
+
+func TestAvroDecoder_Transform(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		user, pass, _ := r.BasicAuth()
+		assert.Equal(t, "svc:pw", user+":"+pass)
+		if r.URL.Path != "/schemas/ids/7" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"schema": avroTestSchema})
+	}))
+	defer server.Close()
+
+	registry, err := flow.NewSchemaRegistryClient(flow.SchemaRegistryConfig{URL: server.URL + "/", Username: "svc", Password: "pw"})
+	assert.NoError(t, err)
+
+	var letters int
+	decoder, err := flow.NewAvroDecoder(registry, func([]byte, error) { letters++ })
+	assert.NoError(t, err)
+
+	in := make(chan []byte, 5)
+	in <- avroMessage(t, 7, `{"user":"alice","port":22,"note":"first"}`)
+	in <- avroMessage(t, 7, `{"user":"bob","port":443,"note":null}`)
+	in <- avroMessage(t, 8, `{"user":"carol","port":80,"note":null}`)
+	in <- []byte(`{"user":"plain json"}`)
+	in <- avroMessage(t, 7, `{"user":"dave","port":1}`)[:8]
+	close(in)
+
+	eventC := make(chan pipeline.Event, 3)
+	var records []string
+	for r := range decoder.Transform(in, eventC) {
+		data, _ := r.Data().Read()
+		records = append(records, string(data))
+	}
+	close(eventC)
+
+	assert.Len(t, records, 2)
+	assert.JSONEq(t, `{"user":"alice","port":22,"note":"first"}`, records[0])
+	assert.JSONEq(t, `{"user":"bob","port":443,"note":null}`, records[1])
+	assert.Equal(t, 3, letters)
+	assert.Equal(t, int32(2), requests.Load(), "schema 7 is fetched once, schema 8 is not found")
+
+	var errs []error
+	for e := range eventC {
+		errs = append(errs, e.(pipeline.ErrorEvent))
+	}
+	assert.Len(t, errs, 3)
+	assert.NotErrorIs(t, errs[0], flow.ErrMalformedAvro)
+	assert.ErrorIs(t, errs[1], flow.ErrMalformedAvro)
+	assert.ErrorIs(t, errs[2], flow.ErrMalformedAvro)
+}
+
+func TestNewAvroDecoder_Validation(t *testing.T) {
+	_, err := flow.NewAvroDecoder[[]byte](nil, nil)
+	assert.Error(t, err)
+
+	_, err = flow.NewSchemaRegistryClient(flow.SchemaRegistryConfig{URL: "not a url"})
+	assert.Error(t, err)
+}
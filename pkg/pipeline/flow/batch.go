@@ -0,0 +1,79 @@
+package flow
+
+import (
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Batch implements the Flow interface.
+var _ pipeline.Flow[any, []any] = (*Batch[any])(nil)
+
+// Batch is a struct that represents grouping of items on a data stream into slices.
+type Batch[I any] struct {
+	size   int
+	maxAge time.Duration
+}
+
+// NewBatch creates a new Batch flow.
+// A batch is emitted when it holds size items or when maxAge has passed since its first item
+// was received, whichever comes first. The final partial batch is emitted when the input
+// channel is closed. If size is less than or equal to 0, it will default to 100.
+// If maxAge is less than or equal to 0, batches are only emitted when full or on close.
+func NewBatch[I any](size int, maxAge time.Duration) *Batch[I] {
+	if size <= 0 {
+		size = 100
+	}
+	return &Batch[I]{
+		size:   size,
+		maxAge: maxAge,
+	}
+}
+
+// Transform groups the data from the input channel into batches and returns the output channel.
+// Each emitted batch is a new slice owned by the receiver.
+func (b Batch[I]) Transform(in <-chan I, _ chan<- pipeline.Event) <-chan []I {
+	out := make(chan []I)
+
+	go func() {
+		defer close(out)
+
+		batch := make([]I, 0, b.size)
+
+		// the timer only runs while a partial batch is held
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+
+		emit := func() {
+			timer.Stop()
+			out <- batch
+			batch = make([]I, 0, b.size)
+		}
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if len(batch) > 0 {
+						out <- batch
+					}
+					return
+				}
+
+				if len(batch) == 0 && b.maxAge > 0 {
+					timer.Reset(b.maxAge)
+				}
+				batch = append(batch, v)
+				if len(batch) >= b.size {
+					emit()
+				}
+			case <-timer.C:
+				if len(batch) > 0 {
+					emit()
+				}
+			}
+		}
+	}()
+	return out
+}
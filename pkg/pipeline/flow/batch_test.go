@@ -0,0 +1,66 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestBatch_Transform(t *testing.T) {
+	t.Run("emits full batches and the final partial batch", func(t *testing.T) {
+		batch := flow.NewBatch[int](2, time.Hour)
+
+		in := make(chan int)
+		out := batch.Transform(in, nil)
+
+		go func() {
+			for i := 0; i < 5; i++ {
+				in <- i
+			}
+			close(in)
+		}()
+
+		var result [][]int
+		for b := range out {
+			result = append(result, b)
+		}
+
+		assert.Equal(t, [][]int{{0, 1}, {2, 3}, {4}}, result)
+	})
+
+	t.Run("emits partial batches after the max age", func(t *testing.T) {
+		batch := flow.NewBatch[string](10, 20*time.Millisecond)
+
+		in := make(chan string)
+		out := batch.Transform(in, nil)
+
+		in <- "a"
+		in <- "b"
+		select {
+		case b := <-out:
+			assert.Equal(t, []string{"a", "b"}, b)
+		case <-time.After(time.Second):
+			t.Fatal("partial batch was not emitted")
+		}
+
+		close(in)
+		_, ok := <-out
+		assert.False(t, ok)
+	})
+
+	t.Run("defaults the size", func(t *testing.T) {
+		batch := flow.NewBatch[int](0, 0)
+
+		in := make(chan int, 150)
+		for i := 0; i < 150; i++ {
+			in <- i
+		}
+		close(in)
+
+		out := batch.Transform(in, nil)
+		assert.Len(t, <-out, 100)
+		assert.Len(t, <-out, 50)
+	})
+}
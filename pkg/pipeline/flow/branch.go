@@ -0,0 +1,44 @@
+package flow
+
+import (
+	"errors"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Branch is a struct that represents a two-way split of a data stream by a predicate.
+// Unlike Filter, the items that do not satisfy the predicate are kept on a second output.
+type Branch[I any] struct {
+	predicate PredicateFunc[I]
+}
+
+// NewBranch creates a new Branch with the given predicate function.
+func NewBranch[I any](predicate PredicateFunc[I]) (*Branch[I], error) {
+	if predicate == nil {
+		return nil, errors.New("predicate func is nil")
+	}
+
+	return &Branch[I]{
+		predicate: predicate,
+	}, nil
+}
+
+// Branch sends the items from the input channel that satisfy the predicate to the matched channel and the
+// others to the unmatched channel, closing both when the input channel is closed. Both outputs must be read,
+// as a branch that is not read stalls the other; drain an unused branch or use Filter instead.
+func (b Branch[I]) Branch(in <-chan I, _ chan<- pipeline.Event) (matched, unmatched <-chan I) {
+	yes, no := make(chan I), make(chan I)
+
+	go func() {
+		defer close(yes)
+		defer close(no)
+		for v := range in {
+			if b.predicate(v) {
+				yes <- v
+			} else {
+				no <- v
+			}
+		}
+	}()
+	return yes, no
+}
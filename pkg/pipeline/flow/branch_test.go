@@ -0,0 +1,48 @@
+package flow_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestBranch_Branch(t *testing.T) {
+	b, err := flow.NewBranch(func(in int) bool { return in%2 == 0 })
+	assert.NoError(t, err)
+
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 1; i <= 6; i++ {
+			in <- i
+		}
+	}()
+
+	matched, unmatched := b.Branch(in, nil)
+	var even, odd []int
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for v := range matched {
+			even = append(even, v)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for v := range unmatched {
+			odd = append(odd, v)
+		}
+	}()
+	wg.Wait()
+
+	assert.Equal(t, []int{2, 4, 6}, even)
+	assert.Equal(t, []int{1, 3, 5}, odd)
+}
+
+func TestNewBranch_Validation(t *testing.T) {
+	_, err := flow.NewBranch[int](nil)
+	assert.Error(t, err)
+}
@@ -1,13 +1,19 @@
 package flow
 
-import "github.com/witfoo/krapht/pkg/pipeline"
+import (
+	"errors"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
 
 // Ensure that Buffer implements the Flow interface.
 var _ pipeline.Flow[any, any] = (*Buffer[any])(nil)
 
 // Buffer is a struct that represents a passthrough with buffered channel operation on a data stream.
 type Buffer[I any] struct {
-	size int
+	size  int
+	name  string
+	queue *pipeline.AdaptiveQueue[I] // queue of an adaptive buffer
 }
 
 // NewBuffer creates a new Buffer flow.
@@ -26,9 +32,43 @@ func NewBuffer[I any](size int) *Buffer[I] {
 	}
 }
 
+// NewAdaptiveBuffer creates a new Buffer flow whose size adapts to its fill within the bounds of the
+// configuration, rather than being fixed up front. A metric event of the new size, labelled with the
+// name of the buffer, is sent when it is resized.
+func NewAdaptiveBuffer[I any](name string, conf pipeline.AdaptiveConfig) (*Buffer[I], error) {
+	if name == "" {
+		return nil, errors.New("buffer name is empty")
+	}
+	queue, err := pipeline.NewAdaptiveQueue[I](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &Buffer[I]{
+		size:  conf.Min,
+		name:  name,
+		queue: queue,
+	}, nil
+}
+
+// Size returns the size of the buffer, which changes as an adaptive buffer is resized.
+func (b Buffer[I]) Size() int {
+	if b.queue != nil {
+		return b.queue.Size()
+	}
+	return b.size
+}
+
 // Transform applies the passthrough operation on the input channel and returns the output channel.
 // The passthrough operation simply forwards the data from the input channel to the buffered output channel.
-func (b Buffer[I]) Transform(in <-chan I, _ chan<- pipeline.Event) <-chan I {
+func (b Buffer[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan I {
+	if b.queue != nil {
+		labels := map[string]string{"buffer": b.name}
+		return b.queue.Relay(in, func(_, to int) {
+			pipeline.SendEvent(eventC, pipeline.NewMetricEvent(
+				pipeline.MetricBufferSize, float64(to), labels, pipeline.MetricTypeGauge))
+		})
+	}
+
 	out := make(chan I, b.size)
 
 	go func() {
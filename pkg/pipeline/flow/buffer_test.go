@@ -133,3 +133,42 @@ func TestBuffer_Transform(t *testing.T) {
 		assert.Equal(t, []int{0, 1, 2, 3, 4}, result)
 	})
 }
+
+func TestAdaptiveBuffer_Transform(t *testing.T) {
+	_, err := flow.NewAdaptiveBuffer[int]("", pipeline.AdaptiveConfig{Min: 1})
+	assert.Error(t, err)
+	_, err = flow.NewAdaptiveBuffer[int]("buffer", pipeline.AdaptiveConfig{})
+	assert.Error(t, err)
+
+	buffer, err := flow.NewAdaptiveBuffer[int]("buffer", pipeline.AdaptiveConfig{
+		Min:      1,
+		Max:      4,
+		Interval: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, buffer.Size())
+
+	in := make(chan int)
+	eventC := make(chan pipeline.Event, 10)
+	out := buffer.Transform(in, eventC)
+
+	// the buffer grows while the output is not read
+	go func() {
+		defer close(in)
+		for i := range 4 {
+			in <- i
+		}
+	}()
+	assert.Eventually(t, func() bool { return buffer.Size() == 4 }, time.Second, time.Millisecond)
+
+	var result []int
+	for v := range out {
+		result = append(result, v)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3}, result)
+
+	event := (<-eventC).(pipeline.Measurable)
+	assert.Equal(t, pipeline.MetricBufferSize, event.Name())
+	assert.Equal(t, 2.0, event.Value())
+	assert.Equal(t, map[string]string{"buffer": "buffer"}, event.Labels())
+}
@@ -0,0 +1,152 @@
+package flow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMalformedCEF is the error of a message that is not valid Common Event Format.
+var ErrMalformedCEF = errors.New("malformed cef message")
+
+// CEFEvent is the structured data of a Common Event Format message.
+type CEFEvent struct {
+	Version       int               `json:"version"`
+	DeviceVendor  string            `json:"device_vendor"`
+	DeviceProduct string            `json:"device_product"`
+	DeviceVersion string            `json:"device_version"`
+	SignatureID   string            `json:"signature_id"`
+	Name          string            `json:"name"`
+	Severity      string            `json:"severity"` // 0 to 10, or Low, Medium, High or Very-High
+	Extensions    map[string]string `json:"extensions,omitempty"`
+}
+
+// NewCEFParser creates a new Parser flow decoding Common Event Format messages into records
+// holding a CEFEvent as JSON data. Messages that fail to parse are passed to deadLetter when
+// it is not nil.
+func NewCEFParser[I any](deadLetter DeadLetterFunc[I]) (*Parser[I], error) {
+	return NewParser("cef", func(raw []byte) (any, error) {
+		return ParseCEF(raw)
+	}, deadLetter)
+}
+
+// cefHeaderFields is the number of pipe delimited fields before the extension
+const cefHeaderFields = 7
+
+// ParseCEF parses a Common Event Format message. Anything before the "CEF:" marker,
+// such as a syslog header, is ignored.
+func ParseCEF(raw []byte) (CEFEvent, error) {
+	start := bytes.Index(raw, []byte("CEF:"))
+	if start < 0 {
+		return CEFEvent{}, fmt.Errorf("%w: missing CEF marker", ErrMalformedCEF)
+	}
+	s := strings.TrimRight(string(raw[start+len("CEF:"):]), "\r\n")
+
+	// header fields escape pipes and backslashes
+	fields := make([]string, 0, cefHeaderFields)
+	var field strings.Builder
+	i := 0
+	for ; i < len(s) && len(fields) < cefHeaderFields; i++ {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\'):
+			field.WriteByte(s[i+1])
+			i++
+		case c == '|':
+			fields = append(fields, field.String())
+			field.Reset()
+		default:
+			field.WriteByte(c)
+		}
+	}
+	if len(fields) < cefHeaderFields {
+		return CEFEvent{}, fmt.Errorf("%w: expected %d header fields, got %d", ErrMalformedCEF, cefHeaderFields, len(fields))
+	}
+
+	version, ok := atoi([]byte(strings.TrimSpace(fields[0])))
+	if !ok {
+		return CEFEvent{}, fmt.Errorf("%w: invalid version %q", ErrMalformedCEF, fields[0])
+	}
+
+	extensions, err := parseCEFExtensions(s[i:])
+	if err != nil {
+		return CEFEvent{}, err
+	}
+
+	return CEFEvent{
+		Version:       version,
+		DeviceVendor:  fields[1],
+		DeviceProduct: fields[2],
+		DeviceVersion: fields[3],
+		SignatureID:   fields[4],
+		Name:          fields[5],
+		Severity:      fields[6],
+		Extensions:    extensions,
+	}, nil
+}
+
+// parseCEFExtensions parses space separated key=value pairs whose values may contain spaces.
+// A value runs until the space before the next key, so an unescaped equals sign starts a new key
+// only when it follows a space and a key.
+func parseCEFExtensions(s string) (map[string]string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	// positions of the equals signs that are not escaped
+	var eqs []int
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '=':
+			eqs = append(eqs, i)
+		}
+	}
+	if len(eqs) == 0 || strings.ContainsRune(s[:eqs[0]], ' ') {
+		return nil, fmt.Errorf("%w: invalid extension", ErrMalformedCEF)
+	}
+
+	extensions := make(map[string]string)
+	key, valueStart := s[:eqs[0]], eqs[0]+1
+	for _, eq := range eqs[1:] {
+		sp := strings.LastIndexByte(s[:eq], ' ')
+		if sp < valueStart {
+			// an equals sign within the value
+			continue
+		}
+		extensions[key] = unescapeCEFValue(strings.TrimRight(s[valueStart:sp], " "))
+		key, valueStart = s[sp+1:eq], eq+1
+	}
+	extensions[key] = unescapeCEFValue(s[valueStart:])
+	return extensions, nil
+}
+
+// unescapeCEFValue replaces the escaped equals signs, backslashes and line breaks of an extension value
+func unescapeCEFValue(v string) string {
+	if !strings.ContainsRune(v, '\\') {
+		return v
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' || i+1 == len(v) {
+			b.WriteByte(v[i])
+			continue
+		}
+		i++
+		switch v[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case '=', '\\':
+			b.WriteByte(v[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String()
+}
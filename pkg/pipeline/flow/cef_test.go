@@ -0,0 +1,86 @@
+package flow_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestParseCEF(t *testing.T) {
+	t.Run("parses the header and extensions", func(t *testing.T) {
+		event, err := flow.ParseCEF([]byte(`<134>Jan  2 03:04:05 fw1 CEF:0|Security|threat\|manager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 msg=Detected a threat. No action needed\=ok path=C:\\temp\\x.exe note=line\nbreak`))
+		assert.NoError(t, err)
+		assert.Equal(t, flow.CEFEvent{
+			Version:       0,
+			DeviceVendor:  "Security",
+			DeviceProduct: "threat|manager",
+			DeviceVersion: "1.0",
+			SignatureID:   "100",
+			Name:          "worm successfully stopped",
+			Severity:      "10",
+			Extensions: map[string]string{
+				"src":  "10.0.0.1",
+				"dst":  "2.1.2.2",
+				"spt":  "1232",
+				"msg":  "Detected a threat. No action needed=ok",
+				"path": `C:\temp\x.exe`,
+				"note": "line\nbreak",
+			},
+		}, event)
+	})
+
+	t.Run("parses messages without extensions", func(t *testing.T) {
+		event, err := flow.ParseCEF([]byte("CEF:1|Vendor|Product|2|login|User login|Low|\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, event.Version)
+		assert.Equal(t, "Low", event.Severity)
+		assert.Nil(t, event.Extensions)
+	})
+
+	t.Run("keeps unescaped equals signs within values", func(t *testing.T) {
+		event, err := flow.ParseCEF([]byte("CEF:0|V|P|1|1|N|5|request=https://example.com/?a=b act=blocked"))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{"request": "https://example.com/?a=b", "act": "blocked"}, event.Extensions)
+	})
+
+	t.Run("rejects malformed messages", func(t *testing.T) {
+		for _, raw := range []string{
+			"not cef",
+			"CEF:0|Vendor|Product|1.0",
+			"CEF:x|V|P|1|1|N|5|",
+			"CEF:0|V|P|1|1|N|5|no pairs here",
+		} {
+			_, err := flow.ParseCEF([]byte(raw))
+			assert.ErrorIs(t, err, flow.ErrMalformedCEF, raw)
+		}
+	})
+}
+
+func TestCEFParser_Transform(t *testing.T) {
+	parser, err := flow.NewCEFParser[[]byte](nil)
+	assert.NoError(t, err)
+
+	in := make(chan []byte, 2)
+	in <- []byte("CEF:0|V|P|1|1|N|5|src=10.0.0.1")
+	in <- []byte("garbage")
+	close(in)
+
+	eventC := make(chan pipeline.Event, 1)
+	var result []pipeline.DataRawReadable
+	for r := range parser.Transform(in, eventC) {
+		result = append(result, r)
+	}
+
+	assert.Len(t, result, 1)
+	data, _ := result[0].Data().Read()
+	var event map[string]any
+	assert.NoError(t, json.Unmarshal(data, &event))
+	assert.Equal(t, map[string]any{"src": "10.0.0.1"}, event["extensions"])
+
+	errEvent, ok := (<-eventC).(pipeline.ErrorEvent)
+	assert.True(t, ok)
+	assert.ErrorIs(t, errEvent, flow.ErrMalformedCEF)
+}
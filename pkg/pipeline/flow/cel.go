@@ -0,0 +1,306 @@
+package flow
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/common/types/traits"
+	"github.com/google/cel-go/ext"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that CELFilter and CELTransform implement the Flow interface.
+var (
+	_ pipeline.Flow[any, any]                      = (*CELFilter[any])(nil)
+	_ pipeline.Flow[any, pipeline.DataRawReadable] = (*CELTransform[any])(nil)
+)
+
+// celCostLimit bounds the evaluation cost of an expression per item, so an expression over a large record
+// cannot stall the stream.
+const celCostLimit = 1_000_000
+
+// celProgram is an expression compiled against the variables of an item: record, the payload decoded as a
+// JSON object or an empty map, and payload, the payload as a string.
+type celProgram struct {
+	program cel.Program
+}
+
+// newCELProgram compiles an expression whose result type is accepted by check
+func newCELProgram(expr string, check func(t *cel.Type) bool) (celProgram, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("record", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("payload", cel.StringType),
+		ext.Strings(),
+	)
+	if err != nil {
+		return celProgram{}, err
+	}
+
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return celProgram{}, fmt.Errorf("could not compile expression: %w", iss.Err())
+	}
+	if t := ast.OutputType(); !t.IsExactType(cel.DynType) && !check(t) {
+		return celProgram{}, fmt.Errorf("expression has unexpected result type %s", t)
+	}
+
+	program, err := env.Program(ast, cel.CostLimit(celCostLimit))
+	if err != nil {
+		return celProgram{}, err
+	}
+	return celProgram{program: program}, nil
+}
+
+// eval evaluates the expression on a payload, returning the decoded record with the result
+func (c celProgram) eval(b []byte) (map[string]any, ref.Val, error) {
+	record, err := decodeObject(b)
+	if err != nil {
+		record = make(map[string]any)
+	}
+	for k, v := range record {
+		record[k] = celFromJSON(v)
+	}
+
+	out, _, err := c.program.Eval(map[string]any{"record": record, "payload": string(b)})
+	return record, out, err
+}
+
+// celFromJSON converts the numbers of a decoded JSON value to integers or doubles
+func celFromJSON(v any) any {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]any:
+		for k, e := range t {
+			t[k] = celFromJSON(e)
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = celFromJSON(e)
+		}
+	}
+	return v
+}
+
+// celToJSON converts an expression result to a value that can be encoded as JSON
+func celToJSON(v ref.Val) (any, error) {
+	switch t := v.(type) {
+	case types.Null:
+		return nil, nil
+	case traits.Mapper:
+		m := make(map[string]any)
+		for it := t.Iterator(); it.HasNext() == types.True; {
+			k := it.Next()
+			key, ok := k.Value().(string)
+			if !ok {
+				return nil, fmt.Errorf("map key %v is not a string", k.Value())
+			}
+			e, err := celToJSON(t.Get(k))
+			if err != nil {
+				return nil, err
+			}
+			m[key] = e
+		}
+		return m, nil
+	case traits.Lister:
+		n, _ := t.Size().Value().(int64)
+		l := make([]any, 0, n)
+		for i := range n {
+			e, err := celToJSON(t.Get(types.Int(i)))
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, e)
+		}
+		return l, nil
+	case *types.Err:
+		return nil, t
+	default:
+		return v.Value(), nil
+	}
+}
+
+// CELFilter is a struct that represents a filter on a data stream by a CEL expression.
+type CELFilter[I any] struct {
+	program    celProgram
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewCELFilter creates a new CELFilter flow with a boolean CEL expression over the variables record,
+// the payload decoded as a JSON object or an empty map when it is not one, and payload, the payload
+// as a string, such as `record.severity >= 5 && payload.contains("denied")`.
+// Items whose evaluation fails are passed to deadLetter when it is not nil.
+func NewCELFilter[I any](expr string, deadLetter DeadLetterFunc[I]) (*CELFilter[I], error) {
+	program, err := newCELProgram(expr, func(t *cel.Type) bool { return t.IsExactType(cel.BoolType) })
+	if err != nil {
+		return nil, err
+	}
+
+	return &CELFilter[I]{
+		program:    program,
+		deadLetter: deadLetter,
+	}, nil
+}
+
+// Transform passes the data from the input channel for which the expression is true to the output channel.
+// Items whose evaluation fails, such as for a missing field or a result that is not a boolean, are dropped
+// and reported with an error event carrying them as the record.
+func (f CELFilter[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+	go func() {
+		defer close(out)
+		for v := range in {
+			ok, err := f.match(v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("cel filter error", err, false, v))
+				if f.deadLetter != nil {
+					f.deadLetter(v, err)
+				}
+				continue
+			}
+			if ok {
+				out <- v
+			}
+		}
+	}()
+	return out
+}
+
+// match evaluates the expression on the item
+func (f CELFilter[I]) match(v I) (bool, error) {
+	b, err := payload(v)
+	if err != nil {
+		return false, err
+	}
+
+	_, result, err := f.program.eval(b)
+	if err != nil {
+		return false, err
+	}
+
+	ok, isBool := result.Value().(bool)
+	if !isBool {
+		return false, errors.New("expression result is not a boolean")
+	}
+	return ok, nil
+}
+
+// CELTransformConfig is the configuration of a CEL transform.
+type CELTransformConfig struct {
+	// Expression evaluates to the new record, a map encoded as a JSON object, or a string or bytes used
+	// as the payload, such as `{"src": record.src_ip, "action": record.blocked ? "deny" : "allow"}`.
+	Expression string
+
+	// Merge sets the fields of the resulting map on the input record instead, removing fields set to null,
+	// so an expression only needs to name the fields it changes.
+	Merge bool
+}
+
+// CELTransform is a struct that represents transformation of the records on a data stream by a CEL expression.
+type CELTransform[I any] struct {
+	conf       CELTransformConfig
+	program    celProgram
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewCELTransform creates a new CELTransform flow with the given configuration. The expression has the
+// variables of NewCELFilter. Items whose evaluation fails are passed to deadLetter when it is not nil.
+func NewCELTransform[I any](conf CELTransformConfig, deadLetter DeadLetterFunc[I]) (*CELTransform[I], error) {
+	program, err := newCELProgram(conf.Expression, func(t *cel.Type) bool {
+		if conf.Merge {
+			return t.Kind() == types.MapKind
+		}
+		return t.Kind() == types.MapKind || t.IsExactType(cel.StringType) || t.IsExactType(cel.BytesType)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CELTransform[I]{
+		conf:       conf,
+		program:    program,
+		deadLetter: deadLetter,
+	}, nil
+}
+
+// Transform evaluates the expression on the records from the input channel and returns the output channel
+// of the resulting records, retaining the raw message of the input. Items whose evaluation fails are
+// dropped and reported with an error event carrying them as the record.
+func (c CELTransform[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		for v := range in {
+			record, err := c.record(v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("cel transform error", err, false, v))
+				if c.deadLetter != nil {
+					c.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- record
+		}
+	}()
+	return out
+}
+
+// record evaluates the expression on the item into a record
+func (c CELTransform[I]) record(v I) (pipeline.DataRawReadable, error) {
+	b, err := payload(v)
+	if err != nil {
+		return nil, err
+	}
+
+	record, result, err := c.program.eval(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	switch r := result.Value().(type) {
+	case string:
+		if c.conf.Merge {
+			return nil, errors.New("expression result is not a map")
+		}
+		data = []byte(r)
+	case []byte:
+		if c.conf.Merge {
+			return nil, errors.New("expression result is not a map")
+		}
+		data = bytes.Clone(r)
+	default:
+		converted, err := celToJSON(result)
+		if err != nil {
+			return nil, err
+		}
+		fields, ok := converted.(map[string]any)
+		if !ok {
+			return nil, errors.New("expression result is not a map")
+		}
+		if c.conf.Merge {
+			for k, e := range fields {
+				if e == nil {
+					delete(record, k)
+				} else {
+					record[k] = e
+				}
+			}
+			fields = record
+		}
+		if data, err = json.Marshal(fields); err != nil {
+			return nil, err
+		}
+	}
+
+	return pipeline.DeriveRecord(pipeline.Bytes(data), rawOrPayload(v, b), v), nil
+}
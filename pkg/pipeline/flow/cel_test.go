@@ -0,0 +1,96 @@
+package flow_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestCELFilter_Transform(t *testing.T) {
+	var dead []string
+	f, err := flow.NewCELFilter(`record.severity >= 5 && payload.contains("denied")`, func(s string, _ error) {
+		dead = append(dead, s)
+	})
+	assert.NoError(t, err)
+
+	in := make(chan string, 4)
+	in <- `{"severity": 7, "msg": "access denied"}`
+	in <- `{"severity": 2, "msg": "access denied"}`
+	in <- `{"severity": 9, "msg": "login"}`
+	in <- `{"msg": "denied, no severity"}`
+	close(in)
+
+	eventC := make(chan pipeline.Event, 1)
+	var got []string
+	for s := range f.Transform(in, eventC) {
+		got = append(got, s)
+	}
+	assert.Equal(t, []string{`{"severity": 7, "msg": "access denied"}`}, got)
+	assert.Equal(t, []string{`{"msg": "denied, no severity"}`}, dead)
+	assert.Len(t, eventC, 1)
+}
+
+func TestCELTransform_Transform(t *testing.T) {
+	tests := []struct {
+		name string
+		conf flow.CELTransformConfig
+		in   string
+		want string
+	}{
+		{
+			name: "new record",
+			conf: flow.CELTransformConfig{Expression: `{"src": record.src_ip, "action": record.blocked ? "deny" : "allow", "n": record.n + 1}`},
+			in:   `{"src_ip": "192.0.2.1", "blocked": true, "n": 41}`,
+			want: `{"src": "192.0.2.1", "action": "deny", "n": 42}`,
+		},
+		{
+			name: "merged fields",
+			conf: flow.CELTransformConfig{Expression: `{"level": record.level.upperAscii(), "debug": null, "tags": ["a", 1.5]}`, Merge: true},
+			in:   `{"level": "warn", "debug": "x", "host": "web1"}`,
+			want: `{"level": "WARN", "host": "web1", "tags": ["a", 1.5]}`,
+		},
+		{
+			name: "string payload",
+			conf: flow.CELTransformConfig{Expression: `"host=" + record.host`},
+			in:   `{"host": "web1"}`,
+			want: `"host=web1"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := flow.NewCELTransform[string](tt.conf, nil)
+			assert.NoError(t, err)
+
+			in := make(chan string, 1)
+			in <- tt.in
+			close(in)
+
+			r := <-c.Transform(in, nil)
+			data, _ := r.Data().Read()
+			raw, _ := r.Raw().Read()
+			if tt.want[0] == '"' {
+				assert.Equal(t, tt.want[1:len(tt.want)-1], string(data))
+			} else {
+				assert.JSONEq(t, tt.want, string(data))
+			}
+			assert.Equal(t, tt.in, string(raw))
+		})
+	}
+}
+
+func TestNewCEL_Validation(t *testing.T) {
+	_, err := flow.NewCELFilter[string](`record.`, nil)
+	assert.Error(t, err)
+
+	_, err = flow.NewCELFilter[string](`"not a bool"`, nil)
+	assert.Error(t, err)
+
+	_, err = flow.NewCELTransform[string](flow.CELTransformConfig{Expression: `1 + 2`}, nil)
+	assert.Error(t, err)
+
+	_, err = flow.NewCELTransform[string](flow.CELTransformConfig{Expression: `"x"`, Merge: true}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package flow
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Chunker implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.Readable] = (*Chunker[any])(nil)
+
+// ErrRecordTooLong is reported for records longer than the maximum record length, which are dropped.
+var ErrRecordTooLong = errors.New("record exceeds maximum length")
+
+// ChunkerConfig is the configuration of a record chunker.
+type ChunkerConfig[I any] struct {
+	Delimiter []byte // separator of records, defaults to a newline
+	MaxLength int    // length of a record at most, longer records are dropped, defaults to 1MiB
+
+	// TrimCR removes a carriage return at the end of records, for CRLF separated input with the newline delimiter.
+	TrimCR bool
+	// KeepEmpty emits empty records between consecutive delimiters, which are skipped by default.
+	KeepEmpty bool
+
+	// Last reports whether an item is the last chunk of an object, such as an HTTP body or a stored file,
+	// which ends its final record even without a trailing delimiter. When nil, the items are chunks of
+	// a single stream whose final record ends when the input channel is closed.
+	Last func(in I) bool
+}
+
+// Chunker is a struct that represents splitting of byte payloads on a data stream into records.
+type Chunker[I any] struct {
+	conf ChunkerConfig[I]
+}
+
+// NewChunker creates a new Chunker flow with the given configuration.
+func NewChunker[I any](conf ChunkerConfig[I]) *Chunker[I] {
+	if len(conf.Delimiter) == 0 {
+		conf.Delimiter = []byte("\n")
+	}
+
+	if conf.MaxLength <= 0 {
+		conf.MaxLength = 1 << 20
+	}
+
+	return &Chunker[I]{
+		conf: conf,
+	}
+}
+
+// Transform splits the payloads from the input channel into records by the delimiter and returns the
+// output channel of records. A record spanning chunks is held until its delimiter arrives, and the rest
+// of a record is emitted when its object or the input ends. Records longer than the maximum length are
+// dropped with an error event holding their start, and at most the maximum length of a record is held.
+func (c Chunker[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.Readable {
+	out := make(chan pipeline.Readable)
+
+	go func() {
+		defer close(out)
+
+		var partial []byte
+		var skipping bool // dropping the rest of a record that is too long
+
+		// emit sends a complete record
+		emit := func(record []byte) {
+			if c.conf.TrimCR {
+				record = bytes.TrimSuffix(record, []byte("\r"))
+			}
+			if len(record) == 0 && !c.conf.KeepEmpty {
+				return
+			}
+			out <- pipeline.Bytes(bytes.Clone(record))
+		}
+
+		// tooLong reports a record that is too long by its start
+		tooLong := func(start []byte) {
+			pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("chunker: record too long", ErrRecordTooLong,
+				false, pipeline.Bytes(bytes.Clone(start[:c.conf.MaxLength]))))
+		}
+
+		for v := range in {
+			b, err := payload(v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("chunker: could not read item", err, false, v))
+				continue
+			}
+
+			// continue the held record, searching from where a delimiter split across chunks may start
+			var from int
+			if len(partial) > 0 {
+				from = max(0, len(partial)-len(c.conf.Delimiter)+1)
+				b = append(partial, b...)
+			}
+
+			for {
+				i := bytes.Index(b[from:], c.conf.Delimiter)
+				if i < 0 {
+					break
+				}
+				record := b[:from+i]
+				b, from = b[from+i+len(c.conf.Delimiter):], 0
+
+				switch {
+				case skipping:
+					skipping = false
+				case len(record) > c.conf.MaxLength:
+					tooLong(record)
+				default:
+					emit(record)
+				}
+			}
+
+			partial = append(partial[:0], b...)
+			if !skipping && len(partial) > c.conf.MaxLength {
+				tooLong(partial)
+				skipping = true
+			}
+			if skipping {
+				// only the end that may hold the start of the delimiter is kept
+				partial = partial[len(partial)-min(len(partial), len(c.conf.Delimiter)-1):]
+			}
+
+			if c.conf.Last != nil && c.conf.Last(v) {
+				if !skipping && len(partial) > 0 {
+					emit(partial)
+				}
+				partial, skipping = partial[:0], false
+			}
+		}
+
+		if !skipping && len(partial) > 0 {
+			emit(partial)
+		}
+	}()
+	return out
+}
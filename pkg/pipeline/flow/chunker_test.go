@@ -0,0 +1,83 @@
+package flow_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// chunk runs the chunks through a chunker and returns the records
+func chunk(c *flow.Chunker[string], chunks []string, eventC chan<- pipeline.Event) []string {
+	in := make(chan string, len(chunks))
+	for _, s := range chunks {
+		in <- s
+	}
+	close(in)
+
+	var records []string
+	for r := range c.Transform(in, eventC) {
+		b, _ := r.Read()
+		records = append(records, string(b))
+	}
+	return records
+}
+
+func TestChunker_Transform(t *testing.T) {
+	tests := []struct {
+		name   string
+		conf   flow.ChunkerConfig[string]
+		chunks []string
+		want   []string
+	}{
+		{
+			name:   "splits lines across chunks",
+			chunks: []string{"a\nb", "c\n\nd", "e"},
+			want:   []string{"a", "bc", "de"},
+		},
+		{
+			name:   "keeps empty records and trims carriage returns",
+			conf:   flow.ChunkerConfig[string]{TrimCR: true, KeepEmpty: true},
+			chunks: []string{"a\r\n\r", "\nb\r\n"},
+			want:   []string{"a", "", "b"},
+		},
+		{
+			name:   "finds a delimiter split across chunks",
+			conf:   flow.ChunkerConfig[string]{Delimiter: []byte("||")},
+			chunks: []string{"a|", "|b|c|", "|", "d"},
+			want:   []string{"a", "b|c", "d"},
+		},
+		{
+			name:   "ends records with their object",
+			conf:   flow.ChunkerConfig[string]{Last: func(s string) bool { return s[len(s)-1] == '.' }},
+			chunks: []string{"a\nb.", "c\nd", "e."},
+			want:   []string{"a", "b.", "c", "de."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, chunk(flow.NewChunker(tt.conf), tt.chunks, nil))
+		})
+	}
+}
+
+func TestChunker_MaxLength(t *testing.T) {
+	c := flow.NewChunker(flow.ChunkerConfig[string]{MaxLength: 4})
+	eventC := make(chan pipeline.Event, 4)
+
+	records := chunk(c, []string{"abc\nlonger\nab", "cdef", "gh\nxyz"}, eventC)
+	close(eventC)
+	assert.Equal(t, []string{"abc", "xyz"}, records)
+
+	var starts []string
+	for e := range eventC {
+		ee := e.(pipeline.ErrorEvent)
+		assert.True(t, errors.Is(ee, flow.ErrRecordTooLong))
+		b, _ := ee.Record().(pipeline.Readable).Read()
+		starts = append(starts, string(b))
+	}
+	assert.Equal(t, []string{"long", "abcd"}, starts)
+}
@@ -0,0 +1,142 @@
+package flow
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Compress implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*Compress[any])(nil)
+
+// Compression codecs
+const (
+	CompressionGzip   = "gzip"
+	CompressionZstd   = "zstd"
+	CompressionSnappy = "snappy" // snappy framing format, which unlike the block format can be detected
+)
+
+// CompressConfig is the configuration of a compression flow.
+type CompressConfig struct {
+	Codec string // one of the Compression constants, required
+
+	// Level is the compression level of the codec: 1 to 9 or -2 for Huffman only with gzip, 1 to 22
+	// with zstd, which is mapped to the closest supported level. Snappy has no levels.
+	// Defaults to the default level of the codec.
+	Level int
+}
+
+// compressWriter is a compressing writer that can be reused
+type compressWriter interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// Compress is a struct that represents compression of payloads on a data stream.
+type Compress[I any] struct {
+	codec   string
+	writers *sync.Pool // of compressWriter
+}
+
+// NewCompress creates a new Compress flow with the given configuration.
+func NewCompress[I any](conf CompressConfig) (*Compress[I], error) {
+	var newWriter func() (compressWriter, error)
+	switch conf.Codec {
+	case CompressionGzip:
+		if conf.Level == 0 {
+			conf.Level = gzip.DefaultCompression
+		}
+		newWriter = func() (compressWriter, error) {
+			return gzip.NewWriterLevel(nil, conf.Level)
+		}
+	case CompressionZstd:
+		level := zstd.SpeedDefault
+		if conf.Level < 0 || conf.Level > 22 {
+			return nil, fmt.Errorf("invalid zstd level %d", conf.Level)
+		}
+		if conf.Level > 0 {
+			level = zstd.EncoderLevelFromZstd(conf.Level)
+		}
+		newWriter = func() (compressWriter, error) {
+			return zstd.NewWriter(nil, zstd.WithEncoderLevel(level), zstd.WithEncoderConcurrency(1))
+		}
+	case CompressionSnappy:
+		if conf.Level != 0 {
+			return nil, fmt.Errorf("invalid snappy level %d", conf.Level)
+		}
+		newWriter = func() (compressWriter, error) {
+			return snappy.NewBufferedWriter(nil), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported compression codec %q", conf.Codec)
+	}
+
+	// the first writer validates the level
+	w, err := newWriter()
+	if err != nil {
+		return nil, err
+	}
+	writers := &sync.Pool{New: func() any {
+		w, _ := newWriter()
+		return w
+	}}
+	writers.Put(w)
+
+	return &Compress[I]{
+		codec:   conf.Codec,
+		writers: writers,
+	}, nil
+}
+
+// Transform compresses the payloads from the input channel and returns the output channel of records
+// holding the compressed payload. The payload is the data of a DataReadable, the raw message of a
+// RawReadable, the bytes of a Readable, []byte or string, and the raw message of a RawReadable or the
+// payload itself is retained as the raw message. Writers are reused across payloads, and the buffers of
+// compressed payloads once their records are released.
+// Items whose payload cannot be read are dropped and reported with an error event carrying them as the record.
+func (c Compress[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		for v := range in {
+			b, err := payload(v)
+			var data *pipeline.PooledBytes
+			if err == nil {
+				data, err = c.compress(b)
+			}
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent(c.codec+" compression error", err, false, v))
+				continue
+			}
+			out <- pipeline.DeriveRecord(data, rawOrPayload(v, b), v)
+		}
+	}()
+	return out
+}
+
+// compress returns the compressed payload in a pooled buffer
+func (c Compress[I]) compress(b []byte) (*pipeline.PooledBytes, error) {
+	w := c.writers.Get().(compressWriter)
+	defer func() {
+		// release the buffer before the writer is pooled
+		w.Reset(io.Discard)
+		c.writers.Put(w)
+	}()
+
+	buf := pipeline.DefaultBufferPool.Get()
+	w.Reset(buf)
+	if _, err := w.Write(b); err != nil {
+		pipeline.DefaultBufferPool.Put(buf)
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		pipeline.DefaultBufferPool.Put(buf)
+		return nil, err
+	}
+	return pipeline.DefaultBufferPool.Bytes(buf), nil
+}
@@ -0,0 +1,71 @@
+package flow_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestCompress_Transform(t *testing.T) {
+	readers := map[string]func(r io.Reader) (io.Reader, error){
+		flow.CompressionGzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		flow.CompressionZstd: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+		flow.CompressionSnappy: func(r io.Reader) (io.Reader, error) {
+			return snappy.NewReader(r), nil
+		},
+	}
+
+	payloads := []string{strings.Repeat("compressible ", 100), "", "short"}
+	for _, conf := range []flow.CompressConfig{
+		{Codec: flow.CompressionGzip},
+		{Codec: flow.CompressionGzip, Level: gzip.BestSpeed},
+		{Codec: flow.CompressionZstd},
+		{Codec: flow.CompressionZstd, Level: 19},
+		{Codec: flow.CompressionSnappy},
+	} {
+		c, err := flow.NewCompress[pipeline.DataRawReadable](conf)
+		assert.NoError(t, err)
+
+		in := make(chan pipeline.DataRawReadable, len(payloads))
+		for _, p := range payloads {
+			in <- pipeline.NewRecord(pipeline.Bytes(p), pipeline.Bytes("raw "+p))
+		}
+		close(in)
+
+		var i int
+		for r := range c.Transform(in, nil) {
+			data, _ := r.Data().Read()
+			decompressed, err := readers[conf.Codec](bytes.NewReader(data))
+			assert.NoError(t, err, conf)
+			b, err := io.ReadAll(decompressed)
+			assert.NoError(t, err, conf)
+			assert.Equal(t, payloads[i], string(b), conf)
+
+			raw, _ := r.Raw().Read()
+			assert.Equal(t, "raw "+payloads[i], string(raw), conf)
+			i++
+		}
+		assert.Equal(t, len(payloads), i, conf)
+	}
+}
+
+func TestNewCompress_Validation(t *testing.T) {
+	for _, conf := range []flow.CompressConfig{
+		{},
+		{Codec: "lz4"},
+		{Codec: flow.CompressionGzip, Level: 10},
+		{Codec: flow.CompressionZstd, Level: 23},
+		{Codec: flow.CompressionSnappy, Level: 1},
+	} {
+		_, err := flow.NewCompress[[]byte](conf)
+		assert.Error(t, err, conf)
+	}
+}
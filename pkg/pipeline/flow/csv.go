@@ -0,0 +1,159 @@
+package flow
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that CSVParser implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*CSVParser[any])(nil)
+
+// ErrMalformedCSV is the error of a row that does not match the columns or their types.
+var ErrMalformedCSV = errors.New("malformed csv row")
+
+// CSVType is the type a CSV column is coerced to.
+type CSVType int
+
+const (
+	CSVString CSVType = iota // kept as text
+	CSVInt                   // parsed as a base 10 integer
+	CSVFloat                 // parsed as a floating point number
+	CSVBool                  // parsed with strconv.ParseBool
+	CSVTime                  // parsed with the time layout
+)
+
+// CSVConfig is the configuration of a CSV parser.
+type CSVConfig struct {
+	Delimiter  rune               // field delimiter, defaults to ',', use '\t' for TSV
+	Columns    []string           // column names, read from the first row when empty
+	Types      map[string]CSVType // types of the columns, columns not listed are strings
+	TimeLayout string             // layout of CSVTime columns, defaults to time.RFC3339
+	LazyQuotes bool               // allow quotes within unquoted fields
+}
+
+// CSVParser is a struct that represents parsing of delimited rows on a data stream into records.
+type CSVParser[I any] struct {
+	conf       CSVConfig
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewCSVParser creates a new CSVParser flow with the given configuration.
+// Each item holds one row, and empty values are omitted from the record rather than coerced.
+// Rows that do not match the columns or their types are passed to deadLetter when it is not nil.
+func NewCSVParser[I any](conf CSVConfig, deadLetter DeadLetterFunc[I]) (*CSVParser[I], error) {
+	if conf.Delimiter == 0 {
+		conf.Delimiter = ','
+	}
+
+	if conf.Delimiter == '"' || conf.Delimiter == '\r' || conf.Delimiter == '\n' {
+		return nil, fmt.Errorf("invalid delimiter %q", conf.Delimiter)
+	}
+
+	if conf.TimeLayout == "" {
+		conf.TimeLayout = time.RFC3339
+	}
+
+	return &CSVParser[I]{
+		conf:       conf,
+		deadLetter: deadLetter,
+	}, nil
+}
+
+// Transform parses the rows from the input channel and returns the output channel of records
+// holding a JSON object of the columns, with the row retained as the raw message.
+// When the columns are read from the header, the header row is consumed and not emitted.
+// Rows that fail to parse are dropped and reported with an error event carrying them as the record.
+func (c CSVParser[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		columns := c.conf.Columns
+		for v := range in {
+			raw, fields, err := c.read(v)
+			if err == nil && len(columns) == 0 {
+				columns = fields
+				continue
+			}
+
+			var data []byte
+			if err == nil {
+				data, err = c.encode(columns, fields)
+			}
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("csv parse error", err, false, v))
+				if c.deadLetter != nil {
+					c.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- pipeline.DeriveRecord(pipeline.Bytes(data), raw, v)
+		}
+	}()
+	return out
+}
+
+// read returns the raw message of the item and the fields of its row
+func (c CSVParser[I]) read(v I) (pipeline.Readable, []string, error) {
+	raw, err := rawOf(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p, err := raw.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	r := csv.NewReader(bytes.NewReader(p))
+	r.Comma = c.conf.Delimiter
+	r.LazyQuotes = c.conf.LazyQuotes
+	r.FieldsPerRecord = -1
+	fields, err := r.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrMalformedCSV, err)
+	}
+	return raw, fields, nil
+}
+
+// encode returns the JSON object of the fields named by the columns
+func (c CSVParser[I]) encode(columns, fields []string) ([]byte, error) {
+	if len(fields) != len(columns) {
+		return nil, fmt.Errorf("%w: expected %d fields, got %d", ErrMalformedCSV, len(columns), len(fields))
+	}
+
+	row := make(map[string]any, len(columns))
+	for i, name := range columns {
+		if fields[i] == "" {
+			continue
+		}
+		value, err := c.coerce(c.conf.Types[name], fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w: column %s: %w", ErrMalformedCSV, name, err)
+		}
+		row[name] = value
+	}
+	return json.Marshal(row)
+}
+
+// coerce converts a field to the type of its column
+func (c CSVParser[I]) coerce(t CSVType, field string) (any, error) {
+	switch t {
+	case CSVInt:
+		return strconv.ParseInt(field, 10, 64)
+	case CSVFloat:
+		return strconv.ParseFloat(field, 64)
+	case CSVBool:
+		return strconv.ParseBool(field)
+	case CSVTime:
+		return time.Parse(c.conf.TimeLayout, field)
+	default:
+		return field, nil
+	}
+}
@@ -0,0 +1,93 @@
+package flow_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// parseRows sends the rows through the parser and returns the decoded records and dead letters
+func parseRows(t *testing.T, conf flow.CSVConfig, rows ...string) ([]map[string]any, []string) {
+	t.Helper()
+
+	var letters []string
+	parser, err := flow.NewCSVParser(conf, func(row string, _ error) {
+		letters = append(letters, row)
+	})
+	assert.NoError(t, err)
+
+	in := make(chan string, len(rows))
+	for _, row := range rows {
+		in <- row
+	}
+	close(in)
+
+	var records []map[string]any
+	for r := range parser.Transform(in, nil) {
+		data, err := r.Data().Read()
+		assert.NoError(t, err)
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal(data, &record))
+		records = append(records, record)
+	}
+	return records, letters
+}
+
+func TestCSVParser_Transform(t *testing.T) {
+	t.Run("reads the columns from the header and coerces types", func(t *testing.T) {
+		records, letters := parseRows(t, flow.CSVConfig{
+			Types: map[string]flow.CSVType{
+				"port":    flow.CSVInt,
+				"score":   flow.CSVFloat,
+				"blocked": flow.CSVBool,
+				"seen":    flow.CSVTime,
+			},
+		},
+			"host,port,score,blocked,seen,note",
+			`db1,5432,0.5,true,2026-01-02T03:04:05Z,"quoted, with comma"`,
+			"web1,not a port,1,false,2026-01-02T03:04:05Z,",
+			"short,1",
+			"web2,443,,,,",
+		)
+
+		assert.Equal(t, []map[string]any{
+			{"host": "db1", "port": float64(5432), "score": 0.5, "blocked": true, "seen": "2026-01-02T03:04:05Z", "note": "quoted, with comma"},
+			{"host": "web2", "port": float64(443)},
+		}, records)
+		assert.Equal(t, []string{"web1,not a port,1,false,2026-01-02T03:04:05Z,", "short,1"}, letters)
+	})
+
+	t.Run("parses tab separated rows against configured columns", func(t *testing.T) {
+		records, letters := parseRows(t, flow.CSVConfig{Delimiter: '\t', Columns: []string{"user", "action"}},
+			"alice\tlogin",
+			"bob\tlogout\n",
+		)
+
+		assert.Equal(t, []map[string]any{{"user": "alice", "action": "login"}, {"user": "bob", "action": "logout"}}, records)
+		assert.Empty(t, letters)
+	})
+
+	t.Run("retains the row as the raw message", func(t *testing.T) {
+		parser, err := flow.NewCSVParser[[]byte](flow.CSVConfig{Columns: []string{"a"}}, nil)
+		assert.NoError(t, err)
+
+		in := make(chan []byte, 1)
+		in <- []byte("1")
+		close(in)
+
+		var result []pipeline.DataRawReadable
+		for r := range parser.Transform(in, nil) {
+			result = append(result, r)
+		}
+		raw, _ := result[0].Raw().Read()
+		assert.Equal(t, "1", string(raw))
+	})
+}
+
+func TestNewCSVParser_Validation(t *testing.T) {
+	_, err := flow.NewCSVParser[string](flow.CSVConfig{Delimiter: '"'}, nil)
+	assert.Error(t, err)
+}
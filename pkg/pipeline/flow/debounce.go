@@ -0,0 +1,105 @@
+package flow
+
+import (
+	"errors"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Debounce implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*Debounce[any, string])(nil)
+
+// Debounce is a struct that represents collapsing of bursts of items per key on a data stream.
+type Debounce[I any, K comparable] struct {
+	quiet time.Duration
+	key   KeyFunc[I, K]
+}
+
+// NewDebounce creates a new Debounce flow.
+// Items of a key are held until no item of that key has arrived for the quiet period,
+// and only the last one is emitted.
+func NewDebounce[I any, K comparable](quiet time.Duration, key KeyFunc[I, K]) (*Debounce[I, K], error) {
+	if quiet <= 0 {
+		return nil, errors.New("quiet period must be positive")
+	}
+
+	if key == nil {
+		return nil, errors.New("key func is nil")
+	}
+
+	return &Debounce[I, K]{
+		quiet: quiet,
+		key:   key,
+	}, nil
+}
+
+// pending is the last item of a key waiting for the key to go quiet
+type pending[I any] struct {
+	value    I
+	deadline time.Time
+}
+
+// deadline is the time a key goes quiet unless another item of it arrives
+type deadline[K comparable] struct {
+	key K
+	at  time.Time
+}
+
+// Transform debounces the data from the input channel and returns the output channel.
+// Items are emitted in the order their keys went quiet, and the held items of all keys
+// are emitted when the input channel is closed.
+func (d Debounce[I, K]) Transform(in <-chan I, _ chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+
+	go func() {
+		defer close(out)
+
+		held := make(map[K]pending[I])
+		// the quiet period is fixed, so deadlines are queued in order and superseded ones skipped
+		var queue []deadline[K]
+
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+
+		// emit sends the held items of the keys quiet by now and sets the timer for the next one
+		emit := func(now time.Time) {
+			for len(queue) > 0 && !queue[0].at.After(now) {
+				next := queue[0]
+				queue = queue[1:]
+				if p, ok := held[next.key]; ok && p.deadline.Equal(next.at) {
+					delete(held, next.key)
+					out <- p.value
+				}
+			}
+			if len(queue) > 0 {
+				timer.Reset(time.Until(queue[0].at))
+			}
+		}
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					emit(maxTime)
+					return
+				}
+
+				k := d.key(v)
+				at := time.Now().Add(d.quiet)
+				held[k] = pending[I]{value: v, deadline: at}
+				queue = append(queue, deadline[K]{key: k, at: at})
+				if len(queue) == 1 {
+					timer.Reset(d.quiet)
+				}
+			case <-timer.C:
+				emit(time.Now())
+			}
+		}
+	}()
+	return out
+}
+
+// maxTime is after every deadline
+var maxTime = time.Unix(0, maxInt64)
@@ -0,0 +1,66 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// state is a device state update used in the debounce tests
+type state struct {
+	device string
+	up     bool
+}
+
+func TestDebounce_Transform(t *testing.T) {
+	byDevice := func(s state) string { return s.device }
+
+	t.Run("emits the last item once a key goes quiet", func(t *testing.T) {
+		debounce, err := flow.NewDebounce(20*time.Millisecond, byDevice)
+		assert.NoError(t, err)
+
+		in := make(chan state)
+		out := debounce.Transform(in, nil)
+
+		in <- state{device: "sw1", up: false}
+		in <- state{device: "sw2", up: false}
+		in <- state{device: "sw1", up: true}
+
+		start := time.Now()
+		assert.Equal(t, state{device: "sw2", up: false}, <-out)
+		assert.Equal(t, state{device: "sw1", up: true}, <-out)
+		assert.Less(t, time.Since(start), time.Second)
+
+		close(in)
+		_, ok := <-out
+		assert.False(t, ok)
+	})
+
+	t.Run("emits held items when the input is closed", func(t *testing.T) {
+		debounce, err := flow.NewDebounce(time.Hour, byDevice)
+		assert.NoError(t, err)
+
+		in := make(chan state, 3)
+		in <- state{device: "sw1", up: false}
+		in <- state{device: "sw2", up: true}
+		in <- state{device: "sw1", up: true}
+		close(in)
+
+		var result []state
+		for s := range debounce.Transform(in, nil) {
+			result = append(result, s)
+		}
+
+		assert.Equal(t, []state{{device: "sw2", up: true}, {device: "sw1", up: true}}, result)
+	})
+}
+
+func TestNewDebounce_Validation(t *testing.T) {
+	_, err := flow.NewDebounce(0, func(s string) string { return s })
+	assert.Error(t, err)
+
+	_, err = flow.NewDebounce[string, string](time.Second, nil)
+	assert.Error(t, err)
+}
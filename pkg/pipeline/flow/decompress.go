@@ -0,0 +1,172 @@
+package flow
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Decompress implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*Decompress[any])(nil)
+
+var (
+	// ErrDecompressedTooLarge is the error of a payload that decompresses beyond the size limit.
+	ErrDecompressedTooLarge = errors.New("decompressed payload exceeds the size limit")
+	// ErrUnknownCompression is the error of a payload in no known compression format.
+	ErrUnknownCompression = errors.New("unknown compression format")
+)
+
+// magic numbers of the compression formats
+var (
+	gzipMagic   = []byte{0x1f, 0x8b}
+	zstdMagic   = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	snappyMagic = []byte("\xff\x06\x00\x00sNaPpY")
+)
+
+// DecompressConfig is the configuration of a decompression flow.
+type DecompressConfig[I any] struct {
+	// Codec returns the codec of an item from its metadata, such as a content encoding header,
+	// as one of the Compression constants or "" to detect it from the magic bytes of the payload.
+	// The codec is always detected when Codec is nil.
+	Codec func(I) string
+
+	MaxSize int64 // decompressed size limit in bytes, defaults to 64MiB
+	Strict  bool  // reject payloads in no known format rather than passing them unchanged
+}
+
+// decompressReader is a decompressing reader that can be reused
+type decompressReader interface {
+	io.Reader
+	Reset(r io.Reader) error
+}
+
+// snappyReader adapts the snappy reader, whose Reset cannot fail
+type snappyReader struct {
+	*snappy.Reader
+}
+
+// Reset discards the state of the reader and reads from r.
+func (s snappyReader) Reset(r io.Reader) error {
+	s.Reader.Reset(r)
+	return nil
+}
+
+// Decompress is a struct that represents decompression of payloads on a data stream.
+type Decompress[I any] struct {
+	conf    DecompressConfig[I]
+	readers map[string]*sync.Pool // of decompressReader by codec
+}
+
+// NewDecompress creates a new Decompress flow with the given configuration.
+func NewDecompress[I any](conf DecompressConfig[I]) *Decompress[I] {
+	if conf.MaxSize <= 0 {
+		conf.MaxSize = 64 << 20
+	}
+
+	maxSize := uint64(conf.MaxSize)
+	return &Decompress[I]{
+		conf: conf,
+		readers: map[string]*sync.Pool{
+			CompressionGzip: {New: func() any {
+				return new(gzip.Reader)
+			}},
+			CompressionZstd: {New: func() any {
+				// concurrency 1 decodes synchronously, so pooled decoders hold no goroutines
+				d, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxSize))
+				return d
+			}},
+			CompressionSnappy: {New: func() any {
+				return snappyReader{snappy.NewReader(nil)}
+			}},
+		},
+	}
+}
+
+// Transform decompresses the payloads from the input channel and returns the output channel of records
+// holding the decompressed payload. The payload is the data of a DataReadable, the raw message of a
+// RawReadable, the bytes of a Readable, []byte or string, and the raw message of a RawReadable or the
+// payload itself is retained as the raw message. Readers are reused across payloads, and the buffers of
+// decompressed payloads once their records are released.
+// Items that fail to decompress or exceed the size limit are dropped and reported with an error event
+// carrying them as the record.
+func (d Decompress[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		for v := range in {
+			b, err := payload(v)
+			var data pipeline.Readable
+			if err == nil {
+				data, err = d.decompress(v, b)
+			}
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("decompression error", err, false, v))
+				continue
+			}
+			out <- pipeline.DeriveRecord(data, rawOrPayload(v, b), v)
+		}
+	}()
+	return out
+}
+
+// decompress returns the decompressed payload of the item, in a pooled buffer unless it was not compressed
+func (d Decompress[I]) decompress(v I, b []byte) (pipeline.Readable, error) {
+	var codec string
+	if d.conf.Codec != nil {
+		codec = d.conf.Codec(v)
+	}
+	if codec == "" {
+		codec = sniffCompression(b)
+	}
+	if codec == "" {
+		if d.conf.Strict {
+			return nil, ErrUnknownCompression
+		}
+		return pipeline.Bytes(b), nil
+	}
+
+	pool, ok := d.readers[codec]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCompression, codec)
+	}
+	r := pool.Get().(decompressReader)
+	defer pool.Put(r)
+
+	if err := r.Reset(bytes.NewReader(b)); err != nil {
+		return nil, fmt.Errorf("%s: %w", codec, err)
+	}
+
+	// read one byte past the limit to tell a payload of exactly the limit from a larger one
+	buf := pipeline.DefaultBufferPool.Get()
+	_, err := buf.ReadFrom(io.LimitReader(r, d.conf.MaxSize+1))
+	if errors.Is(err, zstd.ErrDecoderSizeExceeded) || int64(buf.Len()) > d.conf.MaxSize {
+		pipeline.DefaultBufferPool.Put(buf)
+		return nil, ErrDecompressedTooLarge
+	}
+	if err != nil {
+		pipeline.DefaultBufferPool.Put(buf)
+		return nil, fmt.Errorf("%s: %w", codec, err)
+	}
+	return pipeline.DefaultBufferPool.Bytes(buf), nil
+}
+
+// sniffCompression returns the codec of the payload detected from its magic bytes, or "" if there is none
+func sniffCompression(b []byte) string {
+	switch {
+	case bytes.HasPrefix(b, gzipMagic):
+		return CompressionGzip
+	case bytes.HasPrefix(b, zstdMagic):
+		return CompressionZstd
+	case bytes.HasPrefix(b, snappyMagic):
+		return CompressionSnappy
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,121 @@
+package flow_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// compressed returns the payload compressed with the codec
+func compressed(t *testing.T, codec, payload string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	switch codec {
+	case flow.CompressionGzip:
+		w := gzip.NewWriter(&buf)
+		_, _ = w.Write([]byte(payload))
+		assert.NoError(t, w.Close())
+	case flow.CompressionZstd:
+		w, err := zstd.NewWriter(&buf)
+		assert.NoError(t, err)
+		_, _ = w.Write([]byte(payload))
+		assert.NoError(t, w.Close())
+	case flow.CompressionSnappy:
+		w := snappy.NewBufferedWriter(&buf)
+		_, _ = w.Write([]byte(payload))
+		assert.NoError(t, w.Close())
+	}
+	return buf.Bytes()
+}
+
+// decompress sends the items through the flow and returns the decompressed payloads and error events
+func decompress(conf flow.DecompressConfig[[]byte], items ...[]byte) ([]string, []error) {
+	in := make(chan []byte, len(items))
+	for _, item := range items {
+		in <- item
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, len(items))
+	var payloads []string
+	for r := range flow.NewDecompress(conf).Transform(in, eventC) {
+		data, _ := r.Data().Read()
+		payloads = append(payloads, string(data))
+	}
+	close(eventC)
+
+	var errs []error
+	for e := range eventC {
+		errs = append(errs, e.(pipeline.ErrorEvent))
+	}
+	return payloads, errs
+}
+
+func TestDecompress_Transform(t *testing.T) {
+	large := strings.Repeat("a", 1024)
+
+	t.Run("detects the codec and reuses readers", func(t *testing.T) {
+		var items [][]byte
+		var want []string
+		for _, codec := range []string{flow.CompressionGzip, flow.CompressionZstd, flow.CompressionSnappy} {
+			for _, p := range []string{"first " + codec, "second " + codec, large} {
+				items = append(items, compressed(t, codec, p))
+				want = append(want, p)
+			}
+		}
+		items = append(items, []byte("not compressed"))
+		want = append(want, "not compressed")
+
+		payloads, errs := decompress(flow.DecompressConfig[[]byte]{}, items...)
+		assert.Equal(t, want, payloads)
+		assert.Empty(t, errs)
+	})
+
+	t.Run("enforces the size limit", func(t *testing.T) {
+		conf := flow.DecompressConfig[[]byte]{MaxSize: int64(len(large))}
+		payloads, errs := decompress(conf,
+			compressed(t, flow.CompressionGzip, large),
+			compressed(t, flow.CompressionGzip, large+"b"),
+			compressed(t, flow.CompressionZstd, large+large),
+			compressed(t, flow.CompressionSnappy, large+"c"),
+		)
+
+		assert.Equal(t, []string{large}, payloads)
+		assert.Len(t, errs, 3)
+		for _, err := range errs {
+			assert.ErrorIs(t, err, flow.ErrDecompressedTooLarge)
+		}
+	})
+
+	t.Run("uses the codec from metadata and rejects unknown payloads when strict", func(t *testing.T) {
+		conf := flow.DecompressConfig[[]byte]{
+			Codec: func(b []byte) string {
+				if bytes.HasPrefix(b, []byte("plain")) {
+					return flow.CompressionGzip // wrong metadata
+				}
+				return ""
+			},
+			Strict: true,
+		}
+		payloads, errs := decompress(conf,
+			compressed(t, flow.CompressionZstd, "sniffed"),
+			[]byte("plain text"),
+			[]byte("unknown"),
+			compressed(t, flow.CompressionGzip, "x")[:5],
+		)
+
+		assert.Equal(t, []string{"sniffed"}, payloads)
+		assert.Len(t, errs, 3)
+		assert.NotErrorIs(t, errs[0], flow.ErrUnknownCompression)
+		assert.ErrorIs(t, errs[1], flow.ErrUnknownCompression)
+		assert.NotErrorIs(t, errs[2], flow.ErrUnknownCompression)
+	})
+}
@@ -0,0 +1,97 @@
+package flow
+
+import (
+	"errors"
+	"hash/fnv"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Dedup implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*Dedup[any])(nil)
+
+// MetricDedupDropped is the counter of items dropped as duplicates.
+const MetricDedupDropped = "krapht_dedup_dropped_total"
+
+// DedupConfig is the configuration of a deduplication flow.
+type DedupConfig[I any] struct {
+	// Key returns the identity of an item. When nil, items are identified by a hash of their
+	// content: the data of a DataReadable, the raw message of a RawReadable, the bytes of a Readable,
+	// []byte or string, or the formatted value otherwise.
+	Key func(in I) string
+
+	TTL     time.Duration // how long a key is remembered after it was first passed, required
+	MaxKeys int           // bound on the keys remembered, defaults to 100000
+}
+
+// Dedup is a struct that represents dropping of repeated items on a data stream.
+type Dedup[I any] struct {
+	conf DedupConfig[I]
+}
+
+// NewDedup creates a new Dedup flow with the given configuration.
+// When more than MaxKeys keys are seen within the TTL, the least recently seen keys are
+// forgotten first, so their repeats may pass.
+func NewDedup[I any](conf DedupConfig[I]) (*Dedup[I], error) {
+	if conf.TTL <= 0 {
+		return nil, errors.New("ttl must be positive")
+	}
+
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = 100000
+	}
+
+	return &Dedup[I]{
+		conf: conf,
+	}, nil
+}
+
+// Transform passes the first item of each key within the TTL from the input channel to the
+// output channel and drops the rest, counting them with a metric event.
+func (d Dedup[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+
+	go func() {
+		defer close(out)
+
+		seen := newLRU[string, time.Time](d.conf.MaxKeys)
+		var dropped float64
+
+		for v := range in {
+			key, err := d.key(v)
+			if err != nil {
+				// items that cannot be identified are passed rather than lost
+				pipeline.SendEvent(eventC, pipeline.NewErrorEvent("dedup: could not read item", err, true))
+				out <- v
+				continue
+			}
+
+			now := time.Now()
+			if first, ok := seen.get(key); ok && now.Sub(first) < d.conf.TTL {
+				dropped++
+				pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricDedupDropped, dropped, nil, pipeline.MetricTypeCounter))
+				continue
+			}
+			seen.put(key, now)
+			out <- v
+		}
+	}()
+	return out
+}
+
+// key returns the key of the item from the key func or a hash of its content
+func (d Dedup[I]) key(v I) (string, error) {
+	if d.conf.Key != nil {
+		return d.conf.Key(v), nil
+	}
+
+	p, err := payload(v)
+	if err != nil {
+		return "", err
+	}
+
+	h := fnv.New128a()
+	_, _ = h.Write(p)
+	return string(h.Sum(nil)), nil
+}
@@ -0,0 +1,101 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestDedup_Transform(t *testing.T) {
+	t.Run("drops repeated content and counts them", func(t *testing.T) {
+		dedup, err := flow.NewDedup(flow.DedupConfig[[]byte]{TTL: time.Hour})
+		assert.NoError(t, err)
+
+		in := make(chan []byte, 4)
+		in <- []byte("a")
+		in <- []byte("b")
+		in <- []byte("a")
+		in <- []byte("a")
+		close(in)
+
+		eventC := make(chan pipeline.Event, 2)
+		var result []string
+		for v := range dedup.Transform(in, eventC) {
+			result = append(result, string(v))
+		}
+
+		assert.Equal(t, []string{"a", "b"}, result)
+		assert.Len(t, eventC, 2)
+		<-eventC
+		metric, ok := (<-eventC).(pipeline.MetricEvent)
+		assert.True(t, ok)
+		assert.Equal(t, flow.MetricDedupDropped, metric.Name())
+		assert.Equal(t, float64(2), metric.Value())
+	})
+
+	t.Run("identifies items by the key func", func(t *testing.T) {
+		dedup, err := flow.NewDedup(flow.DedupConfig[reading]{
+			Key: func(r reading) string { return r.host },
+			TTL: time.Hour,
+		})
+		assert.NoError(t, err)
+
+		in := make(chan reading, 3)
+		in <- reading{host: "a", value: 1}
+		in <- reading{host: "a", value: 2}
+		in <- reading{host: "b", value: 3}
+		close(in)
+
+		var result []int
+		for r := range dedup.Transform(in, nil) {
+			result = append(result, r.value)
+		}
+
+		assert.Equal(t, []int{1, 3}, result)
+	})
+
+	t.Run("passes repeats after the ttl", func(t *testing.T) {
+		dedup, err := flow.NewDedup(flow.DedupConfig[string]{TTL: 10 * time.Millisecond})
+		assert.NoError(t, err)
+
+		in := make(chan string)
+		out := dedup.Transform(in, nil)
+
+		in <- "a"
+		assert.Equal(t, "a", <-out)
+		time.Sleep(20 * time.Millisecond)
+		in <- "a"
+		assert.Equal(t, "a", <-out)
+
+		close(in)
+		_, ok := <-out
+		assert.False(t, ok)
+	})
+
+	t.Run("forgets the least recently seen keys beyond the bound", func(t *testing.T) {
+		dedup, err := flow.NewDedup(flow.DedupConfig[string]{TTL: time.Hour, MaxKeys: 2})
+		assert.NoError(t, err)
+
+		in := make(chan string, 5)
+		for _, v := range []string{"a", "b", "a", "c", "b"} {
+			in <- v
+		}
+		close(in)
+
+		var result []string
+		for v := range dedup.Transform(in, nil) {
+			result = append(result, v)
+		}
+
+		// the repeat of a keeps it recent, so b is forgotten when c arrives
+		assert.Equal(t, []string{"a", "b", "c", "b"}, result)
+	})
+}
+
+func TestNewDedup_Validation(t *testing.T) {
+	_, err := flow.NewDedup(flow.DedupConfig[string]{})
+	assert.Error(t, err)
+}
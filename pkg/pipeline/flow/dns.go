@@ -0,0 +1,69 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+)
+
+// DNSResolver resolves addresses to names and names to addresses. *net.Resolver implements it.
+type DNSResolver interface {
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DNSLookup returns a lookup function for an Enrich flow resolving IP addresses to host names
+// and host names to addresses. Names are returned without the trailing dot and names or addresses
+// that do not exist are reported as ErrLookupNotFound. If resolver is nil, net.DefaultResolver is used.
+func DNSLookup(resolver DNSResolver) func(ctx context.Context, key string) ([]string, error) {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return func(ctx context.Context, key string) ([]string, error) {
+		var results []string
+		var err error
+		if net.ParseIP(key) != nil {
+			results, err = resolver.LookupAddr(ctx, key)
+		} else {
+			results, err = resolver.LookupHost(ctx, key)
+		}
+
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, ErrLookupNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(results) == 0 {
+			return nil, ErrLookupNotFound
+		}
+
+		names := make([]string, len(results))
+		for i, r := range results {
+			names[i] = strings.TrimSuffix(r, ".")
+		}
+		return names, nil
+	}
+}
+
+// NewDNS creates a new Enrich flow resolving the IP addresses or host names returned by the key
+// function of the configuration with the resolver, and merging the results into the items.
+// Items whose resolution fails are passed unchanged. Unless set, lookups time out after 2s and
+// 8 items are resolved concurrently. If resolver is nil, net.DefaultResolver is used.
+func NewDNS[I any](conf EnrichConfig[I, []string], resolver DNSResolver) (*Enrich[I, []string], error) {
+	conf.Lookup = DNSLookup(resolver)
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 2 * time.Second
+	}
+
+	if conf.Workers <= 0 {
+		conf.Workers = 8
+	}
+
+	return NewEnrich(conf)
+}
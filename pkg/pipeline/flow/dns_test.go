@@ -0,0 +1,91 @@
+package flow_test
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// fakeResolver resolves from fixed tables
+type fakeResolver struct {
+	addrs   map[string][]string
+	hosts   map[string][]string
+	lookups atomic.Int32
+}
+
+func (r *fakeResolver) LookupAddr(_ context.Context, addr string) ([]string, error) {
+	r.lookups.Add(1)
+	if names, ok := r.addrs[addr]; ok {
+		return names, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: addr, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	r.lookups.Add(1)
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	if host == "timeout.example" {
+		return nil, &net.DNSError{Err: "i/o timeout", Name: host, IsTimeout: true}
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// connection is a record with a peer resolved by the dns tests
+type connection struct {
+	peer     string
+	resolved []string
+}
+
+func TestDNS_Transform(t *testing.T) {
+	resolver := &fakeResolver{
+		addrs: map[string][]string{"10.0.0.1": {"db1.example."}},
+		hosts: map[string][]string{"web1.example": {"10.0.0.2"}},
+	}
+
+	dns, err := flow.NewDNS(flow.EnrichConfig[connection, []string]{
+		Key: func(c connection) (string, bool) { return c.peer, true },
+		Merge: func(c connection, names []string) connection {
+			c.resolved = names
+			return c
+		},
+		Ordered: true,
+	}, resolver)
+	assert.NoError(t, err)
+
+	in := make(chan connection, 5)
+	for _, peer := range []string{"10.0.0.1", "web1.example", "10.0.0.9", "timeout.example", "10.0.0.1"} {
+		in <- connection{peer: peer}
+	}
+	close(in)
+
+	var result []connection
+	for c := range dns.Transform(in, nil) {
+		result = append(result, c)
+	}
+
+	assert.Equal(t, []connection{
+		{peer: "10.0.0.1", resolved: []string{"db1.example"}},
+		{peer: "web1.example", resolved: []string{"10.0.0.2"}},
+		{peer: "10.0.0.9"},
+		{peer: "timeout.example"},
+		{peer: "10.0.0.1", resolved: []string{"db1.example"}},
+	}, result)
+	assert.LessOrEqual(t, resolver.lookups.Load(), int32(5))
+}
+
+func TestDNSLookup(t *testing.T) {
+	lookup := flow.DNSLookup(&fakeResolver{})
+
+	_, err := lookup(context.Background(), "192.0.2.1")
+	assert.ErrorIs(t, err, flow.ErrLookupNotFound)
+
+	_, err = lookup(context.Background(), "timeout.example")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, flow.ErrLookupNotFound)
+}
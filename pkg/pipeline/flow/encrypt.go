@@ -0,0 +1,238 @@
+package flow
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Encrypt and Decrypt implement the Flow interface.
+var (
+	_ pipeline.Flow[any, pipeline.DataRawReadable] = (*Encrypt[any])(nil)
+	_ pipeline.Flow[any, pipeline.DataRawReadable] = (*Decrypt[any])(nil)
+)
+
+var (
+	// ErrDecrypt is the error of a payload that is not an envelope or fails authentication.
+	ErrDecrypt = errors.New("decryption failed")
+	// ErrUnknownKey is the error of an envelope sealed with a key that is not in the keyring.
+	ErrUnknownKey = errors.New("unknown encryption key")
+)
+
+// envelopeVersion is the version of the envelope framing
+const envelopeVersion = 1
+
+// dataKeySize is the size of the AES-256 data keys sealing the payloads
+const dataKeySize = 32
+
+// KeyringConfig is the configuration of the encryption and decryption flows.
+//
+// Each payload is sealed with a random AES-256-GCM data key, which is itself sealed with the key
+// encryption key named by KeyID and stored in the envelope:
+//
+//	version (1) | key ID length (1) | key ID | data key nonce (12) | sealed data key (48) | nonce (12) | sealed payload
+//
+// The version and key ID are authenticated with both. To rotate keys, add the new key, switch KeyID
+// to it and keep the old key for as long as envelopes sealed with it must be decrypted.
+type KeyringConfig struct {
+	Keys  map[string][]byte // AES key encryption keys of 16, 24 or 32 bytes by key ID, required
+	KeyID string            // ID of the key that encrypts, up to 255 bytes, required to encrypt
+}
+
+// keyring holds the ciphers of the key encryption keys by key ID
+type keyring map[string]cipher.AEAD
+
+// newKeyring creates the ciphers of the keys
+func newKeyring(keys map[string][]byte) (keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption keys")
+	}
+
+	ring := make(keyring, len(keys))
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > 255 {
+			return nil, fmt.Errorf("key ID %q must be 1 to 255 bytes", id)
+		}
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		ring[id] = aead
+	}
+	return ring, nil
+}
+
+// newGCM returns the AES-GCM cipher of the key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt is a struct that represents envelope encryption of payloads on a data stream.
+type Encrypt[I any] struct {
+	keyID string
+	kek   cipher.AEAD
+}
+
+// NewEncrypt creates a new Encrypt flow sealing payloads with the key named by the KeyID of the configuration.
+func NewEncrypt[I any](conf KeyringConfig) (*Encrypt[I], error) {
+	ring, err := newKeyring(conf.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	kek, ok := ring[conf.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, conf.KeyID)
+	}
+
+	return &Encrypt[I]{
+		keyID: conf.KeyID,
+		kek:   kek,
+	}, nil
+}
+
+// Transform encrypts the payloads from the input channel and returns the output channel of records
+// holding the envelopes. The payload is the data of a DataReadable, the raw message of a RawReadable,
+// the bytes of a Readable, []byte or string, and the raw message of a RawReadable or the payload itself
+// is retained as the raw message.
+// Items that fail to encrypt are dropped and reported with an error event carrying them as the record.
+func (e Encrypt[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		for v := range in {
+			b, err := payload(v)
+			var data []byte
+			if err == nil {
+				data, err = e.seal(b)
+			}
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("encryption error", err, false, v))
+				continue
+			}
+			out <- pipeline.DeriveRecord(pipeline.Bytes(data), rawOrPayload(v, b), v)
+		}
+	}()
+	return out
+}
+
+// seal returns the envelope of the payload
+func (e Encrypt[I]) seal(b []byte) ([]byte, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	dek, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	header := append([]byte{envelopeVersion, byte(len(e.keyID))}, e.keyID...)
+	kekNonce := make([]byte, e.kek.NonceSize())
+	nonce := make([]byte, dek.NonceSize())
+	if _, err := rand.Read(kekNonce); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	size := len(header) + len(kekNonce) + dataKeySize + e.kek.Overhead() + len(nonce) + len(b) + dek.Overhead()
+	envelope := make([]byte, 0, size)
+	envelope = append(envelope, header...)
+	envelope = append(envelope, kekNonce...)
+	envelope = e.kek.Seal(envelope, kekNonce, dataKey, header)
+	envelope = append(envelope, nonce...)
+	return dek.Seal(envelope, nonce, b, header), nil
+}
+
+// Decrypt is a struct that represents decryption of envelopes on a data stream.
+type Decrypt[I any] struct {
+	ring       keyring
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewDecrypt creates a new Decrypt flow opening envelopes sealed with any key of the configuration,
+// whose KeyID is ignored. Envelopes that fail to decrypt are passed to deadLetter when it is not nil.
+func NewDecrypt[I any](conf KeyringConfig, deadLetter DeadLetterFunc[I]) (*Decrypt[I], error) {
+	ring, err := newKeyring(conf.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Decrypt[I]{
+		ring:       ring,
+		deadLetter: deadLetter,
+	}, nil
+}
+
+// Transform decrypts the envelopes from the input channel and returns the output channel of records
+// holding the payloads, with the raw message of a RawReadable or the envelope retained as the raw message.
+// Envelopes that fail to decrypt are dropped and reported with an error event carrying them as the record.
+func (d Decrypt[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		for v := range in {
+			b, err := payload(v)
+			var data []byte
+			if err == nil {
+				data, err = d.open(b)
+			}
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("decryption error", err, false, v))
+				if d.deadLetter != nil {
+					d.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- pipeline.DeriveRecord(pipeline.Bytes(data), rawOrPayload(v, b), v)
+		}
+	}()
+	return out
+}
+
+// open returns the payload of the envelope
+func (d Decrypt[I]) open(b []byte) ([]byte, error) {
+	if len(b) < 2 || b[0] != envelopeVersion || len(b) < 2+int(b[1]) {
+		return nil, fmt.Errorf("%w: invalid envelope header", ErrDecrypt)
+	}
+	header, rest := b[:2+int(b[1])], b[2+int(b[1]):]
+
+	keyID := string(header[2:])
+	kek, ok := d.ring[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+
+	sealedKey := kek.NonceSize() + dataKeySize + kek.Overhead()
+	if len(rest) < sealedKey {
+		return nil, fmt.Errorf("%w: truncated envelope", ErrDecrypt)
+	}
+	dataKey, err := kek.Open(nil, rest[:kek.NonceSize()], rest[kek.NonceSize():sealedKey], header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: data key: %w", ErrDecrypt, err)
+	}
+	rest = rest[sealedKey:]
+
+	dek, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) < dek.NonceSize()+dek.Overhead() {
+		return nil, fmt.Errorf("%w: truncated envelope", ErrDecrypt)
+	}
+	data, err := dek.Open(nil, rest[:dek.NonceSize()], rest[dek.NonceSize():], header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,97 @@
+package flow_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// encryptAll sends the payloads through the flow and returns the envelopes
+func encryptAll(t *testing.T, conf flow.KeyringConfig, payloads ...string) [][]byte {
+	t.Helper()
+
+	e, err := flow.NewEncrypt[string](conf)
+	assert.NoError(t, err)
+
+	in := make(chan string, len(payloads))
+	for _, p := range payloads {
+		in <- p
+	}
+	close(in)
+
+	var envelopes [][]byte
+	for r := range e.Transform(in, nil) {
+		data, _ := r.Data().Read()
+		envelopes = append(envelopes, data)
+	}
+	return envelopes
+}
+
+func TestEncrypt_Decrypt(t *testing.T) {
+	keys := map[string][]byte{
+		"2025": bytes.Repeat([]byte{1}, 32),
+		"2026": bytes.Repeat([]byte{2}, 16),
+	}
+
+	// envelopes sealed before and after rotating to the new key
+	old := encryptAll(t, flow.KeyringConfig{Keys: map[string][]byte{"2025": keys["2025"]}, KeyID: "2025"}, "secret", "")
+	rotated := encryptAll(t, flow.KeyringConfig{Keys: keys, KeyID: "2026"}, "secret")
+	assert.NotContains(t, string(old[0]), "secret")
+	assert.NotEqual(t, old[0], rotated[0])
+
+	tampered := bytes.Clone(rotated[0])
+	tampered[len(tampered)-1] ^= 1
+	relabelled := bytes.Clone(rotated[0])
+	relabelled[5] = '5' // key ID 2026 becomes 2025, failing authentication
+
+	var letters int
+	d, err := flow.NewDecrypt(flow.KeyringConfig{Keys: keys}, func([]byte, error) { letters++ })
+	assert.NoError(t, err)
+
+	in := make(chan []byte, 7)
+	for _, envelope := range [][]byte{old[0], old[1], rotated[0], tampered, relabelled, []byte("plain"), {1, 4, '2', '0', '2', '7'}} {
+		in <- envelope
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, 4)
+	var payloads []string
+	for r := range d.Transform(in, eventC) {
+		data, _ := r.Data().Read()
+		payloads = append(payloads, string(data))
+	}
+	close(eventC)
+
+	assert.Equal(t, []string{"secret", "", "secret"}, payloads)
+	assert.Equal(t, 4, letters)
+
+	var errs []error
+	for e := range eventC {
+		errs = append(errs, e.(pipeline.ErrorEvent))
+	}
+	assert.Len(t, errs, 4)
+	assert.ErrorIs(t, errs[0], flow.ErrDecrypt)
+	assert.ErrorIs(t, errs[1], flow.ErrDecrypt)
+	assert.ErrorIs(t, errs[2], flow.ErrDecrypt)
+	assert.ErrorIs(t, errs[3], flow.ErrUnknownKey)
+}
+
+func TestNewEncrypt_Validation(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	for _, conf := range []flow.KeyringConfig{
+		{},
+		{Keys: map[string][]byte{"a": key}},
+		{Keys: map[string][]byte{"a": key}, KeyID: "b"},
+		{Keys: map[string][]byte{"a": key[:10]}, KeyID: "a"},
+		{Keys: map[string][]byte{"": key}, KeyID: ""},
+	} {
+		_, err := flow.NewEncrypt[[]byte](conf)
+		assert.Error(t, err, conf)
+	}
+
+	_, err := flow.NewDecrypt[[]byte](flow.KeyringConfig{}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,156 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"golang.org/x/sync/singleflight"
+)
+
+// Ensure that Enrich implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*Enrich[any, any])(nil)
+
+// ErrLookupNotFound is returned by a lookup function when the key has no value.
+// Keys not found are cached for the negative TTL and their items passed unchanged.
+var ErrLookupNotFound = errors.New("lookup key not found")
+
+// EnrichConfig is the configuration of an enrichment flow.
+type EnrichConfig[I, V any] struct {
+	// Key returns the lookup key of an item, or false for items that are passed unchanged, required.
+	Key func(in I) (string, bool)
+	// Lookup fetches the value of a key, returning ErrLookupNotFound when there is none, required.
+	Lookup func(ctx context.Context, key string) (V, error)
+	// Merge returns the item enriched with the value of its key, required.
+	Merge func(in I, value V) I
+
+	TTL         time.Duration // how long values are cached, defaults to 5m
+	NegativeTTL time.Duration // how long keys not found are cached, defaults to 1m
+	MaxKeys     int           // bound on the keys cached, the least recently used is evicted, defaults to 10000
+	Timeout     time.Duration // deadline of a lookup, defaults to 5s
+	Workers     int           // items enriched concurrently, defaults to 1
+	Ordered     bool          // preserve the input order when Workers is more than 1
+}
+
+// Enrich is a struct that represents enrichment of a data stream with cached lookups.
+type Enrich[I, V any] struct {
+	conf EnrichConfig[I, V]
+}
+
+// NewEnrich creates a new Enrich flow with the given configuration.
+func NewEnrich[I, V any](conf EnrichConfig[I, V]) (*Enrich[I, V], error) {
+	if conf.Key == nil {
+		return nil, errors.New("key func is nil")
+	}
+
+	if conf.Lookup == nil {
+		return nil, errors.New("lookup func is nil")
+	}
+
+	if conf.Merge == nil {
+		return nil, errors.New("merge func is nil")
+	}
+
+	if conf.TTL <= 0 {
+		conf.TTL = 5 * time.Minute
+	}
+
+	if conf.NegativeTTL <= 0 {
+		conf.NegativeTTL = time.Minute
+	}
+
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = 10000
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 5 * time.Second
+	}
+
+	if conf.Workers <= 0 {
+		conf.Workers = 1
+	}
+
+	return &Enrich[I, V]{
+		conf: conf,
+	}, nil
+}
+
+// lookupEntry is a cached lookup result
+type lookupEntry[V any] struct {
+	value   V
+	found   bool
+	expires time.Time
+}
+
+// lookupCache caches lookup results and de-duplicates concurrent lookups of a key
+type lookupCache[V any] struct {
+	mu      sync.Mutex
+	entries *lru[string, lookupEntry[V]]
+	group   singleflight.Group
+}
+
+// Transform enriches the data from the input channel and returns the output channel.
+// Items whose lookup fails are passed unchanged and reported with an error event.
+// The cache lives as long as the returned channel.
+func (e Enrich[I, V]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan I {
+	cache := &lookupCache[V]{entries: newLRU[string, lookupEntry[V]](e.conf.MaxKeys)}
+
+	enrich := func(v I) (I, error) {
+		key, ok := e.conf.Key(v)
+		if !ok {
+			return v, nil
+		}
+
+		value, found, err := e.lookup(cache, key)
+		if err != nil {
+			pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("enrich lookup error", err, true, v))
+			return v, nil
+		}
+		if !found {
+			return v, nil
+		}
+		return e.conf.Merge(v, value), nil
+	}
+
+	// enrich never fails, so the parallel map passes every item
+	m := ParallelMap[I, I]{transform: enrich, workers: e.conf.Workers, ordered: e.conf.Ordered}
+	return m.Transform(in, eventC)
+}
+
+// lookup returns the value of the key from the cache or the lookup function
+func (e Enrich[I, V]) lookup(cache *lookupCache[V], key string) (V, bool, error) {
+	cache.mu.Lock()
+	entry, ok := cache.entries.get(key)
+	cache.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.value, entry.found, nil
+	}
+
+	res, err, _ := cache.group.Do(key, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), e.conf.Timeout)
+		defer cancel()
+
+		value, err := e.conf.Lookup(ctx, key)
+		entry := lookupEntry[V]{value: value, found: true, expires: time.Now().Add(e.conf.TTL)}
+		if errors.Is(err, ErrLookupNotFound) {
+			entry = lookupEntry[V]{expires: time.Now().Add(e.conf.NegativeTTL)}
+		} else if err != nil {
+			return nil, err
+		}
+
+		cache.mu.Lock()
+		cache.entries.put(key, entry)
+		cache.mu.Unlock()
+		return entry, nil
+	})
+	if err != nil {
+		var zero V
+		return zero, false, err
+	}
+
+	entry = res.(lookupEntry[V])
+	return entry.value, entry.found, nil
+}
@@ -0,0 +1,133 @@
+package flow_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// asset is an item enriched with the owner of its host
+type asset struct {
+	host  string
+	owner string
+}
+
+func TestEnrich_Transform(t *testing.T) {
+	owners := map[string]string{"db1": "dba", "web1": "web"}
+
+	newEnrich := func(t *testing.T, lookups *atomic.Int32, conf flow.EnrichConfig[asset, string]) *flow.Enrich[asset, string] {
+		conf.Key = func(a asset) (string, bool) { return a.host, a.host != "" }
+		conf.Merge = func(a asset, owner string) asset {
+			a.owner = owner
+			return a
+		}
+		if conf.Lookup == nil {
+			conf.Lookup = func(_ context.Context, host string) (string, error) {
+				lookups.Add(1)
+				if host == "broken" {
+					return "", errors.New("unavailable")
+				}
+				owner, ok := owners[host]
+				if !ok {
+					return "", flow.ErrLookupNotFound
+				}
+				return owner, nil
+			}
+		}
+		enrich, err := flow.NewEnrich(conf)
+		assert.NoError(t, err)
+		return enrich
+	}
+
+	t.Run("enriches items and caches lookups", func(t *testing.T) {
+		var lookups atomic.Int32
+		enrich := newEnrich(t, &lookups, flow.EnrichConfig[asset, string]{})
+
+		in := make(chan asset, 7)
+		for _, host := range []string{"db1", "web1", "db1", "unknown", "unknown", "", "broken"} {
+			in <- asset{host: host}
+		}
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		var result []asset
+		for a := range enrich.Transform(in, eventC) {
+			result = append(result, a)
+		}
+
+		assert.Equal(t, []asset{
+			{host: "db1", owner: "dba"},
+			{host: "web1", owner: "web"},
+			{host: "db1", owner: "dba"},
+			{host: "unknown"},
+			{host: "unknown"},
+			{},
+			{host: "broken"},
+		}, result)
+		assert.Equal(t, int32(4), lookups.Load(), "found and not found keys are cached")
+
+		errEvent, ok := (<-eventC).(pipeline.ErrorEvent)
+		assert.True(t, ok)
+		assert.Equal(t, asset{host: "broken"}, errEvent.Record())
+	})
+
+	t.Run("expires cached values after the ttl", func(t *testing.T) {
+		var lookups atomic.Int32
+		enrich := newEnrich(t, &lookups, flow.EnrichConfig[asset, string]{TTL: 10 * time.Millisecond})
+
+		in := make(chan asset)
+		out := enrich.Transform(in, nil)
+
+		in <- asset{host: "db1"}
+		<-out
+		in <- asset{host: "db1"}
+		<-out
+		time.Sleep(20 * time.Millisecond)
+		in <- asset{host: "db1"}
+		<-out
+		close(in)
+
+		assert.Equal(t, int32(2), lookups.Load())
+	})
+
+	t.Run("shares concurrent lookups of a key", func(t *testing.T) {
+		var lookups atomic.Int32
+		release := make(chan struct{})
+		enrich := newEnrich(t, &lookups, flow.EnrichConfig[asset, string]{
+			Workers: 4,
+			Lookup: func(_ context.Context, host string) (string, error) {
+				lookups.Add(1)
+				<-release
+				return owners[host], nil
+			},
+		})
+
+		in := make(chan asset, 4)
+		for range 4 {
+			in <- asset{host: "db1"}
+		}
+		close(in)
+
+		out := enrich.Transform(in, nil)
+		time.AfterFunc(20*time.Millisecond, func() { close(release) })
+
+		count := 0
+		for a := range out {
+			assert.Equal(t, "dba", a.owner)
+			count++
+		}
+		assert.Equal(t, 4, count)
+		assert.Equal(t, int32(1), lookups.Load())
+	})
+}
+
+func TestNewEnrich_Validation(t *testing.T) {
+	_, err := flow.NewEnrich(flow.EnrichConfig[asset, string]{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,215 @@
+package flow
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"unicode"
+)
+
+// CEFFields is the structured data of a Common Event Format message as the spans of its fields in the
+// message, parsed without allocating. It is reused by parsing into it again, so high volume pipelines
+// can parse without building a CEFEvent for each message. The header fields and extension values are
+// escaped.
+type CEFFields struct {
+	Version       int
+	DeviceVendor  Span
+	DeviceProduct Span
+	DeviceVersion Span
+	SignatureID   Span
+	Name          Span
+	Severity      Span
+	// Extensions are the extensions, in order.
+	Extensions []Field
+
+	eqs     []int  // positions of the unescaped equals signs, reused
+	scratch []byte // unescaped values, reused
+}
+
+// NewFastCEFParser creates a new Parser flow decoding Common Event Format messages into records holding
+// the JSON encoding of a CEFEvent, like the parser of NewCEFParser, through reused CEFFields rather than
+// a CEFEvent for each message. Messages that fail to parse are passed to deadLetter when it is not nil.
+func NewFastCEFParser[I any](deadLetter DeadLetterFunc[I]) (*Parser[I], error) {
+	pool := sync.Pool{New: func() any { return new(CEFFields) }}
+	return NewAppendParser("cef", func(dst, raw []byte) ([]byte, error) {
+		f := pool.Get().(*CEFFields)
+		defer pool.Put(f)
+		if err := f.Parse(raw); err != nil {
+			return nil, err
+		}
+		return f.AppendJSON(dst, raw), nil
+	}, deadLetter)
+}
+
+// Parse parses a Common Event Format message into the fields, as ParseCEF does.
+// The spans of the fields are only valid for the message.
+func (f *CEFFields) Parse(raw []byte) error {
+	*f = CEFFields{
+		Extensions: f.Extensions[:0],
+		eqs:        f.eqs[:0],
+		scratch:    f.scratch[:0],
+	}
+
+	start := bytes.Index(raw, []byte("CEF:"))
+	if start < 0 {
+		return fmt.Errorf("%w: missing CEF marker", ErrMalformedCEF)
+	}
+	start += len("CEF:")
+	end := len(raw)
+	for end > start && (raw[end-1] == '\r' || raw[end-1] == '\n') {
+		end--
+	}
+
+	// header fields escape pipes and backslashes
+	var fields [cefHeaderFields]Span
+	n, fieldStart := 0, start
+	i := start
+	for ; i < end && n < cefHeaderFields; i++ {
+		switch c := raw[i]; {
+		case c == '\\' && i+1 < end && (raw[i+1] == '|' || raw[i+1] == '\\'):
+			i++
+		case c == '|':
+			fields[n] = Span{fieldStart, i}
+			n++
+			fieldStart = i + 1
+		}
+	}
+	if n < cefHeaderFields {
+		return fmt.Errorf("%w: expected %d header fields, got %d", ErrMalformedCEF, cefHeaderFields, n)
+	}
+
+	version, ok := atoi(bytes.TrimSpace(fields[0].Of(raw)))
+	if !ok || bytes.ContainsRune(fields[0].Of(raw), '\\') {
+		return fmt.Errorf("%w: invalid version %q", ErrMalformedCEF, fields[0].Of(raw))
+	}
+	f.Version = version
+	f.DeviceVendor, f.DeviceProduct, f.DeviceVersion = fields[1], fields[2], fields[3]
+	f.SignatureID, f.Name, f.Severity = fields[4], fields[5], fields[6]
+
+	return f.parseExtensions(raw[:end], i)
+}
+
+// parseExtensions parses the extensions at the offset, as parseCEFExtensions does
+func (f *CEFFields) parseExtensions(raw []byte, off int) error {
+	trimmed := bytes.TrimLeftFunc(raw[off:], unicode.IsSpace)
+	off += len(raw[off:]) - len(trimmed)
+	raw = raw[:off+len(bytes.TrimRightFunc(trimmed, unicode.IsSpace))]
+	if off == len(raw) {
+		return nil
+	}
+
+	for i := off; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '=':
+			f.eqs = append(f.eqs, i)
+		}
+	}
+	if len(f.eqs) == 0 || bytes.IndexByte(raw[off:f.eqs[0]], ' ') >= 0 {
+		return fmt.Errorf("%w: invalid extension", ErrMalformedCEF)
+	}
+
+	key, valueStart := Span{off, f.eqs[0]}, f.eqs[0]+1
+	for _, eq := range f.eqs[1:] {
+		sp := bytes.LastIndexByte(raw[:eq], ' ')
+		if sp < valueStart {
+			// an equals sign within the value
+			continue
+		}
+		valueEnd := sp
+		for valueEnd > valueStart && raw[valueEnd-1] == ' ' {
+			valueEnd--
+		}
+		f.Extensions = append(f.Extensions, Field{Key: key, Value: Span{valueStart, valueEnd}})
+		key, valueStart = Span{sp + 1, eq}, eq+1
+	}
+	f.Extensions = append(f.Extensions, Field{Key: key, Value: Span{valueStart, len(raw)}})
+	return nil
+}
+
+// CEF returns the CEFEvent of the fields of the message.
+func (f *CEFFields) CEF(raw []byte) CEFEvent {
+	event := CEFEvent{
+		Version:       f.Version,
+		DeviceVendor:  string(appendCEFHeader(nil, f.DeviceVendor.Of(raw))),
+		DeviceProduct: string(appendCEFHeader(nil, f.DeviceProduct.Of(raw))),
+		DeviceVersion: string(appendCEFHeader(nil, f.DeviceVersion.Of(raw))),
+		SignatureID:   string(appendCEFHeader(nil, f.SignatureID.Of(raw))),
+		Name:          string(appendCEFHeader(nil, f.Name.Of(raw))),
+		Severity:      string(appendCEFHeader(nil, f.Severity.Of(raw))),
+	}
+	if len(f.Extensions) > 0 {
+		event.Extensions = make(map[string]string, len(f.Extensions))
+		for _, ext := range f.Extensions {
+			event.Extensions[string(ext.Key.Of(raw))] = string(appendCEFValue(nil, ext.Value.Of(raw)))
+		}
+	}
+	return event
+}
+
+// AppendJSON appends the JSON encoding of the CEFEvent of the fields of the message to dst.
+func (f *CEFFields) AppendJSON(dst, raw []byte) []byte {
+	dst = append(dst, `{"version":`...)
+	dst = strconv.AppendInt(dst, int64(f.Version), 10)
+	for _, header := range [...]struct {
+		key  string
+		span Span
+	}{
+		{`,"device_vendor":`, f.DeviceVendor},
+		{`,"device_product":`, f.DeviceProduct},
+		{`,"device_version":`, f.DeviceVersion},
+		{`,"signature_id":`, f.SignatureID},
+		{`,"name":`, f.Name},
+		{`,"severity":`, f.Severity},
+	} {
+		f.scratch = appendCEFHeader(f.scratch[:0], header.span.Of(raw))
+		dst = append(dst, header.key...)
+		dst = appendJSONString(dst, f.scratch)
+	}
+
+	if len(f.Extensions) > 0 {
+		dst = append(dst, `,"extensions":{`...)
+		for i, ext := range f.Extensions {
+			f.scratch = appendCEFValue(f.scratch[:0], ext.Value.Of(raw))
+			dst = appendJSONKey(dst, ext.Key.Of(raw), i == 0)
+			dst = appendJSONString(dst, f.scratch)
+		}
+		dst = append(dst, '}')
+	}
+	return append(dst, '}')
+}
+
+// appendCEFHeader appends a header field, unescaping pipes and backslashes
+func appendCEFHeader(dst, v []byte) []byte {
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) && (v[i+1] == '|' || v[i+1] == '\\') {
+			i++
+		}
+		dst = append(dst, v[i])
+	}
+	return dst
+}
+
+// appendCEFValue appends an extension value, unescaping as unescapeCEFValue does
+func appendCEFValue(dst, v []byte) []byte {
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' || i+1 == len(v) {
+			dst = append(dst, v[i])
+			continue
+		}
+		i++
+		switch v[i] {
+		case 'n':
+			dst = append(dst, '\n')
+		case 'r':
+			dst = append(dst, '\r')
+		case '=', '\\':
+			dst = append(dst, v[i])
+		default:
+			dst = append(dst, '\\', v[i])
+		}
+	}
+	return dst
+}
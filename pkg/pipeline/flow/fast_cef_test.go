@@ -0,0 +1,103 @@
+package flow_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// cefMessages are valid messages parsed alike by the CEF parsers
+var cefMessages = []string{
+	`<134>Jan  2 03:04:05 fw1 CEF:0|Security|threat\|manager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 msg=Detected a threat. No action needed\=ok path=C:\\temp\\x.exe note=line\nbreak`,
+	"CEF:1|Vendor|Product|2|login|User login|Low|\n",
+	"CEF:0|V|P|1|1|N|5|request=https://example.com/?a=b act=blocked act=allowed  ",
+	`CEF:0|V|P|1|1|<N>|5|msg="quoted" \x`,
+}
+
+func TestCEFFields_Parse(t *testing.T) {
+	var f flow.CEFFields
+	for _, raw := range cefMessages {
+		want, err := flow.ParseCEF([]byte(raw))
+		require.NoError(t, err, raw)
+
+		assert.NoError(t, f.Parse([]byte(raw)), raw)
+		assert.Equal(t, want, f.CEF([]byte(raw)), raw)
+
+		encoded, err := json.Marshal(want)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(encoded), string(f.AppendJSON(nil, []byte(raw))), raw)
+	}
+
+	t.Run("rejects malformed messages", func(t *testing.T) {
+		for _, raw := range []string{
+			"not cef",
+			"CEF:0|Vendor|Product|1.0",
+			"CEF:x|V|P|1|1|N|5|",
+			"CEF:0|V|P|1|1|N|5|no pairs here",
+		} {
+			assert.ErrorIs(t, f.Parse([]byte(raw)), flow.ErrMalformedCEF, raw)
+		}
+	})
+
+	t.Run("does not allocate", func(t *testing.T) {
+		dst := make([]byte, 0, 1024)
+		for _, msg := range cefMessages {
+			raw := []byte(msg)
+			allocs := testing.AllocsPerRun(100, func() {
+				_ = f.Parse(raw)
+				dst = f.AppendJSON(dst[:0], raw)
+			})
+			assert.Zero(t, allocs, msg)
+		}
+	})
+}
+
+func TestFastCEFParser_Transform(t *testing.T) {
+	parser, err := flow.NewFastCEFParser[[]byte](nil)
+	assert.NoError(t, err)
+
+	in := make(chan []byte, 2)
+	in <- []byte("CEF:0|V|P|1|1|N|5|src=10.0.0.1")
+	in <- []byte("garbage")
+	close(in)
+
+	eventC := make(chan pipeline.Event, 1)
+	var result []pipeline.DataRawReadable
+	for r := range parser.Transform(in, eventC) {
+		result = append(result, r)
+	}
+
+	assert.Len(t, result, 1)
+	data, _ := result[0].Data().Read()
+	var event map[string]any
+	assert.NoError(t, json.Unmarshal(data, &event))
+	assert.Equal(t, map[string]any{"src": "10.0.0.1"}, event["extensions"])
+
+	errEvent, ok := (<-eventC).(pipeline.ErrorEvent)
+	assert.True(t, ok)
+	assert.ErrorIs(t, errEvent, flow.ErrMalformedCEF)
+}
+
+func BenchmarkParseCEF(b *testing.B) {
+	raw := []byte(cefMessages[0])
+	b.Run("map", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			event, _ := flow.ParseCEF(raw)
+			_, _ = json.Marshal(event)
+		}
+	})
+	b.Run("fields", func(b *testing.B) {
+		b.ReportAllocs()
+		var f flow.CEFFields
+		dst := make([]byte, 0, 1024)
+		for b.Loop() {
+			_ = f.Parse(raw)
+			dst = f.AppendJSON(dst[:0], raw)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package flow
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// Span is the range of a field in a message, as the byte offsets of its start and end.
+// The bytes of a span are those of the message, with any escapes it carries.
+type Span struct {
+	Start, End int
+}
+
+// Of returns the bytes of the span in the message.
+func (s Span) Of(raw []byte) []byte {
+	return raw[s.Start:s.End]
+}
+
+// Len returns the length of the span.
+func (s Span) Len() int {
+	return s.End - s.Start
+}
+
+// Field is a key and value of a message.
+type Field struct {
+	Key, Value Span
+}
+
+// AppendParseFunc is a function that parses a raw message and appends its structured data encoded as
+// JSON to dst, so it can parse into reused buffers without allocating.
+type AppendParseFunc func(dst, raw []byte) ([]byte, error)
+
+// NewAppendParser creates a new Parser flow with the given append parse function, whose output is
+// appended to pooled buffers rather than encoded from a value.
+// The name is used in the error events of messages that fail to parse.
+// Messages that fail to parse are passed to deadLetter when it is not nil.
+func NewAppendParser[I any](name string, parse AppendParseFunc, deadLetter DeadLetterFunc[I]) (*Parser[I], error) {
+	if parse == nil {
+		return nil, errors.New("parse func is nil")
+	}
+
+	return &Parser[I]{
+		name:        name,
+		appendParse: parse,
+		deadLetter:  deadLetter,
+	}, nil
+}
+
+// hexDigits are the digits of the \u escapes of JSON strings
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends the bytes as a JSON string, escaped as encoding/json escapes them, with
+// invalid UTF-8 replaced by the replacement character
+func appendJSONString(dst, s []byte) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= ' ' && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, size := utf8.DecodeRune(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+		case r == '\u2028' || r == '\u2029':
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// appendJSONKey appends the bytes as the key of a JSON object member, preceded by a comma unless the
+// member is the first
+func appendJSONKey(dst, key []byte, first bool) []byte {
+	if !first {
+		dst = append(dst, ',')
+	}
+	dst = appendJSONString(dst, key)
+	return append(dst, ':')
+}
+
+// atoi parses the unsigned decimal integer of the bytes without allocating
+func atoi(b []byte) (int, bool) {
+	if len(b) == 0 || len(b) > 9 {
+		return 0, false
+	}
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	return n, true
+}
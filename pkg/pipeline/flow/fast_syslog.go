@@ -0,0 +1,355 @@
+package flow
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SyslogFields is the structured data of a syslog message as the spans of its fields in the message,
+// parsed without allocating. It is reused by parsing into it again, so high volume pipelines can parse
+// without building a SyslogMessage for each message.
+type SyslogFields struct {
+	Priority  int
+	Facility  int
+	Severity  int
+	Version   int // 1 for RFC 5424, 0 for RFC 3164
+	Timestamp time.Time
+	Hostname  Span
+	AppName   Span
+	ProcID    Span
+	MsgID     Span
+	Message   Span
+	// SDElements are the ids of the structured data elements, in order.
+	SDElements []Span
+	// SDParams are the params of the structured data elements, in order. Their values are escaped.
+	SDParams []SDParam
+
+	scratch []byte // unescaped values, reused
+}
+
+// SDParam is a param of a structured data element of a syslog message.
+type SDParam struct {
+	Element int // index of the element in SDElements
+	Field
+}
+
+// NewFastSyslogParser creates a new Parser flow decoding RFC 3164 and RFC 5424 syslog messages into
+// records holding the JSON encoding of a SyslogMessage, like the parser of NewSyslogParser, through
+// reused SyslogFields rather than a SyslogMessage for each message. Messages that fail to parse are
+// passed to deadLetter when it is not nil.
+func NewFastSyslogParser[I any](conf SyslogConfig, deadLetter DeadLetterFunc[I]) (*Parser[I], error) {
+	pool := sync.Pool{New: func() any { return new(SyslogFields) }}
+	return NewAppendParser("syslog", func(dst, raw []byte) ([]byte, error) {
+		f := pool.Get().(*SyslogFields)
+		defer pool.Put(f)
+		if err := f.Parse(raw, conf.Location); err != nil {
+			return nil, err
+		}
+		return f.AppendJSON(dst, raw), nil
+	}, deadLetter)
+}
+
+// Parse parses an RFC 3164 or RFC 5424 syslog message into the fields, as ParseSyslog does.
+// The spans of the fields are only valid for the message.
+func (f *SyslogFields) Parse(raw []byte, loc *time.Location) error {
+	*f = SyslogFields{
+		SDElements: f.SDElements[:0],
+		SDParams:   f.SDParams[:0],
+		scratch:    f.scratch[:0],
+		Priority:   13,
+	}
+
+	end := len(raw)
+	for end > 0 && (raw[end-1] == '\r' || raw[end-1] == '\n') {
+		end--
+	}
+	if end == 0 {
+		return fmt.Errorf("%w: empty message", ErrMalformedSyslog)
+	}
+
+	off := 0
+	if raw[0] == '<' {
+		gt := bytes.IndexByte(raw[:end], '>')
+		if gt < 2 || gt > 4 {
+			return fmt.Errorf("%w: invalid priority", ErrMalformedSyslog)
+		}
+		pri, ok := atoi(raw[1:gt])
+		if !ok || pri > 191 {
+			return fmt.Errorf("%w: invalid priority %q", ErrMalformedSyslog, raw[1:gt])
+		}
+		f.Priority = pri
+		off = gt + 1
+	}
+	f.Facility = f.Priority / 8
+	f.Severity = f.Priority % 8
+
+	// an RFC 5424 version is followed by a space
+	if sp := bytes.IndexByte(raw[off:end], ' '); sp >= 1 && sp <= 2 && raw[off] != '0' {
+		if version, ok := atoi(raw[off : off+sp]); ok {
+			f.Version = version
+			return f.parseRFC5424(raw[:end], off+sp+1)
+		}
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	f.parseRFC3164(raw[:end], off, loc)
+	return nil
+}
+
+// parseRFC5424 parses the header, structured data and message following the version at the offset
+func (f *SyslogFields) parseRFC5424(raw []byte, off int) error {
+	var fields [5]Span
+	for i := range fields {
+		sp := bytes.IndexByte(raw[off:], ' ')
+		if sp < 0 && i < len(fields)-1 {
+			return fmt.Errorf("%w: truncated header", ErrMalformedSyslog)
+		}
+		end, next := len(raw), len(raw)
+		if sp >= 0 {
+			end, next = off+sp, off+sp+1
+		}
+		if end == off {
+			return fmt.Errorf("%w: empty header field", ErrMalformedSyslog)
+		}
+		if end-off != 1 || raw[off] != '-' {
+			fields[i] = Span{off, end}
+		}
+		off = next
+	}
+
+	if fields[0].Len() > 0 {
+		ts, err := time.Parse(time.RFC3339Nano, string(fields[0].Of(raw)))
+		if err != nil {
+			return fmt.Errorf("%w: invalid timestamp: %w", ErrMalformedSyslog, err)
+		}
+		f.Timestamp = ts
+	}
+	f.Hostname, f.AppName, f.ProcID, f.MsgID = fields[1], fields[2], fields[3], fields[4]
+
+	switch {
+	case off == len(raw):
+		return fmt.Errorf("%w: missing structured data", ErrMalformedSyslog)
+	case raw[off] == '-':
+		off++
+	default:
+		var err error
+		if off, err = f.parseStructuredData(raw, off); err != nil {
+			return err
+		}
+	}
+
+	if off < len(raw) {
+		if raw[off] != ' ' {
+			return fmt.Errorf("%w: invalid structured data", ErrMalformedSyslog)
+		}
+		off++
+		if bytes.HasPrefix(raw[off:], []byte("\ufeff")) { // byte order mark of UTF-8 messages
+			off += len("\ufeff")
+		}
+		f.Message = Span{off, len(raw)}
+	}
+	return nil
+}
+
+// parseStructuredData parses the structured data elements at the offset and returns the offset after
+func (f *SyslogFields) parseStructuredData(raw []byte, off int) (int, error) {
+	for off < len(raw) && raw[off] == '[' {
+		end := bytes.IndexAny(raw[off:], " ]")
+		if end < 2 {
+			return 0, fmt.Errorf("%w: invalid structured data id", ErrMalformedSyslog)
+		}
+		element := len(f.SDElements)
+		f.SDElements = append(f.SDElements, Span{off + 1, off + end})
+		off += end
+
+		for off < len(raw) && raw[off] == ' ' {
+			eq := bytes.IndexByte(raw[off:], '=')
+			if eq < 2 || len(raw)-off < eq+2 || raw[off+eq+1] != '"' {
+				return 0, fmt.Errorf("%w: invalid structured data param", ErrMalformedSyslog)
+			}
+			name := Span{off + 1, off + eq}
+			start := off + eq + 2
+			quote := paramValueEnd(raw, start)
+			if quote < 0 {
+				return 0, fmt.Errorf("%w: unterminated structured data value", ErrMalformedSyslog)
+			}
+			f.SDParams = append(f.SDParams, SDParam{
+				Element: element,
+				Field:   Field{Key: name, Value: Span{start, quote}},
+			})
+			off = quote + 1
+		}
+
+		if off == len(raw) || raw[off] != ']' {
+			return 0, fmt.Errorf("%w: unterminated structured data element", ErrMalformedSyslog)
+		}
+		off++
+	}
+	return off, nil
+}
+
+// paramValueEnd returns the offset of the closing quote of the param value at the offset, skipping
+// escaped quotes, or -1 if the value is unterminated
+func paramValueEnd(raw []byte, off int) int {
+	for i := off; i < len(raw); i++ {
+		switch c := raw[i]; {
+		case c == '"':
+			return i
+		case c == '\\' && i+1 < len(raw) && (raw[i+1] == '"' || raw[i+1] == '\\' || raw[i+1] == ']'):
+			i++
+		}
+	}
+	return -1
+}
+
+// parseRFC3164 parses the timestamp, host, tag and message following the priority at the offset.
+// Parts that are not found are left empty and the unparsed remainder becomes the message.
+func (f *SyslogFields) parseRFC3164(raw []byte, off int, loc *time.Location) {
+	hasTime := false
+	if len(raw)-off >= len(rfc3164Time) && likeRFC3164Time(raw[off:]) {
+		if ts, err := time.ParseInLocation(rfc3164Time, string(raw[off:off+len(rfc3164Time)]), loc); err == nil {
+			f.Timestamp = withYear(ts, time.Now().In(loc))
+			off += len(rfc3164Time)
+			if off < len(raw) && raw[off] == ' ' {
+				off++
+			}
+			hasTime = true
+		}
+	}
+	if !hasTime {
+		// some senders use RFC 3339 timestamps in the BSD format
+		if sp := bytes.IndexByte(raw[off:], ' '); sp >= 0 && likeRFC3339(raw[off:off+sp]) {
+			if ts, err := time.Parse(time.RFC3339Nano, string(raw[off:off+sp])); err == nil {
+				f.Timestamp = ts
+				off += sp + 1
+				hasTime = true
+			}
+		}
+	}
+
+	// the host follows the timestamp unless the next word is already the tag
+	if hasTime {
+		if sp := bytes.IndexByte(raw[off:], ' '); sp >= 0 && (sp == 0 || raw[off+sp-1] != ':') {
+			f.Hostname = Span{off, off + sp}
+			off += sp + 1
+		}
+	}
+
+	if sp := bytes.IndexByte(raw[off:], ' '); sp > 0 && raw[off+sp-1] == ':' {
+		tag := Span{off, off + sp - 1}
+		if open := bytes.IndexByte(tag.Of(raw), '['); open > 0 && raw[tag.End-1] == ']' {
+			f.ProcID = Span{tag.Start + open + 1, tag.End - 1}
+			tag.End = tag.Start + open
+		}
+		f.AppName = tag
+		off += sp + 1
+	}
+	f.Message = Span{off, len(raw)}
+}
+
+// likeRFC3164Time reports whether the bytes may start with an RFC 3164 timestamp, so failing to parse
+// the timestamps of messages without one does not allocate errors
+func likeRFC3164Time(b []byte) bool {
+	return b[3] == ' ' && b[9] == ':' && b[12] == ':'
+}
+
+// likeRFC3339 reports whether the bytes may be an RFC 3339 timestamp
+func likeRFC3339(b []byte) bool {
+	return len(b) >= 20 && b[4] == '-' && b[7] == '-' && b[10] == 'T'
+}
+
+// Syslog returns the SyslogMessage of the fields of the message.
+func (f *SyslogFields) Syslog(raw []byte) SyslogMessage {
+	msg := SyslogMessage{
+		Priority:  f.Priority,
+		Facility:  f.Facility,
+		Severity:  f.Severity,
+		Version:   f.Version,
+		Timestamp: f.Timestamp,
+		Hostname:  string(f.Hostname.Of(raw)),
+		AppName:   string(f.AppName.Of(raw)),
+		ProcID:    string(f.ProcID.Of(raw)),
+		MsgID:     string(f.MsgID.Of(raw)),
+		Message:   string(f.Message.Of(raw)),
+	}
+	if len(f.SDElements) > 0 {
+		msg.StructuredData = make(map[string]map[string]string, len(f.SDElements))
+		elements := make([]map[string]string, len(f.SDElements))
+		for i, id := range f.SDElements {
+			elements[i] = make(map[string]string)
+			msg.StructuredData[string(id.Of(raw))] = elements[i]
+		}
+		for _, p := range f.SDParams {
+			elements[p.Element][string(p.Key.Of(raw))] = string(appendParamValue(nil, p.Value.Of(raw)))
+		}
+	}
+	return msg
+}
+
+// AppendJSON appends the JSON encoding of the SyslogMessage of the fields of the message to dst.
+func (f *SyslogFields) AppendJSON(dst, raw []byte) []byte {
+	dst = append(dst, `{"priority":`...)
+	dst = strconv.AppendInt(dst, int64(f.Priority), 10)
+	dst = append(dst, `,"facility":`...)
+	dst = strconv.AppendInt(dst, int64(f.Facility), 10)
+	dst = append(dst, `,"severity":`...)
+	dst = strconv.AppendInt(dst, int64(f.Severity), 10)
+	if f.Version != 0 {
+		dst = append(dst, `,"version":`...)
+		dst = strconv.AppendInt(dst, int64(f.Version), 10)
+	}
+	if !f.Timestamp.IsZero() {
+		dst = append(dst, `,"timestamp":"`...)
+		dst = f.Timestamp.AppendFormat(dst, time.RFC3339Nano)
+		dst = append(dst, '"')
+	}
+	dst = appendJSONSpan(dst, `,"hostname":`, f.Hostname, raw)
+	dst = appendJSONSpan(dst, `,"app_name":`, f.AppName, raw)
+	dst = appendJSONSpan(dst, `,"proc_id":`, f.ProcID, raw)
+	dst = appendJSONSpan(dst, `,"msg_id":`, f.MsgID, raw)
+
+	if len(f.SDElements) > 0 {
+		dst = append(dst, `,"structured_data":{`...)
+		params := f.SDParams
+		for i, id := range f.SDElements {
+			dst = appendJSONKey(dst, id.Of(raw), i == 0)
+			dst = append(dst, '{')
+			for first := true; len(params) > 0 && params[0].Element == i; params = params[1:] {
+				f.scratch = appendParamValue(f.scratch[:0], params[0].Value.Of(raw))
+				dst = appendJSONKey(dst, params[0].Key.Of(raw), first)
+				dst = appendJSONString(dst, f.scratch)
+				first = false
+			}
+			dst = append(dst, '}')
+		}
+		dst = append(dst, '}')
+	}
+
+	dst = appendJSONSpan(dst, `,"message":`, f.Message, raw)
+	return append(dst, '}')
+}
+
+// appendJSONSpan appends the member of a span that is not empty, the key including its comma
+func appendJSONSpan(dst []byte, key string, s Span, raw []byte) []byte {
+	if s.Len() == 0 {
+		return dst
+	}
+	dst = append(dst, key...)
+	return appendJSONString(dst, s.Of(raw))
+}
+
+// appendParamValue appends a structured data param value, unescaping \", \\ and \]
+func appendParamValue(dst, v []byte) []byte {
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c == '\\' && i+1 < len(v) && (v[i+1] == '"' || v[i+1] == '\\' || v[i+1] == ']') {
+			i++
+		}
+		dst = append(dst, v[i])
+	}
+	return dst
+}
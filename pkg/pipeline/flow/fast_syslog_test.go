@@ -0,0 +1,107 @@
+package flow_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// syslogMessages are valid messages parsed alike by the syslog parsers
+var syslogMessages = []string{
+	`<165>1 2026-01-02T03:04:05.003Z fw1 evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App \"x\" \]"][meta seq="1"] ` + "\ufeff" + `An application event`,
+	"<34>1 - - - - - -\n",
+	"<34>1 2026-01-02T03:04:05Z host app - - [empty][dup k=\"1\" k=\"2\"] <b>&\"quoted\"\t ",
+	"<38>" + time.Now().UTC().Add(-time.Hour).Format(time.Stamp) + " web1 sshd[811]: Accepted publickey for alice",
+	"<13>2026-01-02T03:04:05+01:00 kernel: link down",
+	"just some text \xff",
+}
+
+func TestSyslogFields_Parse(t *testing.T) {
+	var f flow.SyslogFields
+	for _, raw := range syslogMessages {
+		want, err := flow.ParseSyslog([]byte(raw), nil)
+		require.NoError(t, err, raw)
+
+		assert.NoError(t, f.Parse([]byte(raw), nil), raw)
+		assert.Equal(t, want, f.Syslog([]byte(raw)), raw)
+
+		encoded, err := json.Marshal(want)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(encoded), string(f.AppendJSON(nil, []byte(raw))), raw)
+	}
+
+	t.Run("rejects malformed messages", func(t *testing.T) {
+		for _, raw := range []string{
+			"",
+			"<999>1 - - - - - -",
+			"<13",
+			"<13>1 2026-01-02",
+			"<13>1 yesterday host app - - -",
+			`<13>1 - host app - - [id k="unterminated]`,
+			"<13>1 - host app - - [id]trailing",
+		} {
+			assert.ErrorIs(t, f.Parse([]byte(raw), nil), flow.ErrMalformedSyslog, raw)
+		}
+	})
+
+	t.Run("does not allocate", func(t *testing.T) {
+		dst := make([]byte, 0, 1024)
+		for _, msg := range syslogMessages {
+			raw := []byte(msg)
+			allocs := testing.AllocsPerRun(100, func() {
+				_ = f.Parse(raw, nil)
+				dst = f.AppendJSON(dst[:0], raw)
+			})
+			assert.Zero(t, allocs, msg)
+		}
+	})
+}
+
+func TestFastSyslogParser_Transform(t *testing.T) {
+	var letters []string
+	parser, err := flow.NewFastSyslogParser(flow.SyslogConfig{}, func(raw string, _ error) {
+		letters = append(letters, raw)
+	})
+	assert.NoError(t, err)
+
+	raw := "<34>1 2026-01-02T03:04:05Z host app - - - message"
+	in := make(chan string, 2)
+	in <- raw
+	in <- "<13>1 bad"
+	close(in)
+
+	var result [][]byte
+	for r := range parser.Transform(in, nil) {
+		data, err := r.Data().Read()
+		assert.NoError(t, err)
+		result = append(result, data)
+	}
+
+	assert.Len(t, result, 1)
+	assert.JSONEq(t, `{"priority":34,"facility":4,"severity":2,"version":1,"timestamp":"2026-01-02T03:04:05Z","hostname":"host","app_name":"app","message":"message"}`, string(result[0]))
+	assert.Equal(t, []string{"<13>1 bad"}, letters)
+}
+
+func BenchmarkParseSyslog(b *testing.B) {
+	raw := []byte(syslogMessages[0])
+	b.Run("map", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			msg, _ := flow.ParseSyslog(raw, nil)
+			_, _ = json.Marshal(msg)
+		}
+	})
+	b.Run("fields", func(b *testing.B) {
+		b.ReportAllocs()
+		var f flow.SyslogFields
+		dst := make([]byte, 0, 1024)
+		for b.Loop() {
+			_ = f.Parse(raw, nil)
+			dst = f.AppendJSON(dst[:0], raw)
+		}
+	})
+}
@@ -7,8 +7,11 @@ import (
 	"github.com/witfoo/krapht/pkg/pipeline"
 )
 
-// Ensure Filter implements the Flow interface
-var _ pipeline.Flow[any, any] = (*Filter[any])(nil)
+// Ensure Filter implements the Flow and ChunkFlow interfaces
+var (
+	_ pipeline.Flow[any, any]      = (*Filter[any])(nil)
+	_ pipeline.ChunkFlow[any, any] = (*Filter[any])(nil)
+)
 
 // PredicateFunc is a function type that takes an input of type I and returns a boolean value.
 type PredicateFunc[I any] func(in I) bool
@@ -43,3 +46,25 @@ func (f Filter[I]) Transform(in <-chan I, _ chan<- pipeline.Event) <-chan I {
 	}()
 	return out
 }
+
+// TransformChunks applies the filter operation on the chunks of the input channel and returns the output
+// channel of the chunks of the items satisfying the predicate function, which are filtered in place.
+// Chunks without such items are not sent.
+func (f Filter[I]) TransformChunks(in <-chan []I, _ chan<- pipeline.Event) <-chan []I {
+	out := make(chan []I)
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			passed := chunk[:0]
+			for _, v := range chunk {
+				if f.predicate(v) {
+					passed = append(passed, v)
+				}
+			}
+			if len(passed) > 0 {
+				out <- passed
+			}
+		}
+	}()
+	return out
+}
@@ -45,3 +45,21 @@ func TestFilter_Transform(t *testing.T) {
 	expected := []int{2, 4}
 	assert.Equal(t, expected, result)
 }
+
+func TestFilter_TransformChunks(t *testing.T) {
+	filterFlow, err := flow.NewFilter(func(in int) bool {
+		return in%2 == 0 // Filter even numbers
+	})
+	assert.NoError(t, err)
+
+	input := make(chan []int, 2)
+	input <- []int{1, 2, 3, 4}
+	input <- []int{5, 7}
+	close(input)
+
+	var result [][]int
+	for chunk := range filterFlow.TransformChunks(input, nil) {
+		result = append(result, chunk)
+	}
+	assert.Equal(t, [][]int{{2, 4}}, result, "chunks without passing items are not sent")
+}
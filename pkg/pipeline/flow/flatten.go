@@ -0,0 +1,67 @@
+package flow
+
+import (
+	"iter"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Flatten and FlattenSeq implement the Flow interface.
+var (
+	_ pipeline.Flow[[]any, any]         = (*Flatten[any])(nil)
+	_ pipeline.Flow[iter.Seq[any], any] = (*FlattenSeq[any])(nil)
+)
+
+// Flatten is a struct that represents unbatching of slices on a data stream into their items.
+type Flatten[I any] struct {
+}
+
+// NewFlatten creates a new Flatten flow.
+func NewFlatten[I any]() *Flatten[I] {
+	return &Flatten[I]{}
+}
+
+// Transform emits each item of the slices from the input channel in order and returns the output channel.
+// Empty and nil slices emit nothing.
+func (f Flatten[I]) Transform(in <-chan []I, _ chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+
+	go func() {
+		defer close(out)
+		for batch := range in {
+			for _, v := range batch {
+				out <- v
+			}
+		}
+	}()
+	return out
+}
+
+// FlattenSeq is a struct that represents unbatching of iterators on a data stream into their items.
+// It suits upstream stages that produce items lazily, such as splitters of large payloads.
+type FlattenSeq[I any] struct {
+}
+
+// NewFlattenSeq creates a new FlattenSeq flow.
+func NewFlattenSeq[I any]() *FlattenSeq[I] {
+	return &FlattenSeq[I]{}
+}
+
+// Transform emits each item yielded by the iterators from the input channel in order and returns the output channel.
+// Nil iterators emit nothing.
+func (f FlattenSeq[I]) Transform(in <-chan iter.Seq[I], _ chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+
+	go func() {
+		defer close(out)
+		for seq := range in {
+			if seq == nil {
+				continue
+			}
+			for v := range seq {
+				out <- v
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,45 @@
+package flow_test
+
+import (
+	"iter"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestFlatten_Transform(t *testing.T) {
+	flatten := flow.NewFlatten[int]()
+
+	in := make(chan []int, 4)
+	in <- []int{1, 2}
+	in <- nil
+	in <- []int{}
+	in <- []int{3}
+	close(in)
+
+	var result []int
+	for v := range flatten.Transform(in, nil) {
+		result = append(result, v)
+	}
+
+	assert.Equal(t, []int{1, 2, 3}, result)
+}
+
+func TestFlattenSeq_Transform(t *testing.T) {
+	flatten := flow.NewFlattenSeq[string]()
+
+	in := make(chan iter.Seq[string], 3)
+	in <- slices.Values([]string{"a", "b"})
+	in <- nil
+	in <- slices.Values([]string{"c"})
+	close(in)
+
+	var result []string
+	for v := range flatten.Transform(in, nil) {
+		result = append(result, v)
+	}
+
+	assert.Equal(t, []string{"a", "b", "c"}, result)
+}
@@ -0,0 +1,218 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrGrokNoMatch is the error of a message matching none of the grok expressions.
+var ErrGrokNoMatch = errors.New("no grok expression matched")
+
+// grokReference matches %{NAME}, %{NAME:field} and %{NAME:field:type}
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::(int|float|string))?\}`)
+
+// grokName matches valid pattern names
+var grokName = regexp.MustCompile(`^\w+$`)
+
+// Grok is a library of named patterns that grok expressions are compiled against.
+type Grok struct {
+	patterns map[string]string
+}
+
+// NewGrok creates a new Grok library holding the standard patterns.
+func NewGrok() *Grok {
+	return &Grok{
+		patterns: maps.Clone(grokPatterns),
+	}
+}
+
+// AddPattern registers a pattern under the name, replacing a standard pattern of the same name.
+// The pattern is a regular expression that may reference other patterns.
+func (g *Grok) AddPattern(name, pattern string) error {
+	if !grokName.MatchString(name) {
+		return fmt.Errorf("invalid grok pattern name %q", name)
+	}
+	g.patterns[name] = pattern
+	return nil
+}
+
+// grokField is a field captured by a group of a compiled expression
+type grokField struct {
+	path []string // nested field names, from [a][b] notation
+	kind string   // int, float or string
+}
+
+// GrokExpression is a compiled grok expression.
+type GrokExpression struct {
+	re     *regexp.Regexp
+	fields map[int]grokField // by group index
+}
+
+// Compile expands the pattern references of the expression and compiles it.
+// A reference of the form %{NAME:field} captures the field, which may be nested with the
+// [outer][inner] notation, and %{NAME:field:int} or %{NAME:field:float} converts it.
+// Named groups (?P<field>...) and (?<field>...) capture fields too.
+func (g *Grok) Compile(expr string) (*GrokExpression, error) {
+	var captures []grokField
+	expanded, err := g.expand(expr, nil, &captures)
+	if err != nil {
+		return nil, err
+	}
+
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("grok expression %q: %w", expr, err)
+	}
+
+	fields := make(map[int]grokField)
+	for i, name := range re.SubexpNames() {
+		switch {
+		case name == "":
+		case strings.HasPrefix(name, "_grok"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(name, "_grok"))
+			fields[i] = captures[n]
+		default:
+			fields[i] = grokField{path: []string{name}, kind: "string"}
+		}
+	}
+	return &GrokExpression{re: re, fields: fields}, nil
+}
+
+// expand replaces the pattern references of expr, tracking the patterns being expanded to detect cycles,
+// and records the fields captured by references
+func (g *Grok) expand(expr string, stack []string, captures *[]grokField) (string, error) {
+	var err error
+	expanded := grokReference.ReplaceAllStringFunc(expr, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		m := grokReference.FindStringSubmatch(ref)
+		name, field, kind := m[1], m[2], m[3]
+
+		pattern, ok := g.patterns[name]
+		if !ok {
+			err = fmt.Errorf("unknown grok pattern %q", name)
+			return ""
+		}
+		for _, s := range stack {
+			if s == name {
+				err = fmt.Errorf("grok pattern %q references itself", name)
+				return ""
+			}
+		}
+
+		var inner string
+		inner, err = g.expand(pattern, append(stack, name), captures)
+		if err != nil {
+			return ""
+		}
+
+		if field == "" {
+			return "(?:" + inner + ")"
+		}
+		if kind == "" {
+			kind = "string"
+		}
+		*captures = append(*captures, grokField{path: grokPath(field), kind: kind})
+		return fmt.Sprintf("(?P<_grok%d>%s)", len(*captures)-1, inner)
+	})
+	return expanded, err
+}
+
+// grokPath splits a field name in [outer][inner] notation into its names
+func grokPath(field string) []string {
+	if !strings.HasPrefix(field, "[") || !strings.HasSuffix(field, "]") {
+		return []string{field}
+	}
+	return strings.Split(field[1:len(field)-1], "][")
+}
+
+// Match returns the fields captured from s, or false when the expression does not match.
+// Fields that captured nothing are omitted.
+func (e *GrokExpression) Match(s string) (map[string]any, bool, error) {
+	loc := e.re.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return nil, false, nil
+	}
+
+	fields := make(map[string]any)
+	for i, field := range e.fields {
+		start, end := loc[2*i], loc[2*i+1]
+		if start < 0 || start == end {
+			continue
+		}
+
+		var value any = s[start:end]
+		var err error
+		switch field.kind {
+		case "int":
+			value, err = strconv.ParseInt(s[start:end], 10, 64)
+		case "float":
+			value, err = strconv.ParseFloat(s[start:end], 64)
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("field %s: %w", strings.Join(field.path, "."), err)
+		}
+
+		target := fields
+		for _, name := range field.path[:len(field.path)-1] {
+			next, ok := target[name].(map[string]any)
+			if !ok {
+				next = make(map[string]any)
+				target[name] = next
+			}
+			target = next
+		}
+		target[field.path[len(field.path)-1]] = value
+	}
+	return fields, true, nil
+}
+
+// GrokConfig is the configuration of a grok parser.
+type GrokConfig struct {
+	Expressions []string          // grok expressions tried in order until one matches, required
+	Patterns    map[string]string // custom patterns added to the standard library
+}
+
+// NewGrokParser creates a new Parser flow extracting the fields of the first matching grok
+// expression into records holding them as JSON data. Messages matching no expression are
+// passed to deadLetter when it is not nil.
+func NewGrokParser[I any](conf GrokConfig, deadLetter DeadLetterFunc[I]) (*Parser[I], error) {
+	if len(conf.Expressions) == 0 {
+		return nil, errors.New("no grok expressions")
+	}
+
+	g := NewGrok()
+	for name, pattern := range conf.Patterns {
+		if err := g.AddPattern(name, pattern); err != nil {
+			return nil, err
+		}
+	}
+
+	expressions := make([]*GrokExpression, len(conf.Expressions))
+	for i, expr := range conf.Expressions {
+		e, err := g.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		expressions[i] = e
+	}
+
+	return NewParser("grok", func(raw []byte) (any, error) {
+		s := string(raw)
+		for _, e := range expressions {
+			fields, ok, err := e.Match(s)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return fields, nil
+			}
+		}
+		return nil, ErrGrokNoMatch
+	}, deadLetter)
+}
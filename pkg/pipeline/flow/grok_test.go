@@ -0,0 +1,133 @@
+package flow_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestGrok_Compile(t *testing.T) {
+	g := flow.NewGrok()
+
+	t.Run("matches the standard apache pattern", func(t *testing.T) {
+		e, err := g.Compile("%{COMBINEDAPACHELOG}")
+		assert.NoError(t, err)
+
+		fields, ok, err := e.Match(`203.0.113.7 - frank [10/Oct/2025:13:55:36 -0700] "GET /apache_pb.gif?x=1 HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, map[string]any{
+			"clientip":    "203.0.113.7",
+			"ident":       "-",
+			"auth":        "frank",
+			"timestamp":   "10/Oct/2025:13:55:36 -0700",
+			"verb":        "GET",
+			"request":     "/apache_pb.gif?x=1",
+			"httpversion": "1.0",
+			"response":    "200",
+			"bytes":       "2326",
+			"referrer":    `"http://www.example.com/start.html"`,
+			"agent":       `"Mozilla/4.08"`,
+		}, fields)
+	})
+
+	t.Run("converts types and nests fields", func(t *testing.T) {
+		e, err := g.Compile(`%{IP:[source][ip]}:%{POSINT:[source][port]:int} took %{NUMBER:duration:float}ms (?P<flag>\w+)`)
+		assert.NoError(t, err)
+
+		fields, ok, err := e.Match("2001:db8::1:443 took 1.5ms ok")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, map[string]any{
+			"source":   map[string]any{"ip": "2001:db8::1", "port": int64(443)},
+			"duration": 1.5,
+			"flag":     "ok",
+		}, fields)
+
+		_, ok, err = e.Match("no match here")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("uses custom patterns", func(t *testing.T) {
+		g := flow.NewGrok()
+		assert.NoError(t, g.AddPattern("TICKET", `[A-Z]+-%{POSINT}`))
+		e, err := g.Compile("%{TICKET:ticket} %{GREEDYDATA:summary}")
+		assert.NoError(t, err)
+
+		fields, ok, _ := e.Match("OPS-42 disk full")
+		assert.True(t, ok)
+		assert.Equal(t, map[string]any{"ticket": "OPS-42", "summary": "disk full"}, fields)
+
+		assert.Error(t, g.AddPattern("not valid", "x"))
+	})
+
+	t.Run("rejects unknown and cyclic patterns", func(t *testing.T) {
+		_, err := g.Compile("%{NOPE}")
+		assert.Error(t, err)
+
+		g := flow.NewGrok()
+		assert.NoError(t, g.AddPattern("A", "%{B}"))
+		assert.NoError(t, g.AddPattern("B", "%{A}"))
+		_, err = g.Compile("%{A}")
+		assert.Error(t, err)
+	})
+}
+
+func TestGrok_StandardPatterns(t *testing.T) {
+	g := flow.NewGrok()
+	for _, name := range []string{
+		"USERNAME", "EMAILADDRESS", "INT", "NUMBER", "BASE16NUM", "BASE16FLOAT", "POSINT", "NONNEGINT", "WORD",
+		"NOTSPACE", "SPACE", "DATA", "GREEDYDATA", "QUOTEDSTRING", "UUID", "URN", "MAC", "IP", "IPV4", "IPV6", "HOSTNAME",
+		"IPORHOST", "HOSTPORT", "PATH", "TTY", "URI", "MONTH", "MONTHNUM2", "DAY", "TIME", "TIMESTAMP_ISO8601",
+		"DATESTAMP", "DATESTAMP_RFC822", "DATESTAMP_RFC2822", "DATESTAMP_OTHER", "DATESTAMP_EVENTLOG",
+		"HTTPDATE", "SYSLOGBASE", "LOGLEVEL", "HTTPDERROR_DATE", "COMBINEDAPACHELOG",
+	} {
+		_, err := g.Compile("%{" + name + "}")
+		assert.NoError(t, err, name)
+	}
+
+	e, err := g.Compile("%{SYSLOGBASE} %{GREEDYDATA:message}")
+	assert.NoError(t, err)
+	fields, ok, _ := e.Match("Jan  2 03:04:05 web1 sshd[811]: Accepted publickey")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]any{
+		"timestamp": "Jan  2 03:04:05",
+		"logsource": "web1",
+		"program":   "sshd",
+		"pid":       "811",
+		"message":   "Accepted publickey",
+	}, fields)
+}
+
+func TestGrokParser_Transform(t *testing.T) {
+	parser, err := flow.NewGrokParser[string](flow.GrokConfig{
+		Expressions: []string{`%{LOGLEVEL:level} %{GREEDYDATA:message}`, `%{INT:code:int}`},
+	}, nil)
+	assert.NoError(t, err)
+
+	in := make(chan string, 3)
+	in <- "ERROR disk full"
+	in <- "42"
+	in <- "???"
+	close(in)
+
+	eventC := make(chan pipeline.Event, 1)
+	var records []map[string]any
+	for r := range parser.Transform(in, eventC) {
+		data, _ := r.Data().Read()
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal(data, &record))
+		records = append(records, record)
+	}
+
+	assert.Equal(t, []map[string]any{{"level": "ERROR", "message": "disk full"}, {"code": float64(42)}}, records)
+	errEvent := (<-eventC).(pipeline.ErrorEvent)
+	assert.ErrorIs(t, errEvent, flow.ErrGrokNoMatch)
+
+	_, err = flow.NewGrokParser[string](flow.GrokConfig{}, nil)
+	assert.Error(t, err)
+}
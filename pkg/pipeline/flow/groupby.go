@@ -0,0 +1,65 @@
+package flow
+
+import (
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that GroupBy implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*GroupBy[any, any])(nil)
+
+// GroupBy is a struct that represents keyed partitioning of a data stream across parallel flows.
+type GroupBy[I, O any] struct {
+	n       uint8
+	split   *HashSplit[I]
+	newFlow func(partition int) pipeline.Flow[I, O]
+}
+
+// NewGroupBy creates a new GroupBy flow with n partitions.
+// Items are assigned to a partition by a hash of their key, and newFlow is called once per
+// partition to create the flow processing it, so a stateful flow sees every item of a key in
+// order while different keys are processed in parallel.
+// If n is less than or equal to 0, it will default to 1, and it is capped at 255.
+func NewGroupBy[I, O any](n int, key func(in I) string, newFlow func(partition int) pipeline.Flow[I, O]) (*GroupBy[I, O], error) {
+	split, err := NewHashSplit(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if newFlow == nil {
+		return nil, errors.New("flow func is nil")
+	}
+
+	return &GroupBy[I, O]{
+		n:       uint8(min(max(n, 1), math.MaxUint8)),
+		split:   split,
+		newFlow: newFlow,
+	}, nil
+}
+
+// Transform partitions the data from the input channel, runs the flow of each partition and
+// merges their outputs into the output channel. The order of items is preserved per key but
+// not across keys. The output channel is closed once every partition flow has closed its output.
+func (g GroupBy[I, O]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan O {
+	out := make(chan O)
+
+	var wg sync.WaitGroup
+	for i, part := range g.split.Split(in, eventC, g.n) {
+		wg.Add(1)
+		go func(flowOut <-chan O) {
+			defer wg.Done()
+			for v := range flowOut {
+				out <- v
+			}
+		}(g.newFlow(i).Transform(part, eventC))
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
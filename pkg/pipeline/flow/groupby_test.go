@@ -0,0 +1,84 @@
+package flow_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestGroupBy_Transform(t *testing.T) {
+	t.Run("processes each key serially in one partition", func(t *testing.T) {
+		var mu sync.Mutex
+		partitions := map[string]map[int]bool{}
+
+		groupBy, err := flow.NewGroupBy(4, func(r reading) string { return r.host }, func(p int) pipeline.Flow[reading, reading] {
+			m, _ := flow.NewMap(func(r reading) (reading, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				if partitions[r.host] == nil {
+					partitions[r.host] = map[int]bool{}
+				}
+				partitions[r.host][p] = true
+				return r, nil
+			})
+			return m
+		})
+		assert.NoError(t, err)
+
+		in := make(chan reading)
+		out := groupBy.Transform(in, nil)
+		go func() {
+			for i := range 100 {
+				in <- reading{host: string(rune('a' + i%5)), value: i}
+			}
+			close(in)
+		}()
+
+		last := map[string]int{}
+		count := 0
+		for r := range out {
+			if prev, ok := last[r.host]; ok {
+				assert.Greater(t, r.value, prev, "items of a key must stay in order")
+			}
+			last[r.host] = r.value
+			count++
+		}
+
+		assert.Equal(t, 100, count)
+		assert.Len(t, partitions, 5)
+		for host, p := range partitions {
+			assert.Len(t, p, 1, "key %s was processed by more than one partition", host)
+		}
+	})
+
+	t.Run("defaults to a single partition", func(t *testing.T) {
+		groupBy, err := flow.NewGroupBy(0, func(s string) string { return s }, func(int) pipeline.Flow[string, string] {
+			return flow.NewBuffer[string](1)
+		})
+		assert.NoError(t, err)
+
+		in := make(chan string, 3)
+		in <- "a"
+		in <- "b"
+		in <- "c"
+		close(in)
+
+		var result []string
+		for v := range groupBy.Transform(in, nil) {
+			result = append(result, v)
+		}
+
+		assert.Equal(t, []string{"a", "b", "c"}, result)
+	})
+}
+
+func TestNewGroupBy_Validation(t *testing.T) {
+	_, err := flow.NewGroupBy[string, string](2, nil, func(int) pipeline.Flow[string, string] { return flow.NewBuffer[string](1) })
+	assert.Error(t, err)
+
+	_, err = flow.NewGroupBy[string, string](2, func(s string) string { return s }, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,164 @@
+package flow
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that HashFields implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*HashFields[any])(nil)
+
+// HashFieldsConfig is the configuration of a field hashing flow.
+type HashFieldsConfig struct {
+	Fields []string // paths of the fields to hash, in the notation of ProjectionConfig, required
+	Salt   []byte   // HMAC key, hashes are only comparable between flows with the same salt, required
+}
+
+// HashFields is a struct that represents replacement of the fields of JSON records on a data stream by their hashes.
+type HashFields[I any] struct {
+	fields     [][]fieldSegment
+	salt       []byte
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewHashFields creates a new HashFields flow with the given configuration.
+// Items whose payload is not a JSON object are passed to deadLetter when it is not nil.
+func NewHashFields[I any](conf HashFieldsConfig, deadLetter DeadLetterFunc[I]) (*HashFields[I], error) {
+	if len(conf.Fields) == 0 {
+		return nil, errors.New("no fields to hash")
+	}
+
+	if len(conf.Salt) == 0 {
+		return nil, errors.New("salt is empty")
+	}
+
+	h := &HashFields[I]{
+		salt:       conf.Salt,
+		deadLetter: deadLetter,
+	}
+	for _, expr := range conf.Fields {
+		path, err := parseFieldPath(expr)
+		if err != nil {
+			return nil, err
+		}
+		h.fields = append(h.fields, path)
+	}
+	return h, nil
+}
+
+// Transform replaces the selected fields of the JSON objects from the input channel by the hex encoded
+// HMAC-SHA256 of their value and returns the output channel of records holding the objects. Strings are
+// hashed as is and other values as their JSON encoding, so equal values always have equal hashes, while
+// null and missing fields are left alone. The payload is the data of a DataReadable, the raw message of a
+// RawReadable, the bytes of a Readable, []byte or string, and the raw message of a RawReadable or the
+// payload itself is retained as the raw message.
+// Items that are not JSON objects are dropped and reported with an error event carrying them as the record.
+func (h HashFields[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		mac := hmac.New(sha256.New, h.salt)
+		for v := range in {
+			record, err := h.record(v, mac)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("hash fields error", err, false, v))
+				if h.deadLetter != nil {
+					h.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- record
+		}
+	}()
+	return out
+}
+
+// record hashes the selected fields of the payload of the item into a record
+func (h HashFields[I]) record(v I, mac hash.Hash) (pipeline.DataRawReadable, error) {
+	b, err := payload(v)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := decodeObject(b)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range h.fields {
+		err = visitPath(obj, path, func(value any) (any, error) {
+			var p []byte
+			switch value := value.(type) {
+			case nil:
+				return nil, nil
+			case string:
+				p = []byte(value)
+			default:
+				var err error
+				if p, err = json.Marshal(value); err != nil {
+					return nil, err
+				}
+			}
+			mac.Reset()
+			mac.Write(p)
+			return hex.EncodeToString(mac.Sum(nil)), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.DeriveRecord(pipeline.Bytes(data), rawOrPayload(v, b), v), nil
+}
+
+// visitPath replaces the values of v selected by the path with the result of fn
+func visitPath(v any, path []fieldSegment, fn func(any) (any, error)) error {
+	seg, last := path[0], len(path) == 1
+	switch c := v.(type) {
+	case map[string]any:
+		for key, child := range c {
+			if !seg.matchKey(key) {
+				continue
+			}
+			if !last {
+				if err := visitPath(child, path[1:], fn); err != nil {
+					return err
+				}
+				continue
+			}
+			value, err := fn(child)
+			if err != nil {
+				return err
+			}
+			c[key] = value
+		}
+	case []any:
+		for i, child := range c {
+			if !seg.matchIndex(i) {
+				continue
+			}
+			if !last {
+				if err := visitPath(child, path[1:], fn); err != nil {
+					return err
+				}
+				continue
+			}
+			value, err := fn(child)
+			if err != nil {
+				return err
+			}
+			c[i] = value
+		}
+	}
+	return nil
+}
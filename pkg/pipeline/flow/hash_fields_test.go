@@ -0,0 +1,92 @@
+package flow_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// hmacHex returns the hex encoded HMAC-SHA256 of the value
+func hmacHex(salt, value string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// hashFields sends the payloads through a field hashing flow and returns the decoded records
+func hashFields(t *testing.T, conf flow.HashFieldsConfig, payloads ...string) []map[string]any {
+	t.Helper()
+
+	h, err := flow.NewHashFields[string](conf, nil)
+	assert.NoError(t, err)
+
+	in := make(chan string, len(payloads))
+	for _, p := range payloads {
+		in <- p
+	}
+	close(in)
+
+	var records []map[string]any
+	for r := range h.Transform(in, nil) {
+		data, _ := r.Data().Read()
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal(data, &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestHashFields_Transform(t *testing.T) {
+	conf := flow.HashFieldsConfig{Fields: []string{"user", "hosts[*].ip", "uid", "missing", "empty"}, Salt: []byte("pepper")}
+	records := hashFields(t, conf,
+		`{"user":"alice","uid":1001,"hosts":[{"ip":"10.0.0.1","name":"db1"}],"empty":null,"action":"login"}`,
+		`{"user":"alice","hosts":"not an array"}`,
+	)
+
+	assert.Equal(t, []map[string]any{
+		{
+			"user":   hmacHex("pepper", "alice"),
+			"uid":    hmacHex("pepper", "1001"),
+			"hosts":  []any{map[string]any{"ip": hmacHex("pepper", "10.0.0.1"), "name": "db1"}},
+			"empty":  nil,
+			"action": "login",
+		},
+		// the same user correlates across records
+		{"user": hmacHex("pepper", "alice"), "hosts": "not an array"},
+	}, records)
+
+	salted := hashFields(t, flow.HashFieldsConfig{Fields: []string{"user"}, Salt: []byte("other")}, `{"user":"alice"}`)
+	assert.NotEqual(t, records[0]["user"], salted[0]["user"])
+}
+
+func TestHashFields_NotObject(t *testing.T) {
+	h, err := flow.NewHashFields[string](flow.HashFieldsConfig{Fields: []string{"a"}, Salt: []byte("s")}, nil)
+	assert.NoError(t, err)
+
+	in := make(chan string, 1)
+	in <- "alice"
+	close(in)
+
+	eventC := make(chan pipeline.Event, 1)
+	for range h.Transform(in, eventC) {
+		t.Fatal("unexpected record")
+	}
+	assert.ErrorIs(t, (<-eventC).(pipeline.ErrorEvent), flow.ErrNotObject)
+}
+
+func TestNewHashFields_Validation(t *testing.T) {
+	for _, conf := range []flow.HashFieldsConfig{
+		{Salt: []byte("s")},
+		{Fields: []string{"a"}},
+		{Fields: []string{"a..b"}, Salt: []byte("s")},
+	} {
+		_, err := flow.NewHashFields[string](conf, nil)
+		assert.Error(t, err, conf)
+	}
+}
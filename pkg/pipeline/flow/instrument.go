@@ -0,0 +1,78 @@
+package flow
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Instrumented implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*Instrumented[any, any])(nil)
+
+// MetricFlowLatency is the metric name of the latency recorded by the instrumented flow.
+const MetricFlowLatency = "krapht_flow_latency_seconds"
+
+// Instrumented is a struct that represents the measuring of the latency of a wrapped flow.
+type Instrumented[I, O any] struct {
+	name  string
+	inner pipeline.Flow[I, O]
+}
+
+// Instrument creates a new Instrumented flow wrapping the inner flow, whose latencies are labelled with
+// the name as the "flow" label. The inner flow is used unchanged.
+func Instrument[I, O any](name string, inner pipeline.Flow[I, O]) (*Instrumented[I, O], error) {
+	if name == "" {
+		return nil, errors.New("name is empty")
+	}
+
+	if inner == nil {
+		return nil, errors.New("inner flow is nil")
+	}
+
+	return &Instrumented[I, O]{
+		name:  name,
+		inner: inner,
+	}, nil
+}
+
+// Transform passes the items from the input channel through the inner flow, timestamping them when the
+// inner flow takes them and when it emits its output. The latency of each output, from the entry of the
+// first item taken since the previous output, or from the previous output when no item was taken since,
+// is sent as a histogram metric event of the seconds; events are dropped rather than slowing the flow
+// when the event channel is full. The latencies assume the inner flow processes an item at a time, so
+// the time spent on items it drops is added to the latency of the next output.
+func (f Instrumented[I, O]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan O {
+	// entry time of the first item taken since the previous output, in unix nanoseconds
+	var entered atomic.Int64
+
+	inner := make(chan I)
+	go func() {
+		defer close(inner)
+		for v := range in {
+			inner <- v
+			entered.CompareAndSwap(0, time.Now().UnixNano())
+		}
+	}()
+
+	labels := map[string]string{"flow": f.name}
+	out := make(chan O)
+	go func() {
+		defer close(out)
+		var since int64 // start of the latency of the next output
+		for v := range f.inner.Transform(inner, eventC) {
+			now := time.Now().UnixNano()
+			if t := entered.Swap(0); t != 0 {
+				since = t
+			}
+			if since != 0 {
+				latency := time.Duration(max(now-since, 0))
+				pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricFlowLatency, latency.Seconds(), labels, pipeline.MetricTypeHistogram))
+			}
+			since = now
+			out <- v
+		}
+	}()
+	return out
+}
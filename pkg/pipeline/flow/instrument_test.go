@@ -0,0 +1,66 @@
+package flow_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestInstrument(t *testing.T) {
+	slow, err := flow.NewMap(func(in int) (int, error) {
+		if in == 2 {
+			return 0, errors.New("dropped")
+		}
+		time.Sleep(5 * time.Millisecond)
+		return in * 10, nil
+	})
+	assert.NoError(t, err)
+
+	f, err := flow.Instrument[int, int]("slow", slow)
+	assert.NoError(t, err)
+
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 1; i <= 3; i++ {
+			in <- i
+		}
+	}()
+
+	eventC := make(chan pipeline.Event, 10)
+	var got []int
+	for v := range f.Transform(in, eventC) {
+		got = append(got, v)
+	}
+	close(eventC)
+	assert.Equal(t, []int{10, 30}, got)
+
+	var latencies []float64
+	for e := range eventC {
+		m, ok := e.(pipeline.Measurable)
+		if !ok {
+			continue
+		}
+		assert.Equal(t, flow.MetricFlowLatency, m.Name())
+		assert.Equal(t, string(pipeline.MetricTypeHistogram), m.MetricType())
+		assert.Equal(t, map[string]string{"flow": "slow"}, m.Labels())
+		latencies = append(latencies, m.Value())
+	}
+	assert.Len(t, latencies, 2)
+	for _, l := range latencies {
+		assert.GreaterOrEqual(t, l, 0.004)
+	}
+}
+
+func TestInstrumentErrors(t *testing.T) {
+	_, err := flow.Instrument[int, int]("", flow.NewPassthrough[int]())
+	assert.Error(t, err)
+
+	_, err = flow.Instrument[int, int]("nil", nil)
+	assert.Error(t, err)
+}
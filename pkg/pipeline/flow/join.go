@@ -0,0 +1,225 @@
+package flow
+
+import (
+	"container/heap"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// MetricJoinUnmatched is the counter of items that expired without a match, labelled by side.
+const MetricJoinUnmatched = "krapht_join_unmatched_total"
+
+// JoinResult is a pair of items of two streams with the same key, or an item that expired without
+// a match when unmatched items are emitted, whose other side is nil.
+type JoinResult[L, R any] struct {
+	Key   string
+	Left  *L
+	Right *R
+}
+
+// JoinConfig is the configuration of a stream join.
+type JoinConfig[L, R any] struct {
+	LeftKey  func(in L) string // key of a left item, required
+	RightKey func(in R) string // key of a right item, required
+
+	// LeftTime and RightTime return the event time of an item. When nil, items are joined by
+	// arrival time; set both or neither.
+	LeftTime  func(in L) time.Time
+	RightTime func(in R) time.Time
+
+	Window time.Duration // items match when their times are at most this far apart, required
+
+	EmitUnmatched bool // emit items that expire without a match, as in an outer join
+	MaxItems      int  // items held per side at most, the oldest expire early when exceeded, defaults to 10000
+}
+
+// Join is a struct that represents joining of two data streams by key within a time window.
+type Join[L, R any] struct {
+	conf JoinConfig[L, R]
+}
+
+// NewJoin creates a new Join with the given configuration.
+func NewJoin[L, R any](conf JoinConfig[L, R]) (*Join[L, R], error) {
+	if conf.LeftKey == nil || conf.RightKey == nil {
+		return nil, errors.New("key func is nil")
+	}
+
+	if (conf.LeftTime == nil) != (conf.RightTime == nil) {
+		return nil, errors.New("time funcs must be set for both sides or neither")
+	}
+
+	if conf.Window <= 0 {
+		return nil, errors.New("join window must be positive")
+	}
+
+	if conf.MaxItems <= 0 {
+		conf.MaxItems = 10000
+	}
+
+	return &Join[L, R]{
+		conf: conf,
+	}, nil
+}
+
+// joinEntry is an item held for matching
+type joinEntry[T any] struct {
+	v       T
+	key     string
+	ts      int64
+	matched bool
+}
+
+// joinSide holds the items of one side by key and by time for expiry
+type joinSide[T any] struct {
+	byKey  map[string][]*joinEntry[T]
+	expiry reorderHeap[*joinEntry[T]]
+	seq    int
+}
+
+// newJoinSide creates an empty side
+func newJoinSide[T any]() *joinSide[T] {
+	return &joinSide[T]{byKey: make(map[string][]*joinEntry[T])}
+}
+
+// add holds an entry
+func (s *joinSide[T]) add(e *joinEntry[T]) {
+	s.seq++
+	s.byKey[e.key] = append(s.byKey[e.key], e)
+	heap.Push(&s.expiry, reorderItem[*joinEntry[T]]{v: e, ts: e.ts, seq: s.seq})
+}
+
+// pop removes the oldest entry
+func (s *joinSide[T]) pop() *joinEntry[T] {
+	e := heap.Pop(&s.expiry).(reorderItem[*joinEntry[T]]).v
+	entries := slices.DeleteFunc(s.byKey[e.key], func(o *joinEntry[T]) bool { return o == e })
+	if len(entries) == 0 {
+		delete(s.byKey, e.key)
+	} else {
+		s.byKey[e.key] = entries
+	}
+	return e
+}
+
+// oldest returns the time of the oldest entry, or maxInt64 when there is none
+func (s *joinSide[T]) oldest() int64 {
+	if len(s.expiry) == 0 {
+		return maxInt64
+	}
+	return s.expiry[0].ts
+}
+
+// Join matches the items from the left and right input channels and returns the output channel of pairs.
+// A pair is emitted for every left and right item with the same key whose times are at most the window
+// apart, as soon as the later of them arrives. Items expire once the latest time seen on either side is
+// more than the window past theirs, and all held items expire when both input channels are closed.
+// Items expiring without a match are counted with a metric event and emitted alone when configured.
+func (j Join[L, R]) Join(left <-chan L, right <-chan R, eventC chan<- pipeline.Event) <-chan JoinResult[L, R] {
+	out := make(chan JoinResult[L, R])
+
+	go func() {
+		defer close(out)
+
+		lefts, rights := newJoinSide[L](), newJoinSide[R]()
+		window := j.conf.Window.Nanoseconds()
+		clock := int64(minInt64)
+		var unmatchedLeft, unmatchedRight float64
+
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+
+		// expire removes the entries older than the window before the time
+		expire := func(now int64) {
+			for lefts.oldest() < now-window || len(lefts.expiry) > j.conf.MaxItems {
+				if e := lefts.pop(); !e.matched {
+					unmatchedLeft++
+					pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricJoinUnmatched, unmatchedLeft,
+						map[string]string{"side": "left"}, pipeline.MetricTypeCounter))
+					if j.conf.EmitUnmatched {
+						out <- JoinResult[L, R]{Key: e.key, Left: &e.v}
+					}
+				}
+			}
+			for rights.oldest() < now-window || len(rights.expiry) > j.conf.MaxItems {
+				if e := rights.pop(); !e.matched {
+					unmatchedRight++
+					pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricJoinUnmatched, unmatchedRight,
+						map[string]string{"side": "right"}, pipeline.MetricTypeCounter))
+					if j.conf.EmitUnmatched {
+						out <- JoinResult[L, R]{Key: e.key, Right: &e.v}
+					}
+				}
+			}
+		}
+
+		// schedule sets the timer for the next arrival time expiry
+		schedule := func() {
+			timer.Stop()
+			if j.conf.LeftTime != nil {
+				return
+			}
+			if next := min(lefts.oldest(), rights.oldest()); next != maxInt64 {
+				timer.Reset(time.Until(time.Unix(0, next+window+1)))
+			}
+		}
+
+		// within reports whether two times are at most the window apart
+		within := func(a, b int64) bool {
+			return a-b <= window && b-a <= window
+		}
+
+		for left != nil || right != nil {
+			select {
+			case v, ok := <-left:
+				if !ok {
+					left = nil
+					continue
+				}
+				n := time.Now().UnixNano()
+				if j.conf.LeftTime != nil {
+					n = j.conf.LeftTime(v).UnixNano()
+				}
+				e := &joinEntry[L]{v: v, key: j.conf.LeftKey(v), ts: n}
+				for _, r := range rights.byKey[e.key] {
+					if within(n, r.ts) {
+						e.matched, r.matched = true, true
+						out <- JoinResult[L, R]{Key: e.key, Left: &e.v, Right: &r.v}
+					}
+				}
+				lefts.add(e)
+				clock = max(clock, n)
+				expire(clock)
+				schedule()
+			case v, ok := <-right:
+				if !ok {
+					right = nil
+					continue
+				}
+				n := time.Now().UnixNano()
+				if j.conf.RightTime != nil {
+					n = j.conf.RightTime(v).UnixNano()
+				}
+				e := &joinEntry[R]{v: v, key: j.conf.RightKey(v), ts: n}
+				for _, l := range lefts.byKey[e.key] {
+					if within(l.ts, n) {
+						e.matched, l.matched = true, true
+						out <- JoinResult[L, R]{Key: e.key, Left: &l.v, Right: &e.v}
+					}
+				}
+				rights.add(e)
+				clock = max(clock, n)
+				expire(clock)
+				schedule()
+			case <-timer.C:
+				clock = max(clock, time.Now().UnixNano())
+				expire(clock)
+				schedule()
+			}
+		}
+		expire(maxInt64)
+	}()
+	return out
+}
@@ -0,0 +1,142 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// dnsAnswer and connLog are the items of the join tests, with event times in seconds
+type dnsAnswer struct {
+	name, ip string
+	sec      int64
+}
+
+type connLog struct {
+	ip  string
+	sec int64
+}
+
+// joinConfig returns the configuration of an event time join of answers with later connections to their ip
+func joinConfig(window time.Duration) flow.JoinConfig[dnsAnswer, connLog] {
+	return flow.JoinConfig[dnsAnswer, connLog]{
+		LeftKey:   func(a dnsAnswer) string { return a.ip },
+		RightKey:  func(c connLog) string { return c.ip },
+		LeftTime:  func(a dnsAnswer) time.Time { return time.Unix(a.sec, 0) },
+		RightTime: func(c connLog) time.Time { return time.Unix(c.sec, 0) },
+		Window:    window,
+	}
+}
+
+// pair is a join result reduced to comparable values, with "-" for a missing side
+type pair struct{ left, right string }
+
+func pairOf(r flow.JoinResult[dnsAnswer, connLog]) pair {
+	p := pair{"-", "-"}
+	if r.Left != nil {
+		p.left = r.Left.name
+	}
+	if r.Right != nil {
+		p.right = r.Right.ip
+	}
+	return p
+}
+
+func TestJoin_Join(t *testing.T) {
+	t.Run("pairs items with the same key within the window", func(t *testing.T) {
+		j, err := flow.NewJoin(joinConfig(10 * time.Second))
+		assert.NoError(t, err)
+
+		left, right := make(chan dnsAnswer), make(chan connLog)
+		eventC := make(chan pipeline.Event, 8)
+		out := j.Join(left, right, eventC)
+
+		left <- dnsAnswer{"example.com", "192.0.2.1", 100}
+		left <- dnsAnswer{"example.org", "192.0.2.2", 101}
+		right <- connLog{"192.0.2.1", 105}
+		assert.Equal(t, pair{"example.com", "192.0.2.1"}, pairOf(<-out))
+
+		right <- connLog{"192.0.2.1", 108} // matches again
+		assert.Equal(t, pair{"example.com", "192.0.2.1"}, pairOf(<-out))
+
+		right <- connLog{"192.0.2.9", 109} // no answer
+		right <- connLog{"192.0.2.2", 125} // expires everything but itself
+		close(left)
+		close(right)
+
+		_, ok := <-out
+		assert.False(t, ok)
+		close(eventC)
+
+		unmatched := make(map[string]float64)
+		for e := range eventC {
+			m := e.(pipeline.MetricEvent)
+			assert.Equal(t, flow.MetricJoinUnmatched, m.Name())
+			unmatched[m.Labels()["side"]] = m.Value()
+		}
+		assert.Equal(t, map[string]float64{"left": 1, "right": 2}, unmatched)
+	})
+
+	t.Run("emits unmatched items", func(t *testing.T) {
+		conf := joinConfig(10 * time.Second)
+		conf.EmitUnmatched = true
+		j, err := flow.NewJoin(conf)
+		assert.NoError(t, err)
+
+		left, right := make(chan dnsAnswer), make(chan connLog)
+		out := j.Join(left, right, nil)
+
+		left <- dnsAnswer{"example.com", "192.0.2.1", 100}
+		right <- connLog{"192.0.2.1", 200}
+		assert.Equal(t, pair{"example.com", "-"}, pairOf(<-out))
+
+		close(left)
+		close(right)
+		assert.Equal(t, pair{"-", "192.0.2.1"}, pairOf(<-out))
+		_, ok := <-out
+		assert.False(t, ok)
+	})
+
+	t.Run("expires by arrival time", func(t *testing.T) {
+		j, err := flow.NewJoin(flow.JoinConfig[string, string]{
+			LeftKey:       func(s string) string { return s },
+			RightKey:      func(s string) string { return s },
+			Window:        20 * time.Millisecond,
+			EmitUnmatched: true,
+		})
+		assert.NoError(t, err)
+
+		left, right := make(chan string), make(chan string)
+		defer close(left)
+		defer close(right)
+		out := j.Join(left, right, nil)
+
+		left <- "a"
+		select {
+		case r := <-out:
+			assert.Equal(t, "a", *r.Left)
+			assert.Nil(t, r.Right)
+		case <-time.After(time.Second):
+			t.Fatal("unmatched item did not expire")
+		}
+	})
+}
+
+func TestNewJoin_Validation(t *testing.T) {
+	conf := joinConfig(0)
+	_, err := flow.NewJoin(conf)
+	assert.Error(t, err)
+
+	conf = joinConfig(time.Second)
+	conf.RightKey = nil
+	_, err = flow.NewJoin(conf)
+	assert.Error(t, err)
+
+	conf = joinConfig(time.Second)
+	conf.RightTime = nil
+	_, err = flow.NewJoin(conf)
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrMalformedKV is the error of a message that is not valid key=value pairs.
+var ErrMalformedKV = errors.New("malformed kv message")
+
+// KVFields is the structured data of a message of key=value pairs as the spans of the pairs in the
+// message, parsed without allocating. It is reused by parsing into it again. Quoted values are escaped.
+type KVFields struct {
+	// Fields are the pairs of the message, in order.
+	Fields []Field
+
+	scratch []byte // unescaped values, reused
+}
+
+// NewKVParser creates a new Parser flow decoding messages of key=value pairs into records holding the
+// pairs as a JSON object. Messages that fail to parse are passed to deadLetter when it is not nil.
+func NewKVParser[I any](deadLetter DeadLetterFunc[I]) (*Parser[I], error) {
+	return NewParser("kv", func(raw []byte) (any, error) {
+		return ParseKV(raw)
+	}, deadLetter)
+}
+
+// NewFastKVParser creates a new Parser flow decoding messages of key=value pairs like the parser of
+// NewKVParser, through reused KVFields rather than a map for each message. Messages that fail to parse
+// are passed to deadLetter when it is not nil.
+func NewFastKVParser[I any](deadLetter DeadLetterFunc[I]) (*Parser[I], error) {
+	pool := sync.Pool{New: func() any { return new(KVFields) }}
+	return NewAppendParser("kv", func(dst, raw []byte) ([]byte, error) {
+		f := pool.Get().(*KVFields)
+		defer pool.Put(f)
+		if err := f.Parse(raw); err != nil {
+			return nil, err
+		}
+		return f.AppendJSON(dst, raw), nil
+	}, deadLetter)
+}
+
+// ParseKV parses a message of whitespace separated key=value pairs into a map. Values may be double
+// quoted to hold whitespace, escaping quotes and backslashes with a backslash. A key without a value is
+// given an empty value, and the last value of a repeated key is kept.
+func ParseKV(raw []byte) (map[string]string, error) {
+	var f KVFields
+	if err := f.Parse(raw); err != nil {
+		return nil, err
+	}
+	return f.Map(raw), nil
+}
+
+// Parse parses a message of key=value pairs into the fields, as ParseKV does.
+// The spans of the fields are only valid for the message.
+func (f *KVFields) Parse(raw []byte) error {
+	f.Fields = f.Fields[:0]
+	f.scratch = f.scratch[:0]
+
+	for i := 0; i < len(raw); {
+		if isKVSpace(raw[i]) {
+			i++
+			continue
+		}
+
+		key := Span{i, i}
+		for i < len(raw) && raw[i] != '=' && !isKVSpace(raw[i]) {
+			i++
+		}
+		key.End = i
+		if key.Len() == 0 {
+			return fmt.Errorf("%w: empty key at %d", ErrMalformedKV, i)
+		}
+		if i == len(raw) || raw[i] != '=' {
+			f.Fields = append(f.Fields, Field{Key: key, Value: Span{i, i}})
+			continue
+		}
+		i++
+
+		if i < len(raw) && raw[i] == '"' {
+			value := Span{i + 1, i + 1}
+			for i++; i < len(raw) && raw[i] != '"'; i++ {
+				if raw[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(raw) {
+				return fmt.Errorf("%w: unterminated value of %q", ErrMalformedKV, key.Of(raw))
+			}
+			value.End = i
+			i++
+			f.Fields = append(f.Fields, Field{Key: key, Value: value})
+			continue
+		}
+
+		value := Span{i, i}
+		for i < len(raw) && !isKVSpace(raw[i]) {
+			i++
+		}
+		value.End = i
+		f.Fields = append(f.Fields, Field{Key: key, Value: value})
+	}
+	return nil
+}
+
+// isKVSpace reports whether the byte separates pairs
+func isKVSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// Map returns the pairs of the fields of the message as a map.
+func (f *KVFields) Map(raw []byte) map[string]string {
+	pairs := make(map[string]string, len(f.Fields))
+	for _, field := range f.Fields {
+		pairs[string(field.Key.Of(raw))] = string(appendKVValue(nil, raw, field.Value))
+	}
+	return pairs
+}
+
+// AppendJSON appends the JSON object of the pairs of the fields of the message to dst.
+func (f *KVFields) AppendJSON(dst, raw []byte) []byte {
+	dst = append(dst, '{')
+	for i, field := range f.Fields {
+		f.scratch = appendKVValue(f.scratch[:0], raw, field.Value)
+		dst = appendJSONKey(dst, field.Key.Of(raw), i == 0)
+		dst = appendJSONString(dst, f.scratch)
+	}
+	return append(dst, '}')
+}
+
+// appendKVValue appends a value, unescaping it if it is quoted
+func appendKVValue(dst, raw []byte, s Span) []byte {
+	v := s.Of(raw)
+	if s.Start == 0 || raw[s.Start-1] != '"' {
+		return append(dst, v...)
+	}
+	for i := 0; i < len(v); i++ {
+		if v[i] == '\\' && i+1 < len(v) {
+			i++
+		}
+		dst = append(dst, v[i])
+	}
+	return dst
+}
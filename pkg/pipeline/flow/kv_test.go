@@ -0,0 +1,126 @@
+package flow_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestParseKV(t *testing.T) {
+	t.Run("parses bare and quoted values", func(t *testing.T) {
+		pairs, err := flow.ParseKV([]byte(`level=info msg="user \"alice\" logged in" path=C:\temp empty= flag user=bob user=eve` + "\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"level": "info",
+			"msg":   `user "alice" logged in`,
+			"path":  `C:\temp`,
+			"empty": "",
+			"flag":  "",
+			"user":  "eve",
+		}, pairs)
+	})
+
+	t.Run("rejects malformed messages", func(t *testing.T) {
+		for _, raw := range []string{
+			"=value",
+			`msg="unterminated`,
+			`msg="escaped end\"`,
+		} {
+			_, err := flow.ParseKV([]byte(raw))
+			assert.ErrorIs(t, err, flow.ErrMalformedKV, raw)
+		}
+	})
+}
+
+func TestKVFields_Parse(t *testing.T) {
+	raw := []byte(`ts=2026-01-02T03:04:05Z level=warn msg="disk <90%> full" host=db1 bytes=1024`)
+	var f flow.KVFields
+	require.NoError(t, f.Parse(raw))
+	assert.Len(t, f.Fields, 5)
+	assert.Equal(t, "msg", string(f.Fields[2].Key.Of(raw)))
+	assert.Equal(t, "disk <90%> full", string(f.Fields[2].Value.Of(raw)))
+
+	encoded, err := json.Marshal(f.Map(raw))
+	require.NoError(t, err)
+	assert.JSONEq(t, string(encoded), string(f.AppendJSON(nil, raw)))
+
+	dst := make([]byte, 0, 1024)
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = f.Parse(raw)
+		dst = f.AppendJSON(dst[:0], raw)
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestKVParser_Transform(t *testing.T) {
+	newParsers := map[string]func(flow.DeadLetterFunc[string]) (*flow.Parser[string], error){
+		"map":    flow.NewKVParser[string],
+		"fields": flow.NewFastKVParser[string],
+	}
+	for name, newParser := range newParsers {
+		t.Run(name, func(t *testing.T) {
+			var letters []string
+			parser, err := newParser(func(raw string, _ error) {
+				letters = append(letters, raw)
+			})
+			assert.NoError(t, err)
+
+			in := make(chan string, 2)
+			in <- `level=info msg="started"`
+			in <- "=bad"
+			close(in)
+
+			var result []pipeline.DataRawReadable
+			for r := range parser.Transform(in, nil) {
+				result = append(result, r)
+			}
+
+			assert.Len(t, result, 1)
+			data, err := result[0].Data().Read()
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"level":"info","msg":"started"}`, string(data))
+			assert.Equal(t, []string{"=bad"}, letters)
+		})
+	}
+}
+
+func TestKVParser_Metadata(t *testing.T) {
+	parser, err := flow.NewFastKVParser[pipeline.Record](nil)
+	assert.NoError(t, err)
+
+	meta := pipeline.Metadata{Source: "http", Tenant: "acme"}
+	in := make(chan pipeline.Record, 1)
+	in <- pipeline.NewRecord(pipeline.Bytes("level=info"), pipeline.Bytes("level=info")).WithMetadata(meta)
+	close(in)
+
+	// the records parsed keep the metadata of the envelopes of their raw messages
+	for r := range parser.Transform(in, nil) {
+		got, ok := pipeline.MetadataOf(r)
+		assert.True(t, ok)
+		assert.Equal(t, meta, got)
+	}
+}
+
+func BenchmarkParseKV(b *testing.B) {
+	raw := []byte(`ts=2026-01-02T03:04:05Z level=warn msg="disk 90% full" host=db1 bytes=1024 user=alice`)
+	b.Run("map", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			pairs, _ := flow.ParseKV(raw)
+			_, _ = json.Marshal(pairs)
+		}
+	})
+	b.Run("fields", func(b *testing.B) {
+		b.ReportAllocs()
+		var f flow.KVFields
+		dst := make([]byte, 0, 1024)
+		for b.Loop() {
+			_ = f.Parse(raw)
+			dst = f.AppendJSON(dst[:0], raw)
+		}
+	})
+}
@@ -0,0 +1,230 @@
+package flow
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that LookupTable implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*LookupTable[any, any])(nil)
+
+// MetricLookupTableKeys is the gauge of the keys in the lookup table after each load.
+const MetricLookupTableKeys = "krapht_lookup_table_keys"
+
+// TableLoader loads the whole lookup table, the value of each key.
+type TableLoader[V any] func(ctx context.Context) (map[string]V, error)
+
+// LookupTableConfig is the configuration of a lookup table join flow.
+type LookupTableConfig[I, V any] struct {
+	// Key returns the lookup key of an item, or false for items that are passed unchanged, required.
+	Key func(in I) (string, bool)
+	// Load loads the table, required. See LoadCSVTable, LoadJSONTable and LoadKVTable.
+	Load TableLoader[V]
+	// Merge returns the item enriched with the value of its key, required.
+	Merge func(in I, value V) I
+
+	Refresh time.Duration // interval between reloads of the table, zero loads it once
+	Timeout time.Duration // deadline of a load, defaults to 30s
+}
+
+// LookupTable is a struct that represents enrichment of a data stream from a local lookup table.
+type LookupTable[I, V any] struct {
+	conf LookupTableConfig[I, V]
+}
+
+// NewLookupTable creates a new LookupTable flow with the given configuration.
+func NewLookupTable[I, V any](conf LookupTableConfig[I, V]) (*LookupTable[I, V], error) {
+	if conf.Key == nil {
+		return nil, errors.New("key func is nil")
+	}
+
+	if conf.Load == nil {
+		return nil, errors.New("load func is nil")
+	}
+
+	if conf.Merge == nil {
+		return nil, errors.New("merge func is nil")
+	}
+
+	if conf.Refresh < 0 {
+		conf.Refresh = 0
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 30 * time.Second
+	}
+
+	return &LookupTable[I, V]{
+		conf: conf,
+	}, nil
+}
+
+// Transform enriches the data from the input channel with the values of their keys in the table and
+// returns the output channel. The table is loaded before the first item is read and reloaded every
+// refresh interval in the background, replacing the table in use at once when the load completes.
+// Items without a key or whose key is not in the table are passed unchanged. A failed load is sent
+// as an error event and the previous table, empty at first, stays in use until the next reload.
+func (l LookupTable[I, V]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+
+	go func() {
+		defer close(out)
+
+		var table atomic.Pointer[map[string]V]
+		table.Store(new(map[string]V))
+		l.load(&table, eventC)
+
+		if l.conf.Refresh > 0 {
+			done := make(chan struct{})
+			var wg sync.WaitGroup
+			defer wg.Wait()
+			defer close(done)
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ticker := time.NewTicker(l.conf.Refresh)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ticker.C:
+						l.load(&table, eventC)
+					case <-done:
+						return
+					}
+				}
+			}()
+		}
+
+		for v := range in {
+			if key, ok := l.conf.Key(v); ok {
+				if value, ok := (*table.Load())[key]; ok {
+					v = l.conf.Merge(v, value)
+				}
+			}
+			out <- v
+		}
+	}()
+	return out
+}
+
+// load loads the table and swaps it in, keeping the previous table on failure
+func (l LookupTable[I, V]) load(table *atomic.Pointer[map[string]V], eventC chan<- pipeline.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), l.conf.Timeout)
+	defer cancel()
+
+	t, err := l.conf.Load(ctx)
+	if err != nil {
+		pipeline.SendEvent(eventC, pipeline.NewErrorEvent("lookup table: could not load table", err, true))
+		return
+	}
+	if t == nil {
+		t = make(map[string]V)
+	}
+	table.Store(&t)
+	pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricLookupTableKeys, float64(len(t)), nil, pipeline.MetricTypeGauge))
+}
+
+// LoadCSVTable returns a loader of a CSV file with a header row, whose rows are keyed by the key column.
+// The value of a key is its row as a map of the column names to the fields, and later rows replace
+// earlier rows with the same key.
+func LoadCSVTable(path, keyColumn string) TableLoader[map[string]string] {
+	return func(_ context.Context) (map[string]map[string]string, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		r := csv.NewReader(f)
+		header, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("could not read csv header: %w", err)
+		}
+
+		keyIndex := -1
+		for i, name := range header {
+			if name == keyColumn {
+				keyIndex = i
+				break
+			}
+		}
+		if keyIndex < 0 {
+			return nil, fmt.Errorf("key column %q not in csv header", keyColumn)
+		}
+
+		table := make(map[string]map[string]string)
+		for {
+			record, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				return table, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			row := make(map[string]string, len(header))
+			for i, name := range header {
+				row[name] = record[i]
+			}
+			table[record[keyIndex]] = row
+		}
+	}
+}
+
+// LoadJSONTable returns a loader of a JSON file holding an object of the values by key.
+func LoadJSONTable[V any](path string) TableLoader[V] {
+	return func(_ context.Context) (map[string]V, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var table map[string]V
+		if err := json.Unmarshal(b, &table); err != nil {
+			return nil, fmt.Errorf("could not decode json table: %w", err)
+		}
+		return table, nil
+	}
+}
+
+// LoadKVTable returns a loader of the keys of a NATS key value bucket, whose values are JSON encoded.
+func LoadKVTable[V any](kv jetstream.KeyValue) TableLoader[V] {
+	return func(ctx context.Context) (map[string]V, error) {
+		watcher, err := kv.WatchAll(ctx, jetstream.IgnoreDeletes())
+		if err != nil {
+			return nil, err
+		}
+		defer watcher.Stop()
+
+		table := make(map[string]V)
+		for {
+			select {
+			case entry, ok := <-watcher.Updates():
+				if !ok {
+					return nil, errors.New("kv watcher stopped")
+				}
+				if entry == nil { // all current values were delivered
+					return table, nil
+				}
+				var value V
+				if err := json.Unmarshal(entry.Value(), &value); err != nil {
+					return nil, fmt.Errorf("could not decode value of key %q: %w", entry.Key(), err)
+				}
+				table[entry.Key()] = value
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package flow_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// hostAsset is the item of the lookup table tests, enriched with the owner of its host
+type hostAsset struct {
+	host, owner string
+}
+
+func assetKey(a hostAsset) (string, bool) { return a.host, a.host != "" }
+
+func TestLookupTable_Transform(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "assets.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("host,owner,site\nweb1,alice,dc1\ndb1,bob,dc2\n"), 0o600))
+
+	lt, err := flow.NewLookupTable(flow.LookupTableConfig[hostAsset, map[string]string]{
+		Key:   assetKey,
+		Load:  flow.LoadCSVTable(path, "host"),
+		Merge: func(a hostAsset, row map[string]string) hostAsset { a.owner = row["owner"]; return a },
+	})
+	assert.NoError(t, err)
+
+	in := make(chan hostAsset, 3)
+	in <- hostAsset{host: "web1"}
+	in <- hostAsset{host: "unknown"}
+	in <- hostAsset{host: "db1"}
+	close(in)
+
+	eventC := make(chan pipeline.Event, 4)
+	var got []hostAsset
+	for a := range lt.Transform(in, eventC) {
+		got = append(got, a)
+	}
+	assert.Equal(t, []hostAsset{{"web1", "alice"}, {"unknown", ""}, {"db1", "bob"}}, got)
+
+	m := (<-eventC).(pipeline.MetricEvent)
+	assert.Equal(t, flow.MetricLookupTableKeys, m.Name())
+	assert.Equal(t, float64(2), m.Value())
+}
+
+func TestLookupTable_Refresh(t *testing.T) {
+	var loads atomic.Int32
+	lt, err := flow.NewLookupTable(flow.LookupTableConfig[hostAsset, string]{
+		Key: assetKey,
+		Load: func(context.Context) (map[string]string, error) {
+			switch loads.Add(1) {
+			case 1:
+				return map[string]string{"web1": "alice"}, nil
+			case 2:
+				return nil, errors.New("unavailable")
+			default:
+				return map[string]string{"web1": "carol"}, nil
+			}
+		},
+		Merge:   func(a hostAsset, owner string) hostAsset { a.owner = owner; return a },
+		Refresh: 10 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	in := make(chan hostAsset)
+	defer close(in)
+	eventC := make(chan pipeline.Event, 64)
+	out := lt.Transform(in, eventC)
+
+	in <- hostAsset{host: "web1"}
+	assert.Equal(t, "alice", (<-out).owner)
+
+	assert.Eventually(t, func() bool {
+		in <- hostAsset{host: "web1"}
+		return (<-out).owner == "carol"
+	}, time.Second, 5*time.Millisecond)
+
+	var failed bool
+	for len(eventC) > 0 {
+		if e, ok := (<-eventC).(pipeline.ErrorEvent); ok {
+			failed = true
+			assert.ErrorContains(t, e, "unavailable")
+		}
+	}
+	assert.True(t, failed)
+}
+
+func TestLoadJSONTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"alice":{"dept":"it"},"bob":{"dept":"hr"}}`), 0o600))
+
+	table, err := flow.LoadJSONTable[map[string]string](path)(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"alice": {"dept": "it"}, "bob": {"dept": "hr"}}, table)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`[]`), 0o600))
+	_, err = flow.LoadJSONTable[map[string]string](path)(context.Background())
+	assert.Error(t, err)
+}
+
+func TestLoadCSVTable_MissingKeyColumn(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "assets.csv")
+	assert.NoError(t, os.WriteFile(path, []byte("host,owner\nweb1,alice\n"), 0o600))
+
+	_, err := flow.LoadCSVTable(path, "ip")(context.Background())
+	assert.Error(t, err)
+}
+
+func TestNewLookupTable_Validation(t *testing.T) {
+	_, err := flow.NewLookupTable(flow.LookupTableConfig[hostAsset, string]{Key: assetKey})
+	assert.Error(t, err)
+}
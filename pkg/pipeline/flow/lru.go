@@ -0,0 +1,90 @@
+package flow
+
+import (
+	"container/list"
+	"iter"
+)
+
+// lru is a bounded map that evicts the least recently used entry when full.
+// It is not safe for concurrent use.
+type lru[K comparable, V any] struct {
+	capacity int
+	order    *list.List // front is the most recently used
+	entries  map[K]*list.Element
+}
+
+// lruEntry is an entry held by the order list
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// newLRU creates a new lru holding up to capacity entries.
+func newLRU[K comparable, V any](capacity int) *lru[K, V] {
+	return &lru[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[K]*list.Element, capacity),
+	}
+}
+
+// get returns the value of the key and marks it as recently used.
+func (c *lru[K, V]) get(key K) (V, bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry[K, V]).value, true
+}
+
+// put sets the value of the key and marks it as recently used. When the cache is full,
+// the least recently used entry is evicted and returned.
+func (c *lru[K, V]) put(key K, value V) (evicted *lruEntry[K, V]) {
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(e)
+		return nil
+	}
+
+	if c.order.Len() >= c.capacity {
+		evicted = c.removeElement(c.order.Back())
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+	return evicted
+}
+
+// oldest returns the least recently used entry, or nil when the cache is empty.
+func (c *lru[K, V]) oldest() *lruEntry[K, V] {
+	if e := c.order.Back(); e != nil {
+		return e.Value.(*lruEntry[K, V])
+	}
+	return nil
+}
+
+// remove deletes the entry of the key.
+func (c *lru[K, V]) remove(key K) {
+	if e, ok := c.entries[key]; ok {
+		c.removeElement(e)
+	}
+}
+
+// all returns the entries from the least to the most recently used.
+func (c *lru[K, V]) all() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for e := c.order.Back(); e != nil; e = e.Prev() {
+			entry := e.Value.(*lruEntry[K, V])
+			if !yield(entry.key, entry.value) {
+				return
+			}
+		}
+	}
+}
+
+// removeElement deletes the element from the list and the map and returns its entry
+func (c *lru[K, V]) removeElement(e *list.Element) *lruEntry[K, V] {
+	entry := c.order.Remove(e).(*lruEntry[K, V])
+	delete(c.entries, entry.key)
+	return entry
+}
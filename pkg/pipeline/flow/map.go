@@ -6,7 +6,10 @@ import (
 	"github.com/witfoo/krapht/pkg/pipeline"
 )
 
-var _ pipeline.Flow[any, any] = (*Map[any, any])(nil)
+var (
+	_ pipeline.Flow[any, any]      = (*Map[any, any])(nil)
+	_ pipeline.ChunkFlow[any, any] = (*Map[any, any])(nil)
+)
 
 // MapFunc is a function that transforms an input value to an output value.
 type MapFunc[I, O any] func(in I) (O, error)
@@ -47,3 +50,31 @@ func (m Map[I, O]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan O
 	}()
 	return out
 }
+
+// TransformChunks applies the map operation on the chunks of the input channel in a goroutine and returns
+// the output channel of the chunks of the transformed items. Chunks whose items all fail are not sent.
+func (m Map[I, O]) TransformChunks(in <-chan []I, eventC chan<- pipeline.Event) <-chan []O {
+	out := make(chan []O)
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			vals := make([]O, 0, len(chunk))
+			for _, v := range chunk {
+				val, err := m.transform(v)
+				if err != nil {
+					// Send an error event when transform fails
+					eventC <- pipeline.NewErrorEvent(
+						"map transform error",
+						err,
+						true)
+					continue
+				}
+				vals = append(vals, val)
+			}
+			if len(vals) > 0 {
+				out <- vals
+			}
+		}
+	}()
+	return out
+}
@@ -1,6 +1,7 @@
 package flow_test
 
 import (
+	"errors"
 	"strconv"
 	"testing"
 
@@ -51,3 +52,26 @@ func TestMap_Transform(t *testing.T) {
 	expectedOutput := []string{"1", "2", "3"}
 	assert.Equal(t, expectedOutput, result)
 }
+
+func TestMap_TransformChunks(t *testing.T) {
+	eventC := make(chan pipeline.Event, 2)
+	m, err := flow.NewMap(func(in int) (int, error) {
+		if in < 0 {
+			return 0, errors.New("negative")
+		}
+		return in * 10, nil
+	})
+	assert.NoError(t, err)
+
+	input := make(chan []int, 2)
+	input <- []int{1, -1, 2}
+	input <- []int{-2}
+	close(input)
+
+	var result [][]int
+	for chunk := range m.TransformChunks(input, eventC) {
+		result = append(result, chunk)
+	}
+	assert.Equal(t, [][]int{{10, 20}}, result, "chunks whose items all fail are not sent")
+	assert.Len(t, eventC, 2)
+}
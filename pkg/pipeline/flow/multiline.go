@@ -0,0 +1,165 @@
+package flow
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Multiline implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.Readable] = (*Multiline[any])(nil)
+
+// MultilineConfig is the configuration of a multiline aggregation flow. Exactly one of Start and
+// Continue is required.
+type MultilineConfig[I any] struct {
+	Start    string // regex matching the first line of a record, other lines continue the record before them
+	Continue string // regex matching the lines that continue the record before them, such as `^\s`
+
+	// Key returns the source of a line, such as a host or file, whose lines are aggregated separately
+	// so that interleaved sources are not mixed. When nil, all lines are from one source.
+	Key func(in I) string
+
+	Separator string        // joins the lines of a record, defaults to a newline
+	MaxLines  int           // lines of a record at most, the record is emitted when reached, defaults to 500
+	Timeout   time.Duration // a record is emitted when no line of its source arrives for this long, defaults to 1s
+}
+
+// Multiline is a struct that represents merging of continuation lines on a data stream into records.
+type Multiline[I any] struct {
+	conf      MultilineConfig[I]
+	pattern   *regexp.Regexp
+	negate    bool // the pattern matches continuation lines rather than first lines
+	separator []byte
+}
+
+// NewMultiline creates a new Multiline flow with the given configuration.
+func NewMultiline[I any](conf MultilineConfig[I]) (*Multiline[I], error) {
+	if (conf.Start == "") == (conf.Continue == "") {
+		return nil, errors.New("exactly one of the start and continue patterns must be set")
+	}
+
+	expr, negate := conf.Start, false
+	if conf.Continue != "" {
+		expr, negate = conf.Continue, true
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("could not compile multiline pattern: %w", err)
+	}
+
+	if conf.Separator == "" {
+		conf.Separator = "\n"
+	}
+
+	if conf.MaxLines <= 0 {
+		conf.MaxLines = 500
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = time.Second
+	}
+
+	return &Multiline[I]{
+		conf:      conf,
+		pattern:   pattern,
+		negate:    negate,
+		separator: []byte(conf.Separator),
+	}, nil
+}
+
+// multilineRecord is the record of a source being aggregated
+type multilineRecord struct {
+	data     []byte
+	lines    int
+	deadline time.Time
+}
+
+// Transform merges the lines from the input channel into records and returns the output channel of records.
+// A line continuing a record is appended to the held record of its source, and any other line emits
+// the held record and starts the next one. Held records are emitted after the timeout without a line
+// of their source, on reaching the line limit and when the input channel is closed.
+func (m Multiline[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.Readable {
+	out := make(chan pipeline.Readable)
+
+	go func() {
+		defer close(out)
+
+		held := make(map[string]*multilineRecord)
+		// the timeout is fixed, so deadlines are queued in order and superseded ones skipped
+		var queue []deadline[string]
+
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+
+		// flush sends the held record of a source
+		flush := func(key string) {
+			if r, ok := held[key]; ok {
+				delete(held, key)
+				out <- pipeline.Bytes(r.data)
+			}
+		}
+
+		// expire sends the held records of the sources quiet by now and sets the timer for the next one
+		expire := func(now time.Time) {
+			for len(queue) > 0 && !queue[0].at.After(now) {
+				next := queue[0]
+				queue = queue[1:]
+				if r, ok := held[next.key]; ok && r.deadline.Equal(next.at) {
+					flush(next.key)
+				}
+			}
+			if len(queue) > 0 {
+				timer.Reset(time.Until(queue[0].at))
+			}
+		}
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					expire(maxTime)
+					return
+				}
+
+				line, err := payload(v)
+				if err != nil {
+					pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("multiline: could not read item", err, false, v))
+					continue
+				}
+
+				var key string
+				if m.conf.Key != nil {
+					key = m.conf.Key(v)
+				}
+
+				r, ok := held[key]
+				if ok && m.pattern.Match(line) == m.negate {
+					r.data = append(append(r.data, m.separator...), line...)
+					r.lines++
+				} else {
+					flush(key)
+					r = &multilineRecord{data: append([]byte(nil), line...), lines: 1}
+					held[key] = r
+				}
+
+				if r.lines >= m.conf.MaxLines {
+					flush(key)
+					continue
+				}
+
+				r.deadline = time.Now().Add(m.conf.Timeout)
+				queue = append(queue, deadline[string]{key: key, at: r.deadline})
+				if len(queue) == 1 {
+					timer.Reset(m.conf.Timeout)
+				}
+			case <-timer.C:
+				expire(time.Now())
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,91 @@
+package flow_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// aggregate runs the lines through a multiline flow and returns the records
+func aggregate(t *testing.T, conf flow.MultilineConfig[string], lines []string) []string {
+	m, err := flow.NewMultiline(conf)
+	assert.NoError(t, err)
+
+	in := make(chan string, len(lines))
+	for _, s := range lines {
+		in <- s
+	}
+	close(in)
+
+	var records []string
+	for r := range m.Transform(in, nil) {
+		b, _ := r.Read()
+		records = append(records, string(b))
+	}
+	return records
+}
+
+func TestMultiline_Transform(t *testing.T) {
+	trace := []string{
+		"2024-01-02 ERROR request failed",
+		"java.lang.NullPointerException",
+		"\tat com.example.Handler.serve(Handler.java:42)",
+		"2024-01-02 INFO next request",
+	}
+
+	t.Run("start pattern", func(t *testing.T) {
+		records := aggregate(t, flow.MultilineConfig[string]{Start: `^\d{4}-\d{2}-\d{2} `}, trace)
+		assert.Equal(t, []string{strings.Join(trace[:3], "\n"), trace[3]}, records)
+	})
+
+	t.Run("continue pattern", func(t *testing.T) {
+		records := aggregate(t, flow.MultilineConfig[string]{Continue: `^\s`, Separator: " | "}, trace)
+		assert.Equal(t, []string{trace[0], trace[1] + " | " + trace[2], trace[3]}, records)
+	})
+
+	t.Run("max lines", func(t *testing.T) {
+		records := aggregate(t, flow.MultilineConfig[string]{Continue: `^\s`, MaxLines: 2}, []string{"a", " b", " c", " d"})
+		assert.Equal(t, []string{"a\n b", " c\n d"}, records)
+	})
+
+	t.Run("sources aggregated separately", func(t *testing.T) {
+		records := aggregate(t, flow.MultilineConfig[string]{
+			Continue: `^. \s`,
+			Key:      func(s string) string { return s[:1] },
+		}, []string{"a x", "b y", "a  1", "b  2", "a z"})
+		assert.Equal(t, []string{"a x\na  1", "b y\nb  2", "a z"}, records)
+	})
+}
+
+func TestMultiline_Timeout(t *testing.T) {
+	m, err := flow.NewMultiline(flow.MultilineConfig[[]byte]{Continue: `^\s`, Timeout: 10 * time.Millisecond})
+	assert.NoError(t, err)
+
+	in := make(chan []byte)
+	defer close(in)
+	out := m.Transform(in, nil)
+
+	in <- []byte("panic: boom")
+	in <- []byte("\tgoroutine 1")
+	select {
+	case r := <-out:
+		b, _ := r.Read()
+		assert.Equal(t, "panic: boom\n\tgoroutine 1", string(b))
+	case <-time.After(time.Second):
+		t.Fatal("held record was not emitted after the timeout")
+	}
+}
+
+func TestNewMultiline_Validation(t *testing.T) {
+	_, err := flow.NewMultiline(flow.MultilineConfig[string]{})
+	assert.Error(t, err)
+
+	_, err = flow.NewMultiline(flow.MultilineConfig[string]{Start: "^a", Continue: "^b"})
+	assert.Error(t, err)
+
+	_, err = flow.NewMultiline(flow.MultilineConfig[string]{Start: "("})
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+package flow
+
+import (
+	"container/heap"
+	"errors"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that OrderedMerge implements the FanIn interface.
+var _ pipeline.FanIn[any] = (*OrderedMerge[any])(nil)
+
+// OrderedMergeConfig is the configuration of a time ordered merge.
+type OrderedMergeConfig[I any] struct {
+	Time func(in I) time.Time // event time of an item, required
+
+	// IdleTimeout is how long the merge waits for the next item of an input before going on without it,
+	// so a quiet input does not hold back the others. An idle input rejoins the merge with its next item,
+	// which may be older than items already emitted. Zero waits indefinitely, keeping the order exact.
+	IdleTimeout time.Duration
+}
+
+// OrderedMerge is a struct that represents a merge of time sorted data streams into one sorted stream.
+type OrderedMerge[I any] struct {
+	conf OrderedMergeConfig[I]
+}
+
+// NewOrderedMerge creates a new OrderedMerge fan in with the given configuration.
+func NewOrderedMerge[I any](conf OrderedMergeConfig[I]) (*OrderedMerge[I], error) {
+	if conf.Time == nil {
+		return nil, errors.New("time func is nil")
+	}
+
+	if conf.IdleTimeout < 0 {
+		conf.IdleTimeout = 0
+	}
+
+	return &OrderedMerge[I]{
+		conf: conf,
+	}, nil
+}
+
+// mergeArrival is the next item of an input, or its end
+type mergeArrival[I any] struct {
+	src int
+	v   I
+	ok  bool
+}
+
+// mergeState is the state of an input of the merge
+type mergeState uint8
+
+const (
+	mergeWaiting mergeState = iota // its next item is awaited
+	mergeIdle                      // its next item is awaited without holding back the merge
+	mergeHeld                      // its next item is held in the heap
+	mergeClosed
+)
+
+// Merge merges the items of the input channels, each sorted by event time, into the output channel in event
+// time order, with ties in the order of the inputs. An item is emitted once every open input has an item
+// held, so the merge proceeds at the pace of the slowest input unless the idle timeout is set. Each input
+// is read one item ahead at most.
+func (m OrderedMerge[I]) Merge(ins []<-chan I, _ chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+	arrivals := make(chan mergeArrival[I])
+	next := make([]chan struct{}, len(ins))
+
+	for i, in := range ins {
+		next[i] = make(chan struct{}, 1)
+		go func() {
+			for {
+				v, ok := <-in
+				arrivals <- mergeArrival[I]{src: i, v: v, ok: ok}
+				if !ok {
+					return
+				}
+				<-next[i]
+			}
+		}()
+	}
+
+	go func() {
+		defer close(out)
+
+		states := make([]mergeState, len(ins))
+		var held reorderHeap[int]
+		items := make([]I, len(ins))
+		open := len(ins)
+
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+
+		arrive := func(a mergeArrival[I]) {
+			if !a.ok {
+				states[a.src] = mergeClosed
+				open--
+				return
+			}
+			states[a.src] = mergeHeld
+			items[a.src] = a.v
+			heap.Push(&held, reorderItem[int]{v: a.src, ts: m.conf.Time(a.v).UnixNano(), seq: a.src})
+		}
+
+		for open > 0 {
+			var waiting, idle bool
+			for _, s := range states {
+				waiting = waiting || s == mergeWaiting
+				idle = idle || s == mergeIdle
+			}
+
+			switch {
+			case waiting:
+				// every open input needs an item held before the earliest can be emitted
+				if m.conf.IdleTimeout > 0 {
+					timer.Reset(m.conf.IdleTimeout)
+				}
+				select {
+				case a := <-arrivals:
+					arrive(a)
+				case <-timer.C:
+					for i, s := range states {
+						if s == mergeWaiting {
+							states[i] = mergeIdle
+						}
+					}
+				}
+				timer.Stop()
+			case len(held) == 0 && idle:
+				arrive(<-arrivals)
+			case len(held) > 0:
+				src := heap.Pop(&held).(reorderItem[int]).v
+				v := items[src]
+				var zero I
+				items[src] = zero
+				states[src] = mergeWaiting
+				next[src] <- struct{}{}
+				out <- v
+			}
+
+			// idle inputs that have an item ready rejoin without waiting
+			for drained := false; !drained; {
+				select {
+				case a := <-arrivals:
+					arrive(a)
+				default:
+					drained = true
+				}
+			}
+		}
+	}()
+	return out
+}
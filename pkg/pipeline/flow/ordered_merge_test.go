@@ -0,0 +1,71 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// deviceReading is the item of the ordered merge tests, with its time in seconds
+type deviceReading struct {
+	device string
+	sec    int64
+}
+
+func deviceReadingTime(r deviceReading) time.Time { return time.Unix(r.sec, 0) }
+
+// deviceReadings returns a closed channel of the deviceReadings of a device at the times
+func deviceReadings(device string, secs ...int64) <-chan deviceReading {
+	c := make(chan deviceReading, len(secs))
+	for _, s := range secs {
+		c <- deviceReading{device, s}
+	}
+	close(c)
+	return c
+}
+
+func TestOrderedMerge_Merge(t *testing.T) {
+	m, err := flow.NewOrderedMerge(flow.OrderedMergeConfig[deviceReading]{Time: deviceReadingTime})
+	assert.NoError(t, err)
+
+	out := m.Merge([]<-chan deviceReading{
+		deviceReadings("a", 1, 4, 7),
+		deviceReadings("b", 2, 4, 9, 10),
+		deviceReadings("c"),
+		deviceReadings("d", 3),
+	}, nil)
+
+	var got []deviceReading
+	for r := range out {
+		got = append(got, r)
+	}
+	assert.Equal(t, []deviceReading{{"a", 1}, {"b", 2}, {"d", 3}, {"a", 4}, {"b", 4}, {"a", 7}, {"b", 9}, {"b", 10}}, got)
+}
+
+func TestOrderedMerge_IdleTimeout(t *testing.T) {
+	m, err := flow.NewOrderedMerge(flow.OrderedMergeConfig[deviceReading]{Time: deviceReadingTime, IdleTimeout: 10 * time.Millisecond})
+	assert.NoError(t, err)
+
+	quiet := make(chan deviceReading)
+	defer close(quiet)
+	out := m.Merge([]<-chan deviceReading{deviceReadings("a", 1, 2), quiet}, nil)
+
+	for _, want := range []deviceReading{{"a", 1}, {"a", 2}} {
+		select {
+		case r := <-out:
+			assert.Equal(t, want, r)
+		case <-time.After(time.Second):
+			t.Fatal("quiet input held back the merge")
+		}
+	}
+
+	quiet <- deviceReading{"q", 5}
+	assert.Equal(t, deviceReading{"q", 5}, <-out)
+}
+
+func TestNewOrderedMerge_Validation(t *testing.T) {
+	_, err := flow.NewOrderedMerge(flow.OrderedMergeConfig[deviceReading]{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package flow
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that ParallelMap implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*ParallelMap[any, any])(nil)
+
+// ParallelMap is a struct that represents a map operation on a data stream run by a pool of workers.
+type ParallelMap[I, O any] struct {
+	transform MapFunc[I, O]
+	workers   int
+	ordered   bool
+}
+
+// NewParallelMap creates a new ParallelMap with the given transform function and number of workers.
+// Unordered, values are emitted as soon as they are transformed. Ordered, values are emitted in the
+// order of their inputs, holding back at most a few values per worker behind a slow one.
+// If workers is less than or equal to 0, it will default to the number of CPUs.
+func NewParallelMap[I, O any](transform MapFunc[I, O], workers int, ordered bool) (*ParallelMap[I, O], error) {
+	if transform == nil {
+		return nil, errors.New("transform func is nil")
+	}
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	return &ParallelMap[I, O]{
+		transform: transform,
+		workers:   workers,
+		ordered:   ordered,
+	}, nil
+}
+
+// sequenced is a value tagged with the position of its input
+type sequenced[T any] struct {
+	seq   uint64
+	value T
+	err   error
+}
+
+// Transform applies the map operation on the input channel across the workers and returns the output channel.
+// Values whose transform fails are dropped and reported with an error event.
+func (m ParallelMap[I, O]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan O {
+	if m.ordered {
+		return m.transformOrdered(in, eventC)
+	}
+
+	out := make(chan O)
+	var wg sync.WaitGroup
+	for range m.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range in {
+				val, err := m.transform(v)
+				if err != nil {
+					pipeline.SendEvent(eventC, pipeline.NewErrorEvent("map transform error", err, true))
+					continue
+				}
+				out <- val
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// transformOrdered applies the map operation across the workers and restores the input order
+func (m ParallelMap[I, O]) transformOrdered(in <-chan I, eventC chan<- pipeline.Event) <-chan O {
+	out := make(chan O)
+	jobs := make(chan sequenced[I])
+	results := make(chan sequenced[O])
+	// tokens bound the values in flight, so a slow value holds back a limited number of others
+	tokens := make(chan struct{}, m.workers*4)
+
+	go func() {
+		defer close(jobs)
+		var seq uint64
+		for v := range in {
+			tokens <- struct{}{}
+			jobs <- sequenced[I]{seq: seq, value: v}
+			seq++
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range m.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				val, err := m.transform(job.value)
+				results <- sequenced[O]{seq: job.seq, value: val, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	go func() {
+		defer close(out)
+		pending := make(map[uint64]sequenced[O])
+		var next uint64
+		for r := range results {
+			pending[r.seq] = r
+			for r, ok := pending[next]; ok; r, ok = pending[next] {
+				delete(pending, next)
+				next++
+				if r.err != nil {
+					pipeline.SendEvent(eventC, pipeline.NewErrorEvent("map transform error", r.err, true))
+				} else {
+					out <- r.value
+				}
+				<-tokens
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,75 @@
+package flow_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestParallelMap_Transform(t *testing.T) {
+	// square sleeps longer for the early values so they finish out of order
+	square := func(v int) (int, error) {
+		time.Sleep(time.Duration(10-v%10) * time.Millisecond)
+		if v == 7 {
+			return 0, errors.New("unlucky")
+		}
+		return v * v, nil
+	}
+
+	run := func(ordered bool) ([]int, []pipeline.Event) {
+		m, err := flow.NewParallelMap(square, 4, ordered)
+		assert.NoError(t, err)
+
+		in := make(chan int)
+		eventC := make(chan pipeline.Event, 1)
+		out := m.Transform(in, eventC)
+		go func() {
+			for i := range 20 {
+				in <- i
+			}
+			close(in)
+		}()
+
+		var result []int
+		for v := range out {
+			result = append(result, v)
+		}
+		close(eventC)
+
+		var events []pipeline.Event
+		for e := range eventC {
+			events = append(events, e)
+		}
+		return result, events
+	}
+
+	var want []int
+	for i := range 20 {
+		if i != 7 {
+			want = append(want, i*i)
+		}
+	}
+
+	t.Run("preserves the input order when ordered", func(t *testing.T) {
+		result, events := run(true)
+		assert.Equal(t, want, result)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("emits every value when unordered", func(t *testing.T) {
+		result, events := run(false)
+		slices.Sort(result)
+		assert.Equal(t, want, result)
+		assert.Len(t, events, 1)
+	})
+}
+
+func TestNewParallelMap_Validation(t *testing.T) {
+	_, err := flow.NewParallelMap[int, int](nil, 2, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+package flow
+
+import (
+	"encoding/json"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that ParseJSON implements the Flow interface.
+var _ pipeline.Flow[any, map[string]any] = (*ParseJSON[any, map[string]any])(nil)
+
+// ParseJSON is a struct that represents decoding of JSON payloads on a data stream.
+type ParseJSON[I, O any] struct {
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewParseJSON creates a new ParseJSON flow decoding payloads into values of type O,
+// such as map[string]any or a struct with json tags.
+// The payload is the data of a DataReadable, the raw message of a RawReadable, the bytes of a
+// Readable, []byte or string. Malformed items are passed to deadLetter when it is not nil.
+func NewParseJSON[I, O any](deadLetter DeadLetterFunc[I]) *ParseJSON[I, O] {
+	return &ParseJSON[I, O]{
+		deadLetter: deadLetter,
+	}
+}
+
+// Transform decodes the data from the input channel and returns the output channel.
+// Items that cannot be read or decoded are dropped and reported with an error event carrying
+// them as the record.
+func (p ParseJSON[I, O]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan O {
+	out := make(chan O)
+	go func() {
+		defer close(out)
+		for v := range in {
+			var val O
+			b, err := payload(v)
+			if err == nil {
+				err = json.Unmarshal(b, &val)
+			}
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("parse json error", err, false, v))
+				if p.deadLetter != nil {
+					p.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- val
+		}
+	}()
+	return out
+}
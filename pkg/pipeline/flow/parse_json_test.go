@@ -0,0 +1,59 @@
+package flow_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+)
+
+func TestParseJSON_Transform(t *testing.T) {
+	t.Run("decodes payloads into maps", func(t *testing.T) {
+		var letters []pipeline.Readable
+		parse := flow.NewParseJSON[pipeline.Readable, map[string]any](func(r pipeline.Readable, _ error) {
+			letters = append(letters, r)
+		})
+
+		bad := mock.NewReadableImpl([]byte("{not json"))
+		in := make(chan pipeline.Readable, 3)
+		in <- mock.NewReadableImpl([]byte(`{"host":"db1","port":5432}`))
+		in <- bad
+		in <- mock.ReadableBad{}
+		close(in)
+
+		eventC := make(chan pipeline.Event, 2)
+		var result []map[string]any
+		for m := range parse.Transform(in, eventC) {
+			result = append(result, m)
+		}
+
+		assert.Equal(t, []map[string]any{{"host": "db1", "port": float64(5432)}}, result)
+		assert.Equal(t, []pipeline.Readable{bad, mock.ReadableBad{}}, letters)
+
+		errEvent, ok := (<-eventC).(pipeline.ErrorEvent)
+		assert.True(t, ok)
+		assert.Equal(t, bad, errEvent.Record())
+		assert.False(t, errEvent.IsTemporary())
+	})
+
+	t.Run("decodes the data of records into structs", func(t *testing.T) {
+		type login struct {
+			User    string `json:"user"`
+			Success bool   `json:"success"`
+		}
+		parse := flow.NewParseJSON[pipeline.DataRawReadable, login](nil)
+
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- mock.NewDataRawReadableImpl(mock.NewReadableImpl([]byte(`{"user":"alice","success":true}`)), mock.NewReadableImpl([]byte("raw")))
+		close(in)
+
+		var result []login
+		for l := range parse.Transform(in, nil) {
+			result = append(result, l)
+		}
+
+		assert.Equal(t, []login{{User: "alice", Success: true}}, result)
+	})
+}
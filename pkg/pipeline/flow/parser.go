@@ -0,0 +1,115 @@
+package flow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Parser implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*Parser[any])(nil)
+
+// ParseFunc is a function that parses a raw message into structured data encoded as JSON.
+type ParseFunc func(raw []byte) (any, error)
+
+// Parser is a struct that represents parsing of raw messages on a data stream into records.
+type Parser[I any] struct {
+	name        string
+	parse       ParseFunc
+	appendParse AppendParseFunc // parses into pooled buffers, in place of parse
+	deadLetter  DeadLetterFunc[I]
+}
+
+// NewParser creates a new Parser flow with the given parse function.
+// The name is used in the error events of messages that fail to parse.
+// Messages that fail to parse are passed to deadLetter when it is not nil.
+func NewParser[I any](name string, parse ParseFunc, deadLetter DeadLetterFunc[I]) (*Parser[I], error) {
+	if parse == nil {
+		return nil, errors.New("parse func is nil")
+	}
+
+	return &Parser[I]{
+		name:       name,
+		parse:      parse,
+		deadLetter: deadLetter,
+	}, nil
+}
+
+// Transform parses the raw messages from the input channel and returns the output channel of records.
+// The raw message is the raw message of a RawReadable, or a Readable, []byte or string itself, and is
+// retained as the raw message of the record so it can still be acknowledged. Messages that fail to
+// parse are dropped and reported with an error event carrying them as the record.
+func (p Parser[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		for v := range in {
+			record, err := p.record(v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent(p.name+" parse error", err, false, v))
+				if p.deadLetter != nil {
+					p.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- record
+		}
+	}()
+	return out
+}
+
+// record parses the raw message of the item into a record
+func (p Parser[I]) record(v I) (pipeline.DataRawReadable, error) {
+	raw, err := rawOf(v)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := raw.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	// encode into a pooled buffer, which is reused once the record is released
+	buf := pipeline.DefaultBufferPool.Get()
+	if p.appendParse != nil {
+		data, err := p.appendParse(buf.AvailableBuffer(), b)
+		if err != nil {
+			pipeline.DefaultBufferPool.Put(buf)
+			return nil, err
+		}
+		buf.Write(data)
+		return pipeline.DeriveRecord(pipeline.DefaultBufferPool.Bytes(buf), raw, v), nil
+	}
+
+	parsed, err := p.parse(b)
+	if err != nil {
+		pipeline.DefaultBufferPool.Put(buf)
+		return nil, err
+	}
+
+	if err := json.NewEncoder(buf).Encode(parsed); err != nil {
+		pipeline.DefaultBufferPool.Put(buf)
+		return nil, err
+	}
+	buf.Truncate(buf.Len() - 1) // the newline of the encoder
+	return pipeline.DeriveRecord(pipeline.DefaultBufferPool.Bytes(buf), raw, v), nil
+}
+
+// rawOf returns the raw message of an item
+func rawOf(v any) (pipeline.Readable, error) {
+	switch r := v.(type) {
+	case pipeline.RawReadable:
+		return r.Raw(), nil
+	case pipeline.Readable:
+		return r, nil
+	case []byte:
+		return pipeline.Bytes(r), nil
+	case string:
+		return pipeline.Bytes(r), nil
+	default:
+		return nil, fmt.Errorf("unsupported message type %T", v)
+	}
+}
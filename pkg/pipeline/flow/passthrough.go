@@ -2,8 +2,11 @@ package flow
 
 import "github.com/witfoo/krapht/pkg/pipeline"
 
-// Ensure that Passthrough implements the Flow interface.
-var _ pipeline.Flow[any, any] = (*Passthrough[any])(nil)
+// Ensure that Passthrough implements the Flow and ChunkFlow interfaces.
+var (
+	_ pipeline.Flow[any, any]      = (*Passthrough[any])(nil)
+	_ pipeline.ChunkFlow[any, any] = (*Passthrough[any])(nil)
+)
 
 // Passthrough is a struct that represents a passthrough operation on a data stream.
 type Passthrough[I any] struct {
@@ -28,3 +31,17 @@ func (pt Passthrough[I]) Transform(in <-chan I, _ chan<- pipeline.Event) <-chan
 	}()
 	return out
 }
+
+// TransformChunks applies the passthrough operation on the chunks of the input channel and returns the
+// output channel of the chunks.
+func (pt Passthrough[I]) TransformChunks(in <-chan []I, _ chan<- pipeline.Event) <-chan []I {
+	out := make(chan []I)
+
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			out <- chunk
+		}
+	}()
+	return out
+}
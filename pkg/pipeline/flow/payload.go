@@ -0,0 +1,34 @@
+package flow
+
+import (
+	"fmt"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// payload returns the bytes of an item: the data of a DataReadable, the raw message of a RawReadable,
+// the bytes of a Readable, []byte or string, or the formatted value otherwise
+func payload(v any) ([]byte, error) {
+	switch r := v.(type) {
+	case pipeline.DataReadable:
+		return r.Data().Read()
+	case pipeline.RawReadable:
+		return r.Raw().Read()
+	case pipeline.Readable:
+		return r.Read()
+	case []byte:
+		return r, nil
+	case string:
+		return []byte(r), nil
+	default:
+		return fmt.Appendf(nil, "%#v", r), nil
+	}
+}
+
+// rawOrPayload returns the raw message of a RawReadable, or the payload read from the item otherwise
+func rawOrPayload(v any, b []byte) pipeline.Readable {
+	if r, ok := v.(pipeline.RawReadable); ok {
+		return r.Raw()
+	}
+	return pipeline.Bytes(b)
+}
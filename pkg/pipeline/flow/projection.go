@@ -0,0 +1,393 @@
+package flow
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Projection implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*Projection[any])(nil)
+
+// ErrNotObject is the error of a payload that is not a JSON object.
+var ErrNotObject = errors.New("payload is not a JSON object")
+
+// ProjectionConfig is the configuration of a projection.
+// Paths use dot notation, optionally prefixed with "$." as in JSONPath: a.b selects the field b of
+// the object a, a[0] or a.0 the first element of the array a, ['a.b'] a field whose name contains
+// a dot, and * every field of an object or every element of an array.
+type ProjectionConfig struct {
+	Include []string          // paths of the fields to keep, every field is kept when empty
+	Exclude []string          // paths of the fields to remove, applied after Include
+	Rename  map[string]string // paths of fields moved from the key to the value path, applied last, without wildcards
+}
+
+// Projection is a struct that represents selection and reshaping of the fields of JSON records on a data stream.
+type Projection[I any] struct {
+	include    [][]fieldSegment
+	exclude    [][]fieldSegment
+	rename     [][2][]fieldSegment
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewProjection creates a new Projection flow with the given configuration.
+// Items whose payload is not a JSON object are passed to deadLetter when it is not nil.
+func NewProjection[I any](conf ProjectionConfig, deadLetter DeadLetterFunc[I]) (*Projection[I], error) {
+	p := &Projection[I]{deadLetter: deadLetter}
+
+	for _, expr := range conf.Include {
+		path, err := parseFieldPath(expr)
+		if err != nil {
+			return nil, err
+		}
+		p.include = append(p.include, path)
+	}
+
+	for _, expr := range conf.Exclude {
+		path, err := parseFieldPath(expr)
+		if err != nil {
+			return nil, err
+		}
+		p.exclude = append(p.exclude, path)
+	}
+
+	// renames are applied in a stable order
+	for _, from := range slices.Sorted(maps.Keys(conf.Rename)) {
+		src, err := parseFieldPath(from)
+		if err != nil {
+			return nil, err
+		}
+		dst, err := parseFieldPath(conf.Rename[from])
+		if err != nil {
+			return nil, err
+		}
+		for _, seg := range slices.Concat(src, dst) {
+			if seg.wildcard {
+				return nil, fmt.Errorf("rename %q: wildcards are not supported", from)
+			}
+		}
+		p.rename = append(p.rename, [2][]fieldSegment{src, dst})
+	}
+
+	return p, nil
+}
+
+// Transform projects the JSON objects from the input channel and returns the output channel of records
+// holding the projected object. The payload is the data of a DataReadable, the raw message of a
+// RawReadable, the bytes of a Readable, []byte or string, and the raw message of a RawReadable or the
+// payload itself is retained as the raw message. Fields missing from an object are skipped.
+// Items that are not JSON objects are dropped and reported with an error event carrying them as the record.
+func (p Projection[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		for v := range in {
+			record, err := p.record(v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("projection error", err, false, v))
+				if p.deadLetter != nil {
+					p.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- record
+		}
+	}()
+	return out
+}
+
+// record projects the payload of the item into a record
+func (p Projection[I]) record(v I) (pipeline.DataRawReadable, error) {
+	b, err := payload(v)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := decodeObject(b)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(p.project(obj))
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.DeriveRecord(pipeline.Bytes(data), rawOrPayload(v, b), v), nil
+}
+
+// project applies the includes, excludes and renames to the object
+func (p Projection[I]) project(obj map[string]any) map[string]any {
+	if len(p.include) > 0 {
+		var selected any
+		for _, path := range p.include {
+			selected = includePath(selected, obj, path)
+		}
+		obj, _ = compactSelection(selected).(map[string]any)
+		if obj == nil {
+			obj = make(map[string]any)
+		}
+	}
+
+	for _, path := range p.exclude {
+		excludePath(obj, path)
+	}
+
+	for _, rename := range p.rename {
+		if value, ok := getPath(obj, rename[0]); ok {
+			excludePath(obj, rename[0])
+			setPath(obj, rename[1], value)
+		}
+	}
+	return obj
+}
+
+// decodeObject decodes a JSON object, keeping numbers as written
+func decodeObject(b []byte) (map[string]any, error) {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var obj map[string]any
+	if err := d.Decode(&obj); err != nil || obj == nil {
+		return nil, ErrNotObject
+	}
+	return obj, nil
+}
+
+// fieldSegment is a segment of a field path
+type fieldSegment struct {
+	key      string
+	index    int // index of the segment when it is numeric, -1 otherwise
+	wildcard bool
+}
+
+// matchKey reports whether the segment selects the field of an object
+func (s fieldSegment) matchKey(key string) bool {
+	return s.wildcard || s.key == key
+}
+
+// matchIndex reports whether the segment selects the element of an array
+func (s fieldSegment) matchIndex(i int) bool {
+	return s.wildcard || s.index == i
+}
+
+// newFieldSegment returns the segment of a dot separated name
+func newFieldSegment(name string) fieldSegment {
+	if name == "*" {
+		return fieldSegment{index: -1, wildcard: true}
+	}
+	index, err := strconv.Atoi(name)
+	if err != nil || index < 0 {
+		index = -1
+	}
+	return fieldSegment{key: name, index: index}
+}
+
+// parseFieldPath parses a path in dot or bracket notation into its segments
+func parseFieldPath(expr string) ([]fieldSegment, error) {
+	s := strings.TrimPrefix(strings.TrimPrefix(expr, "$"), ".")
+	var path []fieldSegment
+	for len(s) > 0 {
+		if s[0] == '[' {
+			end := strings.IndexByte(s, ']')
+			if len(s) > 1 && (s[1] == '\'' || s[1] == '"') {
+				// quoted names may contain brackets
+				quote := strings.IndexByte(s[2:], s[1])
+				if quote < 0 || len(s) < quote+4 || s[quote+3] != ']' {
+					return nil, fmt.Errorf("path %q: unterminated quoted name", expr)
+				}
+				path = append(path, fieldSegment{key: s[2 : quote+2], index: -1})
+				end = quote + 3
+			} else if end < 0 {
+				return nil, fmt.Errorf("path %q: unterminated bracket", expr)
+			} else {
+				seg := newFieldSegment(s[1:end])
+				if !seg.wildcard && seg.index < 0 {
+					return nil, fmt.Errorf("path %q: invalid index %q", expr, s[1:end])
+				}
+				path = append(path, seg)
+			}
+			s = s[end+1:]
+		} else {
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("path %q: empty field name", expr)
+			}
+			path = append(path, newFieldSegment(s[:end]))
+			s = s[end:]
+		}
+
+		if strings.HasPrefix(s, ".") {
+			s = s[1:]
+			if s == "" {
+				return nil, fmt.Errorf("path %q: empty field name", expr)
+			}
+		} else if s != "" && s[0] != '[' {
+			return nil, fmt.Errorf("path %q: expected . or [", expr)
+		}
+	}
+
+	if len(path) == 0 {
+		return nil, fmt.Errorf("path %q is empty", expr)
+	}
+	return path, nil
+}
+
+// selection holds the elements of an array selected by index while projecting
+type selection map[int]any
+
+// includePath merges the values of src selected by the path into dst and returns it
+func includePath(dst, src any, path []fieldSegment) any {
+	if len(path) == 0 {
+		return src
+	}
+
+	seg := path[0]
+	switch src := src.(type) {
+	case map[string]any:
+		d, ok := dst.(map[string]any)
+		for key, child := range src {
+			if !seg.matchKey(key) {
+				continue
+			}
+			if !ok {
+				d, ok = make(map[string]any), true
+			}
+			d[key] = includePath(d[key], child, path[1:])
+		}
+		if ok {
+			return d
+		}
+	case []any:
+		if _, ok := dst.([]any); ok {
+			return dst // the whole array is already included
+		}
+		d, ok := dst.(selection)
+		for i, child := range src {
+			if !seg.matchIndex(i) {
+				continue
+			}
+			if !ok {
+				d, ok = make(selection), true
+			}
+			d[i] = includePath(d[i], child, path[1:])
+		}
+		if ok {
+			return d
+		}
+	}
+	return dst
+}
+
+// compactSelection replaces the selections of a projected value by arrays of the selected elements in order
+func compactSelection(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			v[key] = compactSelection(child)
+		}
+	case selection:
+		arr := make([]any, 0, len(v))
+		for _, i := range slices.Sorted(maps.Keys(v)) {
+			arr = append(arr, compactSelection(v[i]))
+		}
+		return arr
+	}
+	return v
+}
+
+// excludePath removes the values selected by the path from v and returns it
+func excludePath(v any, path []fieldSegment) any {
+	seg, last := path[0], len(path) == 1
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			switch {
+			case !seg.matchKey(key):
+			case last:
+				delete(v, key)
+			default:
+				v[key] = excludePath(child, path[1:])
+			}
+		}
+	case []any:
+		kept := v[:0]
+		for i, child := range v {
+			switch {
+			case !seg.matchIndex(i):
+				kept = append(kept, child)
+			case !last:
+				kept = append(kept, excludePath(child, path[1:]))
+			}
+		}
+		return kept
+	}
+	return v
+}
+
+// getPath returns the value at a path without wildcards
+func getPath(v any, path []fieldSegment) (any, bool) {
+	for _, seg := range path {
+		switch c := v.(type) {
+		case map[string]any:
+			child, ok := c[seg.key]
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []any:
+			if seg.index < 0 || seg.index >= len(c) {
+				return nil, false
+			}
+			v = c[seg.index]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// setPath sets the value at a path without wildcards, creating the objects leading to it.
+// Existing values along the path that are not objects or arrays are replaced by objects.
+func setPath(obj map[string]any, path []fieldSegment, value any) {
+	var v any = obj
+	for i, seg := range path {
+		last := i == len(path)-1
+		if arr, ok := v.([]any); ok && seg.index >= 0 && seg.index < len(arr) {
+			if last {
+				arr[seg.index] = value
+				return
+			}
+			if _, ok := arr[seg.index].(map[string]any); !ok {
+				if _, ok := arr[seg.index].([]any); !ok {
+					arr[seg.index] = make(map[string]any)
+				}
+			}
+			v = arr[seg.index]
+			continue
+		}
+
+		m, ok := v.(map[string]any)
+		if !ok {
+			return // out of range of an array
+		}
+		if last {
+			m[seg.key] = value
+			return
+		}
+		switch m[seg.key].(type) {
+		case map[string]any, []any:
+		default:
+			m[seg.key] = make(map[string]any)
+		}
+		v = m[seg.key]
+	}
+}
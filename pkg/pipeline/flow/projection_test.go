@@ -0,0 +1,129 @@
+package flow_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+const projectionInput = `{"id":1234567890123456789,"user":{"name":"alice","password":"secret","roles":["admin","dev"]},` +
+	`"hosts":[{"name":"db1","ip":"10.0.0.1"},{"name":"web1","ip":"10.0.0.2"}],"a.b":true,"note":"drop me"}`
+
+// project sends the payload through a projection with the configuration and returns the projected data
+func project(t *testing.T, conf flow.ProjectionConfig, payload string) string {
+	t.Helper()
+
+	p, err := flow.NewProjection[string](conf, nil)
+	assert.NoError(t, err)
+
+	in := make(chan string, 1)
+	in <- payload
+	close(in)
+
+	var data string
+	for r := range p.Transform(in, nil) {
+		b, err := r.Data().Read()
+		assert.NoError(t, err)
+		raw, _ := r.Raw().Read()
+		assert.Equal(t, payload, string(raw))
+		data = string(b)
+	}
+	return data
+}
+
+func TestProjection_Transform(t *testing.T) {
+	tests := []struct {
+		name string
+		conf flow.ProjectionConfig
+		want string
+	}{
+		{
+			name: "keeps every field without configuration",
+			want: projectionInput,
+		},
+		{
+			name: "includes nested fields and keeps numbers as written",
+			conf: flow.ProjectionConfig{Include: []string{"id", "$.user.name", "missing.field"}},
+			want: `{"id":1234567890123456789,"user":{"name":"alice"}}`,
+		},
+		{
+			name: "includes array elements by index and wildcard",
+			conf: flow.ProjectionConfig{Include: []string{"hosts[*].ip", "user.roles[1]", "['a.b']"}},
+			want: `{"a.b":true,"hosts":[{"ip":"10.0.0.1"},{"ip":"10.0.0.2"}],"user":{"roles":["dev"]}}`,
+		},
+		{
+			name: "merges whole and partial includes",
+			conf: flow.ProjectionConfig{Include: []string{"hosts", "hosts.0.name"}},
+			want: `{"hosts":[{"ip":"10.0.0.1","name":"db1"},{"ip":"10.0.0.2","name":"web1"}]}`,
+		},
+		{
+			name: "excludes fields after including them",
+			conf: flow.ProjectionConfig{
+				Include: []string{"user", "hosts"},
+				Exclude: []string{"user.password", "hosts.*.ip", "user.roles[0]"},
+			},
+			want: `{"hosts":[{"name":"db1"},{"name":"web1"}],"user":{"name":"alice","roles":["dev"]}}`,
+		},
+		{
+			name: "renames fields into new objects",
+			conf: flow.ProjectionConfig{
+				Exclude: []string{"hosts", "user", "['a.b']"},
+				Rename:  map[string]string{"id": "event.id", "note": "event.note", "missing": "never"},
+			},
+			want: `{"event":{"id":1234567890123456789,"note":"drop me"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.JSONEq(t, tt.want, project(t, tt.conf, projectionInput))
+		})
+	}
+
+	// JSONEq compares numbers as floats
+	assert.Equal(t, `{"id":1234567890123456789}`, project(t, flow.ProjectionConfig{Include: []string{"id"}}, projectionInput))
+}
+
+func TestProjection_NotObject(t *testing.T) {
+	var letters []string
+	p, err := flow.NewProjection(flow.ProjectionConfig{Include: []string{"a"}}, func(v string, _ error) {
+		letters = append(letters, v)
+	})
+	assert.NoError(t, err)
+
+	in := make(chan string, 3)
+	in <- "[1,2]"
+	in <- "not json"
+	in <- `{"a":1,"b":2}`
+	close(in)
+
+	eventC := make(chan pipeline.Event, 2)
+	var data []string
+	for r := range p.Transform(in, eventC) {
+		b, _ := r.Data().Read()
+		data = append(data, string(b))
+	}
+
+	assert.Equal(t, []string{`{"a":1}`}, data)
+	assert.Equal(t, []string{"[1,2]", "not json"}, letters)
+	assert.ErrorIs(t, (<-eventC).(pipeline.ErrorEvent), flow.ErrNotObject)
+}
+
+func TestNewProjection_Validation(t *testing.T) {
+	for _, conf := range []flow.ProjectionConfig{
+		{Include: []string{""}},
+		{Include: []string{"a..b"}},
+		{Include: []string{"a."}},
+		{Include: []string{"a[x]"}},
+		{Include: []string{"a[0"}},
+		{Include: []string{"['a"}},
+		{Exclude: []string{"a[0]b"}},
+		{Rename: map[string]string{"a.*": "b"}},
+		{Rename: map[string]string{"a": "$"}},
+	} {
+		_, err := flow.NewProjection[string](conf, nil)
+		assert.Error(t, err, conf)
+	}
+}
@@ -0,0 +1,165 @@
+package flow
+
+import (
+	"errors"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Reduce implements the Flow interface.
+var _ pipeline.Flow[any, ReduceResult[any]] = (*Reduce[any, any])(nil)
+
+// ReduceEmit is when a Reduce flow emits its accumulators.
+type ReduceEmit int
+
+const (
+	// ReduceOnEviction emits the accumulator of a key when it is evicted from the state
+	// or the input channel is closed.
+	ReduceOnEviction ReduceEmit = iota
+	// ReduceEveryItem emits the accumulator of a key after every item folded into it.
+	ReduceEveryItem
+	// ReduceOnInterval emits the accumulators of all keys every interval, in addition to
+	// emitting them on eviction.
+	ReduceOnInterval
+)
+
+// ReduceResult is the accumulated state of a key.
+type ReduceResult[A any] struct {
+	Key   string // key of the items, empty for global state
+	Value A      // accumulator folded over the items
+	Count int    // number of items folded
+}
+
+// ReduceConfig is the configuration of a reduce flow.
+type ReduceConfig[I, A any] struct {
+	Fold FoldFunc[I, A] // folds an item into the accumulator, starting from the zero value, required
+
+	// Key returns the key of an item. When nil, all items are folded into a single global state.
+	Key func(in I) string
+
+	Emit     ReduceEmit    // when accumulators are emitted
+	Interval time.Duration // period of ReduceOnInterval, required for it
+
+	MaxKeys int           // bound on the keys held, the least recently updated is evicted, defaults to 10000
+	IdleTTL time.Duration // evicts keys not updated for this long, zero disables it
+}
+
+// Reduce is a struct that represents stateful aggregation of a data stream.
+type Reduce[I, A any] struct {
+	conf ReduceConfig[I, A]
+}
+
+// NewReduce creates a new Reduce flow with the given configuration.
+func NewReduce[I, A any](conf ReduceConfig[I, A]) (*Reduce[I, A], error) {
+	if conf.Fold == nil {
+		return nil, errors.New("fold func is nil")
+	}
+
+	if conf.Emit == ReduceOnInterval && conf.Interval <= 0 {
+		return nil, errors.New("interval must be positive")
+	}
+
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = 10000
+	}
+
+	return &Reduce[I, A]{
+		conf: conf,
+	}, nil
+}
+
+// reduceState is the state held for a key
+type reduceState[A any] struct {
+	result  ReduceResult[A]
+	updated time.Time
+}
+
+// Transform folds the data from the input channel into per key state and returns the output channel
+// of accumulators. Unless emitting every item, the state of all keys is emitted when the input channel
+// is closed. Evicted state is discarded when emitting every item, since it was already emitted.
+func (r Reduce[I, A]) Transform(in <-chan I, _ chan<- pipeline.Event) <-chan ReduceResult[A] {
+	out := make(chan ReduceResult[A])
+
+	go func() {
+		defer close(out)
+
+		state := newLRU[string, *reduceState[A]](r.conf.MaxKeys)
+
+		// evict sends the final state of an evicted key
+		evict := func(s *reduceState[A]) {
+			if r.conf.Emit != ReduceEveryItem {
+				out <- s.result
+			}
+		}
+
+		var tick <-chan time.Time
+		if r.conf.Emit == ReduceOnInterval {
+			ticker := time.NewTicker(r.conf.Interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		// the idle timer runs until the least recently updated key expires
+		idle := time.NewTimer(time.Hour)
+		idle.Stop()
+		defer idle.Stop()
+		expire := func() {
+			if r.conf.IdleTTL <= 0 {
+				return
+			}
+			idle.Stop()
+			now := time.Now()
+			for oldest := state.oldest(); oldest != nil; oldest = state.oldest() {
+				if wait := oldest.value.updated.Add(r.conf.IdleTTL).Sub(now); wait > 0 {
+					idle.Reset(wait)
+					return
+				}
+				state.remove(oldest.key)
+				evict(oldest.value)
+			}
+		}
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if r.conf.Emit != ReduceEveryItem {
+						for _, s := range state.all() {
+							out <- s.result
+						}
+					}
+					return
+				}
+
+				var key string
+				if r.conf.Key != nil {
+					key = r.conf.Key(v)
+				}
+
+				s, ok := state.get(key)
+				if !ok {
+					s = &reduceState[A]{result: ReduceResult[A]{Key: key}}
+				}
+				s.result.Value = r.conf.Fold(s.result.Value, v)
+				s.result.Count++
+				s.updated = time.Now()
+				if evicted := state.put(key, s); evicted != nil {
+					evict(evicted.value)
+				}
+
+				if r.conf.Emit == ReduceEveryItem {
+					out <- s.result
+				}
+				expire()
+			case <-tick:
+				for _, s := range state.all() {
+					out <- s.result
+				}
+			case <-idle.C:
+				expire()
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,125 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestReduce_Transform(t *testing.T) {
+	sum := func(acc int, r reading) int { return acc + r.value }
+	byHost := func(r reading) string { return r.host }
+
+	t.Run("emits running totals every item", func(t *testing.T) {
+		reduce, err := flow.NewReduce(flow.ReduceConfig[reading, int]{Fold: sum, Key: byHost, Emit: flow.ReduceEveryItem})
+		assert.NoError(t, err)
+
+		in := make(chan reading, 3)
+		in <- reading{host: "a", value: 1}
+		in <- reading{host: "b", value: 2}
+		in <- reading{host: "a", value: 3}
+		close(in)
+
+		var result []flow.ReduceResult[int]
+		for r := range reduce.Transform(in, nil) {
+			result = append(result, r)
+		}
+
+		assert.Equal(t, []flow.ReduceResult[int]{
+			{Key: "a", Value: 1, Count: 1},
+			{Key: "b", Value: 2, Count: 1},
+			{Key: "a", Value: 4, Count: 2},
+		}, result)
+	})
+
+	t.Run("folds global state and emits it on close", func(t *testing.T) {
+		reduce, err := flow.NewReduce(flow.ReduceConfig[reading, int]{Fold: sum})
+		assert.NoError(t, err)
+
+		in := make(chan reading, 3)
+		in <- reading{host: "a", value: 1}
+		in <- reading{host: "b", value: 2}
+		in <- reading{host: "a", value: 3}
+		close(in)
+
+		var result []flow.ReduceResult[int]
+		for r := range reduce.Transform(in, nil) {
+			result = append(result, r)
+		}
+
+		assert.Equal(t, []flow.ReduceResult[int]{{Value: 6, Count: 3}}, result)
+	})
+
+	t.Run("emits keys evicted beyond the bound", func(t *testing.T) {
+		reduce, err := flow.NewReduce(flow.ReduceConfig[reading, int]{Fold: sum, Key: byHost, MaxKeys: 1})
+		assert.NoError(t, err)
+
+		in := make(chan reading)
+		out := reduce.Transform(in, nil)
+
+		in <- reading{host: "a", value: 1}
+		in <- reading{host: "a", value: 2}
+		in <- reading{host: "b", value: 5}
+		assert.Equal(t, flow.ReduceResult[int]{Key: "a", Value: 3, Count: 2}, <-out)
+
+		close(in)
+		assert.Equal(t, flow.ReduceResult[int]{Key: "b", Value: 5, Count: 1}, <-out)
+		_, ok := <-out
+		assert.False(t, ok)
+	})
+
+	t.Run("emits keys evicted when idle", func(t *testing.T) {
+		reduce, err := flow.NewReduce(flow.ReduceConfig[reading, int]{Fold: sum, Key: byHost, IdleTTL: 20 * time.Millisecond})
+		assert.NoError(t, err)
+
+		in := make(chan reading)
+		out := reduce.Transform(in, nil)
+
+		in <- reading{host: "a", value: 1}
+		select {
+		case r := <-out:
+			assert.Equal(t, flow.ReduceResult[int]{Key: "a", Value: 1, Count: 1}, r)
+		case <-time.After(time.Second):
+			t.Fatal("idle key was not evicted")
+		}
+
+		close(in)
+		_, ok := <-out
+		assert.False(t, ok)
+	})
+
+	t.Run("emits all keys on the interval", func(t *testing.T) {
+		reduce, err := flow.NewReduce(flow.ReduceConfig[reading, int]{
+			Fold:     sum,
+			Key:      byHost,
+			Emit:     flow.ReduceOnInterval,
+			Interval: 20 * time.Millisecond,
+		})
+		assert.NoError(t, err)
+
+		in := make(chan reading)
+		out := reduce.Transform(in, nil)
+
+		in <- reading{host: "a", value: 1}
+		assert.Equal(t, flow.ReduceResult[int]{Key: "a", Value: 1, Count: 1}, <-out)
+		in <- reading{host: "a", value: 2}
+		assert.Equal(t, flow.ReduceResult[int]{Key: "a", Value: 3, Count: 2}, <-out)
+
+		close(in)
+		for range out {
+		}
+	})
+}
+
+func TestNewReduce_Validation(t *testing.T) {
+	_, err := flow.NewReduce(flow.ReduceConfig[int, int]{})
+	assert.Error(t, err)
+
+	_, err = flow.NewReduce(flow.ReduceConfig[int, int]{
+		Fold: func(acc, v int) int { return acc + v },
+		Emit: flow.ReduceOnInterval,
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package flow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that RegexParser implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*RegexParser[any])(nil)
+
+const (
+	// MetricRegexHits is the counter of messages matched by a pattern, labelled by the pattern index.
+	MetricRegexHits = "krapht_regex_hits_total"
+	// MetricRegexMisses is the counter of messages a pattern was tried on without matching, labelled by the pattern index.
+	MetricRegexMisses = "krapht_regex_misses_total"
+)
+
+// ErrRegexNoMatch is the error of a message matching none of the patterns.
+var ErrRegexNoMatch = errors.New("no regex pattern matched")
+
+// RegexParser is a struct that represents extraction of fields from raw messages on a data stream
+// by regular expressions with named capture groups.
+type RegexParser[I any] struct {
+	patterns   []*regexp.Regexp
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewRegexParser creates a new RegexParser flow with the given patterns, which are compiled here
+// and must each have at least one named capture group.
+// Messages matching no pattern are passed to deadLetter when it is not nil.
+func NewRegexParser[I any](patterns []string, deadLetter DeadLetterFunc[I]) (*RegexParser[I], error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("no regex patterns")
+	}
+
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("regex pattern %d: %w", i, err)
+		}
+
+		named := false
+		for _, name := range re.SubexpNames() {
+			named = named || name != ""
+		}
+		if !named {
+			return nil, fmt.Errorf("regex pattern %d has no named capture groups", i)
+		}
+		compiled[i] = re
+	}
+
+	return &RegexParser[I]{
+		patterns:   compiled,
+		deadLetter: deadLetter,
+	}, nil
+}
+
+// Transform matches the raw messages from the input channel against the patterns in order and returns
+// the output channel of records holding a JSON object of the named groups of the first match, with the
+// message retained as the raw message. Groups that captured nothing are omitted.
+// Messages that match no pattern are dropped and reported with an error event carrying them as the record.
+func (r RegexParser[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		hits := make([]float64, len(r.patterns))
+		misses := make([]float64, len(r.patterns))
+
+		for v := range in {
+			record, err := r.record(v, eventC, hits, misses)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("regex parse error", err, false, v))
+				if r.deadLetter != nil {
+					r.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- record
+		}
+	}()
+	return out
+}
+
+// record extracts the fields of the item into a record, counting the hits and misses of the patterns tried
+func (r RegexParser[I]) record(v I, eventC chan<- pipeline.Event, hits, misses []float64) (pipeline.DataRawReadable, error) {
+	raw, err := rawOf(v)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := raw.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, re := range r.patterns {
+		loc := re.FindSubmatchIndex(b)
+		labels := map[string]string{"pattern": strconv.Itoa(i)}
+		if loc == nil {
+			misses[i]++
+			pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricRegexMisses, misses[i], labels, pipeline.MetricTypeCounter))
+			continue
+		}
+		hits[i]++
+		pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricRegexHits, hits[i], labels, pipeline.MetricTypeCounter))
+
+		fields := make(map[string]string)
+		for g, name := range re.SubexpNames() {
+			if name == "" || loc[2*g] < 0 || loc[2*g] == loc[2*g+1] {
+				continue
+			}
+			fields[name] = string(b[loc[2*g]:loc[2*g+1]])
+		}
+
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		return pipeline.DeriveRecord(pipeline.Bytes(data), raw, v), nil
+	}
+	return nil, ErrRegexNoMatch
+}
@@ -0,0 +1,79 @@
+package flow_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestRegexParser_Transform(t *testing.T) {
+	var letters []string
+	parser, err := flow.NewRegexParser([]string{
+		`^(?P<user>\w+) logged in from (?P<ip>[\d.]+)(?: port (?P<port>\d+))?$`,
+		`^error: (?P<message>.+)$`,
+	}, func(v string, _ error) {
+		letters = append(letters, v)
+	})
+	assert.NoError(t, err)
+
+	in := make(chan string, 4)
+	in <- "alice logged in from 10.0.0.1 port 22"
+	in <- "bob logged in from 10.0.0.2"
+	in <- "error: disk full"
+	in <- "unrelated"
+	close(in)
+
+	eventC := make(chan pipeline.Event, 32)
+	var records []map[string]any
+	var raws []string
+	for r := range parser.Transform(in, eventC) {
+		data, _ := r.Data().Read()
+		var record map[string]any
+		assert.NoError(t, json.Unmarshal(data, &record))
+		records = append(records, record)
+		raw, _ := r.Raw().Read()
+		raws = append(raws, string(raw))
+	}
+	close(eventC)
+
+	assert.Equal(t, []map[string]any{
+		{"user": "alice", "ip": "10.0.0.1", "port": "22"},
+		{"user": "bob", "ip": "10.0.0.2"},
+		{"message": "disk full"},
+	}, records)
+	assert.Equal(t, "error: disk full", raws[2])
+	assert.Equal(t, []string{"unrelated"}, letters)
+
+	counts := make(map[string]float64)
+	var errs []error
+	for e := range eventC {
+		switch e := e.(type) {
+		case pipeline.MetricEvent:
+			counts[e.Name()+"/"+e.Labels()["pattern"]] = e.Value()
+		case pipeline.ErrorEvent:
+			errs = append(errs, e)
+		}
+	}
+	assert.Equal(t, map[string]float64{
+		flow.MetricRegexHits + "/0":   2,
+		flow.MetricRegexMisses + "/0": 2,
+		flow.MetricRegexHits + "/1":   1,
+		flow.MetricRegexMisses + "/1": 1,
+	}, counts)
+	assert.Len(t, errs, 1)
+	assert.ErrorIs(t, errs[0], flow.ErrRegexNoMatch)
+}
+
+func TestNewRegexParser_Validation(t *testing.T) {
+	_, err := flow.NewRegexParser[string](nil, nil)
+	assert.Error(t, err)
+
+	_, err = flow.NewRegexParser[string]([]string{`(unclosed`}, nil)
+	assert.Error(t, err)
+
+	_, err = flow.NewRegexParser[string]([]string{`(\w+) unnamed`}, nil)
+	assert.Error(t, err)
+}
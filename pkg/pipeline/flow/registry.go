@@ -0,0 +1,1093 @@
+package flow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that AnyFlow implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*AnyFlow[string, int])(nil)
+
+// ErrUnknownFlow is returned when no factory is registered under a name.
+var ErrUnknownFlow = errors.New("unknown flow")
+
+// Factory creates a flow from its configuration, a JSON document that is empty when none is given.
+type Factory func(conf json.RawMessage) (pipeline.Flow[any, any], error)
+
+// the registry of the flow factories
+var registry = pipeline.NewRegistry[Factory](pipeline.StageFlow)
+
+// Register makes a flow factory available by name to NewRegistered. It is meant to be called from the
+// init function of the package providing the flow, which is linked in by a blank import, optionally in
+// a file behind a build tag, or in a plugin loaded with LoadPlugin. The options describe the
+// configuration of the flow, which NewRegistered checks against them. Register panics when the factory
+// is nil or the name is already registered.
+func Register(name string, factory Factory, options ...pipeline.Option) {
+	registry.Register(name, factory, options...)
+}
+
+// Registered returns the sorted names of the registered flows.
+func Registered() []string {
+	return registry.Names()
+}
+
+// Components returns the descriptions of the registered flows, sorted by name.
+func Components() []pipeline.Component {
+	return registry.Components()
+}
+
+// NewRegistered creates the flow registered under the name with its configuration.
+func NewRegistered(name string, conf json.RawMessage) (pipeline.Flow[any, any], error) {
+	factory, ok, err := registry.Factory(name, conf)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFlow, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create flow %s: %w", name, err)
+	}
+
+	f, err := factory(conf)
+	if err != nil {
+		return nil, fmt.Errorf("could not create flow %s: %w", name, err)
+	}
+	return f, nil
+}
+
+// decodeConfig decodes a factory configuration into v, leaving v unchanged when it is empty
+func decodeConfig(conf json.RawMessage, v any) error {
+	return pipeline.DecodeOptions(conf, v)
+}
+
+// AnyFlow is a struct that represents a typed flow adapted to a data stream of any items, as created by
+// factories. Items that are not of the input type are dropped and reported with an error event.
+type AnyFlow[I, O any] struct {
+	flow pipeline.Flow[I, O]
+}
+
+// NewAnyFlow creates a new AnyFlow adapting the flow.
+func NewAnyFlow[I, O any](flow pipeline.Flow[I, O]) *AnyFlow[I, O] {
+	return &AnyFlow[I, O]{
+		flow: flow,
+	}
+}
+
+// Transform passes the items of the input type from the input channel through the flow and returns the
+// output channel of its items.
+func (a AnyFlow[I, O]) Transform(in <-chan any, eventC chan<- pipeline.Event) <-chan any {
+	typed := make(chan I)
+	go func() {
+		defer close(typed)
+		for v := range in {
+			t, ok := v.(I)
+			if !ok {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("flow: unexpected item type",
+					fmt.Errorf("item of type %T is not %T", v, t), false, v))
+				continue
+			}
+			typed <- t
+		}
+	}()
+
+	out := make(chan any)
+	go func() {
+		defer close(out)
+		for v := range a.flow.Transform(typed, eventC) {
+			out <- v
+		}
+	}()
+	return out
+}
+
+// the flows configurable without code are registered by default, those taking Go functions and the
+// fan-outs are not
+func init() {
+	Register("passthrough", func(json.RawMessage) (pipeline.Flow[any, any], error) {
+		return NewPassthrough[any](), nil
+	})
+
+	Register("cel_filter", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Expression string `json:"expression"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		return NewCELFilter[any](c.Expression, nil)
+	}, pipeline.Option{Name: "expression", Type: pipeline.OptionString, Required: true,
+		Description: "CEL expression evaluating to whether an item is kept"})
+
+	Register("cel_transform", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Expression string `json:"expression"`
+			Merge      bool   `json:"merge"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		f, err := NewCELTransform[any](CELTransformConfig{Expression: c.Expression, Merge: c.Merge}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](f), nil
+	},
+		pipeline.Option{Name: "expression", Type: pipeline.OptionString, Required: true,
+			Description: "CEL expression evaluating to the transformed data"},
+		pipeline.Option{Name: "merge", Type: pipeline.OptionBoolean,
+			Description: "merge the fields of the map evaluated into the record instead of replacing it"},
+	)
+
+	Register("starlark", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Script   string `json:"script"`
+			Function string `json:"function"`
+			MaxSteps uint64 `json:"max_steps"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		f, err := NewStarlark[any](StarlarkConfig{Script: c.Script, Function: c.Function, MaxSteps: c.MaxSteps}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](f), nil
+	},
+		pipeline.Option{Name: "script", Type: pipeline.OptionString, Required: true,
+			Description: "Starlark script defining the transform function"},
+		pipeline.Option{Name: "function", Type: pipeline.OptionString, Default: "transform",
+			Description: "name of the function called with each item"},
+		pipeline.Option{Name: "max_steps", Type: pipeline.OptionInteger, Default: 100000,
+			Description: "computation steps of a call at most, longer calls are cancelled"},
+	)
+
+	registerParsers()
+	registerReshapers()
+	registerStateful()
+	registerEnrichers()
+}
+
+// registerParsers registers the flows parsing raw messages into records
+func registerParsers() {
+	locationOption := pipeline.Option{Name: "location", Type: pipeline.OptionString, Default: "UTC",
+		Description: "IANA time zone of RFC 3164 timestamps, which carry none"}
+
+	Register("syslog", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		c, err := decodeSyslogConfig(conf)
+		if err != nil {
+			return nil, err
+		}
+		p, err := NewSyslogParser[any](c, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](p), nil
+	}, locationOption)
+
+	Register("fast_syslog", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		c, err := decodeSyslogConfig(conf)
+		if err != nil {
+			return nil, err
+		}
+		p, err := NewFastSyslogParser[any](c, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](p), nil
+	}, locationOption)
+
+	for name, newParser := range map[string]func(DeadLetterFunc[any]) (*Parser[any], error){
+		"cef":      NewCEFParser[any],
+		"fast_cef": NewFastCEFParser[any],
+		"kv":       NewKVParser[any],
+		"fast_kv":  NewFastKVParser[any],
+	} {
+		Register(name, func(json.RawMessage) (pipeline.Flow[any, any], error) {
+			p, err := newParser(nil)
+			if err != nil {
+				return nil, err
+			}
+			return NewAnyFlow[any, pipeline.DataRawReadable](p), nil
+		})
+	}
+
+	Register("csv", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Delimiter  string            `json:"delimiter"`
+			Columns    []string          `json:"columns"`
+			Types      map[string]string `json:"types"`
+			TimeLayout string            `json:"time_layout"`
+			LazyQuotes bool              `json:"lazy_quotes"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+
+		csvConf := CSVConfig{Columns: c.Columns, TimeLayout: c.TimeLayout, LazyQuotes: c.LazyQuotes}
+		if c.Delimiter != "" {
+			delimiter := []rune(c.Delimiter)
+			if len(delimiter) != 1 {
+				return nil, fmt.Errorf("%w: delimiter %q is not a single character", pipeline.ErrOptions, c.Delimiter)
+			}
+			csvConf.Delimiter = delimiter[0]
+		}
+		for column, name := range c.Types {
+			t, ok := map[string]CSVType{
+				"string": CSVString, "int": CSVInt, "float": CSVFloat, "bool": CSVBool, "time": CSVTime,
+			}[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: type %q of column %s must be one of string, int, float, bool, time",
+					pipeline.ErrOptions, name, column)
+			}
+			if csvConf.Types == nil {
+				csvConf.Types = make(map[string]CSVType, len(c.Types))
+			}
+			csvConf.Types[column] = t
+		}
+
+		p, err := NewCSVParser[any](csvConf, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](p), nil
+	},
+		pipeline.Option{Name: "delimiter", Type: pipeline.OptionString, Default: ",",
+			Description: "field delimiter, a tab for TSV"},
+		pipeline.Option{Name: "columns", Type: pipeline.OptionArray,
+			Description: "column names, read from the first row when empty"},
+		pipeline.Option{Name: "types", Type: pipeline.OptionObject,
+			Description: "string, int, float, bool or time by column, columns not listed are strings"},
+		pipeline.Option{Name: "time_layout", Type: pipeline.OptionString, Default: time.RFC3339,
+			Description: "Go layout of the time columns"},
+		pipeline.Option{Name: "lazy_quotes", Type: pipeline.OptionBoolean,
+			Description: "allow quotes within unquoted fields"},
+	)
+
+	Register("grok", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Expressions []string          `json:"expressions"`
+			Patterns    map[string]string `json:"patterns"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		p, err := NewGrokParser[any](GrokConfig{Expressions: c.Expressions, Patterns: c.Patterns}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](p), nil
+	},
+		pipeline.Option{Name: "expressions", Type: pipeline.OptionArray, Required: true,
+			Description: "grok expressions tried in order until one matches"},
+		pipeline.Option{Name: "patterns", Type: pipeline.OptionObject,
+			Description: "custom patterns by name added to the standard library"},
+	)
+
+	Register("regex", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Patterns []string `json:"patterns"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		p, err := NewRegexParser[any](c.Patterns, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](p), nil
+	}, pipeline.Option{Name: "patterns", Type: pipeline.OptionArray, Required: true,
+		Description: "regular expressions with named groups tried in order until one matches"})
+
+	Register("parse_json", func(json.RawMessage) (pipeline.Flow[any, any], error) {
+		return NewAnyFlow[any, map[string]any](NewParseJSON[any, map[string]any](nil)), nil
+	})
+
+	Register("avro", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			URL      string            `json:"url"`
+			Username string            `json:"username"`
+			Password string            `json:"password"`
+			Timeout  pipeline.Duration `json:"timeout"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		registry, err := NewSchemaRegistryClient(SchemaRegistryConfig{
+			URL: c.URL, Username: c.Username, Password: c.Password, Timeout: time.Duration(c.Timeout),
+		})
+		if err != nil {
+			return nil, err
+		}
+		d, err := NewAvroDecoder[any](registry, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](d), nil
+	},
+		pipeline.Option{Name: "url", Type: pipeline.OptionString, Required: true,
+			Description: "base URL of the schema registry"},
+		pipeline.Option{Name: "username", Type: pipeline.OptionString,
+			Description: "basic auth user of the schema registry"},
+		pipeline.Option{Name: "password", Type: pipeline.OptionString,
+			Description: "basic auth password of the schema registry"},
+		pipeline.Option{Name: "timeout", Type: pipeline.OptionDuration, Default: "10s",
+			Description: "schema registry request timeout"},
+	)
+}
+
+// decodeSyslogConfig decodes the configuration of the syslog parsers
+func decodeSyslogConfig(conf json.RawMessage) (SyslogConfig, error) {
+	var c struct {
+		Location string `json:"location"`
+	}
+	if err := decodeConfig(conf, &c); err != nil {
+		return SyslogConfig{}, err
+	}
+	if c.Location == "" {
+		return SyslogConfig{}, nil
+	}
+	loc, err := time.LoadLocation(c.Location)
+	if err != nil {
+		return SyslogConfig{}, fmt.Errorf("%w: %w", pipeline.ErrOptions, err)
+	}
+	return SyslogConfig{Location: loc}, nil
+}
+
+// registerReshapers registers the flows reshaping, encoding and protecting records
+func registerReshapers() {
+	Register("projection", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Include []string          `json:"include"`
+			Exclude []string          `json:"exclude"`
+			Rename  map[string]string `json:"rename"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		p, err := NewProjection[any](ProjectionConfig{Include: c.Include, Exclude: c.Exclude, Rename: c.Rename}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](p), nil
+	},
+		pipeline.Option{Name: "include", Type: pipeline.OptionArray,
+			Description: "paths of the fields to keep, every field is kept when empty"},
+		pipeline.Option{Name: "exclude", Type: pipeline.OptionArray,
+			Description: "paths of the fields to remove, applied after include"},
+		pipeline.Option{Name: "rename", Type: pipeline.OptionObject,
+			Description: "destination paths by the path of the fields moved, applied last"},
+	)
+
+	Register("remap", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Operations []struct {
+				Op    string `json:"op"`
+				Field string `json:"field"`
+				To    string `json:"to"`
+				Value any    `json:"value"`
+				Type  string `json:"type"`
+			} `json:"operations"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		ops := make([]RemapOperation, len(c.Operations))
+		for i, op := range c.Operations {
+			ops[i] = RemapOperation{Op: op.Op, Field: op.Field, To: op.To, Value: op.Value, Type: op.Type}
+		}
+		r, err := NewRemap[any](ops, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](r), nil
+	}, pipeline.Option{Name: "operations", Type: pipeline.OptionArray, Required: true,
+		Description: "operations of op rename, move, default, delete or convert on a field, with their to, value or type"})
+
+	Register("template", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Text   string `json:"text"`
+			Strict bool   `json:"strict"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		t, err := NewTemplate[any](TemplateConfig{Text: c.Text, Strict: c.Strict})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](t), nil
+	},
+		pipeline.Option{Name: "text", Type: pipeline.OptionString, Required: true,
+			Description: "text/template rendered with each record"},
+		pipeline.Option{Name: "strict", Type: pipeline.OptionBoolean,
+			Description: "fail on missing fields rather than rendering them as <no value>"},
+	)
+
+	Register("hash_fields", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Fields []string `json:"fields"`
+			Salt   string   `json:"salt"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		h, err := NewHashFields[any](HashFieldsConfig{Fields: c.Fields, Salt: []byte(c.Salt)}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](h), nil
+	},
+		pipeline.Option{Name: "fields", Type: pipeline.OptionArray, Required: true,
+			Description: "paths of the fields replaced by their HMAC"},
+		pipeline.Option{Name: "salt", Type: pipeline.OptionString, Required: true,
+			Description: "HMAC key, hashes are only comparable between flows with the same salt"},
+	)
+
+	keysOption := pipeline.Option{Name: "keys", Type: pipeline.OptionObject, Required: true,
+		Description: "base64 encoded AES keys of 16, 24 or 32 bytes by key ID"}
+
+	Register("encrypt", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Keys  map[string][]byte `json:"keys"`
+			KeyID string            `json:"key_id"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		e, err := NewEncrypt[any](KeyringConfig{Keys: c.Keys, KeyID: c.KeyID})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](e), nil
+	}, keysOption, pipeline.Option{Name: "key_id", Type: pipeline.OptionString, Required: true,
+		Description: "ID of the key that encrypts"})
+
+	Register("decrypt", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Keys map[string][]byte `json:"keys"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		d, err := NewDecrypt[any](KeyringConfig{Keys: c.Keys}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](d), nil
+	}, keysOption)
+
+	Register("compress", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Codec string `json:"codec"`
+			Level int    `json:"level"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		f, err := NewCompress[any](CompressConfig{Codec: c.Codec, Level: c.Level})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](f), nil
+	},
+		pipeline.Option{Name: "codec", Type: pipeline.OptionString, Required: true,
+			Description: "gzip, zstd or snappy"},
+		pipeline.Option{Name: "level", Type: pipeline.OptionInteger,
+			Description: "compression level of the codec, defaults to its default level"},
+	)
+
+	Register("decompress", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Codec   string `json:"codec"`
+			MaxSize int64  `json:"max_size"`
+			Strict  bool   `json:"strict"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		decompressConf := DecompressConfig[any]{MaxSize: c.MaxSize, Strict: c.Strict}
+		if c.Codec != "" {
+			decompressConf.Codec = func(any) string { return c.Codec }
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](NewDecompress(decompressConf)), nil
+	},
+		pipeline.Option{Name: "codec", Type: pipeline.OptionString,
+			Description: "gzip, zstd or snappy, detected from the payload when empty"},
+		pipeline.Option{Name: "max_size", Type: pipeline.OptionInteger, Default: 64 << 20,
+			Description: "decompressed size limit in bytes"},
+		pipeline.Option{Name: "strict", Type: pipeline.OptionBoolean,
+			Description: "reject payloads in no known format rather than passing them unchanged"},
+	)
+
+	Register("wasm", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Module      string            `json:"module"`
+			Timeout     pipeline.Duration `json:"timeout"`
+			MemoryPages uint32            `json:"memory_pages"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		module, err := os.ReadFile(c.Module)
+		if err != nil {
+			return nil, err
+		}
+		w, err := NewWASM[any](WASMConfig{Module: module, Timeout: time.Duration(c.Timeout), MemoryPages: c.MemoryPages}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.DataRawReadable](w), nil
+	},
+		pipeline.Option{Name: "module", Type: pipeline.OptionString, Required: true,
+			Description: "path of the WebAssembly module implementing the transform ABI"},
+		pipeline.Option{Name: "timeout", Type: pipeline.OptionDuration, Default: "1s",
+			Description: "deadline of the transform of an item"},
+		pipeline.Option{Name: "memory_pages", Type: pipeline.OptionInteger, Default: 256,
+			Description: "bound on the linear memory of the module in 64KiB pages"},
+	)
+}
+
+// registerStateful registers the flows batching, framing, sampling and aggregating items
+func registerStateful() {
+	Register("batch", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Size   int               `json:"size"`
+			MaxAge pipeline.Duration `json:"max_age"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, []any](NewBatch[any](c.Size, time.Duration(c.MaxAge))), nil
+	},
+		pipeline.Option{Name: "size", Type: pipeline.OptionInteger, Default: 100,
+			Description: "items of a batch at most"},
+		pipeline.Option{Name: "max_age", Type: pipeline.OptionDuration,
+			Description: "time a batch is held at most before it is emitted, zero holds it until full"},
+	)
+
+	Register("flatten", func(json.RawMessage) (pipeline.Flow[any, any], error) {
+		return NewAnyFlow[[]any, any](NewFlatten[any]()), nil
+	})
+
+	Register("buffer", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Size int `json:"size"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		return NewBuffer[any](c.Size), nil
+	}, pipeline.Option{Name: "size", Type: pipeline.OptionInteger, Default: 1,
+		Description: "items buffered at most"})
+
+	countOption := pipeline.Option{Name: "n", Type: pipeline.OptionInteger, Required: true,
+		Description: "number of items"}
+
+	Register("take", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			N int `json:"n"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		return NewTake[any](c.N), nil
+	}, countOption)
+
+	Register("skip", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			N int `json:"n"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		return NewSkip[any](c.N), nil
+	}, countOption)
+
+	Register("chunker", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Delimiter string `json:"delimiter"`
+			MaxLength int    `json:"max_length"`
+			TrimCR    bool   `json:"trim_cr"`
+			KeepEmpty bool   `json:"keep_empty"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.Readable](NewChunker(ChunkerConfig[any]{
+			Delimiter: []byte(c.Delimiter), MaxLength: c.MaxLength, TrimCR: c.TrimCR, KeepEmpty: c.KeepEmpty,
+		})), nil
+	},
+		pipeline.Option{Name: "delimiter", Type: pipeline.OptionString, Default: "\n",
+			Description: "separator of records"},
+		pipeline.Option{Name: "max_length", Type: pipeline.OptionInteger, Default: 1 << 20,
+			Description: "length of a record at most, longer records are dropped"},
+		pipeline.Option{Name: "trim_cr", Type: pipeline.OptionBoolean,
+			Description: "remove a carriage return at the end of records"},
+		pipeline.Option{Name: "keep_empty", Type: pipeline.OptionBoolean,
+			Description: "emit empty records between consecutive delimiters"},
+	)
+
+	Register("multiline", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Start     string            `json:"start"`
+			Continue  string            `json:"continue"`
+			Key       string            `json:"key"`
+			Separator string            `json:"separator"`
+			MaxLines  int               `json:"max_lines"`
+			Timeout   pipeline.Duration `json:"timeout"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		key, err := optionalFieldKey(c.Key)
+		if err != nil {
+			return nil, err
+		}
+		m, err := NewMultiline(MultilineConfig[any]{
+			Start: c.Start, Continue: c.Continue, Key: key,
+			Separator: c.Separator, MaxLines: c.MaxLines, Timeout: time.Duration(c.Timeout),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, pipeline.Readable](m), nil
+	},
+		pipeline.Option{Name: "start", Type: pipeline.OptionString,
+			Description: "regex matching the first line of a record"},
+		pipeline.Option{Name: "continue", Type: pipeline.OptionString,
+			Description: "regex matching the lines continuing the record before them"},
+		keyOption("source of a line, whose lines are aggregated separately"),
+		pipeline.Option{Name: "separator", Type: pipeline.OptionString, Default: "\n",
+			Description: "joins the lines of a record"},
+		pipeline.Option{Name: "max_lines", Type: pipeline.OptionInteger, Default: 500,
+			Description: "lines of a record at most"},
+		pipeline.Option{Name: "timeout", Type: pipeline.OptionDuration, Default: "1s",
+			Description: "time without a line of its source after which a record is emitted"},
+	)
+
+	Register("dedup", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Key     string            `json:"key"`
+			TTL     pipeline.Duration `json:"ttl"`
+			MaxKeys int               `json:"max_keys"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		dedupConf := DedupConfig[any]{TTL: time.Duration(c.TTL), MaxKeys: c.MaxKeys}
+		if c.Key != "" {
+			key, err := fieldKey(c.Key)
+			if err != nil {
+				return nil, err
+			}
+			// items without the field are identified by their content
+			dedupConf.Key = func(v any) string {
+				if k, ok := key(v); ok {
+					return "field:" + k
+				}
+				b, _ := payload(v)
+				return "payload:" + string(b)
+			}
+		}
+		d, err := NewDedup(dedupConf)
+		if err != nil {
+			return nil, err
+		}
+		return d, nil
+	},
+		keyOption("identity of a record, records are identified by their content when empty"),
+		pipeline.Option{Name: "ttl", Type: pipeline.OptionDuration, Required: true,
+			Description: "how long a key is remembered after it was first passed"},
+		pipeline.Option{Name: "max_keys", Type: pipeline.OptionInteger, Default: 100000,
+			Description: "bound on the keys remembered"},
+	)
+
+	Register("debounce", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Quiet pipeline.Duration `json:"quiet"`
+			Key   string            `json:"key"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		key, err := fieldKey(c.Key)
+		if err != nil {
+			return nil, err
+		}
+		return NewDebounce(time.Duration(c.Quiet), func(v any) string {
+			k, _ := key(v)
+			return k
+		})
+	},
+		pipeline.Option{Name: "quiet", Type: pipeline.OptionDuration, Required: true,
+			Description: "time without an item of a key after which its last item is emitted"},
+		pipeline.Option{Name: "key", Type: pipeline.OptionString, Required: true,
+			Description: "path of the field of JSON records holding the key of an item"},
+	)
+
+	Register("throughput", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Name     string            `json:"name"`
+			Interval pipeline.Duration `json:"interval"`
+			Key      string            `json:"key"`
+			MaxKeys  int               `json:"max_keys"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		key, err := optionalFieldKey(c.Key)
+		if err != nil {
+			return nil, err
+		}
+		return NewThroughput(ThroughputConfig[any]{
+			Name: c.Name, Interval: time.Duration(c.Interval), Key: key, MaxKeys: c.MaxKeys,
+		})
+	},
+		pipeline.Option{Name: "name", Type: pipeline.OptionString, Required: true,
+			Description: "name of the measured point used as the flow label"},
+		pipeline.Option{Name: "interval", Type: pipeline.OptionDuration, Default: "10s",
+			Description: "interval between metric events"},
+		keyOption("key label of the metrics broken down per key"),
+		pipeline.Option{Name: "max_keys", Type: pipeline.OptionInteger, Default: 100,
+			Description: "keys tracked at most, later keys are counted as other"},
+	)
+
+	Register("window", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Size        pipeline.Duration `json:"size"`
+			Key         string            `json:"key"`
+			Sum         string            `json:"sum"`
+			Time        string            `json:"time"`
+			Lateness    pipeline.Duration `json:"lateness"`
+			IdleTimeout pipeline.Duration `json:"idle_timeout"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		key, err := optionalFieldKey(c.Key)
+		if err != nil {
+			return nil, err
+		}
+		if key == nil {
+			key = func(any) string { return "" }
+		}
+		eventTime, err := optionalFieldTime(c.Time)
+		if err != nil {
+			return nil, err
+		}
+
+		// the aggregate is the sum of the field, the window results always count the items
+		fold := func(acc float64, _ any) float64 { return acc }
+		if c.Sum != "" {
+			path, err := fieldPath(c.Sum)
+			if err != nil {
+				return nil, err
+			}
+			fold = func(acc float64, v any) float64 {
+				n, _ := fieldNumber(v, path)
+				return acc + n
+			}
+		}
+
+		w, err := NewTumblingWindow(WindowConfig[any, string, float64]{
+			Size: time.Duration(c.Size), Key: KeyFunc[any, string](key), Fold: fold, Time: eventTime,
+			Lateness: time.Duration(c.Lateness), IdleTimeout: time.Duration(c.IdleTimeout),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnyFlow[any, WindowResult[string, float64]](w), nil
+	},
+		pipeline.Option{Name: "size", Type: pipeline.OptionDuration, Required: true,
+			Description: "length of the windows"},
+		keyOption("key of the items aggregated together, all items share a window when empty"),
+		pipeline.Option{Name: "sum", Type: pipeline.OptionString,
+			Description: "path of the numeric field of JSON records summed as the value of a window"},
+		timeOption,
+		pipeline.Option{Name: "lateness", Type: pipeline.OptionDuration,
+			Description: "how far behind the latest event time an item may arrive and still be counted"},
+		pipeline.Option{Name: "idle_timeout", Type: pipeline.OptionDuration,
+			Description: "time without an item after which all open windows are emitted, zero disables it"},
+	)
+
+	Register("reorder", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Time        string            `json:"time"`
+			Lateness    pipeline.Duration `json:"lateness"`
+			MaxItems    int               `json:"max_items"`
+			IdleTimeout pipeline.Duration `json:"idle_timeout"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		eventTime, err := optionalFieldTime(c.Time)
+		if err != nil {
+			return nil, err
+		}
+		return NewReorder(ReorderConfig[any]{
+			Time: eventTime, Lateness: time.Duration(c.Lateness), MaxItems: c.MaxItems,
+			IdleTimeout: time.Duration(c.IdleTimeout),
+		})
+	},
+		pipeline.Option{Name: "time", Type: pipeline.OptionString, Required: true,
+			Description: timeOption.Description},
+		pipeline.Option{Name: "lateness", Type: pipeline.OptionDuration,
+			Description: "how far behind the latest event time an item may arrive and still be emitted in order"},
+		pipeline.Option{Name: "max_items", Type: pipeline.OptionInteger, Default: 10000,
+			Description: "items held at most, the earliest is emitted early when exceeded"},
+		pipeline.Option{Name: "idle_timeout", Type: pipeline.OptionDuration,
+			Description: "time without an item after which all held items are emitted, zero disables it"},
+	)
+}
+
+// registerEnrichers registers the flows enriching records with looked up values
+func registerEnrichers() {
+	targetOption := pipeline.Option{Name: "target", Type: pipeline.OptionString, Required: true,
+		Description: "path of the field the value of the key is set at"}
+
+	Register("lookup_table", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Path      string            `json:"path"`
+			Format    string            `json:"format"`
+			KeyColumn string            `json:"key_column"`
+			Key       string            `json:"key"`
+			Target    string            `json:"target"`
+			Refresh   pipeline.Duration `json:"refresh"`
+			Timeout   pipeline.Duration `json:"timeout"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		key, err := fieldKey(c.Key)
+		if err != nil {
+			return nil, err
+		}
+		target, err := fieldPath(c.Target)
+		if err != nil {
+			return nil, err
+		}
+
+		switch c.Format {
+		case "", "csv":
+			if c.KeyColumn == "" {
+				return nil, fmt.Errorf("%w: key_column is required by csv tables", pipeline.ErrOptions)
+			}
+			return NewLookupTable(LookupTableConfig[any, map[string]string]{
+				Key: key, Load: LoadCSVTable(c.Path, c.KeyColumn), Merge: mergeField[map[string]string](target),
+				Refresh: time.Duration(c.Refresh), Timeout: time.Duration(c.Timeout),
+			})
+		case "json":
+			return NewLookupTable(LookupTableConfig[any, any]{
+				Key: key, Load: LoadJSONTable[any](c.Path), Merge: mergeField[any](target),
+				Refresh: time.Duration(c.Refresh), Timeout: time.Duration(c.Timeout),
+			})
+		default:
+			return nil, fmt.Errorf("%w: format must be one of csv, json", pipeline.ErrOptions)
+		}
+	},
+		pipeline.Option{Name: "path", Type: pipeline.OptionString, Required: true,
+			Description: "path of the table file"},
+		pipeline.Option{Name: "format", Type: pipeline.OptionString, Default: "csv",
+			Description: "csv, with a header row, or json, an object of the values by key"},
+		pipeline.Option{Name: "key_column", Type: pipeline.OptionString,
+			Description: "column of csv tables holding the key of a row"},
+		pipeline.Option{Name: "key", Type: pipeline.OptionString, Required: true,
+			Description: "path of the field of JSON records holding the lookup key"},
+		targetOption,
+		pipeline.Option{Name: "refresh", Type: pipeline.OptionDuration,
+			Description: "interval between reloads of the table, zero loads it once"},
+		pipeline.Option{Name: "timeout", Type: pipeline.OptionDuration, Default: "30s",
+			Description: "deadline of a load"},
+	)
+
+	Register("dns", func(conf json.RawMessage) (pipeline.Flow[any, any], error) {
+		var c struct {
+			Key         string            `json:"key"`
+			Target      string            `json:"target"`
+			TTL         pipeline.Duration `json:"ttl"`
+			NegativeTTL pipeline.Duration `json:"negative_ttl"`
+			MaxKeys     int               `json:"max_keys"`
+			Timeout     pipeline.Duration `json:"timeout"`
+			Workers     int               `json:"workers"`
+			Ordered     bool              `json:"ordered"`
+		}
+		if err := decodeConfig(conf, &c); err != nil {
+			return nil, err
+		}
+		key, err := fieldKey(c.Key)
+		if err != nil {
+			return nil, err
+		}
+		target, err := fieldPath(c.Target)
+		if err != nil {
+			return nil, err
+		}
+		return NewDNS(EnrichConfig[any, []string]{
+			Key: key, Merge: mergeField[[]string](target),
+			TTL: time.Duration(c.TTL), NegativeTTL: time.Duration(c.NegativeTTL), MaxKeys: c.MaxKeys,
+			Timeout: time.Duration(c.Timeout), Workers: c.Workers, Ordered: c.Ordered,
+		}, nil)
+	},
+		pipeline.Option{Name: "key", Type: pipeline.OptionString, Required: true,
+			Description: "path of the field of JSON records holding the IP address or host name resolved"},
+		targetOption,
+		pipeline.Option{Name: "ttl", Type: pipeline.OptionDuration, Default: "5m",
+			Description: "how long results are cached"},
+		pipeline.Option{Name: "negative_ttl", Type: pipeline.OptionDuration, Default: "1m",
+			Description: "how long names and addresses not found are cached"},
+		pipeline.Option{Name: "max_keys", Type: pipeline.OptionInteger, Default: 10000,
+			Description: "bound on the keys cached"},
+		pipeline.Option{Name: "timeout", Type: pipeline.OptionDuration, Default: "2s",
+			Description: "deadline of a lookup"},
+		pipeline.Option{Name: "workers", Type: pipeline.OptionInteger, Default: 8,
+			Description: "items resolved concurrently"},
+		pipeline.Option{Name: "ordered", Type: pipeline.OptionBoolean,
+			Description: "preserve the input order with several workers"},
+	)
+}
+
+// timeOption is the option of the field holding the event time of JSON records
+var timeOption = pipeline.Option{Name: "time", Type: pipeline.OptionString,
+	Description: "path of the field of JSON records holding the event time, as RFC 3339 or Unix seconds"}
+
+// keyOption returns the option of the field of JSON records holding a key
+func keyOption(description string) pipeline.Option {
+	return pipeline.Option{Name: "key", Type: pipeline.OptionString,
+		Description: "path of the field of JSON records holding the " + description}
+}
+
+// fieldPath parses the path of a field in the notation of ProjectionConfig, without wildcards
+func fieldPath(expr string) ([]fieldSegment, error) {
+	path, err := parseFieldPath(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", pipeline.ErrOptions, err)
+	}
+	for _, seg := range path {
+		if seg.wildcard {
+			return nil, fmt.Errorf("%w: path %q: wildcards are not supported", pipeline.ErrOptions, expr)
+		}
+	}
+	return path, nil
+}
+
+// fieldValue returns the value at the path of the JSON object payload of an item
+func fieldValue(v any, path []fieldSegment) (any, bool) {
+	b, err := payload(v)
+	if err != nil {
+		return nil, false
+	}
+	obj, err := decodeObject(b)
+	if err != nil {
+		return nil, false
+	}
+	return getPath(obj, path)
+}
+
+// fieldKey returns a function reading the field at the path of JSON records as a key, strings as is and
+// other values as JSON, or false for items without the field
+func fieldKey(expr string) (func(any) (string, bool), error) {
+	path, err := fieldPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return func(v any) (string, bool) {
+		value, ok := fieldValue(v, path)
+		if !ok || value == nil {
+			return "", false
+		}
+		if s, ok := value.(string); ok {
+			return s, true
+		}
+		b, err := json.Marshal(value)
+		return string(b), err == nil
+	}, nil
+}
+
+// optionalFieldKey returns fieldKey with an empty key for items without the field, or nil when the path
+// is empty
+func optionalFieldKey(expr string) (func(any) string, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	key, err := fieldKey(expr)
+	if err != nil {
+		return nil, err
+	}
+	return func(v any) string {
+		k, _ := key(v)
+		return k
+	}, nil
+}
+
+// fieldNumber returns the number in the field at the path of a JSON record
+func fieldNumber(v any, path []fieldSegment) (float64, bool) {
+	value, ok := fieldValue(v, path)
+	if !ok {
+		return 0, false
+	}
+	switch n := value.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// optionalFieldTime returns a function reading the event time of JSON records from the field at the path,
+// RFC 3339 strings or Unix seconds, and the arrival time for items without a valid time, or nil when the
+// path is empty
+func optionalFieldTime(expr string) (func(any) time.Time, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	path, err := fieldPath(expr)
+	if err != nil {
+		return nil, err
+	}
+	return func(v any) time.Time {
+		value, ok := fieldValue(v, path)
+		if !ok {
+			return time.Now()
+		}
+		switch t := value.(type) {
+		case string:
+			if ts, err := time.Parse(time.RFC3339Nano, t); err == nil {
+				return ts
+			}
+		case json.Number:
+			if f, err := t.Float64(); err == nil {
+				return time.Unix(0, int64(f*float64(time.Second)))
+			}
+		}
+		return time.Now()
+	}, nil
+}
+
+// mergeField returns a function setting the value of a key at the path of JSON records. Items that are not
+// JSON objects are returned unchanged.
+func mergeField[V any](path []fieldSegment) func(any, V) any {
+	return func(v any, value V) any {
+		b, err := payload(v)
+		if err != nil {
+			return v
+		}
+		obj, err := decodeObject(b)
+		if err != nil {
+			return v
+		}
+		setPath(obj, path, value)
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return v
+		}
+		return pipeline.DeriveRecord(pipeline.Bytes(data), rawOrPayload(v, b), v)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !plugins
+
+package flow
+
+import "errors"
+
+// LoadPlugin opens a Go plugin whose init functions register its flows, and the sources and sinks it
+// registers with their packages. Plugin support is only built with the plugins build tag, as linking
+// the plugin package keeps every exported method in the binary.
+func LoadPlugin(string) error {
+	return errors.New("plugin support is not built in, build with -tags plugins")
+}
@@ -0,0 +1,18 @@
+//go:build plugins
+
+package flow
+
+import (
+	"fmt"
+	"plugin"
+)
+
+// LoadPlugin opens a Go plugin whose init functions register its flows, and the sources and sinks it
+// registers with their packages. Plugins must be built with the same toolchain and module versions as
+// the program, and are only supported where the plugin package is.
+func LoadPlugin(path string) error {
+	if _, err := plugin.Open(path); err != nil {
+		return fmt.Errorf("could not load plugin: %w", err)
+	}
+	return nil
+}
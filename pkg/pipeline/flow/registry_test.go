@@ -0,0 +1,185 @@
+package flow_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func init() {
+	flow.Register("test_length", func(json.RawMessage) (pipeline.Flow[any, any], error) {
+		m, err := flow.NewMap(func(s string) (int, error) { return len(s), nil })
+		if err != nil {
+			return nil, err
+		}
+		return flow.NewAnyFlow[string, int](m), nil
+	})
+}
+
+func TestNewRegistered(t *testing.T) {
+	assert.Subset(t, flow.Registered(), []string{
+		"avro", "batch", "buffer", "cef", "cel_filter", "cel_transform", "chunker", "compress", "csv", "debounce",
+		"decompress", "decrypt", "dedup", "dns", "encrypt", "fast_cef", "fast_kv", "fast_syslog", "flatten",
+		"grok", "hash_fields", "kv", "lookup_table", "multiline", "parse_json", "passthrough", "projection",
+		"regex", "remap", "reorder", "skip", "starlark", "syslog", "take", "template", "test_length",
+		"throughput", "wasm", "window",
+	})
+
+	f, err := flow.NewRegistered("test_length", nil)
+	assert.NoError(t, err)
+
+	in := make(chan any, 3)
+	in <- "abc"
+	in <- 42 // not a string
+	in <- "de"
+	close(in)
+
+	eventC := make(chan pipeline.Event, 1)
+	var got []any
+	for v := range f.Transform(in, eventC) {
+		got = append(got, v)
+	}
+	assert.Equal(t, []any{3, 2}, got)
+	assert.Equal(t, 42, (<-eventC).(pipeline.ErrorEvent).Record())
+}
+
+func TestNewRegistered_Builtin(t *testing.T) {
+	f, err := flow.NewRegistered("cel_filter", json.RawMessage(`{"expression": "record.n > 1"}`))
+	assert.NoError(t, err)
+
+	in := make(chan any, 2)
+	in <- `{"n": 1}`
+	in <- `{"n": 2}`
+	close(in)
+
+	var got []any
+	for v := range f.Transform(in, nil) {
+		got = append(got, v)
+	}
+	assert.Equal(t, []any{`{"n": 2}`}, got)
+
+	_, err = flow.NewRegistered("cel_filter", json.RawMessage(`{"expression": "record."}`))
+	assert.Error(t, err)
+
+	_, err = flow.NewRegistered("cel_filter", json.RawMessage(`{"expr": "record.n > 1"}`))
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+}
+
+// transform passes the items through the flow and returns the data of the records it emits, or the
+// items it passes unchanged.
+func transform(t *testing.T, f pipeline.Flow[any, any], items ...any) []string {
+	t.Helper()
+	in := make(chan any, len(items))
+	for _, item := range items {
+		in <- item
+	}
+	close(in)
+
+	var got []string
+	for v := range f.Transform(in, nil) {
+		switch v := v.(type) {
+		case string: // passed unchanged
+			got = append(got, v)
+		case pipeline.DataReadable:
+			b, err := v.Data().Read()
+			assert.NoError(t, err)
+			got = append(got, string(b))
+		default:
+			t.Errorf("%T is not a record", v)
+		}
+	}
+	return got
+}
+
+func TestNewRegistered_Parsers(t *testing.T) {
+	f, err := flow.NewRegistered("kv", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"a":"1","b":"x"}`}, transform(t, f, "a=1 b=x"))
+
+	f, err = flow.NewRegistered("csv", json.RawMessage(`{"columns": ["n", "s"], "types": {"n": "int"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"n":1,"s":"x"}`}, transform(t, f, "1,x"))
+
+	_, err = flow.NewRegistered("csv", json.RawMessage(`{"delimiter": ";;"}`))
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+
+	_, err = flow.NewRegistered("csv", json.RawMessage(`{"types": {"n": "decimal"}}`))
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+
+	_, err = flow.NewRegistered("syslog", json.RawMessage(`{"location": "Nowhere/Town"}`))
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+
+	_, err = flow.NewRegistered("grok", nil)
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+}
+
+func TestNewRegistered_Reshapers(t *testing.T) {
+	f, err := flow.NewRegistered("projection", json.RawMessage(`{"exclude": ["b"], "rename": {"a": "c"}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"c":1}`}, transform(t, f, `{"a": 1, "b": 2}`))
+
+	f, err = flow.NewRegistered("remap", json.RawMessage(`{"operations": [{"op": "default", "field": "b", "value": 2}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"a":1,"b":2}`}, transform(t, f, `{"a": 1}`))
+
+	f, err = flow.NewRegistered("template", json.RawMessage(`{"text": "n={{.n}}"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"n=1"}, transform(t, f, `{"n": 1}`))
+
+	f, err = flow.NewRegistered("compress", json.RawMessage(`{"codec": "gzip"}`))
+	assert.NoError(t, err)
+	d, err := flow.NewRegistered("decompress", nil)
+	assert.NoError(t, err)
+	in := make(chan any, 1)
+	in <- "payload"
+	close(in)
+	assert.Equal(t, []string{"payload"}, transform(t, d, <-f.Transform(in, nil)))
+}
+
+func TestNewRegistered_Stateful(t *testing.T) {
+	f, err := flow.NewRegistered("take", json.RawMessage(`{"n": 2}`))
+	assert.NoError(t, err)
+	assert.Len(t, transform(t, f, `{}`, `{}`, `{}`), 2)
+
+	f, err = flow.NewRegistered("dedup", json.RawMessage(`{"key": "id", "ttl": "1m"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"id": 1, "n": 1}`, `{"id": 2}`, `{}`},
+		transform(t, f, `{"id": 1, "n": 1}`, `{"id": 1, "n": 2}`, `{"id": 2}`, `{}`, `{}`))
+
+	_, err = flow.NewRegistered("dedup", json.RawMessage(`{"key": "a[*]", "ttl": "1m"}`))
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+
+	_, err = flow.NewRegistered("take", nil)
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+}
+
+func TestNewRegistered_LookupTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "table.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"10.0.0.1": {"host": "db"}}`), 0o600))
+
+	f, err := flow.NewRegistered("lookup_table", json.RawMessage(
+		`{"path": "`+path+`", "format": "json", "key": "ip", "target": "asset"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`{"asset":{"host":"db"},"ip":"10.0.0.1"}`, `{"ip": "10.0.0.2"}`},
+		transform(t, f, `{"ip": "10.0.0.1"}`, `{"ip": "10.0.0.2"}`))
+
+	_, err = flow.NewRegistered("lookup_table", json.RawMessage(
+		`{"path": "`+path+`", "key": "ip", "target": "asset"}`))
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+}
+
+func TestNewRegistered_Unknown(t *testing.T) {
+	_, err := flow.NewRegistered("missing", nil)
+	assert.ErrorIs(t, err, flow.ErrUnknownFlow)
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	assert.Panics(t, func() {
+		flow.Register("passthrough", func(json.RawMessage) (pipeline.Flow[any, any], error) { return nil, nil })
+	})
+}
@@ -0,0 +1,212 @@
+package flow
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Remap implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*Remap[any])(nil)
+
+// Remap operations
+const (
+	RemapRename  = "rename"  // renames the field within its object to To, a field name
+	RemapMove    = "move"    // moves the field to the path To, creating the objects leading to it
+	RemapDefault = "default" // sets the field to Value when it is missing or null
+	RemapDelete  = "delete"  // removes the field
+	RemapConvert = "convert" // converts the field to Type
+)
+
+// Remap conversion types
+const (
+	RemapString = "string"
+	RemapInt    = "int"
+	RemapFloat  = "float"
+	RemapBool   = "bool"
+)
+
+// RemapOperation is an operation of a remap.
+// Fields are paths in the notation of ProjectionConfig; wildcards are supported by delete and convert.
+type RemapOperation struct {
+	Op    string // one of the Remap operations
+	Field string // path of the field operated on
+	To    string // new name of rename or destination path of move
+	Value any    // value of default
+	Type  string // one of the Remap conversion types of convert
+}
+
+// remapOp is a RemapOperation with parsed paths
+type remapOp struct {
+	RemapOperation
+	field []fieldSegment
+	to    []fieldSegment
+}
+
+// Remap is a struct that represents declarative reshaping of JSON records on a data stream.
+type Remap[I any] struct {
+	ops        []remapOp
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewRemap creates a new Remap flow applying the operations in order.
+// Items that are not JSON objects or fail a conversion are passed to deadLetter when it is not nil.
+func NewRemap[I any](ops []RemapOperation, deadLetter DeadLetterFunc[I]) (*Remap[I], error) {
+	r := &Remap[I]{deadLetter: deadLetter}
+	for i, op := range ops {
+		field, err := parseFieldPath(op.Field)
+		if err != nil {
+			return nil, fmt.Errorf("remap operation %d: %w", i, err)
+		}
+		parsed := remapOp{RemapOperation: op, field: field}
+
+		switch op.Op {
+		case RemapRename:
+			if op.To == "" {
+				return nil, fmt.Errorf("remap operation %d: rename to is empty", i)
+			}
+			parsed.to = append(field[:len(field)-1:len(field)-1], fieldSegment{key: op.To, index: -1})
+		case RemapMove:
+			if parsed.to, err = parseFieldPath(op.To); err != nil {
+				return nil, fmt.Errorf("remap operation %d: %w", i, err)
+			}
+		case RemapDefault, RemapDelete:
+		case RemapConvert:
+			switch op.Type {
+			case RemapString, RemapInt, RemapFloat, RemapBool:
+			default:
+				return nil, fmt.Errorf("remap operation %d: unsupported type %q", i, op.Type)
+			}
+		default:
+			return nil, fmt.Errorf("remap operation %d: unsupported operation %q", i, op.Op)
+		}
+
+		if op.Op != RemapDelete && op.Op != RemapConvert {
+			for _, seg := range slices.Concat(parsed.field, parsed.to) {
+				if seg.wildcard {
+					return nil, fmt.Errorf("remap operation %d: wildcards are not supported by %s", i, op.Op)
+				}
+			}
+		}
+		r.ops = append(r.ops, parsed)
+	}
+	return r, nil
+}
+
+// Transform applies the operations to the JSON objects from the input channel and returns the output
+// channel of records holding the objects. Operations on missing fields are skipped. The payload is the
+// data of a DataReadable, the raw message of a RawReadable, the bytes of a Readable, []byte or string,
+// and the raw message of a RawReadable or the payload itself is retained as the raw message.
+// Items that are not JSON objects or fail a conversion are dropped and reported with an error event
+// carrying them as the record.
+func (r Remap[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		for v := range in {
+			record, err := r.record(v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("remap error", err, false, v))
+				if r.deadLetter != nil {
+					r.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- record
+		}
+	}()
+	return out
+}
+
+// record applies the operations to the payload of the item
+func (r Remap[I]) record(v I) (pipeline.DataRawReadable, error) {
+	b, err := payload(v)
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := decodeObject(b)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range r.ops {
+		switch op.Op {
+		case RemapRename, RemapMove:
+			if value, ok := getPath(obj, op.field); ok {
+				excludePath(obj, op.field)
+				setPath(obj, op.to, value)
+			}
+		case RemapDefault:
+			if value, ok := getPath(obj, op.field); !ok || value == nil {
+				setPath(obj, op.field, op.Value)
+			}
+		case RemapDelete:
+			excludePath(obj, op.field)
+		case RemapConvert:
+			err := visitPath(obj, op.field, func(value any) (any, error) {
+				converted, err := convertValue(value, op.Type)
+				if err != nil {
+					return nil, fmt.Errorf("convert %s: %w", op.Field, err)
+				}
+				return converted, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return pipeline.DeriveRecord(pipeline.Bytes(data), rawOrPayload(v, b), v), nil
+}
+
+// convertValue converts a decoded JSON value to the type, leaving null alone
+func convertValue(value any, typ string) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch typ {
+	case RemapString:
+		switch value := value.(type) {
+		case string:
+			return value, nil
+		case json.Number:
+			return value.String(), nil
+		case bool:
+			return strconv.FormatBool(value), nil
+		default:
+			b, err := json.Marshal(value)
+			return string(b), err
+		}
+	case RemapInt:
+		switch value := value.(type) {
+		case string:
+			return strconv.ParseInt(value, 10, 64)
+		case json.Number:
+			return value.Int64()
+		}
+	case RemapFloat:
+		switch value := value.(type) {
+		case string:
+			return strconv.ParseFloat(value, 64)
+		case json.Number:
+			return value.Float64()
+		}
+	case RemapBool:
+		switch value := value.(type) {
+		case string:
+			return strconv.ParseBool(value)
+		case bool:
+			return value, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %T to %s", value, typ)
+}
@@ -0,0 +1,84 @@
+package flow_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// remap sends the payloads through a remap with the operations and returns the records and errors
+func remap(t *testing.T, ops []flow.RemapOperation, payloads ...string) ([]string, []error) {
+	t.Helper()
+
+	r, err := flow.NewRemap[string](ops, nil)
+	assert.NoError(t, err)
+
+	in := make(chan string, len(payloads))
+	for _, p := range payloads {
+		in <- p
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, len(payloads))
+	var records []string
+	for record := range r.Transform(in, eventC) {
+		data, _ := record.Data().Read()
+		records = append(records, string(data))
+	}
+	close(eventC)
+
+	var errs []error
+	for e := range eventC {
+		errs = append(errs, e.(pipeline.ErrorEvent))
+	}
+	return records, errs
+}
+
+func TestRemap_Transform(t *testing.T) {
+	ops := []flow.RemapOperation{
+		{Op: flow.RemapRename, Field: "src.addr", To: "ip"},
+		{Op: flow.RemapMove, Field: "dpt", To: "destination.port"},
+		{Op: flow.RemapDefault, Field: "severity", Value: "info"},
+		{Op: flow.RemapDefault, Field: "src.zone", Value: "internal"},
+		{Op: flow.RemapDelete, Field: "debug"},
+		{Op: flow.RemapDelete, Field: "tags[*].internal"},
+		{Op: flow.RemapConvert, Field: "destination.port", Type: flow.RemapInt},
+		{Op: flow.RemapConvert, Field: "bytes", Type: flow.RemapFloat},
+		{Op: flow.RemapConvert, Field: "blocked", Type: flow.RemapBool},
+		{Op: flow.RemapConvert, Field: "code", Type: flow.RemapString},
+	}
+
+	records, errs := remap(t, ops,
+		`{"src":{"addr":"10.0.0.1"},"dpt":"443","severity":null,"debug":{"x":1},"tags":[{"name":"a","internal":true}],`+
+			`"bytes":"1.5","blocked":"true","code":404}`,
+		`{"severity":"high","src":{"zone":"dmz"}}`,
+		`{"dpt":"https"}`,
+		`"not an object"`,
+	)
+
+	assert.Equal(t, []string{
+		`{"blocked":true,"bytes":1.5,"code":"404","destination":{"port":443},"severity":"info",` +
+			`"src":{"ip":"10.0.0.1","zone":"internal"},"tags":[{"name":"a"}]}`,
+		`{"severity":"high","src":{"zone":"dmz"}}`,
+	}, records)
+	assert.Len(t, errs, 2)
+	assert.ErrorContains(t, errs[0], "convert destination.port")
+	assert.ErrorIs(t, errs[1], flow.ErrNotObject)
+}
+
+func TestNewRemap_Validation(t *testing.T) {
+	for _, op := range []flow.RemapOperation{
+		{Op: "copy", Field: "a"},
+		{Op: flow.RemapRename, Field: "a"},
+		{Op: flow.RemapMove, Field: "a", To: ""},
+		{Op: flow.RemapMove, Field: "a.*", To: "b"},
+		{Op: flow.RemapDefault, Field: "a[*]"},
+		{Op: flow.RemapConvert, Field: "a", Type: "time"},
+		{Op: flow.RemapDelete, Field: ""},
+	} {
+		_, err := flow.NewRemap[string]([]flow.RemapOperation{op}, nil)
+		assert.Error(t, err, op)
+	}
+}
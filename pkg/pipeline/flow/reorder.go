@@ -0,0 +1,159 @@
+package flow
+
+import (
+	"container/heap"
+	"errors"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Reorder implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*Reorder[any])(nil)
+
+// MetricReorderLateItems is the counter of items that arrived after later items were already emitted.
+const MetricReorderLateItems = "krapht_reorder_late_items_total"
+
+// ReorderConfig is the configuration of a reordering flow.
+type ReorderConfig[I any] struct {
+	Time func(in I) time.Time // event time of an item, required
+
+	// Lateness is how far behind the latest event time an item may arrive and still be emitted in order.
+	// Items are held until the watermark, the latest event time minus Lateness, passes their time.
+	Lateness time.Duration
+
+	MaxItems int // items held at most, the earliest is emitted early when exceeded, defaults to 10000
+
+	// IdleTimeout emits all held items when no item arrives for this long, so items are not held back
+	// indefinitely by a quiet input. Zero disables it.
+	IdleTimeout time.Duration
+
+	Late     LatePolicy // handling of items older than an item already emitted, defaults to LateDrop
+	LateFunc func(in I) // receives the late items with LateSideOutput, required by it
+}
+
+// Reorder is a struct that represents sorting of a data stream by event time within a bounded lateness.
+type Reorder[I any] struct {
+	conf ReorderConfig[I]
+}
+
+// NewReorder creates a new Reorder flow with the given configuration.
+func NewReorder[I any](conf ReorderConfig[I]) (*Reorder[I], error) {
+	if conf.Time == nil {
+		return nil, errors.New("time func is nil")
+	}
+
+	if conf.Lateness < 0 {
+		conf.Lateness = 0
+	}
+
+	if conf.MaxItems <= 0 {
+		conf.MaxItems = 10000
+	}
+
+	if conf.Late == LateSideOutput && conf.LateFunc == nil {
+		return nil, errors.New("late func is nil")
+	}
+
+	return &Reorder[I]{
+		conf: conf,
+	}, nil
+}
+
+// reorderItem is an item held with its event time and arrival order, which breaks ties
+type reorderItem[I any] struct {
+	v   I
+	ts  int64
+	seq int
+}
+
+// reorderHeap is a min heap of items by event time
+type reorderHeap[I any] []reorderItem[I]
+
+func (h reorderHeap[I]) Len() int { return len(h) }
+func (h reorderHeap[I]) Less(i, j int) bool {
+	if h[i].ts != h[j].ts {
+		return h[i].ts < h[j].ts
+	}
+	return h[i].seq < h[j].seq
+}
+func (h reorderHeap[I]) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *reorderHeap[I]) Push(x any)   { *h = append(*h, x.(reorderItem[I])) }
+func (h *reorderHeap[I]) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = reorderItem[I]{} // release the item
+	*h = old[:len(old)-1]
+	return item
+}
+
+// Transform sorts the data from the input channel by event time and returns the output channel.
+// Items are emitted once the watermark passes their time, and all held items are emitted when the
+// input channel is closed. Items older than an item already emitted are counted with a metric event
+// and handled by the late policy, which emits included items immediately, out of order.
+func (r Reorder[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+
+	go func() {
+		defer close(out)
+
+		var held reorderHeap[I]
+		var seq int
+		watermark := NewWatermark(r.conf.Lateness)
+		emitted := int64(minInt64)
+		var late float64
+
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+
+		// emit sends the held items up to the watermark in order
+		emit := func(watermark int64) {
+			for len(held) > 0 && held[0].ts <= watermark {
+				item := heap.Pop(&held).(reorderItem[I])
+				emitted = max(emitted, item.ts)
+				out <- item.v
+			}
+		}
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					emit(maxInt64)
+					return
+				}
+
+				ts := r.conf.Time(v)
+				n := ts.UnixNano()
+				if n < emitted {
+					late++
+					pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricReorderLateItems, late, nil, pipeline.MetricTypeCounter))
+					switch r.conf.Late {
+					case LateSideOutput:
+						r.conf.LateFunc(v)
+					case LateInclude:
+						out <- v
+					}
+					continue
+				}
+
+				seq++
+				heap.Push(&held, reorderItem[I]{v: v, ts: n, seq: seq})
+				watermark.Observe(ts)
+				emit(watermark.watermark)
+				for len(held) > r.conf.MaxItems {
+					emit(held[0].ts)
+				}
+
+				timer.Stop()
+				if r.conf.IdleTimeout > 0 && len(held) > 0 {
+					timer.Reset(r.conf.IdleTimeout)
+				}
+			case <-timer.C:
+				emit(maxInt64)
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,114 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// reorderEvent is an item with an event time in seconds
+type reorderEvent struct {
+	name string
+	sec  int64
+}
+
+func reorderTime(e reorderEvent) time.Time { return time.Unix(e.sec, 0) }
+
+func TestReorder_Transform(t *testing.T) {
+	t.Run("sorts within the lateness and includes late items", func(t *testing.T) {
+		r, err := flow.NewReorder(flow.ReorderConfig[reorderEvent]{Time: reorderTime, Lateness: 5 * time.Second, Late: flow.LateInclude})
+		assert.NoError(t, err)
+
+		in := make(chan reorderEvent)
+		eventC := make(chan pipeline.Event, 1)
+		out := r.Transform(in, eventC)
+
+		in <- reorderEvent{"c", 13}
+		in <- reorderEvent{"a", 10}
+		in <- reorderEvent{"b", 12}
+		in <- reorderEvent{"b2", 12}
+		in <- reorderEvent{"e", 20} // watermark 15 releases a to c
+		assert.Equal(t, []string{"a", "b", "b2", "c"}, []string{(<-out).name, (<-out).name, (<-out).name, (<-out).name})
+
+		in <- reorderEvent{"late", 11}
+		assert.Equal(t, "late", (<-out).name)
+		metric := (<-eventC).(pipeline.MetricEvent)
+		assert.Equal(t, flow.MetricReorderLateItems, metric.Name())
+		assert.Equal(t, float64(1), metric.Value())
+
+		in <- reorderEvent{"d", 16}
+		close(in)
+		var rest []string
+		for e := range out {
+			rest = append(rest, e.name)
+		}
+		assert.Equal(t, []string{"d", "e"}, rest)
+	})
+
+	t.Run("bounds the held items", func(t *testing.T) {
+		r, err := flow.NewReorder(flow.ReorderConfig[reorderEvent]{Time: reorderTime, Lateness: time.Hour, MaxItems: 2})
+		assert.NoError(t, err)
+
+		in := make(chan reorderEvent)
+		out := r.Transform(in, nil)
+		in <- reorderEvent{"b", 2}
+		in <- reorderEvent{"c", 3}
+		in <- reorderEvent{"a", 1}
+		assert.Equal(t, "a", (<-out).name)
+		close(in)
+		assert.Equal(t, "b", (<-out).name)
+		assert.Equal(t, "c", (<-out).name)
+	})
+
+	t.Run("emits held items when idle", func(t *testing.T) {
+		r, err := flow.NewReorder(flow.ReorderConfig[reorderEvent]{Time: reorderTime, Lateness: time.Hour, IdleTimeout: 20 * time.Millisecond})
+		assert.NoError(t, err)
+
+		in := make(chan reorderEvent)
+		defer close(in)
+		out := r.Transform(in, nil)
+		in <- reorderEvent{"b", 2}
+		in <- reorderEvent{"a", 1}
+
+		select {
+		case e := <-out:
+			assert.Equal(t, "a", e.name)
+			assert.Equal(t, "b", (<-out).name)
+		case <-time.After(time.Second):
+			t.Fatal("held items were not emitted when idle")
+		}
+	})
+
+	t.Run("drops or side outputs late items", func(t *testing.T) {
+		var side []string
+		for _, conf := range []flow.ReorderConfig[reorderEvent]{
+			{Time: reorderTime},
+			{Time: reorderTime, Late: flow.LateSideOutput, LateFunc: func(e reorderEvent) { side = append(side, e.name) }},
+		} {
+			r, err := flow.NewReorder(conf)
+			assert.NoError(t, err)
+
+			in := make(chan reorderEvent, 3)
+			in <- reorderEvent{"a", 1}
+			in <- reorderEvent{"c", 3}
+			in <- reorderEvent{"late", 2}
+			close(in)
+
+			var names []string
+			for e := range r.Transform(in, nil) {
+				names = append(names, e.name)
+			}
+			assert.Equal(t, []string{"a", "c"}, names)
+		}
+		assert.Equal(t, []string{"late"}, side)
+	})
+
+	_, err := flow.NewReorder(flow.ReorderConfig[reorderEvent]{})
+	assert.Error(t, err)
+
+	_, err = flow.NewReorder(flow.ReorderConfig[reorderEvent]{Time: reorderTime, Late: flow.LateSideOutput})
+	assert.Error(t, err)
+}
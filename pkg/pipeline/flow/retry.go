@@ -0,0 +1,94 @@
+package flow
+
+import (
+	"errors"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Retry implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*Retry[any, any])(nil)
+
+// DeadLetterFunc receives an item a flow gave up on and the error it failed with.
+type DeadLetterFunc[I any] func(in I, err error)
+
+// RetryPolicy is how a failing transform is retried.
+type RetryPolicy struct {
+	Attempts   int                  // attempts per item including the first, defaults to 3
+	Backoff    time.Duration        // wait before the first retry, doubled for each retry after it, defaults to 100ms
+	MaxBackoff time.Duration        // cap on the wait between retries, zero means no cap
+	Retryable  func(err error) bool // reports whether an error is worth retrying, nil retries every error
+}
+
+// Retry is a struct that represents a map operation on a data stream retried per item.
+type Retry[I, O any] struct {
+	transform  MapFunc[I, O]
+	policy     RetryPolicy
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewRetry creates a new Retry flow with the given transform function and policy.
+// Items that still fail after the policy is exhausted are passed to deadLetter when it is not nil.
+func NewRetry[I, O any](transform MapFunc[I, O], policy RetryPolicy, deadLetter DeadLetterFunc[I]) (*Retry[I, O], error) {
+	if transform == nil {
+		return nil, errors.New("transform func is nil")
+	}
+
+	if policy.Attempts <= 0 {
+		policy.Attempts = 3
+	}
+
+	if policy.Backoff <= 0 {
+		policy.Backoff = 100 * time.Millisecond
+	}
+
+	if policy.Retryable == nil {
+		policy.Retryable = func(error) bool { return true }
+	}
+
+	return &Retry[I, O]{
+		transform:  transform,
+		policy:     policy,
+		deadLetter: deadLetter,
+	}, nil
+}
+
+// Transform applies the map operation on the input channel in a goroutine and returns the output channel.
+// An item is retried with backoff while the transform fails with a retryable error, which holds back the
+// items after it. An item that is given up on is reported with an error event carrying it as the record.
+func (r Retry[I, O]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan O {
+	out := make(chan O)
+	go func() {
+		defer close(out)
+		for v := range in {
+			val, err := r.try(v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("retry transform error", err, false, v))
+				if r.deadLetter != nil {
+					r.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- val
+		}
+	}()
+	return out
+}
+
+// try runs the transform until it succeeds, fails with an error that is not retryable or runs out of attempts
+func (r Retry[I, O]) try(v I) (O, error) {
+	backoff := r.policy.Backoff
+	for attempt := 1; ; attempt++ {
+		val, err := r.transform(v)
+		if err == nil || attempt == r.policy.Attempts || !r.policy.Retryable(err) {
+			return val, err
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+		if r.policy.MaxBackoff > 0 {
+			backoff = min(backoff, r.policy.MaxBackoff)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package flow_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+)
+
+var errPermanent = errors.New("permanent")
+
+func TestRetry_Transform(t *testing.T) {
+	// flaky fails the first two attempts of every value and always fails negative values
+	attempts := map[int]int{}
+	flaky := func(v int) (int, error) {
+		attempts[v]++
+		if v < 0 {
+			return 0, errPermanent
+		}
+		if attempts[v] < 3 {
+			return 0, errors.New("try again")
+		}
+		return v * 10, nil
+	}
+
+	t.Run("retries failing items until they succeed", func(t *testing.T) {
+		clear(attempts)
+		retry, err := flow.NewRetry(flaky, flow.RetryPolicy{Attempts: 3, Backoff: time.Millisecond}, nil)
+		assert.NoError(t, err)
+
+		in := make(chan int, 2)
+		in <- 1
+		in <- 2
+		close(in)
+
+		var result []int
+		for v := range retry.Transform(in, nil) {
+			result = append(result, v)
+		}
+
+		assert.Equal(t, []int{10, 20}, result)
+		assert.Equal(t, map[int]int{1: 3, 2: 3}, attempts)
+	})
+
+	t.Run("routes items given up on to the dead letter func", func(t *testing.T) {
+		clear(attempts)
+		var letters []int
+		retry, err := flow.NewRetry(flaky, flow.RetryPolicy{
+			Attempts:  5,
+			Backoff:   time.Millisecond,
+			Retryable: func(err error) bool { return !errors.Is(err, errPermanent) },
+		}, func(v int, err error) {
+			assert.ErrorIs(t, err, errPermanent)
+			letters = append(letters, v)
+		})
+		assert.NoError(t, err)
+
+		in := make(chan int, 2)
+		in <- -1
+		in <- 1
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		var result []int
+		for v := range retry.Transform(in, eventC) {
+			result = append(result, v)
+		}
+
+		assert.Equal(t, []int{10}, result)
+		assert.Equal(t, []int{-1}, letters)
+		assert.Equal(t, 1, attempts[-1], "permanent errors are not retried")
+
+		errEvent, ok := (<-eventC).(pipeline.ErrorEvent)
+		assert.True(t, ok)
+		assert.Equal(t, -1, errEvent.Record())
+	})
+
+	t.Run("gives up after the attempts", func(t *testing.T) {
+		clear(attempts)
+		retry, err := flow.NewRetry(flaky, flow.RetryPolicy{Attempts: 2, Backoff: time.Millisecond}, nil)
+		assert.NoError(t, err)
+
+		in := make(chan int, 1)
+		in <- 1
+		close(in)
+
+		var result []int
+		for v := range retry.Transform(in, nil) {
+			result = append(result, v)
+		}
+
+		assert.Empty(t, result)
+		assert.Equal(t, 2, attempts[1])
+	})
+
+	t.Run("retries the failing calls of a mock flow", func(t *testing.T) {
+		flaky := mock.NewFlow(mock.FlowConfig[int, int]{
+			Transform:  func(v int) (int, error) { return v * 10, nil },
+			Delay:      mock.FixedDelay(time.Millisecond),
+			ErrorEvery: 2,
+		})
+		retry, err := flow.NewRetry(flaky.Call, flow.RetryPolicy{Attempts: 2, Backoff: time.Millisecond}, nil)
+		assert.NoError(t, err)
+
+		in := make(chan int, 3)
+		in <- 1
+		in <- 2
+		in <- 3
+		close(in)
+
+		var result []int
+		for v := range retry.Transform(in, nil) {
+			result = append(result, v)
+		}
+
+		// calls 2 and 4 fail, retrying the second and third items once each
+		assert.Equal(t, []int{10, 20, 30}, result)
+		assert.Equal(t, 5, flaky.Calls())
+	})
+}
+
+func TestNewRetry_Validation(t *testing.T) {
+	_, err := flow.NewRetry[int, int](nil, flow.RetryPolicy{}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package flow
+
+import (
+	"errors"
+	"hash/fnv"
+	"strconv"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that the splits implement the FanOut interface.
+var (
+	_ pipeline.FanOut[any] = (*RoundRobinSplit[any])(nil)
+	_ pipeline.FanOut[any] = (*HashSplit[any])(nil)
+)
+
+// MetricSplitItems is the counter of items sent to a branch of a split.
+const MetricSplitItems = "krapht_split_items_total"
+
+// splitMetricItems is the number of items sent to a branch between its metric events, so the splits do
+// not flood the event channel at ingest rates
+const splitMetricItems = 1000
+
+// RoundRobinSplit is a struct that represents even distribution of a data stream across branches.
+type RoundRobinSplit[I any] struct{}
+
+// NewRoundRobinSplit creates a new RoundRobinSplit fan out.
+func NewRoundRobinSplit[I any]() *RoundRobinSplit[I] {
+	return &RoundRobinSplit[I]{}
+}
+
+// Split sends the items from the input channel to the n returned channels in turn and closes
+// them when the input channel is closed. A branch that is not read stalls the others.
+// The items sent to each branch are counted with a metric event labelled with the branch index, sent
+// every 1000 items of the branch and when the input channel is closed.
+func (RoundRobinSplit[I]) Split(in <-chan I, eventC chan<- pipeline.Event, n uint8) []<-chan I {
+	outs, branches := newBranches[I](n, 0)
+
+	go func() {
+		defer closeBranches(outs)
+		if n == 0 {
+			drain(in)
+			return
+		}
+
+		sent := newSplitCounter(eventC, n)
+		defer sent.flush()
+		next := 0
+		for v := range in {
+			outs[next] <- v
+			sent.add(next)
+			next = (next + 1) % int(n)
+		}
+	}()
+	return branches
+}
+
+// HashSplit is a struct that represents distribution of a data stream across branches by key.
+type HashSplit[I any] struct {
+	key func(in I) string
+}
+
+// NewHashSplit creates a new HashSplit fan out with the given key function.
+func NewHashSplit[I any](key func(in I) string) (*HashSplit[I], error) {
+	if key == nil {
+		return nil, errors.New("key func is nil")
+	}
+
+	return &HashSplit[I]{
+		key: key,
+	}, nil
+}
+
+// Split sends each item from the input channel to the one of the n returned channels chosen by a
+// hash of its key, so all items of a key land on the same branch in order, and closes them when the
+// input channel is closed. A branch that is not read stalls the others.
+// The items sent to each branch are counted with a metric event labelled with the branch index, sent
+// every 1000 items of the branch and when the input channel is closed.
+func (h HashSplit[I]) Split(in <-chan I, eventC chan<- pipeline.Event, n uint8) []<-chan I {
+	outs, branches := newBranches[I](n, 0)
+
+	go func() {
+		defer closeBranches(outs)
+		if n == 0 {
+			drain(in)
+			return
+		}
+
+		sent := newSplitCounter(eventC, n)
+		defer sent.flush()
+		for v := range in {
+			branch := branchOf(h.key(v), n)
+			outs[branch] <- v
+			sent.add(branch)
+		}
+	}()
+	return branches
+}
+
+// branchOf returns the branch of a key
+func branchOf(key string, n uint8) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
+
+// newBranches creates the channels of n branches buffering size items and their receive only views
+func newBranches[I any](n uint8, size int) ([]chan I, []<-chan I) {
+	outs := make([]chan I, n)
+	branches := make([]<-chan I, n)
+	for i := range outs {
+		outs[i] = make(chan I, size)
+		branches[i] = outs[i]
+	}
+	return outs, branches
+}
+
+// closeBranches closes the channels of all branches
+func closeBranches[I any](outs []chan I) {
+	for _, out := range outs {
+		close(out)
+	}
+}
+
+// splitCounter counts the items sent to the branches of a split
+type splitCounter struct {
+	eventC   chan<- pipeline.Event
+	sent     []float64
+	reported []float64
+}
+
+// newSplitCounter creates a counter of the items sent to n branches
+func newSplitCounter(eventC chan<- pipeline.Event, n uint8) *splitCounter {
+	return &splitCounter{
+		eventC:   eventC,
+		sent:     make([]float64, n),
+		reported: make([]float64, n),
+	}
+}
+
+// add counts an item sent to a branch, sending its count every splitMetricItems items
+func (c *splitCounter) add(branch int) {
+	c.sent[branch]++
+	if c.sent[branch]-c.reported[branch] >= splitMetricItems {
+		c.send(branch)
+	}
+}
+
+// flush sends the counts of the branches that changed since they were last sent
+func (c *splitCounter) flush() {
+	for branch := range c.sent {
+		if c.sent[branch] > c.reported[branch] {
+			c.send(branch)
+		}
+	}
+}
+
+// send sends the count of items sent to a branch
+func (c *splitCounter) send(branch int) {
+	c.reported[branch] = c.sent[branch]
+	pipeline.SendEvent(c.eventC, pipeline.NewMetricEvent(MetricSplitItems, c.sent[branch],
+		map[string]string{"branch": strconv.Itoa(branch)}, pipeline.MetricTypeCounter))
+}
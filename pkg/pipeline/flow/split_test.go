@@ -0,0 +1,117 @@
+package flow_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// readBranches reads every branch concurrently until closed and returns their items
+func readBranches[I any](branches []<-chan I) [][]I {
+	results := make([][]I, len(branches))
+	var wg sync.WaitGroup
+	for i, branch := range branches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range branch {
+				results[i] = append(results[i], v)
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func TestRoundRobinSplit_Split(t *testing.T) {
+	t.Run("distributes items evenly", func(t *testing.T) {
+		split := flow.NewRoundRobinSplit[int]()
+
+		in := make(chan int, 7)
+		for i := range 7 {
+			in <- i
+		}
+		close(in)
+
+		eventC := make(chan pipeline.Event, 7)
+		results := readBranches(split.Split(in, eventC, 3))
+
+		assert.Equal(t, [][]int{{0, 3, 6}, {1, 4}, {2, 5}}, results)
+
+		// the counts are sent once the input is closed
+		sent := map[string]float64{}
+		for range 3 {
+			metric := (<-eventC).(pipeline.MetricEvent)
+			assert.Equal(t, flow.MetricSplitItems, metric.Name())
+			sent[metric.Labels()["branch"]] = metric.Value()
+		}
+		assert.Equal(t, map[string]float64{"0": 3, "1": 2, "2": 2}, sent)
+		assert.Empty(t, eventC)
+	})
+
+	t.Run("counts items every 1000 items of a branch", func(t *testing.T) {
+		split := flow.NewRoundRobinSplit[int]()
+
+		in := make(chan int, 2500)
+		for i := range 2500 {
+			in <- i
+		}
+		close(in)
+
+		eventC := make(chan pipeline.Event, 10)
+		readBranches(split.Split(in, eventC, 1))
+		close(eventC)
+
+		var counts []float64
+		for e := range eventC {
+			counts = append(counts, e.(pipeline.MetricEvent).Value())
+		}
+		assert.Equal(t, []float64{1000, 2000, 2500}, counts)
+	})
+
+	t.Run("drains the input without branches", func(t *testing.T) {
+		split := flow.NewRoundRobinSplit[int]()
+
+		in := make(chan int)
+		assert.Empty(t, split.Split(in, nil, 0))
+		in <- 1
+		close(in)
+	})
+}
+
+func TestHashSplit_Split(t *testing.T) {
+	split, err := flow.NewHashSplit(func(r reading) string { return r.host })
+	assert.NoError(t, err)
+
+	in := make(chan reading, 20)
+	for i := range 20 {
+		in <- reading{host: string(rune('a' + i%4)), value: i}
+	}
+	close(in)
+
+	results := readBranches(split.Split(in, nil, 3))
+
+	total := 0
+	branches := map[string]int{}
+	for i, result := range results {
+		last := map[string]int{}
+		for _, r := range result {
+			if b, ok := branches[r.host]; ok {
+				assert.Equal(t, b, i, "key %s was sent to more than one branch", r.host)
+			}
+			branches[r.host] = i
+			if prev, ok := last[r.host]; ok {
+				assert.Greater(t, r.value, prev, "items of a key must stay in order")
+			}
+			last[r.host] = r.value
+			total++
+		}
+	}
+	assert.Equal(t, 20, total)
+
+	_, err = flow.NewHashSplit[reading](nil)
+	assert.Error(t, err)
+}
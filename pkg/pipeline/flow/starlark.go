@@ -0,0 +1,238 @@
+package flow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	starjson "go.starlark.net/lib/json"
+	starmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Ensure that Starlark implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*Starlark[any])(nil)
+
+// StarlarkConfig is the configuration of a Starlark script transform.
+type StarlarkConfig struct {
+	// Script is the Starlark source defining the function, required. The json and math modules are predeclared.
+	Script   string
+	Function string // name of the function called per record, defaults to "transform"
+	MaxSteps uint64 // computation steps of a call at most, longer calls are cancelled, defaults to 100000
+}
+
+// Starlark is a struct that represents transformation of the records on a data stream by a Starlark script.
+type Starlark[I any] struct {
+	conf       StarlarkConfig
+	fn         starlark.Callable
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewStarlark creates a new Starlark flow with the given configuration, executing the script once to define
+// its function. The function is called with the record, the payload decoded as a dict when it is a JSON
+// object or the payload as a string otherwise, and returns the new record as a dict encoded as a JSON object,
+// a string used as the payload, or None to drop the item, for example
+//
+//	def transform(record):
+//	    if record.get("level") == "debug":
+//	        return None
+//	    record["host"] = record["host"].lower()
+//	    return record
+//
+// Items whose call fails are passed to deadLetter when it is not nil.
+func NewStarlark[I any](conf StarlarkConfig, deadLetter DeadLetterFunc[I]) (*Starlark[I], error) {
+	if conf.Script == "" {
+		return nil, errors.New("starlark script is empty")
+	}
+
+	if conf.Function == "" {
+		conf.Function = "transform"
+	}
+
+	if conf.MaxSteps == 0 {
+		conf.MaxSteps = 100000
+	}
+
+	thread := &starlark.Thread{Name: "init"}
+	thread.SetMaxExecutionSteps(conf.MaxSteps)
+	predeclared := starlark.StringDict{"json": starjson.Module, "math": starmath.Module}
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, "script.star", conf.Script, predeclared)
+	if err != nil {
+		return nil, fmt.Errorf("could not execute starlark script: %w", err)
+	}
+
+	fn, ok := globals[conf.Function].(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("starlark script does not define function %s", conf.Function)
+	}
+
+	return &Starlark[I]{
+		conf:       conf,
+		fn:         fn,
+		deadLetter: deadLetter,
+	}, nil
+}
+
+// Transform calls the function on the records from the input channel and returns the output channel of the
+// resulting records, retaining the raw message of the input. Items whose call fails or exceeds the step
+// budget are dropped and reported with an error event carrying them as the record.
+func (s Starlark[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		for v := range in {
+			record, ok, err := s.record(v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("starlark transform error", err, false, v))
+				if s.deadLetter != nil {
+					s.deadLetter(v, err)
+				}
+				continue
+			}
+			if ok {
+				out <- record
+			}
+		}
+	}()
+	return out
+}
+
+// record calls the function on the item, reporting false for dropped items
+func (s Starlark[I]) record(v I) (pipeline.DataRawReadable, bool, error) {
+	b, err := payload(v)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var arg starlark.Value = starlark.String(b)
+	if obj, err := decodeObject(b); err == nil {
+		if arg, err = toStarlark(obj); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// each call runs in its own thread, so the budget applies per call
+	thread := &starlark.Thread{Name: s.conf.Function}
+	thread.SetMaxExecutionSteps(s.conf.MaxSteps)
+	result, err := starlark.Call(thread, s.fn, starlark.Tuple{arg}, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var data []byte
+	switch r := result.(type) {
+	case starlark.NoneType:
+		return nil, false, nil
+	case starlark.String:
+		data = []byte(r)
+	case starlark.Bytes:
+		data = []byte(r)
+	case *starlark.Dict:
+		obj, err := fromStarlark(r)
+		if err != nil {
+			return nil, false, err
+		}
+		if data, err = json.Marshal(obj); err != nil {
+			return nil, false, err
+		}
+	default:
+		return nil, false, fmt.Errorf("starlark function returned %s, not a dict, string or None", result.Type())
+	}
+
+	return pipeline.DeriveRecord(pipeline.Bytes(data), rawOrPayload(v, b), v), true, nil
+}
+
+// toStarlark converts a decoded JSON value to a Starlark value
+func toStarlark(v any) (starlark.Value, error) {
+	switch t := v.(type) {
+	case nil:
+		return starlark.None, nil
+	case bool:
+		return starlark.Bool(t), nil
+	case string:
+		return starlark.String(t), nil
+	case json.Number:
+		if i, ok := new(big.Int).SetString(t.String(), 10); ok {
+			return starlark.MakeBigInt(i), nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return starlark.Float(f), nil
+	case []any:
+		l := make([]starlark.Value, 0, len(t))
+		for _, e := range t {
+			sv, err := toStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, sv)
+		}
+		return starlark.NewList(l), nil
+	case map[string]any:
+		d := starlark.NewDict(len(t))
+		for k, e := range t {
+			sv, err := toStarlark(e)
+			if err != nil {
+				return nil, err
+			}
+			if err := d.SetKey(starlark.String(k), sv); err != nil {
+				return nil, err
+			}
+		}
+		return d, nil
+	default:
+		return nil, fmt.Errorf("unexpected json value of type %T", v)
+	}
+}
+
+// fromStarlark converts a Starlark value to a value that can be encoded as JSON
+func fromStarlark(v starlark.Value) (any, error) {
+	switch t := v.(type) {
+	case starlark.NoneType:
+		return nil, nil
+	case starlark.Bool:
+		return bool(t), nil
+	case starlark.String:
+		return string(t), nil
+	case starlark.Bytes:
+		return string(t), nil
+	case starlark.Int:
+		if i, ok := t.Int64(); ok {
+			return i, nil
+		}
+		return json.Number(t.String()), nil
+	case starlark.Float:
+		return float64(t), nil
+	case *starlark.Dict:
+		m := make(map[string]any, t.Len())
+		for _, item := range t.Items() {
+			k, ok := item[0].(starlark.String)
+			if !ok {
+				return nil, fmt.Errorf("dict key %s is not a string", item[0])
+			}
+			e, err := fromStarlark(item[1])
+			if err != nil {
+				return nil, err
+			}
+			m[string(k)] = e
+		}
+		return m, nil
+	case starlark.Indexable: // lists and tuples
+		l := make([]any, 0, t.Len())
+		for i := range t.Len() {
+			e, err := fromStarlark(t.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, e)
+		}
+		return l, nil
+	default:
+		return nil, fmt.Errorf("starlark %s cannot be encoded as json", v.Type())
+	}
+}
@@ -0,0 +1,60 @@
+package flow_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+const starlarkScript = `
+def transform(record):
+    if type(record) == "string":
+        return "line: " + record
+    if record.get("level") == "debug":
+        return None
+    if record.get("loop"):
+        for i in range(1000000):
+            pass
+    record["host"] = record["host"].lower()
+    record["count"] = record.get("count", 0) + 1
+    return record
+`
+
+func TestStarlark_Transform(t *testing.T) {
+	var dead []string
+	s, err := flow.NewStarlark(flow.StarlarkConfig{Script: starlarkScript, MaxSteps: 10000}, func(in string, _ error) {
+		dead = append(dead, in)
+	})
+	assert.NoError(t, err)
+
+	in := make(chan string, 5)
+	in <- `{"host": "WEB1", "count": 9007199254740993}`
+	in <- `{"host": "db1", "level": "debug"}`
+	in <- `plain text`
+	in <- `{"host": "x", "loop": true}`
+	in <- `{"level": "info"}`
+	close(in)
+
+	eventC := make(chan pipeline.Event, 2)
+	var got []string
+	for r := range s.Transform(in, eventC) {
+		data, _ := r.Data().Read()
+		got = append(got, string(data))
+	}
+	assert.Equal(t, []string{`{"count":9007199254740994,"host":"web1"}`, `line: plain text`}, got)
+	assert.Equal(t, []string{`{"host": "x", "loop": true}`, `{"level": "info"}`}, dead)
+	assert.Len(t, eventC, 2)
+}
+
+func TestNewStarlark_Validation(t *testing.T) {
+	_, err := flow.NewStarlark[string](flow.StarlarkConfig{}, nil)
+	assert.Error(t, err)
+
+	_, err = flow.NewStarlark[string](flow.StarlarkConfig{Script: "def transform(:"}, nil)
+	assert.Error(t, err)
+
+	_, err = flow.NewStarlark[string](flow.StarlarkConfig{Script: "def other(record):\n    return record\n"}, nil)
+	assert.Error(t, err)
+}
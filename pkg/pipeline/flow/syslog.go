@@ -0,0 +1,251 @@
+package flow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrMalformedSyslog is the error of a message that is not valid syslog.
+var ErrMalformedSyslog = errors.New("malformed syslog message")
+
+// SyslogMessage is the structured data of a syslog message.
+type SyslogMessage struct {
+	Priority       int                          `json:"priority"`
+	Facility       int                          `json:"facility"`
+	Severity       int                          `json:"severity"`
+	Version        int                          `json:"version,omitempty"` // 1 for RFC 5424, 0 for RFC 3164
+	Timestamp      time.Time                    `json:"timestamp,omitzero"`
+	Hostname       string                       `json:"hostname,omitempty"`
+	AppName        string                       `json:"app_name,omitempty"`
+	ProcID         string                       `json:"proc_id,omitempty"`
+	MsgID          string                       `json:"msg_id,omitempty"`
+	StructuredData map[string]map[string]string `json:"structured_data,omitempty"`
+	Message        string                       `json:"message,omitempty"`
+}
+
+// SyslogConfig is the configuration of a syslog parser.
+type SyslogConfig struct {
+	// Location is the time zone of RFC 3164 timestamps, which carry none, defaults to UTC.
+	Location *time.Location
+}
+
+// NewSyslogParser creates a new Parser flow decoding RFC 3164 and RFC 5424 syslog messages into
+// records holding a SyslogMessage as JSON data. Messages that fail to parse are passed to deadLetter
+// when it is not nil.
+func NewSyslogParser[I any](conf SyslogConfig, deadLetter DeadLetterFunc[I]) (*Parser[I], error) {
+	return NewParser("syslog", func(raw []byte) (any, error) {
+		return ParseSyslog(raw, conf.Location)
+	}, deadLetter)
+}
+
+// ParseSyslog parses an RFC 3164 or RFC 5424 syslog message.
+// RFC 3164 timestamps are read in loc, UTC if nil, and are given the year that puts them closest
+// before now. A message without a priority is given the default of 13, user-level notice.
+func ParseSyslog(raw []byte, loc *time.Location) (SyslogMessage, error) {
+	s := string(bytes.TrimRight(raw, "\r\n"))
+	if s == "" {
+		return SyslogMessage{}, fmt.Errorf("%w: empty message", ErrMalformedSyslog)
+	}
+
+	msg := SyslogMessage{Priority: 13}
+	if s[0] == '<' {
+		end := strings.IndexByte(s, '>')
+		if end < 2 || end > 4 {
+			return SyslogMessage{}, fmt.Errorf("%w: invalid priority", ErrMalformedSyslog)
+		}
+		pri, ok := atoi([]byte(s[1:end]))
+		if !ok || pri > 191 {
+			return SyslogMessage{}, fmt.Errorf("%w: invalid priority %q", ErrMalformedSyslog, s[1:end])
+		}
+		msg.Priority = pri
+		s = s[end+1:]
+	}
+	msg.Facility = msg.Priority / 8
+	msg.Severity = msg.Priority % 8
+
+	if version, rest, ok := syslogVersion(s); ok {
+		msg.Version = version
+		return msg, parseRFC5424(&msg, rest)
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	parseRFC3164(&msg, s, loc)
+	return msg, nil
+}
+
+// syslogVersion returns the RFC 5424 version at the start of the message and the rest after it
+func syslogVersion(s string) (int, string, bool) {
+	sp := strings.IndexByte(s, ' ')
+	if sp < 1 || sp > 2 || s[0] == '0' {
+		return 0, "", false
+	}
+	version, ok := atoi([]byte(s[:sp]))
+	if !ok {
+		return 0, "", false
+	}
+	return version, s[sp+1:], true
+}
+
+// parseRFC5424 parses the header, structured data and message following the version
+func parseRFC5424(msg *SyslogMessage, s string) error {
+	fields := make([]string, 5)
+	for i := range fields {
+		field, rest, ok := strings.Cut(s, " ")
+		if !ok && i < len(fields)-1 {
+			return fmt.Errorf("%w: truncated header", ErrMalformedSyslog)
+		}
+		if field == "" {
+			return fmt.Errorf("%w: empty header field", ErrMalformedSyslog)
+		}
+		if field != "-" {
+			fields[i] = field
+		}
+		s = rest
+	}
+
+	if fields[0] != "" {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("%w: invalid timestamp: %w", ErrMalformedSyslog, err)
+		}
+		msg.Timestamp = ts
+	}
+	msg.Hostname, msg.AppName, msg.ProcID, msg.MsgID = fields[1], fields[2], fields[3], fields[4]
+
+	switch {
+	case s == "":
+		return fmt.Errorf("%w: missing structured data", ErrMalformedSyslog)
+	case s[0] == '-':
+		s = s[1:]
+	default:
+		sd, rest, err := parseStructuredData(s)
+		if err != nil {
+			return err
+		}
+		msg.StructuredData = sd
+		s = rest
+	}
+
+	if s != "" {
+		if s[0] != ' ' {
+			return fmt.Errorf("%w: invalid structured data", ErrMalformedSyslog)
+		}
+		msg.Message = strings.TrimPrefix(s[1:], "\ufeff") // byte order mark of UTF-8 messages
+	}
+	return nil
+}
+
+// parseStructuredData parses the structured data elements at the start of s and returns the rest
+func parseStructuredData(s string) (map[string]map[string]string, string, error) {
+	var sd map[string]map[string]string
+	for s != "" && s[0] == '[' {
+		end := strings.IndexAny(s, " ]")
+		if end < 2 {
+			return nil, "", fmt.Errorf("%w: invalid structured data id", ErrMalformedSyslog)
+		}
+		if sd == nil {
+			sd = make(map[string]map[string]string)
+		}
+		params := make(map[string]string)
+		sd[s[1:end]] = params
+		s = s[end:]
+
+		for s != "" && s[0] == ' ' {
+			eq := strings.IndexByte(s, '=')
+			if eq < 2 || len(s) < eq+2 || s[eq+1] != '"' {
+				return nil, "", fmt.Errorf("%w: invalid structured data param", ErrMalformedSyslog)
+			}
+			name := s[1:eq]
+			value, rest, err := parseParamValue(s[eq+2:])
+			if err != nil {
+				return nil, "", err
+			}
+			params[name] = value
+			s = rest
+		}
+
+		if s == "" || s[0] != ']' {
+			return nil, "", fmt.Errorf("%w: unterminated structured data element", ErrMalformedSyslog)
+		}
+		s = s[1:]
+	}
+	return sd, s, nil
+}
+
+// parseParamValue reads a param value up to its closing quote, unescaping \", \\ and \],
+// and returns the rest after the quote
+func parseParamValue(s string) (string, string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return b.String(), s[i+1:], nil
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']'):
+			b.WriteByte(s[i+1])
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("%w: unterminated structured data value", ErrMalformedSyslog)
+}
+
+// rfc3164Time is the layout of RFC 3164 timestamps
+const rfc3164Time = "Jan _2 15:04:05"
+
+// parseRFC3164 parses the timestamp, host, tag and message following the priority.
+// Parts that are not found are left empty and the unparsed remainder becomes the message.
+func parseRFC3164(msg *SyslogMessage, s string, loc *time.Location) {
+	hasTime := false
+	if len(s) >= len(rfc3164Time) {
+		if ts, err := time.ParseInLocation(rfc3164Time, s[:len(rfc3164Time)], loc); err == nil {
+			msg.Timestamp = withYear(ts, time.Now().In(loc))
+			s = strings.TrimPrefix(s[len(rfc3164Time):], " ")
+			hasTime = true
+		}
+	}
+	if !hasTime {
+		// some senders use RFC 3339 timestamps in the BSD format
+		if field, rest, ok := strings.Cut(s, " "); ok {
+			if ts, err := time.Parse(time.RFC3339Nano, field); err == nil {
+				msg.Timestamp = ts
+				s = rest
+				hasTime = true
+			}
+		}
+	}
+
+	// the host follows the timestamp unless the next word is already the tag
+	if hasTime {
+		if field, rest, ok := strings.Cut(s, " "); ok && !strings.HasSuffix(field, ":") {
+			msg.Hostname = field
+			s = rest
+		}
+	}
+
+	if field, rest, ok := strings.Cut(s, " "); ok && strings.HasSuffix(field, ":") {
+		tag := strings.TrimSuffix(field, ":")
+		if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+			msg.ProcID = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		msg.AppName = tag
+		s = rest
+	}
+	msg.Message = s
+}
+
+// withYear sets the year of a timestamp parsed without one to the year that puts it closest before now,
+// allowing a day of clock skew
+func withYear(ts, now time.Time) time.Time {
+	ts = ts.AddDate(now.Year()-ts.Year(), 0, 0)
+	if ts.After(now.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+	return ts
+}
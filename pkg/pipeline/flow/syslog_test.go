@@ -0,0 +1,118 @@
+package flow_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestParseSyslog(t *testing.T) {
+	t.Run("parses RFC 5424 messages", func(t *testing.T) {
+		msg, err := flow.ParseSyslog([]byte(`<165>1 2026-01-02T03:04:05.003Z fw1 evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App \"x\" \]"][meta seq="1"] `+"\ufeff"+`An application event`), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, flow.SyslogMessage{
+			Priority:  165,
+			Facility:  20,
+			Severity:  5,
+			Version:   1,
+			Timestamp: time.Date(2026, 1, 2, 3, 4, 5, 3e6, time.UTC),
+			Hostname:  "fw1",
+			AppName:   "evntslog",
+			ProcID:    "1234",
+			MsgID:     "ID47",
+			StructuredData: map[string]map[string]string{
+				"exampleSDID@32473": {"iut": "3", "eventSource": `App "x" ]`},
+				"meta":              {"seq": "1"},
+			},
+			Message: "An application event",
+		}, msg)
+	})
+
+	t.Run("parses RFC 5424 messages with nil values", func(t *testing.T) {
+		msg, err := flow.ParseSyslog([]byte("<34>1 - - - - - -\n"), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, flow.SyslogMessage{Priority: 34, Facility: 4, Severity: 2, Version: 1}, msg)
+	})
+
+	t.Run("parses RFC 3164 messages", func(t *testing.T) {
+		sent := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+		msg, err := flow.ParseSyslog([]byte("<38>"+sent.Format(time.Stamp)+" web1 sshd[811]: Accepted publickey for alice"), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, flow.SyslogMessage{
+			Priority:  38,
+			Facility:  4,
+			Severity:  6,
+			Timestamp: sent,
+			Hostname:  "web1",
+			AppName:   "sshd",
+			ProcID:    "811",
+			Message:   "Accepted publickey for alice",
+		}, msg)
+	})
+
+	t.Run("parses RFC 3164 messages with RFC 3339 timestamps and no host", func(t *testing.T) {
+		msg, err := flow.ParseSyslog([]byte("<13>2026-01-02T03:04:05+01:00 kernel: link down"), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "kernel", msg.AppName)
+		assert.Empty(t, msg.Hostname)
+		assert.Equal(t, "link down", msg.Message)
+		assert.True(t, msg.Timestamp.Equal(time.Date(2026, 1, 2, 2, 4, 5, 0, time.UTC)))
+	})
+
+	t.Run("defaults the priority and keeps unstructured text", func(t *testing.T) {
+		msg, err := flow.ParseSyslog([]byte("just some text"), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, flow.SyslogMessage{Priority: 13, Facility: 1, Severity: 5, Message: "just some text"}, msg)
+	})
+
+	t.Run("rejects malformed messages", func(t *testing.T) {
+		for _, raw := range []string{
+			"",
+			"<999>1 - - - - - -",
+			"<13",
+			"<13>1 2026-01-02",
+			"<13>1 yesterday host app - - -",
+			`<13>1 - host app - - [id k="unterminated]`,
+			"<13>1 - host app - - [id]trailing",
+		} {
+			_, err := flow.ParseSyslog([]byte(raw), nil)
+			assert.ErrorIs(t, err, flow.ErrMalformedSyslog, raw)
+		}
+	})
+}
+
+func TestSyslogParser_Transform(t *testing.T) {
+	var letters []string
+	parser, err := flow.NewSyslogParser(flow.SyslogConfig{}, func(raw string, _ error) {
+		letters = append(letters, raw)
+	})
+	assert.NoError(t, err)
+
+	raw := "<34>1 2026-01-02T03:04:05Z host app - - - message"
+	in := make(chan string, 2)
+	in <- raw
+	in <- "<13>1 bad"
+	close(in)
+
+	var result []pipeline.DataRawReadable
+	for r := range parser.Transform(in, nil) {
+		result = append(result, r)
+	}
+
+	assert.Len(t, result, 1)
+	data, err := result[0].Data().Read()
+	assert.NoError(t, err)
+	var msg map[string]any
+	assert.NoError(t, json.Unmarshal(data, &msg))
+	assert.Equal(t, "host", msg["hostname"])
+	assert.Equal(t, "2026-01-02T03:04:05Z", msg["timestamp"])
+
+	p, err := result[0].Raw().Read()
+	assert.NoError(t, err)
+	assert.Equal(t, raw, string(p))
+	assert.Equal(t, []string{"<13>1 bad"}, letters)
+}
@@ -0,0 +1,153 @@
+package flow
+
+import (
+	"errors"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that the stream control flows implement the Flow interface.
+var (
+	_ pipeline.Flow[any, any] = (*Take[any])(nil)
+	_ pipeline.Flow[any, any] = (*Skip[any])(nil)
+	_ pipeline.Flow[any, any] = (*TakeWhile[any])(nil)
+	_ pipeline.Flow[any, any] = (*DropWhile[any])(nil)
+)
+
+// Take is a struct that represents passing only the first items of a data stream.
+type Take[I any] struct {
+	n int
+}
+
+// NewTake creates a new Take flow passing the first n items. If n is less than or equal to 0, no items
+// are passed.
+func NewTake[I any](n int) *Take[I] {
+	return &Take[I]{
+		n: n,
+	}
+}
+
+// Transform passes the first n items from the input channel and closes the output channel as soon as
+// the nth is sent, without waiting for the next item. The rest of the input channel is drained so
+// upstream stages are not blocked.
+func (t Take[I]) Transform(in <-chan I, _ chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+	go func() {
+		taken := 0
+		if taken < t.n {
+			for v := range in {
+				out <- v
+				taken++
+				if taken == t.n {
+					break
+				}
+			}
+		}
+		close(out)
+		drain(in)
+	}()
+	return out
+}
+
+// Skip is a struct that represents dropping the first items of a data stream.
+type Skip[I any] struct {
+	n int
+}
+
+// NewSkip creates a new Skip flow dropping the first n items.
+func NewSkip[I any](n int) *Skip[I] {
+	return &Skip[I]{
+		n: n,
+	}
+}
+
+// Transform drops the first n items from the input channel and passes the rest to the output channel.
+func (s Skip[I]) Transform(in <-chan I, _ chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+	go func() {
+		defer close(out)
+		skipped := 0
+		for v := range in {
+			if skipped < s.n {
+				skipped++
+				continue
+			}
+			out <- v
+		}
+	}()
+	return out
+}
+
+// TakeWhile is a struct that represents passing items of a data stream until a predicate fails.
+type TakeWhile[I any] struct {
+	predicate PredicateFunc[I]
+}
+
+// NewTakeWhile creates a new TakeWhile flow with the given predicate function.
+func NewTakeWhile[I any](predicate PredicateFunc[I]) (*TakeWhile[I], error) {
+	if predicate == nil {
+		return nil, errors.New("predicate func is nil")
+	}
+
+	return &TakeWhile[I]{
+		predicate: predicate,
+	}, nil
+}
+
+// Transform passes items from the input channel while they satisfy the predicate function and
+// closes the output channel at the first that does not. The rest of the input channel is drained
+// so upstream stages are not blocked.
+func (t TakeWhile[I]) Transform(in <-chan I, _ chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+	go func() {
+		for v := range in {
+			if !t.predicate(v) {
+				break
+			}
+			out <- v
+		}
+		close(out)
+		drain(in)
+	}()
+	return out
+}
+
+// DropWhile is a struct that represents dropping items of a data stream until a predicate fails.
+type DropWhile[I any] struct {
+	predicate PredicateFunc[I]
+}
+
+// NewDropWhile creates a new DropWhile flow with the given predicate function.
+func NewDropWhile[I any](predicate PredicateFunc[I]) (*DropWhile[I], error) {
+	if predicate == nil {
+		return nil, errors.New("predicate func is nil")
+	}
+
+	return &DropWhile[I]{
+		predicate: predicate,
+	}, nil
+}
+
+// Transform drops items from the input channel while they satisfy the predicate function and
+// passes the first that does not and every item after it to the output channel.
+func (d DropWhile[I]) Transform(in <-chan I, _ chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+	go func() {
+		defer close(out)
+		dropping := true
+		for v := range in {
+			if dropping && d.predicate(v) {
+				continue
+			}
+			dropping = false
+			out <- v
+		}
+	}()
+	return out
+}
+
+// drain discards the remaining items of the channel until it is closed
+func drain[I any](in <-chan I) {
+	for range in {
+	}
+}
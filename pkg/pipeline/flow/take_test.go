@@ -0,0 +1,88 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// collect sends the items to the flow and returns what it emits
+func collect(f pipeline.Flow[int, int], items ...int) []int {
+	in := make(chan int)
+	out := f.Transform(in, nil)
+	go func() {
+		for _, v := range items {
+			in <- v
+		}
+		close(in)
+	}()
+
+	result := []int{}
+	for v := range out {
+		result = append(result, v)
+	}
+	return result
+}
+
+func TestTake_Transform(t *testing.T) {
+	assert.Equal(t, []int{1, 2}, collect(flow.NewTake[int](2), 1, 2, 3, 4))
+	assert.Equal(t, []int{1}, collect(flow.NewTake[int](5), 1))
+	assert.Equal(t, []int{}, collect(flow.NewTake[int](0), 1, 2))
+	assert.Equal(t, []int{}, collect(flow.NewTake[int](-1), 1, 2))
+}
+
+func TestTake_IdleInput(t *testing.T) {
+	for _, n := range []int{0, 2} {
+		// the input sends n items and then idles without being closed
+		in := make(chan int)
+		defer close(in)
+		out := flow.NewTake[int](n).Transform(in, nil)
+		go func() {
+			for i := range n {
+				in <- i
+			}
+		}()
+
+		var got []int
+		timeout := time.After(time.Second)
+	recv:
+		for {
+			select {
+			case v, ok := <-out:
+				if !ok {
+					break recv
+				}
+				got = append(got, v)
+			case <-timeout:
+				t.Fatalf("output of Take(%d) not closed on an idle input", n)
+			}
+		}
+		assert.Len(t, got, n)
+	}
+}
+
+func TestSkip_Transform(t *testing.T) {
+	assert.Equal(t, []int{3, 4}, collect(flow.NewSkip[int](2), 1, 2, 3, 4))
+	assert.Equal(t, []int{}, collect(flow.NewSkip[int](5), 1))
+}
+
+func TestTakeWhile_Transform(t *testing.T) {
+	takeWhile, err := flow.NewTakeWhile(func(v int) bool { return v < 3 })
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, collect(takeWhile, 1, 2, 3, 1))
+
+	_, err = flow.NewTakeWhile[int](nil)
+	assert.Error(t, err)
+}
+
+func TestDropWhile_Transform(t *testing.T) {
+	dropWhile, err := flow.NewDropWhile(func(v int) bool { return v < 3 })
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3, 1}, collect(dropWhile, 1, 2, 3, 1))
+
+	_, err = flow.NewDropWhile[int](nil)
+	assert.Error(t, err)
+}
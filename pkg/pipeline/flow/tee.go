@@ -0,0 +1,68 @@
+package flow
+
+import (
+	"strconv"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Tee implements the FanOut interface.
+var _ pipeline.FanOut[any] = (*Tee[any])(nil)
+
+// MetricTeeDropped is the counter of items dropped for a branch whose buffer was full.
+const MetricTeeDropped = "krapht_tee_dropped_total"
+
+// TeeConfig is the configuration of a tee flow.
+type TeeConfig struct {
+	BufferSize   int  // items buffered per branch, defaults to 1
+	DropWhenFull bool // drop items for a branch whose buffer is full instead of waiting for it
+}
+
+// Tee is a struct that represents duplication of a data stream onto several branches.
+type Tee[I any] struct {
+	bufferSize   int
+	dropWhenFull bool
+}
+
+// NewTee creates a new Tee flow with the given configuration.
+// Each branch has its own buffer, so a slow consumer only stalls the others once its
+// buffer is full, or never when DropWhenFull is set.
+func NewTee[I any](conf TeeConfig) *Tee[I] {
+	if conf.BufferSize <= 0 {
+		conf.BufferSize = 1
+	}
+	return &Tee[I]{
+		bufferSize:   conf.BufferSize,
+		dropWhenFull: conf.DropWhenFull,
+	}
+}
+
+// Split sends every item from the input channel to each of the n returned channels and closes
+// them when the input channel is closed. Items dropped for a full branch are counted with a
+// metric event labelled with the branch index.
+func (t Tee[I]) Split(in <-chan I, eventC chan<- pipeline.Event, n uint8) []<-chan I {
+	outs, branches := newBranches[I](n, t.bufferSize)
+
+	go func() {
+		defer closeBranches(outs)
+
+		dropped := make([]float64, n)
+		for v := range in {
+			for i, out := range outs {
+				if !t.dropWhenFull {
+					out <- v
+					continue
+				}
+
+				select {
+				case out <- v:
+				default:
+					dropped[i]++
+					pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricTeeDropped, dropped[i],
+						map[string]string{"branch": strconv.Itoa(i)}, pipeline.MetricTypeCounter))
+				}
+			}
+		}
+	}()
+	return branches
+}
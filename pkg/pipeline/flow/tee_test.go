@@ -0,0 +1,75 @@
+package flow_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestTee_Split(t *testing.T) {
+	t.Run("duplicates every item to each branch", func(t *testing.T) {
+		tee := flow.NewTee[int](flow.TeeConfig{})
+
+		in := make(chan int)
+		branches := tee.Split(in, nil, 3)
+		assert.Len(t, branches, 3)
+
+		go func() {
+			for i := range 5 {
+				in <- i
+			}
+			close(in)
+		}()
+
+		results := make([][]int, len(branches))
+		var wg sync.WaitGroup
+		for i, branch := range branches {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for v := range branch {
+					results[i] = append(results[i], v)
+				}
+			}()
+		}
+		wg.Wait()
+
+		for _, result := range results {
+			assert.Equal(t, []int{0, 1, 2, 3, 4}, result)
+		}
+	})
+
+	t.Run("drops items for a full branch", func(t *testing.T) {
+		tee := flow.NewTee[int](flow.TeeConfig{BufferSize: 2, DropWhenFull: true})
+
+		in := make(chan int)
+		eventC := make(chan pipeline.Event, 10)
+		branches := tee.Split(in, eventC, 2)
+
+		// neither branch is read until the input is done
+		for i := range 5 {
+			in <- i
+		}
+		close(in)
+
+		for _, branch := range branches {
+			var result []int
+			for v := range branch {
+				result = append(result, v)
+			}
+			assert.Equal(t, []int{0, 1}, result)
+		}
+
+		assert.Len(t, eventC, 6)
+		dropped := map[string]float64{}
+		for range 6 {
+			metric := (<-eventC).(pipeline.MetricEvent)
+			assert.Equal(t, flow.MetricTeeDropped, metric.Name())
+			dropped[metric.Labels()["branch"]] = metric.Value()
+		}
+		assert.Equal(t, map[string]float64{"0": 3, "1": 3}, dropped)
+	})
+}
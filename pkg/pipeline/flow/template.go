@@ -0,0 +1,283 @@
+package flow
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Template implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*Template[any])(nil)
+
+// TemplateConfig is the configuration of a template formatting flow.
+type TemplateConfig struct {
+	Text   string           // text/template rendered with each record, required
+	Strict bool             // fail on missing fields of records rather than rendering them as "<no value>"
+	Funcs  template.FuncMap // functions added to the helpers, replacing helpers of the same name
+}
+
+// Template is a struct that represents formatting of records on a data stream through a template.
+type Template[I any] struct {
+	tmpl *template.Template
+}
+
+// NewTemplate creates a new Template flow with the given configuration.
+//
+// Besides the builtin functions, templates have these helpers, named and ordered as in sprig so the
+// piped value comes last: upper, lower, trim, trimPrefix, trimSuffix, replace, contains, hasPrefix,
+// hasSuffix, repeat, trunc, indent, nindent, quote, squote, splitList, join, default, coalesce, empty,
+// ternary, toString, int, float64, toJson, toPrettyJson, b64enc, b64dec, list, dict, now, date and unixEpoch.
+func NewTemplate[I any](conf TemplateConfig) (*Template[I], error) {
+	if conf.Text == "" {
+		return nil, errors.New("template is empty")
+	}
+
+	tmpl := template.New("template").Funcs(templateHelpers).Funcs(conf.Funcs)
+	if conf.Strict {
+		tmpl = tmpl.Option("missingkey=error")
+	}
+	tmpl, err := tmpl.Parse(conf.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Template[I]{tmpl: tmpl}, nil
+}
+
+// Transform renders the items from the input channel and returns the output channel of records holding
+// the rendered text. Items are rendered with their decoded JSON payload, with numbers kept as written,
+// or with the payload as a string when it is not JSON. The payload is the data of a DataReadable, the raw
+// message of a RawReadable, the bytes of a Readable, []byte or string, and the raw message of a RawReadable
+// or the payload itself is retained as the raw message.
+// Items that fail to render are dropped and reported with an error event carrying them as the record.
+func (t Template[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+		var buf bytes.Buffer
+		for v := range in {
+			b, err := payload(v)
+			if err == nil {
+				buf.Reset()
+				err = t.tmpl.Execute(&buf, templateData(b))
+			}
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("template error", err, false, v))
+				continue
+			}
+			out <- pipeline.DeriveRecord(pipeline.Bytes(bytes.Clone(buf.Bytes())), rawOrPayload(v, b), v)
+		}
+	}()
+	return out
+}
+
+// templateData returns the decoded JSON payload, or the payload as a string when it is not JSON
+func templateData(b []byte) any {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var data any
+	if err := d.Decode(&data); err != nil || d.More() {
+		return string(b)
+	}
+	return data
+}
+
+// templateHelpers are the sprig style helpers of templates
+var templateHelpers = template.FuncMap{
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"repeat":     func(n int, s string) string { return strings.Repeat(s, max(n, 0)) },
+	"trunc": func(n int, s string) string {
+		if n >= 0 && n < len(s) {
+			return s[:n]
+		}
+		return s
+	},
+	"indent":  indent,
+	"nindent": func(n int, s string) string { return "\n" + indent(n, s) },
+	"quote": func(v ...any) string {
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = strconv.Quote(templateString(s))
+		}
+		return strings.Join(quoted, " ")
+	},
+	"squote": func(v ...any) string {
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = "'" + templateString(s) + "'"
+		}
+		return strings.Join(quoted, " ")
+	},
+	"splitList": func(sep, s string) []string { return strings.Split(s, sep) },
+	"join": func(sep string, v any) string {
+		var items []string
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			for i := range rv.Len() {
+				items = append(items, templateString(rv.Index(i).Interface()))
+			}
+		} else {
+			items = append(items, templateString(v))
+		}
+		return strings.Join(items, sep)
+	},
+	"default": func(d any, v ...any) any {
+		if len(v) == 0 || templateEmpty(v[0]) {
+			return d
+		}
+		return v[0]
+	},
+	"coalesce": func(v ...any) any {
+		for _, value := range v {
+			if !templateEmpty(value) {
+				return value
+			}
+		}
+		return nil
+	},
+	"empty": templateEmpty,
+	"ternary": func(yes, no any, cond bool) any {
+		if cond {
+			return yes
+		}
+		return no
+	},
+	"toString": templateString,
+	"int": func(v any) (int64, error) {
+		f, err := templateFloat(v)
+		return int64(f), err
+	},
+	"float64": templateFloat,
+	"toJson": func(v any) (string, error) {
+		p, err := json.Marshal(v)
+		return string(p), err
+	},
+	"toPrettyJson": func(v any) (string, error) {
+		p, err := json.MarshalIndent(v, "", "  ")
+		return string(p), err
+	},
+	"b64enc": func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"b64dec": func(s string) (string, error) {
+		p, err := base64.StdEncoding.DecodeString(s)
+		return string(p), err
+	},
+	"list": func(v ...any) []any { return v },
+	"dict": func(v ...any) (map[string]any, error) {
+		if len(v)%2 != 0 {
+			return nil, errors.New("dict expects key value pairs")
+		}
+		d := make(map[string]any, len(v)/2)
+		for i := 0; i < len(v); i += 2 {
+			d[templateString(v[i])] = v[i+1]
+		}
+		return d, nil
+	},
+	"now": time.Now,
+	"date": func(layout string, v any) (string, error) {
+		t, err := templateTime(v)
+		return t.Format(layout), err
+	},
+	"unixEpoch": func(v any) (string, error) {
+		t, err := templateTime(v)
+		return strconv.FormatInt(t.Unix(), 10), err
+	},
+}
+
+// indent prefixes every line of s with n spaces
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", max(n, 0))
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+// templateString returns the text of a value
+func templateString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// templateEmpty reports whether a value is nil, false, zero or empty
+func templateEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	if n, ok := v.(json.Number); ok {
+		f, err := n.Float64()
+		return err == nil && f == 0
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return rv.IsZero()
+	}
+}
+
+// templateFloat converts a number or numeric string to a float
+func templateFloat(v any) (float64, error) {
+	switch v := v.(type) {
+	case json.Number:
+		return v.Float64()
+	case string:
+		return strconv.ParseFloat(v, 64)
+	case nil:
+		return 0, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.CanInt():
+		return float64(rv.Int()), nil
+	case rv.CanUint():
+		return float64(rv.Uint()), nil
+	case rv.CanFloat():
+		return rv.Float(), nil
+	}
+	return 0, fmt.Errorf("cannot convert %T to a number", v)
+}
+
+// templateTime converts a time, epoch seconds or RFC 3339 string to a time
+func templateTime(v any) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t, nil
+		}
+	}
+
+	f, err := templateFloat(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("cannot convert %v to a time", v)
+	}
+	return time.Unix(0, int64(f*float64(time.Second))).UTC(), nil
+}
@@ -0,0 +1,108 @@
+package flow_test
+
+import (
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// render sends the payloads through a template flow and returns the rendered texts and errors
+func render(t *testing.T, conf flow.TemplateConfig, payloads ...string) ([]string, []error) {
+	t.Helper()
+
+	tmpl, err := flow.NewTemplate[string](conf)
+	assert.NoError(t, err)
+
+	in := make(chan string, len(payloads))
+	for _, p := range payloads {
+		in <- p
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, len(payloads))
+	var texts []string
+	for r := range tmpl.Transform(in, eventC) {
+		data, _ := r.Data().Read()
+		texts = append(texts, string(data))
+		raw, _ := r.Raw().Read()
+		assert.Contains(t, payloads, string(raw))
+	}
+	close(eventC)
+
+	var errs []error
+	for e := range eventC {
+		errs = append(errs, e.(pipeline.ErrorEvent))
+	}
+	return texts, errs
+}
+
+func TestTemplate_Transform(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		in   string
+		want string
+	}{
+		{
+			name: "formats a syslog line",
+			text: `<{{ .pri }}>{{ .ts | date "Jan _2 15:04:05" }} {{ .host | lower }} {{ .app }}: {{ .msg | trim }}`,
+			in:   `{"pri":34,"ts":"2026-01-02T03:04:05Z","host":"WEB1","app":"sshd","msg":" Accepted publickey "}`,
+			want: `<34>Jan  2 03:04:05 web1 sshd: Accepted publickey`,
+		},
+		{
+			name: "formats a csv row with defaults",
+			text: `{{ .user | quote }},{{ .score | default 0 }},{{ join ";" .roles }},{{ .note | default "n/a" }}`,
+			in:   `{"user":"alice","score":0,"roles":["admin","dev"],"note":""}`,
+			want: `"alice",0,admin;dev,n/a`,
+		},
+		{
+			name: "keeps large numbers as written",
+			text: `{{ .id }} {{ .bytes | int }} {{ unixEpoch .ts }} {{ toJson (dict "k" .id) }}`,
+			in:   `{"id":1234567890123456789,"bytes":"1024","ts":1767323045}`,
+			want: `1234567890123456789 1024 1767323045 {"k":1234567890123456789}`,
+		},
+		{
+			name: "renders text payloads as strings",
+			text: `alert: {{ . | upper | trunc 5 }}{{ if contains "disk" . }} (disk){{ end }}`,
+			in:   `disk full`,
+			want: `alert: DISK  (disk)`,
+		},
+		{
+			name: "uses sprig argument order",
+			text: `{{ "a-b-c" | replace "-" "_" }} {{ "x" | repeat 3 }} {{ ternary "yes" "no" (empty .missing) }} {{ "hi" | b64enc }}`,
+			in:   `{}`,
+			want: `a_b_c xxx yes aGk=`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			texts, errs := render(t, flow.TemplateConfig{Text: tt.text}, tt.in)
+			assert.Empty(t, errs)
+			assert.Equal(t, []string{tt.want}, texts)
+		})
+	}
+}
+
+func TestTemplate_Options(t *testing.T) {
+	texts, _ := render(t, flow.TemplateConfig{Text: "{{ .missing }}"}, `{}`)
+	assert.Equal(t, []string{"<no value>"}, texts)
+
+	texts, errs := render(t, flow.TemplateConfig{Text: "{{ .present }}{{ .missing }}", Strict: true}, `{"present":1,"missing":2}`, `{"present":1}`)
+	assert.Equal(t, []string{"12"}, texts)
+	assert.Len(t, errs, 1)
+
+	texts, _ = render(t, flow.TemplateConfig{
+		Text:  "{{ shout .msg }}",
+		Funcs: template.FuncMap{"shout": func(s string) string { return s + "!" }},
+	}, `{"msg":"hi"}`)
+	assert.Equal(t, []string{"hi!"}, texts)
+
+	_, err := flow.NewTemplate[string](flow.TemplateConfig{})
+	assert.Error(t, err)
+	_, err = flow.NewTemplate[string](flow.TemplateConfig{Text: "{{ .a "})
+	assert.Error(t, err)
+}
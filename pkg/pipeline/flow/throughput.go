@@ -0,0 +1,152 @@
+package flow
+
+import (
+	"errors"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Throughput implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*Throughput[any])(nil)
+
+// Metric names recorded by the throughput flow
+const (
+	MetricFlowItems          = "krapht_flow_items_total"
+	MetricFlowBytes          = "krapht_flow_bytes_total"
+	MetricFlowItemsPerSecond = "krapht_flow_items_per_second"
+	MetricFlowBytesPerSecond = "krapht_flow_bytes_per_second"
+)
+
+// throughputOther is the key label of the items of keys beyond the limit
+const throughputOther = "other"
+
+// ThroughputConfig is the configuration of a throughput flow.
+type ThroughputConfig[I any] struct {
+	Name     string        // name of the measured point used as the "flow" label, required
+	Interval time.Duration // interval between metric events, defaults to 10 seconds
+
+	// Key returns the "key" label of an item, for metrics broken down per key in addition to the totals.
+	Key     func(in I) string
+	MaxKeys int // keys tracked at most, later keys are counted as "other", defaults to 100
+}
+
+// Throughput is a struct that represents measuring of the items passing a point of a data stream.
+type Throughput[I any] struct {
+	conf ThroughputConfig[I]
+}
+
+// NewThroughput creates a new Throughput flow with the given configuration.
+func NewThroughput[I any](conf ThroughputConfig[I]) (*Throughput[I], error) {
+	if conf.Name == "" {
+		return nil, errors.New("name is empty")
+	}
+
+	if conf.Interval <= 0 {
+		conf.Interval = 10 * time.Second
+	}
+
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = 100
+	}
+
+	return &Throughput[I]{
+		conf: conf,
+	}, nil
+}
+
+// throughputCount is the count of items and bytes of the totals or a key
+type throughputCount struct {
+	items, bytes         uint64
+	lastItems, lastBytes uint64
+}
+
+// Transform passes the data from the input channel to the output channel unchanged, counting the items
+// and their bytes. The counters and the rates over the interval are sent as metric events every interval
+// and once more when the input channel is closed. Bytes are counted for Readable, DataReadable, []byte
+// and string items.
+func (t Throughput[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan I {
+	out := make(chan I)
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(t.conf.Interval)
+		defer ticker.Stop()
+
+		var total throughputCount
+		keys := make(map[string]*throughputCount)
+		last := time.Now()
+
+		report := func() {
+			now := time.Now()
+			elapsed := now.Sub(last).Seconds()
+			last = now
+
+			t.report(eventC, map[string]string{"flow": t.conf.Name}, &total, elapsed)
+			for key, count := range keys {
+				t.report(eventC, map[string]string{"flow": t.conf.Name, "key": key}, count, elapsed)
+			}
+		}
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					report()
+					return
+				}
+
+				size := uint64(payloadSize(v))
+				total.items++
+				total.bytes += size
+				if t.conf.Key != nil {
+					key := t.conf.Key(v)
+					count, ok := keys[key]
+					if !ok && len(keys) >= t.conf.MaxKeys {
+						key = throughputOther
+						count, ok = keys[key]
+					}
+					if !ok {
+						count = new(throughputCount)
+						keys[key] = count
+					}
+					count.items++
+					count.bytes += size
+				}
+				out <- v
+			case <-ticker.C:
+				report()
+			}
+		}
+	}()
+	return out
+}
+
+// report sends the counters and rates of a count with the labels
+func (t Throughput[I]) report(eventC chan<- pipeline.Event, labels map[string]string, count *throughputCount, elapsed float64) {
+	pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricFlowItems, float64(count.items), labels, pipeline.MetricTypeCounter))
+	pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricFlowBytes, float64(count.bytes), labels, pipeline.MetricTypeCounter))
+	if elapsed > 0 {
+		pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricFlowItemsPerSecond, float64(count.items-count.lastItems)/elapsed, labels, pipeline.MetricTypeGauge))
+		pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricFlowBytesPerSecond, float64(count.bytes-count.lastBytes)/elapsed, labels, pipeline.MetricTypeGauge))
+	}
+	count.lastItems, count.lastBytes = count.items, count.bytes
+}
+
+// payloadSize returns the payload size of an item when it can be determined without encoding it
+func payloadSize(v any) int {
+	switch r := v.(type) {
+	case pipeline.DataReadable:
+		p, _ := r.Data().Read()
+		return len(p)
+	case pipeline.Readable:
+		p, _ := r.Read()
+		return len(p)
+	case []byte:
+		return len(r)
+	case string:
+		return len(r)
+	default:
+		return 0
+	}
+}
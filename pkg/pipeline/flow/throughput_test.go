@@ -0,0 +1,88 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestThroughput_Transform(t *testing.T) {
+	th, err := flow.NewThroughput(flow.ThroughputConfig[string]{
+		Name:     "ingest",
+		Interval: time.Hour,
+		Key:      func(s string) string { return s[:1] },
+		MaxKeys:  2,
+	})
+	assert.NoError(t, err)
+
+	in := make(chan string, 5)
+	for _, s := range []string{"aa", "b", "aaaa", "cc", "d"} {
+		in <- s
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, 64)
+	var items []string
+	for s := range th.Transform(in, eventC) {
+		items = append(items, s)
+	}
+	close(eventC)
+	assert.Equal(t, []string{"aa", "b", "aaaa", "cc", "d"}, items)
+
+	counters := make(map[string]float64)
+	var rates int
+	for e := range eventC {
+		m := e.(pipeline.MetricEvent)
+		assert.Equal(t, "ingest", m.Labels()["flow"])
+		switch m.MetricType() {
+		case string(pipeline.MetricTypeCounter):
+			counters[m.Name()+"/"+m.Labels()["key"]] = m.Value()
+		case string(pipeline.MetricTypeGauge):
+			rates++
+			assert.Positive(t, m.Value())
+		}
+	}
+
+	assert.Equal(t, map[string]float64{
+		flow.MetricFlowItems + "/": 5, flow.MetricFlowBytes + "/": 10,
+		flow.MetricFlowItems + "/a": 2, flow.MetricFlowBytes + "/a": 6,
+		flow.MetricFlowItems + "/b": 1, flow.MetricFlowBytes + "/b": 1,
+		flow.MetricFlowItems + "/other": 2, flow.MetricFlowBytes + "/other": 3,
+	}, counters)
+	assert.Equal(t, 8, rates)
+}
+
+func TestThroughput_Interval(t *testing.T) {
+	th, err := flow.NewThroughput(flow.ThroughputConfig[[]byte]{Name: "ingest", Interval: 10 * time.Millisecond})
+	assert.NoError(t, err)
+
+	in := make(chan []byte)
+	defer close(in)
+	eventC := make(chan pipeline.Event, 16)
+	out := th.Transform(in, eventC)
+
+	in <- []byte("abc")
+	<-out
+
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case e := <-eventC:
+			m := e.(pipeline.MetricEvent)
+			if m.Name() == flow.MetricFlowBytes {
+				assert.Equal(t, float64(3), m.Value())
+				return
+			}
+		case <-deadline:
+			t.Fatal("no metrics were sent within the interval")
+		}
+	}
+}
+
+func TestNewThroughput_Validation(t *testing.T) {
+	_, err := flow.NewThroughput(flow.ThroughputConfig[string]{})
+	assert.Error(t, err)
+}
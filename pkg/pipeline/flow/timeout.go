@@ -0,0 +1,97 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Timeout implements the Flow interface.
+var _ pipeline.Flow[any, any] = (*Timeout[any, any])(nil)
+
+// ErrItemTimeout is the error of an item whose transform exceeded its deadline.
+var ErrItemTimeout = errors.New("item processing timed out")
+
+// ContextMapFunc is a function that transforms an input value to an output value and stops
+// when the context is done.
+type ContextMapFunc[I, O any] func(ctx context.Context, in I) (O, error)
+
+// WithTimeout returns a MapFunc running transform with a deadline of timeout per item, so it can
+// be wrapped by the other flows. When the deadline passes, the MapFunc returns ErrItemTimeout
+// without waiting for transform, which is left to observe its cancelled context and return.
+func WithTimeout[I, O any](transform ContextMapFunc[I, O], timeout time.Duration) MapFunc[I, O] {
+	return func(in I) (O, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		type result struct {
+			val O
+			err error
+		}
+		// buffered so an abandoned transform does not block when it returns
+		done := make(chan result, 1)
+		go func() {
+			val, err := transform(ctx, in)
+			done <- result{val: val, err: err}
+		}()
+
+		select {
+		case r := <-done:
+			// a transform failing on its expired context timed out as well
+			if r.err == nil || ctx.Err() == nil {
+				return r.val, r.err
+			}
+		case <-ctx.Done():
+		}
+		var zero O
+		return zero, fmt.Errorf("%w after %s", ErrItemTimeout, timeout)
+	}
+}
+
+// Timeout is a struct that represents a map operation on a data stream with a deadline per item.
+type Timeout[I, O any] struct {
+	transform  MapFunc[I, O]
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewTimeout creates a new Timeout flow applying transform with a deadline of timeout per item.
+// Items that fail or exceed the deadline are passed to deadLetter when it is not nil.
+func NewTimeout[I, O any](transform ContextMapFunc[I, O], timeout time.Duration, deadLetter DeadLetterFunc[I]) (*Timeout[I, O], error) {
+	if transform == nil {
+		return nil, errors.New("transform func is nil")
+	}
+
+	if timeout <= 0 {
+		return nil, errors.New("timeout must be positive")
+	}
+
+	return &Timeout[I, O]{
+		transform:  WithTimeout(transform, timeout),
+		deadLetter: deadLetter,
+	}, nil
+}
+
+// Transform applies the map operation on the input channel in a goroutine and returns the output channel.
+// An item that fails or exceeds its deadline is dropped and reported with an error event carrying it as
+// the record, so a hung transform holds back the items after it for at most the timeout.
+func (t Timeout[I, O]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan O {
+	out := make(chan O)
+	go func() {
+		defer close(out)
+		for v := range in {
+			val, err := t.transform(v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("timeout transform error", err, errors.Is(err, ErrItemTimeout), v))
+				if t.deadLetter != nil {
+					t.deadLetter(v, err)
+				}
+				continue
+			}
+			out <- val
+		}
+	}()
+	return out
+}
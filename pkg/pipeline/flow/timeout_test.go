@@ -0,0 +1,71 @@
+package flow_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// lookup hangs for the value 0 until its context is done and doubles the others
+func lookup(ctx context.Context, v int) (int, error) {
+	if v == 0 {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}
+	return v * 2, nil
+}
+
+func TestTimeout_Transform(t *testing.T) {
+	var letters []int
+	timeout, err := flow.NewTimeout(lookup, 20*time.Millisecond, func(v int, err error) {
+		assert.ErrorIs(t, err, flow.ErrItemTimeout)
+		letters = append(letters, v)
+	})
+	assert.NoError(t, err)
+
+	in := make(chan int, 3)
+	in <- 1
+	in <- 0
+	in <- 2
+	close(in)
+
+	eventC := make(chan pipeline.Event, 1)
+	start := time.Now()
+	var result []int
+	for v := range timeout.Transform(in, eventC) {
+		result = append(result, v)
+	}
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, []int{2, 4}, result)
+	assert.Equal(t, []int{0}, letters)
+
+	errEvent, ok := (<-eventC).(pipeline.ErrorEvent)
+	assert.True(t, ok)
+	assert.ErrorIs(t, errEvent, flow.ErrItemTimeout)
+	assert.Equal(t, 0, errEvent.Record())
+}
+
+func TestWithTimeout(t *testing.T) {
+	transform := flow.WithTimeout(lookup, 10*time.Millisecond)
+
+	v, err := transform(3)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, v)
+
+	_, err = transform(0)
+	assert.True(t, errors.Is(err, flow.ErrItemTimeout))
+}
+
+func TestNewTimeout_Validation(t *testing.T) {
+	_, err := flow.NewTimeout[int, int](nil, time.Second, nil)
+	assert.Error(t, err)
+
+	_, err = flow.NewTimeout(lookup, 0, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,203 @@
+package flow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that WASM implements the Flow interface.
+var _ pipeline.Flow[any, pipeline.DataRawReadable] = (*WASM[any])(nil)
+
+// WASMConfig is the configuration of a WebAssembly transform.
+type WASMConfig struct {
+	Module      []byte        // WebAssembly binary implementing the transform ABI, required
+	Timeout     time.Duration // deadline of the transform of an item, defaults to 1s
+	MemoryPages uint32        // bound on the linear memory of the module in 64KiB pages, defaults to 256 (16MiB)
+}
+
+// WASM is a struct that represents transformation of the payloads on a data stream by a WebAssembly module.
+//
+// The module exports its memory as "memory" and the functions
+//
+//	alloc(size i32) i32                 returns a buffer of size bytes for the input payload
+//	transform(ptr i32, size i32) i64    transforms the payload in the buffer
+//	free(ptr i32, size i32)             optional, releases the input buffer after the transform
+//
+// The result of transform is the address of the output in its high 32 bits and its size in the low
+// 32 bits, or a negative value to drop the item. The output is read before the next call, so the
+// module may reuse its buffer. Modules run without access to the file system, network or real clock,
+// and those built for WASI are instantiated as reactors, running "_initialize" rather than "_start".
+type WASM[I any] struct {
+	conf       WASMConfig
+	runtime    wazero.Runtime
+	compiled   wazero.CompiledModule
+	hasFree    bool
+	deadLetter DeadLetterFunc[I]
+}
+
+// NewWASM creates a new WASM flow with the given configuration, compiling the module.
+// Items that fail to transform are passed to deadLetter when it is not nil.
+func NewWASM[I any](conf WASMConfig, deadLetter DeadLetterFunc[I]) (*WASM[I], error) {
+	if len(conf.Module) == 0 {
+		return nil, errors.New("wasm module is empty")
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = time.Second
+	}
+
+	if conf.MemoryPages == 0 {
+		conf.MemoryPages = 256
+	}
+
+	ctx := context.Background()
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(conf.MemoryPages).
+		WithCloseOnContextDone(true))
+
+	compiled, err := runtime.CompileModule(ctx, conf.Module)
+	if err != nil {
+		_ = runtime.Close(ctx)
+		return nil, fmt.Errorf("could not compile wasm module: %w", err)
+	}
+
+	exports := compiled.ExportedFunctions()
+	for name, signature := range map[string][2][]api.ValueType{
+		"alloc":     {{api.ValueTypeI32}, {api.ValueTypeI32}},
+		"transform": {{api.ValueTypeI32, api.ValueTypeI32}, {api.ValueTypeI64}},
+	} {
+		f, ok := exports[name]
+		if !ok || !slices.Equal(f.ParamTypes(), signature[0]) || !slices.Equal(f.ResultTypes(), signature[1]) {
+			_ = runtime.Close(ctx)
+			return nil, fmt.Errorf("wasm module does not export %s with the transform abi signature", name)
+		}
+	}
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		_ = runtime.Close(ctx)
+		return nil, errors.New("wasm module does not export memory")
+	}
+	_, hasFree := exports["free"]
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		_ = runtime.Close(ctx)
+		return nil, err
+	}
+
+	return &WASM[I]{
+		conf:       conf,
+		runtime:    runtime,
+		compiled:   compiled,
+		hasFree:    hasFree,
+		deadLetter: deadLetter,
+	}, nil
+}
+
+// Close releases the compiled module and the runtime. It must not be called while a transform runs.
+func (w *WASM[I]) Close() error {
+	return w.runtime.Close(context.Background())
+}
+
+// Transform passes the payloads from the input channel through the module and returns the output channel
+// of the resulting records, retaining the raw message of the input. Each call of Transform runs its own
+// instance of the module. An item whose transform fails or exceeds the timeout is dropped and reported
+// with an error event carrying it as the record, and the instance is replaced when it was stopped.
+func (w WASM[I]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan pipeline.DataRawReadable {
+	out := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(out)
+
+		var mod api.Module
+		defer func() {
+			if mod != nil {
+				_ = mod.Close(context.Background())
+			}
+		}()
+
+		for v := range in {
+			if mod == nil || mod.IsClosed() {
+				var err error
+				if mod, err = w.instantiate(); err != nil {
+					pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("wasm: could not instantiate module", err, false, v))
+					if w.deadLetter != nil {
+						w.deadLetter(v, err)
+					}
+					continue
+				}
+			}
+
+			record, ok, err := w.record(mod, v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("wasm transform error", err, false, v))
+				if w.deadLetter != nil {
+					w.deadLetter(v, err)
+				}
+				continue
+			}
+			if ok {
+				out <- record
+			}
+		}
+	}()
+	return out
+}
+
+// instantiate creates an instance of the module without a name, so several can run at once
+func (w WASM[I]) instantiate() (api.Module, error) {
+	return w.runtime.InstantiateModule(context.Background(), w.compiled,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize"))
+}
+
+// record transforms the payload of the item in the instance, reporting false for dropped items
+func (w WASM[I]) record(mod api.Module, v I) (pipeline.DataRawReadable, bool, error) {
+	b, err := payload(v)
+	if err != nil {
+		return nil, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), w.conf.Timeout)
+	defer cancel()
+
+	results, err := mod.ExportedFunction("alloc").Call(ctx, uint64(len(b)))
+	if err != nil {
+		return nil, false, err
+	}
+	ptr := api.DecodeU32(results[0])
+	if !mod.Memory().Write(ptr, b) {
+		return nil, false, errors.New("wasm input buffer is out of range")
+	}
+
+	results, err = mod.ExportedFunction("transform").Call(ctx, uint64(ptr), uint64(len(b)))
+	if err != nil {
+		return nil, false, err
+	}
+	result := int64(results[0])
+
+	var data []byte
+	if result >= 0 {
+		view, ok := mod.Memory().Read(uint32(result>>32), uint32(result))
+		if !ok {
+			return nil, false, errors.New("wasm output is out of range")
+		}
+		data = bytes.Clone(view)
+	}
+
+	if w.hasFree {
+		if _, err := mod.ExportedFunction("free").Call(ctx, uint64(ptr), uint64(len(b))); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if result < 0 {
+		return nil, false, nil
+	}
+	return pipeline.DeriveRecord(pipeline.Bytes(data), rawOrPayload(v, b), v), true, nil
+}
@@ -0,0 +1,77 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// trimModule is a module implementing the transform ABI that drops the first byte of the payload.
+// Empty payloads are dropped, payloads starting with "!" trap and payloads starting with "~" loop forever.
+//
+//	(module
+//	  (memory (export "memory") 1)
+//	  (func (export "alloc") (param i32) (result i32) (i32.const 1024))
+//	  (func (export "transform") (param $p i32) (param $n i32) (result i64)
+//	    (if (result i64) (i32.eqz (local.get $n))
+//	      (then (i64.const -1))
+//	      (else
+//	        (if (i32.eq (i32.load8_u (local.get $p)) (i32.const 33)) (then unreachable))
+//	        (if (i32.eq (i32.load8_u (local.get $p)) (i32.const 126)) (then (loop (br 0))))
+//	        (i64.or
+//	          (i64.shl (i64.extend_i32_u (i32.add (local.get $p) (i32.const 1))) (i64.const 32))
+//	          (i64.extend_i32_u (i32.sub (local.get $n) (i32.const 1))))))))
+var trimModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00, 0x01, 0x0c, 0x02, 0x60, 0x01, 0x7f, 0x01, 0x7f,
+	0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e, 0x03, 0x03, 0x02, 0x00, 0x01, 0x05, 0x03, 0x01, 0x00, 0x01,
+	0x07, 0x1e, 0x03, 0x06, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x02, 0x00, 0x05, 0x61, 0x6c, 0x6c,
+	0x6f, 0x63, 0x00, 0x00, 0x09, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x6f, 0x72, 0x6d, 0x00, 0x01,
+	0x0a, 0x40, 0x02, 0x05, 0x00, 0x41, 0x80, 0x08, 0x0b, 0x38, 0x00, 0x20, 0x01, 0x45, 0x04, 0x7e,
+	0x42, 0x7f, 0x05, 0x20, 0x00, 0x2d, 0x00, 0x00, 0x41, 0x21, 0x46, 0x04, 0x40, 0x00, 0x0b, 0x20,
+	0x00, 0x2d, 0x00, 0x00, 0x41, 0xfe, 0x00, 0x46, 0x04, 0x40, 0x03, 0x40, 0x0c, 0x00, 0x0b, 0x0b,
+	0x20, 0x00, 0x41, 0x01, 0x6a, 0xad, 0x42, 0x20, 0x86, 0x20, 0x01, 0x41, 0x01, 0x6b, 0xad, 0x84,
+	0x0b, 0x0b,
+}
+
+func TestWASM_Transform(t *testing.T) {
+	w, err := flow.NewWASM[string](flow.WASMConfig{Module: trimModule, Timeout: 50 * time.Millisecond}, nil)
+	assert.NoError(t, err)
+	defer w.Close()
+
+	in := make(chan string, 5)
+	for _, s := range []string{">hello", "", "!trap", "~loop", ">world"} {
+		in <- s
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, 4)
+	var got []string
+	for r := range w.Transform(in, eventC) {
+		data, _ := r.Data().Read()
+		raw, _ := r.Raw().Read()
+		got = append(got, string(data)+"/"+string(raw))
+	}
+	close(eventC)
+	assert.Equal(t, []string{"hello/>hello", "world/>world"}, got)
+
+	var failed []any
+	for e := range eventC {
+		failed = append(failed, e.(pipeline.ErrorEvent).Record())
+	}
+	assert.Equal(t, []any{"!trap", "~loop"}, failed)
+}
+
+func TestNewWASM_Validation(t *testing.T) {
+	_, err := flow.NewWASM[string](flow.WASMConfig{}, nil)
+	assert.Error(t, err)
+
+	_, err = flow.NewWASM[string](flow.WASMConfig{Module: []byte("not wasm")}, nil)
+	assert.Error(t, err)
+
+	// a module without exports
+	_, err = flow.NewWASM[string](flow.WASMConfig{Module: []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package flow
+
+import "time"
+
+// LatePolicy is the handling of items arriving behind the watermark of an event time flow.
+// Late items are counted with a metric event under every policy.
+type LatePolicy int
+
+const (
+	LateDrop       LatePolicy = iota // drops late items
+	LateSideOutput                   // passes late items to the late func of the flow instead
+	LateInclude                      // includes late items in the earliest open window, or emits them out of order
+)
+
+// Watermark tracks the progress of event time on a data stream with bounded out of order items.
+// The watermark trails the latest event time observed by the lateness, and items with an earlier
+// time are late. It is not safe for concurrent use.
+type Watermark struct {
+	lateness     int64
+	maxEventTime int64
+	watermark    int64
+}
+
+// NewWatermark creates a new Watermark allowing items to arrive up to lateness behind the latest event time.
+// Before any item is observed, the watermark is the minimum time and no item is late.
+func NewWatermark(lateness time.Duration) *Watermark {
+	return &Watermark{
+		lateness:     max(lateness.Nanoseconds(), 0),
+		maxEventTime: minInt64,
+		watermark:    minInt64,
+	}
+}
+
+// Observe records the event time of an item and reports whether the watermark advanced.
+func (w *Watermark) Observe(t time.Time) bool {
+	n := t.UnixNano()
+	if n <= w.maxEventTime {
+		return false
+	}
+	w.maxEventTime = n
+
+	if n-w.lateness <= w.watermark {
+		return false
+	}
+	w.watermark = n - w.lateness
+	return true
+}
+
+// Advance moves the watermark to t if it is behind, such as when the input goes idle.
+func (w *Watermark) Advance(t time.Time) {
+	w.advance(t.UnixNano())
+}
+
+// advance moves the watermark to the Unix time in nanoseconds if it is behind
+func (w *Watermark) advance(n int64) {
+	w.watermark = max(w.watermark, n)
+}
+
+// Current returns the watermark.
+func (w *Watermark) Current() time.Time {
+	return time.Unix(0, w.watermark).UTC()
+}
+
+// Late reports whether an item with the event time is behind the watermark.
+func (w *Watermark) Late(t time.Time) bool {
+	return t.UnixNano() < w.watermark
+}
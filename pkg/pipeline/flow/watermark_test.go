@@ -0,0 +1,30 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+func TestWatermark(t *testing.T) {
+	base := time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC)
+	w := flow.NewWatermark(10 * time.Second)
+	assert.False(t, w.Late(time.Unix(0, 0)), "nothing is late before an item is observed")
+
+	assert.True(t, w.Observe(base))
+	assert.Equal(t, base.Add(-10*time.Second), w.Current())
+	assert.False(t, w.Observe(base.Add(-time.Second)), "earlier items do not move the watermark")
+	assert.True(t, w.Observe(base.Add(5*time.Second)))
+	assert.Equal(t, base.Add(-5*time.Second), w.Current())
+
+	assert.True(t, w.Late(base.Add(-6*time.Second)))
+	assert.False(t, w.Late(base.Add(-5*time.Second)))
+
+	w.Advance(base.Add(time.Minute))
+	assert.Equal(t, base.Add(time.Minute), w.Current())
+	w.Advance(base)
+	assert.Equal(t, base.Add(time.Minute), w.Current(), "the watermark never moves back")
+	assert.False(t, w.Observe(base.Add(30*time.Second)), "items behind an advanced watermark do not move it")
+}
@@ -0,0 +1,243 @@
+package flow
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that TumblingWindow implements the Flow interface.
+var _ pipeline.Flow[any, WindowResult[string, any]] = (*TumblingWindow[any, string, any])(nil)
+
+// MetricWindowLateItems is the counter of items dropped because their window was already emitted.
+const MetricWindowLateItems = "krapht_window_late_items_total"
+
+// KeyFunc is a function that returns the key of an input value.
+type KeyFunc[I any, K comparable] func(in I) K
+
+// FoldFunc is a function that folds an input value into an accumulator.
+type FoldFunc[I, A any] func(acc A, in I) A
+
+// WindowResult is the aggregate of the items of a key within a window.
+type WindowResult[K comparable, A any] struct {
+	Key   K         // key of the items
+	Start time.Time // inclusive start of the window
+	End   time.Time // exclusive end of the window
+	Value A         // aggregate folded over the items
+	Count int       // number of items in the window
+}
+
+// WindowConfig is the configuration of a tumbling window flow.
+type WindowConfig[I any, K comparable, A any] struct {
+	Size time.Duration  // length of the windows, required
+	Key  KeyFunc[I, K]  // key of an item, required
+	Fold FoldFunc[I, A] // folds an item into the aggregate, starting from the zero value, required
+
+	// Time returns the event time of an item. When nil, items are windowed by arrival time.
+	Time func(in I) time.Time
+
+	// Lateness is how far behind the latest event time an item may arrive and still be counted.
+	// A window is emitted once the watermark, the latest event time minus Lateness, passes its end.
+	Lateness time.Duration
+
+	// IdleTimeout emits all open windows when no item arrives for this long, so event time windows
+	// are not held back indefinitely by a quiet input. Zero disables it.
+	IdleTimeout time.Duration
+
+	Late     LatePolicy // handling of items arriving for an emitted window, defaults to LateDrop
+	LateFunc func(in I) // receives the late items with LateSideOutput, required by it
+}
+
+// TumblingWindow is a struct that represents aggregation of a data stream in fixed, non-overlapping time windows.
+type TumblingWindow[I any, K comparable, A any] struct {
+	conf WindowConfig[I, K, A]
+}
+
+// NewTumblingWindow creates a new TumblingWindow flow with the given configuration.
+// Windows are aligned to multiples of the size since the Unix epoch.
+func NewTumblingWindow[I any, K comparable, A any](conf WindowConfig[I, K, A]) (*TumblingWindow[I, K, A], error) {
+	if conf.Size <= 0 {
+		return nil, errors.New("window size must be positive")
+	}
+
+	if conf.Key == nil {
+		return nil, errors.New("key func is nil")
+	}
+
+	if conf.Fold == nil {
+		return nil, errors.New("fold func is nil")
+	}
+
+	if conf.Lateness < 0 {
+		conf.Lateness = 0
+	}
+
+	if conf.Late == LateSideOutput && conf.LateFunc == nil {
+		return nil, errors.New("late func is nil")
+	}
+
+	return &TumblingWindow[I, K, A]{
+		conf: conf,
+	}, nil
+}
+
+// windowID identifies the window of a key
+type windowID[K comparable] struct {
+	key   K
+	start int64
+}
+
+// openWindow is a window that is still being aggregated
+type openWindow[K comparable, A any] struct {
+	result WindowResult[K, A]
+	seq    int
+}
+
+// Transform aggregates the data from the input channel and returns the output channel of window results.
+// Windows are emitted in order of their start time as the watermark passes their end, and all open
+// windows are emitted when the input channel is closed. Items arriving for an emitted window are counted
+// with a metric event and handled by the late policy; included items count towards the earliest open window
+// of their key, whose bounds then do not cover their time.
+func (w TumblingWindow[I, K, A]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan WindowResult[K, A] {
+	out := make(chan WindowResult[K, A])
+
+	go func() {
+		defer close(out)
+
+		size := w.conf.Size.Nanoseconds()
+		windows := make(map[windowID[K]]*openWindow[K, A])
+		var seq int
+		watermark := NewWatermark(w.conf.Lateness)
+		var late float64
+
+		timer := time.NewTimer(time.Hour)
+		timer.Stop()
+		defer timer.Stop()
+
+		// emit sends the windows ending at or before the watermark in order of their start
+		// and returns the latest end of the windows sent
+		emit := func(watermark int64) int64 {
+			var ready []*openWindow[K, A]
+			for id, open := range windows {
+				if open.result.End.UnixNano() <= watermark {
+					ready = append(ready, open)
+					delete(windows, id)
+				}
+			}
+			slices.SortFunc(ready, func(a, b *openWindow[K, A]) int {
+				if c := a.result.Start.Compare(b.result.Start); c != 0 {
+					return c
+				}
+				return a.seq - b.seq
+			})
+			last := int64(minInt64)
+			for _, open := range ready {
+				out <- open.result
+				last = max(last, open.result.End.UnixNano())
+			}
+			return last
+		}
+
+		// schedule sets the timer for the next processing time emit or the idle timeout
+		schedule := func() {
+			timer.Stop()
+			if w.conf.Time != nil {
+				if w.conf.IdleTimeout > 0 && len(windows) > 0 {
+					timer.Reset(w.conf.IdleTimeout)
+				}
+				return
+			}
+
+			next := int64(maxInt64)
+			for _, open := range windows {
+				next = min(next, open.result.End.UnixNano())
+			}
+			if next != maxInt64 {
+				timer.Reset(time.Until(time.Unix(0, next).Add(w.conf.Lateness)))
+			}
+		}
+
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					emit(maxInt64)
+					return
+				}
+
+				ts := time.Now()
+				if w.conf.Time != nil {
+					ts = w.conf.Time(v)
+				}
+				n := ts.UnixNano()
+				start := floorDiv(n, size) * size
+
+				if start+size <= watermark.watermark {
+					late++
+					pipeline.SendEvent(eventC, pipeline.NewMetricEvent(MetricWindowLateItems, late, nil, pipeline.MetricTypeCounter))
+					switch w.conf.Late {
+					case LateSideOutput:
+						w.conf.LateFunc(v)
+						continue
+					case LateInclude:
+						start = floorDiv(watermark.watermark, size) * size
+					default:
+						continue
+					}
+				}
+
+				id := windowID[K]{key: w.conf.Key(v), start: start}
+				open, exists := windows[id]
+				if !exists {
+					seq++
+					open = &openWindow[K, A]{
+						result: WindowResult[K, A]{
+							Key:   id.key,
+							Start: time.Unix(0, start).UTC(),
+							End:   time.Unix(0, start+size).UTC(),
+						},
+						seq: seq,
+					}
+					windows[id] = open
+				}
+				open.result.Value = w.conf.Fold(open.result.Value, v)
+				open.result.Count++
+
+				if w.conf.Time != nil && watermark.Observe(ts) {
+					emit(watermark.watermark)
+				}
+
+				// processing time emits only move when a window opens
+				if w.conf.Time != nil || !exists {
+					schedule()
+				}
+			case <-timer.C:
+				if w.conf.Time != nil {
+					// the input went idle, items arriving later for the emitted windows are late
+					watermark.advance(emit(maxInt64))
+					continue
+				}
+				watermark.Advance(time.Now().Add(-w.conf.Lateness))
+				emit(watermark.watermark)
+				schedule()
+			}
+		}
+	}()
+	return out
+}
+
+const (
+	minInt64 = -1 << 63
+	maxInt64 = 1<<63 - 1
+)
+
+// floorDiv divides rounding towards negative infinity
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,204 @@
+package flow_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+)
+
+// reading is an item with an event time used in the window tests
+type reading struct {
+	host  string
+	at    time.Time
+	value int
+}
+
+// sumConfig returns the configuration of an event time window summing the values per host
+func sumConfig(size time.Duration) flow.WindowConfig[reading, string, int] {
+	return flow.WindowConfig[reading, string, int]{
+		Size: size,
+		Key:  func(r reading) string { return r.host },
+		Fold: func(acc int, r reading) int { return acc + r.value },
+		Time: func(r reading) time.Time { return r.at },
+	}
+}
+
+func TestTumblingWindow_Transform(t *testing.T) {
+	base := time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC)
+
+	t.Run("emits per key aggregates as the watermark passes", func(t *testing.T) {
+		window, err := flow.NewTumblingWindow(sumConfig(time.Minute))
+		assert.NoError(t, err)
+
+		in := make(chan reading)
+		out := window.Transform(in, nil)
+
+		go func() {
+			in <- reading{host: "a", at: base.Add(10 * time.Second), value: 1}
+			in <- reading{host: "b", at: base.Add(20 * time.Second), value: 2}
+			in <- reading{host: "a", at: base.Add(30 * time.Second), value: 3}
+			in <- reading{host: "a", at: base.Add(70 * time.Second), value: 4}
+		}()
+
+		first, second := <-out, <-out
+		assert.Equal(t, flow.WindowResult[string, int]{Key: "a", Start: base, End: base.Add(time.Minute), Value: 4, Count: 2}, first)
+		assert.Equal(t, flow.WindowResult[string, int]{Key: "b", Start: base, End: base.Add(time.Minute), Value: 2, Count: 1}, second)
+
+		close(in)
+		last := <-out
+		assert.Equal(t, base.Add(time.Minute), last.Start)
+		assert.Equal(t, 4, last.Value)
+
+		_, ok := <-out
+		assert.False(t, ok)
+	})
+
+	t.Run("counts items within the allowed lateness and drops later ones", func(t *testing.T) {
+		conf := sumConfig(time.Minute)
+		conf.Lateness = 30 * time.Second
+		window, err := flow.NewTumblingWindow(conf)
+		assert.NoError(t, err)
+
+		in := make(chan reading, 5)
+		in <- reading{host: "a", at: base.Add(50 * time.Second), value: 1}
+		in <- reading{host: "a", at: base.Add(80 * time.Second), value: 2}
+		in <- reading{host: "a", at: base.Add(55 * time.Second), value: 4} // within lateness
+		in <- reading{host: "a", at: base.Add(95 * time.Second), value: 8}
+		in <- reading{host: "a", at: base.Add(40 * time.Second), value: 16} // late
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		var result []flow.WindowResult[string, int]
+		for r := range window.Transform(in, eventC) {
+			result = append(result, r)
+		}
+
+		assert.Len(t, result, 2)
+		assert.Equal(t, 5, result[0].Value)
+		assert.Equal(t, 10, result[1].Value)
+
+		metric, ok := (<-eventC).(pipeline.MetricEvent)
+		assert.True(t, ok)
+		assert.Equal(t, flow.MetricWindowLateItems, metric.Name())
+		assert.Equal(t, float64(1), metric.Value())
+	})
+
+	t.Run("emits open windows when the input goes idle", func(t *testing.T) {
+		conf := sumConfig(time.Hour)
+		conf.IdleTimeout = 20 * time.Millisecond
+		window, err := flow.NewTumblingWindow(conf)
+		assert.NoError(t, err)
+
+		in := make(chan reading)
+		out := window.Transform(in, nil)
+
+		in <- reading{host: "a", at: base, value: 1}
+		select {
+		case r := <-out:
+			assert.Equal(t, 1, r.Count)
+		case <-time.After(time.Second):
+			t.Fatal("window was not emitted after the idle timeout")
+		}
+
+		close(in)
+		_, ok := <-out
+		assert.False(t, ok)
+	})
+
+	t.Run("windows by arrival time without a time func", func(t *testing.T) {
+		window, err := flow.NewTumblingWindow(flow.WindowConfig[string, string, int]{
+			Size: 20 * time.Millisecond,
+			Key:  func(s string) string { return s },
+			Fold: func(acc int, _ string) int { return acc + 1 },
+		})
+		assert.NoError(t, err)
+
+		in := make(chan string)
+		out := window.Transform(in, nil)
+
+		in <- "a"
+		select {
+		case r := <-out:
+			assert.Equal(t, "a", r.Key)
+			assert.Equal(t, 1, r.Value)
+			assert.Equal(t, 20*time.Millisecond, r.End.Sub(r.Start))
+		case <-time.After(time.Second):
+			t.Fatal("window was not emitted after it ended")
+		}
+
+		close(in)
+		_, ok := <-out
+		assert.False(t, ok)
+	})
+}
+
+func TestTumblingWindow_LatePolicy(t *testing.T) {
+	base := time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC)
+
+	// run sends readings at 10s, 70s, 130s and a late one at 20s through a one minute window
+	run := func(conf flow.WindowConfig[reading, string, int]) []flow.WindowResult[string, int] {
+		window, err := flow.NewTumblingWindow(conf)
+		assert.NoError(t, err)
+
+		in := make(chan reading, 4)
+		in <- reading{host: "a", at: base.Add(10 * time.Second), value: 1}
+		in <- reading{host: "a", at: base.Add(70 * time.Second), value: 2}
+		in <- reading{host: "a", at: base.Add(130 * time.Second), value: 4}
+		in <- reading{host: "a", at: base.Add(20 * time.Second), value: 8}
+		close(in)
+
+		var result []flow.WindowResult[string, int]
+		for r := range window.Transform(in, nil) {
+			result = append(result, r)
+		}
+		return result
+	}
+
+	values := func(result []flow.WindowResult[string, int]) []int {
+		var v []int
+		for _, r := range result {
+			v = append(v, r.Value)
+		}
+		return v
+	}
+
+	assert.Equal(t, []int{1, 2, 4}, values(run(sumConfig(time.Minute))))
+
+	conf := sumConfig(time.Minute)
+	var side []reading
+	conf.Late = flow.LateSideOutput
+	conf.LateFunc = func(r reading) { side = append(side, r) }
+	assert.Equal(t, []int{1, 2, 4}, values(run(conf)))
+	assert.Len(t, side, 1)
+	assert.Equal(t, 8, side[0].value)
+
+	conf = sumConfig(time.Minute)
+	conf.Late = flow.LateInclude
+	result := run(conf)
+	assert.Equal(t, []int{1, 2, 12}, values(result), "the late item joins the open window at 120s")
+	assert.Equal(t, 2, result[2].Count)
+
+	conf = sumConfig(time.Minute)
+	conf.Late = flow.LateSideOutput
+	_, err := flow.NewTumblingWindow(conf)
+	assert.Error(t, err)
+}
+
+func TestNewTumblingWindow_Validation(t *testing.T) {
+	conf := sumConfig(0)
+	_, err := flow.NewTumblingWindow(conf)
+	assert.Error(t, err)
+
+	conf = sumConfig(time.Minute)
+	conf.Key = nil
+	_, err = flow.NewTumblingWindow(conf)
+	assert.Error(t, err)
+
+	conf = sumConfig(time.Minute)
+	conf.Fold = nil
+	_, err = flow.NewTumblingWindow(conf)
+	assert.Error(t, err)
+}
@@ -0,0 +1,144 @@
+package fuzz_test
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+	"github.com/witfoo/krapht/pkg/pipeline/fuzz"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+)
+
+func TestGenerators(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 1))
+	syslog, err := flow.NewSyslogParser[[]byte](flow.SyslogConfig{}, nil)
+	assert.NoError(t, err)
+	cef, err := flow.NewCEFParser[[]byte](nil)
+	assert.NoError(t, err)
+
+	for range 100 {
+		assert.True(t, json.Valid(fuzz.JSON(rng)))
+	}
+
+	var payloads [][]byte
+	for range 100 {
+		payloads = append(payloads, fuzz.Syslog(rng))
+	}
+	in := make(chan []byte, len(payloads))
+	for _, p := range payloads {
+		in <- p
+	}
+	close(in)
+	var n int
+	for range syslog.Transform(in, nil) {
+		n++
+	}
+	assert.Equal(t, len(payloads), n, "generated syslog messages parse")
+
+	in = make(chan []byte, 100)
+	for range 100 {
+		in <- fuzz.CEF(rng)
+	}
+	close(in)
+	n = 0
+	for range cef.Transform(in, nil) {
+		n++
+	}
+	assert.Equal(t, 100, n, "generated CEF messages parse")
+}
+
+func TestCorpus(t *testing.T) {
+	corpus := fuzz.Corpus(7, 3, fuzz.JSON, fuzz.Syslog)
+	assert.Len(t, corpus, 2*3*2+len(fuzz.Adversarial()))
+	assert.Equal(t, corpus, fuzz.Corpus(7, 3, fuzz.JSON, fuzz.Syslog), "same seed returns the same corpus")
+}
+
+func TestCheck(t *testing.T) {
+	passthrough := flow.NewPassthrough[[]byte]()
+	assert.NoError(t, fuzz.Check(passthrough, fuzz.Bytes, []byte("ok"), fuzz.Config{}))
+
+	slow := mock.NewFlow(mock.FlowConfig[[]byte, []byte]{Delay: mock.FixedDelay(100 * time.Millisecond)})
+	err := fuzz.Check(slow, fuzz.Bytes, []byte("slow"), fuzz.Config{Timeout: 10 * time.Millisecond})
+	assert.ErrorIs(t, err, fuzz.ErrFuzz)
+
+	greedy := mock.NewFlow(mock.FlowConfig[[]byte, []byte]{Transform: func(p []byte) ([]byte, error) {
+		return make([]byte, 1<<20), nil
+	}})
+	err = fuzz.Check(greedy, fuzz.Bytes, []byte("greedy"), fuzz.Config{MaxAlloc: 1 << 10, MaxFactor: 1})
+	assert.ErrorIs(t, err, fuzz.ErrFuzz)
+}
+
+func TestRunParsers(t *testing.T) {
+	corpus := fuzz.Corpus(1, 20, fuzz.Syslog, fuzz.JSON, fuzz.CEF)
+
+	syslog, err := flow.NewSyslogParser[[]byte](flow.SyslogConfig{}, nil)
+	assert.NoError(t, err)
+	fuzz.Run(t, syslog, fuzz.Bytes, corpus, fuzz.Config{})
+
+	cef, err := flow.NewCEFParser[[]byte](nil)
+	assert.NoError(t, err)
+	fuzz.Run(t, cef, fuzz.Bytes, corpus, fuzz.Config{})
+
+	fuzz.Run(t, flow.NewParseJSON[[]byte, any](nil), fuzz.Bytes, corpus, fuzz.Config{})
+
+	fastSyslog, err := flow.NewFastSyslogParser[[]byte](flow.SyslogConfig{}, nil)
+	assert.NoError(t, err)
+	fuzz.Run(t, fastSyslog, fuzz.Bytes, corpus, fuzz.Config{})
+
+	fastCEF, err := flow.NewFastCEFParser[[]byte](nil)
+	assert.NoError(t, err)
+	fuzz.Run(t, fastCEF, fuzz.Bytes, corpus, fuzz.Config{})
+
+	fastKV, err := flow.NewFastKVParser[[]byte](nil)
+	assert.NoError(t, err)
+	fuzz.Run(t, fastKV, fuzz.Bytes, corpus, fuzz.Config{})
+}
+
+func TestFastParsers(t *testing.T) {
+	// the fast parsers parse the messages the parsers do alike
+	var syslog flow.SyslogFields
+	var cef flow.CEFFields
+	for _, raw := range fuzz.Corpus(2, 200, fuzz.Syslog, fuzz.CEF) {
+		if want, err := flow.ParseSyslog(raw, nil); err == nil {
+			if assert.NoError(t, syslog.Parse(raw, nil), string(raw)) {
+				assert.Equal(t, want, syslog.Syslog(raw), string(raw))
+				assertJSON(t, want, syslog.AppendJSON(nil, raw))
+			}
+		}
+		if want, err := flow.ParseCEF(raw); err == nil {
+			if assert.NoError(t, cef.Parse(raw), string(raw)) {
+				assert.Equal(t, want, cef.CEF(raw), string(raw))
+				assertJSON(t, want, cef.AppendJSON(nil, raw))
+			}
+		}
+	}
+}
+
+// assertJSON asserts that the JSON is the encoding of the value
+func assertJSON(t *testing.T, v any, data []byte) {
+	t.Helper()
+	encoded, err := json.Marshal(v)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(encoded), string(data))
+}
+
+func FuzzSyslogParser(f *testing.F) {
+	syslog, err := flow.NewSyslogParser[[]byte](flow.SyslogConfig{}, nil)
+	assert.NoError(f, err)
+	fuzz.Fuzz(f, syslog, fuzz.Bytes, fuzz.Config{}, fuzz.Syslog)
+}
+
+func FuzzCEFParser(f *testing.F) {
+	cef, err := flow.NewCEFParser[[]byte](nil)
+	assert.NoError(f, err)
+	fuzz.Fuzz(f, cef, fuzz.Bytes, fuzz.Config{}, fuzz.CEF)
+}
+
+func FuzzParseJSON(f *testing.F) {
+	fuzz.Fuzz(f, flow.NewParseJSON[pipeline.Readable, any](nil), fuzz.Readable, fuzz.Config{}, fuzz.JSON)
+}
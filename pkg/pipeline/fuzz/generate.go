@@ -0,0 +1,249 @@
+// Package fuzz provides generators of random and adversarial log payloads, and harnesses running any
+// flow against them, to catch parsers that panic, hang or allocate without bound before they are
+// released.
+package fuzz
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Generator generates a random payload from the random source.
+type Generator func(rng *rand.Rand) []byte
+
+// fuzzWords are the words of the generated messages and values
+var fuzzWords = []string{
+	"connection", "accepted", "denied", "user", "session", "opened", "closed", "request", "timeout",
+	"disk", "usage", "high", "service", "restarted", "packet", "dropped", "login", "failed", "from",
+	"héllo", "日本語", "emoji😀", `quote"d`, `back\slash`, "pipe|d", "equal=s", "new\nline", "tab\tbed",
+}
+
+// fuzzMonths are the months of RFC 3164 timestamps
+var fuzzMonths = []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+// words appends n random words separated by spaces
+func words(b []byte, rng *rand.Rand, n int) []byte {
+	for i := range n {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = append(b, fuzzWords[rng.IntN(len(fuzzWords))]...)
+	}
+	return b
+}
+
+// token returns a random word without spaces or separators
+func token(rng *rand.Rand) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(" \t\n|=\\\"[]", r) {
+			return '-'
+		}
+		return r
+	}, fuzzWords[rng.IntN(len(fuzzWords))])
+}
+
+// Syslog generates RFC 3164 and RFC 5424 syslog messages.
+func Syslog(rng *rand.Rand) []byte {
+	b := []byte{'<'}
+	b = strconv.AppendInt(b, int64(rng.IntN(192)), 10)
+	b = append(b, '>')
+
+	ts := time.Date(2025, time.Month(1+rng.IntN(12)), 1+rng.IntN(28), rng.IntN(24), rng.IntN(60), rng.IntN(60), rng.IntN(1e9), time.UTC)
+	host, app := token(rng), token(rng)
+	if rng.IntN(2) == 0 {
+		b = append(b, fuzzMonths[ts.Month()-1]...)
+		b = append(b, ts.Format(" _2 15:04:05 ")...)
+		b = append(b, host...)
+		b = append(b, ' ')
+		b = append(b, app...)
+		b = append(b, '[')
+		b = strconv.AppendInt(b, int64(rng.IntN(65536)), 10)
+		b = append(b, "]: "...)
+		return words(b, rng, 1+rng.IntN(12))
+	}
+
+	b = append(b, "1 "...)
+	b = ts.AppendFormat(b, time.RFC3339Nano)
+	b = append(b, ' ')
+	b = append(b, host...)
+	b = append(b, ' ')
+	b = append(b, app...)
+	b = append(b, ' ')
+	b = strconv.AppendInt(b, int64(rng.IntN(65536)), 10)
+	b = append(b, " ID"...)
+	b = strconv.AppendInt(b, int64(rng.IntN(100)), 10)
+	b = append(b, ' ')
+	if rng.IntN(3) == 0 {
+		b = append(b, '-')
+	} else {
+		b = append(b, "[meta@32473 "...)
+		b = append(b, token(rng)...)
+		b = append(b, `="`...)
+		b = append(b, strings.NewReplacer(`"`, `\"`, `\`, `\\`, "]", `\]`).Replace(fuzzWords[rng.IntN(len(fuzzWords))])...)
+		b = append(b, `"]`...)
+	}
+	b = append(b, ' ')
+	return words(b, rng, 1+rng.IntN(12))
+}
+
+// JSON generates JSON objects of nested values of every JSON type.
+func JSON(rng *rand.Rand) []byte {
+	return jsonValue(nil, rng, 0, true)
+}
+
+// jsonValue appends a random JSON value, an object when object is set
+func jsonValue(b []byte, rng *rand.Rand, depth int, object bool) []byte {
+	kind := 6
+	if !object {
+		kind = rng.IntN(7)
+		if depth >= 3 && kind >= 5 {
+			kind = rng.IntN(5)
+		}
+	}
+
+	switch kind {
+	case 0:
+		return append(b, "null"...)
+	case 1:
+		return strconv.AppendBool(b, rng.IntN(2) == 0)
+	case 2:
+		return strconv.AppendInt(b, rng.Int64()-rng.Int64(), 10)
+	case 3:
+		return strconv.AppendFloat(b, rng.NormFloat64()*1e6, 'g', -1, 64)
+	case 4:
+		return strconv.AppendQuote(b, string(words(nil, rng, rng.IntN(4))))
+	case 5:
+		b = append(b, '[')
+		for i := range rng.IntN(4) {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = jsonValue(b, rng, depth+1, false)
+		}
+		return append(b, ']')
+	default:
+		b = append(b, '{')
+		for i := range 1 + rng.IntN(5) {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = strconv.AppendQuote(b, token(rng)+strconv.Itoa(i))
+			b = append(b, ':')
+			b = jsonValue(b, rng, depth+1, false)
+		}
+		return append(b, '}')
+	}
+}
+
+// CEF generates ArcSight Common Event Format messages, with escaped header fields and extensions.
+func CEF(rng *rand.Rand) []byte {
+	header := strings.NewReplacer(`\`, `\\`, "|", `\|`, "\n", " ")
+	ext := strings.NewReplacer(`\`, `\\`, "=", `\=`, "\n", `\n`)
+
+	b := []byte("CEF:0")
+	for _, field := range []string{token(rng), token(rng), "1." + strconv.Itoa(rng.IntN(10)), strconv.Itoa(rng.IntN(1000))} {
+		b = append(b, '|')
+		b = append(b, header.Replace(field)...)
+	}
+	b = append(b, '|')
+	b = append(b, header.Replace(string(words(nil, rng, 1+rng.IntN(4))))...)
+	b = append(b, '|')
+	b = strconv.AppendInt(b, int64(rng.IntN(11)), 10)
+	b = append(b, '|')
+
+	for i := range rng.IntN(6) {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = append(b, []string{"src", "dst", "spt", "dpt", "suser", "msg", "act", "cs1", "cs1Label"}[rng.IntN(9)]...)
+		b = append(b, '=')
+		b = append(b, ext.Replace(string(words(nil, rng, 1+rng.IntN(3))))...)
+	}
+	return b
+}
+
+// Mutate returns a copy of the payload with random bytes flipped, inserted, removed or the payload
+// truncated, to derive malformed payloads from valid ones.
+func Mutate(rng *rand.Rand, p []byte) []byte {
+	b := bytes.Clone(p)
+	for range 1 + rng.IntN(4) {
+		if len(b) == 0 {
+			return append(b, byte(rng.IntN(256)))
+		}
+		i := rng.IntN(len(b))
+		switch rng.IntN(4) {
+		case 0:
+			b[i] ^= byte(1 << rng.IntN(8))
+		case 1:
+			b = append(b[:i], append([]byte{byte(rng.IntN(256))}, b[i:]...)...)
+		case 2:
+			b = append(b[:i], b[i+1:]...)
+		default:
+			b = b[:i]
+		}
+	}
+	return b
+}
+
+// Adversarial returns payloads of the edge cases that break parsers: empty and whitespace payloads, huge
+// lines, invalid UTF-8, NUL bytes, deep nesting, overflowing numbers and unterminated or excessive
+// separators and escapes.
+func Adversarial() [][]byte {
+	huge := bytes.Repeat([]byte("a"), 1<<20)
+	return [][]byte{
+		{},
+		[]byte(" "),
+		[]byte("\r\n"),
+		[]byte("\x00"),
+		[]byte("a\x00b\x00c"),
+		[]byte("\xff\xfe\xfd"),
+		[]byte("\xc3\x28\xa0\xa1\xe2\x28\xa1\xf0\x28\x8c\xbc"),
+		huge,
+		append([]byte("<13>Jan  1 00:00:00 host app: "), huge...),
+		append([]byte(`{"message":"`), append(huge, `"}`...)...),
+		append([]byte("CEF:0|v|p|1|1|n|1|msg="), huge...),
+		[]byte("<"),
+		[]byte("<>"),
+		[]byte("<-1>"),
+		[]byte("<99999999999999999999999>1 - - - - -"),
+		[]byte("<13>1 9999-99-99T99:99:99Z host app - - -"),
+		[]byte("<13>1 2025-01-01T00:00:00Z host app - - [unterminated"),
+		[]byte(`<13>1 2025-01-01T00:00:00Z host app - - [a b="\`),
+		[]byte("<13>Jan 1 00:00:00 host app: \xc3\x28"),
+		[]byte("{"),
+		[]byte(`{"a":`),
+		[]byte(`{"a":"\u12"}`),
+		[]byte(`{"a":"\ud800"}`),
+		[]byte("1e999999"),
+		[]byte("-"),
+		[]byte(`{"a":1,"a":2}`),
+		bytes.Repeat([]byte("["), 100000),
+		bytes.Repeat([]byte(`{"a":`), 10000),
+		[]byte("CEF:"),
+		[]byte("CEF:0|"),
+		[]byte("CEF:0|a|b|c|d|e|f|"),
+		[]byte(`CEF:0|a|b|c|d|e|f|k=v\`),
+		[]byte("CEF:0|a|b|c|d|e|f|=v ==== k="),
+		append([]byte("CEF:0"), bytes.Repeat([]byte("|"), 10000)...),
+		append([]byte("CEF:0|a|b|c|d|e|f|"), bytes.Repeat([]byte("a=1 "), 10000)...),
+		bytes.Repeat([]byte(`\`), 10000),
+		bytes.Repeat([]byte("\n"), 10000),
+	}
+}
+
+// Corpus returns n payloads of each generator, mutations of them and the adversarial payloads,
+// the same seed returning the same corpus.
+func Corpus(seed uint64, n int, gens ...Generator) [][]byte {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	var corpus [][]byte
+	for _, gen := range gens {
+		for range n {
+			p := gen(rng)
+			corpus = append(corpus, p, Mutate(rng, p))
+		}
+	}
+	return append(corpus, Adversarial()...)
+}
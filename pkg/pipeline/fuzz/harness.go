@@ -0,0 +1,130 @@
+package fuzz
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// ErrFuzz is the error of payloads a flow failed on
+var ErrFuzz = errors.New("fuzz error")
+
+// Config is the configuration of the harnesses.
+type Config struct {
+	Timeout   time.Duration // time a flow has to process a payload and close its output, defaults to 5s
+	MaxAlloc  uint64        // bytes a flow may allocate per payload besides MaxFactor per payload byte, defaults to 64MiB
+	MaxFactor uint64        // bytes a flow may allocate per payload byte, defaults to 64
+	Seed      uint64        // seed of the corpus of the Fuzz harness
+	N         int           // payloads of each generator in the corpus of the Fuzz harness, defaults to 20
+}
+
+// withDefaults returns the configuration with the defaults applied
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+	if c.MaxAlloc == 0 {
+		c.MaxAlloc = 64 << 20
+	}
+	if c.MaxFactor == 0 {
+		c.MaxFactor = 64
+	}
+	if c.N <= 0 {
+		c.N = 20
+	}
+	return c
+}
+
+// Check passes the payload, converted to the input of the flow, through the flow alone, and returns an
+// error if the flow does not close its output within the timeout or allocates more than allowed. The
+// output items and events are discarded. A panic of the flow fails the test binary with its stack, as it
+// happens in the goroutine of the flow; the Go fuzzer records the payload that caused it.
+func Check[I, O any](f pipeline.Flow[I, O], conv func([]byte) I, p []byte, conf Config) error {
+	conf = conf.withDefaults()
+
+	in := make(chan I, 1)
+	in <- conv(p)
+	close(in)
+
+	eventC := make(chan pipeline.Event, 16)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-eventC:
+			case <-done:
+				return
+			}
+		}
+	}()
+	defer close(done)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	out := f.Transform(in, eventC)
+	timer := time.NewTimer(conf.Timeout)
+	defer timer.Stop()
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-out:
+			closed = !ok
+		case <-timer.C:
+			return fmt.Errorf("%w: output not closed within %s", ErrFuzz, conf.Timeout)
+		}
+	}
+
+	runtime.ReadMemStats(&after)
+	limit := conf.MaxAlloc + conf.MaxFactor*uint64(len(p))
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > limit {
+		return fmt.Errorf("%w: allocated %d bytes for a %d byte payload, over the limit of %d", ErrFuzz, alloc, len(p), limit)
+	}
+	return nil
+}
+
+// Run checks the flow against each payload, failing the test for the payloads it fails on.
+func Run[I, O any](t testing.TB, f pipeline.Flow[I, O], conv func([]byte) I, payloads [][]byte, conf Config) {
+	t.Helper()
+	for _, p := range payloads {
+		if err := Check(f, conv, p, conf); err != nil {
+			t.Errorf("payload %s: %v", quote(p), err)
+		}
+	}
+}
+
+// Fuzz adds the corpus of the generators to the Go fuzzer, and fuzzes the flow with it.
+func Fuzz[I, O any](f *testing.F, flow pipeline.Flow[I, O], conv func([]byte) I, conf Config, gens ...Generator) {
+	f.Helper()
+	conf = conf.withDefaults()
+	for _, p := range Corpus(conf.Seed, conf.N, gens...) {
+		f.Add(p)
+	}
+
+	f.Fuzz(func(t *testing.T, p []byte) {
+		if err := Check(flow, conv, p, conf); err != nil {
+			t.Errorf("payload %s: %v", quote(p), err)
+		}
+	})
+}
+
+// Bytes converts a payload to the input of flows of []byte.
+func Bytes(p []byte) []byte {
+	return p
+}
+
+// Readable converts a payload to the input of flows of Readable.
+func Readable(p []byte) pipeline.Readable {
+	return pipeline.Bytes(p)
+}
+
+// quote returns the payload quoted, truncated to 64 bytes
+func quote(p []byte) string {
+	if len(p) > 64 {
+		return fmt.Sprintf("%q... (%d bytes)", p[:64], len(p))
+	}
+	return fmt.Sprintf("%q", p)
+}
@@ -0,0 +1,200 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HealthState is the state of a component, ordered from healthy to unhealthy.
+type HealthState int
+
+const (
+	// HealthHealthy is a component working as expected
+	HealthHealthy HealthState = iota
+	// HealthDegraded is a component working with reduced capacity, such as while reconnecting
+	HealthDegraded
+	// HealthUnhealthy is a component that is not working
+	HealthUnhealthy
+)
+
+// String returns the name of the state
+func (s HealthState) String() string {
+	switch s {
+	case HealthHealthy:
+		return "healthy"
+	case HealthDegraded:
+		return "degraded"
+	default:
+		return "unhealthy"
+	}
+}
+
+// MarshalJSON encodes the state as its name
+func (s HealthState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// HealthStatus is the health of a component.
+type HealthStatus struct {
+	State       HealthState `json:"state"`
+	Message     string      `json:"message,omitempty"`
+	LastSuccess time.Time   `json:"last_success,omitzero"` // time of the last successful operation, if tracked
+}
+
+// Health is implemented by sources, flows and sinks that can report their health, such as whether their
+// connection is up, their listener is bound or when they last published successfully.
+type Health interface {
+	Health(ctx context.Context) HealthStatus
+}
+
+// HealthReport is the health of a set of components, in the state of the least healthy of them.
+type HealthReport struct {
+	State      HealthState             `json:"state"`
+	Components map[string]HealthStatus `json:"components"`
+}
+
+// HealthOption represents a functional option for configuring HealthAggregator.
+type HealthOption func(*HealthAggregator)
+
+// WithCheckTimeout configures the time a component has to report its health before it is unhealthy.
+func WithCheckTimeout(timeout time.Duration) HealthOption {
+	return func(a *HealthAggregator) {
+		if timeout > 0 {
+			a.timeout = timeout
+		}
+	}
+}
+
+// WithStaleAfter configures the time since the last success of a component after which it is degraded.
+func WithStaleAfter(d time.Duration) HealthOption {
+	return func(a *HealthAggregator) {
+		if d > 0 {
+			a.staleAfter = d
+		}
+	}
+}
+
+// WithDegradedReady configures whether a degraded report is ready, as it is by default.
+func WithDegradedReady(ready bool) HealthOption {
+	return func(a *HealthAggregator) {
+		a.degradedReady = ready
+	}
+}
+
+// HealthAggregator checks the health of components concurrently and reports the overall health, for
+// readiness endpoints and supervisors.
+type HealthAggregator struct {
+	timeout       time.Duration
+	staleAfter    time.Duration
+	degradedReady bool
+
+	mu         sync.Mutex
+	components map[string]Health
+}
+
+// NewHealthAggregator creates a new health aggregator with default settings.
+// It accepts optional HealthOption functions to configure the aggregator.
+func NewHealthAggregator(opts ...HealthOption) *HealthAggregator {
+	a := &HealthAggregator{
+		timeout:       5 * time.Second, // Default check timeout
+		degradedReady: true,
+		components:    make(map[string]Health),
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a
+}
+
+// Add adds a component to the report under the name, replacing the component of the same name.
+func (a *HealthAggregator) Add(name string, h Health) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.components[name] = h
+}
+
+// Remove removes the component of the name from the report.
+func (a *HealthAggregator) Remove(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.components, name)
+}
+
+// Check checks the health of the components. A component that does not report in time is unhealthy, and
+// one whose last success is older than the stale time is degraded at least.
+func (a *HealthAggregator) Check(ctx context.Context) HealthReport {
+	a.mu.Lock()
+	components := make(map[string]Health, len(a.components))
+	for name, h := range a.components {
+		components[name] = h
+	}
+	a.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	report := HealthReport{Components: make(map[string]HealthStatus, len(components))}
+	for name, h := range components {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := a.check(ctx, h)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Components[name] = status
+			report.State = max(report.State, status.State)
+		}()
+	}
+	wg.Wait()
+	return report
+}
+
+// check checks the health of a component before the context is done
+func (a *HealthAggregator) check(ctx context.Context, h Health) HealthStatus {
+	result := make(chan HealthStatus, 1)
+	go func() {
+		result <- h.Health(ctx)
+	}()
+
+	var status HealthStatus
+	select {
+	case status = <-result:
+	case <-ctx.Done():
+		return HealthStatus{State: HealthUnhealthy, Message: "health check timed out"}
+	}
+
+	if a.staleAfter > 0 && !status.LastSuccess.IsZero() && time.Since(status.LastSuccess) > a.staleAfter {
+		status.State = max(status.State, HealthDegraded)
+		if status.Message == "" {
+			status.Message = "no success since " + status.LastSuccess.Format(time.RFC3339)
+		}
+	}
+	return status
+}
+
+// Ready reports whether the report is ready: healthy, or degraded when degraded reports are ready.
+func (a *HealthAggregator) Ready(r HealthReport) bool {
+	return r.State == HealthHealthy || r.State == HealthDegraded && a.degradedReady
+}
+
+// Handler returns a handler for readiness endpoints such as /readyz, responding with the JSON report and
+// status 200 when it is ready or 503 otherwise.
+func (a *HealthAggregator) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := a.Check(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		if !a.Ready(report) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,137 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+// healthFunc is a component reporting the health returned by a function
+type healthFunc func(ctx context.Context) pipeline.HealthStatus
+
+func (f healthFunc) Health(ctx context.Context) pipeline.HealthStatus { return f(ctx) }
+
+func staticHealth(state pipeline.HealthState) healthFunc {
+	return func(context.Context) pipeline.HealthStatus { return pipeline.HealthStatus{State: state} }
+}
+
+func TestHealthAggregator(t *testing.T) {
+	tests := []struct {
+		name       string
+		components map[string]pipeline.Health
+		opts       []pipeline.HealthOption
+		state      pipeline.HealthState
+		code       int
+	}{
+		{
+			name:  "no components",
+			state: pipeline.HealthHealthy,
+			code:  http.StatusOK,
+		},
+		{
+			name: "least healthy component",
+			components: map[string]pipeline.Health{
+				"source": staticHealth(pipeline.HealthHealthy),
+				"sink":   staticHealth(pipeline.HealthDegraded),
+			},
+			state: pipeline.HealthDegraded,
+			code:  http.StatusOK,
+		},
+		{
+			name: "degraded not ready",
+			components: map[string]pipeline.Health{
+				"sink": staticHealth(pipeline.HealthDegraded),
+			},
+			opts:  []pipeline.HealthOption{pipeline.WithDegradedReady(false)},
+			state: pipeline.HealthDegraded,
+			code:  http.StatusServiceUnavailable,
+		},
+		{
+			name: "unhealthy",
+			components: map[string]pipeline.Health{
+				"source": staticHealth(pipeline.HealthUnhealthy),
+				"sink":   staticHealth(pipeline.HealthHealthy),
+			},
+			state: pipeline.HealthUnhealthy,
+			code:  http.StatusServiceUnavailable,
+		},
+		{
+			name: "timed out",
+			components: map[string]pipeline.Health{
+				"slow": healthFunc(func(ctx context.Context) pipeline.HealthStatus {
+					<-ctx.Done()
+					time.Sleep(10 * time.Millisecond)
+					return pipeline.HealthStatus{}
+				}),
+			},
+			opts:  []pipeline.HealthOption{pipeline.WithCheckTimeout(10 * time.Millisecond)},
+			state: pipeline.HealthUnhealthy,
+			code:  http.StatusServiceUnavailable,
+		},
+		{
+			name: "stale",
+			components: map[string]pipeline.Health{
+				"sink": healthFunc(func(context.Context) pipeline.HealthStatus {
+					return pipeline.HealthStatus{LastSuccess: time.Now().Add(-time.Hour)}
+				}),
+			},
+			opts:  []pipeline.HealthOption{pipeline.WithStaleAfter(time.Minute)},
+			state: pipeline.HealthDegraded,
+			code:  http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := pipeline.NewHealthAggregator(tt.opts...)
+			for name, h := range tt.components {
+				a.Add(name, h)
+			}
+
+			report := a.Check(context.Background())
+			assert.Equal(t, tt.state, report.State)
+			assert.Len(t, report.Components, len(tt.components))
+
+			rec := httptest.NewRecorder()
+			a.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			assert.Equal(t, tt.code, rec.Code)
+
+			var body map[string]any
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+			assert.Equal(t, tt.state.String(), body["state"])
+		})
+	}
+}
+
+// healthySink is a sink reporting a fixed health
+type healthySink struct {
+	sink.Noop
+	status pipeline.HealthStatus
+}
+
+func (s healthySink) Health(context.Context) pipeline.HealthStatus { return s.status }
+
+func TestRunnerHealth(t *testing.T) {
+	r := pipeline.NewRunner("test", newRunnerSource())
+	assert.Equal(t, pipeline.HealthHealthy, r.Health(context.Background()).State)
+
+	last := time.Now().Add(-time.Minute)
+	assert.NoError(t, pipeline.SetSink[any](r, "store", healthySink{status: pipeline.HealthStatus{
+		State: pipeline.HealthDegraded, Message: "reconnecting", LastSuccess: last,
+	}}))
+
+	a := pipeline.NewHealthAggregator()
+	a.Add(r.Name(), r)
+	report := a.Check(context.Background())
+	assert.Equal(t, pipeline.HealthDegraded, report.State)
+	assert.Equal(t, "store degraded: reconnecting", report.Components["test"].Message)
+	assert.Equal(t, last, report.Components["test"].LastSuccess)
+}
@@ -0,0 +1,287 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrManager is returned when a pipeline of a Manager cannot be added, started or stopped.
+var ErrManager = errors.New("manager error")
+
+// PipelineState is the state of a pipeline of a Manager.
+type PipelineState int
+
+const (
+	// PipelineStopped is a pipeline that is not running
+	PipelineStopped PipelineState = iota
+	// PipelineRunning is a running pipeline
+	PipelineRunning
+	// PipelineStopping is a pipeline whose run is cancelled and draining
+	PipelineStopping
+)
+
+// String returns the name of the state
+func (s PipelineState) String() string {
+	switch s {
+	case PipelineRunning:
+		return "running"
+	case PipelineStopping:
+		return "stopping"
+	default:
+		return "stopped"
+	}
+}
+
+// MarshalJSON encodes the state as its name
+func (s PipelineState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// PipelineStatus is the status of a pipeline of a Manager.
+type PipelineStatus struct {
+	Name      string        `json:"name"`
+	State     PipelineState `json:"state"`
+	Runs      int           `json:"runs"`                 // times the pipeline was started
+	Started   time.Time     `json:"started,omitzero"`     // start of the current or last run
+	Stopped   time.Time     `json:"stopped,omitzero"`     // end of the last run
+	Events    uint64        `json:"events"`               // events of the runs
+	Errors    uint64        `json:"errors"`               // error events of the runs
+	LastError string        `json:"last_error,omitempty"` // last error event of the runs
+	Health    *HealthStatus `json:"health,omitempty"`     // health of the pipeline when it reports its health
+}
+
+// Manager runs many pipelines concurrently, each with its own context and event collector, and starts,
+// stops and reports them by name.
+type Manager struct {
+	mu        sync.Mutex
+	pipelines map[string]*managedPipeline
+}
+
+// managedPipeline is a pipeline of a Manager with its status
+type managedPipeline struct {
+	r         Runnable
+	collector *EventCollector
+	status    PipelineStatus
+	cancel    context.CancelFunc
+	done      chan struct{} // closed when the current run has ended
+}
+
+// NewManager creates a new Manager without pipelines.
+func NewManager() *Manager {
+	return &Manager{
+		pipelines: make(map[string]*managedPipeline),
+	}
+}
+
+// Add adds the pipeline r under the name, stopped, with an event collector configured by the options
+// processing the events of its runs.
+func (m *Manager) Add(name string, r Runnable, opts ...EventCollectorOption) error {
+	if name == "" {
+		return fmt.Errorf("%w: pipeline name is empty", ErrManager)
+	}
+	if r == nil {
+		return fmt.Errorf("%w: pipeline %s is nil", ErrManager, name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.pipelines[name]; ok {
+		return fmt.Errorf("%w: pipeline %s already added", ErrManager, name)
+	}
+	m.pipelines[name] = &managedPipeline{
+		r:         r,
+		collector: NewEventCollector(opts...),
+		status:    PipelineStatus{Name: name},
+	}
+	return nil
+}
+
+// Remove stops the pipeline under the name as Stop does and removes it.
+func (m *Manager) Remove(ctx context.Context, name string) error {
+	if err := m.Stop(ctx, name); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.pipelines[name]; ok && p.status.State == PipelineStopped {
+		delete(m.pipelines, name)
+	}
+	return nil
+}
+
+// Names returns the sorted names of the pipelines.
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.pipelines))
+	for name := range m.pipelines {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Collector returns the event collector of the pipeline under the name, or nil when there is none.
+func (m *Manager) Collector(name string) *EventCollector {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p, ok := m.pipelines[name]; ok {
+		return p.collector
+	}
+	return nil
+}
+
+// Start runs the pipeline under the name until it ends, it is stopped or the context is done.
+func (m *Manager) Start(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.pipelines[name]
+	if !ok {
+		return fmt.Errorf("%w: no pipeline %s", ErrManager, name)
+	}
+	if p.status.State != PipelineStopped {
+		return fmt.Errorf("%w: pipeline %s is %s", ErrManager, name, p.status.State)
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+	p.status.State = PipelineRunning
+	p.status.Runs++
+	p.status.Started = time.Now()
+
+	events := p.r.Run(ctx)
+	eventC := p.collector.Collect()
+	go m.collect(p, events, eventC)
+	return nil
+}
+
+// collect sends the events of a run of a pipeline to its collector, updating its status, until the
+// run ends
+func (m *Manager) collect(p *managedPipeline, events <-chan Event, eventC chan<- Event) {
+	defer close(p.done)
+
+	for e := range events {
+		m.mu.Lock()
+		p.status.Events++
+		if e.Type() == EventError {
+			p.status.Errors++
+			p.status.LastError = e.String()
+		}
+		m.mu.Unlock()
+		eventC <- e
+	}
+	p.collector.Close()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p.cancel()
+	p.status.State = PipelineStopped
+	p.status.Stopped = time.Now()
+}
+
+// Stop cancels the run of the pipeline under the name and waits for it to drain and end, or for the
+// context to be done. Stopping a pipeline that is not running does nothing.
+func (m *Manager) Stop(ctx context.Context, name string) error {
+	m.mu.Lock()
+	p, ok := m.pipelines[name]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("%w: no pipeline %s", ErrManager, name)
+	}
+	if p.status.State == PipelineStopped {
+		m.mu.Unlock()
+		return nil
+	}
+	p.status.State = PipelineStopping
+	p.cancel()
+	done := p.done
+	m.mu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: pipeline %s did not stop: %w", ErrManager, name, ctx.Err())
+	}
+}
+
+// StartAll starts the pipelines that are not running, returning the errors of those that could not be
+// started.
+func (m *Manager) StartAll(ctx context.Context) error {
+	var errs []error
+	for _, name := range m.Names() {
+		if m.state(name) != PipelineStopped {
+			continue
+		}
+		if err := m.Start(ctx, name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// StopAll stops the running pipelines concurrently and waits for them as Stop does, returning the
+// errors of those that did not stop.
+func (m *Manager) StopAll(ctx context.Context) error {
+	names := m.Names()
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = m.Stop(ctx, name)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// state returns the state of the pipeline under the name
+func (m *Manager) state(name string) PipelineState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p, ok := m.pipelines[name]; ok {
+		return p.status.State
+	}
+	return PipelineStopped
+}
+
+// Status returns the status of the pipeline under the name, with its health when it reports its health.
+func (m *Manager) Status(ctx context.Context, name string) (PipelineStatus, error) {
+	m.mu.Lock()
+	p, ok := m.pipelines[name]
+	if !ok {
+		m.mu.Unlock()
+		return PipelineStatus{}, fmt.Errorf("%w: no pipeline %s", ErrManager, name)
+	}
+	status := p.status
+	m.mu.Unlock()
+
+	if h, ok := p.r.(Health); ok {
+		health := h.Health(ctx)
+		status.Health = &health
+	}
+	return status, nil
+}
+
+// Statuses returns the statuses of the pipelines, sorted by name.
+func (m *Manager) Statuses(ctx context.Context) []PipelineStatus {
+	var statuses []PipelineStatus
+	for _, name := range m.Names() {
+		if status, err := m.Status(ctx, name); err == nil {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,114 @@
+package pipeline_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// holdRunnable is a pipeline sending an error event and a log event, then running until its context is done
+func holdRunnable() *countedRunnable {
+	return &countedRunnable{f: func(ctx context.Context, _ int, eventC chan<- pipeline.Event) {
+		eventC <- pipeline.NewErrorEvent("parse error", errOutage, true)
+		eventC <- pipeline.NewLogEvent("source", pipeline.LevelInfo, "listening")
+		<-ctx.Done()
+	}}
+}
+
+func TestManager(t *testing.T) {
+	m := pipeline.NewManager()
+
+	var collected atomic.Int32
+	require.NoError(t, m.Add("syslog", holdRunnable(), pipeline.WithCallback(func(pipeline.Event) { collected.Add(1) })))
+	require.NoError(t, m.Add("audit", holdRunnable()))
+	assert.Equal(t, []string{"audit", "syslog"}, m.Names())
+	assert.NotNil(t, m.Collector("syslog"))
+
+	ctx := context.Background()
+	require.NoError(t, m.StartAll(ctx))
+	assert.ErrorIs(t, m.Start(ctx, "syslog"), pipeline.ErrManager, "already running")
+
+	assert.Eventually(t, func() bool {
+		status, err := m.Status(ctx, "syslog")
+		return err == nil && status.Events == 2
+	}, time.Second, time.Millisecond)
+	status, err := m.Status(ctx, "syslog")
+	require.NoError(t, err)
+	assert.Equal(t, pipeline.PipelineRunning, status.State)
+	assert.Equal(t, 1, status.Runs)
+	assert.Equal(t, uint64(1), status.Errors)
+	assert.Contains(t, status.LastError, "connection lost")
+	assert.Nil(t, status.Health)
+
+	require.NoError(t, m.Stop(ctx, "syslog"))
+	assert.Equal(t, int32(2), collected.Load(), "the collector processed the events of the run")
+
+	statuses := m.Statuses(ctx)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, pipeline.PipelineRunning, statuses[0].State, "stopping one pipeline leaves the others running")
+	assert.Equal(t, pipeline.PipelineStopped, statuses[1].State)
+	assert.False(t, statuses[1].Stopped.IsZero())
+
+	require.NoError(t, m.Start(ctx, "syslog"))
+	status, err = m.Status(ctx, "syslog")
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.Runs)
+
+	require.NoError(t, m.StopAll(ctx))
+	for _, status := range m.Statuses(ctx) {
+		assert.Equal(t, pipeline.PipelineStopped, status.State)
+	}
+	require.NoError(t, m.Remove(ctx, "audit"))
+	assert.Equal(t, []string{"syslog"}, m.Names())
+}
+
+func TestManagerEndedRun(t *testing.T) {
+	m := pipeline.NewManager()
+	require.NoError(t, m.Add("batch", &countedRunnable{f: func(context.Context, int, chan<- pipeline.Event) {}}))
+	require.NoError(t, m.Start(context.Background(), "batch"))
+
+	assert.Eventually(t, func() bool {
+		status, err := m.Status(context.Background(), "batch")
+		return err == nil && status.State == pipeline.PipelineStopped
+	}, time.Second, time.Millisecond)
+}
+
+func TestManagerStopTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	m := pipeline.NewManager()
+	require.NoError(t, m.Add("stuck", &countedRunnable{f: func(context.Context, int, chan<- pipeline.Event) { <-release }}))
+	require.NoError(t, m.Start(context.Background(), "stuck"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.Stop(ctx, "stuck"), pipeline.ErrManager)
+
+	status, err := m.Status(context.Background(), "stuck")
+	require.NoError(t, err)
+	assert.Equal(t, pipeline.PipelineStopping, status.State)
+	assert.ErrorIs(t, m.Start(context.Background(), "stuck"), pipeline.ErrManager)
+}
+
+func TestManagerInvalid(t *testing.T) {
+	m := pipeline.NewManager()
+	assert.ErrorIs(t, m.Add("", holdRunnable()), pipeline.ErrManager)
+	assert.ErrorIs(t, m.Add("nil", nil), pipeline.ErrManager)
+	require.NoError(t, m.Add("syslog", holdRunnable()))
+	assert.ErrorIs(t, m.Add("syslog", holdRunnable()), pipeline.ErrManager)
+
+	ctx := context.Background()
+	assert.ErrorIs(t, m.Start(ctx, "missing"), pipeline.ErrManager)
+	assert.ErrorIs(t, m.Stop(ctx, "missing"), pipeline.ErrManager)
+	_, err := m.Status(ctx, "missing")
+	assert.ErrorIs(t, err, pipeline.ErrManager)
+	assert.Nil(t, m.Collector("missing"))
+	assert.NoError(t, m.Stop(ctx, "syslog"), "stopping a stopped pipeline does nothing")
+}
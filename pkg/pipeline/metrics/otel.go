@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that OTelBridge observes runners.
+var _ pipeline.Observer = (*OTelBridge)(nil)
+
+// ScopeName is the instrumentation scope of the meter of the bridge.
+const ScopeName = "github.com/witfoo/krapht/pkg/pipeline/metrics"
+
+// OTelBridgeConfig is the configuration of an OpenTelemetry metrics bridge.
+type OTelBridgeConfig struct {
+	MeterProvider metric.MeterProvider // provider of the meter, defaults to the global provider
+}
+
+// OTelBridge converts metric events into OpenTelemetry instruments of a meter, named after the metric:
+// counters into counters, gauges into gauges, and histograms and summaries into histograms. As counter
+// events carry cumulative values, the counters are added the difference with the previous value of the
+// series, or the value when it decreased as its source restarted. Errors creating instruments are passed
+// to the OpenTelemetry error handler.
+type OTelBridge struct {
+	meter metric.Meter
+
+	mu         sync.Mutex
+	counters   map[string]metric.Float64Counter
+	gauges     map[string]metric.Float64Gauge
+	histograms map[string]metric.Float64Histogram
+	last       map[string]float64 // last cumulative value of a counter series
+}
+
+// NewOTelBridge creates a new OpenTelemetry metrics bridge with the given configuration.
+func NewOTelBridge(conf OTelBridgeConfig) *OTelBridge {
+	if conf.MeterProvider == nil {
+		conf.MeterProvider = otel.GetMeterProvider()
+	}
+
+	return &OTelBridge{
+		meter:      conf.MeterProvider.Meter(ScopeName),
+		counters:   make(map[string]metric.Float64Counter),
+		gauges:     make(map[string]metric.Float64Gauge),
+		histograms: make(map[string]metric.Float64Histogram),
+		last:       make(map[string]float64),
+	}
+}
+
+// Callback returns an event callback recording the metric events of an event collector.
+func (b *OTelBridge) Callback() pipeline.EventCallback {
+	return func(e pipeline.Event) {
+		if m, ok := e.(pipeline.Measurable); ok {
+			b.Record(context.Background(), m, nil)
+		}
+	}
+}
+
+// Start is called when a run of a runner the bridge is attached to starts.
+func (b *OTelBridge) Start(context.Context, *pipeline.Runner) {}
+
+// Observe records the metric events of a run, with the pipeline attribute set to the runner name.
+func (b *OTelBridge) Observe(r *pipeline.Runner, e pipeline.Event) {
+	if m, ok := e.(pipeline.Measurable); ok {
+		b.Record(context.Background(), m, map[string]string{"pipeline": r.Name()})
+	}
+}
+
+// Stop is called when a run of a runner the bridge is attached to ends.
+func (b *OTelBridge) Stop(*pipeline.Runner) {}
+
+// Record records a metric event on its instrument, with attributes from its labels and the extra labels.
+func (b *OTelBridge) Record(ctx context.Context, m pipeline.Measurable, extra map[string]string) {
+	labels := make(map[string]string, len(m.Labels())+len(extra))
+	for k, v := range m.Labels() {
+		labels[k] = v
+	}
+	for k, v := range extra {
+		labels[k] = v
+	}
+	attrs := make([]attribute.KeyValue, 0, len(labels))
+	for k, v := range labels {
+		attrs = append(attrs, attribute.String(k, v))
+	}
+	opt := metric.WithAttributes(attrs...)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch pipeline.MetricType(m.MetricType()) {
+	case pipeline.MetricTypeCounter:
+		c, err := instrument(b.counters, m.Name(), b.meter.Float64Counter)
+		if err != nil {
+			otel.Handle(err)
+			return
+		}
+		key := m.Name() + "\x00" + seriesKey(labels)
+		delta := m.Value() - b.last[key]
+		if delta < 0 {
+			delta = m.Value()
+		}
+		b.last[key] = m.Value()
+		c.Add(ctx, delta, opt)
+	case pipeline.MetricTypeHistogram, pipeline.MetricTypeSummary:
+		h, err := instrument(b.histograms, m.Name(), b.meter.Float64Histogram)
+		if err != nil {
+			otel.Handle(err)
+			return
+		}
+		h.Record(ctx, m.Value(), opt)
+	default:
+		g, err := instrument(b.gauges, m.Name(), b.meter.Float64Gauge)
+		if err != nil {
+			otel.Handle(err)
+			return
+		}
+		g.Record(ctx, m.Value(), opt)
+	}
+}
+
+// instrument returns the instrument of the name, creating it on first use
+func instrument[T any, O any](instruments map[string]T, name string, create func(string, ...O) (T, error)) (T, error) {
+	if i, ok := instruments[name]; ok {
+		return i, nil
+	}
+	i, err := create(name)
+	if err != nil {
+		return i, err
+	}
+	instruments[name] = i
+	return i, nil
+}
@@ -0,0 +1,79 @@
+package metrics_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/metrics"
+)
+
+// collectMetrics returns the metrics of the reader by name
+func collectMetrics(t *testing.T, reader sdkmetric.Reader) map[string]metricdata.Aggregation {
+	var rm metricdata.ResourceMetrics
+	assert.NoError(t, reader.Collect(context.Background(), &rm))
+
+	data := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			data[m.Name] = m.Data
+		}
+	}
+	return data
+}
+
+func TestOTelBridge(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	b := metrics.NewOTelBridge(metrics.OTelBridgeConfig{MeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))})
+
+	labels := map[string]string{"sink": "test"}
+	record := b.Callback()
+	record(pipeline.NewMetricEvent("records_total", 5, labels, pipeline.MetricTypeCounter))
+	record(pipeline.NewMetricEvent("records_total", 8, labels, pipeline.MetricTypeCounter))
+	record(pipeline.NewMetricEvent("records_total", 2, labels, pipeline.MetricTypeCounter)) // restarted
+	record(pipeline.NewMetricEvent("queue_depth", 3, labels, pipeline.MetricTypeGauge))
+	record(pipeline.NewMetricEvent("queue_depth", 7, labels, pipeline.MetricTypeGauge))
+	record(pipeline.NewMetricEvent("load_seconds", 0.5, labels, pipeline.MetricTypeHistogram))
+	record(pipeline.NewMetricEvent("load_seconds", 1.5, labels, pipeline.MetricTypeSummary))
+	record(pipeline.NewLogEvent("test", pipeline.LevelInfo, "not a metric"))
+
+	data := collectMetrics(t, reader)
+	assert.Len(t, data, 3)
+
+	sum, ok := data["records_total"].(metricdata.Sum[float64])
+	assert.True(t, ok)
+	assert.True(t, sum.IsMonotonic)
+	assert.Equal(t, 10.0, sum.DataPoints[0].Value)
+	sinkAttr, _ := sum.DataPoints[0].Attributes.Value(attribute.Key("sink"))
+	assert.Equal(t, "test", sinkAttr.AsString())
+
+	gauge, ok := data["queue_depth"].(metricdata.Gauge[float64])
+	assert.True(t, ok)
+	assert.Equal(t, 7.0, gauge.DataPoints[0].Value)
+
+	hist, ok := data["load_seconds"].(metricdata.Histogram[float64])
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), hist.DataPoints[0].Count)
+	assert.Equal(t, 2.0, hist.DataPoints[0].Sum)
+}
+
+func TestOTelBridgeObserver(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	b := metrics.NewOTelBridge(metrics.OTelBridgeConfig{MeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))})
+
+	r := pipeline.NewRunner("ingest", metricSource{})
+	assert.NoError(t, pipeline.SetSink[int](r, "discard", discard{}))
+	r.Attach(b)
+	for range r.Run(context.Background()) {
+	}
+
+	sum, ok := collectMetrics(t, reader)["source_items_total"].(metricdata.Sum[float64])
+	assert.True(t, ok)
+	name, _ := sum.DataPoints[0].Attributes.Value(attribute.Key("pipeline"))
+	assert.Equal(t, "ingest", name.AsString())
+}
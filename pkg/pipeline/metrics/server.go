@@ -0,0 +1,417 @@
+// Package metrics exposes the statistics and metric events of pipelines to Prometheus.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Server observes runners and collects Prometheus metrics.
+var (
+	_ pipeline.Observer    = (*Server)(nil)
+	_ prometheus.Collector = (*Server)(nil)
+)
+
+// Metric names of the pipeline internals exported by the server
+const (
+	MetricStageItems         = "krapht_stage_items_total"
+	MetricStageQueued        = "krapht_stage_queued_items"
+	MetricStageCapacity      = "krapht_stage_queue_capacity"
+	MetricStageErrors        = "krapht_stage_errors_total"
+	MetricStageLatency       = "krapht_stage_latency_seconds"
+	MetricPipelineEvents     = "krapht_pipeline_events_total"
+	MetricEventsDropped      = "krapht_events_dropped_total"
+	MetricCollectorProcessed = "krapht_collector_events_processed_total"
+	MetricCollectorQueued    = "krapht_collector_events_queued"
+	MetricCollectorWorkers   = "krapht_collector_workers"
+)
+
+// builtinMetrics are the names of the pipeline internals, which metric events cannot use
+var builtinMetrics = map[string]bool{
+	MetricStageItems:         true,
+	MetricStageQueued:        true,
+	MetricStageCapacity:      true,
+	MetricStageErrors:        true,
+	MetricStageLatency:       true,
+	MetricPipelineEvents:     true,
+	MetricEventsDropped:      true,
+	MetricCollectorProcessed: true,
+	MetricCollectorQueued:    true,
+	MetricCollectorWorkers:   true,
+}
+
+// ServerConfig is the configuration of a metrics server.
+type ServerConfig struct {
+	Addr string // listen address, defaults to ":9090"
+	Path string // path of the metrics endpoint, defaults to "/metrics"
+
+	// Registry receives the pipeline metrics and is served on the endpoint. When nil, a new registry
+	// with the Go runtime and process collectors is used.
+	Registry *prometheus.Registry
+}
+
+// Server is a metrics server that aggregates the statistics of the runners it is attached to, the
+// metric events of their runs, dropped events and event collector statistics, and serves them with
+// the other metrics of its registry on a Prometheus endpoint.
+type Server struct {
+	conf     ServerConfig
+	registry *prometheus.Registry
+
+	mu         sync.Mutex
+	runners    map[*pipeline.Runner]struct{}
+	collectors map[string]*pipeline.EventCollector
+	events     map[eventKey]uint64 // events observed by pipeline and type
+	series     *eventSeries
+
+	srv *http.Server
+}
+
+// eventKey identifies the events of a type observed in a pipeline
+type eventKey struct {
+	pipeline  string
+	eventType pipeline.EventType
+}
+
+// NewServer creates a new metrics server with the given configuration, registering its collector.
+func NewServer(conf ServerConfig) (*Server, error) {
+	if conf.Addr == "" {
+		conf.Addr = ":9090"
+	}
+
+	if conf.Path == "" {
+		conf.Path = "/metrics"
+	}
+
+	registry := conf.Registry
+	if registry == nil {
+		registry = prometheus.NewRegistry()
+		registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+
+	s := &Server{
+		conf:       conf,
+		registry:   registry,
+		runners:    make(map[*pipeline.Runner]struct{}),
+		collectors: make(map[string]*pipeline.EventCollector),
+		events:     make(map[eventKey]uint64),
+		series:     newEventSeries(),
+	}
+	if err := registry.Register(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// AddCollector adds the statistics of an event collector under the name.
+func (s *Server) AddCollector(name string, c *pipeline.EventCollector) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.collectors[name] = c
+}
+
+// Handler returns the handler of the metrics endpoint, to serve it on another server.
+func (s *Server) Handler() http.Handler {
+	return promhttp.HandlerFor(s.registry, promhttp.HandlerOpts{Registry: s.registry})
+}
+
+// Listen starts serving the metrics endpoint on the configured address. It returns once the address
+// is bound, serving in the background until Close.
+func (s *Server) Listen() error {
+	ln, err := net.Listen("tcp", s.conf.Addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(s.conf.Path, s.Handler())
+
+	s.mu.Lock()
+	s.srv = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	srv := s.srv
+	s.mu.Unlock()
+
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			_ = ln.Close()
+		}
+	}()
+	return nil
+}
+
+// Close stops serving the metrics endpoint, waiting for requests in progress until the context is done.
+func (s *Server) Close(ctx context.Context) error {
+	s.mu.Lock()
+	srv := s.srv
+	s.srv = nil
+	s.mu.Unlock()
+
+	if srv == nil {
+		return nil
+	}
+	return srv.Shutdown(ctx)
+}
+
+// Start adds the statistics of the runner to the metrics when a run starts.
+func (s *Server) Start(_ context.Context, r *pipeline.Runner) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runners[r] = struct{}{}
+}
+
+// Observe counts the events of a run and records its metric events.
+func (s *Server) Observe(r *pipeline.Runner, e pipeline.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.events[eventKey{pipeline: r.Name(), eventType: e.Type()}]++
+	if m, ok := e.(pipeline.Measurable); ok {
+		s.series.record(r.Name(), m)
+	}
+}
+
+// Stop keeps the statistics of the last run of the runner until it runs again.
+func (s *Server) Stop(*pipeline.Runner) {}
+
+// Describe sends no descriptors, as the metrics of pipelines are only known once they run.
+func (s *Server) Describe(chan<- *prometheus.Desc) {}
+
+// Collect sends the current pipeline metrics.
+func (s *Server) Collect(ch chan<- prometheus.Metric) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stageItems := prometheus.NewDesc(MetricStageItems, "Items sent by a pipeline stage to the next stage.",
+		[]string{"pipeline", "stage", "kind"}, nil)
+	stageQueued := prometheus.NewDesc(MetricStageQueued, "Items waiting in the channel after a pipeline stage.",
+		[]string{"pipeline", "stage", "kind"}, nil)
+	stageCapacity := prometheus.NewDesc(MetricStageCapacity, "Buffer size of the channel after a pipeline stage.",
+		[]string{"pipeline", "stage", "kind"}, nil)
+	stageErrors := prometheus.NewDesc(MetricStageErrors, "Error events sent by a pipeline stage.",
+		[]string{"pipeline", "stage", "kind"}, nil)
+	stageLatency := prometheus.NewDesc(MetricStageLatency, "Time a busy pipeline stage takes per item.",
+		[]string{"pipeline", "stage", "kind"}, nil)
+	for r := range s.runners {
+		for _, st := range r.Stats() {
+			ch <- prometheus.MustNewConstMetric(stageErrors, prometheus.CounterValue, float64(st.Errors), r.Name(), st.Name, string(st.Kind))
+			if st.Kind != pipeline.StageSource {
+				l := st.Latency
+				ch <- prometheus.MustNewConstSummary(stageLatency, l.Count, l.Mean.Seconds()*float64(l.Count), map[float64]float64{
+					0.5: l.P50.Seconds(), 0.9: l.P90.Seconds(), 0.99: l.P99.Seconds(),
+				}, r.Name(), st.Name, string(st.Kind))
+			}
+			if st.Kind == pipeline.StageSink {
+				continue
+			}
+			ch <- prometheus.MustNewConstMetric(stageItems, prometheus.CounterValue, float64(st.ItemsOut), r.Name(), st.Name, string(st.Kind))
+			ch <- prometheus.MustNewConstMetric(stageQueued, prometheus.GaugeValue, float64(st.Queued), r.Name(), st.Name, string(st.Kind))
+			ch <- prometheus.MustNewConstMetric(stageCapacity, prometheus.GaugeValue, float64(st.Capacity), r.Name(), st.Name, string(st.Kind))
+		}
+	}
+
+	events := prometheus.NewDesc(MetricPipelineEvents, "Events sent by the stages of a pipeline by type.",
+		[]string{"pipeline", "type"}, nil)
+	for k, n := range s.events {
+		ch <- prometheus.MustNewConstMetric(events, prometheus.CounterValue, float64(n), k.pipeline, eventTypeName(k.eventType))
+	}
+
+	ch <- prometheus.MustNewConstMetric(
+		prometheus.NewDesc(MetricEventsDropped, "Events dropped because the event channel was full.", nil, nil),
+		prometheus.CounterValue, float64(pipeline.DroppedEvents()))
+
+	processed := prometheus.NewDesc(MetricCollectorProcessed, "Events processed by the callbacks of an event collector.",
+		[]string{"collector"}, nil)
+	queued := prometheus.NewDesc(MetricCollectorQueued, "Events waiting in the channel of an event collector.",
+		[]string{"collector"}, nil)
+	workers := prometheus.NewDesc(MetricCollectorWorkers, "Running workers of an event collector.",
+		[]string{"collector"}, nil)
+	for name, c := range s.collectors {
+		ch <- prometheus.MustNewConstMetric(processed, prometheus.CounterValue, float64(c.Processed()), name)
+		ch <- prometheus.MustNewConstMetric(queued, prometheus.GaugeValue, float64(c.Queued()), name)
+		ch <- prometheus.MustNewConstMetric(workers, prometheus.GaugeValue, float64(c.Workers()), name)
+	}
+
+	s.series.collect(ch)
+}
+
+// eventTypeName returns the label value of an event type
+func eventTypeName(t pipeline.EventType) string {
+	switch t {
+	case pipeline.EventLog:
+		return "log"
+	case pipeline.EventError:
+		return "error"
+	case pipeline.EventMetric:
+		return "metric"
+	default:
+		return "unknown"
+	}
+}
+
+// eventSeries holds the latest values of the metric events by name and labels
+type eventSeries struct {
+	types  map[string]pipeline.MetricType // type of a name, the type it was first seen with
+	values map[string]map[string]*eventValue
+}
+
+// eventValue is the value of a series: the latest value of counters and gauges, or the observations of
+// histograms and summaries
+type eventValue struct {
+	labels  map[string]string
+	value   float64
+	count   uint64
+	sum     float64
+	buckets []uint64 // observations at most each of prometheus.DefBuckets
+}
+
+func newEventSeries() *eventSeries {
+	return &eventSeries{
+		types:  make(map[string]pipeline.MetricType),
+		values: make(map[string]map[string]*eventValue),
+	}
+}
+
+// record records a metric event of a pipeline. Events named like a pipeline internal or with a name
+// reserved by Prometheus are ignored, and reserved label names get an "event_" prefix.
+func (s *eventSeries) record(name string, m pipeline.Measurable) {
+	metric := sanitizeName(m.Name())
+	if builtinMetrics[metric] || strings.HasPrefix(metric, "__") {
+		return
+	}
+
+	t, ok := s.types[metric]
+	if !ok {
+		t = pipeline.MetricType(m.MetricType())
+		s.types[metric] = t
+		s.values[metric] = make(map[string]*eventValue)
+	}
+
+	labels := map[string]string{"pipeline": name}
+	for k, v := range m.Labels() {
+		k = sanitizeName(k)
+		if k == "pipeline" {
+			continue
+		}
+		if reservedLabel(k) {
+			k = "event_" + k
+		}
+		labels[k] = v
+	}
+	key := seriesKey(labels)
+	v, ok := s.values[metric][key]
+	if !ok {
+		v = &eventValue{labels: labels}
+		s.values[metric][key] = v
+	}
+
+	switch t {
+	case pipeline.MetricTypeHistogram, pipeline.MetricTypeSummary:
+		if v.buckets == nil {
+			v.buckets = make([]uint64, len(prometheus.DefBuckets))
+		}
+		v.count++
+		v.sum += m.Value()
+		for i, upper := range prometheus.DefBuckets {
+			if m.Value() <= upper {
+				v.buckets[i]++
+			}
+		}
+	default:
+		v.value = m.Value()
+	}
+}
+
+// reservedLabel reports whether a label name is reserved by Prometheus, including the bucket and
+// quantile labels of histograms and summaries
+func reservedLabel(name string) bool {
+	return strings.HasPrefix(name, "__") || name == "le" || name == "quantile"
+}
+
+// collect sends the series, with the union of the label names of a metric so its series are consistent.
+// Series that are not valid metrics are skipped.
+func (s *eventSeries) collect(ch chan<- prometheus.Metric) {
+	for metric, values := range s.values {
+		names := make(map[string]struct{})
+		for _, v := range values {
+			for k := range v.labels {
+				names[k] = struct{}{}
+			}
+		}
+		labelNames := make([]string, 0, len(names))
+		for k := range names {
+			labelNames = append(labelNames, k)
+		}
+		sort.Strings(labelNames)
+		desc := prometheus.NewDesc(metric, "Metric events of pipeline stages.", labelNames, nil)
+
+		for _, v := range values {
+			labelValues := make([]string, len(labelNames))
+			for i, k := range labelNames {
+				labelValues[i] = v.labels[k]
+			}
+
+			var m prometheus.Metric
+			var err error
+			switch s.types[metric] {
+			case pipeline.MetricTypeCounter:
+				m, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, v.value, labelValues...)
+			case pipeline.MetricTypeHistogram:
+				buckets := make(map[float64]uint64, len(v.buckets))
+				for i, upper := range prometheus.DefBuckets {
+					buckets[upper] = v.buckets[i]
+				}
+				m, err = prometheus.NewConstHistogram(desc, v.count, v.sum, buckets, labelValues...)
+			case pipeline.MetricTypeSummary:
+				m, err = prometheus.NewConstSummary(desc, v.count, v.sum, nil, labelValues...)
+			default:
+				m, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, v.value, labelValues...)
+			}
+			if err == nil {
+				ch <- m
+			}
+		}
+	}
+}
+
+// seriesKey returns a key identifying a label set
+func seriesKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(labels[k])
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// sanitizeName replaces the characters that are not valid in Prometheus metric and label names
+func sanitizeName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9'
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	if len(b) == 0 {
+		return "_"
+	}
+	return string(b)
+}
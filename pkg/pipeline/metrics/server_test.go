@@ -0,0 +1,121 @@
+package metrics_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/metrics"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+// metricSource emits a single item and metric events of several types
+type metricSource struct{}
+
+func (metricSource) Extract(_ context.Context, eventC chan<- pipeline.Event) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		eventC <- pipeline.NewMetricEvent("source_items_total", 1, map[string]string{"source": "test"}, pipeline.MetricTypeCounter)
+		eventC <- pipeline.NewMetricEvent("source_latency", 0.02, nil, pipeline.MetricTypeHistogram)
+		eventC <- pipeline.NewMetricEvent("source_latency", 0.2, nil, pipeline.MetricTypeHistogram)
+		out <- 1
+	}()
+	return out
+}
+
+type discard struct{}
+
+func (discard) Load(in <-chan int, _ chan<- pipeline.Event) {
+	for range in {
+	}
+}
+
+func scrape(t *testing.T, s *metrics.Server) string {
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	b, err := io.ReadAll(rec.Body)
+	assert.NoError(t, err)
+	return string(b)
+}
+
+func TestServer(t *testing.T) {
+	s, err := metrics.NewServer(metrics.ServerConfig{Registry: prometheus.NewRegistry()})
+	assert.NoError(t, err)
+
+	collector := pipeline.NewEventCollector()
+	s.AddCollector("main", collector)
+
+	r := pipeline.NewRunner("ingest", metricSource{})
+	assert.NoError(t, pipeline.SetSink[int](r, "discard", discard{}))
+	r.Attach(s)
+
+	eventC := collector.Collect()
+	for e := range r.Run(context.Background()) {
+		eventC <- e
+	}
+	collector.Close()
+
+	body := scrape(t, s)
+	assert.Contains(t, body, `krapht_stage_items_total{kind="source",pipeline="ingest",stage="source"} 1`)
+	assert.Contains(t, body, `krapht_stage_queue_capacity{kind="source",pipeline="ingest",stage="source"} 0`)
+	assert.Contains(t, body, `krapht_stage_errors_total{kind="sink",pipeline="ingest",stage="discard"} 0`)
+	assert.Contains(t, body, `krapht_stage_latency_seconds_count{kind="sink",pipeline="ingest",stage="discard"}`)
+	assert.Contains(t, body, `krapht_pipeline_events_total{pipeline="ingest",type="metric"} 3`)
+	assert.Contains(t, body, `krapht_collector_events_processed_total{collector="main"} 3`)
+	assert.Contains(t, body, `krapht_collector_workers{collector="main"} 0`)
+	assert.Contains(t, body, `krapht_events_dropped_total`)
+	assert.Contains(t, body, `source_items_total{pipeline="ingest",source="test"} 1`)
+	assert.Contains(t, body, `source_latency_bucket{pipeline="ingest",le="0.025"} 1`)
+	assert.Contains(t, body, `source_latency_count{pipeline="ingest"} 2`)
+}
+
+func TestServerInconsistentLabels(t *testing.T) {
+	s, err := metrics.NewServer(metrics.ServerConfig{Registry: prometheus.NewRegistry()})
+	assert.NoError(t, err)
+
+	r := pipeline.NewRunner("labels", mock.NewSourceImpl(nil))
+	s.Observe(r, pipeline.NewMetricEvent("sink-writes", 1, map[string]string{"sink": "a"}, pipeline.MetricTypeGauge))
+	s.Observe(r, pipeline.NewMetricEvent("sink-writes", 2, map[string]string{"table": "b"}, pipeline.MetricTypeGauge))
+
+	body := scrape(t, s)
+	assert.Contains(t, body, `sink_writes{pipeline="labels",sink="a",table=""} 1`)
+	assert.Contains(t, body, `sink_writes{pipeline="labels",sink="",table="b"} 2`)
+}
+
+func TestServerReservedNames(t *testing.T) {
+	s, err := metrics.NewServer(metrics.ServerConfig{Registry: prometheus.NewRegistry()})
+	assert.NoError(t, err)
+
+	r := pipeline.NewRunner("reserved", mock.NewSourceImpl(nil))
+	assert.NoError(t, pipeline.SetSink[any](r, "discard", sink.Noop{}))
+	r.Attach(s)
+	for range r.Run(context.Background()) {
+	}
+
+	s.Observe(r, pipeline.NewMetricEvent("writes", 1, map[string]string{"__x": "a"}, pipeline.MetricTypeCounter))
+	s.Observe(r, pipeline.NewMetricEvent("wait", 0.1, map[string]string{"le": "b"}, pipeline.MetricTypeHistogram))
+	s.Observe(r, pipeline.NewMetricEvent(metrics.MetricStageItems, 5, nil, pipeline.MetricTypeCounter))
+	s.Observe(r, pipeline.NewMetricEvent("__internal", 1, nil, pipeline.MetricTypeGauge))
+
+	body := scrape(t, s)
+	assert.Contains(t, body, `writes{event___x="a",pipeline="reserved"} 1`)
+	assert.Contains(t, body, `wait_count{event_le="b",pipeline="reserved"} 1`)
+	assert.Contains(t, body, `krapht_stage_items_total{kind="source",pipeline="reserved",stage="source"} 0`)
+	assert.NotContains(t, body, "__internal")
+}
+
+func TestServerListen(t *testing.T) {
+	s, err := metrics.NewServer(metrics.ServerConfig{Addr: "127.0.0.1:0"})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Listen())
+	assert.NoError(t, s.Close(context.Background()))
+}
@@ -0,0 +1,85 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure ChannelSource implements the pipeline.Source interface
+var _ pipeline.Source[any] = (*ChannelSource[any])(nil)
+
+// ChannelSource is a source for testing that extracts the items of a channel
+type ChannelSource[T any] struct {
+	ch <-chan T
+}
+
+// NewChannelSource creates a new ChannelSource extracting the items sent on the channel until it is
+// closed, the channel is not closed by the source
+func NewChannelSource[T any](ch <-chan T) *ChannelSource[T] {
+	return &ChannelSource[T]{
+		ch: ch,
+	}
+}
+
+// NewChannelSourceOf creates a new ChannelSource extracting the items once, in order
+func NewChannelSourceOf[T any](items ...T) *ChannelSource[T] {
+	ch := make(chan T, len(items))
+	for _, v := range items {
+		ch <- v
+	}
+	close(ch)
+	return NewChannelSource[T](ch)
+}
+
+// Extract passes the items of the channel to the output channel, which is closed when the channel is
+// closed or the context is done
+func (s *ChannelSource[T]) Extract(ctx context.Context, _ chan<- pipeline.Event) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for {
+			var v T
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case v, ok = <-s.ch:
+				if !ok {
+					return
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- v:
+			}
+		}
+	}()
+	return out
+}
+
+// Ensure ChannelSink implements the pipeline.Sink interface
+var _ pipeline.Sink[any] = (*ChannelSink[any])(nil)
+
+// ChannelSink is a sink for testing that sends the items it loads to a channel
+type ChannelSink[T any] struct {
+	ch chan<- T
+}
+
+// NewChannelSink creates a new ChannelSink sending the items it loads to the channel, which it closes
+// when its input is closed, so the sink must be loaded once
+func NewChannelSink[T any](ch chan<- T) *ChannelSink[T] {
+	return &ChannelSink[T]{
+		ch: ch,
+	}
+}
+
+// Load sends the items of the input channel to the channel and closes it when the input is closed
+func (s *ChannelSink[T]) Load(in <-chan T, _ chan<- pipeline.Event) {
+	defer close(s.ch)
+	for v := range in {
+		s.ch <- v
+	}
+}
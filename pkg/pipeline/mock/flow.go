@@ -0,0 +1,129 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure Flow implements the pipeline.Flow interface
+var _ pipeline.Flow[any, any] = (*Flow[any, any])(nil)
+
+// ErrFlow is the default error of the failing calls of a mock Flow
+var ErrFlow = errors.New("error from mock Flow")
+
+// DelayFunc returns the delay of the nth call of a mock Flow, counting from 1
+type DelayFunc func(n int) time.Duration
+
+// FixedDelay returns a DelayFunc delaying every call by d
+func FixedDelay(d time.Duration) DelayFunc {
+	return func(int) time.Duration {
+		return d
+	}
+}
+
+// UniformDelay returns a DelayFunc delaying calls uniformly between lo and hi,
+// the same seed producing the same delays
+func UniformDelay(lo, hi time.Duration, seed uint64) DelayFunc {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewPCG(seed, seed))
+	return func(int) time.Duration {
+		if hi <= lo {
+			return lo
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return lo + time.Duration(rng.Int64N(int64(hi-lo)))
+	}
+}
+
+// ExponentialDelay returns a DelayFunc delaying calls exponentially distributed around the mean, which has
+// the long tail of real services, the same seed producing the same delays
+func ExponentialDelay(mean time.Duration, seed uint64) DelayFunc {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewPCG(seed, seed))
+	return func(int) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		return time.Duration(rng.ExpFloat64() * float64(mean))
+	}
+}
+
+// FlowConfig is the configuration of a mock Flow
+type FlowConfig[I, O any] struct {
+	Transform  func(I) (O, error) // transform of the items, defaults to passing items through when O is the type of I
+	Delay      DelayFunc          // delay of each call, defaults to none
+	ErrorEvery int                // every nth call fails, 0 never fails
+	Err        error              // error of the failing calls, defaults to ErrFlow
+	Temporary  bool               // whether the error events of failed items are temporary
+}
+
+// Flow is a flow for testing with programmable latency and failures, for deterministic tests of retries,
+// dead letters and backpressure. Its calls can be used as the transform of other flows, such as Retry.
+type Flow[I, O any] struct {
+	conf  FlowConfig[I, O]
+	calls atomic.Int64
+}
+
+// NewFlow creates a new mock Flow with the given configuration
+func NewFlow[I, O any](conf FlowConfig[I, O]) *Flow[I, O] {
+	if conf.Transform == nil {
+		conf.Transform = func(in I) (O, error) {
+			out, ok := any(in).(O)
+			if !ok {
+				return out, fmt.Errorf("mock Flow: cannot pass %T through as %T", in, out)
+			}
+			return out, nil
+		}
+	}
+
+	if conf.Err == nil {
+		conf.Err = ErrFlow
+	}
+
+	return &Flow[I, O]{
+		conf: conf,
+	}
+}
+
+// Call applies the delay, failure and transform of the next call to the item
+func (f *Flow[I, O]) Call(in I) (O, error) {
+	n := int(f.calls.Add(1))
+	if f.conf.Delay != nil {
+		time.Sleep(f.conf.Delay(n))
+	}
+
+	if f.conf.ErrorEvery > 0 && n%f.conf.ErrorEvery == 0 {
+		var zero O
+		return zero, f.conf.Err
+	}
+	return f.conf.Transform(in)
+}
+
+// Calls returns the number of calls so far
+func (f *Flow[I, O]) Calls() int {
+	return int(f.calls.Load())
+}
+
+// Transform calls the flow on each item of the input channel in a goroutine and returns the output channel.
+// Failed items are dropped and reported with an error event carrying the item as the record.
+func (f *Flow[I, O]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan O {
+	out := make(chan O)
+	go func() {
+		defer close(out)
+		for v := range in {
+			val, err := f.Call(v)
+			if err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("mock Flow error", err, f.conf.Temporary, v))
+				continue
+			}
+			out <- val
+		}
+	}()
+	return out
+}
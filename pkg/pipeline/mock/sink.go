@@ -0,0 +1,178 @@
+package mock
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure Sink implements the pipeline.Sink interface
+var _ pipeline.Sink[any] = (*Sink[any])(nil)
+
+// Sink is a sink for testing that records the items it loads, so tests can wait for and assert on the
+// output of a pipeline without draining channels themselves.
+type Sink[T any] struct {
+	mu      sync.Mutex
+	items   []T
+	closed  bool
+	changed chan struct{} // closed and replaced when items are added or the input closes
+}
+
+// NewSink creates a new Sink with no items
+func NewSink[T any]() *Sink[T] {
+	return &Sink[T]{
+		changed: make(chan struct{}),
+	}
+}
+
+// Load records the items of the input channel until it is closed
+func (s *Sink[T]) Load(in <-chan T, _ chan<- pipeline.Event) {
+	for v := range in {
+		s.mu.Lock()
+		s.items = append(s.items, v)
+		s.notify()
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	s.closed = true
+	s.notify()
+	s.mu.Unlock()
+}
+
+// notify wakes the waiters, it must be called with the lock held
+func (s *Sink[T]) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Items returns a copy of the items loaded so far
+func (s *Sink[T]) Items() []T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.items)
+}
+
+// Len returns the number of items loaded so far
+func (s *Sink[T]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// Closed returns whether the input channel of the sink was closed
+func (s *Sink[T]) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Reset clears the items and closed state, so the sink can be loaded again
+func (s *Sink[T]) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = nil
+	s.closed = false
+}
+
+// WaitFor waits until at least n items are loaded, returning an error if they are not within the timeout
+// or the input closes with fewer items
+func (s *Sink[T]) WaitFor(n int, timeout time.Duration) error {
+	return s.wait(timeout, func() error {
+		if len(s.items) >= n {
+			return nil
+		}
+		if s.closed {
+			return fmt.Errorf("mock sink: input closed after %d of %d items", len(s.items), n)
+		}
+		return errWaiting
+	}, func() error {
+		return fmt.Errorf("mock sink: received %d of %d items within %s", len(s.items), n, timeout)
+	})
+}
+
+// WaitClosed waits until the input channel is closed, returning an error if it is not within the timeout
+func (s *Sink[T]) WaitClosed(timeout time.Duration) error {
+	return s.wait(timeout, func() error {
+		if s.closed {
+			return nil
+		}
+		return errWaiting
+	}, func() error {
+		return fmt.Errorf("mock sink: input not closed within %s, received %d items", timeout, len(s.items))
+	})
+}
+
+// errWaiting is returned by the conditions of wait that are not met yet
+var errWaiting = errors.New("waiting")
+
+// wait checks the condition on every change until it is done or the timeout passes, the condition and the
+// timeout error are called with the lock held
+func (s *Sink[T]) wait(timeout time.Duration, cond func() error, expired func() error) error {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		s.mu.Lock()
+		err := cond()
+		changed := s.changed
+		s.mu.Unlock()
+		if err != errWaiting {
+			return err
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C:
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if err := cond(); err != errWaiting {
+				return err
+			}
+			return expired()
+		}
+	}
+}
+
+// AssertReceived asserts that at least n items are loaded within the timeout
+func (s *Sink[T]) AssertReceived(t testing.TB, n int, timeout time.Duration) bool {
+	t.Helper()
+	return assert.NoError(t, s.WaitFor(n, timeout))
+}
+
+// AssertLen asserts that exactly n items are loaded
+func (s *Sink[T]) AssertLen(t testing.TB, n int) bool {
+	t.Helper()
+	return assert.Len(t, s.Items(), n)
+}
+
+// AssertItems asserts that the loaded items equal the expected items in order
+func (s *Sink[T]) AssertItems(t testing.TB, want []T) bool {
+	t.Helper()
+	return assert.Equal(t, want, s.Items())
+}
+
+// AssertItemsMatch asserts that the loaded items equal the expected items in any order
+func (s *Sink[T]) AssertItemsMatch(t testing.TB, want []T) bool {
+	t.Helper()
+	return assert.ElementsMatch(t, want, s.Items())
+}
+
+// AssertContains asserts that the item is among the loaded items
+func (s *Sink[T]) AssertContains(t testing.TB, item T) bool {
+	t.Helper()
+	return assert.Contains(t, s.Items(), item)
+}
+
+// AssertClosed asserts that the input channel is closed within the timeout
+func (s *Sink[T]) AssertClosed(t testing.TB, timeout time.Duration) bool {
+	t.Helper()
+	return assert.NoError(t, s.WaitClosed(timeout))
+}
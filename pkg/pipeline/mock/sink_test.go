@@ -0,0 +1,109 @@
+package mock_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+)
+
+func TestSink_WaitFor(t *testing.T) {
+	t.Run("returns once the items are loaded", func(t *testing.T) {
+		s := mock.NewSink[int]()
+		in := make(chan int)
+		go s.Load(in, nil)
+
+		go func() {
+			in <- 1
+			in <- 2
+		}()
+		assert.NoError(t, s.WaitFor(2, time.Second))
+		s.AssertItems(t, []int{1, 2})
+		close(in)
+	})
+
+	t.Run("times out with fewer items", func(t *testing.T) {
+		s := mock.NewSink[int]()
+		in := make(chan int, 1)
+		in <- 1
+		go s.Load(in, nil)
+		defer close(in)
+
+		start := time.Now()
+		err := s.WaitFor(2, 20*time.Millisecond)
+		assert.ErrorContains(t, err, "received 1 of 2 items within 20ms")
+		assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+		assert.False(t, s.Closed())
+	})
+
+	t.Run("fails when the input closes with fewer items", func(t *testing.T) {
+		s := mock.NewSink[int]()
+		in := make(chan int, 1)
+		in <- 1
+		close(in)
+		go s.Load(in, nil)
+
+		err := s.WaitFor(3, time.Second)
+		assert.ErrorContains(t, err, "input closed after 1 of 3 items")
+	})
+}
+
+func TestSink_WaitClosed(t *testing.T) {
+	t.Run("returns once the input closes with fewer items than expected", func(t *testing.T) {
+		s := mock.NewSink[int]()
+		in := make(chan int, 1)
+		in <- 1
+		close(in)
+		go s.Load(in, nil)
+
+		s.AssertClosed(t, time.Second)
+		s.AssertLen(t, 1)
+		assert.Error(t, s.WaitFor(2, time.Second))
+	})
+
+	t.Run("times out while the input is open", func(t *testing.T) {
+		s := mock.NewSink[int]()
+		in := make(chan int)
+		go s.Load(in, nil)
+		defer close(in)
+
+		err := s.WaitClosed(20 * time.Millisecond)
+		assert.ErrorContains(t, err, "input not closed within 20ms, received 0 items")
+	})
+}
+
+func TestSink_ConcurrentItems(t *testing.T) {
+	s := mock.NewSink[int]()
+	in := make(chan int)
+	loaded := make(chan struct{})
+	go func() {
+		defer close(loaded)
+		s.Load(in, nil)
+	}()
+
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for !s.Closed() {
+				items := s.Items()
+				assert.LessOrEqual(t, len(items), 100)
+				_ = s.Len()
+			}
+		}()
+	}
+
+	for i := range 100 {
+		in <- i
+	}
+	close(in)
+	<-loaded
+	readers.Wait()
+
+	s.AssertLen(t, 100)
+	s.AssertContains(t, 99)
+}
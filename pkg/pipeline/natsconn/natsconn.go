@@ -0,0 +1,53 @@
+// Package natsconn connects the nats sources and sinks to their server and reports the health of their
+// connection.
+package natsconn
+
+import (
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/tlsconf"
+)
+
+// ConnectJetStream connects to the nats server at the URL, the default URL when empty, with the client
+// TLS configuration when set. The connection is open for the lifetime of the process.
+func ConnectJetStream(url string, conf *tlsconf.Config) (jetstream.JetStream, error) {
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	tlsConf, err := conf.Client()
+	if err != nil {
+		return nil, err
+	}
+	var opts []nats.Option
+	if tlsConf != nil {
+		opts = append(opts, nats.Secure(tlsConf))
+	}
+	nc, err := nats.Connect(url, opts...)
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return js, nil
+}
+
+// Health reports the status of a nats connection, healthy when connected and degraded while reconnecting
+func Health(nc *nats.Conn) pipeline.HealthStatus {
+	if nc == nil {
+		return pipeline.HealthStatus{State: pipeline.HealthUnhealthy, Message: "no nats connection"}
+	}
+
+	switch status := nc.Status(); status {
+	case nats.CONNECTED:
+		return pipeline.HealthStatus{State: pipeline.HealthHealthy}
+	case nats.RECONNECTING, nats.CONNECTING:
+		return pipeline.HealthStatus{State: pipeline.HealthDegraded, Message: "nats " + status.String()}
+	default:
+		return pipeline.HealthStatus{State: pipeline.HealthUnhealthy, Message: "nats " + status.String()}
+	}
+}
@@ -0,0 +1,25 @@
+package natsconn_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/natsconn"
+	"github.com/witfoo/krapht/pkg/tlsconf"
+)
+
+func TestHealth(t *testing.T) {
+	status := natsconn.Health(nil)
+	assert.Equal(t, pipeline.HealthUnhealthy, status.State)
+	assert.Equal(t, "no nats connection", status.Message)
+}
+
+func TestConnectJetStream(t *testing.T) {
+	_, err := natsconn.ConnectJetStream("nats://127.0.0.1:1", nil)
+	assert.Error(t, err)
+
+	_, err = natsconn.ConnectJetStream("", &tlsconf.Config{CAFile: "missing.pem"})
+	assert.Error(t, err, "invalid TLS configuration")
+}
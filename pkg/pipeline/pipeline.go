@@ -29,6 +29,13 @@ type Flow[In any, Out any] interface {
 	Transform(in <-chan In, eventC chan<- Event) (out <-chan Out)
 }
 
+// ChunkFlow interface represents a flow that can transform chunks of items.
+// A Runner moving items between stages in chunks passes them to TransformChunks without unchunking them.
+// The chunks received are owned by the flow, which may reuse them for its output.
+type ChunkFlow[In any, Out any] interface {
+	TransformChunks(in <-chan []In, eventC chan<- Event) (out <-chan []Out)
+}
+
 // FanOut interface represents a fan-out transformation in the pipeline.
 // Split method takes an input channel and returns multiple output channels.
 type FanOut[T any] interface {
@@ -42,11 +49,19 @@ type FanIn[T any] interface {
 }
 
 // Sink interface represents the end of the pipeline.
-// Load method takes an input channel and returns nothing.
+// Load method takes an input channel and returns nothing. Load must block until the input channel
+// is closed and all in-flight work, such as asynchronous publishes and their acks, has resolved:
+// callers close the event channel once Load returns.
 type Sink[In any] interface {
 	Load(in <-chan In, eventC chan<- Event)
 }
 
+// ChunkSink interface represents a sink that can load chunks of items.
+// A Runner moving items between stages in chunks passes them to LoadChunks without unchunking them.
+type ChunkSink[In any] interface {
+	LoadChunks(in <-chan []In, eventC chan<- Event)
+}
+
 // Runnable interface represents a runnable pipeline.
 // Run method takes a context and returns a channel of errors.
 type Runnable interface {
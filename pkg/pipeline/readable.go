@@ -1,5 +1,7 @@
 package pipeline
 
+import "time"
+
 // Readable is an interface that represents a source of data.
 type Readable interface {
 	// Read returns the data and an error if any.
@@ -23,3 +25,182 @@ type DataRawReadable interface {
 	DataReadable
 	RawReadable
 }
+
+// MetadataReadable represents a message carrying the metadata common to the messages of all sources
+type MetadataReadable interface {
+	// Metadata returns the metadata of the message
+	Metadata() Metadata
+}
+
+// Ackable represents a message acknowledged upstream once it is loaded
+type Ackable interface {
+	// Ack acknowledges the message upstream
+	Ack() error
+}
+
+// Envelope is implemented by items wrapping another item, such as the envelopes of the tracing
+// package, so the metadata and ack token of a wrapped record are kept by the records derived from it.
+type Envelope interface {
+	// Unwrap returns the wrapped item
+	Unwrap() any
+}
+
+// Ensure that Record and Bytes implement the readable interfaces.
+var (
+	_ DataRawReadable  = Record{}
+	_ MetadataReadable = Record{}
+	_ Ackable          = Record{}
+	_ Releasable       = Record{}
+	_ Readable         = Bytes(nil)
+)
+
+// Bytes is a Readable over a byte slice.
+type Bytes []byte
+
+// Read returns the bytes and a nil error.
+func (b Bytes) Read() ([]byte, error) {
+	return b, nil
+}
+
+// Metadata is the metadata common to the messages of all sources, which downstream stages can rely on
+// whichever source received a message.
+type Metadata struct {
+	Received time.Time         // time the message was received by the source
+	Source   string            // name of the source
+	Addr     string            // address or subject the message was received from
+	Tenant   string            // organization or tenant id of the message
+	Labels   map[string]string // labels of the message, set by sources and flows
+}
+
+// Record is a message holding structured data derived from a raw message, such as the
+// output of a parser. Keeping the raw message lets sinks acknowledge it upstream.
+// A record is the envelope of a message, also carrying its metadata and the token acknowledging it.
+type Record struct {
+	data Readable
+	raw  Readable
+	meta *Metadata // nil when the record has no metadata
+	ack  Ackable   // nil when the raw message is acknowledged
+}
+
+// NewRecord creates a new Record with the given structured data and raw message.
+func NewRecord(data, raw Readable) Record {
+	return Record{
+		data: data,
+		raw:  raw,
+	}
+}
+
+// DeriveRecord creates a new Record with the given structured data and raw message derived from an
+// item, keeping the metadata and ack token of the item when it is a Record, or its metadata when it is
+// a MetadataReadable. Items in an Envelope are unwrapped first.
+func DeriveRecord(data, raw Readable, from any) Record {
+	for {
+		e, ok := from.(Envelope)
+		if !ok {
+			break
+		}
+		from = e.Unwrap()
+	}
+
+	r := NewRecord(data, raw)
+	switch f := from.(type) {
+	case Record:
+		r.meta, r.ack = f.meta, f.ack
+		if a, ok := raw.(ackedReadable); ok && f.ack != nil {
+			r.raw = a.Readable // the raw message of the item, acknowledged with the token again
+		}
+	case MetadataReadable:
+		if m := f.Metadata(); !m.isZero() {
+			r.meta = &m
+		}
+	}
+	return r
+}
+
+// Data returns the structured data
+func (r Record) Data() Readable {
+	return r.data
+}
+
+// Raw returns the raw message, acknowledged with the ack token of the record when it has one
+func (r Record) Raw() Readable {
+	if r.ack != nil {
+		return ackedReadable{Readable: r.raw, ack: r.ack}
+	}
+	return r.raw
+}
+
+// Metadata returns the metadata of the record, which is zero when it has none
+func (r Record) Metadata() Metadata {
+	if r.meta == nil {
+		return Metadata{}
+	}
+	return *r.meta
+}
+
+// WithMetadata returns a copy of the record with the metadata.
+func (r Record) WithMetadata(m Metadata) Record {
+	r.meta = &m
+	return r
+}
+
+// WithAck returns a copy of the record acknowledged with the ack token instead of its raw message, for
+// sources acknowledging messages by a token such as an offset or a delivery tag.
+func (r Record) WithAck(ack Ackable) Record {
+	r.ack = ack
+	return r
+}
+
+// Ack acknowledges the record with its ack token, or its raw message when it is Ackable.
+func (r Record) Ack() error {
+	if r.ack != nil {
+		return r.ack.Ack()
+	}
+	if a, ok := r.raw.(Ackable); ok {
+		return a.Ack()
+	}
+	return nil
+}
+
+// Release releases the structured data and the raw message if they are Releasable.
+func (r Record) Release() {
+	Release(r.data)
+	Release(r.raw)
+}
+
+// MetadataOf returns the metadata of an item, or of its raw message, when it has any.
+func MetadataOf(v any) (Metadata, bool) {
+	if m, ok := v.(MetadataReadable); ok {
+		if meta := m.Metadata(); !meta.isZero() {
+			return meta, true
+		}
+	}
+	if r, ok := v.(RawReadable); ok {
+		if m, ok := r.Raw().(MetadataReadable); ok {
+			meta := m.Metadata()
+			return meta, !meta.isZero()
+		}
+	}
+	return Metadata{}, false
+}
+
+// isZero reports whether the metadata is empty
+func (m Metadata) isZero() bool {
+	return m.Received.IsZero() && m.Source == "" && m.Addr == "" && m.Tenant == "" && len(m.Labels) == 0
+}
+
+// ackedReadable is a raw message acknowledged with the ack token of its record
+type ackedReadable struct {
+	Readable
+	ack Ackable
+}
+
+// Ack acknowledges the message with the ack token.
+func (a ackedReadable) Ack() error {
+	return a.ack.Ack()
+}
+
+// Release releases the raw message if it is Releasable.
+func (a ackedReadable) Release() {
+	Release(a.Readable)
+}
@@ -0,0 +1,104 @@
+package pipeline_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+func TestNewRecord(t *testing.T) {
+	raw := pipeline.Bytes("<13>Jan  2 03:04:05 host app: message")
+	record := pipeline.NewRecord(pipeline.Bytes(`{"host":"host"}`), raw)
+
+	data, err := record.Data().Read()
+	if err != nil {
+		t.Fatalf("Expected no error reading data, got %v", err)
+	}
+	if string(data) != `{"host":"host"}` {
+		t.Errorf("Expected data %s, got %s", `{"host":"host"}`, data)
+	}
+
+	if record.Raw() == nil {
+		t.Fatal("Expected the raw message to be retained")
+	}
+	p, _ := record.Raw().Read()
+	if string(p) != string(raw) {
+		t.Errorf("Expected raw message %s, got %s", raw, p)
+	}
+}
+
+// ackCounter counts the acknowledgments of a message
+type ackCounter struct {
+	pipeline.Bytes
+	acks *int
+}
+
+func (a ackCounter) Ack() error {
+	*a.acks++
+	return nil
+}
+
+// metaBytes is a raw message carrying metadata
+type metaBytes struct {
+	pipeline.Bytes
+	meta pipeline.Metadata
+}
+
+func (m metaBytes) Metadata() pipeline.Metadata { return m.meta }
+
+func TestRecord_Metadata(t *testing.T) {
+	received := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	meta := pipeline.Metadata{Received: received, Source: "http", Addr: "10.0.0.1:5000", Tenant: "acme", Labels: map[string]string{"site": "a"}}
+	record := pipeline.NewRecord(pipeline.Bytes("raw"), pipeline.Bytes("raw")).WithMetadata(meta)
+
+	if got := record.Metadata(); !reflect.DeepEqual(got, meta) {
+		t.Errorf("Expected metadata %v, got %v", meta, got)
+	}
+	if got := pipeline.NewRecord(nil, nil).Metadata(); !reflect.DeepEqual(got, pipeline.Metadata{}) {
+		t.Errorf("Expected no metadata, got %v", got)
+	}
+
+	// records derived from a record keep its metadata
+	derived := pipeline.DeriveRecord(pipeline.Bytes(`{"msg":"raw"}`), record.Raw(), record)
+	if got, ok := pipeline.MetadataOf(derived); !ok || got.Tenant != "acme" {
+		t.Errorf("Expected the metadata of the derived record, got %v", got)
+	}
+	if _, ok := pipeline.MetadataOf(pipeline.DeriveRecord(nil, nil, pipeline.Bytes("raw"))); ok {
+		t.Error("Expected no metadata for a record derived from bytes")
+	}
+
+	// the metadata of the raw message of an item is found
+	wrapped := pipeline.NewRecord(pipeline.Bytes("data"), metaBytes{Bytes: pipeline.Bytes("raw"), meta: meta})
+	if got, ok := pipeline.MetadataOf(wrapped); !ok || got.Source != "http" {
+		t.Errorf("Expected the metadata of the raw message, got %v", got)
+	}
+}
+
+func TestRecord_Ack(t *testing.T) {
+	var rawAcks, tokenAcks int
+	raw := ackCounter{Bytes: pipeline.Bytes("raw"), acks: &rawAcks}
+
+	record := pipeline.NewRecord(pipeline.Bytes("data"), raw)
+	if err := record.Ack(); err != nil || rawAcks != 1 {
+		t.Errorf("Expected the raw message to be acked once, got %d acks and error %v", rawAcks, err)
+	}
+
+	// the ack token acknowledges the record, also when sinks ack its raw message
+	token := ackCounter{acks: &tokenAcks}
+	record = record.WithAck(token)
+	if err := record.Ack(); err != nil || tokenAcks != 1 {
+		t.Errorf("Expected the token to be acked once, got %d acks and error %v", tokenAcks, err)
+	}
+	derived := pipeline.DeriveRecord(pipeline.Bytes("parsed"), record.Raw(), record)
+	if a, ok := derived.Raw().(pipeline.Ackable); !ok || a.Ack() != nil || tokenAcks != 2 {
+		t.Errorf("Expected the raw message of the derived record to ack the token, got %d acks", tokenAcks)
+	}
+	if p, _ := derived.Raw().Read(); string(p) != "raw" {
+		t.Errorf("Expected raw message raw, got %s", p)
+	}
+	if rawAcks != 1 {
+		t.Errorf("Expected the raw message not to be acked with a token, got %d acks", rawAcks)
+	}
+}
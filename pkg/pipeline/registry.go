@@ -0,0 +1,243 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrOptions is returned when the configuration of a registered component does not match its options.
+var ErrOptions = errors.New("invalid options")
+
+// OptionType is the JSON type of the value of an option.
+type OptionType string
+
+const (
+	// OptionString is an option holding a string
+	OptionString OptionType = "string"
+	// OptionInteger is an option holding an integer
+	OptionInteger OptionType = "integer"
+	// OptionNumber is an option holding a number
+	OptionNumber OptionType = "number"
+	// OptionBoolean is an option holding a boolean
+	OptionBoolean OptionType = "boolean"
+	// OptionDuration is an option holding a Duration, a string such as "1m30s" or a number of nanoseconds
+	OptionDuration OptionType = "duration"
+	// OptionArray is an option holding an array
+	OptionArray OptionType = "array"
+	// OptionObject is an option holding an object
+	OptionObject OptionType = "object"
+)
+
+// Option describes an option of the JSON configuration of a registered component.
+type Option struct {
+	Name        string     `json:"name"`
+	Type        OptionType `json:"type"`
+	Required    bool       `json:"required,omitempty"`
+	Default     any        `json:"default,omitempty"`
+	Description string     `json:"description,omitempty"`
+}
+
+// Component describes a component registered under a name, with the options of its configuration.
+type Component struct {
+	Kind    StageKind `json:"kind"`
+	Name    string    `json:"name"`
+	Options []Option  `json:"options,omitempty"`
+}
+
+// Registry holds the factories of a kind of component by name, with the options of their
+// configurations. The source, flow and sink packages each hold a registry their components register
+// with, which config loaders and command line tools construct components from.
+type Registry[F any] struct {
+	kind    StageKind
+	mu      sync.RWMutex
+	entries map[string]registration[F]
+}
+
+// registration is a factory with the options of its configuration
+type registration[F any] struct {
+	factory F
+	options []Option // nil when the options are not described
+}
+
+// NewRegistry creates a new registry of factories of the kind of component.
+func NewRegistry[F any](kind StageKind) *Registry[F] {
+	return &Registry[F]{
+		kind:    kind,
+		entries: make(map[string]registration[F]),
+	}
+}
+
+// Register makes a factory available by name. The options describe its configuration, which is checked
+// against them before the factory is called, a factory without options being given its configuration
+// unchecked. Register panics when the factory is nil or the name is already registered.
+func (r *Registry[F]) Register(name string, factory F, options ...Option) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if v := reflect.ValueOf(&factory).Elem(); v.Kind() == reflect.Func && v.IsNil() {
+		panic(fmt.Sprintf("%s: register factory is nil", r.kind))
+	}
+	if _, dup := r.entries[name]; dup {
+		panic(fmt.Sprintf("%s: register called twice for %s %s", r.kind, r.kind, name))
+	}
+	r.entries[name] = registration[F]{factory: factory, options: options}
+}
+
+// Names returns the sorted names of the registered factories.
+func (r *Registry[F]) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Components returns the descriptions of the registered factories, sorted by name.
+func (r *Registry[F]) Components() []Component {
+	names := r.Names()
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	components := make([]Component, 0, len(names))
+	for _, name := range names {
+		components = append(components, Component{Kind: r.kind, Name: name, Options: r.entries[name].options})
+	}
+	return components
+}
+
+// Factory returns the factory registered under the name after checking the configuration against its
+// options, and whether one is registered.
+func (r *Registry[F]) Factory(name string, conf json.RawMessage) (F, bool, error) {
+	r.mu.RLock()
+	entry, ok := r.entries[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		var zero F
+		return zero, false, nil
+	}
+	if entry.options != nil {
+		if err := CheckOptions(entry.options, conf); err != nil {
+			return entry.factory, true, err
+		}
+	}
+	return entry.factory, true, nil
+}
+
+// CheckOptions checks that a JSON configuration only sets the options, to values of their types, and
+// sets the required ones. An empty configuration sets no options.
+func CheckOptions(options []Option, conf json.RawMessage) error {
+	var values map[string]json.RawMessage
+	if conf := bytes.TrimSpace(conf); len(conf) > 0 && !bytes.Equal(conf, []byte("null")) {
+		if err := json.Unmarshal(conf, &values); err != nil {
+			return fmt.Errorf("%w: %w", ErrOptions, err)
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		i := slices.IndexFunc(options, func(o Option) bool { return o.Name == name })
+		if i < 0 {
+			return fmt.Errorf("%w: unknown option %s", ErrOptions, name)
+		}
+		if !options[i].Type.accepts(values[name]) {
+			return fmt.Errorf("%w: option %s is not of type %s", ErrOptions, name, options[i].Type)
+		}
+	}
+
+	for _, o := range options {
+		if _, ok := values[o.Name]; o.Required && !ok {
+			return fmt.Errorf("%w: option %s is required", ErrOptions, o.Name)
+		}
+	}
+	return nil
+}
+
+// accepts reports whether the JSON value is of the type, null being of every type
+func (t OptionType) accepts(v json.RawMessage) bool {
+	if bytes.Equal(v, []byte("null")) {
+		return true
+	}
+
+	switch t {
+	case OptionString:
+		var s string
+		return json.Unmarshal(v, &s) == nil
+	case OptionInteger:
+		var n int64
+		return json.Unmarshal(v, &n) == nil
+	case OptionNumber:
+		var n float64
+		return json.Unmarshal(v, &n) == nil
+	case OptionBoolean:
+		var b bool
+		return json.Unmarshal(v, &b) == nil
+	case OptionDuration:
+		var d Duration
+		return json.Unmarshal(v, &d) == nil
+	case OptionArray:
+		return len(v) > 0 && v[0] == '['
+	case OptionObject:
+		return len(v) > 0 && v[0] == '{'
+	default:
+		return true
+	}
+}
+
+// DecodeOptions decodes a JSON configuration into v, leaving v unchanged when it is empty.
+func DecodeOptions(conf json.RawMessage, v any) error {
+	if len(bytes.TrimSpace(conf)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(conf, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrOptions, err)
+	}
+	return nil
+}
+
+// Duration is a time.Duration decoded from a JSON string such as "1m30s", or a number of nanoseconds,
+// and encoded as a string.
+type Duration time.Duration
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON decodes the duration from a string or a number of nanoseconds.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		s, err := strconv.Unquote(string(b))
+		if err != nil {
+			return err
+		}
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(v)
+		return nil
+	}
+
+	var n int64
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("duration %s is neither a string nor an integer", b)
+	}
+	*d = Duration(n)
+	return nil
+}
@@ -0,0 +1,96 @@
+package pipeline_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+func TestCheckOptions(t *testing.T) {
+	options := []pipeline.Option{
+		{Name: "path", Type: pipeline.OptionString, Required: true},
+		{Name: "size", Type: pipeline.OptionInteger},
+		{Name: "rate", Type: pipeline.OptionNumber},
+		{Name: "fast", Type: pipeline.OptionBoolean},
+		{Name: "timeout", Type: pipeline.OptionDuration},
+		{Name: "hosts", Type: pipeline.OptionArray},
+		{Name: "headers", Type: pipeline.OptionObject},
+	}
+
+	tests := []struct {
+		conf string
+		err  string
+	}{
+		{conf: `{"path": "a", "size": 3, "rate": 1.5, "fast": true, "timeout": "1s", "hosts": [], "headers": {}}`},
+		{conf: `{"path": "a", "timeout": 1000, "size": null}`},
+		{conf: ``, err: "invalid options: option path is required"},
+		{conf: `{"path": "a", "extra": 1}`, err: "invalid options: unknown option extra"},
+		{conf: `{"path": 1}`, err: "invalid options: option path is not of type string"},
+		{conf: `{"path": "a", "size": 1.5}`, err: "invalid options: option size is not of type integer"},
+		{conf: `{"path": "a", "timeout": "soon"}`, err: "invalid options: option timeout is not of type duration"},
+		{conf: `{"path": "a", "hosts": "a"}`, err: "invalid options: option hosts is not of type array"},
+		{conf: `[]`, err: "invalid options: json: cannot unmarshal array"},
+	}
+	for _, tt := range tests {
+		err := pipeline.CheckOptions(options, json.RawMessage(tt.conf))
+		if tt.err == "" {
+			assert.NoError(t, err, tt.conf)
+			continue
+		}
+		assert.ErrorIs(t, err, pipeline.ErrOptions, tt.conf)
+		assert.ErrorContains(t, err, tt.err, tt.conf)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	var c struct {
+		A pipeline.Duration `json:"a"`
+		B pipeline.Duration `json:"b"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(`{"a": "1m30s", "b": 1000}`), &c))
+	assert.Equal(t, pipeline.Duration(90*time.Second), c.A)
+	assert.Equal(t, pipeline.Duration(time.Microsecond), c.B)
+
+	b, err := json.Marshal(c)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"a": "1m30s", "b": "1µs"}`, string(b))
+
+	assert.Error(t, json.Unmarshal([]byte(`{"a": "soon"}`), &c))
+	assert.Error(t, json.Unmarshal([]byte(`{"a": true}`), &c))
+}
+
+func TestRegistry(t *testing.T) {
+	r := pipeline.NewRegistry[func() int](pipeline.StageFlow)
+	r.Register("one", func() int { return 1 }, pipeline.Option{Name: "n", Type: pipeline.OptionInteger})
+	r.Register("two", func() int { return 2 })
+
+	assert.Equal(t, []string{"one", "two"}, r.Names())
+	assert.Equal(t, []pipeline.Component{
+		{Kind: pipeline.StageFlow, Name: "one", Options: []pipeline.Option{{Name: "n", Type: pipeline.OptionInteger}}},
+		{Kind: pipeline.StageFlow, Name: "two"},
+	}, r.Components())
+
+	f, ok, err := r.Factory("one", json.RawMessage(`{"n": 1}`))
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, f())
+
+	_, ok, err = r.Factory("one", json.RawMessage(`{"m": 1}`))
+	assert.True(t, ok)
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+
+	// the configurations of factories without options are not checked
+	_, ok, err = r.Factory("two", json.RawMessage(`{"m": 1}`))
+	assert.True(t, ok)
+	assert.NoError(t, err)
+
+	_, ok, _ = r.Factory("three", nil)
+	assert.False(t, ok)
+
+	assert.PanicsWithValue(t, "flow: register called twice for flow one", func() { r.Register("one", func() int { return 0 }) })
+	assert.PanicsWithValue(t, "flow: register factory is nil", func() { r.Register("nil", nil) })
+}
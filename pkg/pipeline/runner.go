@@ -0,0 +1,696 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Ensure that Runner implements the Runnable and Health interfaces.
+var (
+	_ Runnable = (*Runner)(nil)
+	_ Health   = (*Runner)(nil)
+)
+
+// StageKind is the kind of a stage of a Runner.
+type StageKind string
+
+const (
+	// StageSource is the source stage of a pipeline
+	StageSource StageKind = "source"
+	// StageFlow is a flow stage of a pipeline
+	StageFlow StageKind = "flow"
+	// StageSink is the sink stage of a pipeline
+	StageSink StageKind = "sink"
+)
+
+// Profiler labels of the goroutines of the stages of a run, relating goroutine profiles to the stages.
+const (
+	ProfileLabelPipeline = "krapht_pipeline"
+	ProfileLabelStage    = "krapht_stage"
+)
+
+// ErrRunner is returned when the stages of a Runner cannot be connected or run.
+var ErrRunner = errors.New("runner error")
+
+// Observer is attached to a Runner to observe its runs, such as to export its statistics.
+type Observer interface {
+	// Start is called when a run starts, before its stages are started.
+	Start(ctx context.Context, r *Runner)
+	// Observe is called with each event of the run before it is sent on the event channel.
+	Observe(r *Runner, e Event)
+	// Stop is called when a run has ended, after its last event.
+	Stop(r *Runner)
+}
+
+// RunnerOption represents a functional option for configuring Runner.
+type RunnerOption func(*Runner)
+
+// WithLinkBuffer configures the buffer size of the channels connecting the stages.
+func WithLinkBuffer(size int) RunnerOption {
+	return func(r *Runner) {
+		if size > 0 {
+			r.linkBuffer = size
+		}
+	}
+}
+
+// WithEventBuffer configures the buffer size of the event channel.
+func WithEventBuffer(size int) RunnerOption {
+	return func(r *Runner) {
+		if size > 0 {
+			r.eventBuffer = size
+		}
+	}
+}
+
+// WithChunkSize configures the links between the stages to move items in chunks of up to size items
+// rather than one at a time, to spend less time on channel operations at high item rates. Chunks are
+// sent when they are full or no item is ready, so items are not held back, and are unchunked at the
+// boundaries of the stages that do not implement ChunkFlow or ChunkSink. The link buffers, and the
+// queued items and capacities of the stage statistics, then count chunks.
+func WithChunkSize(size int) RunnerOption {
+	return func(r *Runner) {
+		if size > 1 {
+			r.chunkSize = size
+		}
+	}
+}
+
+// WithDrainTimeout configures how long a run waits, once its context is done and its sources are
+// stopped, for the items already extracted to flush through the flows and the sinks. A run that has not
+// drained by then sends a non-temporary error event and closes its event channel, abandoning the stages
+// still running. Without it a run waits for the sinks to return however long it takes.
+func WithDrainTimeout(d time.Duration) RunnerOption {
+	return func(r *Runner) {
+		if d > 0 {
+			r.drainTimeout = d
+		}
+	}
+}
+
+// runnerStage is a stage of a Runner with its statistics
+type runnerStage struct {
+	name string
+	kind StageKind
+	// start starts the stage on its input channel, a typed receive channel held as any, and returns
+	// its output channel the same way, or nil for a sink, whose start blocks until it returns
+	start  func(ctx context.Context, in any, eventC chan<- Event) any
+	inputs []int                      // indices of the stages whose output the stage takes
+	out    reflect.Type               // item type of the output, nil for a sink
+	fanOut func(out any, n int) []any // copies the output channel for each of the n stages taking it
+	fanIn  func(ins []any) any        // merges the output channels of the stages of the inputs
+	health Health                     // the component when it reports its health
+
+	taken   atomic.Uint64 // items taken from the input link
+	items   atomic.Uint64 // items sent to the output link
+	errors  atomic.Uint64
+	latency latencyWindow
+	fill    atomic.Pointer[func() (int, int)] // length and capacity of the current output link
+}
+
+// Runner is a Runnable pipeline of a source, flows and a sink, linear or, with the stages added by
+// AddSource, AddFlowFrom and AddSinkFrom, a directed acyclic graph of sources, flows and sinks. The stages
+// are connected by links that count the items passing between them and time their processing, and are
+// type checked when they are added.
+type Runner struct {
+	name         string
+	linkBuffer   int
+	eventBuffer  int
+	chunkSize    int
+	drainTimeout time.Duration
+
+	stages    []*runnerStage
+	sunk      bool // whether a sink was added
+	observers []Observer
+	running   atomic.Bool
+	stopped   chan struct{} // closed once the current or last run is no longer running
+	mu        sync.Mutex
+}
+
+// NewRunner creates a new Runner named name reading from the source.
+// It accepts optional RunnerOption functions to configure the runner.
+func NewRunner[T any](name string, src Source[T], opts ...RunnerOption) *Runner {
+	r := &Runner{
+		name:        name,
+		eventBuffer: 100, // Default event buffer size
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.stages = append(r.stages, newSourceStage(r, "source", src))
+	return r
+}
+
+// AddFlow appends a flow named name to the stages of the runner taking the output of the last stage.
+// The input type of the flow must be the output type of the last stage or an interface it implements.
+func AddFlow[I, O any](r *Runner, name string, f Flow[I, O]) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.accept(name, reflect.TypeFor[I]()); err != nil {
+		return err
+	}
+	r.stages = append(r.stages, newFlowStage(r, name, f, []int{len(r.stages) - 1}))
+	return nil
+}
+
+// SetSink sets the sink named name as the last stage of the runner taking the output of the stage before
+// it. The input type of the sink must be the output type of the last stage or an interface it implements.
+func SetSink[I any](r *Runner, name string, snk Sink[I]) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.accept(name, reflect.TypeFor[I]()); err != nil {
+		return err
+	}
+	r.stages = append(r.stages, newSinkStage(r, name, snk, []int{len(r.stages) - 1}))
+	r.sunk = true
+	return nil
+}
+
+// newSourceStage creates the stage of a source
+func newSourceStage[T any](r *Runner, name string, src Source[T]) *runnerStage {
+	s := &runnerStage{name: name, kind: StageSource, out: reflect.TypeFor[T](), fanOut: fanOut[T](r)}
+	s.health, _ = any(src).(Health)
+	s.start = func(ctx context.Context, _ any, eventC chan<- Event) any {
+		if r.chunkSize > 1 {
+			return linkChunks(s, src.Extract(ctx, eventC), r.linkBuffer, r.chunkSize)
+		}
+		return link(s, src.Extract(ctx, eventC), r.linkBuffer)
+	}
+	return s
+}
+
+// newFlowStage creates the stage of a flow taking the output of the stages of the inputs
+func newFlowStage[I, O any](r *Runner, name string, f Flow[I, O], inputs []int) *runnerStage {
+	s := &runnerStage{name: name, kind: StageFlow, inputs: inputs, out: reflect.TypeFor[O](), fanOut: fanOut[O](r), fanIn: fanIn[I](r)}
+	s.health, _ = any(f).(Health)
+	s.start = func(_ context.Context, in any, eventC chan<- Event) any {
+		if r.chunkSize <= 1 {
+			return link(s, f.Transform(relay[I](s, in), eventC), r.linkBuffer)
+		}
+		if cf, ok := any(f).(ChunkFlow[I, O]); ok {
+			return linkChunked(s, cf.TransformChunks(relayChunked[I](s, in), eventC), r.linkBuffer)
+		}
+		return linkChunks(s, f.Transform(relayChunks[I](s, in), eventC), r.linkBuffer, r.chunkSize)
+	}
+	return s
+}
+
+// newSinkStage creates the stage of a sink taking the output of the stages of the inputs
+func newSinkStage[I any](r *Runner, name string, snk Sink[I], inputs []int) *runnerStage {
+	s := &runnerStage{name: name, kind: StageSink, inputs: inputs, fanIn: fanIn[I](r)}
+	s.health, _ = any(snk).(Health)
+	s.start = func(_ context.Context, in any, eventC chan<- Event) any {
+		if r.chunkSize <= 1 {
+			snk.Load(relay[I](s, in), eventC)
+		} else if cs, ok := any(snk).(ChunkSink[I]); ok {
+			cs.LoadChunks(relayChunked[I](s, in), eventC)
+		} else {
+			snk.Load(relayChunks[I](s, in), eventC)
+		}
+		return nil
+	}
+	return s
+}
+
+// accept checks that a stage named name taking items of type in can be appended after the last stage
+func (r *Runner) accept(name string, in reflect.Type) error {
+	if err := r.unique(name); err != nil {
+		return err
+	}
+
+	last := r.stages[len(r.stages)-1]
+	if last.kind == StageSink {
+		return fmt.Errorf("%w: stage %s added after the sink", ErrRunner, name)
+	}
+	if !accepts(last.out, in) {
+		return fmt.Errorf("%w: stage %s takes %s, not the %s of the stage before it", ErrRunner, name, in, last.out)
+	}
+	return nil
+}
+
+// accepts reports whether a stage taking items of type in can take the items of type out
+func accepts(out, in reflect.Type) bool {
+	return out == in || (in.Kind() == reflect.Interface && out.Implements(in))
+}
+
+// Attach adds an observer of the runs of the runner. Observers attached during a run observe the next run.
+func (r *Runner) Attach(o Observer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.observers = append(r.observers, o)
+}
+
+// Name returns the name of the runner.
+func (r *Runner) Name() string {
+	return r.name
+}
+
+// Stats returns the statistics of the stages of the runner, in order from the source to the sink.
+// The counters are those of the current or last run.
+func (r *Runner) Stats() []StageStats {
+	r.mu.Lock()
+	stages := r.stages
+	r.mu.Unlock()
+
+	stats := make([]StageStats, len(stages))
+	for i, s := range stages {
+		stats[i] = StageStats{
+			Name:     s.name,
+			Kind:     s.kind,
+			ItemsIn:  s.taken.Load(),
+			ItemsOut: s.items.Load(),
+			Errors:   s.errors.Load(),
+			Latency:  s.latency.stats(),
+		}
+		if fill := s.fill.Load(); fill != nil {
+			stats[i].Queued, stats[i].Capacity = (*fill)()
+		}
+	}
+	return stats
+}
+
+// Health reports the health of the stages whose components report theirs, in the state of the least
+// healthy of them, with the messages of the stages that are not healthy and the oldest last success.
+func (r *Runner) Health(ctx context.Context) HealthStatus {
+	r.mu.Lock()
+	stages := r.stages
+	r.mu.Unlock()
+
+	var status HealthStatus
+	var messages []string
+	for _, s := range stages {
+		if s.health == nil {
+			continue
+		}
+		st := s.health.Health(ctx)
+		status.State = max(status.State, st.State)
+		if st.State != HealthHealthy {
+			messages = append(messages, fmt.Sprintf("%s %s: %s", s.name, st.State, st.Message))
+		}
+		// the stalest stage dates the last success of the pipeline
+		if !st.LastSuccess.IsZero() && (status.LastSuccess.IsZero() || st.LastSuccess.Before(status.LastSuccess)) {
+			status.LastSuccess = st.LastSuccess
+		}
+	}
+	status.Message = strings.Join(messages, "; ")
+	return status
+}
+
+// Run starts the stages and returns the event channel of the run, which is closed when the sinks return.
+// The events of all stages are sent on it, and it must be read for the events not to be dropped. A runner
+// without a sink, with a source or flow whose output no stage takes, or one that is already running,
+// sends a single error event and closes the channel.
+//
+// Only the sources are given the context, so cancelling it stops the sources while the flows and the
+// sinks drain the items already extracted, for up to the drain timeout when the runner has one.
+func (r *Runner) Run(ctx context.Context) <-chan Event {
+	out := make(chan Event, r.eventBuffer)
+
+	r.mu.Lock()
+	stages, observers, sunk := r.stages, r.observers, r.sunk
+	r.mu.Unlock()
+
+	consumers, err := r.connected(stages, sunk)
+	if err == nil && !r.running.CompareAndSwap(false, true) {
+		err = fmt.Errorf("%w: %s is already running", ErrRunner, r.name)
+	}
+	if err != nil {
+		out <- NewErrorEvent("runner: could not run pipeline", err, false)
+		close(out)
+		return out
+	}
+
+	stopped := make(chan struct{})
+	r.mu.Lock()
+	r.stopped = stopped
+	r.mu.Unlock()
+	stop := func() {
+		r.running.Store(false)
+		close(stopped)
+	}
+
+	for _, s := range stages {
+		s.reset()
+	}
+	for _, o := range observers {
+		o.Start(ctx, r)
+	}
+
+	// stages send on their own channels, counting their errors, which are never closed so late events
+	// are dropped rather than panic
+	events := make(chan Event, r.eventBuffer)
+	done := make(chan struct{})
+	abandoned := make(chan struct{})
+	var forwarders sync.WaitGroup
+	stageEvents := make([]chan Event, len(stages))
+	for i, s := range stages {
+		stageEvents[i] = make(chan Event, r.eventBuffer)
+		forwarders.Add(1)
+		go func(c <-chan Event) {
+			defer forwarders.Done()
+			forward := func(e Event) {
+				if e.Type() == EventError {
+					s.errors.Add(1)
+				}
+				select {
+				case events <- e:
+				case <-abandoned:
+				}
+			}
+			for {
+				select {
+				case e := <-c:
+					forward(e)
+				case <-done:
+					for {
+						select {
+						case e := <-c:
+							forward(e)
+						default:
+							return
+						}
+					}
+				}
+			}
+		}(stageEvents[i])
+	}
+	forwarded := make(chan struct{})
+	go func() {
+		forwarders.Wait()
+		close(forwarded)
+	}()
+
+	go func() {
+		// the output channels of the stages not yet taken by the stages after them
+		outs := make([][]any, len(stages))
+		var sinks sync.WaitGroup
+		for i, s := range stages {
+			// the goroutines started by the stage inherit its labels
+			pprof.SetGoroutineLabels(pprof.WithLabels(ctx, pprof.Labels(ProfileLabelPipeline, r.name, ProfileLabelStage, s.name)))
+
+			var in any
+			ins := make([]any, len(s.inputs))
+			for k, j := range s.inputs {
+				ins[k], outs[j] = outs[j][0], outs[j][1:]
+			}
+			if len(ins) == 1 {
+				in = ins[0]
+			} else if len(ins) > 1 {
+				in = s.fanIn(ins)
+			}
+
+			if s.kind == StageSink {
+				sinks.Add(1)
+				go func() {
+					defer sinks.Done()
+					s.start(ctx, in, stageEvents[i])
+				}()
+				continue
+			}
+			if out := s.start(ctx, in, stageEvents[i]); consumers[i] > 1 {
+				outs[i] = s.fanOut(out, consumers[i])
+			} else {
+				outs[i] = []any{out}
+			}
+		}
+		sinks.Wait()
+		close(done)
+	}()
+
+	if r.drainTimeout > 0 {
+		go r.drain(ctx, done, events, abandoned)
+	}
+
+	go func() {
+		defer close(out)
+
+		forward := func(e Event) {
+			for _, o := range observers {
+				o.Observe(r, e)
+			}
+			out <- e
+		}
+		flush := func() {
+			for {
+				select {
+				case e := <-events:
+					forward(e)
+				default:
+					for _, o := range observers {
+						o.Stop(r)
+					}
+					return
+				}
+			}
+		}
+		for {
+			select {
+			case e := <-events:
+				forward(e)
+			case <-forwarded:
+				flush()
+				stop()
+				return
+			case <-abandoned:
+				flush()
+				// the abandoned stages are still running until they return
+				go func() {
+					<-done
+					stop()
+				}()
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Wait blocks until the runner is not running, after the stages abandoned by a run past its drain
+// timeout have returned too, so it can be run again, or until the context is done.
+func (r *Runner) Wait(ctx context.Context) error {
+	r.mu.Lock()
+	stopped := r.stopped
+	r.mu.Unlock()
+	if stopped == nil {
+		return nil
+	}
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// drain closes abandoned, after sending an error event, when the stages of a run have not returned
+// within the drain timeout of the context being done
+func (r *Runner) drain(ctx context.Context, done <-chan struct{}, events chan<- Event, abandoned chan<- struct{}) {
+	select {
+	case <-ctx.Done():
+	case <-done:
+		return
+	}
+
+	timer := time.NewTimer(r.drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		err := fmt.Errorf("%w: %s did not drain within %s", ErrRunner, r.name, r.drainTimeout)
+		select {
+		case events <- NewErrorEvent("runner: pipeline abandoned", err, false):
+			close(abandoned)
+		case <-done:
+		}
+	}
+}
+
+// link connects the output of a stage to the next stage, counting the items passed
+func link[T any](s *runnerStage, in <-chan T, size int) <-chan T {
+	out := make(chan T, size)
+	fill := func() (int, int) { return len(out), cap(out) }
+	s.fill.Store(&fill)
+
+	go func() {
+		defer close(out)
+		for v := range in {
+			s.items.Add(1)
+			out <- v
+		}
+	}()
+	return out
+}
+
+// linkChunks connects the output of a stage to the next stage in chunks of up to n items, counting the
+// items passed
+func linkChunks[T any](s *runnerStage, in <-chan T, size, n int) <-chan []T {
+	out := make(chan []T, size)
+	fill := func() (int, int) { return len(out), cap(out) }
+	s.fill.Store(&fill)
+
+	go func() {
+		defer close(out)
+		for chunk := range chunks(in, n) {
+			s.items.Add(uint64(len(chunk)))
+			out <- chunk
+		}
+	}()
+	return out
+}
+
+// linkChunked connects the chunked output of a stage to the next stage, counting the items passed and
+// dropping empty chunks
+func linkChunked[T any](s *runnerStage, in <-chan []T, size int) <-chan []T {
+	out := make(chan []T, size)
+	fill := func() (int, int) { return len(out), cap(out) }
+	s.fill.Store(&fill)
+
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			if len(chunk) == 0 {
+				continue
+			}
+			s.items.Add(uint64(len(chunk)))
+			out <- chunk
+		}
+	}()
+	return out
+}
+
+// relay returns the input channel of a stage taking items of type I from the output channel of the
+// stage before it, held as any. It counts the items taken by the stage and records its latency when
+// an item has to wait for the stage to take it.
+func relay[I any](s *runnerStage, in any) <-chan I {
+	c := receiver[I](in)
+	return relayItems(s, func(yield func(I) bool) {
+		for v := range c {
+			if !yield(v) {
+				return
+			}
+		}
+	})
+}
+
+// relayChunks is relay for a stage taking items from the chunked output channel of the stage before it
+func relayChunks[I any](s *runnerStage, in any) <-chan I {
+	c := chunkReceiver[I](in)
+	return relayItems(s, func(yield func(I) bool) {
+		for chunk := range c {
+			for _, v := range chunk {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	})
+}
+
+// relayItems sends the items to the returned input channel of a stage, counting them and recording the
+// latency of the stage
+func relayItems[I any](s *runnerStage, items func(yield func(I) bool)) <-chan I {
+	out := make(chan I)
+	go func() {
+		defer close(out)
+		var last time.Time
+		for v := range items {
+			select {
+			case out <- v:
+			default:
+				// the stage is busy with the previous item until it takes this one
+				out <- v
+				if !last.IsZero() {
+					s.latency.observe(time.Since(last))
+				}
+			}
+			last = time.Now()
+			s.taken.Add(1)
+		}
+	}()
+	return out
+}
+
+// relayChunked is relay for a stage taking chunks, which records the latency of a chunk per item of the
+// chunk before it
+func relayChunked[I any](s *runnerStage, in any) <-chan []I {
+	c := chunkReceiver[I](in)
+	out := make(chan []I)
+	go func() {
+		defer close(out)
+		var last time.Time
+		var items int
+		for chunk := range c {
+			select {
+			case out <- chunk:
+			default:
+				// the stage is busy with the previous chunk until it takes this one
+				out <- chunk
+				if !last.IsZero() && items > 0 {
+					s.latency.observe(time.Since(last) / time.Duration(items))
+				}
+			}
+			last, items = time.Now(), len(chunk)
+			s.taken.Add(uint64(len(chunk)))
+		}
+	}()
+	return out
+}
+
+// receiver returns the channel of items of type I of the output channel of a stage, held as any,
+// converting the items when that stage emits a type implementing the interface I
+func receiver[I any](in any) <-chan I {
+	if c, ok := in.(<-chan I); ok {
+		return c
+	}
+
+	out := make(chan I)
+	go func() {
+		defer close(out)
+		c := reflect.ValueOf(in)
+		for {
+			v, ok := c.Recv()
+			if !ok {
+				return
+			}
+			out <- v.Interface().(I)
+		}
+	}()
+	return out
+}
+
+// chunkReceiver is receiver for the chunked output channel of a stage, held as any
+func chunkReceiver[I any](in any) <-chan []I {
+	if c, ok := in.(<-chan []I); ok {
+		return c
+	}
+
+	out := make(chan []I)
+	go func() {
+		defer close(out)
+		c := reflect.ValueOf(in)
+		for {
+			v, ok := c.Recv()
+			if !ok {
+				return
+			}
+			chunk := make([]I, v.Len())
+			for i := range chunk {
+				chunk[i] = v.Index(i).Interface().(I)
+			}
+			out <- chunk
+		}
+	}()
+	return out
+}
@@ -0,0 +1,202 @@
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// AddSource adds another source named name to the stages of the runner, making its pipeline a graph
+// whose stages name the stages they take the output of. Stages added after it with AddFlow or SetSink
+// take its output.
+func AddSource[T any](r *Runner, name string, src Source[T]) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.unique(name); err != nil {
+		return err
+	}
+	r.stages = append(r.stages, newSourceStage(r, name, src))
+	return nil
+}
+
+// AddFlowFrom adds a flow named name to the stages of the runner taking the output of the named stages
+// added before it, which are merged when there are several. The input type of the flow must be the
+// output type of each of them or an interface it implements. The output of a stage taken by several
+// stages is copied to each of them, so a stage holding back its items holds back the others.
+func AddFlowFrom[I, O any](r *Runner, name string, f Flow[I, O], inputs ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	in, err := r.inputs(name, reflect.TypeFor[I](), inputs)
+	if err != nil {
+		return err
+	}
+	r.stages = append(r.stages, newFlowStage(r, name, f, in))
+	return nil
+}
+
+// AddSinkFrom adds a sink named name to the stages of the runner taking the output of the named stages
+// added before it, as AddFlowFrom does. A runner can have several sinks, and its runs end when all of
+// them have returned.
+func AddSinkFrom[I any](r *Runner, name string, snk Sink[I], inputs ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	in, err := r.inputs(name, reflect.TypeFor[I](), inputs)
+	if err != nil {
+		return err
+	}
+	r.stages = append(r.stages, newSinkStage(r, name, snk, in))
+	r.sunk = true
+	return nil
+}
+
+// unique checks that no stage is named name
+func (r *Runner) unique(name string) error {
+	if slices.ContainsFunc(r.stages, func(s *runnerStage) bool { return s.name == name }) {
+		return fmt.Errorf("%w: stage name %s is already used", ErrRunner, name)
+	}
+	return nil
+}
+
+// inputs returns the indices of the named stages a stage named name taking items of type in takes the
+// output of. Stages can only take the output of stages added before them, so the stages are acyclic.
+func (r *Runner) inputs(name string, in reflect.Type, names []string) ([]int, error) {
+	if err := r.unique(name); err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: stage %s takes no stage", ErrRunner, name)
+	}
+
+	inputs := make([]int, len(names))
+	for k, input := range names {
+		i := slices.IndexFunc(r.stages, func(s *runnerStage) bool { return s.name == input })
+		switch {
+		case i < 0:
+			return nil, fmt.Errorf("%w: stage %s takes unknown stage %s", ErrRunner, name, input)
+		case r.stages[i].kind == StageSink:
+			return nil, fmt.Errorf("%w: stage %s takes sink %s", ErrRunner, name, input)
+		case slices.Contains(inputs[:k], i):
+			return nil, fmt.Errorf("%w: stage %s takes stage %s twice", ErrRunner, name, input)
+		case !accepts(r.stages[i].out, in):
+			return nil, fmt.Errorf("%w: stage %s takes %s, not the %s of stage %s", ErrRunner, name, in, r.stages[i].out, input)
+		}
+		inputs[k] = i
+	}
+	return inputs, nil
+}
+
+// connected checks that the stages have a sink and that the output of each source and flow is taken by
+// a stage, and returns the number of stages taking the output of each stage
+func (r *Runner) connected(stages []*runnerStage, sunk bool) ([]int, error) {
+	if !sunk {
+		return nil, fmt.Errorf("%w: %s has no sink", ErrRunner, r.name)
+	}
+
+	consumers := make([]int, len(stages))
+	for _, s := range stages {
+		for _, i := range s.inputs {
+			consumers[i]++
+		}
+	}
+	for i, s := range stages {
+		if s.kind != StageSink && consumers[i] == 0 {
+			return nil, fmt.Errorf("%w: %s has no stage taking the output of %s %s", ErrRunner, r.name, s.kind, s.name)
+		}
+	}
+	return consumers, nil
+}
+
+// fanOut returns the fan out of the output channel of a stage emitting items of type T, held as any, to
+// the n stages taking it. The chunks of a chunked output are copied for all stages but the last, as
+// chunks are owned by their receiver.
+func fanOut[T any](r *Runner) func(out any, n int) []any {
+	return func(out any, n int) []any {
+		if r.chunkSize > 1 {
+			return anys(broadcast(out.(<-chan []T), n, func(chunk []T) []T { return slices.Clone(chunk) }))
+		}
+		return anys(broadcast(out.(<-chan T), n, nil))
+	}
+}
+
+// fanIn returns the merge of the output channels of the stages a stage taking items of type I takes, held
+// as any, into its input channel held the same way
+func fanIn[I any](r *Runner) func(ins []any) any {
+	return func(ins []any) any {
+		if r.chunkSize > 1 {
+			cs := make([]<-chan []I, len(ins))
+			for i, in := range ins {
+				cs[i] = chunkReceiver[I](in)
+			}
+			return merge(cs)
+		}
+		cs := make([]<-chan I, len(ins))
+		for i, in := range ins {
+			cs[i] = receiver[I](in)
+		}
+		return merge(cs)
+	}
+}
+
+// broadcast sends the items of the input channel to each of the n returned channels, waiting for each of
+// them to take it, and closes them when the input channel is closed. Items are copied with clone, when
+// it is not nil, for all channels but the last, which is sent the item once it has been copied.
+func broadcast[T any](in <-chan T, n int, clone func(T) T) []<-chan T {
+	outs := make([]chan T, n)
+	branches := make([]<-chan T, n)
+	for i := range outs {
+		outs[i] = make(chan T)
+		branches[i] = outs[i]
+	}
+
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for v := range in {
+			for i, out := range outs {
+				if clone != nil && i < n-1 {
+					out <- clone(v)
+					continue
+				}
+				out <- v
+			}
+		}
+	}()
+	return branches
+}
+
+// merge sends the items of the input channels to the returned channel, which is closed when all of them
+// are closed
+func merge[T any](ins []<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range in {
+				out <- v
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// anys returns the channels held as any
+func anys[T any](cs []<-chan T) []any {
+	out := make([]any, len(cs))
+	for i, c := range cs {
+		out[i] = c
+	}
+	return out
+}
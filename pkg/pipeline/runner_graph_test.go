@@ -0,0 +1,159 @@
+package pipeline_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+)
+
+// sliceSource is a source sending its items
+type sliceSource[T any] []T
+
+func (s sliceSource[T]) Extract(context.Context, chan<- pipeline.Event) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		for _, v := range s {
+			out <- v
+		}
+	}()
+	return out
+}
+
+// collectSink is a sink collecting its items from concurrent inputs
+type collectSink[T any] struct {
+	mu    sync.Mutex
+	items []T
+}
+
+func (s *collectSink[T]) Load(in <-chan T, _ chan<- pipeline.Event) {
+	for v := range in {
+		s.mu.Lock()
+		s.items = append(s.items, v)
+		s.mu.Unlock()
+	}
+}
+
+func TestRunnerGraph(t *testing.T) {
+	for name, opts := range map[string][]pipeline.RunnerOption{
+		"items":  nil,
+		"chunks": {pipeline.WithChunkSize(4)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := pipeline.NewRunner("routing", sliceSource[int]{1, 2, 3, 4, 5}, opts...)
+
+			scale, err := flow.NewMap(func(v int) (int, error) { return v * 10, nil })
+			require.NoError(t, err)
+			alerts, err := flow.NewFilter(func(v int) bool { return v > 30 })
+			require.NoError(t, err)
+			require.NoError(t, pipeline.AddFlowFrom[int, int](r, "scale", scale, "source"))
+			require.NoError(t, pipeline.AddFlowFrom[int, int](r, "alerts", alerts, "scale"))
+
+			raw, archive, alerted := mock.NewSink[int](), mock.NewSink[int](), mock.NewSink[int]()
+			require.NoError(t, pipeline.AddSinkFrom[int](r, "raw", raw, "source"))
+			require.NoError(t, pipeline.AddSinkFrom[int](r, "archive", archive, "scale"))
+			require.NoError(t, pipeline.AddSinkFrom[int](r, "alert", alerted, "alerts"))
+
+			for e := range r.Run(context.Background()) {
+				assert.NotEqual(t, pipeline.EventError, e.Type(), e.String())
+			}
+			raw.AssertItems(t, []int{1, 2, 3, 4, 5})
+			archive.AssertItems(t, []int{10, 20, 30, 40, 50})
+			alerted.AssertItems(t, []int{40, 50})
+
+			var names []string
+			for _, s := range r.Stats() {
+				names = append(names, s.Name)
+			}
+			assert.Equal(t, []string{"source", "scale", "alerts", "raw", "archive", "alert"}, names)
+			assert.Equal(t, uint64(5), r.Stats()[0].ItemsOut, "items copied to several stages are counted once")
+		})
+	}
+}
+
+func TestRunnerGraphJoin(t *testing.T) {
+	r := pipeline.NewRunner("join", sliceSource[int]{1, 2, 3})
+	require.NoError(t, pipeline.AddSource[int](r, "more", sliceSource[int]{4, 5}))
+
+	double, err := flow.NewMap(func(v int) (int, error) { return v * 2, nil })
+	require.NoError(t, err)
+	require.NoError(t, pipeline.AddFlowFrom[int, int](r, "double", double, "source", "more"))
+
+	// the join takes the items of a flow and a source as an interface they implement
+	joined := &collectSink[any]{}
+	require.NoError(t, pipeline.AddSinkFrom[any](r, "joined", joined, "double", "more"))
+
+	for e := range r.Run(context.Background()) {
+		assert.NotEqual(t, pipeline.EventError, e.Type(), e.String())
+	}
+	assert.ElementsMatch(t, []any{2, 4, 6, 8, 10, 4, 5}, joined.items)
+
+	stats := r.Stats()
+	assert.Equal(t, uint64(5), stats[2].ItemsIn)
+	assert.Equal(t, uint64(7), stats[3].ItemsIn)
+}
+
+func TestRunnerGraphInvalid(t *testing.T) {
+	r := pipeline.NewRunner("test", sliceSource[int]{1})
+	noop := mock.NewSink[int]()
+
+	assert.ErrorIs(t, pipeline.AddSource[int](r, "source", sliceSource[int]{1}), pipeline.ErrRunner, "name already used")
+	assert.ErrorIs(t, pipeline.AddSinkFrom[int](r, "sink", noop), pipeline.ErrRunner, "no inputs")
+	assert.ErrorIs(t, pipeline.AddSinkFrom[int](r, "sink", noop, "missing"), pipeline.ErrRunner, "unknown stage")
+	assert.ErrorIs(t, pipeline.AddSinkFrom[int](r, "sink", noop, "source", "source"), pipeline.ErrRunner, "stage taken twice")
+	assert.ErrorIs(t, pipeline.AddSinkFrom[string](r, "sink", mock.NewSink[string](), "source"), pipeline.ErrRunner, "type mismatch")
+
+	require.NoError(t, pipeline.AddSinkFrom[int](r, "sink", noop, "source"))
+	assert.ErrorIs(t, pipeline.AddSinkFrom[int](r, "after", noop, "sink"), pipeline.ErrRunner, "sinks have no output")
+	assert.ErrorIs(t, pipeline.AddFlow[int, int](r, "late", flow.NewPassthrough[int]()), pipeline.ErrRunner)
+}
+
+func TestRunnerGraphUnconnected(t *testing.T) {
+	r := pipeline.NewRunner("test", sliceSource[int]{1})
+	require.NoError(t, pipeline.AddFlowFrom[int, int](r, "dangling", flow.NewPassthrough[int](), "source"))
+	require.NoError(t, pipeline.AddSinkFrom[int](r, "sink", mock.NewSink[int](), "source"))
+
+	var events []pipeline.Event
+	for e := range r.Run(context.Background()) {
+		events = append(events, e)
+	}
+	if assert.Len(t, events, 1) {
+		assert.ErrorIs(t, events[0].(pipeline.ErrorEvent), pipeline.ErrRunner)
+		assert.Contains(t, events[0].String(), "dangling")
+	}
+}
+
+// scribbleSink is a chunk sink counting its items and overwriting the chunks it owns
+type scribbleSink struct{ n int }
+
+func (s *scribbleSink) Load(in <-chan int, _ chan<- pipeline.Event) {
+	for range in {
+		s.n++
+	}
+}
+
+func (s *scribbleSink) LoadChunks(in <-chan []int, _ chan<- pipeline.Event) {
+	for chunk := range in {
+		s.n += len(chunk)
+		clear(chunk)
+	}
+}
+
+func TestRunnerGraphChunkCopies(t *testing.T) {
+	r := pipeline.NewRunner("test", sliceSource[int]{1, 2, 3, 4, 5}, pipeline.WithChunkSize(8))
+	scribble, kept := &scribbleSink{}, &collectSink[int]{}
+	require.NoError(t, pipeline.AddSinkFrom[int](r, "scribble", scribble, "source"))
+	require.NoError(t, pipeline.AddSinkFrom[int](r, "kept", kept, "source"))
+
+	for range r.Run(context.Background()) {
+	}
+	assert.Equal(t, 5, scribble.n)
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, kept.items, "a chunk is not shared by the stages taking it")
+}
@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// latencyWindowSize is the number of recent latencies the percentiles of a stage are computed from
+const latencyWindowSize = 1024
+
+// StageStats is a snapshot of the statistics of a stage of a Runner.
+type StageStats struct {
+	Name     string
+	Kind     StageKind
+	ItemsIn  uint64       // items taken from the previous stage
+	ItemsOut uint64       // items sent to the next stage
+	Errors   uint64       // error events sent by the stage
+	Latency  LatencyStats // processing latency of the stage
+	Queued   int          // items waiting in the channel to the next stage
+	Capacity int          // buffer size of the channel to the next stage
+}
+
+// LatencyStats is the processing latency of a stage, the time from taking an item to taking the next one.
+// It is sampled when the next item had to wait for the stage, so it is the time the stage takes per item
+// while busy, including the time its output waits for the next stage. The mean is that of the run, the
+// percentiles those of the latest 1024 latencies.
+type LatencyStats struct {
+	Count uint64
+	Mean  time.Duration
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+}
+
+// latencyWindow records the latencies of a stage
+type latencyWindow struct {
+	mu     sync.Mutex
+	count  uint64
+	sum    time.Duration
+	recent []time.Duration // ring of the latest latencies
+}
+
+// observe records a latency
+func (w *latencyWindow) observe(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.recent == nil {
+		w.recent = make([]time.Duration, 0, latencyWindowSize)
+	}
+	if len(w.recent) < latencyWindowSize {
+		w.recent = append(w.recent, d)
+	} else {
+		w.recent[w.count%latencyWindowSize] = d
+	}
+	w.count++
+	w.sum += d
+}
+
+// stats returns the mean and percentiles of the latencies
+func (w *latencyWindow) stats() LatencyStats {
+	w.mu.Lock()
+	recent := slices.Clone(w.recent)
+	stats := LatencyStats{Count: w.count}
+	if w.count > 0 {
+		stats.Mean = w.sum / time.Duration(w.count)
+	}
+	w.mu.Unlock()
+
+	if len(recent) == 0 {
+		return stats
+	}
+	slices.Sort(recent)
+	// nearest rank percentiles
+	percentile := func(p int) time.Duration {
+		return recent[(len(recent)*p+99)/100-1]
+	}
+	stats.P50, stats.P90, stats.P99 = percentile(50), percentile(90), percentile(99)
+	return stats
+}
+
+// reset clears the latencies
+func (w *latencyWindow) reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.count, w.sum, w.recent = 0, 0, nil
+}
+
+// reset clears the statistics of a stage for a new run
+func (s *runnerStage) reset() {
+	s.taken.Store(0)
+	s.items.Store(0)
+	s.errors.Store(0)
+	s.latency.reset()
+}
@@ -0,0 +1,450 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+// countingObserver counts the calls of a runner's observer
+type countingObserver struct {
+	starts, events, stops int
+}
+
+func (o *countingObserver) Start(context.Context, *pipeline.Runner)  { o.starts++ }
+func (o *countingObserver) Observe(*pipeline.Runner, pipeline.Event) { o.events++ }
+func (o *countingObserver) Stop(*pipeline.Runner)                    { o.stops++ }
+
+func newRunnerSource() *mock.SourceImpl {
+	return mock.NewSourceImpl([]mock.ReadableImpl{
+		mock.NewReadableImpl([]byte("a")),
+		mock.NewReadableImpl([]byte("bb")),
+		mock.NewReadableImpl([]byte("ccc")),
+	})
+}
+
+func TestRunner(t *testing.T) {
+	r := pipeline.NewRunner("test", newRunnerSource(), pipeline.WithLinkBuffer(4))
+
+	length, err := flow.NewMap(func(in mock.ReadableImpl) (int, error) {
+		b, err := in.Read()
+		return len(b), err
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, pipeline.AddFlow[mock.ReadableImpl, int](r, "length", length))
+
+	lengths := mock.NewSink[int]()
+	assert.NoError(t, pipeline.SetSink[int](r, "sum", lengths))
+
+	o := &countingObserver{}
+	r.Attach(o)
+
+	var events int
+	for range r.Run(context.Background()) {
+		events++
+	}
+
+	lengths.AssertItems(t, []int{1, 2, 3})
+	lengths.AssertClosed(t, time.Second)
+	assert.Equal(t, 3, events, "one log event per extracted item")
+	assert.Equal(t, 1, o.starts)
+	assert.Equal(t, 3, o.events)
+	assert.Equal(t, 1, o.stops)
+
+	stats := r.Stats()
+	assert.Len(t, stats, 3)
+	assert.Equal(t, pipeline.StageStats{Name: "source", Kind: pipeline.StageSource, ItemsOut: 3, Capacity: 4}, stats[0])
+
+	// latencies are only sampled when an item waits for the stage
+	latency := stats[1].Latency
+	assert.LessOrEqual(t, latency.Count, uint64(2))
+	assert.LessOrEqual(t, latency.P50, latency.P99)
+	stats[1].Latency = pipeline.LatencyStats{}
+	assert.Equal(t, pipeline.StageStats{Name: "length", Kind: pipeline.StageFlow, ItemsIn: 3, ItemsOut: 3, Capacity: 4}, stats[1])
+
+	stats[2].Latency = pipeline.LatencyStats{}
+	assert.Equal(t, pipeline.StageStats{Name: "sum", Kind: pipeline.StageSink, ItemsIn: 3}, stats[2])
+}
+
+func TestRunnerStats(t *testing.T) {
+	r := pipeline.NewRunner("test", newRunnerSource())
+
+	slow, err := flow.NewMap(func(in mock.ReadableImpl) (mock.ReadableImpl, error) {
+		b, _ := in.Read()
+		if len(b) == 2 {
+			return in, errors.New("even")
+		}
+		time.Sleep(5 * time.Millisecond)
+		return in, nil
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, pipeline.AddFlow[mock.ReadableImpl, mock.ReadableImpl](r, "slow", slow))
+	assert.NoError(t, pipeline.SetSink[any](r, "noop", sink.Noop{}))
+
+	for range r.Run(context.Background()) {
+	}
+
+	stats := r.Stats()
+	assert.Equal(t, uint64(0), stats[0].Errors)
+	assert.Equal(t, uint64(1), stats[1].Errors)
+	assert.Equal(t, uint64(2), stats[1].ItemsOut)
+	// the item after the first waits while the first is processed
+	assert.GreaterOrEqual(t, stats[1].Latency.Count, uint64(1))
+	assert.GreaterOrEqual(t, stats[1].Latency.P99, 5*time.Millisecond)
+}
+
+func TestRunnerInterfaceInput(t *testing.T) {
+	r := pipeline.NewRunner("test", newRunnerSource())
+	assert.NoError(t, pipeline.SetSink[any](r, "noop", sink.Noop{}))
+
+	for range r.Run(context.Background()) {
+	}
+	assert.Equal(t, uint64(3), r.Stats()[1].ItemsIn)
+}
+
+func TestRunnerChannels(t *testing.T) {
+	r := pipeline.NewRunner("test", mock.NewChannelSourceOf(1, 2, 3))
+
+	double, err := flow.NewMap(func(in int) (int, error) { return in * 2, nil })
+	assert.NoError(t, err)
+	assert.NoError(t, pipeline.AddFlow[int, int](r, "double", double))
+
+	out := make(chan int, 3)
+	assert.NoError(t, pipeline.SetSink[int](r, "out", mock.NewChannelSink(out)))
+
+	for range r.Run(context.Background()) {
+	}
+
+	var got []int
+	for v := range out {
+		got = append(got, v)
+	}
+	assert.Equal(t, []int{2, 4, 6}, got)
+}
+
+func TestRunnerChunks(t *testing.T) {
+	r := pipeline.NewRunner("test", mock.NewChannelSourceOf(1, 2, 3, 4, 5, 6, 7, 8, 9, 10), pipeline.WithChunkSize(4))
+
+	// a flow that takes items one at a time, then one that takes chunks
+	double, err := flow.NewMap(func(in int) (int, error) { return in * 2, nil })
+	assert.NoError(t, err)
+	assert.NoError(t, pipeline.AddFlow[int, int](r, "double", mock.NewFlow(mock.FlowConfig[int, int]{})))
+	assert.NoError(t, pipeline.AddFlow[int, int](r, "chunked", double))
+
+	doubled := mock.NewSink[any]()
+	assert.NoError(t, pipeline.SetSink[any](r, "doubled", doubled))
+
+	for range r.Run(context.Background()) {
+	}
+
+	doubled.AssertItems(t, []any{2, 4, 6, 8, 10, 12, 14, 16, 18, 20})
+	for _, s := range r.Stats() {
+		if s.Kind != pipeline.StageSource {
+			assert.Equal(t, uint64(10), s.ItemsIn, s.Name)
+		}
+		if s.Kind != pipeline.StageSink {
+			assert.Equal(t, uint64(10), s.ItemsOut, s.Name)
+		}
+	}
+}
+
+// emptyChunks is a chunk flow passing the chunks with an empty chunk before each of them
+type emptyChunks struct{}
+
+func (emptyChunks) Transform(in <-chan int, _ chan<- pipeline.Event) <-chan int { return in }
+
+func (emptyChunks) TransformChunks(in <-chan []int, _ chan<- pipeline.Event) <-chan []int {
+	out := make(chan []int)
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			out <- []int{}
+			out <- chunk
+		}
+	}()
+	return out
+}
+
+// slowChunks is a chunk flow passing the chunks after a delay per chunk
+type slowChunks struct{}
+
+func (slowChunks) Transform(in <-chan int, _ chan<- pipeline.Event) <-chan int { return in }
+
+func (slowChunks) TransformChunks(in <-chan []int, _ chan<- pipeline.Event) <-chan []int {
+	out := make(chan []int)
+	go func() {
+		defer close(out)
+		for chunk := range in {
+			time.Sleep(2 * time.Millisecond)
+			out <- chunk
+		}
+	}()
+	return out
+}
+
+func TestRunnerEmptyChunks(t *testing.T) {
+	r := pipeline.NewRunner("test", mock.NewChannelSourceOf(1, 2, 3, 4, 5, 6), pipeline.WithChunkSize(2))
+	assert.NoError(t, pipeline.AddFlow[int, int](r, "empty", emptyChunks{}))
+	assert.NoError(t, pipeline.AddFlow[int, int](r, "slow", slowChunks{}))
+
+	s := mock.NewSink[int]()
+	assert.NoError(t, pipeline.SetSink[int](r, "out", s))
+
+	for range r.Run(context.Background()) {
+	}
+
+	s.AssertItems(t, []int{1, 2, 3, 4, 5, 6})
+	assert.Equal(t, uint64(6), r.Stats()[2].ItemsIn)
+}
+
+func TestRunnerTypeMismatch(t *testing.T) {
+	r := pipeline.NewRunner("test", newRunnerSource())
+
+	err := pipeline.SetSink[int](r, "sum", sinkFunc[int](func(int) {}))
+	assert.ErrorIs(t, err, pipeline.ErrRunner)
+
+	assert.NoError(t, pipeline.SetSink[any](r, "noop", sink.Noop{}))
+	err = pipeline.AddFlow[any, any](r, "late", flow.NewPassthrough[any]())
+	assert.ErrorIs(t, err, pipeline.ErrRunner)
+}
+
+func TestRunnerDuplicateName(t *testing.T) {
+	r := pipeline.NewRunner("test", newRunnerSource())
+
+	err := pipeline.AddFlow[any, any](r, "source", flow.NewPassthrough[any]())
+	assert.ErrorIs(t, err, pipeline.ErrRunner)
+
+	assert.NoError(t, pipeline.AddFlow[any, any](r, "pass", flow.NewPassthrough[any]()))
+	err = pipeline.AddFlow[any, any](r, "pass", flow.NewPassthrough[any]())
+	assert.ErrorIs(t, err, pipeline.ErrRunner)
+
+	err = pipeline.SetSink[any](r, "pass", sink.Noop{})
+	assert.ErrorIs(t, err, pipeline.ErrRunner)
+	assert.NoError(t, pipeline.SetSink[any](r, "noop", sink.Noop{}))
+	assert.Len(t, r.Stats(), 3)
+}
+
+func TestRunnerWithoutSink(t *testing.T) {
+	r := pipeline.NewRunner("test", newRunnerSource())
+
+	var events []pipeline.Event
+	for e := range r.Run(context.Background()) {
+		events = append(events, e)
+	}
+	assert.Len(t, events, 1)
+	assert.Equal(t, pipeline.EventError, events[0].Type())
+}
+
+func TestRunnerAsyncSink(t *testing.T) {
+	r := pipeline.NewRunner("test", newRunnerSource())
+
+	length, err := flow.NewMap(func(in mock.ReadableImpl) (int, error) {
+		b, err := in.Read()
+		return len(b), err
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, pipeline.AddFlow[mock.ReadableImpl, int](r, "length", length))
+
+	async := &asyncSink{}
+	assert.NoError(t, pipeline.SetSink[int](r, "async", async))
+
+	var published int
+	for e := range r.Run(context.Background()) {
+		if m, ok := e.(pipeline.MetricEvent); ok && m.Name() == "published" {
+			published++
+		}
+	}
+
+	// the events channel is closed only once the sink resolved its publishes
+	assert.Equal(t, 3, published)
+	assert.Equal(t, []int{1, 2, 3}, async.items())
+}
+
+// sinkFunc is a sink calling a function with each item
+type sinkFunc[I any] func(I)
+
+func (f sinkFunc[I]) Load(in <-chan I, _ chan<- pipeline.Event) {
+	for v := range in {
+		f(v)
+	}
+}
+
+// asyncSink publishes its items in the background, sending a metric event once an item is published,
+// and waits for the publishes to resolve before its Load returns. When release is set, each publish
+// waits for it to be closed.
+type asyncSink struct {
+	release <-chan struct{}
+
+	mu        sync.Mutex
+	published []int
+}
+
+// items returns the published items
+func (s *asyncSink) items() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.published...)
+}
+
+func (s *asyncSink) Load(in <-chan int, eventC chan<- pipeline.Event) {
+	queue := make(chan int, 8)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for v := range queue {
+			if s.release != nil {
+				<-s.release
+			}
+			time.Sleep(time.Millisecond)
+			s.mu.Lock()
+			s.published = append(s.published, v)
+			s.mu.Unlock()
+			pipeline.SendEvent(eventC, pipeline.NewMetricEvent("published", 1, nil, pipeline.MetricTypeCounter))
+		}
+	}()
+
+	for v := range in {
+		queue <- v
+	}
+	close(queue)
+	<-done
+}
+
+// holdSource is a source sending its items and holding its output open until its context is done
+type holdSource []int
+
+func (s holdSource) Extract(ctx context.Context, _ chan<- pipeline.Event) <-chan int {
+	out := make(chan int)
+	go func() {
+		defer close(out)
+		for _, v := range s {
+			out <- v
+		}
+		<-ctx.Done()
+	}()
+	return out
+}
+
+func TestRunnerDrain(t *testing.T) {
+	r := pipeline.NewRunner("test", holdSource{1, 2, 3}, pipeline.WithLinkBuffer(4), pipeline.WithDrainTimeout(time.Second))
+
+	var got []int
+	extracted := make(chan struct{})
+	assert.NoError(t, pipeline.SetSink[int](r, "slow", sinkFunc[int](func(v int) {
+		if v == 1 {
+			<-extracted
+		}
+		time.Sleep(10 * time.Millisecond)
+		got = append(got, v)
+	})))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := r.Run(ctx)
+	assert.Eventually(t, func() bool { return r.Stats()[0].ItemsOut == 3 }, time.Second, time.Millisecond)
+	cancel()
+	close(extracted)
+
+	for e := range events {
+		assert.NotEqual(t, pipeline.EventError, e.Type())
+	}
+	assert.Equal(t, []int{1, 2, 3}, got, "items extracted before the cancellation are flushed to the sink")
+}
+
+func TestRunnerDrainTimeout(t *testing.T) {
+	r := pipeline.NewRunner("test", holdSource{1}, pipeline.WithDrainTimeout(20*time.Millisecond))
+
+	release := make(chan struct{})
+	assert.NoError(t, pipeline.SetSink[int](r, "stuck", sinkFunc[int](func(int) { <-release })))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := r.Run(ctx)
+	assert.Eventually(t, func() bool { return r.Stats()[1].ItemsIn == 1 }, time.Second, time.Millisecond)
+	cancel()
+
+	var errs []pipeline.ErrorEvent
+	for e := range events {
+		if e, ok := e.(pipeline.ErrorEvent); ok {
+			errs = append(errs, e)
+		}
+	}
+	if assert.Len(t, errs, 1) {
+		assert.ErrorIs(t, errs[0], pipeline.ErrRunner)
+		assert.False(t, errs[0].IsTemporary())
+	}
+
+	// the abandoned stages keep the runner running until they return
+	for e := range r.Run(context.Background()) {
+		assert.Equal(t, pipeline.EventError, e.Type())
+	}
+	close(release)
+	assert.Eventually(t, func() bool {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		for e := range r.Run(ctx) {
+			if e.Type() == pipeline.EventError {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestRunnerDrainAsyncSink(t *testing.T) {
+	r := pipeline.NewRunner("test", holdSource{1, 2, 3}, pipeline.WithLinkBuffer(4), pipeline.WithDrainTimeout(time.Second))
+
+	async := &asyncSink{}
+	assert.NoError(t, pipeline.SetSink[int](r, "async", async))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := r.Run(ctx)
+	assert.Eventually(t, func() bool { return r.Stats()[0].ItemsOut == 3 }, time.Second, time.Millisecond)
+	cancel()
+
+	var published int
+	for e := range events {
+		assert.NotEqual(t, pipeline.EventError, e.Type())
+		if m, ok := e.(pipeline.MetricEvent); ok && m.Name() == "published" {
+			published++
+		}
+	}
+	assert.Equal(t, 3, published, "the publishes of the flushed items resolve before the events are closed")
+	assert.Equal(t, []int{1, 2, 3}, async.items())
+}
+
+func TestRunnerDrainTimeoutAsyncSink(t *testing.T) {
+	r := pipeline.NewRunner("test", holdSource{1}, pipeline.WithDrainTimeout(20*time.Millisecond))
+
+	release := make(chan struct{})
+	async := &asyncSink{release: release}
+	assert.NoError(t, pipeline.SetSink[int](r, "async", async))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := r.Run(ctx)
+	assert.Eventually(t, func() bool { return r.Stats()[1].ItemsIn == 1 }, time.Second, time.Millisecond)
+	cancel()
+
+	var errs, published int
+	for e := range events {
+		if e.Type() == pipeline.EventError {
+			errs++
+		}
+		if m, ok := e.(pipeline.MetricEvent); ok && m.Name() == "published" {
+			published++
+		}
+	}
+	assert.Equal(t, 1, errs)
+	assert.Zero(t, published)
+
+	// the publish resolving after the run was abandoned does not send on the closed events
+	close(release)
+	assert.Eventually(t, func() bool { return len(async.items()) == 1 }, time.Second, time.Millisecond)
+}
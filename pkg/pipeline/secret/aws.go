@@ -0,0 +1,95 @@
+package secret
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// Ensure that AWS implements the Provider interface.
+var _ Provider = (*AWS)(nil)
+
+// AWSConfig is the configuration for the AWS Secrets Manager provider.
+type AWSConfig struct {
+	Region   string // optional region, the default AWS config chain is used when empty
+	Endpoint string // optional endpoint override, e.g. for local testing
+	Prefix   string // optional prefix of the secret ids, e.g. "prod/krapht/"
+}
+
+// secretsManagerAPI is the subset of the Secrets Manager client used by the AWS provider
+type secretsManagerAPI interface {
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// AWS is a provider reading secrets from AWS Secrets Manager. The name of a secret is its id, optionally
+// followed by the key read from a secret holding a JSON object, such as "nats#password".
+type AWS struct {
+	client secretsManagerAPI
+	prefix string
+}
+
+// NewAWS creates a new AWS Secrets Manager provider with the given configuration.
+func NewAWS(conf AWSConfig) (*AWS, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if conf.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(conf.Region))
+	}
+
+	awsConf, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to load aws config: %w", ErrSecret, err)
+	}
+
+	client := secretsmanager.NewFromConfig(awsConf, func(o *secretsmanager.Options) {
+		if conf.Endpoint != "" {
+			o.BaseEndpoint = aws.String(conf.Endpoint)
+		}
+	})
+	return newAWS(client, conf), nil
+}
+
+// newAWS creates an AWS Secrets Manager provider using an existing client
+func newAWS(client secretsManagerAPI, conf AWSConfig) *AWS {
+	return &AWS{
+		client: client,
+		prefix: conf.Prefix,
+	}
+}
+
+// Secret returns the string of the secret of the id of the name, or the key of its JSON object.
+func (a *AWS) Secret(ctx context.Context, name string) (string, error) {
+	id, key, hasKey := strings.Cut(name, "#")
+	id = a.prefix + id
+
+	out, err := a.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(id)})
+	if notFound := (*types.ResourceNotFoundException)(nil); errors.As(err, &notFound) {
+		return "", fmt.Errorf("%w: aws secret %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: aws secret %s: %w", ErrSecret, id, err)
+	}
+
+	var value string
+	switch {
+	case out.SecretString != nil:
+		value = *out.SecretString
+	case out.SecretBinary != nil:
+		value = string(out.SecretBinary)
+	}
+	if !hasKey {
+		return value, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("%w: aws secret %s is not a JSON object: %w", ErrSecret, id, err)
+	}
+	return fieldValue(fields, key, "aws secret "+id)
+}
@@ -0,0 +1,47 @@
+package secret
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSecretsManager returns the secrets of a map by id
+type fakeSecretsManager map[string]string
+
+func (f fakeSecretsManager) GetSecretValue(_ context.Context, params *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	v, ok := f[*params.SecretId]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("not found")}
+	}
+	return &secretsmanager.GetSecretValueOutput{SecretString: aws.String(v)}, nil
+}
+
+func TestAWS_Secret(t *testing.T) {
+	a := newAWS(fakeSecretsManager{
+		"prod/token": "secret",
+		"prod/nats":  `{"user": "krapht", "password": "pw"}`,
+	}, AWSConfig{Prefix: "prod/"})
+	ctx := context.Background()
+
+	v, err := a.Secret(ctx, "token")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", v)
+
+	v, err = a.Secret(ctx, "nats#password")
+	assert.NoError(t, err)
+	assert.Equal(t, "pw", v)
+
+	_, err = a.Secret(ctx, "nats#missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = a.Secret(ctx, "token#key")
+	assert.ErrorIs(t, err, ErrSecret)
+
+	_, err = a.Secret(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
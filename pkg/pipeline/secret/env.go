@@ -0,0 +1,72 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Ensure that Env and File implement the Provider interface.
+var (
+	_ Provider = (*Env)(nil)
+	_ Provider = (*File)(nil)
+)
+
+// Env is a provider reading secrets from environment variables. The variable of a secret is the
+// prefix followed by the upper cased name, with dashes, dots and slashes replaced by underscores, so
+// the secret nats-token with the prefix KRAPHT_ is read from KRAPHT_NATS_TOKEN.
+type Env struct {
+	prefix string
+}
+
+// NewEnv creates a new environment variable provider with the given variable prefix.
+func NewEnv(prefix string) *Env {
+	return &Env{
+		prefix: prefix,
+	}
+}
+
+// envReplacer maps the characters of secret names that are not valid in variable names
+var envReplacer = strings.NewReplacer("-", "_", ".", "_", "/", "_")
+
+// Secret returns the value of the variable of the secret.
+func (e Env) Secret(_ context.Context, name string) (string, error) {
+	key := e.prefix + strings.ToUpper(envReplacer.Replace(name))
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("%w: environment variable %s is not set", ErrNotFound, key)
+	}
+	return v, nil
+}
+
+// File is a provider reading secrets from the files of a directory, such as the secrets mounted by
+// Kubernetes or Docker, the secret of a name being the content of the file of the name without its
+// trailing newline.
+type File struct {
+	dir string
+}
+
+// NewFile creates a new file provider reading the files of the directory.
+func NewFile(dir string) *File {
+	return &File{
+		dir: dir,
+	}
+}
+
+// Secret returns the content of the file of the secret.
+func (f File) Secret(_ context.Context, name string) (string, error) {
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w: invalid secret name %s", ErrSecret, name)
+	}
+
+	b, err := os.ReadFile(filepath.Join(f.dir, name))
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSecret, err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,170 @@
+/*
+Package secret resolves the secrets referenced by component configurations, so credentials never live
+in plaintext configuration.
+
+A configuration references a secret by name as ${secret:name} in any string value, which is replaced
+by the value of the secret from a Provider. Providers read secrets from environment variables, files,
+HashiCorp Vault and AWS Secrets Manager, and can be chained or selected by a scheme prefix of the name:
+
+	p := secret.Mux{"env": secret.NewEnv("KRAPHT_"), "vault": vault}
+	conf, err := secret.Resolve(ctx, p, json.RawMessage(`{"url": "nats://krapht:${secret:vault:nats#password}@nats:4222"}`))
+*/
+package secret
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrNotFound is returned when a provider has no secret of a name.
+var ErrNotFound = errors.New("secret not found")
+
+// ErrSecret is returned when a secret reference cannot be resolved.
+var ErrSecret = errors.New("secret error")
+
+// reference is the start of a secret reference, which ends with a closing brace
+const reference = "${secret:"
+
+// Provider is the interface of the stores secrets are read from.
+type Provider interface {
+	// Secret returns the value of the secret of the name, or ErrNotFound when there is none
+	Secret(ctx context.Context, name string) (string, error)
+}
+
+// ProviderFunc is a function implementing the Provider interface.
+type ProviderFunc func(ctx context.Context, name string) (string, error)
+
+// Secret returns the value of the secret of the name.
+func (f ProviderFunc) Secret(ctx context.Context, name string) (string, error) {
+	return f(ctx, name)
+}
+
+// Ensure that the combined providers implement the Provider interface.
+var (
+	_ Provider = Chain(nil)
+	_ Provider = Mux(nil)
+)
+
+// Chain is a provider looking secrets up in its providers in order, returning the first found.
+type Chain []Provider
+
+// Secret returns the value of the secret of the first provider having it.
+func (c Chain) Secret(ctx context.Context, name string) (string, error) {
+	for _, p := range c {
+		v, err := p.Secret(ctx, name)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		return v, err
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Mux is a provider selecting a provider by the scheme prefix of a secret name, a name such as
+// "vault:nats#password" being looked up as "nats#password" in the provider of the vault scheme. Names
+// without a known scheme are looked up in the provider of the empty scheme, when there is one.
+type Mux map[string]Provider
+
+// Secret returns the value of the secret from the provider of the scheme of the name.
+func (m Mux) Secret(ctx context.Context, name string) (string, error) {
+	if scheme, rest, ok := strings.Cut(name, ":"); ok {
+		if p, ok := m[scheme]; ok {
+			return p.Secret(ctx, rest)
+		}
+	}
+	if p, ok := m[""]; ok {
+		return p.Secret(ctx, name)
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// Expand replaces the secret references of a string with the values of the secrets. A reference is
+// escaped by doubling its dollar sign, $${secret:name} expanding to ${secret:name}.
+func Expand(ctx context.Context, p Provider, s string) (string, error) {
+	if !strings.Contains(s, reference) {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, reference)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1])
+			b.WriteString(reference)
+			s = s[i+len(reference):]
+			continue
+		}
+
+		b.WriteString(s[:i])
+		s = s[i+len(reference):]
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", fmt.Errorf("%w: unterminated reference %s%s", ErrSecret, reference, s)
+		}
+		name := s[:end]
+		if name == "" {
+			return "", fmt.Errorf("%w: reference without a name", ErrSecret)
+		}
+		v, err := p.Secret(ctx, name)
+		if err != nil {
+			return "", fmt.Errorf("%w: could not resolve secret %s: %w", ErrSecret, name, err)
+		}
+		b.WriteString(v)
+		s = s[end+1:]
+	}
+}
+
+// Resolve replaces the secret references of the string values of a JSON configuration with the values
+// of the secrets, which are encoded as JSON strings. The configuration is returned unchanged when it
+// has no references.
+func Resolve(ctx context.Context, p Provider, conf json.RawMessage) (json.RawMessage, error) {
+	if !bytes.Contains(conf, []byte(reference)) {
+		return conf, nil
+	}
+
+	d := json.NewDecoder(bytes.NewReader(conf))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSecret, err)
+	}
+
+	v, err := resolve(ctx, p, v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// resolve replaces the secret references of the strings of a decoded JSON value
+func resolve(ctx context.Context, p Provider, v any) (any, error) {
+	switch v := v.(type) {
+	case string:
+		return Expand(ctx, p, v)
+	case []any:
+		for i, e := range v {
+			r, err := resolve(ctx, p, e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = r
+		}
+	case map[string]any:
+		for k, e := range v {
+			r, err := resolve(ctx, p, e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = r
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,138 @@
+package secret
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Ensure that Vault implements the Provider interface.
+var _ Provider = (*Vault)(nil)
+
+// VaultConfig is the configuration for the Vault provider.
+type VaultConfig struct {
+	Addr      string      // server address, defaults to the VAULT_ADDR environment variable
+	Token     string      // token, defaults to the VAULT_TOKEN environment variable
+	Namespace string      // optional namespace of the secrets
+	TLS       *tls.Config // optional TLS configuration
+
+	Mount string // mount path of the KV version 2 secrets engine, defaults to "secret"
+	Field string // field of a secret read when the name has none, defaults to "value"
+
+	Timeout time.Duration // request timeout, defaults to 10 seconds
+}
+
+// Vault is a provider reading secrets from the KV version 2 secrets engine of HashiCorp Vault. The name
+// of a secret is its path, optionally followed by the field read, such as "nats#password".
+type Vault struct {
+	client    *http.Client
+	addr      string
+	token     string
+	namespace string
+	mount     string
+	field     string
+}
+
+// NewVault creates a new Vault provider with the given configuration.
+func NewVault(conf VaultConfig) (*Vault, error) {
+	if conf.Addr == "" {
+		conf.Addr = os.Getenv("VAULT_ADDR")
+	}
+	if conf.Addr == "" {
+		return nil, fmt.Errorf("%w: vault address is empty", ErrSecret)
+	}
+
+	if conf.Token == "" {
+		conf.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if conf.Token == "" {
+		return nil, fmt.Errorf("%w: vault token is empty", ErrSecret)
+	}
+
+	if conf.Mount == "" {
+		conf.Mount = "secret"
+	}
+
+	if conf.Field == "" {
+		conf.Field = "value"
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 10 * time.Second
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = conf.TLS
+
+	return &Vault{
+		client:    &http.Client{Timeout: conf.Timeout, Transport: transport},
+		addr:      strings.TrimSuffix(conf.Addr, "/"),
+		token:     conf.Token,
+		namespace: conf.Namespace,
+		mount:     strings.Trim(conf.Mount, "/"),
+		field:     conf.Field,
+	}, nil
+}
+
+// Secret returns the field of the secret at the path of the name.
+func (v *Vault) Secret(ctx context.Context, name string) (string, error) {
+	path, field, ok := strings.Cut(name, "#")
+	if !ok {
+		field = v.field
+	}
+
+	u := v.addr + "/v1/" + v.mount + "/data/" + (&url.URL{Path: strings.Trim(path, "/")}).EscapedPath()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSecret, err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("%w: vault request failed: %w", ErrSecret, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", fmt.Errorf("%w: vault secret %s", ErrNotFound, path)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("%w: vault secret %s: unexpected status %d", ErrSecret, path, resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: could not decode vault secret %s: %w", ErrSecret, path, err)
+	}
+	return fieldValue(body.Data.Data, field, "vault secret "+path)
+}
+
+// fieldValue returns a field of a secret holding several, encoding values that are not strings as JSON
+func fieldValue(fields map[string]any, field, secret string) (string, error) {
+	value, ok := fields[field]
+	if !ok {
+		return "", fmt.Errorf("%w: %s has no field %s", ErrNotFound, secret, field)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSecret, err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,352 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Alert implements the sink interface
+var _ pl.Sink[any] = (*Alert[any])(nil)
+
+// ErrAlertSink is the error returned by the alert sink
+var ErrAlertSink = errors.New("sink error")
+
+// AlertSinkPrefix is the prefix for the alert sink error
+const AlertSinkPrefix = "alert sink"
+
+// AlertProvider is the incident management service alerts are created in
+type AlertProvider uint8
+
+const (
+	// AlertPagerDuty triggers events with the PagerDuty Events API v2
+	AlertPagerDuty AlertProvider = iota
+	// AlertOpsgenie creates alerts with the Opsgenie Alert API
+	AlertOpsgenie
+)
+
+// Alert severities, from the most to the least urgent
+const (
+	SeverityCritical = "critical"
+	SeverityError    = "error"
+	SeverityWarning  = "warning"
+	SeverityInfo     = "info"
+)
+
+// Default provider endpoints
+const (
+	PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	OpsgenieAlertsURL  = "https://api.opsgenie.com/v2/alerts"
+)
+
+// AlertConfig is the configuration for the alert sink.
+type AlertConfig struct {
+	Provider AlertProvider // incident management service, defaults to AlertPagerDuty
+	Key      string        // PagerDuty integration routing key or Opsgenie API key
+	URL      string        // optional endpoint, e.g. the EU Opsgenie API, defaults to the provider endpoint
+
+	Summary  string // summary template, defaults to "{{json .}}"
+	DedupKey string // optional dedup key template; alerts with the same key are grouped by the provider
+	Source   string // source template of the alert, defaults to "krapht"
+
+	SeverityField   string            // dot separated path of the record field holding the severity
+	SeverityMap     map[string]string // maps field values to severities, e.g. "high" to SeverityCritical
+	DefaultSeverity string            // severity when the field is missing or unmapped, defaults to SeverityError
+
+	Timeout      time.Duration // request timeout, defaults to 10 seconds
+	MaxRetries   int           // attempts for 5xx, 429 and network errors, defaults to 3
+	RetryBackoff time.Duration // initial backoff between attempts, defaults to 1 second
+}
+
+// Alert is a sink that creates an alert in PagerDuty or Opsgenie for every record.
+//
+// Summary, dedup key and source are rendered like in the SMTP sink, so a filter can alert
+// on error events. The severity is read from SeverityField, mapped through SeverityMap, and
+// converted to the provider's severity or priority; values that already name a severity are
+// used as is. The record data is attached to the alert as details. Upstream messages of
+// RawReadable records are acked after the provider accepts the alert.
+type Alert[I any] struct {
+	client          *http.Client
+	provider        AlertProvider
+	key             string
+	url             string
+	summary         *template.Template
+	dedupKey        *template.Template
+	source          *template.Template
+	severityField   string
+	severityMap     map[string]string
+	defaultSeverity string
+	filter          func(I) bool
+	maxRetries      int
+	retryBackoff    time.Duration
+}
+
+// NewAlert creates a new alert sink with the given configuration.
+// A nil filter alerts on every record.
+func NewAlert[I any](conf AlertConfig, filter func(I) bool) (*Alert[I], error) {
+	if conf.Provider > AlertOpsgenie {
+		return nil, fmt.Errorf("%s: %w: unknown provider %d", AlertSinkPrefix, ErrAlertSink, conf.Provider)
+	}
+
+	if conf.Key == "" {
+		return nil, fmt.Errorf("%s: %w: key is empty", AlertSinkPrefix, ErrAlertSink)
+	}
+
+	if conf.URL == "" {
+		conf.URL = PagerDutyEventsURL
+		if conf.Provider == AlertOpsgenie {
+			conf.URL = OpsgenieAlertsURL
+		}
+	}
+
+	if conf.Summary == "" {
+		conf.Summary = "{{json .}}"
+	}
+
+	if conf.Source == "" {
+		conf.Source = "krapht"
+	}
+
+	if conf.DefaultSeverity == "" {
+		conf.DefaultSeverity = SeverityError
+	}
+
+	if !isSeverity(conf.DefaultSeverity) {
+		return nil, fmt.Errorf("%s: %w: unknown severity %q", AlertSinkPrefix, ErrAlertSink, conf.DefaultSeverity)
+	}
+	for value, severity := range conf.SeverityMap {
+		if !isSeverity(severity) {
+			return nil, fmt.Errorf("%s: %w: unknown severity %q for %q", AlertSinkPrefix, ErrAlertSink, severity, value)
+		}
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 10 * time.Second
+	}
+
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 3
+	}
+
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = time.Second
+	}
+
+	summary, err := newNotifyTemplate("summary", conf.Summary)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid summary template: %w", AlertSinkPrefix, ErrAlertSink, err)
+	}
+
+	dedupKey, err := newNotifyTemplate("dedup", conf.DedupKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid dedup key template: %w", AlertSinkPrefix, ErrAlertSink, err)
+	}
+
+	source, err := newNotifyTemplate("source", conf.Source)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid source template: %w", AlertSinkPrefix, ErrAlertSink, err)
+	}
+
+	return &Alert[I]{
+		client:          &http.Client{Timeout: conf.Timeout},
+		provider:        conf.Provider,
+		key:             conf.Key,
+		url:             conf.URL,
+		summary:         summary,
+		dedupKey:        dedupKey,
+		source:          source,
+		severityField:   conf.SeverityField,
+		severityMap:     conf.SeverityMap,
+		defaultSeverity: conf.DefaultSeverity,
+		filter:          filter,
+		maxRetries:      conf.MaxRetries,
+		retryBackoff:    conf.RetryBackoff,
+	}, nil
+}
+
+// Load creates an alert for every record from the in channel.
+// It blocks until the in channel is closed and the final alert is created.
+func (a *Alert[I]) Load(in <-chan I, eventC chan<- pl.Event) {
+	for record := range filterRecords(in, a.filter, eventC) {
+		body, err := a.alert(record)
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to render alert",
+				err,
+				false,
+				record))
+			continue
+		}
+
+		err = withRetry(a.maxRetries, a.retryBackoff, isWebhookTemporary, func() error {
+			return a.post(body)
+		})
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to create alert",
+				err,
+				isWebhookTemporary(err),
+				record))
+			continue
+		}
+
+		// ack the message once the provider accepted the alert
+		ackRecords([]I{record}, eventC)
+	}
+}
+
+// alert renders the request body of the alert for a record
+func (a *Alert[I]) alert(record I) ([]byte, error) {
+	data, err := templateValue(record)
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := executeTemplate(a.summary, data)
+	if err != nil {
+		return nil, err
+	}
+	dedupKey, err := executeTemplate(a.dedupKey, data)
+	if err != nil {
+		return nil, err
+	}
+	source, err := executeTemplate(a.source, data)
+	if err != nil {
+		return nil, err
+	}
+	severity := a.severity(data)
+
+	if a.provider == AlertOpsgenie {
+		msg := map[string]any{
+			"message":     truncate(summary, 130),
+			"description": truncate(summary, 15000),
+			"source":      source,
+			"priority":    opsgeniePriority(severity),
+			"details":     opsgenieDetails(data),
+		}
+		if dedupKey != "" {
+			msg["alias"] = truncate(dedupKey, 512)
+		}
+		return json.Marshal(msg)
+	}
+
+	// values other than decoded payloads, such as events, are attached as text
+	var details any = data
+	if _, ok := data.(map[string]any); !ok {
+		details = fmt.Sprint(data)
+	}
+
+	msg := map[string]any{
+		"routing_key":  a.key,
+		"event_action": "trigger",
+		"payload": map[string]any{
+			"summary":        truncate(summary, 1024),
+			"source":         source,
+			"severity":       severity,
+			"custom_details": details,
+		},
+	}
+	if dedupKey != "" {
+		msg["dedup_key"] = truncate(dedupKey, 255)
+	}
+	return json.Marshal(msg)
+}
+
+// severity reads and maps the severity of a record
+func (a *Alert[I]) severity(data any) string {
+	obj, ok := data.(map[string]any)
+	if !ok || a.severityField == "" {
+		return a.defaultSeverity
+	}
+
+	v := lookupField(obj, a.severityField)
+	if v == nil {
+		return a.defaultSeverity
+	}
+
+	value := fmt.Sprint(v)
+	if severity, ok := a.severityMap[value]; ok {
+		return severity
+	}
+	if value = strings.ToLower(value); isSeverity(value) {
+		return value
+	}
+	return a.defaultSeverity
+}
+
+// post sends a single alert to the provider
+func (a *Alert[I]) post(body []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.provider == AlertOpsgenie {
+		req.Header.Set("Authorization", "GenieKey "+a.key)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return webhookStatusError{status: resp.StatusCode}
+	}
+	return nil
+}
+
+// isSeverity reports whether s names an alert severity
+func isSeverity(s string) bool {
+	switch s {
+	case SeverityCritical, SeverityError, SeverityWarning, SeverityInfo:
+		return true
+	}
+	return false
+}
+
+// opsgeniePriority converts a severity to an Opsgenie priority
+func opsgeniePriority(severity string) string {
+	switch severity {
+	case SeverityCritical:
+		return "P1"
+	case SeverityError:
+		return "P2"
+	case SeverityWarning:
+		return "P3"
+	default:
+		return "P5"
+	}
+}
+
+// opsgenieDetails converts record data to Opsgenie details, which only hold string values
+func opsgenieDetails(data any) map[string]string {
+	obj, ok := data.(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	details := make(map[string]string, len(obj))
+	for k, v := range obj {
+		if s, ok := v.(string); ok {
+			details[k] = s
+			continue
+		}
+		p, _ := json.Marshal(v)
+		details[k] = string(p)
+	}
+	return details
+}
@@ -0,0 +1,174 @@
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// alertServer records the alerts posted to it and their authorization header
+type alertServer struct {
+	status int
+	auth   []string
+	alerts []map[string]any
+}
+
+func (s *alertServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var alert map[string]any
+	_ = json.Unmarshal(body, &alert)
+
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+	s.alerts = append(s.alerts, alert)
+	if s.status == 0 {
+		s.status = http.StatusAccepted
+	}
+	w.WriteHeader(s.status)
+}
+
+// loadAlert posts the records to a test server and returns the events sent
+func loadAlert(t *testing.T, srv *alertServer, conf AlertConfig, records ...ackableRecord) []pipeline.Event {
+	t.Helper()
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	conf.URL = ts.URL
+	alert, err := NewAlert[pipeline.DataRawReadable](conf, nil)
+	assert.NoError(t, err)
+
+	in := make(chan pipeline.DataRawReadable, len(records))
+	for _, r := range records {
+		in <- r
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, 10)
+	alert.Load(in, eventC)
+	close(eventC)
+
+	var events []pipeline.Event
+	for e := range eventC {
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestAlert_Load(t *testing.T) {
+	conf := AlertConfig{
+		Key:           "routing-key",
+		Summary:       "{{.rule}} on {{.host}}",
+		DedupKey:      "{{.rule}}/{{.host}}",
+		SeverityField: "risk.level",
+		SeverityMap:   map[string]string{"high": SeverityCritical},
+		MaxRetries:    1,
+	}
+
+	t.Run("triggers pagerduty events", func(t *testing.T) {
+		srv := &alertServer{}
+		high := newAckableRecord(`{"rule":"brute force","host":"fw01","risk":{"level":"high"}}`)
+		warning := newAckableRecord(`{"rule":"port scan","host":"fw02","risk":{"level":"Warning"}}`)
+		unknown := newAckableRecord(`{"rule":"login","host":"fw03"}`)
+
+		events := loadAlert(t, srv, conf, high, warning, unknown)
+		assert.Empty(t, events)
+
+		assert.Len(t, srv.alerts, 3)
+		assert.Equal(t, "routing-key", srv.alerts[0]["routing_key"])
+		assert.Equal(t, "trigger", srv.alerts[0]["event_action"])
+		assert.Equal(t, "brute force/fw01", srv.alerts[0]["dedup_key"])
+
+		payload := srv.alerts[0]["payload"].(map[string]any)
+		assert.Equal(t, "brute force on fw01", payload["summary"])
+		assert.Equal(t, "krapht", payload["source"])
+		assert.Equal(t, "critical", payload["severity"])
+		assert.Equal(t, "fw01", payload["custom_details"].(map[string]any)["host"])
+
+		assert.Equal(t, "warning", srv.alerts[1]["payload"].(map[string]any)["severity"])
+		assert.Equal(t, "error", srv.alerts[2]["payload"].(map[string]any)["severity"])
+
+		assert.True(t, high.raw.acked)
+		assert.True(t, unknown.raw.acked)
+	})
+
+	t.Run("creates opsgenie alerts", func(t *testing.T) {
+		srv := &alertServer{}
+		opsConf := conf
+		opsConf.Provider = AlertOpsgenie
+		events := loadAlert(t, srv, opsConf, newAckableRecord(`{"rule":"brute force","host":"fw01","risk":{"level":"high"}}`))
+		assert.Empty(t, events)
+
+		assert.Equal(t, []string{"GenieKey routing-key"}, srv.auth)
+		alert := srv.alerts[0]
+		assert.Equal(t, "brute force on fw01", alert["message"])
+		assert.Equal(t, "brute force/fw01", alert["alias"])
+		assert.Equal(t, "P1", alert["priority"])
+		assert.Equal(t, map[string]any{"rule": "brute force", "host": "fw01", "risk": `{"level":"high"}`}, alert["details"])
+	})
+
+	t.Run("does not ack rejected alerts", func(t *testing.T) {
+		record := newAckableRecord(`{"rule":"login","host":"fw03"}`)
+		events := loadAlert(t, &alertServer{status: http.StatusBadRequest}, conf, record)
+
+		assert.Len(t, events, 1)
+		errEvent, ok := events[0].(pipeline.ErrorEvent)
+		assert.True(t, ok)
+		assert.False(t, errEvent.IsTemporary())
+		assert.False(t, record.raw.acked)
+	})
+
+	t.Run("reports records missing template fields", func(t *testing.T) {
+		missingConf := conf
+		missingConf.Summary = "{{.missing}}"
+		record := newAckableRecord(`{"rule":"login"}`)
+		events := loadAlert(t, &alertServer{}, missingConf, record)
+
+		assert.Len(t, events, 1)
+		assert.False(t, record.raw.acked)
+	})
+}
+
+func TestAlert_events(t *testing.T) {
+	srv := &alertServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	alert, err := NewAlert[pipeline.Event](AlertConfig{Key: "k", URL: ts.URL, Summary: "{{.Error}}", RetryBackoff: time.Millisecond},
+		func(e pipeline.Event) bool { return e.Type() == pipeline.EventError })
+	assert.NoError(t, err)
+
+	in := make(chan pipeline.Event, 2)
+	in <- pipeline.NewLogEvent("kafka", pipeline.LevelInfo, "connected")
+	in <- pipeline.NewErrorEvent("failed to write", errors.New("broker down"), true)
+	close(in)
+
+	alert.Load(in, nil)
+
+	assert.Len(t, srv.alerts, 1)
+	assert.Equal(t, "failed to write: broker down", srv.alerts[0]["payload"].(map[string]any)["summary"])
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "abc", truncate("abc", 5))
+	assert.Equal(t, "ab", truncate("abc", 2))
+	// multi-byte runes are not split
+	assert.Equal(t, "a", truncate("aé", 2))
+}
+
+func TestNewAlert_Validation(t *testing.T) {
+	_, err := NewAlert[[]byte](AlertConfig{}, nil)
+	assert.ErrorIs(t, err, ErrAlertSink)
+
+	_, err = NewAlert[[]byte](AlertConfig{Key: "k", Provider: 7}, nil)
+	assert.ErrorIs(t, err, ErrAlertSink)
+
+	_, err = NewAlert[[]byte](AlertConfig{Key: "k", SeverityMap: map[string]string{"high": "urgent"}}, nil)
+	assert.ErrorIs(t, err, ErrAlertSink)
+}
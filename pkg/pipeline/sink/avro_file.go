@@ -0,0 +1,273 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/linkedin/goavro/v2"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that AvroFile implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*AvroFile)(nil)
+
+// ErrAvroFileSink is the error returned by the Avro file sink
+var ErrAvroFileSink = errors.New("sink error")
+
+// AvroFileSinkPrefix is the prefix for the Avro file sink error
+const AvroFileSinkPrefix = "avro file sink"
+
+// Avro container file compression codecs
+const (
+	AvroCodecNull    = "null"
+	AvroCodecDeflate = "deflate"
+	AvroCodecSnappy  = "snappy"
+)
+
+// AvroFileConfig is the configuration for the Avro file sink.
+type AvroFileConfig struct {
+	Schema string // Avro schema of the records, embedded in every file header
+	Codec  string // block compression codec, one of the AvroCodec constants, defaults to null
+
+	// Path is the file path template, rendered with BlobTemplateData when a file is started.
+	// Defaults to "{{.Time.Format "2006/01/02/15"}}/{{.ID}}.avro" relative to the working directory.
+	Path string
+
+	BatchSize     int           // records per block, defaults to 1000
+	FlushInterval time.Duration // max time a partial block is held, defaults to 10 seconds
+	MaxBytes      int64         // file size after which a new file is started, defaults to 128MiB
+	MaxAge        time.Duration // file age after which a new file is started, defaults to 1 hour
+}
+
+// AvroFile is a sink that writes records to Avro object container files.
+//
+// Record payloads are JSON objects matching the schema; union values are plain JSON values
+// rather than Avro's {"type": value} encoding. Each batch is written as a compressed block and
+// synced to disk, after which upstream messages are acked. Size and age limits are checked
+// after each block, so files exceed MaxBytes by up to one block.
+type AvroFile struct {
+	codec         *goavro.Codec
+	compression   string
+	path          *template.Template
+	batchSize     int
+	flushInterval time.Duration
+	maxBytes      int64
+	maxAge        time.Duration
+}
+
+// NewAvroFile creates a new Avro file sink with the given configuration.
+func NewAvroFile(conf AvroFileConfig) (*AvroFile, error) {
+	if conf.Schema == "" {
+		return nil, fmt.Errorf("%s: %w: schema is empty", AvroFileSinkPrefix, ErrAvroFileSink)
+	}
+
+	switch conf.Codec {
+	case "":
+		conf.Codec = AvroCodecNull
+	case AvroCodecNull, AvroCodecDeflate, AvroCodecSnappy:
+	default:
+		return nil, fmt.Errorf("%s: %w: unsupported codec %q", AvroFileSinkPrefix, ErrAvroFileSink, conf.Codec)
+	}
+
+	if conf.Path == "" {
+		conf.Path = `{{.Time.Format "2006/01/02/15"}}/{{.ID}}.avro`
+	}
+
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 1000
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = 10 * time.Second
+	}
+
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = 128 << 20
+	}
+
+	if conf.MaxAge <= 0 {
+		conf.MaxAge = time.Hour
+	}
+
+	codec, err := goavro.NewCodecForStandardJSONFull(conf.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid schema: %w", AvroFileSinkPrefix, ErrAvroFileSink, err)
+	}
+
+	path, err := template.New("path").Parse(conf.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid path template: %w", AvroFileSinkPrefix, ErrAvroFileSink, err)
+	}
+
+	return &AvroFile{
+		codec:         codec,
+		compression:   conf.Codec,
+		path:          path,
+		batchSize:     conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		maxBytes:      conf.MaxBytes,
+		maxAge:        conf.MaxAge,
+	}, nil
+}
+
+// avroFileTarget is the file currently being written
+type avroFileTarget struct {
+	file    *os.File
+	counter *countingWriter
+	ocf     *goavro.OCFWriter
+	started time.Time
+}
+
+// Load writes batches of records as blocks to the current file, rotating files by size and age.
+// It blocks until the in channel is closed and the final file is closed.
+func (a *AvroFile) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	var target *avroFileTarget
+
+	collectBatches(in, a.batchSize, a.flushInterval, func(batch []pl.DataRawReadable) {
+		natives := make([]any, 0, len(batch))
+		written := make([]pl.DataRawReadable, 0, len(batch))
+		for _, drr := range batch {
+			p, err := drr.Data().Read()
+			if err != nil {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"failed to read data",
+					err,
+					true,
+					drr))
+				continue
+			}
+
+			native, _, err := a.codec.NativeFromTextual(p)
+			if err != nil {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"failed to convert record to avro",
+					err,
+					false,
+					drr))
+				continue
+			}
+			natives = append(natives, native)
+			written = append(written, drr)
+		}
+
+		if len(written) == 0 {
+			return
+		}
+
+		if target == nil {
+			next, err := a.newTarget()
+			if err != nil {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"failed to create file",
+					err,
+					true,
+					written))
+				return
+			}
+			target = next
+		}
+
+		if err := target.ocf.Append(natives); err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to write block",
+				err,
+				true,
+				written))
+			return
+		}
+
+		if err := target.file.Sync(); err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to sync file",
+				err,
+				true,
+				written))
+			return
+		}
+
+		// ack the messages once the block is on disk
+		for _, drr := range written {
+			ackMessage(drr.Raw(), eventC)
+		}
+
+		if target.counter.n >= a.maxBytes || time.Since(target.started) >= a.maxAge {
+			target.close(eventC)
+			target = nil
+		}
+	})
+
+	if target != nil {
+		target.close(eventC)
+	}
+}
+
+// newTarget renders the path of a new file, creates it and writes the container header
+func (a *AvroFile) newTarget() (*avroFileTarget, error) {
+	data := BlobTemplateData{
+		Time: time.Now().UTC(),
+		ID:   uuid.NewString(),
+	}
+
+	var name strings.Builder
+	if err := a.path.Execute(&name, data); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name.String()), 0o750); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(name.String(), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return nil, err
+	}
+
+	// the counting writer also keeps goavro from treating the file as an existing container
+	counter := &countingWriter{w: file}
+	ocf, err := goavro.NewOCFWriter(goavro.OCFConfig{
+		W:               counter,
+		Codec:           a.codec,
+		CompressionName: a.compression,
+	})
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+
+	return &avroFileTarget{
+		file:    file,
+		counter: counter,
+		ocf:     ocf,
+		started: data.Time,
+	}, nil
+}
+
+// close closes the file of the target
+func (t *avroFileTarget) close(eventC chan<- pl.Event) {
+	if err := t.file.Close(); err != nil {
+		pl.SendEvent(eventC, pl.NewErrorEvent(
+			"failed to close file",
+			err,
+			false))
+	}
+}
+
+// countingWriter counts the bytes written to the underlying writer
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write writes p to the underlying writer
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
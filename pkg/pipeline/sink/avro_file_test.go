@@ -0,0 +1,156 @@
+package sink
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+const testAvroSchema = `{
+	"type": "record",
+	"name": "event",
+	"fields": [
+		{"name": "host", "type": "string"},
+		{"name": "count", "type": "long"},
+		{"name": "tag", "type": ["null", "string"], "default": null}
+	]
+}`
+
+// readAvroFiles returns the codec and records of every container file in dir
+func readAvroFiles(t *testing.T, dir string) (codecs []string, records [][]any) {
+	t.Helper()
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.avro"))
+	assert.NoError(t, err)
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		assert.NoError(t, err)
+
+		r, err := goavro.NewOCFReader(f)
+		assert.NoError(t, err)
+		codecs = append(codecs, r.CompressionName())
+
+		var file []any
+		for r.Scan() {
+			native, err := r.Read()
+			assert.NoError(t, err)
+			file = append(file, native)
+		}
+		assert.NoError(t, r.Err())
+		assert.NoError(t, f.Close())
+		records = append(records, file)
+	}
+	return codecs, records
+}
+
+// loadAvroFile loads the records with the configuration and returns the events sent
+func loadAvroFile(t *testing.T, conf AvroFileConfig, records ...ackableRecord) []pipeline.Event {
+	t.Helper()
+
+	avro, err := NewAvroFile(conf)
+	assert.NoError(t, err)
+
+	in := make(chan pipeline.DataRawReadable, len(records))
+	for _, r := range records {
+		in <- r
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, 10)
+	avro.Load(in, eventC)
+	close(eventC)
+
+	var events []pipeline.Event
+	for e := range eventC {
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestAvroFile_Load(t *testing.T) {
+	t.Run("writes compressed container files with the schema", func(t *testing.T) {
+		for _, codec := range []string{AvroCodecNull, AvroCodecDeflate, AvroCodecSnappy} {
+			dir := t.TempDir()
+			first := newAckableRecord(`{"host":"a","count":1,"tag":"x"}`)
+			second := newAckableRecord(`{"host":"b","count":2,"tag":null}`)
+
+			events := loadAvroFile(t, AvroFileConfig{
+				Schema: testAvroSchema,
+				Codec:  codec,
+				Path:   filepath.Join(dir, "{{.ID}}.avro"),
+			}, first, second)
+			assert.Empty(t, events)
+
+			codecs, files := readAvroFiles(t, dir)
+			assert.Equal(t, []string{codec}, codecs)
+			assert.Equal(t, [][]any{{
+				map[string]any{"host": "a", "count": int64(1), "tag": map[string]any{"string": "x"}},
+				map[string]any{"host": "b", "count": int64(2), "tag": nil},
+			}}, files)
+
+			assert.True(t, first.raw.acked)
+			assert.True(t, second.raw.acked)
+		}
+	})
+
+	t.Run("rotates files by size", func(t *testing.T) {
+		dir := t.TempDir()
+		loadAvroFile(t, AvroFileConfig{
+			Schema:    testAvroSchema,
+			Path:      filepath.Join(dir, "{{.ID}}.avro"),
+			BatchSize: 1,
+			MaxBytes:  1,
+		}, newAckableRecord(`{"host":"a","count":1}`), newAckableRecord(`{"host":"b","count":2}`))
+
+		_, files := readAvroFiles(t, dir)
+		assert.Len(t, files, 2)
+	})
+
+	t.Run("rotates files by age", func(t *testing.T) {
+		dir := t.TempDir()
+		loadAvroFile(t, AvroFileConfig{
+			Schema:    testAvroSchema,
+			Path:      filepath.Join(dir, "{{.ID}}.avro"),
+			BatchSize: 1,
+			MaxAge:    time.Nanosecond,
+		}, newAckableRecord(`{"host":"a","count":1}`), newAckableRecord(`{"host":"b","count":2}`))
+
+		_, files := readAvroFiles(t, dir)
+		assert.Len(t, files, 2)
+	})
+
+	t.Run("reports records that do not match the schema", func(t *testing.T) {
+		dir := t.TempDir()
+		good, bad := newAckableRecord(`{"host":"a","count":1}`), newAckableRecord(`{"host":1}`)
+
+		events := loadAvroFile(t, AvroFileConfig{
+			Schema: testAvroSchema,
+			Path:   filepath.Join(dir, "{{.ID}}.avro"),
+		}, good, bad)
+
+		assert.Len(t, events, 1)
+		errEvent := events[0].(pipeline.ErrorEvent)
+		assert.False(t, errEvent.IsTemporary())
+		assert.Equal(t, bad, errEvent.Record())
+
+		assert.True(t, good.raw.acked)
+		assert.False(t, bad.raw.acked)
+	})
+}
+
+func TestNewAvroFile_Validation(t *testing.T) {
+	_, err := NewAvroFile(AvroFileConfig{})
+	assert.ErrorIs(t, err, ErrAvroFileSink)
+
+	_, err = NewAvroFile(AvroFileConfig{Schema: testAvroSchema, Codec: "zstd"})
+	assert.ErrorIs(t, err, ErrAvroFileSink)
+
+	_, err = NewAvroFile(AvroFileConfig{Schema: `{"type":"unknown"}`})
+	assert.ErrorIs(t, err, ErrAvroFileSink)
+}
@@ -0,0 +1,250 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/google/uuid"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that AzureBlob implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*AzureBlob)(nil)
+
+// ErrAzureBlobSink is the error returned by the Azure Blob sink
+var ErrAzureBlobSink = errors.New("sink error")
+
+// AzureBlobSinkPrefix is the prefix for the Azure Blob sink error
+const AzureBlobSinkPrefix = "azure blob sink"
+
+// BlobTemplateData is the data available to container and path templates.
+type BlobTemplateData struct {
+	Time time.Time // time the blob was started, in UTC
+	ID   string    // unique id of the blob
+}
+
+// AzureBlobConfig is the configuration for the Azure Blob sink.
+type AzureBlobConfig struct {
+	ConnectionString string                 // storage account connection string
+	AccountURL       string                 // service URL used with Credential when no connection string is set
+	Credential       azcore.TokenCredential // token credential for AccountURL
+
+	Container string // container name template
+	Path      string // blob path template, defaults to "{{.Time.Format "2006/01/02/15"}}/{{.ID}}.ndjson"
+
+	BatchSize     int           // records per staged block, defaults to 1000
+	FlushInterval time.Duration // max time a partial block is held, defaults to 10 seconds
+	MaxBlocks     int           // blocks per blob before a new blob is started, defaults to 1000
+}
+
+// blockStager is the subset of block blob operations used by the Azure Blob sink
+type blockStager interface {
+	StageBlock(ctx context.Context, container, blob, blockID string, body []byte) error
+	CommitBlockList(ctx context.Context, container, blob string, blockIDs []string) error
+}
+
+// AzureBlob is a sink that writes newline delimited records to Azure block blobs.
+// Each batch is staged as a block and committed, after which upstream messages are acked.
+type AzureBlob struct {
+	stager        blockStager
+	container     *template.Template
+	path          *template.Template
+	batchSize     int
+	flushInterval time.Duration
+	maxBlocks     int
+}
+
+// NewAzureBlob creates a new Azure Blob sink with the given configuration.
+func NewAzureBlob(conf AzureBlobConfig) (*AzureBlob, error) {
+	var client *azblob.Client
+	var err error
+
+	switch {
+	case conf.ConnectionString != "":
+		client, err = azblob.NewClientFromConnectionString(conf.ConnectionString, nil)
+	case conf.AccountURL != "" && conf.Credential != nil:
+		client, err = azblob.NewClient(conf.AccountURL, conf.Credential, nil)
+	default:
+		return nil, fmt.Errorf("%s: %w: connection string or account url and credential are required", AzureBlobSinkPrefix, ErrAzureBlobSink)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", AzureBlobSinkPrefix, ErrAzureBlobSink, err)
+	}
+
+	return newAzureBlob(azureBlockStager{client: client}, conf)
+}
+
+// newAzureBlob creates an Azure Blob sink using an existing block stager
+func newAzureBlob(stager blockStager, conf AzureBlobConfig) (*AzureBlob, error) {
+	if conf.Container == "" {
+		return nil, fmt.Errorf("%s: %w: container is empty", AzureBlobSinkPrefix, ErrAzureBlobSink)
+	}
+
+	if conf.Path == "" {
+		conf.Path = `{{.Time.Format "2006/01/02/15"}}/{{.ID}}.ndjson`
+	}
+
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 1000
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = 10 * time.Second
+	}
+
+	if conf.MaxBlocks <= 0 {
+		conf.MaxBlocks = 1000
+	}
+
+	container, err := template.New("container").Parse(conf.Container)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid container template: %w", AzureBlobSinkPrefix, ErrAzureBlobSink, err)
+	}
+
+	path, err := template.New("path").Parse(conf.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid path template: %w", AzureBlobSinkPrefix, ErrAzureBlobSink, err)
+	}
+
+	return &AzureBlob{
+		stager:        stager,
+		container:     container,
+		path:          path,
+		batchSize:     conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		maxBlocks:     conf.MaxBlocks,
+	}, nil
+}
+
+// azureBlobTarget is the blob currently being written
+type azureBlobTarget struct {
+	container string
+	name      string
+	blockIDs  []string
+}
+
+// Load stages batches of records as blocks and commits them to the current blob.
+// It blocks until the in channel is closed and the final block is committed.
+func (a *AzureBlob) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	ctx := context.Background()
+	var target *azureBlobTarget
+
+	collectBatches(in, a.batchSize, a.flushInterval, func(batch []pl.DataRawReadable) {
+		// start a new blob when none is open or the current one is full
+		if target == nil || len(target.blockIDs) >= a.maxBlocks {
+			next, err := a.newTarget()
+			if err != nil {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"failed to render blob name",
+					err,
+					false,
+					batch))
+				return
+			}
+			target = next
+		}
+
+		var block bytes.Buffer
+		written := make([]pl.DataRawReadable, 0, len(batch))
+		for _, drr := range batch {
+			p, err := drr.Data().Read()
+			if err != nil {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"failed to read data",
+					err,
+					true,
+					drr))
+				continue
+			}
+			block.Write(p)
+			if len(p) == 0 || p[len(p)-1] != '\n' {
+				block.WriteByte('\n')
+			}
+			written = append(written, drr)
+		}
+
+		if len(written) == 0 {
+			return
+		}
+
+		// block ids must have the same length within a blob
+		blockID := base64.StdEncoding.EncodeToString(fmt.Appendf(nil, "%08d", len(target.blockIDs)))
+		if err := a.stager.StageBlock(ctx, target.container, target.name, blockID, block.Bytes()); err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to stage block",
+				err,
+				true,
+				written))
+			return
+		}
+
+		blockIDs := append(target.blockIDs, blockID)
+		if err := a.stager.CommitBlockList(ctx, target.container, target.name, blockIDs); err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to commit block list",
+				err,
+				true,
+				written))
+			return
+		}
+		target.blockIDs = blockIDs
+
+		// ack the messages once the block is committed
+		for _, drr := range written {
+			ackMessage(drr.Raw(), eventC)
+		}
+	})
+}
+
+// newTarget renders the container and blob name for a new blob
+func (a *AzureBlob) newTarget() (*azureBlobTarget, error) {
+	data := BlobTemplateData{
+		Time: time.Now().UTC(),
+		ID:   uuid.NewString(),
+	}
+
+	var container, name strings.Builder
+	if err := a.container.Execute(&container, data); err != nil {
+		return nil, err
+	}
+	if err := a.path.Execute(&name, data); err != nil {
+		return nil, err
+	}
+
+	return &azureBlobTarget{
+		container: container.String(),
+		name:      name.String(),
+	}, nil
+}
+
+// azureBlockStager stages and commits blocks with the Azure SDK client
+type azureBlockStager struct {
+	client *azblob.Client
+}
+
+// blob returns the block blob client for the container and blob name
+func (s azureBlockStager) blob(container, blob string) *blockblob.Client {
+	return s.client.ServiceClient().NewContainerClient(container).NewBlockBlobClient(blob)
+}
+
+// StageBlock uploads an uncommitted block
+func (s azureBlockStager) StageBlock(ctx context.Context, container, blob, blockID string, body []byte) error {
+	_, err := s.blob(container, blob).StageBlock(ctx, blockID, streaming.NopCloser(bytes.NewReader(body)), nil)
+	return err
+}
+
+// CommitBlockList commits the staged blocks as the blob content
+func (s azureBlockStager) CommitBlockList(ctx context.Context, container, blob string, blockIDs []string) error {
+	_, err := s.blob(container, blob).CommitBlockList(ctx, blockIDs, nil)
+	return err
+}
@@ -0,0 +1,122 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// fakeBlockStager keeps staged and committed blocks in memory
+type fakeBlockStager struct {
+	staged    map[string]string
+	committed map[string][]string
+	commitErr error
+}
+
+func newFakeBlockStager() *fakeBlockStager {
+	return &fakeBlockStager{
+		staged:    make(map[string]string),
+		committed: make(map[string][]string),
+	}
+}
+
+func (f *fakeBlockStager) StageBlock(_ context.Context, container, blob, blockID string, body []byte) error {
+	f.staged[container+"/"+blob+"#"+blockID] = string(body)
+	return nil
+}
+
+func (f *fakeBlockStager) CommitBlockList(_ context.Context, container, blob string, blockIDs []string) error {
+	if f.commitErr != nil {
+		return f.commitErr
+	}
+	f.committed[container+"/"+blob] = append([]string(nil), blockIDs...)
+	return nil
+}
+
+// content returns the committed content of every blob
+func (f *fakeBlockStager) content() map[string]string {
+	blobs := make(map[string]string)
+	for name, ids := range f.committed {
+		var b strings.Builder
+		for _, id := range ids {
+			b.WriteString(f.staged[name+"#"+id])
+		}
+		blobs[name] = b.String()
+	}
+	return blobs
+}
+
+func TestAzureBlob_Load(t *testing.T) {
+	t.Run("stages blocks and rotates blobs", func(t *testing.T) {
+		stager := newFakeBlockStager()
+		a, err := newAzureBlob(stager, AzureBlobConfig{
+			Container: `logs-{{.Time.Format "2006"}}`,
+			Path:      "{{.ID}}.ndjson",
+			BatchSize: 1,
+			MaxBlocks: 2,
+		})
+		assert.NoError(t, err)
+
+		records := []ackableRecord{
+			newAckableRecord(`{"n":1}`),
+			newAckableRecord(`{"n":2}`),
+			newAckableRecord("{\"n\":3}\n"),
+		}
+
+		in := make(chan pipeline.DataRawReadable, len(records))
+		for _, r := range records {
+			in <- r
+		}
+		close(in)
+
+		a.Load(in, nil)
+
+		blobs := stager.content()
+		assert.Len(t, blobs, 2)
+
+		var all []string
+		for name, content := range blobs {
+			assert.True(t, strings.HasPrefix(name, "logs-"))
+			assert.True(t, strings.HasSuffix(name, ".ndjson"))
+			all = append(all, content)
+		}
+		assert.ElementsMatch(t, []string{"{\"n\":1}\n{\"n\":2}\n", "{\"n\":3}\n"}, all)
+
+		for _, r := range records {
+			assert.True(t, r.raw.acked)
+		}
+	})
+
+	t.Run("does not ack when commit fails", func(t *testing.T) {
+		stager := newFakeBlockStager()
+		stager.commitErr = errors.New("service unavailable")
+		a, err := newAzureBlob(stager, AzureBlobConfig{Container: "logs"})
+		assert.NoError(t, err)
+
+		record := newAckableRecord(`{"n":1}`)
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		a.Load(in, eventC)
+
+		assert.False(t, record.raw.acked)
+		assert.Equal(t, pipeline.EventError, (<-eventC).Type())
+	})
+}
+
+func TestNewAzureBlob_Validation(t *testing.T) {
+	_, err := NewAzureBlob(AzureBlobConfig{Container: "logs"})
+	assert.ErrorIs(t, err, ErrAzureBlobSink)
+
+	_, err = newAzureBlob(newFakeBlockStager(), AzureBlobConfig{})
+	assert.ErrorIs(t, err, ErrAzureBlobSink)
+
+	_, err = newAzureBlob(newFakeBlockStager(), AzureBlobConfig{Container: "{{.Missing"})
+	assert.ErrorIs(t, err, ErrAzureBlobSink)
+}
@@ -0,0 +1,127 @@
+package sink
+
+import "time"
+
+// collectBatches reads items from in and calls flush with batches of up to size items.
+// A partial batch is flushed once interval has passed since its first item was received,
+// and the final partial batch is flushed when the in channel is closed.
+// The flush function takes ownership of the batch slice.
+func collectBatches[T any](in <-chan T, size int, interval time.Duration, flush func([]T)) {
+	collectSizedBatches(in, size, 0, nil, interval, flush)
+}
+
+// collectSizedBatches works like collectBatches and additionally limits batches to maxBytes
+// as measured by sizeOf. A batch is flushed before an item that would push it over the limit
+// is added; an item larger than maxBytes is flushed in a batch of its own.
+// A maxBytes of zero disables the byte limit.
+func collectSizedBatches[T any](in <-chan T, size, maxBytes int, sizeOf func(T) int, interval time.Duration, flush func([]T)) {
+	batch := make([]T, 0, size)
+	bytes := 0
+
+	timer := time.NewTimer(interval)
+	timer.Stop()
+	defer timer.Stop()
+
+	// flushBatch flushes the current batch and starts a new one
+	flushBatch := func() {
+		timer.Stop()
+		flush(batch)
+		batch = make([]T, 0, size)
+		bytes = 0
+	}
+
+	for {
+		select {
+		case item, ok := <-in:
+			if !ok {
+				if len(batch) > 0 {
+					flush(batch)
+				}
+				return
+			}
+
+			n := 0
+			if maxBytes > 0 {
+				n = sizeOf(item)
+				if len(batch) > 0 && bytes+n > maxBytes {
+					flushBatch()
+				}
+			}
+
+			// start the interval with the first item of a batch
+			if len(batch) == 0 {
+				timer.Reset(interval)
+			}
+
+			batch = append(batch, item)
+			bytes += n
+			if len(batch) >= size || (maxBytes > 0 && bytes >= maxBytes) {
+				flushBatch()
+			}
+		case <-timer.C:
+			if len(batch) > 0 {
+				flushBatch()
+			}
+		}
+	}
+}
+
+// withRetry calls fn up to attempts times, doubling the backoff after each failed attempt.
+// It returns early when fn succeeds or fails with an error that temporary reports as permanent.
+func withRetry(attempts int, backoff time.Duration, temporary func(error) bool, fn func() error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		if err = fn(); err == nil || !temporary(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// collectDigests reads items from in and calls send with every item received since the previous send.
+// A digest is sent once window has passed since its first item, but never sooner than
+// minInterval after the previous send, so bursts are coalesced into a single digest while
+// sends are rate limited. The final digest is sent without delay when the in channel is closed.
+func collectDigests[T any](in <-chan T, window, minInterval time.Duration, send func([]T)) {
+	var digest []T
+	var last time.Time
+
+	timer := time.NewTimer(window)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case item, ok := <-in:
+			if !ok {
+				if len(digest) > 0 {
+					send(digest)
+				}
+				return
+			}
+
+			// schedule the digest with its first item
+			if len(digest) == 0 {
+				due := time.Now().Add(window)
+				if next := last.Add(minInterval); next.After(due) {
+					due = next
+				}
+				timer.Reset(time.Until(due))
+			}
+			digest = append(digest, item)
+		case <-timer.C:
+			send(digest)
+			digest = nil
+			last = time.Now()
+		}
+	}
+}
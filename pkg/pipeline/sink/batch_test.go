@@ -0,0 +1,103 @@
+package sink
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectBatches(t *testing.T) {
+	t.Run("flushes full batches and the final partial batch", func(t *testing.T) {
+		in := make(chan int, 5)
+		for i := 0; i < 5; i++ {
+			in <- i
+		}
+		close(in)
+
+		var batches [][]int
+		collectBatches(in, 2, time.Hour, func(batch []int) {
+			batches = append(batches, batch)
+		})
+
+		assert.Equal(t, [][]int{{0, 1}, {2, 3}, {4}}, batches)
+	})
+
+	t.Run("flushes partial batches after the interval", func(t *testing.T) {
+		in := make(chan int)
+		flushed := make(chan []int, 1)
+
+		go collectBatches(in, 10, 20*time.Millisecond, func(batch []int) {
+			flushed <- batch
+		})
+
+		in <- 1
+		select {
+		case batch := <-flushed:
+			assert.Equal(t, []int{1}, batch)
+		case <-time.After(time.Second):
+			t.Fatal("partial batch was not flushed")
+		}
+		close(in)
+	})
+}
+
+func TestWithRetry(t *testing.T) {
+	errTemporary := errors.New("temporary")
+	errPermanent := errors.New("permanent")
+	temporary := func(err error) bool { return errors.Is(err, errTemporary) }
+
+	calls := 0
+	err := withRetry(3, time.Millisecond, temporary, func() error {
+		calls++
+		if calls < 3 {
+			return errTemporary
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = withRetry(3, time.Millisecond, temporary, func() error {
+		calls++
+		return errPermanent
+	})
+	assert.ErrorIs(t, err, errPermanent)
+	assert.Equal(t, 1, calls)
+}
+
+func TestCollectDigests(t *testing.T) {
+	in := make(chan int)
+	type digest struct {
+		items []int
+		at    time.Time
+	}
+	sent := make(chan digest, 3)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		collectDigests(in, 10*time.Millisecond, 100*time.Millisecond, func(items []int) {
+			sent <- digest{items: items, at: time.Now()}
+		})
+	}()
+
+	in <- 1
+	first := <-sent
+	assert.Equal(t, []int{1}, first.items)
+
+	// a burst within the rate limit is coalesced into the next digest
+	in <- 2
+	in <- 3
+	second := <-sent
+	assert.Equal(t, []int{2, 3}, second.items)
+	assert.GreaterOrEqual(t, second.at.Sub(first.at), 100*time.Millisecond)
+
+	// the final digest is sent on close
+	in <- 4
+	close(in)
+	<-done
+	assert.Equal(t, []int{4}, (<-sent).items)
+}
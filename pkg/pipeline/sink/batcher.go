@@ -0,0 +1,105 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Batcher implements the sink interface
+var _ pl.Sink[any] = (*Batcher[any])(nil)
+
+// ErrBatcherSink is the error returned by the batcher sink
+var ErrBatcherSink = errors.New("sink error")
+
+// BatcherSinkPrefix is the prefix for the batcher sink error
+const BatcherSinkPrefix = "batcher sink"
+
+// FlushFunc writes a batch of items, reporting failures on the event channel.
+type FlushFunc[T any] func(batch []T, eventC chan<- pl.Event)
+
+// BatcherConfig is the configuration for the batcher sink.
+type BatcherConfig[T any] struct {
+	MaxItems      int           // items per batch, defaults to 100
+	MaxBytes      int           // optional max bytes per batch as measured by Size, 0 disables
+	Size          func(T) int   // size of an item in bytes, required with MaxBytes
+	FlushInterval time.Duration // max time a partial batch is held, defaults to 1 second
+}
+
+// Batcher is a sink that accumulates items into batches by count, bytes or interval
+// and loads them into a wrapped batch sink or flush function.
+// The final partial batch is flushed when the in channel is closed and Load returns
+// only after the wrapped sink has finished with it.
+type Batcher[T any] struct {
+	sink          pl.Sink[[]T]
+	maxItems      int
+	maxBytes      int
+	size          func(T) int
+	flushInterval time.Duration
+}
+
+// NewBatcher creates a new batcher sink loading batches into the given sink.
+func NewBatcher[T any](s pl.Sink[[]T], conf BatcherConfig[T]) (*Batcher[T], error) {
+	if s == nil {
+		return nil, fmt.Errorf("%s: %w: sink is nil", BatcherSinkPrefix, ErrBatcherSink)
+	}
+
+	if conf.MaxBytes > 0 && conf.Size == nil {
+		return nil, fmt.Errorf("%s: %w: size function is required with max bytes", BatcherSinkPrefix, ErrBatcherSink)
+	}
+
+	if conf.MaxItems <= 0 {
+		conf.MaxItems = 100
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+
+	return &Batcher[T]{
+		sink:          s,
+		maxItems:      conf.MaxItems,
+		maxBytes:      conf.MaxBytes,
+		size:          conf.Size,
+		flushInterval: conf.FlushInterval,
+	}, nil
+}
+
+// NewBatcherFunc creates a new batcher sink calling flush for every batch.
+func NewBatcherFunc[T any](flush FlushFunc[T], conf BatcherConfig[T]) (*Batcher[T], error) {
+	if flush == nil {
+		return nil, fmt.Errorf("%s: %w: flush function is nil", BatcherSinkPrefix, ErrBatcherSink)
+	}
+	return NewBatcher[T](flushSink[T](flush), conf)
+}
+
+// Load batches items from the in channel into the wrapped sink.
+// It blocks until the in channel is closed and the wrapped sink has loaded the final batch.
+func (b *Batcher[T]) Load(in <-chan T, eventC chan<- pl.Event) {
+	batches := make(chan []T)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		b.sink.Load(batches, eventC)
+	}()
+
+	collectSizedBatches(in, b.maxItems, b.maxBytes, b.size, b.flushInterval, func(batch []T) {
+		batches <- batch
+	})
+
+	close(batches)
+	<-done
+}
+
+// flushSink adapts a flush function to a batch sink
+type flushSink[T any] FlushFunc[T]
+
+// Load calls the flush function for every batch
+func (f flushSink[T]) Load(in <-chan []T, eventC chan<- pl.Event) {
+	for batch := range in {
+		f(batch, eventC)
+	}
+}
@@ -0,0 +1,94 @@
+package sink_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+// batchSink records the batches it loads
+type batchSink struct {
+	batches [][]string
+}
+
+func (b *batchSink) Load(in <-chan []string, _ chan<- pipeline.Event) {
+	for batch := range in {
+		b.batches = append(b.batches, batch)
+	}
+}
+
+// stringInput returns a closed channel holding the values
+func stringInput(values ...string) <-chan string {
+	in := make(chan string, len(values))
+	for _, v := range values {
+		in <- v
+	}
+	close(in)
+	return in
+}
+
+func TestBatcher_Load(t *testing.T) {
+	t.Run("batches by count and flushes on shutdown", func(t *testing.T) {
+		inner := &batchSink{}
+		batcher, err := sink.NewBatcher[string](inner, sink.BatcherConfig[string]{MaxItems: 2, FlushInterval: time.Hour})
+		assert.NoError(t, err)
+
+		batcher.Load(stringInput("a", "b", "c"), nil)
+
+		assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, inner.batches)
+	})
+
+	t.Run("batches by bytes", func(t *testing.T) {
+		inner := &batchSink{}
+		batcher, err := sink.NewBatcher[string](inner, sink.BatcherConfig[string]{
+			MaxBytes:      4,
+			Size:          func(s string) int { return len(s) },
+			FlushInterval: time.Hour,
+		})
+		assert.NoError(t, err)
+
+		batcher.Load(stringInput("aa", "b", "cc", "dddddd", "e"), nil)
+
+		assert.Equal(t, [][]string{{"aa", "b"}, {"cc"}, {"dddddd"}, {"e"}}, inner.batches)
+	})
+
+	t.Run("flushes partial batches after the interval", func(t *testing.T) {
+		flushed := make(chan []string, 1)
+		batcher, err := sink.NewBatcherFunc(func(batch []string, _ chan<- pipeline.Event) {
+			flushed <- batch
+		}, sink.BatcherConfig[string]{MaxItems: 10, FlushInterval: 10 * time.Millisecond})
+		assert.NoError(t, err)
+
+		in := make(chan string)
+		done := make(chan struct{})
+		go func() {
+			batcher.Load(in, nil)
+			close(done)
+		}()
+
+		in <- "a"
+		select {
+		case batch := <-flushed:
+			assert.Equal(t, []string{"a"}, batch)
+		case <-time.After(time.Second):
+			t.Fatal("partial batch was not flushed")
+		}
+
+		close(in)
+		<-done
+	})
+}
+
+func TestNewBatcher_Validation(t *testing.T) {
+	_, err := sink.NewBatcher[string](nil, sink.BatcherConfig[string]{})
+	assert.ErrorIs(t, err, sink.ErrBatcherSink)
+
+	_, err = sink.NewBatcher[string](&batchSink{}, sink.BatcherConfig[string]{MaxBytes: 10})
+	assert.ErrorIs(t, err, sink.ErrBatcherSink)
+
+	_, err = sink.NewBatcherFunc[string](nil, sink.BatcherConfig[string]{})
+	assert.ErrorIs(t, err, sink.ErrBatcherSink)
+}
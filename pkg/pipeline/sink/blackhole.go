@@ -0,0 +1,114 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Blackhole implements the sink interfaces
+var (
+	_ pl.Sink[any]      = (*Blackhole[any])(nil)
+	_ pl.ChunkSink[any] = (*Blackhole[any])(nil)
+)
+
+// ErrBlackholeSink is the error returned by the blackhole sink
+var ErrBlackholeSink = errors.New("sink error")
+
+// BlackholeSinkPrefix is the prefix for the blackhole sink error
+const BlackholeSinkPrefix = "blackhole sink"
+
+// BlackholeConfig is the configuration for the blackhole sink.
+type BlackholeConfig struct {
+	Rate   float64 // max records consumed per second, 0 consumes as fast as possible
+	Jitter float64 // random variation of the time between records as a fraction of it, from 0 to 1
+	Seed   uint64  // seed of the jitter, so runs with the same seed consume at the same pace
+
+	// Release releases the pooled buffers of releasable records after discarding them. It must only be set
+	// when the sink is the last consumer of the records.
+	Release bool
+}
+
+// Blackhole is a sink that discards records at a configurable maximum rate.
+// It holds back upstream stages like a slow destination would, so their backpressure
+// behavior can be load tested.
+type Blackhole[I any] struct {
+	interval time.Duration
+	jitter   float64
+	seed     uint64
+	release  bool
+}
+
+// NewBlackhole creates a new blackhole sink with the given configuration.
+func NewBlackhole[I any](conf BlackholeConfig) (*Blackhole[I], error) {
+	if conf.Rate < 0 {
+		return nil, fmt.Errorf("%s: %w: rate must not be negative", BlackholeSinkPrefix, ErrBlackholeSink)
+	}
+
+	if conf.Jitter < 0 || conf.Jitter > 1 {
+		return nil, fmt.Errorf("%s: %w: jitter must be between 0 and 1", BlackholeSinkPrefix, ErrBlackholeSink)
+	}
+
+	var interval time.Duration
+	if conf.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / conf.Rate)
+	}
+
+	return &Blackhole[I]{
+		interval: interval,
+		jitter:   conf.Jitter,
+		seed:     conf.Seed,
+		release:  conf.Release,
+	}, nil
+}
+
+// Load discards records from the in channel at the configured rate.
+// It blocks until the in channel is closed.
+func (b *Blackhole[I]) Load(in <-chan I, _ chan<- pl.Event) {
+	if b.interval == 0 {
+		for v := range in {
+			if b.release {
+				pl.Release(v)
+			}
+		}
+		return
+	}
+
+	rng := rand.New(rand.NewPCG(b.seed, b.seed))
+
+	// schedule records against a fixed clock so sleep overshoot does not lower the rate
+	next := time.Now()
+	for v := range in {
+		if b.release {
+			pl.Release(v)
+		}
+
+		delay := b.interval
+		if b.jitter > 0 {
+			delay = time.Duration(float64(delay) * (1 + b.jitter*(2*rng.Float64()-1)))
+		}
+
+		next = next.Add(delay)
+		time.Sleep(time.Until(next))
+	}
+}
+
+// LoadChunks discards the records of the chunks from the in channel at the configured rate.
+// It blocks until the in channel is closed.
+func (b *Blackhole[I]) LoadChunks(in <-chan []I, eventC chan<- pl.Event) {
+	if b.interval > 0 {
+		b.Load(pl.Unchunk(in), eventC)
+		return
+	}
+
+	for chunk := range in {
+		if b.release {
+			for _, v := range chunk {
+				pl.Release(v)
+			}
+		}
+	}
+}
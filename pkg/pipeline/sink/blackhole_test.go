@@ -0,0 +1,72 @@
+package sink_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+// timeBlackhole returns how long the blackhole takes to consume n records
+func timeBlackhole(t *testing.T, conf sink.BlackholeConfig, n int) time.Duration {
+	t.Helper()
+
+	blackhole, err := sink.NewBlackhole[int](conf)
+	assert.NoError(t, err)
+
+	in := make(chan int, n)
+	for i := range n {
+		in <- i
+	}
+	close(in)
+
+	start := time.Now()
+	blackhole.Load(in, nil)
+	return time.Since(start)
+}
+
+func TestBlackhole_Load(t *testing.T) {
+	t.Run("consumes at the configured rate", func(t *testing.T) {
+		// 10 records at 200 per second take 50ms
+		elapsed := timeBlackhole(t, sink.BlackholeConfig{Rate: 200}, 10)
+		assert.GreaterOrEqual(t, elapsed, 50*time.Millisecond)
+		assert.Less(t, elapsed, time.Second)
+	})
+
+	t.Run("consumes without a rate as fast as possible", func(t *testing.T) {
+		assert.Less(t, timeBlackhole(t, sink.BlackholeConfig{}, 1000), 100*time.Millisecond)
+	})
+
+	t.Run("releases records", func(t *testing.T) {
+		blackhole, err := sink.NewBlackhole[pipeline.Readable](sink.BlackholeConfig{Release: true})
+		assert.NoError(t, err)
+
+		buf := pipeline.DefaultBufferPool.Get()
+		buf.WriteString("one")
+		pooled := pipeline.DefaultBufferPool.Bytes(buf)
+
+		in := make(chan pipeline.Readable, 1)
+		in <- pooled
+		close(in)
+		blackhole.Load(in, nil)
+
+		_, err = pooled.Read()
+		assert.ErrorIs(t, err, pipeline.ErrReleased)
+	})
+
+	t.Run("jitter keeps the average rate", func(t *testing.T) {
+		elapsed := timeBlackhole(t, sink.BlackholeConfig{Rate: 200, Jitter: 0.5, Seed: 1}, 20)
+		assert.Greater(t, elapsed, 50*time.Millisecond)
+		assert.Less(t, elapsed, time.Second)
+	})
+}
+
+func TestNewBlackhole_Validation(t *testing.T) {
+	_, err := sink.NewBlackhole[int](sink.BlackholeConfig{Rate: -1})
+	assert.ErrorIs(t, err, sink.ErrBlackholeSink)
+
+	_, err = sink.NewBlackhole[int](sink.BlackholeConfig{Jitter: 2})
+	assert.ErrorIs(t, err, sink.ErrBlackholeSink)
+}
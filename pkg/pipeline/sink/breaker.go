@@ -0,0 +1,67 @@
+package sink
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// errCircuitOpen is returned when a request is rejected by an open circuit breaker
+var errCircuitOpen = errors.New("circuit breaker is open")
+
+// circuitBreaker stops calls to a failing destination after a number of consecutive failures.
+// Once the cooldown has passed a single trial call is allowed; its result closes or reopens the circuit.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	trial     bool
+}
+
+// newCircuitBreaker creates a circuit breaker; a threshold of zero disables it
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// allow reports whether a call may proceed
+func (c *circuitBreaker) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.threshold <= 0 || c.failures < c.threshold {
+		return true
+	}
+
+	// allow a single trial call once the cooldown has passed
+	if !c.trial && time.Since(c.openedAt) >= c.cooldown {
+		c.trial = true
+		return true
+	}
+	return false
+}
+
+// success records a successful call and closes the circuit
+func (c *circuitBreaker) success() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures = 0
+	c.trial = false
+}
+
+// failure records a failed call, opening the circuit when the threshold is reached
+func (c *circuitBreaker) failure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.failures++
+	c.trial = false
+	if c.threshold > 0 && c.failures >= c.threshold {
+		c.openedAt = time.Now()
+	}
+}
@@ -0,0 +1,34 @@
+package sink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	breaker := newCircuitBreaker(2, 20*time.Millisecond)
+
+	breaker.failure()
+	assert.True(t, breaker.allow())
+
+	breaker.failure()
+	assert.False(t, breaker.allow())
+
+	// a single trial call is allowed after the cooldown
+	time.Sleep(30 * time.Millisecond)
+	assert.True(t, breaker.allow())
+	assert.False(t, breaker.allow())
+
+	breaker.success()
+	assert.True(t, breaker.allow())
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	breaker := newCircuitBreaker(0, time.Hour)
+	for i := 0; i < 10; i++ {
+		breaker.failure()
+	}
+	assert.True(t, breaker.allow())
+}
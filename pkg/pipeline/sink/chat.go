@@ -0,0 +1,251 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Chat implements the sink interface
+var _ pl.Sink[any] = (*Chat[any])(nil)
+
+// ErrChatSink is the error returned by the chat sink
+var ErrChatSink = errors.New("sink error")
+
+// ChatSinkPrefix is the prefix for the chat sink error
+const ChatSinkPrefix = "chat sink"
+
+// ChatPlatform is the chat service an incoming webhook belongs to
+type ChatPlatform uint8
+
+const (
+	// ChatSlack posts Slack incoming webhook messages
+	ChatSlack ChatPlatform = iota
+	// ChatTeams posts Microsoft Teams messages with an Adaptive Card, as accepted by Teams workflows
+	ChatTeams
+)
+
+// ChatConfig is the configuration for the chat sink.
+type ChatConfig struct {
+	URL      string       // incoming webhook URL
+	Platform ChatPlatform // chat service of the webhook, defaults to ChatSlack
+
+	Title    string // summary title template rendered with DigestData, defaults to "{{.Count}} krapht alerts"
+	Template string // template each record is rendered with, defaults to "{{json .}}"
+
+	BurstWindow time.Duration // time a burst of records is coalesced into one message, defaults to 5 seconds
+	MinInterval time.Duration // minimum time between messages, defaults to 1 second
+	MaxRecords  int           // records rendered per summary message, the rest are counted, defaults to 20
+
+	Timeout      time.Duration // request timeout, defaults to 10 seconds
+	MaxRetries   int           // attempts for 5xx, 429 and network errors, defaults to 3
+	RetryBackoff time.Duration // initial backoff between attempts, defaults to 1 second
+}
+
+// Chat is a sink that posts formatted messages to Slack or Microsoft Teams incoming webhooks.
+//
+// Each record is rendered through the template. A record arriving on its own is posted as
+// a single message; a burst of records within the burst window, or arriving while messages
+// are rate limited by MinInterval, is coalesced into a single summary message with the
+// rendered title. Records are rendered like in the SMTP sink, so a filter can alert on
+// error events. Upstream messages of RawReadable records are acked after the webhook
+// accepts the message.
+type Chat[I any] struct {
+	client       *http.Client
+	url          string
+	platform     ChatPlatform
+	title        *template.Template
+	text         *template.Template
+	filter       func(I) bool
+	burstWindow  time.Duration
+	minInterval  time.Duration
+	maxRecords   int
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+// NewChat creates a new chat sink with the given configuration.
+// A nil filter posts every record.
+func NewChat[I any](conf ChatConfig, filter func(I) bool) (*Chat[I], error) {
+	if conf.URL == "" {
+		return nil, fmt.Errorf("%s: %w: url is empty", ChatSinkPrefix, ErrChatSink)
+	}
+
+	if conf.Platform > ChatTeams {
+		return nil, fmt.Errorf("%s: %w: unknown platform %d", ChatSinkPrefix, ErrChatSink, conf.Platform)
+	}
+
+	if conf.Title == "" {
+		conf.Title = "{{.Count}} krapht alerts"
+	}
+
+	if conf.Template == "" {
+		conf.Template = "{{json .}}"
+	}
+
+	if conf.BurstWindow <= 0 {
+		conf.BurstWindow = 5 * time.Second
+	}
+
+	if conf.MinInterval <= 0 {
+		conf.MinInterval = time.Second
+	}
+
+	if conf.MaxRecords <= 0 {
+		conf.MaxRecords = 20
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 10 * time.Second
+	}
+
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 3
+	}
+
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = time.Second
+	}
+
+	title, err := newNotifyTemplate("title", conf.Title)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid title template: %w", ChatSinkPrefix, ErrChatSink, err)
+	}
+
+	text, err := newNotifyTemplate("text", conf.Template)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid template: %w", ChatSinkPrefix, ErrChatSink, err)
+	}
+
+	return &Chat[I]{
+		client:       &http.Client{Timeout: conf.Timeout},
+		url:          conf.URL,
+		platform:     conf.Platform,
+		title:        title,
+		text:         text,
+		filter:       filter,
+		burstWindow:  conf.BurstWindow,
+		minInterval:  conf.MinInterval,
+		maxRecords:   conf.MaxRecords,
+		maxRetries:   conf.MaxRetries,
+		retryBackoff: conf.RetryBackoff,
+	}, nil
+}
+
+// Load coalesces records from the in channel into messages and posts them to the webhook.
+// It blocks until the in channel is closed and the final message is posted.
+func (c *Chat[I]) Load(in <-chan I, eventC chan<- pl.Event) {
+	collectDigests(filterRecords(in, c.filter, eventC), c.burstWindow, c.minInterval, func(digest []I) {
+		c.send(digest, eventC)
+	})
+}
+
+// send renders a digest as a message, posts it and acks its records
+func (c *Chat[I]) send(digest []I, eventC chan<- pl.Event) {
+	body, err := c.message(digest)
+	if err != nil {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to render message",
+			err,
+			false,
+			digest))
+		return
+	}
+
+	err = withRetry(c.maxRetries, c.retryBackoff, isWebhookTemporary, func() error {
+		return c.post(body)
+	})
+	if err != nil {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to post message",
+			err,
+			isWebhookTemporary(err),
+			digest))
+		return
+	}
+
+	// ack the messages once the webhook accepted the message
+	ackRecords(digest, eventC)
+}
+
+// message renders the request body of a message for the platform
+func (c *Chat[I]) message(digest []I) ([]byte, error) {
+	text, err := renderDigest(c.text, digest, c.maxRecords)
+	if err != nil {
+		return nil, err
+	}
+	text = strings.TrimSuffix(text, "\n")
+
+	// a single record is posted as is, bursts get a summary title
+	var title string
+	if len(digest) > 1 {
+		title, err = executeTemplate(c.title, DigestData{Count: len(digest), Time: time.Now().UTC()})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch c.platform {
+	case ChatTeams:
+		return json.Marshal(teamsMessage(title, text))
+	default:
+		if title != "" {
+			text = "*" + title + "*\n" + text
+		}
+		return json.Marshal(map[string]string{"text": text})
+	}
+}
+
+// teamsMessage builds a Teams message with an Adaptive Card holding the title and text
+func teamsMessage(title, text string) map[string]any {
+	var body []map[string]any
+	if title != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": title, "weight": "Bolder", "size": "Medium", "wrap": true})
+	}
+	body = append(body, map[string]any{"type": "TextBlock", "text": text, "wrap": true})
+
+	return map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content": map[string]any{
+				"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+				"type":    "AdaptiveCard",
+				"version": "1.4",
+				"body":    body,
+			},
+		}},
+	}
+}
+
+// post sends a single message to the webhook
+func (c *Chat[I]) post(body []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return webhookStatusError{status: resp.StatusCode}
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// chatServer records the JSON messages posted to it
+type chatServer struct {
+	mu       sync.Mutex
+	status   int
+	messages []map[string]any
+}
+
+func (s *chatServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var msg map[string]any
+	_ = json.Unmarshal(body, &msg)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, msg)
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+	}
+}
+
+// loadChat posts the records to a test server and returns the messages it received
+func loadChat(t *testing.T, srv *chatServer, conf ChatConfig, records ...ackableRecord) ([]map[string]any, []pipeline.Event) {
+	t.Helper()
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	conf.URL = ts.URL
+	chat, err := NewChat[pipeline.DataRawReadable](conf, nil)
+	assert.NoError(t, err)
+
+	in := make(chan pipeline.DataRawReadable, len(records))
+	for _, r := range records {
+		in <- r
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, 10)
+	chat.Load(in, eventC)
+	close(eventC)
+
+	var events []pipeline.Event
+	for e := range eventC {
+		events = append(events, e)
+	}
+	return srv.messages, events
+}
+
+func TestChat_Load(t *testing.T) {
+	conf := ChatConfig{
+		Title:        "{{.Count}} blocked hosts",
+		Template:     "blocked `{{.host}}`",
+		BurstWindow:  time.Hour,
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+	}
+
+	t.Run("posts a single record as is", func(t *testing.T) {
+		record := newAckableRecord(`{"host":"a"}`)
+		messages, events := loadChat(t, &chatServer{}, conf, record)
+
+		assert.Empty(t, events)
+		assert.Equal(t, []map[string]any{{"text": "blocked `a`"}}, messages)
+		assert.True(t, record.raw.acked)
+	})
+
+	t.Run("coalesces a burst into a slack summary", func(t *testing.T) {
+		burstConf := conf
+		burstConf.MaxRecords = 2
+		messages, _ := loadChat(t, &chatServer{}, burstConf,
+			newAckableRecord(`{"host":"a"}`), newAckableRecord(`{"host":"b"}`), newAckableRecord(`{"host":"c"}`))
+
+		assert.Equal(t, []map[string]any{{"text": "*3 blocked hosts*\nblocked `a`\nblocked `b`\n... and 1 more"}}, messages)
+	})
+
+	t.Run("posts teams adaptive cards", func(t *testing.T) {
+		teamsConf := conf
+		teamsConf.Platform = ChatTeams
+		messages, _ := loadChat(t, &chatServer{}, teamsConf, newAckableRecord(`{"host":"a"}`), newAckableRecord(`{"host":"b"}`))
+
+		assert.Len(t, messages, 1)
+		assert.Equal(t, "message", messages[0]["type"])
+
+		attachment := messages[0]["attachments"].([]any)[0].(map[string]any)
+		assert.Equal(t, "application/vnd.microsoft.card.adaptive", attachment["contentType"])
+
+		body := attachment["content"].(map[string]any)["body"].([]any)
+		assert.Equal(t, "2 blocked hosts", body[0].(map[string]any)["text"])
+		assert.Equal(t, "blocked `a`\nblocked `b`", body[1].(map[string]any)["text"])
+	})
+
+	t.Run("does not ack rejected messages", func(t *testing.T) {
+		record := newAckableRecord(`{"host":"a"}`)
+		_, events := loadChat(t, &chatServer{status: http.StatusNotFound}, conf, record)
+
+		assert.Len(t, events, 1)
+		errEvent, ok := events[0].(pipeline.ErrorEvent)
+		assert.True(t, ok)
+		assert.False(t, errEvent.IsTemporary())
+		assert.False(t, record.raw.acked)
+	})
+}
+
+func TestNewChat_Validation(t *testing.T) {
+	_, err := NewChat[[]byte](ChatConfig{}, nil)
+	assert.ErrorIs(t, err, ErrChatSink)
+
+	_, err = NewChat[[]byte](ChatConfig{URL: "http://localhost", Platform: 9}, nil)
+	assert.ErrorIs(t, err, ErrChatSink)
+
+	_, err = NewChat[[]byte](ChatConfig{URL: "http://localhost", Template: "{{"}, nil)
+	assert.ErrorIs(t, err, ErrChatSink)
+}
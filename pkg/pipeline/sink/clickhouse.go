@@ -0,0 +1,247 @@
+package sink
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that ClickHouse implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*ClickHouse)(nil)
+
+// ErrClickHouseSink is the error returned by the ClickHouse sink
+var ErrClickHouseSink = errors.New("sink error")
+
+// ClickHouseSinkPrefix is the prefix for the ClickHouse sink error
+const ClickHouseSinkPrefix = "clickhouse sink"
+
+// ClickHouseConfig is the configuration for the ClickHouse sink.
+type ClickHouseConfig struct {
+	Addr     []string // native protocol addresses (host:9000)
+	Database string
+	Username string
+	Password string
+	TLS      *tls.Config // optional TLS configuration
+
+	Table   string   // target table, optionally qualified by its database as db.table
+	Columns []Column // column mapping, in insert order
+
+	AsyncInsert   bool          // use server side async inserts
+	BatchSize     int           // rows per insert, defaults to 1000
+	FlushInterval time.Duration // max time a partial batch is held, defaults to 1 second
+	MaxRetries    int           // attempts for temporary errors, defaults to 3
+	RetryBackoff  time.Duration // initial backoff between attempts, defaults to 100ms
+}
+
+// clickHouseConn is the subset of the ClickHouse driver connection used by the sink
+type clickHouseConn interface {
+	PrepareBatch(ctx context.Context, query string, opts ...driver.PrepareBatchOption) (driver.Batch, error)
+	Close() error
+}
+
+// ClickHouse is a sink that batches JSON records into ClickHouse inserts.
+// Upstream messages are acked after the batch containing them is sent.
+type ClickHouse struct {
+	conn          clickHouseConn
+	query         string
+	columns       []Column
+	asyncInsert   bool
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	retryBackoff  time.Duration
+}
+
+// NewClickHouse creates a new ClickHouse sink with the given configuration.
+func NewClickHouse(conf ClickHouseConfig) (*ClickHouse, error) {
+	if len(conf.Addr) == 0 {
+		return nil, fmt.Errorf("%s: %w: addr is empty", ClickHouseSinkPrefix, ErrClickHouseSink)
+	}
+
+	conn, err := clickhouse.Open(&clickhouse.Options{
+		Addr: conf.Addr,
+		Auth: clickhouse.Auth{
+			Database: conf.Database,
+			Username: conf.Username,
+			Password: conf.Password,
+		},
+		TLS: conf.TLS,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", ClickHouseSinkPrefix, ErrClickHouseSink, err)
+	}
+
+	ch, err := newClickHouse(conn, conf)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return ch, nil
+}
+
+// newClickHouse creates a ClickHouse sink using an existing connection
+func newClickHouse(conn clickHouseConn, conf ClickHouseConfig) (*ClickHouse, error) {
+	if conf.Table == "" {
+		return nil, fmt.Errorf("%s: %w: table is empty", ClickHouseSinkPrefix, ErrClickHouseSink)
+	}
+
+	if len(conf.Columns) == 0 {
+		return nil, fmt.Errorf("%s: %w: columns are empty", ClickHouseSinkPrefix, ErrClickHouseSink)
+	}
+
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 1000
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 3
+	}
+
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = 100 * time.Millisecond
+	}
+
+	// the table and column names are quoted, so names from a configuration cannot alter the query
+	table := strings.Split(conf.Table, ".")
+	for i, name := range table {
+		if name == "" {
+			return nil, fmt.Errorf("%s: %w: table %q has an empty name", ClickHouseSinkPrefix, ErrClickHouseSink, conf.Table)
+		}
+		table[i] = quoteClickHouseIdentifier(name)
+	}
+
+	names := make([]string, len(conf.Columns))
+	for i, col := range conf.Columns {
+		if col.Name == "" {
+			return nil, fmt.Errorf("%s: %w: column %d has an empty name", ClickHouseSinkPrefix, ErrClickHouseSink, i)
+		}
+		names[i] = quoteClickHouseIdentifier(col.Name)
+	}
+
+	return &ClickHouse{
+		conn:          conn,
+		query:         fmt.Sprintf("INSERT INTO %s (%s)", strings.Join(table, "."), strings.Join(names, ", ")),
+		columns:       conf.Columns,
+		asyncInsert:   conf.AsyncInsert,
+		batchSize:     conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		maxRetries:    conf.MaxRetries,
+		retryBackoff:  conf.RetryBackoff,
+	}, nil
+}
+
+// clickHouseIdentifier escapes the backslashes and backticks of a quoted identifier
+var clickHouseIdentifier = strings.NewReplacer(`\`, `\\`, "`", "\\`")
+
+// quoteClickHouseIdentifier returns the name quoted as a ClickHouse identifier
+func quoteClickHouseIdentifier(name string) string {
+	return "`" + clickHouseIdentifier.Replace(name) + "`"
+}
+
+// Load batches records from the in channel and inserts them into ClickHouse.
+// It blocks until the in channel is closed and the final batch is sent.
+func (c *ClickHouse) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	collectBatches(in, c.batchSize, c.flushInterval, func(batch []pl.DataRawReadable) {
+		c.flush(batch, eventC)
+	})
+}
+
+// flush inserts a batch of records, retrying temporary errors
+func (c *ClickHouse) flush(batch []pl.DataRawReadable, eventC chan<- pl.Event) {
+	rows := make([][]any, 0, len(batch))
+	sent := make([]pl.DataRawReadable, 0, len(batch))
+
+	for _, drr := range batch {
+		row, err := c.row(drr)
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to map record to clickhouse columns",
+				err,
+				true,
+				drr))
+			continue
+		}
+		rows = append(rows, row)
+		sent = append(sent, drr)
+	}
+
+	if len(rows) == 0 {
+		return
+	}
+
+	ctx := context.Background()
+	if c.asyncInsert {
+		ctx = clickhouse.Context(ctx, clickhouse.WithAsync(true))
+	}
+
+	err := withRetry(c.maxRetries, c.retryBackoff, isClickHouseTemporary, func() error {
+		b, err := c.conn.PrepareBatch(ctx, c.query)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if err := b.Append(row...); err != nil {
+				_ = b.Abort()
+				return err
+			}
+		}
+		return b.Send()
+	})
+	if err != nil {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to insert batch into clickhouse",
+			err,
+			isClickHouseTemporary(err),
+			sent))
+		return
+	}
+
+	// ack the messages once the batch is committed
+	for _, drr := range sent {
+		ackMessage(drr.Raw(), eventC)
+	}
+}
+
+// row extracts the configured columns from the record payload
+func (c *ClickHouse) row(drr pl.DataRawReadable) ([]any, error) {
+	p, err := drr.Data().Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(p, &record); err != nil {
+		return nil, err
+	}
+
+	row := make([]any, len(c.columns))
+	for i, col := range c.columns {
+		row[i] = lookupField(record, col.Field)
+	}
+	return row, nil
+}
+
+// Close closes the ClickHouse connection.
+func (c *ClickHouse) Close() error {
+	return c.conn.Close()
+}
+
+// isClickHouseTemporary reports whether an error is worth retrying.
+// Exceptions raised by the server are permanent; network failures are temporary.
+func isClickHouseTemporary(err error) bool {
+	var exception *clickhouse.Exception
+	return !errors.As(err, &exception)
+}
@@ -0,0 +1,156 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// fakeBatch records appended rows and fails Send with the configured errors
+type fakeBatch struct {
+	driver.Batch
+	conn *fakeClickHouseConn
+	rows [][]any
+}
+
+func (b *fakeBatch) Append(v ...any) error {
+	b.rows = append(b.rows, v)
+	return nil
+}
+
+func (b *fakeBatch) Abort() error { return nil }
+
+func (b *fakeBatch) Send() error {
+	if len(b.conn.sendErrs) > 0 {
+		err := b.conn.sendErrs[0]
+		b.conn.sendErrs = b.conn.sendErrs[1:]
+		return err
+	}
+	b.conn.sent = append(b.conn.sent, b.rows...)
+	return nil
+}
+
+// fakeClickHouseConn hands out fake batches and records the query
+type fakeClickHouseConn struct {
+	query    string
+	sendErrs []error
+	attempts int
+	sent     [][]any
+}
+
+func (c *fakeClickHouseConn) PrepareBatch(_ context.Context, query string, _ ...driver.PrepareBatchOption) (driver.Batch, error) {
+	c.query = query
+	c.attempts++
+	return &fakeBatch{conn: c}, nil
+}
+
+func (c *fakeClickHouseConn) Close() error { return nil }
+
+func TestClickHouse_Load(t *testing.T) {
+	columns := []Column{
+		{Name: "src_ip", Field: "src.ip"},
+		{Name: "bytes", Field: "bytes"},
+	}
+
+	t.Run("maps columns and acks after send", func(t *testing.T) {
+		conn := &fakeClickHouseConn{}
+		ch, err := newClickHouse(conn, ClickHouseConfig{Table: "flows", Columns: columns, BatchSize: 2})
+		assert.NoError(t, err)
+
+		first := newAckableRecord(`{"src":{"ip":"10.0.0.1"},"bytes":10}`)
+		second := newAckableRecord(`{"src":{"ip":"10.0.0.2"}}`)
+
+		in := make(chan pipeline.DataRawReadable, 2)
+		in <- first
+		in <- second
+		close(in)
+
+		ch.Load(in, nil)
+
+		assert.Equal(t, "INSERT INTO `flows` (`src_ip`, `bytes`)", conn.query)
+		assert.Equal(t, [][]any{{"10.0.0.1", float64(10)}, {"10.0.0.2", nil}}, conn.sent)
+		assert.True(t, first.raw.acked)
+		assert.True(t, second.raw.acked)
+	})
+
+	t.Run("retries temporary errors", func(t *testing.T) {
+		conn := &fakeClickHouseConn{sendErrs: []error{errors.New("connection reset")}}
+		ch, err := newClickHouse(conn, ClickHouseConfig{Table: "flows", Columns: columns, RetryBackoff: 1})
+		assert.NoError(t, err)
+
+		record := newAckableRecord(`{"bytes":1}`)
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		ch.Load(in, nil)
+
+		assert.Equal(t, 2, conn.attempts)
+		assert.True(t, record.raw.acked)
+	})
+
+	t.Run("does not retry server exceptions", func(t *testing.T) {
+		conn := &fakeClickHouseConn{sendErrs: []error{&clickhouse.Exception{Code: 60, Message: "table does not exist"}}}
+		ch, err := newClickHouse(conn, ClickHouseConfig{Table: "flows", Columns: columns})
+		assert.NoError(t, err)
+
+		record := newAckableRecord(`{"bytes":1}`)
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		ch.Load(in, eventC)
+
+		assert.Equal(t, 1, conn.attempts)
+		assert.False(t, record.raw.acked)
+		assert.Equal(t, pipeline.EventError, (<-eventC).Type())
+	})
+
+	t.Run("reports malformed records", func(t *testing.T) {
+		conn := &fakeClickHouseConn{}
+		ch, err := newClickHouse(conn, ClickHouseConfig{Table: "flows", Columns: columns})
+		assert.NoError(t, err)
+
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- newAckableRecord(`not json`)
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		ch.Load(in, eventC)
+
+		assert.Equal(t, 0, conn.attempts)
+		assert.Equal(t, pipeline.EventError, (<-eventC).Type())
+	})
+}
+
+func TestNewClickHouse_Identifiers(t *testing.T) {
+	ch, err := newClickHouse(&fakeClickHouseConn{}, ClickHouseConfig{
+		Table:   "logs.flows",
+		Columns: []Column{{Name: "a` (x) SELECT 1; --", Field: "a"}, {Name: `b\`, Field: "b"}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "INSERT INTO `logs`.`flows` (`a\\` (x) SELECT 1; --`, `b\\\\`)", ch.query)
+
+	_, err = newClickHouse(&fakeClickHouseConn{}, ClickHouseConfig{Table: "logs.", Columns: []Column{{Name: "a"}}})
+	assert.ErrorIs(t, err, ErrClickHouseSink)
+
+	_, err = newClickHouse(&fakeClickHouseConn{}, ClickHouseConfig{Table: "flows", Columns: []Column{{Field: "a"}}})
+	assert.ErrorIs(t, err, ErrClickHouseSink)
+}
+
+func TestNewClickHouse_Validation(t *testing.T) {
+	_, err := NewClickHouse(ClickHouseConfig{Table: "flows"})
+	assert.ErrorIs(t, err, ErrClickHouseSink)
+
+	_, err = newClickHouse(&fakeClickHouseConn{}, ClickHouseConfig{Columns: []Column{{Name: "a", Field: "a"}}})
+	assert.ErrorIs(t, err, ErrClickHouseSink)
+
+	_, err = newClickHouse(&fakeClickHouseConn{}, ClickHouseConfig{Table: "flows"})
+	assert.ErrorIs(t, err, ErrClickHouseSink)
+}
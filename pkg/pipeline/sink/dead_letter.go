@@ -0,0 +1,214 @@
+package sink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that DeadLetter implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*DeadLetter)(nil)
+
+// ErrDeadLetterSink is the error returned by the dead-letter sink
+var ErrDeadLetterSink = errors.New("sink error")
+
+// DeadLetterSinkPrefix is the prefix for the dead-letter sink error
+const DeadLetterSinkPrefix = "dead letter sink"
+
+// DeadLetterConfig is the configuration for the dead-letter sink.
+type DeadLetterConfig struct {
+	Name      string // name of the wrapped sink recorded with each dead letter
+	QueueSize int    // events of the wrapped sink queued while they are passed on, defaults to 1000
+}
+
+// DeadLetterEnvelope is the structured data of a record routed to the dead-letter sink.
+type DeadLetterEnvelope struct {
+	Sink      string          `json:"sink,omitempty"`
+	Error     string          `json:"error"`
+	Temporary bool            `json:"temporary"`
+	Time      time.Time       `json:"time"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+// DeadLetter is a sink decorator that routes records the wrapped sink fails to load to a
+// dead-letter sink. Failed records are identified by the record carried on the error events
+// of the wrapped sink. Each dead letter holds a DeadLetterEnvelope as its data and the original
+// raw message, so the dead-letter sink acks upstream once it has stored the record.
+// Error events are still forwarded to the event channel. A slow dead-letter sink never holds up
+// the events of the wrapped sink: dead letters it has not taken yet wait in memory, so failures
+// are not dropped as the event channel of the wrapped sink fills.
+type DeadLetter struct {
+	sink      pl.Sink[pl.DataRawReadable]
+	dlq       pl.Sink[pl.DataRawReadable]
+	name      string
+	queueSize int
+}
+
+// NewDeadLetter creates a new dead-letter sink wrapping s and routing failures to dlq.
+func NewDeadLetter(s, dlq pl.Sink[pl.DataRawReadable], conf DeadLetterConfig) (*DeadLetter, error) {
+	if s == nil || dlq == nil {
+		return nil, fmt.Errorf("%s: %w: wrapped and dead-letter sinks are required", DeadLetterSinkPrefix, ErrDeadLetterSink)
+	}
+
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = 1000
+	}
+
+	return &DeadLetter{
+		sink:      s,
+		dlq:       dlq,
+		name:      conf.Name,
+		queueSize: conf.QueueSize,
+	}, nil
+}
+
+// Load loads records into the wrapped sink and routes its failed records to the dead-letter sink.
+// It blocks until both sinks have returned. Failures the wrapped sink reports after its Load returned
+// are not routed.
+func (d *DeadLetter) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	letters := make(chan pl.DataRawReadable)
+	dlqRelay := relayEvents("dead letter", eventC)
+
+	// the relay queues the dead letters rather than waiting for the dead-letter sink
+	queue := newLetterQueue()
+	go queue.feed(letters)
+
+	dlqDone := make(chan struct{})
+	go func() {
+		defer close(dlqDone)
+		d.dlq.Load(letters, dlqRelay.C)
+		dlqRelay.Stop()
+	}()
+
+	sinkRelay := newEventRelay(d.queueSize, func(e pl.Event) {
+		pl.SendEvent(eventC, e)
+
+		errEvent, ok := e.(pl.ErrorEvent)
+		if !ok {
+			return
+		}
+		for _, drr := range failedRecords(errEvent.Record()) {
+			queue.push(d.letter(drr, errEvent))
+		}
+	})
+
+	d.sink.Load(in, sinkRelay.C)
+	// the letters are only queued by the relay, so the queue can be closed once it is stopped
+	sinkRelay.Stop()
+
+	queue.close()
+	<-dlqDone
+}
+
+// letter builds the dead letter for a failed record
+func (d *DeadLetter) letter(drr pl.DataRawReadable, e pl.ErrorEvent) pl.DataRawReadable {
+	envelope := DeadLetterEnvelope{
+		Sink:      d.name,
+		Error:     e.String(),
+		Temporary: e.IsTemporary(),
+		Time:      time.Now().UTC(),
+	}
+
+	if p, err := drr.Data().Read(); err == nil {
+		if json.Valid(p) {
+			envelope.Payload = p
+		} else {
+			envelope.Payload, _ = json.Marshal(string(p))
+		}
+	}
+
+	data, err := json.Marshal(envelope)
+	return deadLetterRecord{
+		data: byteReadable{p: data, err: err},
+		raw:  drr.Raw(),
+	}
+}
+
+// letterQueue is an unbounded queue of the dead letters waiting for the dead-letter sink
+type letterQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	letters []pl.DataRawReadable
+	closed  bool
+}
+
+// newLetterQueue creates an empty letter queue
+func newLetterQueue() *letterQueue {
+	q := &letterQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push queues a dead letter without blocking
+func (q *letterQueue) push(letter pl.DataRawReadable) {
+	q.mu.Lock()
+	q.letters = append(q.letters, letter)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// close ends the queue once the letters already queued have been fed
+func (q *letterQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// feed sends the queued letters to out in order, closing it once the queue is closed and empty
+func (q *letterQueue) feed(out chan<- pl.DataRawReadable) {
+	defer close(out)
+	for {
+		q.mu.Lock()
+		for len(q.letters) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if len(q.letters) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		letter := q.letters[0]
+		q.letters[0] = nil
+		q.letters = q.letters[1:]
+		q.mu.Unlock()
+
+		out <- letter
+	}
+}
+
+// failedRecords returns the records carried by an error event
+func failedRecords(record any) []pl.DataRawReadable {
+	switch r := record.(type) {
+	case pl.DataRawReadable:
+		return []pl.DataRawReadable{r}
+	case []pl.DataRawReadable:
+		return r
+	default:
+		return nil
+	}
+}
+
+// deadLetterRecord is a dead letter holding the envelope and the original raw message
+type deadLetterRecord struct {
+	data pl.Readable
+	raw  pl.Readable
+}
+
+// Data returns the dead-letter envelope
+func (r deadLetterRecord) Data() pl.Readable { return r.data }
+
+// Raw returns the original raw message
+func (r deadLetterRecord) Raw() pl.Readable { return r.raw }
+
+// byteReadable is a Readable over a byte slice
+type byteReadable struct {
+	p   []byte
+	err error
+}
+
+// Read returns the bytes
+func (b byteReadable) Read() ([]byte, error) { return b.p, b.err }
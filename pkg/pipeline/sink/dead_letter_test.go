@@ -0,0 +1,123 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// failingSink fails records with a "bad" payload one by one and acks the others
+type failingSink struct {
+	batch bool // fail all bad records in a single batch event
+}
+
+func (f failingSink) Load(in <-chan pipeline.DataRawReadable, eventC chan<- pipeline.Event) {
+	var failed []pipeline.DataRawReadable
+	for drr := range in {
+		p, _ := drr.Data().Read()
+		if !strings.Contains(string(p), "bad") {
+			ackMessage(drr.Raw(), eventC)
+			continue
+		}
+
+		if f.batch {
+			failed = append(failed, drr)
+			continue
+		}
+		pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("failed to write", errors.New("rejected"), false, drr))
+	}
+
+	if len(failed) > 0 {
+		pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("failed to write batch", errors.New("timeout"), true, failed))
+	}
+}
+
+// loadDeadLetter loads the records and returns the dead letters written to the dlq
+func loadDeadLetter(t *testing.T, wrapped pipeline.Sink[pipeline.DataRawReadable], records ...ackableRecord) []DeadLetterEnvelope {
+	t.Helper()
+
+	var out bytes.Buffer
+	d, err := NewDeadLetter(wrapped, NewWriter[pipeline.DataRawReadable](&out, nil), DeadLetterConfig{Name: "opensearch"})
+	assert.NoError(t, err)
+
+	in := make(chan pipeline.DataRawReadable, len(records))
+	for _, r := range records {
+		in <- r
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, 10)
+	d.Load(in, eventC)
+
+	var letters []DeadLetterEnvelope
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var envelope DeadLetterEnvelope
+		assert.NoError(t, json.Unmarshal([]byte(line), &envelope))
+		letters = append(letters, envelope)
+	}
+	return letters
+}
+
+func TestDeadLetter_Load(t *testing.T) {
+	t.Run("routes failed records with error metadata", func(t *testing.T) {
+		good, bad, text := newAckableRecord(`{"n":1}`), newAckableRecord(`{"bad":true}`), newAckableRecord("bad text")
+
+		letters := loadDeadLetter(t, failingSink{}, good, bad, text)
+
+		assert.Len(t, letters, 2)
+		assert.Equal(t, "opensearch", letters[0].Sink)
+		assert.Equal(t, "failed to write: rejected", letters[0].Error)
+		assert.False(t, letters[0].Temporary)
+		assert.JSONEq(t, `{"bad":true}`, string(letters[0].Payload))
+		assert.JSONEq(t, `"bad text"`, string(letters[1].Payload))
+
+		// the dead-letter sink acks the failed records
+		assert.True(t, good.raw.acked)
+		assert.True(t, bad.raw.acked)
+		assert.True(t, text.raw.acked)
+	})
+
+	t.Run("routes every record of a failed batch", func(t *testing.T) {
+		letters := loadDeadLetter(t, failingSink{batch: true},
+			newAckableRecord(`{"bad":1}`), newAckableRecord(`{"n":1}`), newAckableRecord(`{"bad":2}`))
+
+		assert.Len(t, letters, 2)
+		assert.True(t, letters[0].Temporary)
+		assert.JSONEq(t, `{"bad":2}`, string(letters[1].Payload))
+	})
+}
+
+func TestDeadLetter_SlowDLQ(t *testing.T) {
+	// the dead-letter sink takes far longer per record than the wrapped sink takes to fail them,
+	// with room for only a few queued events
+	dlq := &slowSink{delay: 2 * time.Millisecond}
+	d, err := NewDeadLetter(failingSink{}, dlq, DeadLetterConfig{QueueSize: 4})
+	assert.NoError(t, err)
+
+	const n = 50
+	in := make(chan pipeline.DataRawReadable)
+	go func() {
+		defer close(in)
+		for range n {
+			in <- newAckableRecord(`{"bad":true}`)
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+
+	d.Load(in, make(chan pipeline.Event, 2*n))
+	assert.Len(t, dlq.records, n)
+}
+
+func TestNewDeadLetter_Validation(t *testing.T) {
+	_, err := NewDeadLetter(failingSink{}, nil, DeadLetterConfig{})
+	assert.ErrorIs(t, err, ErrDeadLetterSink)
+}
@@ -0,0 +1,214 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Encoder encodes record payloads onto an output stream.
+// Encoders may keep state between records, so an encoder is used by a single Writer.
+type Encoder interface {
+	// Encode writes a single record payload to w
+	Encode(w io.Writer, p []byte) error
+	// Close writes any trailer to w once all records are encoded
+	Close(w io.Writer) error
+}
+
+// Static checks that the encoders implement the Encoder interface
+var (
+	_ Encoder = (*JSONEncoder)(nil)
+	_ Encoder = (*NDJSONEncoder)(nil)
+	_ Encoder = (*LogfmtEncoder)(nil)
+	_ Encoder = (*RawEncoder)(nil)
+	_ Encoder = (*PrettyEncoder)(nil)
+)
+
+// JSONEncoder writes all records as a single JSON array.
+type JSONEncoder struct {
+	count int
+}
+
+// NewJSONEncoder creates a new JSON array encoder.
+func NewJSONEncoder() *JSONEncoder {
+	return &JSONEncoder{}
+}
+
+// Encode writes the compacted record as the next array element
+func (e *JSONEncoder) Encode(w io.Writer, p []byte) error {
+	var b bytes.Buffer
+	if e.count == 0 {
+		b.WriteByte('[')
+	} else {
+		b.WriteByte(',')
+	}
+
+	if err := json.Compact(&b, p); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(b.Bytes()); err != nil {
+		return err
+	}
+	e.count++
+	return nil
+}
+
+// Close terminates the array, writing an empty array when no records were encoded
+func (e *JSONEncoder) Close(w io.Writer) error {
+	trailer := "]\n"
+	if e.count == 0 {
+		trailer = "[]\n"
+	}
+	_, err := io.WriteString(w, trailer)
+	return err
+}
+
+// NDJSONEncoder writes each record as compacted JSON on its own line.
+type NDJSONEncoder struct{}
+
+// NewNDJSONEncoder creates a new newline delimited JSON encoder.
+func NewNDJSONEncoder() *NDJSONEncoder {
+	return &NDJSONEncoder{}
+}
+
+// Encode writes the compacted record followed by a newline
+func (e *NDJSONEncoder) Encode(w io.Writer, p []byte) error {
+	var b bytes.Buffer
+	if err := json.Compact(&b, p); err != nil {
+		return err
+	}
+	b.WriteByte('\n')
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// Close is a no-op
+func (e *NDJSONEncoder) Close(io.Writer) error { return nil }
+
+// LogfmtEncoder writes each JSON object record as a logfmt line.
+// Nested objects are flattened into dot separated keys and keys are sorted.
+type LogfmtEncoder struct{}
+
+// NewLogfmtEncoder creates a new logfmt encoder.
+func NewLogfmtEncoder() *LogfmtEncoder {
+	return &LogfmtEncoder{}
+}
+
+// Encode writes the record as key=value pairs followed by a newline
+func (e *LogfmtEncoder) Encode(w io.Writer, p []byte) error {
+	var record map[string]any
+	if err := json.Unmarshal(p, &record); err != nil {
+		return err
+	}
+	if record == nil {
+		return errors.New("logfmt record is not a JSON object")
+	}
+
+	fields := make(map[string]string)
+	flattenLogfmt(fields, "", record)
+
+	var b strings.Builder
+	for i, key := range slices.Sorted(maps.Keys(fields)) {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(quoteLogfmt(fields[key]))
+	}
+	b.WriteByte('\n')
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Close is a no-op
+func (e *LogfmtEncoder) Close(io.Writer) error { return nil }
+
+// flattenLogfmt flattens a decoded JSON object into dot separated keys
+func flattenLogfmt(fields map[string]string, prefix string, obj map[string]any) {
+	for k, v := range obj {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch v := v.(type) {
+		case map[string]any:
+			flattenLogfmt(fields, key, v)
+		case string:
+			fields[key] = v
+		case nil:
+			fields[key] = ""
+		case float64:
+			fields[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			fields[key] = strconv.FormatBool(v)
+		default:
+			// arrays are written as compact JSON
+			b, _ := json.Marshal(v)
+			fields[key] = string(b)
+		}
+	}
+}
+
+// quoteLogfmt quotes a value when it is empty or contains spaces, quotes or equal signs
+func quoteLogfmt(v string) string {
+	if v == "" || strings.ContainsAny(v, " =\"\t\r\n") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// RawEncoder writes record payloads unchanged, without any framing.
+type RawEncoder struct{}
+
+// NewRawEncoder creates a new raw bytes encoder.
+func NewRawEncoder() *RawEncoder {
+	return &RawEncoder{}
+}
+
+// Encode writes the payload as is
+func (e *RawEncoder) Encode(w io.Writer, p []byte) error {
+	_, err := w.Write(p)
+	return err
+}
+
+// Close is a no-op
+func (e *RawEncoder) Close(io.Writer) error { return nil }
+
+// PrettyEncoder writes each record as indented JSON for reading on a console.
+type PrettyEncoder struct {
+	indent string
+}
+
+// NewPrettyEncoder creates a new pretty JSON encoder; an empty indent defaults to a tab.
+func NewPrettyEncoder(indent string) *PrettyEncoder {
+	if indent == "" {
+		indent = "\t"
+	}
+	return &PrettyEncoder{indent: indent}
+}
+
+// Encode writes the indented record followed by a newline
+func (e *PrettyEncoder) Encode(w io.Writer, p []byte) error {
+	var b bytes.Buffer
+	if err := json.Indent(&b, p, "", e.indent); err != nil {
+		return fmt.Errorf("failed to indent record: %w", err)
+	}
+	b.WriteByte('\n')
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// Close is a no-op
+func (e *PrettyEncoder) Close(io.Writer) error { return nil }
@@ -0,0 +1,82 @@
+package sink_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+// encodeAll encodes the payloads and closes the encoder, returning the output
+func encodeAll(t *testing.T, enc sink.Encoder, payloads ...string) string {
+	t.Helper()
+
+	var b bytes.Buffer
+	for _, p := range payloads {
+		assert.NoError(t, enc.Encode(&b, []byte(p)))
+	}
+	assert.NoError(t, enc.Close(&b))
+	return b.String()
+}
+
+func TestEncoders(t *testing.T) {
+	tests := []struct {
+		name     string
+		enc      sink.Encoder
+		payloads []string
+		expected string
+	}{
+		{
+			name:     "json array",
+			enc:      sink.NewJSONEncoder(),
+			payloads: []string{`{"a": 1}`, `{"b": 2}`},
+			expected: "[{\"a\":1},{\"b\":2}]\n",
+		},
+		{
+			name:     "empty json array",
+			enc:      sink.NewJSONEncoder(),
+			expected: "[]\n",
+		},
+		{
+			name:     "ndjson",
+			enc:      sink.NewNDJSONEncoder(),
+			payloads: []string{`{"a": 1}`, `{"b": 2}`},
+			expected: "{\"a\":1}\n{\"b\":2}\n",
+		},
+		{
+			name:     "logfmt",
+			enc:      sink.NewLogfmtEncoder(),
+			payloads: []string{`{"msg":"disk full","level":"warn","host":{"name":"db1"},"code":507,"ok":false,"tags":[1,2],"empty":""}`},
+			expected: "code=507 empty=\"\" host.name=db1 level=warn msg=\"disk full\" ok=false tags=[1,2]\n",
+		},
+		{
+			name:     "raw",
+			enc:      sink.NewRawEncoder(),
+			payloads: []string{"a", "b\n"},
+			expected: "ab\n",
+		},
+		{
+			name:     "pretty",
+			enc:      sink.NewPrettyEncoder("  "),
+			payloads: []string{`{"a":1}`},
+			expected: "{\n  \"a\": 1\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, encodeAll(t, tt.enc, tt.payloads...))
+		})
+	}
+}
+
+func TestEncoders_InvalidInput(t *testing.T) {
+	var b bytes.Buffer
+	assert.Error(t, sink.NewJSONEncoder().Encode(&b, []byte("not json")))
+	assert.Error(t, sink.NewNDJSONEncoder().Encode(&b, []byte("not json")))
+	assert.Error(t, sink.NewLogfmtEncoder().Encode(&b, []byte("[1,2]")))
+	assert.Error(t, sink.NewLogfmtEncoder().Encode(&b, []byte("null")))
+	assert.Error(t, sink.NewPrettyEncoder("").Encode(&b, []byte("not json")))
+	assert.Empty(t, b.String())
+}
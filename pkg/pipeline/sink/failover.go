@@ -0,0 +1,246 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Failover implements the sink interface
+var _ pl.Sink[any] = (*Failover[any])(nil)
+
+// ErrFailoverSink is the error returned by the failover sink
+var ErrFailoverSink = errors.New("sink error")
+
+// FailoverSinkPrefix is the prefix for the failover sink error
+const FailoverSinkPrefix = "failover sink"
+
+// FailoverConfig is the configuration for the failover sink.
+type FailoverConfig struct {
+	FailureThreshold int           // error events from the primary within FailureWindow before failing over, defaults to 5
+	FailureWindow    time.Duration // window error events are counted in, defaults to 30 seconds
+
+	Probe         func(ctx context.Context) error // optional health check of the primary destination
+	ProbeInterval time.Duration                   // time between health checks while failed over, defaults to 10 seconds
+
+	HandoffTimeout time.Duration // wait for the primary to take a record before failing over, defaults to 5 seconds
+}
+
+// Failover is a sink that writes to a primary sink and fails over to a secondary sink,
+// e.g. a local file, when the primary reports persistent errors.
+//
+// The sink also fails over when the primary does not take a record within HandoffTimeout, so a
+// hung primary does not stall the pipeline, and the record is written to the secondary instead.
+// While failed over the primary is probed every ProbeInterval and records are routed back
+// to it once the probe succeeds. Without a probe the primary is tried again after a single
+// ProbeInterval. Records the primary failed to write are not replayed to the secondary. A Load that
+// starts while failed over, e.g. when the pipeline is restarted, keeps routing records to the
+// secondary and resumes the probing.
+type Failover[I any] struct {
+	primary          pl.Sink[I]
+	secondary        pl.Sink[I]
+	failureThreshold int
+	failureWindow    time.Duration
+	probe            func(ctx context.Context) error
+	probeInterval    time.Duration
+	handoffTimeout   time.Duration
+
+	failedOver atomic.Bool
+	mu         sync.Mutex
+	failures   []time.Time
+}
+
+// NewFailover creates a new failover sink with the given primary and secondary sinks.
+func NewFailover[I any](primary, secondary pl.Sink[I], conf FailoverConfig) (*Failover[I], error) {
+	if primary == nil || secondary == nil {
+		return nil, fmt.Errorf("%s: %w: primary and secondary sinks are required", FailoverSinkPrefix, ErrFailoverSink)
+	}
+
+	if conf.FailureThreshold <= 0 {
+		conf.FailureThreshold = 5
+	}
+
+	if conf.FailureWindow <= 0 {
+		conf.FailureWindow = 30 * time.Second
+	}
+
+	if conf.ProbeInterval <= 0 {
+		conf.ProbeInterval = 10 * time.Second
+	}
+
+	if conf.HandoffTimeout <= 0 {
+		conf.HandoffTimeout = 5 * time.Second
+	}
+
+	return &Failover[I]{
+		primary:          primary,
+		secondary:        secondary,
+		failureThreshold: conf.FailureThreshold,
+		failureWindow:    conf.FailureWindow,
+		probe:            conf.Probe,
+		probeInterval:    conf.ProbeInterval,
+		handoffTimeout:   conf.HandoffTimeout,
+	}, nil
+}
+
+// FailedOver reports whether records are currently routed to the secondary sink.
+func (f *Failover[I]) FailedOver() bool {
+	return f.failedOver.Load()
+}
+
+// Load routes records from the in channel to the active sink.
+// It blocks until the in channel is closed, both sinks have returned and the probing of the primary
+// has stopped.
+func (f *Failover[I]) Load(in <-chan I, eventC chan<- pl.Event) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	probe := func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f.recover(ctx, eventC)
+		}()
+	}
+	// failedOver starts probing the primary once records are routed to the secondary
+	failedOver := func(reason string) {
+		pl.SendEvent(eventC, pl.NewLogEvent(FailoverSinkPrefix, pl.LevelWarn, reason+", routing records to secondary"))
+		probe()
+	}
+	// a previous Load stopped while failed over, the probing resumes with this one
+	if f.failedOver.Load() {
+		probe()
+	}
+
+	primaryC := make(chan I)
+	secondaryC := make(chan I)
+	// watch the primary events for persistent errors
+	primaryRelay := newEventRelay(100, func(e pl.Event) {
+		pl.SendEvent(eventC, attributeEvent("primary", e))
+		if e.Type() == pl.EventError && f.recordFailure() {
+			failedOver("primary sink failing")
+		}
+	})
+	secondaryRelay := relayEvents("secondary", eventC)
+
+	// the relays run within the sink goroutines, so the probing is started before they are done
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		f.primary.Load(primaryC, primaryRelay.C)
+		primaryRelay.Stop()
+	}()
+
+	go func() {
+		defer wg.Done()
+		f.secondary.Load(secondaryC, secondaryRelay.C)
+		secondaryRelay.Stop()
+	}()
+
+	timer := time.NewTimer(f.handoffTimeout)
+	timer.Stop()
+	for record := range in {
+		if f.failedOver.Load() {
+			secondaryC <- record
+			continue
+		}
+
+		select {
+		case primaryC <- record:
+			continue
+		default:
+		}
+
+		timer.Reset(f.handoffTimeout)
+		select {
+		case primaryC <- record:
+			timer.Stop()
+		case <-timer.C:
+			if f.failOver() {
+				failedOver("primary sink not taking records")
+			}
+			secondaryC <- record
+		}
+	}
+
+	// stop the probing with the input
+	cancel()
+	close(primaryC)
+	close(secondaryC)
+	wg.Wait()
+}
+
+// failOver routes records to the secondary and reports whether the sink just failed over
+func (f *Failover[I]) failOver() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.failures = nil
+	return f.failedOver.CompareAndSwap(false, true)
+}
+
+// recordFailure records a primary error and reports whether the sink just failed over
+func (f *Failover[I]) recordFailure() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failedOver.Load() {
+		return false
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-f.failureWindow)
+	recent := f.failures[:0]
+	for _, t := range f.failures {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	f.failures = append(recent, now)
+
+	if len(f.failures) < f.failureThreshold {
+		return false
+	}
+
+	f.failures = nil
+	f.failedOver.Store(true)
+	return true
+}
+
+// recover probes the primary until it is healthy and routes records back to it
+func (f *Failover[I]) recover(ctx context.Context, eventC chan<- pl.Event) {
+	ticker := time.NewTicker(f.probeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if f.probe != nil {
+			if err := f.probe(ctx); err != nil {
+				pl.SendEvent(eventC, pl.NewErrorEvent(
+					"primary sink health probe failed",
+					err,
+					true))
+				continue
+			}
+		}
+
+		f.mu.Lock()
+		f.failures = nil
+		f.failedOver.Store(false)
+		f.mu.Unlock()
+
+		pl.SendEvent(eventC, pl.NewLogEvent(FailoverSinkPrefix, pl.LevelInfo, "primary sink healthy, routing records to primary"))
+		return
+	}
+}
@@ -0,0 +1,174 @@
+package sink_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+func TestFailover_Load(t *testing.T) {
+	t.Run("writes to the primary while it is healthy", func(t *testing.T) {
+		primary, secondary := &collectSink{}, &collectSink{}
+		failover, err := sink.NewFailover[string](primary, secondary, sink.FailoverConfig{})
+		assert.NoError(t, err)
+
+		in := make(chan string, 2)
+		in <- "1"
+		in <- "2"
+		close(in)
+
+		failover.Load(in, nil)
+
+		assert.Equal(t, []string{"1", "2"}, primary.records)
+		assert.Empty(t, secondary.records)
+	})
+
+	t.Run("fails over on persistent errors and back after a successful probe", func(t *testing.T) {
+		primary, secondary := &collectSink{fail: true}, &collectSink{}
+		var healthy atomic.Bool
+
+		failover, err := sink.NewFailover[string](primary, secondary, sink.FailoverConfig{
+			FailureThreshold: 2,
+			ProbeInterval:    5 * time.Millisecond,
+			Probe: func(context.Context) error {
+				if !healthy.Load() {
+					return errors.New("unreachable")
+				}
+				return nil
+			},
+		})
+		assert.NoError(t, err)
+
+		in := make(chan string)
+		eventC := make(chan pipeline.Event, 100)
+		done := make(chan struct{})
+		go func() {
+			failover.Load(in, eventC)
+			close(done)
+		}()
+
+		in <- "1"
+		in <- "2"
+		assert.Eventually(t, failover.FailedOver, time.Second, time.Millisecond)
+
+		in <- "3"
+
+		primary.mu.Lock()
+		primary.fail = false
+		primary.mu.Unlock()
+		healthy.Store(true)
+		assert.Eventually(t, func() bool { return !failover.FailedOver() }, time.Second, time.Millisecond)
+
+		in <- "4"
+		close(in)
+		<-done
+
+		assert.Equal(t, []string{"1", "2", "4"}, primary.records)
+		assert.Equal(t, []string{"3"}, secondary.records)
+	})
+}
+
+func TestNewFailover_Validation(t *testing.T) {
+	_, err := sink.NewFailover[string](nil, &collectSink{}, sink.FailoverConfig{})
+	assert.ErrorIs(t, err, sink.ErrFailoverSink)
+}
+
+func TestFailover_HungPrimary(t *testing.T) {
+	primary := &collectSink{block: make(chan struct{})}
+	secondary := &collectSink{}
+	failover, err := sink.NewFailover[string](primary, secondary, sink.FailoverConfig{
+		HandoffTimeout: 5 * time.Millisecond,
+		ProbeInterval:  time.Hour,
+	})
+	assert.NoError(t, err)
+
+	in := make(chan string, 2)
+	in <- "1"
+	in <- "2"
+	close(in)
+
+	eventC := make(chan pipeline.Event, 10)
+	done := make(chan struct{})
+	go func() {
+		failover.Load(in, eventC)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		secondary.mu.Lock()
+		defer secondary.mu.Unlock()
+		return len(secondary.records) == 2
+	}, time.Second, time.Millisecond)
+	assert.True(t, failover.FailedOver())
+
+	// Load returns once the primary does, without waiting for the probe interval
+	close(primary.block)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("failover sink did not return")
+	}
+	assert.Empty(t, primary.records)
+}
+
+func TestFailover_Reload(t *testing.T) {
+	primary, secondary := &collectSink{fail: true}, &collectSink{}
+	failover, err := sink.NewFailover[string](primary, secondary, sink.FailoverConfig{
+		FailureThreshold: 1,
+		ProbeInterval:    5 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	// the first Load stops while failed over, before the primary is probed
+	in := make(chan string)
+	done := make(chan struct{})
+	go func() {
+		failover.Load(in, make(chan pipeline.Event, 10))
+		close(done)
+	}()
+	in <- "1"
+	assert.Eventually(t, failover.FailedOver, time.Second, time.Millisecond)
+	close(in)
+	<-done
+	assert.True(t, failover.FailedOver())
+
+	primary.mu.Lock()
+	primary.fail = false
+	primary.mu.Unlock()
+
+	// the second Load resumes the probing and routes records back to the primary
+	in = make(chan string)
+	done = make(chan struct{})
+	go func() {
+		failover.Load(in, make(chan pipeline.Event, 10))
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return !failover.FailedOver() }, time.Second, time.Millisecond)
+	in <- "2"
+	close(in)
+	<-done
+
+	assert.Equal(t, []string{"1", "2"}, primary.records)
+}
+
+func TestFailover_LateEvents(t *testing.T) {
+	late := lateSink[string]{done: make(chan struct{})}
+	failover, err := sink.NewFailover[string](late, &collectSink{}, sink.FailoverConfig{
+		FailureThreshold: 1,
+		ProbeInterval:    time.Millisecond,
+	})
+	assert.NoError(t, err)
+
+	in := make(chan string, 1)
+	in <- "1"
+	close(in)
+
+	failover.Load(in, make(chan pipeline.Event, 10))
+	<-late.done
+}
@@ -0,0 +1,23 @@
+package sink
+
+import "strings"
+
+// Column maps a field of the JSON payload to a table column.
+type Column struct {
+	Name  string // column name in the table
+	Field string // dot separated path of the field in the payload
+}
+
+// lookupField returns the value at the dot separated path in a decoded JSON object.
+// It returns nil when any element of the path is missing.
+func lookupField(record map[string]any, path string) any {
+	var current any = record
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = obj[key]
+	}
+	return current
+}
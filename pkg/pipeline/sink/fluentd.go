@@ -0,0 +1,448 @@
+package sink
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Fluentd implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*Fluentd)(nil)
+
+// ErrFluentdSink is the error returned by the Fluentd sink
+var ErrFluentdSink = errors.New("sink error")
+
+// FluentdSinkPrefix is the prefix for the Fluentd sink error
+const FluentdSinkPrefix = "fluentd sink"
+
+// errFluentdAuth is returned when the server rejects the handshake
+var errFluentdAuth = errors.New("fluentd authentication failed")
+
+// FluentdConfig is the configuration for the Fluentd sink.
+type FluentdConfig struct {
+	Addr string      // forward input address as host:port
+	TLS  *tls.Config // optional TLS configuration
+
+	SharedKey string // shared key of the forward input, enables the authentication handshake
+	Username  string // optional username of the forward input's user authentication
+	Password  string // password of the forward input's user authentication
+	Hostname  string // client hostname sent in the handshake, defaults to the host name
+
+	Tag       string // tag template, e.g. "krapht.{{.type}}"
+	TimeField string // optional dot separated path of the event time, an RFC 3339 string or Unix seconds
+
+	RequireAck    bool          // wait for the server to acknowledge each chunk before acking upstream
+	BatchSize     int           // events per forward message, defaults to 1000
+	FlushInterval time.Duration // max time a partial batch is held, defaults to 1 second
+
+	Timeout      time.Duration // timeout for connecting, writing a message and reading its ack, defaults to 30 seconds
+	MaxRetries   int           // attempts to reconnect and send a batch, defaults to 3
+	RetryBackoff time.Duration // initial backoff between attempts, defaults to 1 second
+}
+
+// Fluentd is a sink that forwards records to fluentd or fluent-bit using the forward protocol.
+//
+// JSON object payloads are sent as the event record; other payloads are sent as the
+// "message" field. Each batch is sent as one forward mode message per tag. Upstream messages
+// are acked once the batch is written or, with RequireAck, once the server acknowledges it.
+type Fluentd struct {
+	addr          string
+	tls           *tls.Config
+	sharedKey     string
+	username      string
+	password      string
+	hostname      string
+	tag           *recordTemplate
+	timeField     string
+	requireAck    bool
+	batchSize     int
+	flushInterval time.Duration
+	timeout       time.Duration
+	maxRetries    int
+	retryBackoff  time.Duration
+
+	conn net.Conn
+	dec  *msgpack.Decoder
+}
+
+// NewFluentd creates a new Fluentd sink with the given configuration.
+// The connection is established when the first batch is sent.
+func NewFluentd(conf FluentdConfig) (*Fluentd, error) {
+	if conf.Addr == "" {
+		return nil, fmt.Errorf("%s: %w: address is empty", FluentdSinkPrefix, ErrFluentdSink)
+	}
+
+	if conf.Tag == "" {
+		return nil, fmt.Errorf("%s: %w: tag is empty", FluentdSinkPrefix, ErrFluentdSink)
+	}
+
+	if conf.Username != "" && conf.SharedKey == "" {
+		return nil, fmt.Errorf("%s: %w: user authentication requires a shared key", FluentdSinkPrefix, ErrFluentdSink)
+	}
+
+	if conf.Hostname == "" {
+		conf.Hostname, _ = os.Hostname()
+	}
+
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 1000
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 30 * time.Second
+	}
+
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 3
+	}
+
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = time.Second
+	}
+
+	tag, err := newRecordTemplate("tag", conf.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid tag template: %w", FluentdSinkPrefix, ErrFluentdSink, err)
+	}
+
+	return &Fluentd{
+		addr:          conf.Addr,
+		tls:           conf.TLS,
+		sharedKey:     conf.SharedKey,
+		username:      conf.Username,
+		password:      conf.Password,
+		hostname:      conf.Hostname,
+		tag:           tag,
+		timeField:     conf.TimeField,
+		requireAck:    conf.RequireAck,
+		batchSize:     conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		timeout:       conf.Timeout,
+		maxRetries:    conf.MaxRetries,
+		retryBackoff:  conf.RetryBackoff,
+	}, nil
+}
+
+// fluentdEntry is an event of a forward mode message
+type fluentdEntry struct {
+	time   time.Time
+	record map[string]any
+}
+
+// fluentdMessage is a forward mode message holding the events of a tag
+type fluentdMessage struct {
+	tag     string
+	entries []fluentdEntry
+	records []pl.DataRawReadable
+}
+
+// Load batches records from the in channel and forwards each batch to the server.
+// It blocks until the in channel is closed and the final batch is sent.
+func (f *Fluentd) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	defer f.close()
+
+	collectBatches(in, f.batchSize, f.flushInterval, func(batch []pl.DataRawReadable) {
+		f.flush(batch, eventC)
+	})
+}
+
+// flush groups a batch by tag, sends a forward message per tag and acks the records sent
+func (f *Fluentd) flush(batch []pl.DataRawReadable, eventC chan<- pl.Event) {
+	var messages []*fluentdMessage
+	byTag := make(map[string]*fluentdMessage)
+
+	for _, drr := range batch {
+		tag, entry, err := f.entry(drr)
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to convert record to fluentd event",
+				err,
+				false,
+				drr))
+			continue
+		}
+
+		msg, ok := byTag[tag]
+		if !ok {
+			msg = &fluentdMessage{tag: tag}
+			byTag[tag] = msg
+			messages = append(messages, msg)
+		}
+		msg.entries = append(msg.entries, entry)
+		msg.records = append(msg.records, drr)
+	}
+
+	for _, msg := range messages {
+		err := withRetry(f.maxRetries, f.retryBackoff, isFluentdTemporary, func() error {
+			return f.send(msg)
+		})
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to forward events to fluentd",
+				err,
+				isFluentdTemporary(err),
+				msg.records))
+			continue
+		}
+
+		// ack the messages once the server received or acknowledged them
+		for _, drr := range msg.records {
+			ackMessage(drr.Raw(), eventC)
+		}
+	}
+}
+
+// entry converts a record to its tag and event
+func (f *Fluentd) entry(drr pl.DataRawReadable) (string, fluentdEntry, error) {
+	p, err := drr.Data().Read()
+	if err != nil {
+		return "", fluentdEntry{}, err
+	}
+
+	tag, err := f.tag.render(p)
+	if err != nil {
+		return "", fluentdEntry{}, err
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(p, &record); err != nil {
+		record = map[string]any{"message": string(p)}
+	}
+
+	ts := time.Now()
+	if f.timeField != "" {
+		if ts, err = recordTime(lookupField(record, f.timeField)); err != nil {
+			return "", fluentdEntry{}, fmt.Errorf("field %s: %w", f.timeField, err)
+		}
+	}
+
+	return tag, fluentdEntry{time: ts, record: record}, nil
+}
+
+// send writes a forward message, connecting first when there is no connection,
+// and waits for its ack when required. The connection is dropped after a failure.
+func (f *Fluentd) send(msg *fluentdMessage) error {
+	if f.conn == nil {
+		if err := f.connect(); err != nil {
+			return err
+		}
+	}
+
+	option := map[string]any{"size": len(msg.entries)}
+	var chunk string
+	if f.requireAck {
+		chunk = newFluentdChunk()
+		option["chunk"] = chunk
+	}
+
+	p, err := encodeFluentdMessage(msg, option)
+	if err != nil {
+		return err
+	}
+
+	if err := f.conn.SetDeadline(time.Now().Add(f.timeout)); err != nil {
+		f.close()
+		return err
+	}
+
+	if _, err := f.conn.Write(p); err != nil {
+		f.close()
+		return err
+	}
+
+	if !f.requireAck {
+		return nil
+	}
+
+	var resp map[string]any
+	if err := f.dec.Decode(&resp); err != nil {
+		f.close()
+		return err
+	}
+	if resp["ack"] != chunk {
+		f.close()
+		return fmt.Errorf("unexpected ack %v for chunk %s", resp["ack"], chunk)
+	}
+	return nil
+}
+
+// connect dials the server and runs the authentication handshake when a shared key is set
+func (f *Fluentd) connect() error {
+	dialer := &net.Dialer{Timeout: f.timeout}
+
+	var conn net.Conn
+	var err error
+	if f.tls != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", f.addr, f.tls)
+	} else {
+		conn, err = dialer.Dial("tcp", f.addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	f.conn = conn
+	f.dec = msgpack.NewDecoder(conn)
+
+	if f.sharedKey == "" {
+		return nil
+	}
+
+	if err := conn.SetDeadline(time.Now().Add(f.timeout)); err != nil {
+		f.close()
+		return err
+	}
+	if err := f.handshake(); err != nil {
+		f.close()
+		return err
+	}
+	return nil
+}
+
+// handshake answers the server HELO with a PING and verifies the PONG
+func (f *Fluentd) handshake() error {
+	var helo []any
+	if err := f.dec.Decode(&helo); err != nil {
+		return err
+	}
+	if len(helo) < 2 || helo[0] != "HELO" {
+		return fmt.Errorf("unexpected handshake message %v", helo)
+	}
+	options, _ := helo[1].(map[string]any)
+	nonce := fluentdBytes(options["nonce"])
+	authSalt := fluentdBytes(options["auth"])
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	sharedSalt := hex.EncodeToString(salt)
+
+	var password string
+	if len(authSalt) > 0 {
+		password = fluentdDigest(authSalt, f.username, f.password)
+	}
+
+	ping := []any{"PING", f.hostname, sharedSalt, fluentdDigest(sharedSalt, f.hostname, nonce, f.sharedKey), f.username, password}
+	p, err := msgpack.Marshal(ping)
+	if err != nil {
+		return err
+	}
+	if _, err := f.conn.Write(p); err != nil {
+		return err
+	}
+
+	var pong []any
+	if err := f.dec.Decode(&pong); err != nil {
+		return err
+	}
+	if len(pong) < 5 || pong[0] != "PONG" {
+		return fmt.Errorf("unexpected handshake message %v", pong)
+	}
+	if ok, _ := pong[1].(bool); !ok {
+		return fmt.Errorf("%w: %v", errFluentdAuth, pong[2])
+	}
+
+	serverHostname, _ := pong[3].(string)
+	if pong[4] != fluentdDigest(sharedSalt, serverHostname, nonce, f.sharedKey) {
+		return fmt.Errorf("%w: server shared key mismatch", errFluentdAuth)
+	}
+	return nil
+}
+
+// close closes the connection, if any
+func (f *Fluentd) close() {
+	if f.conn != nil {
+		_ = f.conn.Close()
+		f.conn = nil
+		f.dec = nil
+	}
+}
+
+// isFluentdTemporary reports whether an error is worth retrying.
+// Rejected authentication is permanent; connection and protocol errors are temporary.
+func isFluentdTemporary(err error) bool {
+	return !errors.Is(err, errFluentdAuth)
+}
+
+// encodeFluentdMessage encodes a forward mode message as [tag, [[time, record], ...], option].
+// Times are encoded as the EventTime extension with nanosecond precision.
+func encodeFluentdMessage(msg *fluentdMessage, option map[string]any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+
+	if err := enc.EncodeArrayLen(3); err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeString(msg.tag); err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeArrayLen(len(msg.entries)); err != nil {
+		return nil, err
+	}
+
+	eventTime := make([]byte, 8)
+	for _, entry := range msg.entries {
+		if err := enc.EncodeArrayLen(2); err != nil {
+			return nil, err
+		}
+		if err := enc.EncodeExtHeader(0, len(eventTime)); err != nil {
+			return nil, err
+		}
+		binary.BigEndian.PutUint32(eventTime, uint32(entry.time.Unix()))
+		binary.BigEndian.PutUint32(eventTime[4:], uint32(entry.time.Nanosecond()))
+		buf.Write(eventTime)
+		if err := enc.Encode(entry.record); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := enc.Encode(option); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fluentdDigest returns the hex SHA-512 digest of the concatenated parts
+func fluentdDigest(parts ...string) string {
+	h := sha512.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fluentdBytes returns a handshake value sent as str or bin
+func fluentdBytes(v any) string {
+	switch b := v.(type) {
+	case string:
+		return b
+	case []byte:
+		return string(b)
+	}
+	return ""
+}
+
+// newFluentdChunk returns a unique chunk id
+func newFluentdChunk() string {
+	p := make([]byte, 16)
+	_, _ = rand.Read(p)
+	return base64.StdEncoding.EncodeToString(p)
+}
@@ -0,0 +1,190 @@
+package sink
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// fluentdEvent is an event received by the fake forward input
+type fluentdEvent struct {
+	tag    string
+	time   time.Time
+	record map[string]any
+}
+
+// fakeFluentd is a forward input accepting a single connection
+type fakeFluentd struct {
+	sharedKey string
+	events    chan fluentdEvent
+	pings     chan []any
+}
+
+// serve accepts a connection, runs the handshake when a shared key is set and reads messages
+func (s *fakeFluentd) serve(l net.Listener) {
+	conn, err := l.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	dec := msgpack.NewDecoder(conn)
+
+	if s.sharedKey != "" {
+		helo, _ := msgpack.Marshal([]any{"HELO", map[string]any{"nonce": []byte("nonce"), "auth": "", "keepalive": true}})
+		_, _ = conn.Write(helo)
+
+		var ping []any
+		if err := dec.Decode(&ping); err != nil {
+			return
+		}
+		s.pings <- ping
+
+		salt, _ := ping[2].(string)
+		ok := ping[3] == fluentdDigest(salt, ping[1].(string), "nonce", s.sharedKey)
+		pong, _ := msgpack.Marshal([]any{"PONG", ok, "shared key mismatch", "aggregator", fluentdDigest(salt, "aggregator", "nonce", s.sharedKey)})
+		_, _ = conn.Write(pong)
+		if !ok {
+			return
+		}
+	}
+
+	for {
+		if _, err := dec.DecodeArrayLen(); err != nil {
+			return
+		}
+		tag, _ := dec.DecodeString()
+		n, _ := dec.DecodeArrayLen()
+		for range n {
+			_, _ = dec.DecodeArrayLen()
+			_, _, _ = dec.DecodeExtHeader()
+			eventTime := make([]byte, 8)
+			_ = dec.ReadFull(eventTime)
+			record, _ := dec.DecodeMap()
+			s.events <- fluentdEvent{
+				tag:    tag,
+				time:   time.Unix(int64(binary.BigEndian.Uint32(eventTime)), int64(binary.BigEndian.Uint32(eventTime[4:]))).UTC(),
+				record: record,
+			}
+		}
+
+		option, _ := dec.DecodeMap()
+		if chunk, ok := option["chunk"]; ok {
+			ack, _ := msgpack.Marshal(map[string]any{"ack": chunk})
+			_, _ = conn.Write(ack)
+		}
+	}
+}
+
+// startFluentd starts a fake forward input and returns its address
+func startFluentd(t *testing.T, s *fakeFluentd) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	go s.serve(l)
+	return l.Addr().String()
+}
+
+func TestFluentd_Load(t *testing.T) {
+	t.Run("forwards events per tag and waits for acks", func(t *testing.T) {
+		srv := &fakeFluentd{events: make(chan fluentdEvent, 3)}
+		f, err := NewFluentd(FluentdConfig{
+			Addr:       startFluentd(t, srv),
+			Tag:        "krapht.{{.type}}",
+			TimeField:  "ts",
+			RequireAck: true,
+		})
+		assert.NoError(t, err)
+
+		records := []ackableRecord{
+			newAckableRecord(`{"type":"fw","ts":"2026-01-02T03:04:05.5Z"}`),
+			newAckableRecord(`{"type":"ids","ts":1767322800}`),
+			newAckableRecord(`{"type":"fw","ts":1767322800}`),
+		}
+		in := make(chan pipeline.DataRawReadable, len(records))
+		for _, r := range records {
+			in <- r
+		}
+		close(in)
+
+		f.Load(in, nil)
+
+		first, second, third := <-srv.events, <-srv.events, <-srv.events
+		assert.Equal(t, "krapht.fw", first.tag)
+		assert.Equal(t, time.Date(2026, 1, 2, 3, 4, 5, 5e8, time.UTC), first.time)
+		assert.Equal(t, "fw", first.record["type"])
+		assert.Equal(t, "krapht.fw", second.tag)
+		assert.Equal(t, "krapht.ids", third.tag)
+
+		for _, r := range records {
+			assert.True(t, r.raw.acked)
+		}
+	})
+
+	t.Run("authenticates with a shared key", func(t *testing.T) {
+		srv := &fakeFluentd{sharedKey: "secret", events: make(chan fluentdEvent, 1), pings: make(chan []any, 1)}
+		f, err := NewFluentd(FluentdConfig{
+			Addr:       startFluentd(t, srv),
+			SharedKey:  "secret",
+			Hostname:   "collector",
+			Tag:        "krapht",
+			RequireAck: true,
+		})
+		assert.NoError(t, err)
+
+		record := newAckableRecord("plain text")
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		f.Load(in, nil)
+
+		ping := <-srv.pings
+		assert.Equal(t, "PING", ping[0])
+		assert.Equal(t, "collector", ping[1])
+		assert.Equal(t, map[string]any{"message": "plain text"}, (<-srv.events).record)
+		assert.True(t, record.raw.acked)
+	})
+
+	t.Run("reports rejected authentication as permanent", func(t *testing.T) {
+		srv := &fakeFluentd{sharedKey: "secret", events: make(chan fluentdEvent, 1), pings: make(chan []any, 1)}
+		f, err := NewFluentd(FluentdConfig{
+			Addr:      startFluentd(t, srv),
+			SharedKey: "wrong",
+			Tag:       "krapht",
+		})
+		assert.NoError(t, err)
+
+		record := newAckableRecord(`{"n":1}`)
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		f.Load(in, eventC)
+
+		errEvent, ok := (<-eventC).(pipeline.ErrorEvent)
+		assert.True(t, ok)
+		assert.ErrorIs(t, errEvent, errFluentdAuth)
+		assert.False(t, errEvent.IsTemporary())
+		assert.False(t, record.raw.acked)
+	})
+}
+
+func TestNewFluentd_Validation(t *testing.T) {
+	_, err := NewFluentd(FluentdConfig{Tag: "krapht"})
+	assert.ErrorIs(t, err, ErrFluentdSink)
+
+	_, err = NewFluentd(FluentdConfig{Addr: "localhost:24224"})
+	assert.ErrorIs(t, err, ErrFluentdSink)
+
+	_, err = NewFluentd(FluentdConfig{Addr: "localhost:24224", Tag: "krapht", Username: "u"})
+	assert.ErrorIs(t, err, ErrFluentdSink)
+}
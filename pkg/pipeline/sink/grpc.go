@@ -0,0 +1,184 @@
+package sink
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Static check that GRPC implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*GRPC)(nil)
+
+// ErrGRPCSink is the error returned by the gRPC sink
+var ErrGRPCSink = errors.New("sink error")
+
+// GRPCSinkPrefix is the prefix for the gRPC sink error
+const GRPCSinkPrefix = "grpc sink"
+
+// GRPCForwardMethod is the full name of the client streaming RPC used to forward records.
+// Each window of records is sent as a stream of BytesValue messages; the receiver replies
+// with a UInt64Value holding the number of records it durably accepted, in order.
+const GRPCForwardMethod = "/krapht.forward.v1.Forwarder/Forward"
+
+// grpcForwardStream describes the client streaming forward RPC
+var grpcForwardStream = &grpc.StreamDesc{
+	StreamName:    "Forward",
+	ClientStreams: true,
+}
+
+// GRPCConfig is the configuration for the gRPC sink.
+type GRPCConfig struct {
+	Target string      // receiver address
+	TLS    *tls.Config // optional TLS configuration, plaintext when nil
+	Token  string      // optional bearer token sent with every window
+
+	WindowSize    int           // records per acknowledgment window, defaults to 500
+	FlushInterval time.Duration // max time a partial window is held, defaults to 1 second
+	Timeout       time.Duration // deadline for sending and acknowledging a window, defaults to 30 seconds
+}
+
+// GRPC is a sink that forwards records to a remote receiver over a client streaming RPC.
+// Records are sent in windows and upstream messages are acked once the receiver acknowledges the window.
+type GRPC struct {
+	conn          *grpc.ClientConn
+	token         string
+	windowSize    int
+	flushInterval time.Duration
+	timeout       time.Duration
+}
+
+// NewGRPC creates a new gRPC sink with the given configuration.
+func NewGRPC(conf GRPCConfig) (*GRPC, error) {
+	if conf.Target == "" {
+		return nil, fmt.Errorf("%s: %w: target is empty", GRPCSinkPrefix, ErrGRPCSink)
+	}
+
+	if conf.WindowSize <= 0 {
+		conf.WindowSize = 500
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 30 * time.Second
+	}
+
+	creds := insecure.NewCredentials()
+	if conf.TLS != nil {
+		creds = credentials.NewTLS(conf.TLS)
+	}
+
+	conn, err := grpc.NewClient(conf.Target, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", GRPCSinkPrefix, ErrGRPCSink, err)
+	}
+
+	return &GRPC{
+		conn:          conn,
+		token:         conf.Token,
+		windowSize:    conf.WindowSize,
+		flushInterval: conf.FlushInterval,
+		timeout:       conf.Timeout,
+	}, nil
+}
+
+// Load forwards records from the in channel in acknowledgment windows.
+// It blocks until the in channel is closed and the final window is acknowledged.
+func (g *GRPC) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	collectBatches(in, g.windowSize, g.flushInterval, func(window []pl.DataRawReadable) {
+		g.forward(window, eventC)
+	})
+}
+
+// forward sends a window of records and acks the ones the receiver accepted
+func (g *GRPC) forward(window []pl.DataRawReadable, eventC chan<- pl.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	if g.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+g.token)
+	}
+
+	stream, err := g.conn.NewStream(ctx, grpcForwardStream, GRPCForwardMethod)
+	if err != nil {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to open grpc forward stream",
+			err,
+			true,
+			window))
+		return
+	}
+
+	sent := make([]pl.DataRawReadable, 0, len(window))
+	for i, drr := range window {
+		p, err := drr.Data().Read()
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to read data",
+				err,
+				true,
+				drr))
+			continue
+		}
+
+		if err := stream.SendMsg(wrapperspb.Bytes(p)); err != nil {
+			// the stream is broken, the records not sent yet fail with it, and the records
+			// already sent are reported with the status returned by RecvMsg below
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to send record on grpc forward stream",
+				err,
+				true,
+				window[i:]))
+			break
+		}
+		sent = append(sent, drr)
+	}
+
+	if err := stream.CloseSend(); err != nil {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to close grpc forward stream",
+			err,
+			true,
+			sent))
+		return
+	}
+
+	ack := &wrapperspb.UInt64Value{}
+	if err := stream.RecvMsg(ack); err != nil {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to receive grpc window acknowledgment",
+			err,
+			true,
+			sent))
+		return
+	}
+
+	accepted := min(int(ack.GetValue()), len(sent))
+	for _, drr := range sent[:accepted] {
+		ackMessage(drr.Raw(), eventC)
+	}
+
+	if accepted < len(sent) {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"grpc receiver did not acknowledge the full window",
+			fmt.Errorf("%d of %d records acknowledged", accepted, len(sent)),
+			true,
+			sent[accepted:]))
+	}
+}
+
+// Close closes the gRPC client connection.
+func (g *GRPC) Close() error {
+	return g.conn.Close()
+}
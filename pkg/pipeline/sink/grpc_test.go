@@ -0,0 +1,221 @@
+package sink
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// fakeForwarder is an in-process receiver for the forward RPC
+type fakeForwarder struct {
+	mu      sync.Mutex
+	windows [][]string
+	auth    []string
+	limit   int  // max records acknowledged per window, 0 acknowledges all
+	token   bool // reject windows without an authorization token
+}
+
+// forward reads a window of records and replies with the acknowledged count
+func (f *fakeForwarder) forward(_ any, stream grpc.ServerStream) error {
+	md, _ := metadata.FromIncomingContext(stream.Context())
+
+	var window []string
+	for {
+		msg := &wrapperspb.BytesValue{}
+		err := stream.RecvMsg(msg)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		window = append(window, string(msg.GetValue()))
+	}
+
+	f.mu.Lock()
+	f.windows = append(f.windows, window)
+	f.auth = append(f.auth, md.Get("authorization")...)
+	f.mu.Unlock()
+
+	if f.token && len(md.Get("authorization")) == 0 {
+		return status.Error(codes.Unauthenticated, "missing token")
+	}
+
+	count := len(window)
+	if f.limit > 0 {
+		count = min(count, f.limit)
+	}
+	return stream.SendMsg(wrapperspb.UInt64(uint64(count)))
+}
+
+// startForwarder serves the forward RPC on a local port and returns its address
+func startForwarder(t *testing.T, f *fakeForwarder) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := grpc.NewServer()
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "krapht.forward.v1.Forwarder",
+		HandlerType: (*any)(nil),
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Forward",
+			Handler:       f.forward,
+			ClientStreams: true,
+		}},
+	}, f)
+
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	return lis.Addr().String()
+}
+
+func TestGRPC_Load(t *testing.T) {
+	t.Run("forwards windows and acks acknowledged records", func(t *testing.T) {
+		f := &fakeForwarder{}
+		addr := startForwarder(t, f)
+
+		g, err := NewGRPC(GRPCConfig{Target: addr, Token: "secret", WindowSize: 2})
+		assert.NoError(t, err)
+		defer g.Close()
+
+		records := []ackableRecord{newAckableRecord("a"), newAckableRecord("b"), newAckableRecord("c")}
+		in := make(chan pipeline.DataRawReadable, len(records))
+		for _, r := range records {
+			in <- r
+		}
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		g.Load(in, eventC)
+
+		assert.Equal(t, [][]string{{"a", "b"}, {"c"}}, f.windows)
+		assert.Equal(t, []string{"Bearer secret", "Bearer secret"}, f.auth)
+		assert.Empty(t, eventC)
+		for _, r := range records {
+			assert.True(t, r.raw.acked)
+		}
+	})
+
+	t.Run("only acks records within a partial acknowledgment", func(t *testing.T) {
+		f := &fakeForwarder{limit: 1}
+		addr := startForwarder(t, f)
+
+		g, err := NewGRPC(GRPCConfig{Target: addr, WindowSize: 2})
+		assert.NoError(t, err)
+		defer g.Close()
+
+		records := []ackableRecord{newAckableRecord("a"), newAckableRecord("b")}
+		in := make(chan pipeline.DataRawReadable, len(records))
+		for _, r := range records {
+			in <- r
+		}
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		g.Load(in, eventC)
+
+		assert.True(t, records[0].raw.acked)
+		assert.False(t, records[1].raw.acked)
+		assert.Len(t, eventC, 1)
+	})
+
+	t.Run("reports rejected windows", func(t *testing.T) {
+		f := &fakeForwarder{token: true}
+		addr := startForwarder(t, f)
+
+		g, err := NewGRPC(GRPCConfig{Target: addr})
+		assert.NoError(t, err)
+		defer g.Close()
+
+		record := newAckableRecord("a")
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		g.Load(in, eventC)
+
+		assert.False(t, record.raw.acked)
+		assert.Len(t, eventC, 1)
+	})
+}
+
+// unreadableRecord is a record whose data fails to read
+type unreadableRecord struct {
+	ackableRecord
+}
+
+func (unreadableRecord) Data() pipeline.Readable { return byteReadable{err: errors.New("corrupt")} }
+
+// recordsOf returns the records carried by the error events
+func recordsOf(eventC chan pipeline.Event) [][]pipeline.DataRawReadable {
+	close(eventC)
+	var records [][]pipeline.DataRawReadable
+	for e := range eventC {
+		records = append(records, failedRecords(e.(pipeline.ErrorEvent).Record()))
+	}
+	return records
+}
+
+func TestGRPC_Forward(t *testing.T) {
+	t.Run("reports the records not sent after a send failure", func(t *testing.T) {
+		f := &fakeForwarder{}
+		addr := startForwarder(t, f)
+
+		g, err := NewGRPC(GRPCConfig{Target: addr})
+		assert.NoError(t, err)
+		defer g.Close()
+
+		// messages over the send limit fail to send halfway through the window
+		g.conn, err = grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(8)))
+		assert.NoError(t, err)
+
+		a, big, c := newAckableRecord("a"), newAckableRecord("too large to send"), newAckableRecord("c")
+		eventC := make(chan pipeline.Event, 3)
+		g.forward([]pipeline.DataRawReadable{a, big, c}, eventC)
+
+		// the failed send breaks the stream, so the record sent before it is reported by RecvMsg
+		assert.False(t, a.raw.acked)
+		assert.False(t, big.raw.acked)
+		assert.False(t, c.raw.acked)
+		assert.Equal(t, [][]pipeline.DataRawReadable{{big, c}, {a}}, recordsOf(eventC))
+	})
+
+	t.Run("reports only the unreadable record of an acknowledged window", func(t *testing.T) {
+		f := &fakeForwarder{}
+		addr := startForwarder(t, f)
+
+		g, err := NewGRPC(GRPCConfig{Target: addr})
+		assert.NoError(t, err)
+		defer g.Close()
+
+		a, bad, c := newAckableRecord("a"), unreadableRecord{newAckableRecord("b")}, newAckableRecord("c")
+		eventC := make(chan pipeline.Event, 3)
+		g.forward([]pipeline.DataRawReadable{a, bad, c}, eventC)
+
+		assert.Equal(t, [][]string{{"a", "c"}}, f.windows)
+		assert.True(t, a.raw.acked)
+		assert.True(t, c.raw.acked)
+		assert.Equal(t, [][]pipeline.DataRawReadable{{bad}}, recordsOf(eventC))
+	})
+}
+
+func TestNewGRPC_Validation(t *testing.T) {
+	_, err := NewGRPC(GRPCConfig{})
+	assert.ErrorIs(t, err, ErrGRPCSink)
+}
@@ -0,0 +1,316 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Instrumented implements the sink interface
+var _ pl.Sink[any] = (*Instrumented[any])(nil)
+
+// ErrInstrumentedSink is the error returned by the instrumented sink
+var ErrInstrumentedSink = errors.New("sink error")
+
+// InstrumentedSinkPrefix is the prefix for the instrumented sink error
+const InstrumentedSinkPrefix = "instrumented sink"
+
+// Metric names recorded by the instrumented sink
+const (
+	MetricSinkRecords          = "krapht_sink_records_total"
+	MetricSinkBytes            = "krapht_sink_bytes_total"
+	MetricSinkFailures         = "krapht_sink_failures_total"
+	MetricSinkLoadLatency      = "krapht_sink_load_latency_seconds"
+	MetricSinkRecordsPerSecond = "krapht_sink_records_per_second"
+	MetricSinkBytesPerSecond   = "krapht_sink_bytes_per_second"
+)
+
+// InstrumentedConfig is the configuration for the instrumented sink.
+type InstrumentedConfig struct {
+	Name string // sink name used as the "sink" label, required
+
+	Interval      time.Duration // interval between metric events, defaults to 10 seconds
+	DisableEvents bool          // do not send metric events, e.g. when only Prometheus is used
+
+	Registerer prometheus.Registerer // optional registerer for Prometheus metrics
+	Buckets    []float64             // load latency histogram buckets, defaults to prometheus.DefBuckets
+}
+
+// Instrumented is a sink decorator that records throughput, failures and load latency of the
+// wrapped sink, as metric events and optionally as Prometheus metrics.
+//
+// Records and bytes are counted as they are handed to the wrapped sink; bytes are counted for
+// Readable, DataReadable, []byte and string records. Failures are the error events of the
+// wrapped sink. Records are handed to the wrapped sink unchanged, and load latency is the time
+// the wrapped sink takes for a record before it takes the next one, so it is only recorded
+// while the wrapped sink is busy.
+type Instrumented[I any] struct {
+	sink     pl.Sink[I]
+	name     string
+	interval time.Duration
+	events   bool
+	prom     *sinkMetrics
+
+	mu           sync.Mutex
+	records      uint64
+	bytes        uint64
+	failures     uint64
+	latencySum   float64
+	latencyCount uint64
+}
+
+// sinkMetrics holds the Prometheus collectors of an instrumented sink
+type sinkMetrics struct {
+	records  prometheus.Counter
+	bytes    prometheus.Counter
+	failures prometheus.Counter
+	latency  prometheus.Observer
+}
+
+// NewInstrumented creates a new instrumented sink wrapping s.
+func NewInstrumented[I any](s pl.Sink[I], conf InstrumentedConfig) (*Instrumented[I], error) {
+	if s == nil {
+		return nil, fmt.Errorf("%s: %w: sink is nil", InstrumentedSinkPrefix, ErrInstrumentedSink)
+	}
+
+	if conf.Name == "" {
+		return nil, fmt.Errorf("%s: %w: name is empty", InstrumentedSinkPrefix, ErrInstrumentedSink)
+	}
+
+	if conf.Interval <= 0 {
+		conf.Interval = 10 * time.Second
+	}
+
+	i := &Instrumented[I]{
+		sink:     s,
+		name:     conf.Name,
+		interval: conf.Interval,
+		events:   !conf.DisableEvents,
+	}
+
+	if conf.Registerer != nil {
+		prom, err := newSinkMetrics(conf.Registerer, conf.Name, conf.Buckets)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: %w", InstrumentedSinkPrefix, ErrInstrumentedSink, err)
+		}
+		i.prom = prom
+	}
+	return i, nil
+}
+
+// newSinkMetrics registers the sink collectors, reusing collectors already registered by other sinks
+func newSinkMetrics(reg prometheus.Registerer, name string, buckets []float64) (*sinkMetrics, error) {
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+
+	records, err := registerVec(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: MetricSinkRecords,
+		Help: "Records handed to the sink.",
+	}, []string{"sink"}))
+	if err != nil {
+		return nil, err
+	}
+
+	bytes, err := registerVec(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: MetricSinkBytes,
+		Help: "Payload bytes handed to the sink.",
+	}, []string{"sink"}))
+	if err != nil {
+		return nil, err
+	}
+
+	failures, err := registerVec(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: MetricSinkFailures,
+		Help: "Error events reported by the sink.",
+	}, []string{"sink"}))
+	if err != nil {
+		return nil, err
+	}
+
+	latency, err := registerVec(reg, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    MetricSinkLoadLatency,
+		Help:    "Time the sink takes for a record before taking the next one.",
+		Buckets: buckets,
+	}, []string{"sink"}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &sinkMetrics{
+		records:  records.WithLabelValues(name),
+		bytes:    bytes.WithLabelValues(name),
+		failures: failures.WithLabelValues(name),
+		latency:  latency.WithLabelValues(name),
+	}, nil
+}
+
+// registerVec registers a collector, returning the existing collector when it is already registered
+func registerVec[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	if err := reg.Register(c); err != nil {
+		var exists prometheus.AlreadyRegisteredError
+		if errors.As(err, &exists) {
+			if existing, ok := exists.ExistingCollector.(C); ok {
+				return existing, nil
+			}
+		}
+		return c, err
+	}
+	return c, nil
+}
+
+// Load hands records from the in channel to the wrapped sink while recording metrics.
+// It blocks until the wrapped sink returns and the final metric events are sent. Events the
+// wrapped sink sends after its Load returned are dropped.
+func (i *Instrumented[I]) Load(in <-chan I, eventC chan<- pl.Event) {
+	out := make(chan I)
+	sinkRelay := newEventRelay(100, func(e pl.Event) {
+		if e.Type() == pl.EventError {
+			i.failure()
+		}
+		pl.SendEvent(eventC, e)
+	})
+
+	reportDone := make(chan struct{})
+	stopReport := make(chan struct{})
+	go func() {
+		defer close(reportDone)
+		i.report(eventC, stopReport)
+	}()
+
+	sinkDone := make(chan struct{})
+	go func() {
+		defer close(sinkDone)
+		i.sink.Load(out, sinkRelay.C)
+	}()
+
+	var last time.Time
+	for record := range in {
+		i.handOff(payloadSize(record))
+		select {
+		case out <- record:
+		default:
+			// the wrapped sink is busy with the previous record until it takes this one
+			out <- record
+			if !last.IsZero() {
+				i.observe(time.Since(last))
+			}
+		}
+		last = time.Now()
+	}
+
+	close(out)
+	<-sinkDone
+	sinkRelay.Stop()
+	close(stopReport)
+	<-reportDone
+}
+
+// handOff counts a record handed to the wrapped sink
+func (i *Instrumented[I]) handOff(size int) {
+	i.mu.Lock()
+	i.records++
+	i.bytes += uint64(size)
+	i.mu.Unlock()
+
+	if i.prom != nil {
+		i.prom.records.Inc()
+		i.prom.bytes.Add(float64(size))
+	}
+}
+
+// failure counts an error event of the wrapped sink
+func (i *Instrumented[I]) failure() {
+	i.mu.Lock()
+	i.failures++
+	i.mu.Unlock()
+
+	if i.prom != nil {
+		i.prom.failures.Inc()
+	}
+}
+
+// observe records the load latency of a record
+func (i *Instrumented[I]) observe(d time.Duration) {
+	i.mu.Lock()
+	i.latencySum += d.Seconds()
+	i.latencyCount++
+	i.mu.Unlock()
+
+	if i.prom != nil {
+		i.prom.latency.Observe(d.Seconds())
+	}
+}
+
+// report sends metric events every interval and once more when stopped
+func (i *Instrumented[I]) report(eventC chan<- pl.Event, stop <-chan struct{}) {
+	if !i.events {
+		<-stop
+		return
+	}
+
+	ticker := time.NewTicker(i.interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	var lastRecords, lastBytes uint64
+
+	for {
+		var stopped bool
+		select {
+		case <-ticker.C:
+		case <-stop:
+			stopped = true
+		}
+
+		now := time.Now()
+		elapsed := now.Sub(last).Seconds()
+		last = now
+
+		i.mu.Lock()
+		records, bytes, failures := i.records, i.bytes, i.failures
+		latencySum, latencyCount := i.latencySum, i.latencyCount
+		i.latencySum, i.latencyCount = 0, 0
+		i.mu.Unlock()
+
+		labels := map[string]string{"sink": i.name}
+		pl.SendEvent(eventC, pl.NewMetricEvent(MetricSinkRecords, float64(records), labels, pl.MetricTypeCounter))
+		pl.SendEvent(eventC, pl.NewMetricEvent(MetricSinkBytes, float64(bytes), labels, pl.MetricTypeCounter))
+		pl.SendEvent(eventC, pl.NewMetricEvent(MetricSinkFailures, float64(failures), labels, pl.MetricTypeCounter))
+
+		if elapsed > 0 {
+			pl.SendEvent(eventC, pl.NewMetricEvent(MetricSinkRecordsPerSecond, float64(records-lastRecords)/elapsed, labels, pl.MetricTypeGauge))
+			pl.SendEvent(eventC, pl.NewMetricEvent(MetricSinkBytesPerSecond, float64(bytes-lastBytes)/elapsed, labels, pl.MetricTypeGauge))
+		}
+		lastRecords, lastBytes = records, bytes
+
+		// the mean load latency over the interval
+		if latencyCount > 0 {
+			pl.SendEvent(eventC, pl.NewMetricEvent(MetricSinkLoadLatency, latencySum/float64(latencyCount), labels, pl.MetricTypeSummary))
+		}
+
+		if stopped {
+			return
+		}
+	}
+}
+
+// payloadSize returns the payload size of a record when it can be determined without encoding it
+func payloadSize(record any) int {
+	var p []byte
+	switch r := record.(type) {
+	case pl.DataReadable:
+		p, _ = r.Data().Read()
+	case pl.Readable:
+		p, _ = r.Read()
+	case []byte:
+		p = r
+	case string:
+		return len(r)
+	}
+	return len(p)
+}
@@ -0,0 +1,169 @@
+package sink
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// metricValues returns the last value of each metric event by name
+func metricValues(eventC chan pipeline.Event) map[string]float64 {
+	close(eventC)
+	values := make(map[string]float64)
+	for e := range eventC {
+		if m, ok := e.(pipeline.MetricEvent); ok {
+			values[m.Name()] = m.Value()
+		}
+	}
+	return values
+}
+
+func TestInstrumented_Load(t *testing.T) {
+	t.Run("sends throughput and failure events", func(t *testing.T) {
+		var out bytes.Buffer
+		instrumented, err := NewInstrumented[pipeline.DataRawReadable](
+			NewWriter[pipeline.DataRawReadable](&out, nil),
+			InstrumentedConfig{Name: "console", Interval: time.Hour},
+		)
+		assert.NoError(t, err)
+
+		records := []ackableRecord{newAckableRecord(`{"n":1}`), newAckableRecord(`{"n":22}`), newAckableRecord("invalid")}
+		in := make(chan pipeline.DataRawReadable, len(records))
+		for _, r := range records {
+			in <- r
+		}
+		close(in)
+
+		eventC := make(chan pipeline.Event, 20)
+		instrumented.Load(in, eventC)
+
+		values := metricValues(eventC)
+		assert.Equal(t, float64(3), values[MetricSinkRecords])
+		assert.Equal(t, float64(22), values[MetricSinkBytes])
+		assert.Equal(t, float64(1), values[MetricSinkFailures])
+		assert.Contains(t, values, MetricSinkRecordsPerSecond)
+
+		// the raw message still acks upstream
+		assert.True(t, records[0].raw.acked)
+		assert.False(t, records[2].raw.acked)
+	})
+
+	t.Run("hands records unchanged and records the latency of a busy sink", func(t *testing.T) {
+		slow := &slowSink{delay: 5 * time.Millisecond}
+		instrumented, err := NewInstrumented[pipeline.DataRawReadable](slow, InstrumentedConfig{Name: "slow", Interval: time.Hour})
+		assert.NoError(t, err)
+
+		in := make(chan pipeline.DataRawReadable, 3)
+		for _, data := range []string{"a", "b", "c"} {
+			in <- newAckableRecord(data)
+		}
+		close(in)
+
+		eventC := make(chan pipeline.Event, 20)
+		instrumented.Load(in, eventC)
+
+		assert.Len(t, slow.records, 3)
+		for _, r := range slow.records {
+			assert.IsType(t, ackableRecord{}, r)
+		}
+
+		values := metricValues(eventC)
+		assert.GreaterOrEqual(t, values[MetricSinkLoadLatency], slow.delay.Seconds())
+	})
+
+	t.Run("does not close the events of a sink that returned early", func(t *testing.T) {
+		done := make(chan struct{})
+		instrumented, err := NewInstrumented[[]byte](earlySink{done: done}, InstrumentedConfig{Name: "early", DisableEvents: true})
+		assert.NoError(t, err)
+
+		in := make(chan []byte, 1)
+		in <- []byte("a")
+		close(in)
+
+		eventC := make(chan pipeline.Event, 10)
+		assert.NotPanics(t, func() {
+			instrumented.Load(in, eventC)
+			<-done
+		})
+	})
+
+	t.Run("records prometheus metrics", func(t *testing.T) {
+		reg := prometheus.NewRegistry()
+		conf := InstrumentedConfig{Name: "noop", Registerer: reg, DisableEvents: true}
+
+		instrumented, err := NewInstrumented[[]byte](failEverySink{}, conf)
+		assert.NoError(t, err)
+
+		// a second sink with another name reuses the registered collectors
+		_, err = NewInstrumented[[]byte](failEverySink{}, InstrumentedConfig{Name: "other", Registerer: reg})
+		assert.NoError(t, err)
+
+		in := make(chan []byte, 2)
+		in <- []byte("abc")
+		in <- []byte("de")
+		close(in)
+
+		eventC := make(chan pipeline.Event, 10)
+		instrumented.Load(in, eventC)
+
+		assert.Equal(t, float64(2), testutil.ToFloat64(instrumented.prom.records))
+		assert.Equal(t, float64(5), testutil.ToFloat64(instrumented.prom.bytes))
+		assert.Equal(t, float64(2), testutil.ToFloat64(instrumented.prom.failures))
+
+		// only the forwarded error events are sent
+		for range 2 {
+			assert.Equal(t, pipeline.EventError, (<-eventC).Type())
+		}
+		assert.Empty(t, eventC)
+	})
+}
+
+// failEverySink reports an error for every record
+type failEverySink struct{}
+
+func (failEverySink) Load(in <-chan []byte, eventC chan<- pipeline.Event) {
+	for range in {
+		pipeline.SendEvent(eventC, pipeline.NewErrorEvent("failed to write", errors.New("boom"), true))
+	}
+}
+
+// slowSink takes delay for every record, keeping the records it loaded
+type slowSink struct {
+	delay   time.Duration
+	records []pipeline.DataRawReadable
+}
+
+func (s *slowSink) Load(in <-chan pipeline.DataRawReadable, _ chan<- pipeline.Event) {
+	for r := range in {
+		time.Sleep(s.delay)
+		s.records = append(s.records, r)
+	}
+}
+
+// earlySink returns from Load at once, sending an error event for every record in the background
+type earlySink struct {
+	done chan struct{}
+}
+
+func (e earlySink) Load(in <-chan []byte, eventC chan<- pipeline.Event) {
+	go func() {
+		defer close(e.done)
+		for range in {
+			pipeline.SendEvent(eventC, pipeline.NewErrorEvent("failed to write", errors.New("boom"), true))
+		}
+	}()
+}
+
+func TestNewInstrumented_Validation(t *testing.T) {
+	_, err := NewInstrumented[[]byte](nil, InstrumentedConfig{Name: "a"})
+	assert.ErrorIs(t, err, ErrInstrumentedSink)
+
+	_, err = NewInstrumented[[]byte](failEverySink{}, InstrumentedConfig{})
+	assert.ErrorIs(t, err, ErrInstrumentedSink)
+}
@@ -0,0 +1,225 @@
+package sink
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Kafka implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*Kafka)(nil)
+
+// ErrKafkaSink is the error returned by the Kafka sink
+var ErrKafkaSink = errors.New("sink error")
+
+// KafkaSinkPrefix is the prefix for the Kafka sink error
+const KafkaSinkPrefix = "kafka sink"
+
+// KafkaAcks represents the broker acknowledgement level required for a produce request
+type KafkaAcks int8
+
+const (
+	// KafkaAcksAll waits for all in-sync replicas to confirm the write
+	KafkaAcksAll KafkaAcks = iota
+	// KafkaAcksLeader waits for the partition leader only
+	KafkaAcksLeader
+	// KafkaAcksNone does not wait for any confirmation
+	KafkaAcksNone
+)
+
+// SASL mechanisms supported by the Kafka sink
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+// KeyFunc extracts the partition key from a record.
+// Records with the same key are written to the same partition.
+type KeyFunc func(drr pl.DataRawReadable) ([]byte, error)
+
+// KafkaConfig is the configuration for the Kafka sink.
+type KafkaConfig struct {
+	Brokers []string // seed brokers
+	Topic   string   // topic records are produced to
+
+	Key        KeyFunc   // optional partition key extractor, nil uses sticky partitioning
+	Acks       KafkaAcks // required acks, defaults to all in-sync replicas
+	Idempotent bool      // enable idempotent writes, requires KafkaAcksAll
+
+	BatchMaxBytes      int32         // max bytes per partition batch, 0 uses client default
+	Linger             time.Duration // how long to wait for a batch to fill
+	MaxBufferedRecords int           // max records buffered before Load blocks
+
+	TLS           *tls.Config // optional TLS configuration
+	SASLMechanism string      // optional SASL mechanism (PLAIN, SCRAM-SHA-256, SCRAM-SHA-512)
+	SASLUser      string
+	SASLPassword  string
+}
+
+// kafkaProducer is the subset of the kgo client used by the Kafka sink
+type kafkaProducer interface {
+	Produce(ctx context.Context, r *kgo.Record, promise func(*kgo.Record, error))
+	Flush(ctx context.Context) error
+	Close()
+}
+
+// Kafka is a sink that produces records to a Kafka topic.
+// Upstream messages are acked only after the broker confirms the write.
+type Kafka struct {
+	client kafkaProducer
+	topic  string
+	key    KeyFunc
+}
+
+// NewKafka creates a new Kafka sink with the given configuration.
+func NewKafka(conf KafkaConfig) (*Kafka, error) {
+	if len(conf.Brokers) == 0 {
+		return nil, fmt.Errorf("%s: %w: brokers are empty", KafkaSinkPrefix, ErrKafkaSink)
+	}
+
+	if conf.Topic == "" {
+		return nil, fmt.Errorf("%s: %w: topic is empty", KafkaSinkPrefix, ErrKafkaSink)
+	}
+
+	if conf.Idempotent && conf.Acks != KafkaAcksAll {
+		return nil, fmt.Errorf("%s: %w: idempotent writes require all acks", KafkaSinkPrefix, ErrKafkaSink)
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(conf.Brokers...),
+		kgo.DefaultProduceTopic(conf.Topic),
+		kgo.RecordPartitioner(kgo.StickyKeyPartitioner(nil)),
+	}
+
+	switch conf.Acks {
+	case KafkaAcksAll:
+		opts = append(opts, kgo.RequiredAcks(kgo.AllISRAcks()))
+	case KafkaAcksLeader:
+		opts = append(opts, kgo.RequiredAcks(kgo.LeaderAck()))
+	case KafkaAcksNone:
+		opts = append(opts, kgo.RequiredAcks(kgo.NoAck()))
+	default:
+		return nil, fmt.Errorf("%s: %w: unknown acks value %d", KafkaSinkPrefix, ErrKafkaSink, conf.Acks)
+	}
+
+	if !conf.Idempotent {
+		opts = append(opts, kgo.DisableIdempotentWrite())
+	}
+
+	if conf.BatchMaxBytes > 0 {
+		opts = append(opts, kgo.ProducerBatchMaxBytes(conf.BatchMaxBytes))
+	}
+
+	if conf.Linger > 0 {
+		opts = append(opts, kgo.ProducerLinger(conf.Linger))
+	}
+
+	if conf.MaxBufferedRecords > 0 {
+		opts = append(opts, kgo.MaxBufferedRecords(conf.MaxBufferedRecords))
+	}
+
+	if conf.TLS != nil {
+		opts = append(opts, kgo.DialTLSConfig(conf.TLS))
+	}
+
+	if conf.SASLMechanism != "" {
+		mechanism, err := kafkaSASL(conf)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", KafkaSinkPrefix, ErrKafkaSink, err)
+	}
+
+	return &Kafka{
+		client: client,
+		topic:  conf.Topic,
+		key:    conf.Key,
+	}, nil
+}
+
+// kafkaSASL returns the SASL mechanism for the configuration
+func kafkaSASL(conf KafkaConfig) (sasl.Mechanism, error) {
+	switch conf.SASLMechanism {
+	case SASLPlain:
+		return plain.Auth{User: conf.SASLUser, Pass: conf.SASLPassword}.AsMechanism(), nil
+	case SASLScramSHA256:
+		return scram.Auth{User: conf.SASLUser, Pass: conf.SASLPassword}.AsSha256Mechanism(), nil
+	case SASLScramSHA512:
+		return scram.Auth{User: conf.SASLUser, Pass: conf.SASLPassword}.AsSha512Mechanism(), nil
+	default:
+		return nil, fmt.Errorf("%s: %w: unsupported SASL mechanism %q", KafkaSinkPrefix, ErrKafkaSink, conf.SASLMechanism)
+	}
+}
+
+// Load produces records from the in channel to Kafka.
+// It blocks until the in channel is closed and all buffered records are flushed.
+func (k *Kafka) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	ctx := context.Background()
+
+	for drr := range in {
+		p, err := drr.Data().Read()
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to read data",
+				err,
+				true,
+				drr))
+			continue
+		}
+
+		record := &kgo.Record{Topic: k.topic, Value: p}
+		if k.key != nil {
+			key, err := k.key(drr)
+			if err != nil {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"failed to extract partition key",
+					err,
+					true,
+					drr))
+				continue
+			}
+			record.Key = key
+		}
+
+		raw := drr.Raw()
+		k.client.Produce(ctx, record, func(_ *kgo.Record, err error) {
+			if err != nil {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"failed to produce message to kafka",
+					err,
+					true,
+					drr))
+				return
+			}
+
+			// ack the message once the broker confirmed the write
+			ackMessage(raw, eventC)
+		})
+	}
+
+	if err := k.client.Flush(ctx); err != nil {
+		pl.SendEvent(eventC, pl.NewErrorEvent(
+			"failed to flush kafka producer",
+			err,
+			true))
+	}
+}
+
+// Close closes the underlying Kafka client.
+func (k *Kafka) Close() {
+	k.client.Close()
+}
@@ -0,0 +1,137 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+)
+
+// fakeProducer records produced messages and completes promises synchronously
+type fakeProducer struct {
+	mu      sync.Mutex
+	records []*kgo.Record
+	err     error
+	flushed bool
+}
+
+func (f *fakeProducer) Produce(_ context.Context, r *kgo.Record, promise func(*kgo.Record, error)) {
+	f.mu.Lock()
+	f.records = append(f.records, r)
+	f.mu.Unlock()
+	promise(r, f.err)
+}
+
+func (f *fakeProducer) Flush(_ context.Context) error {
+	f.flushed = true
+	return nil
+}
+
+func (f *fakeProducer) Close() {}
+
+// ackableReadable is a Readable that records whether it was acked
+type ackableReadable struct {
+	mock.ReadableImpl
+	acked bool
+}
+
+func (a *ackableReadable) Ack() error {
+	a.acked = true
+	return nil
+}
+
+// ackableRecord is a DataRawReadable whose raw message is ackable
+type ackableRecord struct {
+	data mock.ReadableImpl
+	raw  *ackableReadable
+}
+
+func (r ackableRecord) Data() pipeline.Readable { return r.data }
+func (r ackableRecord) Raw() pipeline.Readable  { return r.raw }
+
+// newAckableRecord creates a record with the same data and ackable raw payload
+func newAckableRecord(data string) ackableRecord {
+	return ackableRecord{
+		data: mock.NewReadableImpl([]byte(data)),
+		raw:  &ackableReadable{ReadableImpl: mock.NewReadableImpl([]byte(data))},
+	}
+}
+
+func TestNewKafka_Validation(t *testing.T) {
+	_, err := NewKafka(KafkaConfig{Topic: "test"})
+	assert.ErrorIs(t, err, ErrKafkaSink)
+
+	_, err = NewKafka(KafkaConfig{Brokers: []string{"localhost:9092"}})
+	assert.ErrorIs(t, err, ErrKafkaSink)
+
+	_, err = NewKafka(KafkaConfig{
+		Brokers:    []string{"localhost:9092"},
+		Topic:      "test",
+		Acks:       KafkaAcksLeader,
+		Idempotent: true,
+	})
+	assert.ErrorIs(t, err, ErrKafkaSink)
+
+	_, err = NewKafka(KafkaConfig{
+		Brokers:       []string{"localhost:9092"},
+		Topic:         "test",
+		SASLMechanism: "GSSAPI",
+	})
+	assert.ErrorIs(t, err, ErrKafkaSink)
+}
+
+func TestKafka_Load(t *testing.T) {
+	t.Run("acks after broker confirmation", func(t *testing.T) {
+		producer := &fakeProducer{}
+		k := &Kafka{
+			client: producer,
+			topic:  "test",
+			key: func(drr pipeline.DataRawReadable) ([]byte, error) {
+				return []byte("key"), nil
+			},
+		}
+
+		record := ackableRecord{
+			data: mock.NewReadableImpl([]byte("test data")),
+			raw:  &ackableReadable{ReadableImpl: mock.NewReadableImpl([]byte("test raw data"))},
+		}
+
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		k.Load(in, nil)
+
+		assert.Len(t, producer.records, 1)
+		assert.Equal(t, []byte("test data"), producer.records[0].Value)
+		assert.Equal(t, []byte("key"), producer.records[0].Key)
+		assert.True(t, record.raw.acked)
+		assert.True(t, producer.flushed)
+	})
+
+	t.Run("does not ack on produce failure", func(t *testing.T) {
+		producer := &fakeProducer{err: errors.New("broker unavailable")}
+		k := &Kafka{client: producer, topic: "test"}
+
+		record := ackableRecord{
+			data: mock.NewReadableImpl([]byte("test data")),
+			raw:  &ackableReadable{ReadableImpl: mock.NewReadableImpl([]byte("test raw data"))},
+		}
+
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		k.Load(in, eventC)
+
+		assert.False(t, record.raw.acked)
+		event := <-eventC
+		assert.Equal(t, pipeline.EventError, event.Type())
+	})
+}
@@ -0,0 +1,255 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/aws/smithy-go"
+	"github.com/google/uuid"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Kinesis implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*Kinesis)(nil)
+
+// ErrKinesisSink is the error returned by the Kinesis sink
+var ErrKinesisSink = errors.New("sink error")
+
+// KinesisSinkPrefix is the prefix for the Kinesis sink error
+const KinesisSinkPrefix = "kinesis sink"
+
+// kinesisMaxBatchRecords is the max number of records per PutRecords call
+const kinesisMaxBatchRecords = 500
+
+// kinesisMaxBatchBytes is the max total size of a PutRecords call
+const kinesisMaxBatchBytes = 5 * 1024 * 1024
+
+// KinesisConfig is the configuration for the Kinesis sink.
+type KinesisConfig struct {
+	StreamName string // name of the data stream
+	StreamARN  string // optional stream ARN, used instead of the name when set
+	Region     string // optional region, the default AWS config chain is used when empty
+	Endpoint   string // optional endpoint override, e.g. for local testing
+
+	PartitionKey string // partition key template rendered against each JSON record, a random key is used when empty
+
+	BatchSize     int           // records per PutRecords call, at most 500 and defaults to 500
+	FlushInterval time.Duration // max time a partial batch is held, defaults to 1 second
+	MaxRetries    int           // attempts for failed records and throttled calls, defaults to 5
+	RetryBackoff  time.Duration // initial backoff between attempts, doubled after each, defaults to 100ms
+}
+
+// kinesisAPI is the subset of the Kinesis client used by the Kinesis sink
+type kinesisAPI interface {
+	PutRecords(ctx context.Context, params *kinesis.PutRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error)
+}
+
+// Kinesis is a sink that puts records to a Kinesis data stream in batches.
+// Records that fail individually are retried with backoff and upstream messages
+// are acked once their record is put successfully.
+type Kinesis struct {
+	client        kinesisAPI
+	streamName    *string
+	streamARN     *string
+	partitionKey  *recordTemplate
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	retryBackoff  time.Duration
+}
+
+// NewKinesis creates a new Kinesis sink with the given configuration.
+func NewKinesis(conf KinesisConfig) (*Kinesis, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if conf.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(conf.Region))
+	}
+
+	awsConf, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: failed to load aws config: %w", KinesisSinkPrefix, ErrKinesisSink, err)
+	}
+
+	client := kinesis.NewFromConfig(awsConf, func(o *kinesis.Options) {
+		if conf.Endpoint != "" {
+			o.BaseEndpoint = aws.String(conf.Endpoint)
+		}
+	})
+	return newKinesis(client, conf)
+}
+
+// newKinesis creates a Kinesis sink using an existing client
+func newKinesis(client kinesisAPI, conf KinesisConfig) (*Kinesis, error) {
+	if conf.StreamName == "" && conf.StreamARN == "" {
+		return nil, fmt.Errorf("%s: %w: stream name or arn is required", KinesisSinkPrefix, ErrKinesisSink)
+	}
+
+	if conf.BatchSize <= 0 || conf.BatchSize > kinesisMaxBatchRecords {
+		conf.BatchSize = kinesisMaxBatchRecords
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 5
+	}
+
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = 100 * time.Millisecond
+	}
+
+	k := &Kinesis{
+		client:        client,
+		batchSize:     conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		maxRetries:    conf.MaxRetries,
+		retryBackoff:  conf.RetryBackoff,
+	}
+
+	if conf.StreamARN != "" {
+		k.streamARN = aws.String(conf.StreamARN)
+	} else {
+		k.streamName = aws.String(conf.StreamName)
+	}
+
+	if conf.PartitionKey != "" {
+		partitionKey, err := newRecordTemplate("partition", conf.PartitionKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: invalid partition key template: %w", KinesisSinkPrefix, ErrKinesisSink, err)
+		}
+		k.partitionKey = partitionKey
+	}
+	return k, nil
+}
+
+// kinesisRecord is a record entry and the upstream message it was built from
+type kinesisRecord struct {
+	entry types.PutRecordsRequestEntry
+	drr   pl.DataRawReadable
+}
+
+// Load puts records from the in channel to the stream in batches.
+// It blocks until the in channel is closed and the final batch is put.
+func (k *Kinesis) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	collectBatches(in, k.batchSize, k.flushInterval, func(batch []pl.DataRawReadable) {
+		var records []kinesisRecord
+		size := 0
+
+		for _, drr := range batch {
+			entry, err := k.entry(drr)
+			if err != nil {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"failed to build kinesis record",
+					err,
+					false,
+					drr))
+				continue
+			}
+
+			// keep each call under the request size limit
+			n := len(entry.Data) + len(aws.ToString(entry.PartitionKey))
+			if len(records) > 0 && size+n > kinesisMaxBatchBytes {
+				k.put(records, eventC)
+				records, size = nil, 0
+			}
+
+			records = append(records, kinesisRecord{entry: entry, drr: drr})
+			size += n
+		}
+
+		if len(records) > 0 {
+			k.put(records, eventC)
+		}
+	})
+}
+
+// entry builds the PutRecords entry for a record
+func (k *Kinesis) entry(drr pl.DataRawReadable) (types.PutRecordsRequestEntry, error) {
+	p, err := drr.Data().Read()
+	if err != nil {
+		return types.PutRecordsRequestEntry{}, err
+	}
+
+	key := uuid.NewString()
+	if k.partitionKey != nil {
+		if key, err = k.partitionKey.render(p); err != nil {
+			return types.PutRecordsRequestEntry{}, err
+		}
+	}
+
+	return types.PutRecordsRequestEntry{
+		Data:         p,
+		PartitionKey: aws.String(key),
+	}, nil
+}
+
+// put puts a batch of records, retrying the records that failed individually
+func (k *Kinesis) put(records []kinesisRecord, eventC chan<- pl.Event) {
+	remaining := records
+
+	err := withRetry(k.maxRetries, k.retryBackoff, isKinesisTemporary, func() error {
+		entries := make([]types.PutRecordsRequestEntry, len(remaining))
+		for i, r := range remaining {
+			entries[i] = r.entry
+		}
+
+		out, err := k.client.PutRecords(context.Background(), &kinesis.PutRecordsInput{
+			StreamName: k.streamName,
+			StreamARN:  k.streamARN,
+			Records:    entries,
+		})
+		if err != nil {
+			return err
+		}
+
+		// results are returned in request order
+		var retry []kinesisRecord
+		for i, result := range out.Records {
+			if result.ErrorCode == nil {
+				ackMessage(remaining[i].drr.Raw(), eventC)
+				continue
+			}
+			retry = append(retry, remaining[i])
+		}
+		remaining = retry
+
+		if len(remaining) > 0 {
+			return fmt.Errorf("%d records failed", len(remaining))
+		}
+		return nil
+	})
+	if err != nil {
+		failed := make([]pl.DataRawReadable, len(remaining))
+		for i, r := range remaining {
+			failed[i] = r.drr
+		}
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to put records to kinesis",
+			err,
+			isKinesisTemporary(err),
+			failed))
+	}
+}
+
+// isKinesisTemporary reports whether a Kinesis error is worth retrying.
+// Throttling and server faults are temporary; other API errors are permanent.
+func isKinesisTemporary(err error) bool {
+	var throughputErr *types.ProvisionedThroughputExceededException
+	if errors.As(err, &throughputErr) {
+		return true
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorFault() == smithy.FaultServer
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package sink
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// fakeKinesis records PutRecords calls, throttling records in throttleOnce on their first put
+type fakeKinesis struct {
+	calls        []*kinesis.PutRecordsInput
+	throttleOnce map[string]bool
+	err          error
+}
+
+func (f *fakeKinesis) PutRecords(_ context.Context, params *kinesis.PutRecordsInput, _ ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error) {
+	f.calls = append(f.calls, params)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	out := &kinesis.PutRecordsOutput{}
+	for _, r := range params.Records {
+		if f.throttleOnce[string(r.Data)] {
+			delete(f.throttleOnce, string(r.Data))
+			out.Records = append(out.Records, types.PutRecordsResultEntry{ErrorCode: aws.String("ProvisionedThroughputExceededException")})
+			continue
+		}
+		out.Records = append(out.Records, types.PutRecordsResultEntry{SequenceNumber: aws.String("1")})
+	}
+	return out, nil
+}
+
+// loadKinesis loads the records into the sink and returns the number of events sent
+func loadKinesis(k *Kinesis, records ...ackableRecord) int {
+	in := make(chan pipeline.DataRawReadable, len(records))
+	for _, r := range records {
+		in <- r
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, 10)
+	k.Load(in, eventC)
+	return len(eventC)
+}
+
+func TestKinesis_Load(t *testing.T) {
+	t.Run("puts records with rendered partition keys", func(t *testing.T) {
+		client := &fakeKinesis{}
+		k, err := newKinesis(client, KinesisConfig{StreamName: "events", PartitionKey: "{{.host}}"})
+		assert.NoError(t, err)
+
+		records := []ackableRecord{newAckableRecord(`{"host":"a"}`), newAckableRecord(`{"host":"b"}`)}
+		assert.Zero(t, loadKinesis(k, records...))
+
+		assert.Len(t, client.calls, 1)
+		assert.Equal(t, "events", aws.ToString(client.calls[0].StreamName))
+		assert.Equal(t, "a", aws.ToString(client.calls[0].Records[0].PartitionKey))
+		assert.Equal(t, "b", aws.ToString(client.calls[0].Records[1].PartitionKey))
+		for _, r := range records {
+			assert.True(t, r.raw.acked)
+		}
+	})
+
+	t.Run("retries throttled records only", func(t *testing.T) {
+		client := &fakeKinesis{throttleOnce: map[string]bool{"b": true}}
+		k, err := newKinesis(client, KinesisConfig{StreamName: "events", RetryBackoff: time.Millisecond})
+		assert.NoError(t, err)
+
+		a, b := newAckableRecord("a"), newAckableRecord("b")
+		assert.Zero(t, loadKinesis(k, a, b))
+
+		assert.Len(t, client.calls, 2)
+		assert.Len(t, client.calls[1].Records, 1)
+		assert.Equal(t, "b", string(client.calls[1].Records[0].Data))
+		assert.True(t, a.raw.acked)
+		assert.True(t, b.raw.acked)
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		client := &fakeKinesis{err: &types.ProvisionedThroughputExceededException{}}
+		k, err := newKinesis(client, KinesisConfig{
+			StreamARN:    "arn:aws:kinesis:us-east-1:123456789012:stream/events",
+			MaxRetries:   2,
+			RetryBackoff: time.Millisecond,
+		})
+		assert.NoError(t, err)
+
+		record := newAckableRecord("a")
+		assert.Equal(t, 1, loadKinesis(k, record))
+
+		assert.Len(t, client.calls, 2)
+		assert.NotNil(t, client.calls[0].StreamARN)
+		assert.False(t, record.raw.acked)
+	})
+}
+
+func TestNewKinesis_Validation(t *testing.T) {
+	_, err := newKinesis(&fakeKinesis{}, KinesisConfig{})
+	assert.ErrorIs(t, err, ErrKinesisSink)
+
+	_, err = newKinesis(&fakeKinesis{}, KinesisConfig{StreamName: "events", PartitionKey: "{{.host"})
+	assert.ErrorIs(t, err, ErrKinesisSink)
+}
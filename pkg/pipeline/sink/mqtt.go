@@ -0,0 +1,209 @@
+package sink
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that MQTT implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*MQTT)(nil)
+
+// ErrMQTTSink is the error returned by the MQTT sink
+var ErrMQTTSink = errors.New("sink error")
+
+// MQTTSinkPrefix is the prefix for the MQTT sink error
+const MQTTSinkPrefix = "mqtt sink"
+
+// MQTTConfig is the configuration for the MQTT sink.
+type MQTTConfig struct {
+	Brokers  []string    // broker URLs, e.g. tcp://localhost:1883 or ssl://localhost:8883
+	ClientID string      // client id, generated by the broker when empty
+	Username string      // optional username
+	Password string      // optional password
+	TLS      *tls.Config // optional TLS configuration
+
+	Topic    string // topic template rendered against each JSON record, e.g. "sites/{{.site}}/events"
+	QoS      byte   // quality of service 0, 1 or 2
+	Retained bool   // publish with the retained flag
+
+	BufferSize     int           // publishes held while waiting for delivery or reconnect, defaults to 1000
+	ConnectTimeout time.Duration // timeout for the initial connection, defaults to 10 seconds
+	PublishTimeout time.Duration // max wait for delivery of a buffered publish, defaults to 1 minute
+}
+
+// mqttClient is the subset of the paho client used by the MQTT sink
+type mqttClient interface {
+	Publish(topic string, qos byte, retained bool, payload any) mqtt.Token
+	Disconnect(quiesce uint)
+}
+
+// MQTT is a sink that publishes records to an MQTT broker.
+// The client reconnects automatically and publishes are buffered until they are delivered,
+// after which upstream messages are acked in order.
+type MQTT struct {
+	client         mqttClient
+	topic          *recordTemplate
+	qos            byte
+	retained       bool
+	bufferSize     int
+	publishTimeout time.Duration
+}
+
+// NewMQTT creates a new MQTT sink and connects to the broker.
+func NewMQTT(conf MQTTConfig) (*MQTT, error) {
+	if len(conf.Brokers) == 0 {
+		return nil, fmt.Errorf("%s: %w: no brokers", MQTTSinkPrefix, ErrMQTTSink)
+	}
+
+	if conf.ConnectTimeout <= 0 {
+		conf.ConnectTimeout = 10 * time.Second
+	}
+
+	opts := mqtt.NewClientOptions().
+		SetClientID(conf.ClientID).
+		SetUsername(conf.Username).
+		SetPassword(conf.Password).
+		SetAutoReconnect(true).
+		SetCleanSession(true).
+		SetConnectTimeout(conf.ConnectTimeout)
+	for _, broker := range conf.Brokers {
+		opts.AddBroker(broker)
+	}
+	if conf.TLS != nil {
+		opts.SetTLSConfig(conf.TLS)
+	}
+
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(conf.ConnectTimeout) {
+		return nil, fmt.Errorf("%s: %w: connect timed out", MQTTSinkPrefix, ErrMQTTSink)
+	}
+	if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", MQTTSinkPrefix, ErrMQTTSink, err)
+	}
+
+	m, err := newMQTT(client, conf)
+	if err != nil {
+		client.Disconnect(0)
+		return nil, err
+	}
+	return m, nil
+}
+
+// newMQTT creates an MQTT sink using an existing client
+func newMQTT(client mqttClient, conf MQTTConfig) (*MQTT, error) {
+	if conf.Topic == "" {
+		return nil, fmt.Errorf("%s: %w: topic is empty", MQTTSinkPrefix, ErrMQTTSink)
+	}
+
+	if conf.QoS > 2 {
+		return nil, fmt.Errorf("%s: %w: invalid qos %d", MQTTSinkPrefix, ErrMQTTSink, conf.QoS)
+	}
+
+	if conf.BufferSize <= 0 {
+		conf.BufferSize = 1000
+	}
+
+	if conf.PublishTimeout <= 0 {
+		conf.PublishTimeout = time.Minute
+	}
+
+	topic, err := newRecordTemplate("topic", conf.Topic)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid topic template: %w", MQTTSinkPrefix, ErrMQTTSink, err)
+	}
+
+	return &MQTT{
+		client:         client,
+		topic:          topic,
+		qos:            conf.QoS,
+		retained:       conf.Retained,
+		bufferSize:     conf.BufferSize,
+		publishTimeout: conf.PublishTimeout,
+	}, nil
+}
+
+// mqttPublish is a publish waiting for delivery
+type mqttPublish struct {
+	token mqtt.Token
+	drr   pl.DataRawReadable
+}
+
+// Load publishes records from the in channel to the broker.
+// It blocks until the in channel is closed and all buffered publishes are resolved.
+func (m *MQTT) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	// the buffer bounds the publishes awaiting delivery, e.g. while reconnecting
+	pending := make(chan mqttPublish, m.bufferSize)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for p := range pending {
+			m.await(p, eventC)
+		}
+	}()
+
+	for drr := range in {
+		p, err := drr.Data().Read()
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to read data",
+				err,
+				true,
+				drr))
+			continue
+		}
+
+		topic, err := m.topic.render(p)
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to render mqtt topic",
+				err,
+				false,
+				drr))
+			continue
+		}
+
+		pending <- mqttPublish{
+			token: m.client.Publish(topic, m.qos, m.retained, p),
+			drr:   drr,
+		}
+	}
+
+	close(pending)
+	<-done
+}
+
+// await waits for a publish to be delivered and acks the upstream message
+func (m *MQTT) await(p mqttPublish, eventC chan<- pl.Event) {
+	if !p.token.WaitTimeout(m.publishTimeout) {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to publish to mqtt",
+			errors.New("publish timed out"),
+			true,
+			p.drr))
+		return
+	}
+
+	if err := p.token.Error(); err != nil {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to publish to mqtt",
+			err,
+			true,
+			p.drr))
+		return
+	}
+
+	ackMessage(p.drr.Raw(), eventC)
+}
+
+// Close disconnects from the broker, allowing in flight work to complete.
+func (m *MQTT) Close() error {
+	m.client.Disconnect(250)
+	return nil
+}
@@ -0,0 +1,154 @@
+package sink
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// fakeToken is an mqtt token that resolves with a fixed error
+type fakeToken struct {
+	done chan struct{}
+	err  error
+}
+
+// newFakeToken returns a token that is already resolved
+func newFakeToken(err error) *fakeToken {
+	t := &fakeToken{done: make(chan struct{}), err: err}
+	close(t.done)
+	return t
+}
+
+func (t *fakeToken) Wait() bool { <-t.done; return true }
+func (t *fakeToken) WaitTimeout(d time.Duration) bool {
+	select {
+	case <-t.done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+func (t *fakeToken) Done() <-chan struct{} { return t.done }
+func (t *fakeToken) Error() error          { return t.err }
+
+// fakeMQTTMessage is a message published to the fake client
+type fakeMQTTMessage struct {
+	topic    string
+	qos      byte
+	retained bool
+	payload  string
+}
+
+// fakeMQTTClient records publishes and resolves them with the configured error
+type fakeMQTTClient struct {
+	mu        sync.Mutex
+	published []fakeMQTTMessage
+	err       error
+	pending   bool // return tokens that never resolve
+}
+
+func (c *fakeMQTTClient) Publish(topic string, qos byte, retained bool, payload any) mqtt.Token {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.published = append(c.published, fakeMQTTMessage{topic, qos, retained, string(payload.([]byte))})
+	if c.pending {
+		return &fakeToken{done: make(chan struct{})}
+	}
+	return newFakeToken(c.err)
+}
+
+func (c *fakeMQTTClient) Disconnect(uint) {}
+
+func TestMQTT_Load(t *testing.T) {
+	t.Run("publishes to rendered topics and acks delivered records", func(t *testing.T) {
+		client := &fakeMQTTClient{}
+		m, err := newMQTT(client, MQTTConfig{Topic: "sites/{{.site}}", QoS: 1, Retained: true})
+		assert.NoError(t, err)
+
+		records := []ackableRecord{newAckableRecord(`{"site":"a"}`), newAckableRecord(`{"site":"b"}`)}
+		in := make(chan pipeline.DataRawReadable, len(records))
+		for _, r := range records {
+			in <- r
+		}
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		m.Load(in, eventC)
+
+		assert.Equal(t, []fakeMQTTMessage{
+			{"sites/a", 1, true, `{"site":"a"}`},
+			{"sites/b", 1, true, `{"site":"b"}`},
+		}, client.published)
+		assert.Empty(t, eventC)
+		for _, r := range records {
+			assert.True(t, r.raw.acked)
+		}
+	})
+
+	t.Run("does not ack failed publishes", func(t *testing.T) {
+		client := &fakeMQTTClient{err: errors.New("not connected")}
+		m, err := newMQTT(client, MQTTConfig{Topic: "events"})
+		assert.NoError(t, err)
+
+		record := newAckableRecord("data")
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		m.Load(in, eventC)
+
+		assert.False(t, record.raw.acked)
+		assert.Len(t, eventC, 1)
+	})
+
+	t.Run("reports publishes that are not delivered in time", func(t *testing.T) {
+		client := &fakeMQTTClient{pending: true}
+		m, err := newMQTT(client, MQTTConfig{Topic: "events", PublishTimeout: time.Millisecond})
+		assert.NoError(t, err)
+
+		record := newAckableRecord("data")
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		m.Load(in, eventC)
+
+		assert.False(t, record.raw.acked)
+		assert.Len(t, eventC, 1)
+	})
+
+	t.Run("skips records the topic cannot be rendered for", func(t *testing.T) {
+		client := &fakeMQTTClient{}
+		m, err := newMQTT(client, MQTTConfig{Topic: "sites/{{.site}}"})
+		assert.NoError(t, err)
+
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- newAckableRecord(`{"host":"a"}`)
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		m.Load(in, eventC)
+
+		assert.Empty(t, client.published)
+		assert.False(t, (<-eventC).(pipeline.ErrorEvent).IsTemporary())
+	})
+}
+
+func TestNewMQTT_Validation(t *testing.T) {
+	_, err := NewMQTT(MQTTConfig{})
+	assert.ErrorIs(t, err, ErrMQTTSink)
+
+	_, err = newMQTT(&fakeMQTTClient{}, MQTTConfig{})
+	assert.ErrorIs(t, err, ErrMQTTSink)
+
+	_, err = newMQTT(&fakeMQTTClient{}, MQTTConfig{Topic: "events", QoS: 3})
+	assert.ErrorIs(t, err, ErrMQTTSink)
+}
@@ -5,9 +5,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/nats-io/nats.go/jetstream"
 	pl "github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/natsconn"
 )
 
 // ackable is a type that has an Ack method
@@ -15,8 +18,23 @@ type ackable interface {
 	Ack() error
 }
 
-// Static check that Nats implements the sink interface
-var _ pl.Sink[pl.DataRawReadable] = (*NatsStream)(nil)
+// ackMessage acks the raw message if it is ackable, sending an error event on failure
+func ackMessage(raw pl.Readable, eventC chan<- pl.Event) {
+	if a, ok := raw.(ackable); ok {
+		if err := a.Ack(); err != nil {
+			pl.SendEvent(eventC, pl.NewErrorEvent(
+				"failed to ack message",
+				err,
+				true))
+		}
+	}
+}
+
+// Static check that Nats implements the sink and health interfaces
+var (
+	_ pl.Sink[pl.DataRawReadable] = (*NatsStream)(nil)
+	_ pl.Health                   = (*NatsStream)(nil)
+)
 
 // ErrNatsSink is the error returned by the Nats sink
 var ErrNatsSink = errors.New("sink error")
@@ -26,8 +44,9 @@ const NatsSinkPrefix = "nats stream sink"
 
 // NatsStream implements the source interface
 type NatsStream struct {
-	js      jetstream.JetStream // nats jetstream
-	subject string              // nats jetstream subject
+	js        jetstream.JetStream // nats jetstream
+	subject   string              // nats jetstream subject
+	published *atomic.Int64       // time of the last successful publish in unix nanoseconds, shared by copies
 }
 
 // NewNatsStream creates a new Nats sink
@@ -43,46 +62,53 @@ func NewNatsStream(js jetstream.JetStream, subject string) (NatsStream, error) {
 	}
 
 	return NatsStream{
-		js:      js,
-		subject: subject,
+		js:        js,
+		subject:   subject,
+		published: &atomic.Int64{},
 	}, nil
 }
 
-// Load connects to the nats stream and sends messages to the stream
+// Health reports the status of the nats connection and the time of the last successful publish
+func (b NatsStream) Health(context.Context) pl.HealthStatus {
+	status := natsconn.Health(b.js.Conn())
+	if b.published != nil {
+		if published := b.published.Load(); published != 0 {
+			status.LastSuccess = time.Unix(0, published)
+		}
+	}
+	return status
+}
+
+// Load publishes the records of the in channel to the stream, waiting for the stream to ack each of
+// them before acking its raw message. It blocks until the in channel is closed.
 func (b NatsStream) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
-	go func() {
-		ctx, cancel := context.WithCancel(context.Background())
-		defer cancel()
-
-		// connect in channel to nats producer
-		for drr := range in {
-			p, err := drr.Data().Read()
-			if err != nil {
-				eventC <- pl.NewErrorEvent(
-					"failed to read data",
-					err,
-					true)
-				continue
-			}
-			_, err = b.js.Publish(ctx, b.subject, p)
-			if err != nil {
-				eventC <- pl.NewErrorEvent(
-					"failed to publish message to nats",
-					err,
-					true)
-				continue
-			}
-
-			// ack the message if it is ackable
-			if a, ok := drr.Raw().(ackable); ok {
-				if err := a.Ack(); err != nil {
-					eventC <- pl.NewErrorEvent(
-						"failed to ack message",
-						err,
-						true)
-					continue
-				}
-			}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for drr := range in {
+		p, err := drr.Data().Read()
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to read data",
+				err,
+				true,
+				drr))
+			continue
+		}
+		_, err = b.js.Publish(ctx, b.subject, p)
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to publish message to nats",
+				err,
+				true,
+				drr))
+			continue
 		}
-	}()
+		if b.published != nil {
+			b.published.Store(time.Now().UnixNano())
+		}
+
+		// ack the message if it is ackable
+		ackMessage(drr.Raw(), eventC)
+	}
 }
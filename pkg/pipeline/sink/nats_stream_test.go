@@ -2,6 +2,7 @@ package sink_test
 
 import (
 	"context"
+	"errors"
 	"os"
 	"testing"
 	"time"
@@ -87,10 +88,13 @@ func TestBrokerSink(t *testing.T) {
 
 	// Create a channel to receive the message
 	in := make(chan pipeline.DataRawReadable)
-	defer close(in)
 
-	// Start the sink
-	sink.Load(in, eventC)
+	// Start the sink, which returns once the channel is closed
+	loaded := make(chan struct{})
+	go func() {
+		defer close(loaded)
+		sink.Load(in, eventC)
+	}()
 
 	// Create a mock DataRawReadable
 	drr := mock.NewDataRawReadableImpl(
@@ -101,6 +105,8 @@ func TestBrokerSink(t *testing.T) {
 
 	// Send a message to the sink in channel
 	in <- drr
+	close(in)
+	<-loaded
 
 	// Wait for the message to be received by nats
 	msgs, err := consumer.Fetch(1)
@@ -111,6 +117,61 @@ func TestBrokerSink(t *testing.T) {
 		assert.Equal(t, "test data", string(msg.Data()))
 	}
 
+	health := sink.Health(ctx)
+	assert.Equal(t, pipeline.HealthHealthy, health.State)
+	assert.False(t, health.LastSuccess.IsZero())
+
 	_ = natsContainer.Terminate(ctx)
 
 }
+
+// publishJetStream is a JetStream publishing into memory, or failing with err
+type publishJetStream struct {
+	jetstream.JetStream
+	err       error
+	published []string
+}
+
+func (js *publishJetStream) Publish(_ context.Context, _ string, data []byte, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if js.err != nil {
+		return nil, js.err
+	}
+	js.published = append(js.published, string(data))
+	return &jetstream.PubAck{}, nil
+}
+
+func TestNatsStream_Load(t *testing.T) {
+	js := &publishJetStream{}
+	s, err := sink.NewNatsStream(js, "logs")
+	assert.NoError(t, err)
+
+	in := make(chan pipeline.DataRawReadable, 2)
+	in <- mock.NewDataRawReadableImpl(mock.NewReadableImpl([]byte("a")), mock.NewReadableImpl([]byte("a")))
+	in <- mock.NewDataRawReadableImpl(mock.NewReadableImpl([]byte("b")), mock.NewReadableImpl([]byte("b")))
+	close(in)
+
+	// Load returns once the records are published
+	s.Load(in, nil)
+	assert.Equal(t, []string{"a", "b"}, js.published)
+}
+
+func TestNatsStream_DeadLetter(t *testing.T) {
+	s, err := sink.NewNatsStream(&publishJetStream{err: errors.New("no responders")}, "logs")
+	assert.NoError(t, err)
+
+	dlq := mock.NewSink[pipeline.DataRawReadable]()
+	d, err := sink.NewDeadLetter(s, dlq, sink.DeadLetterConfig{Name: "nats"})
+	assert.NoError(t, err)
+
+	in := make(chan pipeline.DataRawReadable, 1)
+	in <- mock.NewDataRawReadableImpl(mock.NewReadableImpl([]byte("a")), mock.NewReadableImpl([]byte("a")))
+	close(in)
+
+	eventC := make(chan pipeline.Event, 10)
+	d.Load(in, eventC)
+
+	dlq.AssertLen(t, 1)
+	if assert.Len(t, eventC, 1) {
+		assert.Contains(t, (<-eventC).String(), "failed to publish message to nats")
+	}
+}
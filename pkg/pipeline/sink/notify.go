@@ -0,0 +1,134 @@
+package sink
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// DigestData is the data available to the title or subject template of a notification digest.
+type DigestData struct {
+	Count int       // records in the digest
+	Time  time.Time // time the digest is sent, in UTC
+}
+
+// templateFuncs are the functions available to notification templates
+var templateFuncs = template.FuncMap{
+	// json encodes a value as JSON
+	"json": func(v any) (string, error) {
+		p, err := json.Marshal(v)
+		return string(p), err
+	},
+}
+
+// newNotifyTemplate parses a notification template with the template functions.
+// Missing fields of records are errors rather than rendered as "<no value>".
+func newNotifyTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(text)
+}
+
+// templateValue returns the value a record is rendered with: the decoded JSON payload
+// for records carrying a payload and the record itself for any other value, such as events.
+func templateValue(record any) (any, error) {
+	var p []byte
+	var err error
+	switch r := record.(type) {
+	case pl.DataReadable:
+		p, err = r.Data().Read()
+	case pl.Readable:
+		p, err = r.Read()
+	case []byte:
+		p = r
+	default:
+		return record, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var data any
+	if err := json.Unmarshal(p, &data); err != nil {
+		// payloads that are not JSON are rendered as text
+		return string(p), nil
+	}
+	return data, nil
+}
+
+// renderDigest renders up to maxRecords records of a digest through the template, one per line,
+// followed by a count of the records left out
+func renderDigest[I any](tmpl *template.Template, digest []I, maxRecords int) (string, error) {
+	var b strings.Builder
+	for i, record := range digest {
+		if i == maxRecords {
+			fmt.Fprintf(&b, "... and %d more\n", len(digest)-i)
+			break
+		}
+
+		data, err := templateValue(record)
+		if err != nil {
+			return "", err
+		}
+		if err := tmpl.Execute(&b, data); err != nil {
+			return "", err
+		}
+		if !strings.HasSuffix(b.String(), "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String(), nil
+}
+
+// filterRecords forwards the records that pass the filter and acks the upstream messages of the others.
+// A nil filter forwards every record. The returned channel is closed once in is closed.
+func filterRecords[I any](in <-chan I, filter func(I) bool, eventC chan<- pl.Event) <-chan I {
+	if filter == nil {
+		return in
+	}
+
+	out := make(chan I)
+	go func() {
+		defer close(out)
+		for record := range in {
+			if !filter(record) {
+				ackRecords([]I{record}, eventC)
+				continue
+			}
+			out <- record
+		}
+	}()
+	return out
+}
+
+// ackRecords acks the upstream messages of the RawReadable records
+func ackRecords[I any](records []I, eventC chan<- pl.Event) {
+	for _, record := range records {
+		if raw, ok := any(record).(pl.RawReadable); ok {
+			ackMessage(raw.Raw(), eventC)
+		}
+	}
+}
+
+// executeTemplate renders a template to a string
+func executeTemplate(tmpl *template.Template, data any) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
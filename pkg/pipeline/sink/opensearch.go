@@ -0,0 +1,205 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/opensearch-project/opensearch-go/v4"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchapi"
+	"github.com/opensearch-project/opensearch-go/v4/opensearchutil"
+	"github.com/opensearch-project/opensearch-go/v4/signer/awsv2"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that OpenSearch implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*OpenSearch)(nil)
+
+// ErrOpenSearchSink is the error returned by the OpenSearch sink
+var ErrOpenSearchSink = errors.New("sink error")
+
+// OpenSearchSinkPrefix is the prefix for the OpenSearch sink error
+const OpenSearchSinkPrefix = "opensearch sink"
+
+// OpenSearchConfig is the configuration for the OpenSearch sink.
+type OpenSearchConfig struct {
+	Addresses  []string // OpenSearch node URLs
+	Index      string   // target index or data stream name
+	DataStream bool     // write with the create action required by data streams
+
+	Username string // optional basic auth user
+	Password string // optional basic auth password
+
+	AWSRegion  string // enables AWS SigV4 request signing when set
+	AWSService string // SigV4 service name, "es" for managed domains or "aoss" for serverless
+
+	TLS *tls.Config // optional TLS configuration
+
+	Workers       int           // bulk indexer workers, defaults to 1
+	FlushBytes    int           // flush threshold in bytes, defaults to 5MB
+	FlushInterval time.Duration // flush threshold as duration, defaults to 5 seconds
+}
+
+// OpenSearch is a sink that bulk indexes records into an OpenSearch index or data stream.
+// Upstream messages are acked after the bulk item is confirmed by OpenSearch.
+type OpenSearch struct {
+	client        *opensearchapi.Client
+	index         string
+	action        string
+	workers       int
+	flushBytes    int
+	flushInterval time.Duration
+}
+
+// NewOpenSearch creates a new OpenSearch sink with the given configuration.
+func NewOpenSearch(conf OpenSearchConfig) (*OpenSearch, error) {
+	if len(conf.Addresses) == 0 {
+		return nil, fmt.Errorf("%s: %w: addresses are empty", OpenSearchSinkPrefix, ErrOpenSearchSink)
+	}
+
+	if conf.Index == "" {
+		return nil, fmt.Errorf("%s: %w: index is empty", OpenSearchSinkPrefix, ErrOpenSearchSink)
+	}
+
+	if conf.Workers <= 0 {
+		conf.Workers = 1
+	}
+
+	if conf.FlushBytes <= 0 {
+		conf.FlushBytes = 5 << 20
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = 5 * time.Second
+	}
+
+	clientConf := opensearch.Config{
+		Addresses: conf.Addresses,
+		Username:  conf.Username,
+		Password:  conf.Password,
+	}
+
+	if conf.TLS != nil {
+		clientConf.Transport = &http.Transport{TLSClientConfig: conf.TLS}
+	}
+
+	if conf.AWSRegion != "" {
+		if conf.AWSService == "" {
+			conf.AWSService = "es"
+		}
+
+		awsConf, err := awsconfig.LoadDefaultConfig(context.Background(), awsconfig.WithRegion(conf.AWSRegion))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: failed to load aws config: %w", OpenSearchSinkPrefix, ErrOpenSearchSink, err)
+		}
+
+		signer, err := awsv2.NewSignerWithService(awsConf, conf.AWSService)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: failed to create aws signer: %w", OpenSearchSinkPrefix, ErrOpenSearchSink, err)
+		}
+		clientConf.Signer = signer
+	}
+
+	client, err := opensearchapi.NewClient(opensearchapi.Config{Client: clientConf})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", OpenSearchSinkPrefix, ErrOpenSearchSink, err)
+	}
+
+	action := "index"
+	if conf.DataStream {
+		action = "create"
+	}
+
+	return &OpenSearch{
+		client:        client,
+		index:         conf.Index,
+		action:        action,
+		workers:       conf.Workers,
+		flushBytes:    conf.FlushBytes,
+		flushInterval: conf.FlushInterval,
+	}, nil
+}
+
+// Load bulk indexes records from the in channel.
+// It blocks until the in channel is closed and all pending bulk requests are flushed.
+func (o *OpenSearch) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	ctx := context.Background()
+
+	indexer, err := opensearchutil.NewBulkIndexer(opensearchutil.BulkIndexerConfig{
+		Client:        o.client,
+		Index:         o.index,
+		NumWorkers:    o.workers,
+		FlushBytes:    o.flushBytes,
+		FlushInterval: o.flushInterval,
+		OnError: func(_ context.Context, err error) {
+			pl.SendEvent(eventC, pl.NewErrorEvent(
+				"opensearch bulk request failed",
+				err,
+				true))
+		},
+	})
+	if err != nil {
+		pl.SendEvent(eventC, pl.NewErrorEvent(
+			"failed to create opensearch bulk indexer",
+			err,
+			false))
+		// revive:disable-next-line:empty-block
+		for range in {
+		} // drain the input so upstream stages are not blocked
+		return
+	}
+
+	for drr := range in {
+		p, err := drr.Data().Read()
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to read data",
+				err,
+				true,
+				drr))
+			continue
+		}
+
+		record, raw := drr, drr.Raw()
+		err = indexer.Add(ctx, opensearchutil.BulkIndexerItem{
+			Action: o.action,
+			Body:   bytes.NewReader(p),
+			OnSuccess: func(_ context.Context, _ opensearchutil.BulkIndexerItem, _ opensearchapi.BulkRespItem) {
+				// ack the message once the document is confirmed
+				ackMessage(raw, eventC)
+			},
+			OnFailure: func(_ context.Context, _ opensearchutil.BulkIndexerItem, resp opensearchapi.BulkRespItem, err error) {
+				if err == nil {
+					err = fmt.Errorf("status %d", resp.Status)
+					if resp.Error != nil {
+						err = fmt.Errorf("%s: %s", resp.Error.Type, resp.Error.Reason)
+					}
+				}
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"failed to index document in opensearch",
+					err,
+					resp.Status == http.StatusTooManyRequests || resp.Status >= http.StatusInternalServerError,
+					record))
+			},
+		})
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to add document to opensearch bulk indexer",
+				err,
+				true,
+				drr))
+		}
+	}
+
+	if err := indexer.Close(ctx); err != nil {
+		pl.SendEvent(eventC, pl.NewErrorEvent(
+			"failed to flush opensearch bulk indexer",
+			err,
+			true))
+	}
+}
@@ -0,0 +1,85 @@
+package sink_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+func TestNewOpenSearch_Validation(t *testing.T) {
+	_, err := sink.NewOpenSearch(sink.OpenSearchConfig{Index: "logs"})
+	assert.ErrorIs(t, err, sink.ErrOpenSearchSink)
+
+	_, err = sink.NewOpenSearch(sink.OpenSearchConfig{Addresses: []string{"http://localhost:9200"}})
+	assert.ErrorIs(t, err, sink.ErrOpenSearchSink)
+}
+
+func TestOpenSearch_Load(t *testing.T) {
+	var mu sync.Mutex
+	var actions []string
+	var docs []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		// bulk bodies alternate between action metadata and document lines
+		var items []map[string]any
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var meta map[string]map[string]any
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &meta))
+			scanner.Scan()
+
+			for action := range meta {
+				mu.Lock()
+				actions = append(actions, action)
+				docs = append(docs, scanner.Text())
+				mu.Unlock()
+				items = append(items, map[string]any{action: map[string]any{"_index": "logs", "status": 201}})
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"took": 1, "errors": false, "items": items})
+	}))
+	defer server.Close()
+
+	openSearch, err := sink.NewOpenSearch(sink.OpenSearchConfig{
+		Addresses:  []string{server.URL},
+		Index:      "logs",
+		DataStream: true,
+	})
+	assert.NoError(t, err)
+
+	in := make(chan pipeline.DataRawReadable, 2)
+	in <- mock.NewDataRawReadableImpl(
+		mock.NewReadableImpl([]byte(`{"msg":"one"}`)),
+		mock.NewReadableImpl([]byte("one")),
+	)
+	in <- mock.NewDataRawReadableImpl(
+		mock.NewReadableImpl([]byte(`{"msg":"two"}`)),
+		mock.NewReadableImpl([]byte("two")),
+	)
+	close(in)
+
+	eventC := make(chan pipeline.Event, 10)
+	openSearch.Load(in, eventC)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"create", "create"}, actions)
+	assert.Equal(t, []string{`{"msg":"one"}`, `{"msg":"two"}`}, docs)
+	assert.Empty(t, eventC)
+}
@@ -0,0 +1,226 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Postgres implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*Postgres)(nil)
+
+// ErrPostgresSink is the error returned by the Postgres sink
+var ErrPostgresSink = errors.New("sink error")
+
+// PostgresSinkPrefix is the prefix for the Postgres sink error
+const PostgresSinkPrefix = "postgres sink"
+
+// PostgresConfig is the configuration for the Postgres sink.
+type PostgresConfig struct {
+	ConnString string // libpq style connection string or URL
+
+	Table         string   // target table, optionally schema qualified
+	PayloadColumn string   // JSONB column holding the full payload, defaults to "payload"
+	Columns       []Column // metadata columns extracted from the payload
+
+	UseCopy       bool          // load batches with COPY instead of batched INSERTs
+	BatchSize     int           // rows per transaction, defaults to 1000
+	FlushInterval time.Duration // max time a partial batch is held, defaults to 1 second
+}
+
+// postgresPool is the subset of the pgx pool used by the Postgres sink
+type postgresPool interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Close()
+}
+
+// Postgres is a sink that writes JSON records into a Postgres table in transactional batches.
+// Upstream messages are acked after the transaction containing them commits.
+type Postgres struct {
+	pool          postgresPool
+	table         pgx.Identifier
+	columns       []Column
+	columnNames   []string
+	insert        string
+	useCopy       bool
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// NewPostgres creates a new Postgres sink with the given configuration.
+func NewPostgres(conf PostgresConfig) (*Postgres, error) {
+	if conf.ConnString == "" {
+		return nil, fmt.Errorf("%s: %w: connection string is empty", PostgresSinkPrefix, ErrPostgresSink)
+	}
+
+	poolConf, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", PostgresSinkPrefix, ErrPostgresSink, err)
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", PostgresSinkPrefix, ErrPostgresSink, err)
+	}
+
+	p, err := newPostgres(pool, conf)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// newPostgres creates a Postgres sink using an existing pool
+func newPostgres(pool postgresPool, conf PostgresConfig) (*Postgres, error) {
+	if conf.Table == "" {
+		return nil, fmt.Errorf("%s: %w: table is empty", PostgresSinkPrefix, ErrPostgresSink)
+	}
+
+	if conf.PayloadColumn == "" {
+		conf.PayloadColumn = "payload"
+	}
+
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 1000
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+
+	table := pgx.Identifier(strings.Split(conf.Table, "."))
+
+	names := []string{conf.PayloadColumn}
+	quoted := []string{pgx.Identifier{conf.PayloadColumn}.Sanitize()}
+	placeholders := []string{"$1"}
+	for i, col := range conf.Columns {
+		names = append(names, col.Name)
+		quoted = append(quoted, pgx.Identifier{col.Name}.Sanitize())
+		placeholders = append(placeholders, fmt.Sprintf("$%d", i+2))
+	}
+
+	return &Postgres{
+		pool:        pool,
+		table:       table,
+		columns:     conf.Columns,
+		columnNames: names,
+		insert: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+			table.Sanitize(), strings.Join(quoted, ", "), strings.Join(placeholders, ", ")),
+		useCopy:       conf.UseCopy,
+		batchSize:     conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+	}, nil
+}
+
+// Load batches records from the in channel and writes each batch in a single transaction.
+// It blocks until the in channel is closed and the final batch is committed.
+func (p *Postgres) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	collectBatches(in, p.batchSize, p.flushInterval, func(batch []pl.DataRawReadable) {
+		p.flush(batch, eventC)
+	})
+}
+
+// flush writes a batch of records in a transaction and acks them after commit
+func (p *Postgres) flush(batch []pl.DataRawReadable, eventC chan<- pl.Event) {
+	rows := make([][]any, 0, len(batch))
+	written := make([]pl.DataRawReadable, 0, len(batch))
+
+	for _, drr := range batch {
+		row, err := p.row(drr)
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to map record to postgres columns",
+				err,
+				true,
+				drr))
+			continue
+		}
+		rows = append(rows, row)
+		written = append(written, drr)
+	}
+
+	if len(rows) == 0 {
+		return
+	}
+
+	if err := p.write(context.Background(), rows); err != nil {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to write batch to postgres",
+			err,
+			isPostgresTemporary(err),
+			written))
+		return
+	}
+
+	// ack the messages once the transaction is committed
+	for _, drr := range written {
+		ackMessage(drr.Raw(), eventC)
+	}
+}
+
+// write inserts rows in a single transaction using COPY or a batch of INSERTs
+func (p *Postgres) write(ctx context.Context, rows [][]any) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// rollback is a no-op once the transaction is committed
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if p.useCopy {
+		if _, err := tx.CopyFrom(ctx, p.table, p.columnNames, pgx.CopyFromRows(rows)); err != nil {
+			return err
+		}
+	} else {
+		b := &pgx.Batch{}
+		for _, row := range rows {
+			b.Queue(p.insert, row...)
+		}
+		if err := tx.SendBatch(ctx, b).Close(); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// row builds the payload and metadata column values for a record
+func (p *Postgres) row(drr pl.DataRawReadable) ([]any, error) {
+	payload, err := drr.Data().Read()
+	if err != nil {
+		return nil, err
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return nil, err
+	}
+
+	row := make([]any, 0, len(p.columns)+1)
+	row = append(row, string(payload))
+	for _, col := range p.columns {
+		row = append(row, lookupField(record, col.Field))
+	}
+	return row, nil
+}
+
+// Close closes the Postgres connection pool.
+func (p *Postgres) Close() {
+	p.pool.Close()
+}
+
+// isPostgresTemporary reports whether an error is worth retrying.
+// Errors reported by the server are permanent; connection failures are temporary.
+func isPostgresTemporary(err error) bool {
+	var pgErr *pgconn.PgError
+	return !errors.As(err, &pgErr)
+}
@@ -0,0 +1,148 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// fakeBatchResults completes a queued batch with the configured error
+type fakeBatchResults struct {
+	pgx.BatchResults
+	err error
+}
+
+func (r fakeBatchResults) Close() error { return r.err }
+
+// fakeTx records the statements executed in a transaction
+type fakeTx struct {
+	pgx.Tx
+	pool *fakePostgresPool
+}
+
+func (tx *fakeTx) CopyFrom(_ context.Context, table pgx.Identifier, columns []string, src pgx.CopyFromSource) (int64, error) {
+	tx.pool.table = table
+	tx.pool.columns = columns
+	tx.pool.copies++
+	var n int64
+	for src.Next() {
+		values, _ := src.Values()
+		tx.pool.rows = append(tx.pool.rows, values)
+		n++
+	}
+	return n, tx.pool.err
+}
+
+func (tx *fakeTx) SendBatch(_ context.Context, b *pgx.Batch) pgx.BatchResults {
+	for _, q := range b.QueuedQueries {
+		tx.pool.queries = append(tx.pool.queries, q.SQL)
+		tx.pool.rows = append(tx.pool.rows, q.Arguments)
+	}
+	return fakeBatchResults{err: tx.pool.err}
+}
+
+func (tx *fakeTx) Commit(_ context.Context) error {
+	tx.pool.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(_ context.Context) error { return nil }
+
+// fakePostgresPool hands out fake transactions
+type fakePostgresPool struct {
+	err       error
+	table     pgx.Identifier
+	columns   []string
+	queries   []string
+	rows      [][]any
+	copies    int
+	committed bool
+}
+
+func (p *fakePostgresPool) Begin(_ context.Context) (pgx.Tx, error) {
+	return &fakeTx{pool: p}, nil
+}
+
+func (p *fakePostgresPool) Close() {}
+
+func TestPostgres_Load(t *testing.T) {
+	conf := PostgresConfig{
+		Table:   "logs.events",
+		Columns: []Column{{Name: "host", Field: "host.name"}},
+	}
+
+	t.Run("inserts batches in a transaction", func(t *testing.T) {
+		pool := &fakePostgresPool{}
+		p, err := newPostgres(pool, conf)
+		assert.NoError(t, err)
+
+		record := newAckableRecord(`{"host":{"name":"fw01"}}`)
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		p.Load(in, nil)
+
+		assert.Equal(t, []string{`INSERT INTO "logs"."events" ("payload", "host") VALUES ($1, $2)`}, pool.queries)
+		assert.Equal(t, [][]any{{`{"host":{"name":"fw01"}}`, "fw01"}}, pool.rows)
+		assert.True(t, pool.committed)
+		assert.True(t, record.raw.acked)
+	})
+
+	t.Run("copies batches when configured", func(t *testing.T) {
+		pool := &fakePostgresPool{}
+		copyConf := conf
+		copyConf.UseCopy = true
+		p, err := newPostgres(pool, copyConf)
+		assert.NoError(t, err)
+
+		record := newAckableRecord(`{"host":{"name":"fw01"}}`)
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		p.Load(in, nil)
+
+		assert.Equal(t, pgx.Identifier{"logs", "events"}, pool.table)
+		assert.Equal(t, []string{"payload", "host"}, pool.columns)
+		assert.Len(t, pool.rows, 1)
+		assert.True(t, record.raw.acked)
+	})
+
+	t.Run("does not ack when the transaction fails", func(t *testing.T) {
+		pool := &fakePostgresPool{err: &pgconn.PgError{Code: "42P01", Message: "relation does not exist"}}
+		p, err := newPostgres(pool, conf)
+		assert.NoError(t, err)
+
+		record := newAckableRecord(`{"host":{"name":"fw01"}}`)
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		p.Load(in, eventC)
+
+		assert.False(t, pool.committed)
+		assert.False(t, record.raw.acked)
+
+		event := <-eventC
+		errEvent, ok := event.(pipeline.ErrorEvent)
+		assert.True(t, ok)
+		assert.False(t, errEvent.IsTemporary())
+	})
+}
+
+func TestNewPostgres_Validation(t *testing.T) {
+	_, err := NewPostgres(PostgresConfig{Table: "events"})
+	assert.ErrorIs(t, err, ErrPostgresSink)
+
+	_, err = newPostgres(&fakePostgresPool{}, PostgresConfig{})
+	assert.ErrorIs(t, err, ErrPostgresSink)
+
+	assert.True(t, isPostgresTemporary(errors.New("connection refused")))
+}
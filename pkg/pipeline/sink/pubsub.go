@@ -0,0 +1,186 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/pubsub/v2"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+	"google.golang.org/api/option"
+)
+
+// Static check that PubSub implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*PubSub)(nil)
+
+// ErrPubSubSink is the error returned by the Pub/Sub sink
+var ErrPubSubSink = errors.New("sink error")
+
+// PubSubSinkPrefix is the prefix for the Pub/Sub sink error
+const PubSubSinkPrefix = "pubsub sink"
+
+// PubSubConfig is the configuration for the Pub/Sub sink.
+type PubSubConfig struct {
+	ProjectID     string                // Google Cloud project id
+	Topic         string                // topic id or fully qualified topic name
+	ClientOptions []option.ClientOption // optional client options, e.g. credentials or endpoint
+
+	OrderingKey string // optional ordering key template rendered against each JSON record, enables message ordering
+
+	CountThreshold int           // messages per publish request, defaults to the client default of 100
+	ByteThreshold  int           // bytes per publish request, defaults to the client default of 1MB
+	DelayThreshold time.Duration // max time a message is held before publishing, defaults to the client default of 10ms
+	Timeout        time.Duration // publish timeout, defaults to the client default of 60 seconds
+
+	MaxOutstanding int // publishes awaiting their result, defaults to 1000
+}
+
+// PubSub is a sink that publishes records to a Google Cloud Pub/Sub topic.
+// Upstream messages are acked in order once their publish result is returned.
+type PubSub struct {
+	client         *pubsub.Client
+	publisher      *pubsub.Publisher
+	orderingKey    *recordTemplate
+	maxOutstanding int
+}
+
+// NewPubSub creates a new Pub/Sub sink with the given configuration.
+func NewPubSub(conf PubSubConfig) (*PubSub, error) {
+	if conf.ProjectID == "" {
+		return nil, fmt.Errorf("%s: %w: project id is empty", PubSubSinkPrefix, ErrPubSubSink)
+	}
+
+	client, err := pubsub.NewClient(context.Background(), conf.ProjectID, conf.ClientOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", PubSubSinkPrefix, ErrPubSubSink, err)
+	}
+
+	p, err := newPubSub(client, conf)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// newPubSub creates a Pub/Sub sink using an existing client
+func newPubSub(client *pubsub.Client, conf PubSubConfig) (*PubSub, error) {
+	if conf.Topic == "" {
+		return nil, fmt.Errorf("%s: %w: topic is empty", PubSubSinkPrefix, ErrPubSubSink)
+	}
+
+	if conf.MaxOutstanding <= 0 {
+		conf.MaxOutstanding = 1000
+	}
+
+	publisher := client.Publisher(conf.Topic)
+	if conf.CountThreshold > 0 {
+		publisher.PublishSettings.CountThreshold = conf.CountThreshold
+	}
+	if conf.ByteThreshold > 0 {
+		publisher.PublishSettings.ByteThreshold = conf.ByteThreshold
+	}
+	if conf.DelayThreshold > 0 {
+		publisher.PublishSettings.DelayThreshold = conf.DelayThreshold
+	}
+	if conf.Timeout > 0 {
+		publisher.PublishSettings.Timeout = conf.Timeout
+	}
+
+	p := &PubSub{
+		client:         client,
+		publisher:      publisher,
+		maxOutstanding: conf.MaxOutstanding,
+	}
+
+	if conf.OrderingKey != "" {
+		orderingKey, err := newRecordTemplate("ordering", conf.OrderingKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: invalid ordering key template: %w", PubSubSinkPrefix, ErrPubSubSink, err)
+		}
+		p.orderingKey = orderingKey
+		publisher.EnableMessageOrdering = true
+	}
+	return p, nil
+}
+
+// pubSubPublish is a publish waiting for its result
+type pubSubPublish struct {
+	result      *pubsub.PublishResult
+	orderingKey string
+	drr         pl.DataRawReadable
+}
+
+// Load publishes records from the in channel to the topic.
+// It blocks until the in channel is closed and every publish result is returned.
+func (p *PubSub) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	ctx := context.Background()
+	pending := make(chan pubSubPublish, p.maxOutstanding)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for pub := range pending {
+			p.await(ctx, pub, eventC)
+		}
+	}()
+
+	for drr := range in {
+		data, err := drr.Data().Read()
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to read data",
+				err,
+				true,
+				drr))
+			continue
+		}
+
+		msg := &pubsub.Message{Data: data}
+		if p.orderingKey != nil {
+			if msg.OrderingKey, err = p.orderingKey.render(data); err != nil {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"failed to render pubsub ordering key",
+					err,
+					false,
+					drr))
+				continue
+			}
+		}
+
+		pending <- pubSubPublish{
+			result:      p.publisher.Publish(ctx, msg),
+			orderingKey: msg.OrderingKey,
+			drr:         drr,
+		}
+	}
+
+	p.publisher.Flush()
+	close(pending)
+	<-done
+}
+
+// await waits for a publish result and acks the upstream message
+func (p *PubSub) await(ctx context.Context, pub pubSubPublish, eventC chan<- pl.Event) {
+	if _, err := pub.result.Get(ctx); err != nil {
+		// publishing for an ordering key pauses after a failure until resumed
+		if pub.orderingKey != "" {
+			p.publisher.ResumePublish(pub.orderingKey)
+		}
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to publish to pubsub",
+			err,
+			true,
+			pub.drr))
+		return
+	}
+
+	ackMessage(pub.drr.Raw(), eventC)
+}
+
+// Close stops the publisher and closes the client.
+func (p *PubSub) Close() error {
+	p.publisher.Stop()
+	return p.client.Close()
+}
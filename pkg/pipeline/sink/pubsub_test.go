@@ -0,0 +1,96 @@
+package sink
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/pubsub/v2"
+	"cloud.google.com/go/pubsub/v2/apiv1/pubsubpb"
+	"cloud.google.com/go/pubsub/v2/pstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// newFakePubSubClient starts an in-process Pub/Sub server with the topic and returns a client for it
+func newFakePubSubClient(t *testing.T, topic string) (*pstest.Server, *pubsub.Client) {
+	t.Helper()
+
+	srv := pstest.NewServer()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	conn, err := grpc.NewClient(srv.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	client, err := pubsub.NewClient(context.Background(), "project", option.WithGRPCConn(conn))
+	assert.NoError(t, err)
+
+	if topic != "" {
+		_, err = srv.GServer.CreateTopic(context.Background(), &pubsubpb.Topic{Name: "projects/project/topics/" + topic})
+		assert.NoError(t, err)
+	}
+	return srv, client
+}
+
+func TestPubSub_Load(t *testing.T) {
+	t.Run("publishes records with ordering keys and acks them", func(t *testing.T) {
+		srv, client := newFakePubSubClient(t, "events")
+
+		p, err := newPubSub(client, PubSubConfig{Topic: "events", OrderingKey: "{{.host}}"})
+		assert.NoError(t, err)
+		defer p.Close()
+
+		records := []ackableRecord{newAckableRecord(`{"host":"a"}`), newAckableRecord(`{"host":"b"}`)}
+		in := make(chan pipeline.DataRawReadable, len(records))
+		for _, r := range records {
+			in <- r
+		}
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		p.Load(in, eventC)
+
+		assert.Empty(t, eventC)
+		messages := srv.Messages()
+		assert.Len(t, messages, 2)
+		keys := []string{messages[0].OrderingKey, messages[1].OrderingKey}
+		assert.ElementsMatch(t, []string{"a", "b"}, keys)
+		for _, r := range records {
+			assert.True(t, r.raw.acked)
+		}
+	})
+
+	t.Run("does not ack failed publishes", func(t *testing.T) {
+		_, client := newFakePubSubClient(t, "")
+
+		p, err := newPubSub(client, PubSubConfig{Topic: "missing"})
+		assert.NoError(t, err)
+		defer p.Close()
+
+		record := newAckableRecord("data")
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		p.Load(in, eventC)
+
+		assert.False(t, record.raw.acked)
+		assert.Len(t, eventC, 1)
+	})
+}
+
+func TestNewPubSub_Validation(t *testing.T) {
+	_, err := NewPubSub(PubSubConfig{})
+	assert.ErrorIs(t, err, ErrPubSubSink)
+
+	_, client := newFakePubSubClient(t, "")
+	_, err = newPubSub(client, PubSubConfig{})
+	assert.ErrorIs(t, err, ErrPubSubSink)
+
+	_, err = newPubSub(client, PubSubConfig{Topic: "events", OrderingKey: "{{.host"})
+	assert.ErrorIs(t, err, ErrPubSubSink)
+}
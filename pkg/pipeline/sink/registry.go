@@ -0,0 +1,1211 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/natsconn"
+	"github.com/witfoo/krapht/pkg/tlsconf"
+)
+
+// Ensure that AnySink implements the Sink interface.
+var _ pl.Sink[any] = (*AnySink[string])(nil)
+
+// ErrUnknownSink is returned when no factory is registered under a name.
+var ErrUnknownSink = errors.New("unknown sink")
+
+// Factory creates a sink from its configuration, a JSON document that is empty when none is given.
+type Factory func(conf json.RawMessage) (pl.Sink[any], error)
+
+// the registry of the sink factories
+var registry = pl.NewRegistry[Factory](pl.StageSink)
+
+// Register makes a sink factory available by name to NewRegistered. It is meant to be called from the
+// init function of the package providing the sink, which is linked in by a blank import or in a plugin
+// loaded with flow.LoadPlugin. The options
+// describe the configuration of the sink, which NewRegistered checks against them. Register panics
+// when the factory is nil or the name is already registered.
+func Register(name string, factory Factory, options ...pl.Option) {
+	registry.Register(name, factory, options...)
+}
+
+// Registered returns the sorted names of the registered sinks.
+func Registered() []string {
+	return registry.Names()
+}
+
+// Components returns the descriptions of the registered sinks, sorted by name.
+func Components() []pl.Component {
+	return registry.Components()
+}
+
+// NewRegistered creates the sink registered under the name with its configuration.
+func NewRegistered(name string, conf json.RawMessage) (pl.Sink[any], error) {
+	factory, ok, err := registry.Factory(name, conf)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSink, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create sink %s: %w", name, err)
+	}
+
+	s, err := factory(conf)
+	if err != nil {
+		return nil, fmt.Errorf("could not create sink %s: %w", name, err)
+	}
+	return s, nil
+}
+
+// AnySink is a struct that represents a typed sink adapted to a data stream of any items, as created by
+// factories. Items that are not of the input type are dropped and reported with an error event.
+type AnySink[I any] struct {
+	sink pl.Sink[I]
+}
+
+// NewAnySink creates a new AnySink adapting the sink.
+func NewAnySink[I any](sink pl.Sink[I]) *AnySink[I] {
+	return &AnySink[I]{
+		sink: sink,
+	}
+}
+
+// Load loads the items of the input type from the input channel into the sink.
+func (a AnySink[I]) Load(in <-chan any, eventC chan<- pl.Event) {
+	typed := make(chan I)
+	go func() {
+		defer close(typed)
+		for v := range in {
+			t, ok := v.(I)
+			if !ok {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent("sink: unexpected item type",
+					fmt.Errorf("item of type %T is not %T", v, t), false, v))
+				continue
+			}
+			typed <- t
+		}
+	}()
+	a.sink.Load(typed, eventC)
+}
+
+// Health reports the health of the sink when it reports one, and healthy otherwise.
+func (a AnySink[I]) Health(ctx context.Context) pl.HealthStatus {
+	if h, ok := a.sink.(pl.Health); ok {
+		return h.Health(ctx)
+	}
+	return pl.HealthStatus{State: pl.HealthHealthy}
+}
+
+// columnOption is a column mapping of a sink configuration
+type columnOption struct {
+	Name  string `json:"name"`
+	Field string `json:"field"`
+}
+
+// columns returns the column mappings of the options
+func columns(options []columnOption) []Column {
+	var cols []Column
+	for _, o := range options {
+		cols = append(cols, Column{Name: o.Name, Field: o.Field})
+	}
+	return cols
+}
+
+// enumOption returns the value named by an option, or the zero value when the option is empty
+func enumOption[T any](option, name string, values map[string]T) (T, error) {
+	var zero T
+	if name == "" {
+		return zero, nil
+	}
+	v, ok := values[strings.ToLower(name)]
+	if !ok {
+		names := make([]string, 0, len(values))
+		for n := range values {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return zero, fmt.Errorf("%w: %s must be one of %s", pl.ErrOptions, option, strings.Join(names, ", "))
+	}
+	return v, nil
+}
+
+// tlsOption is the option of the client TLS configuration of the network sinks
+var tlsOption = pl.Option{Name: "tls", Type: pl.OptionObject,
+	Description: "client TLS configuration of cert_file, key_file, ca_file, server_name, insecure_skip_verify, min_version and reload"}
+
+// retryOptions are the options of the sinks retrying failed attempts with a backoff
+func retryOptions(attempts int, backoff string) []pl.Option {
+	return []pl.Option{
+		{Name: "max_retries", Type: pl.OptionInteger, Default: attempts,
+			Description: "attempts for failed deliveries"},
+		{Name: "retry_backoff", Type: pl.OptionDuration, Default: backoff,
+			Description: "initial backoff between attempts"},
+	}
+}
+
+// batchOptions are the options of the sinks writing batches of records
+func batchOptions(size int, interval string) []pl.Option {
+	return []pl.Option{
+		{Name: "batch_size", Type: pl.OptionInteger, Default: size,
+			Description: "records per batch"},
+		{Name: "flush_interval", Type: pl.OptionDuration, Default: interval,
+			Description: "max time a partial batch is held"},
+	}
+}
+
+// the built-in sinks configurable without code are registered by default, the sinks wrapping other
+// sinks and those taking clients are created in code
+func init() {
+	Register("blackhole", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Rate    float64 `json:"rate"`
+			Jitter  float64 `json:"jitter"`
+			Seed    uint64  `json:"seed"`
+			Release bool    `json:"release"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		s, err := NewBlackhole[any](BlackholeConfig{Rate: c.Rate, Jitter: c.Jitter, Seed: c.Seed, Release: c.Release})
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	},
+		pl.Option{Name: "rate", Type: pl.OptionNumber,
+			Description: "max records consumed per second, 0 consumes as fast as possible"},
+		pl.Option{Name: "jitter", Type: pl.OptionNumber,
+			Description: "random variation of the time between records as a fraction of it"},
+		pl.Option{Name: "seed", Type: pl.OptionInteger,
+			Description: "seed of the jitter"},
+		pl.Option{Name: "release", Type: pl.OptionBoolean,
+			Description: "release the pooled buffers of the records, when the sink is their last consumer"},
+	)
+
+	Register("noop", func(json.RawMessage) (pl.Sink[any], error) {
+		return Noop{}, nil
+	})
+
+	Register("logger", func(json.RawMessage) (pl.Sink[any], error) {
+		return NewLogger[any](log.Default()), nil
+	})
+
+	Register("writer", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Output   string `json:"output"`
+			Encoding string `json:"encoding"`
+			Indent   string `json:"indent"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		w, err := enumOption("output", c.Output, map[string]*os.File{"stdout": os.Stdout, "stderr": os.Stderr})
+		if err != nil {
+			return nil, err
+		}
+		newEncoder, err := enumOption("encoding", c.Encoding, map[string]func(string) Encoder{
+			"json":   func(string) Encoder { return NewJSONEncoder() },
+			"ndjson": func(string) Encoder { return NewNDJSONEncoder() },
+			"logfmt": func(string) Encoder { return NewLogfmtEncoder() },
+			"raw":    func(string) Encoder { return NewRawEncoder() },
+			"pretty": func(indent string) Encoder { return NewPrettyEncoder(indent) },
+		})
+		if err != nil {
+			return nil, err
+		}
+		var enc Encoder
+		if newEncoder != nil {
+			enc = newEncoder(c.Indent)
+		}
+		if w == nil {
+			return NewWriter[any](nil, enc), nil
+		}
+		return NewWriter[any](w, enc), nil
+	},
+		pl.Option{Name: "output", Type: pl.OptionString, Default: "stdout",
+			Description: "stdout or stderr"},
+		pl.Option{Name: "encoding", Type: pl.OptionString, Default: "ndjson",
+			Description: "json, ndjson, logfmt, raw or pretty"},
+		pl.Option{Name: "indent", Type: pl.OptionString, Default: "\t",
+			Description: "indent of the pretty encoding"},
+	)
+
+	Register("unix_socket", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Path         string      `json:"path"`
+			Datagram     bool        `json:"datagram"`
+			WriteTimeout pl.Duration `json:"write_timeout"`
+			MaxRetries   int         `json:"max_retries"`
+			RetryBackoff pl.Duration `json:"retry_backoff"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		s, err := NewUnixSocket[any](UnixSocketConfig{
+			Path:         c.Path,
+			Datagram:     c.Datagram,
+			WriteTimeout: time.Duration(c.WriteTimeout),
+			MaxRetries:   c.MaxRetries,
+			RetryBackoff: time.Duration(c.RetryBackoff),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}, append([]pl.Option{
+		{Name: "path", Type: pl.OptionString, Required: true,
+			Description: "path of the socket"},
+		{Name: "datagram", Type: pl.OptionBoolean,
+			Description: "write to a datagram socket instead of a stream socket"},
+		{Name: "write_timeout", Type: pl.OptionDuration, Default: "10s",
+			Description: "timeout of a single write"},
+	}, retryOptions(3, "500ms")...)...)
+
+	Register("nats_stream", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			URL     string          `json:"url"`
+			Subject string          `json:"subject"`
+			TLS     *tlsconf.Config `json:"tls"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		js, err := natsconn.ConnectJetStream(c.URL, c.TLS)
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewNatsStream(js, c.Subject)
+		if err != nil {
+			js.Conn().Close()
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	},
+		pl.Option{Name: "url", Type: pl.OptionString, Default: nats.DefaultURL,
+			Description: "nats server URL"},
+		pl.Option{Name: "subject", Type: pl.OptionString, Required: true,
+			Description: "jetstream subject records are published to"},
+		tlsOption,
+	)
+
+	Register("webhook", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			URL              string            `json:"url"`
+			Headers          map[string]string `json:"headers"`
+			Format           string            `json:"format"`
+			BatchSize        int               `json:"batch_size"`
+			FlushInterval    pl.Duration       `json:"flush_interval"`
+			Timeout          pl.Duration       `json:"timeout"`
+			MaxRetries       int               `json:"max_retries"`
+			RetryBackoff     pl.Duration       `json:"retry_backoff"`
+			BreakerThreshold int               `json:"breaker_threshold"`
+			BreakerCooldown  pl.Duration       `json:"breaker_cooldown"`
+			TLS              *tlsconf.Config   `json:"tls"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		tlsConf, err := c.TLS.Client()
+		if err != nil {
+			return nil, err
+		}
+		format, err := enumOption("format", c.Format, map[string]WebhookFormat{
+			"single": WebhookSingle, "json_array": WebhookJSONArray, "ndjson": WebhookNDJSON,
+		})
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewWebhook(WebhookConfig{
+			URL:              c.URL,
+			Headers:          c.Headers,
+			Format:           format,
+			BatchSize:        c.BatchSize,
+			FlushInterval:    time.Duration(c.FlushInterval),
+			Timeout:          time.Duration(c.Timeout),
+			MaxRetries:       c.MaxRetries,
+			RetryBackoff:     time.Duration(c.RetryBackoff),
+			BreakerThreshold: c.BreakerThreshold,
+			BreakerCooldown:  time.Duration(c.BreakerCooldown),
+			TLS:              tlsConf,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	}, slices.Concat([]pl.Option{
+		{Name: "url", Type: pl.OptionString, Required: true,
+			Description: "endpoint records are posted to"},
+		{Name: "headers", Type: pl.OptionObject,
+			Description: "custom request headers"},
+		{Name: "format", Type: pl.OptionString, Default: "single",
+			Description: "single, json_array or ndjson"},
+		{Name: "timeout", Type: pl.OptionDuration, Default: "10s",
+			Description: "request timeout"},
+		{Name: "breaker_threshold", Type: pl.OptionInteger,
+			Description: "consecutive failed requests before the circuit opens, 0 disables"},
+		{Name: "breaker_cooldown", Type: pl.OptionDuration, Default: "30s",
+			Description: "time the circuit stays open before a trial request"},
+		tlsOption,
+	}, batchOptions(100, "1s"), retryOptions(3, "500ms"))...)
+
+	Register("kafka", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Brokers            []string        `json:"brokers"`
+			Topic              string          `json:"topic"`
+			Key                string          `json:"key"`
+			Acks               string          `json:"acks"`
+			Idempotent         bool            `json:"idempotent"`
+			BatchMaxBytes      int32           `json:"batch_max_bytes"`
+			Linger             pl.Duration     `json:"linger"`
+			MaxBufferedRecords int             `json:"max_buffered_records"`
+			SASLMechanism      string          `json:"sasl_mechanism"`
+			SASLUser           string          `json:"sasl_user"`
+			SASLPassword       string          `json:"sasl_password"`
+			TLS                *tlsconf.Config `json:"tls"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		tlsConf, err := c.TLS.Client()
+		if err != nil {
+			return nil, err
+		}
+		acks, err := enumOption("acks", c.Acks, map[string]KafkaAcks{
+			"all": KafkaAcksAll, "leader": KafkaAcksLeader, "none": KafkaAcksNone,
+		})
+		if err != nil {
+			return nil, err
+		}
+		var key KeyFunc
+		if c.Key != "" {
+			tmpl, err := newRecordTemplate("key", c.Key)
+			if err != nil {
+				return nil, err
+			}
+			key = func(drr pl.DataRawReadable) ([]byte, error) {
+				p, err := drr.Data().Read()
+				if err != nil {
+					return nil, err
+				}
+				k, err := tmpl.render(p)
+				return []byte(k), err
+			}
+		}
+		s, err := NewKafka(KafkaConfig{
+			Brokers:            c.Brokers,
+			Topic:              c.Topic,
+			Key:                key,
+			Acks:               acks,
+			Idempotent:         c.Idempotent,
+			BatchMaxBytes:      c.BatchMaxBytes,
+			Linger:             time.Duration(c.Linger),
+			MaxBufferedRecords: c.MaxBufferedRecords,
+			SASLMechanism:      c.SASLMechanism,
+			SASLUser:           c.SASLUser,
+			SASLPassword:       c.SASLPassword,
+			TLS:                tlsConf,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	},
+		pl.Option{Name: "brokers", Type: pl.OptionArray, Required: true,
+			Description: "seed brokers"},
+		pl.Option{Name: "topic", Type: pl.OptionString, Required: true,
+			Description: "topic records are produced to"},
+		pl.Option{Name: "key", Type: pl.OptionString,
+			Description: "partition key template rendered against each JSON record, sticky partitioning when empty"},
+		pl.Option{Name: "acks", Type: pl.OptionString, Default: "all",
+			Description: "all, leader or none"},
+		pl.Option{Name: "idempotent", Type: pl.OptionBoolean,
+			Description: "enable idempotent writes, requires all acks"},
+		pl.Option{Name: "batch_max_bytes", Type: pl.OptionInteger,
+			Description: "max bytes per partition batch, 0 uses the client default"},
+		pl.Option{Name: "linger", Type: pl.OptionDuration,
+			Description: "how long to wait for a batch to fill"},
+		pl.Option{Name: "max_buffered_records", Type: pl.OptionInteger,
+			Description: "max records buffered before loading blocks"},
+		pl.Option{Name: "sasl_mechanism", Type: pl.OptionString,
+			Description: "PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512"},
+		pl.Option{Name: "sasl_user", Type: pl.OptionString},
+		pl.Option{Name: "sasl_password", Type: pl.OptionString},
+		tlsOption,
+	)
+
+	Register("kinesis", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			StreamName    string      `json:"stream_name"`
+			StreamARN     string      `json:"stream_arn"`
+			Region        string      `json:"region"`
+			Endpoint      string      `json:"endpoint"`
+			PartitionKey  string      `json:"partition_key"`
+			BatchSize     int         `json:"batch_size"`
+			FlushInterval pl.Duration `json:"flush_interval"`
+			MaxRetries    int         `json:"max_retries"`
+			RetryBackoff  pl.Duration `json:"retry_backoff"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		s, err := NewKinesis(KinesisConfig{
+			StreamName:    c.StreamName,
+			StreamARN:     c.StreamARN,
+			Region:        c.Region,
+			Endpoint:      c.Endpoint,
+			PartitionKey:  c.PartitionKey,
+			BatchSize:     c.BatchSize,
+			FlushInterval: time.Duration(c.FlushInterval),
+			MaxRetries:    c.MaxRetries,
+			RetryBackoff:  time.Duration(c.RetryBackoff),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	}, slices.Concat([]pl.Option{
+		{Name: "stream_name", Type: pl.OptionString,
+			Description: "name of the data stream"},
+		{Name: "stream_arn", Type: pl.OptionString,
+			Description: "stream ARN, used instead of the name when set"},
+		{Name: "region", Type: pl.OptionString,
+			Description: "region, the default AWS config chain is used when empty"},
+		{Name: "endpoint", Type: pl.OptionString,
+			Description: "endpoint override"},
+		{Name: "partition_key", Type: pl.OptionString,
+			Description: "partition key template rendered against each JSON record, random when empty"},
+	}, batchOptions(500, "1s"), retryOptions(5, "100ms"))...)
+
+	Register("sqs", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			QueueURL        string      `json:"queue_url"`
+			Region          string      `json:"region"`
+			Endpoint        string      `json:"endpoint"`
+			MessageGroupID  string      `json:"message_group_id"`
+			DeduplicationID string      `json:"deduplication_id"`
+			BatchSize       int         `json:"batch_size"`
+			FlushInterval   pl.Duration `json:"flush_interval"`
+			MaxRetries      int         `json:"max_retries"`
+			RetryBackoff    pl.Duration `json:"retry_backoff"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		s, err := NewSQS(SQSConfig{
+			QueueURL:        c.QueueURL,
+			Region:          c.Region,
+			Endpoint:        c.Endpoint,
+			MessageGroupID:  c.MessageGroupID,
+			DeduplicationID: c.DeduplicationID,
+			BatchSize:       c.BatchSize,
+			FlushInterval:   time.Duration(c.FlushInterval),
+			MaxRetries:      c.MaxRetries,
+			RetryBackoff:    time.Duration(c.RetryBackoff),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	}, slices.Concat([]pl.Option{
+		{Name: "queue_url", Type: pl.OptionString, Required: true,
+			Description: "queue URL, queues ending in .fifo are sent as FIFO"},
+		{Name: "region", Type: pl.OptionString,
+			Description: "region, the default AWS config chain is used when empty"},
+		{Name: "endpoint", Type: pl.OptionString,
+			Description: "endpoint override"},
+		{Name: "message_group_id", Type: pl.OptionString,
+			Description: "message group id template, required for FIFO queues"},
+		{Name: "deduplication_id", Type: pl.OptionString,
+			Description: "deduplication id template for FIFO queues"},
+	}, batchOptions(10, "1s"), retryOptions(3, "100ms"))...)
+
+	Register("pubsub", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			ProjectID      string      `json:"project_id"`
+			Topic          string      `json:"topic"`
+			OrderingKey    string      `json:"ordering_key"`
+			CountThreshold int         `json:"count_threshold"`
+			ByteThreshold  int         `json:"byte_threshold"`
+			DelayThreshold pl.Duration `json:"delay_threshold"`
+			Timeout        pl.Duration `json:"timeout"`
+			MaxOutstanding int         `json:"max_outstanding"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		s, err := NewPubSub(PubSubConfig{
+			ProjectID:      c.ProjectID,
+			Topic:          c.Topic,
+			OrderingKey:    c.OrderingKey,
+			CountThreshold: c.CountThreshold,
+			ByteThreshold:  c.ByteThreshold,
+			DelayThreshold: time.Duration(c.DelayThreshold),
+			Timeout:        time.Duration(c.Timeout),
+			MaxOutstanding: c.MaxOutstanding,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	},
+		pl.Option{Name: "project_id", Type: pl.OptionString, Required: true,
+			Description: "Google Cloud project id"},
+		pl.Option{Name: "topic", Type: pl.OptionString, Required: true,
+			Description: "topic id or fully qualified topic name"},
+		pl.Option{Name: "ordering_key", Type: pl.OptionString,
+			Description: "ordering key template rendered against each JSON record, enables message ordering"},
+		pl.Option{Name: "count_threshold", Type: pl.OptionInteger, Default: 100,
+			Description: "messages per publish request"},
+		pl.Option{Name: "byte_threshold", Type: pl.OptionInteger, Default: 1000000,
+			Description: "bytes per publish request"},
+		pl.Option{Name: "delay_threshold", Type: pl.OptionDuration, Default: "10ms",
+			Description: "max time a message is held before publishing"},
+		pl.Option{Name: "timeout", Type: pl.OptionDuration, Default: "1m0s",
+			Description: "publish timeout"},
+		pl.Option{Name: "max_outstanding", Type: pl.OptionInteger, Default: 1000,
+			Description: "publishes awaiting their result"},
+	)
+
+	Register("azure_blob", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			ConnectionString string      `json:"connection_string"`
+			Container        string      `json:"container"`
+			Path             string      `json:"path"`
+			BatchSize        int         `json:"batch_size"`
+			FlushInterval    pl.Duration `json:"flush_interval"`
+			MaxBlocks        int         `json:"max_blocks"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		s, err := NewAzureBlob(AzureBlobConfig{
+			ConnectionString: c.ConnectionString,
+			Container:        c.Container,
+			Path:             c.Path,
+			BatchSize:        c.BatchSize,
+			FlushInterval:    time.Duration(c.FlushInterval),
+			MaxBlocks:        c.MaxBlocks,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	}, slices.Concat([]pl.Option{
+		{Name: "connection_string", Type: pl.OptionString, Required: true,
+			Description: "storage account connection string"},
+		{Name: "container", Type: pl.OptionString, Required: true,
+			Description: "container name template"},
+		{Name: "path", Type: pl.OptionString,
+			Description: "blob path template"},
+		{Name: "max_blocks", Type: pl.OptionInteger, Default: 1000,
+			Description: "blocks per blob before a new blob is started"},
+	}, batchOptions(1000, "10s"))...)
+
+	Register("avro_file", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Schema        string      `json:"schema"`
+			Codec         string      `json:"codec"`
+			Path          string      `json:"path"`
+			BatchSize     int         `json:"batch_size"`
+			FlushInterval pl.Duration `json:"flush_interval"`
+			MaxBytes      int64       `json:"max_bytes"`
+			MaxAge        pl.Duration `json:"max_age"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		s, err := NewAvroFile(AvroFileConfig{
+			Schema:        c.Schema,
+			Codec:         c.Codec,
+			Path:          c.Path,
+			BatchSize:     c.BatchSize,
+			FlushInterval: time.Duration(c.FlushInterval),
+			MaxBytes:      c.MaxBytes,
+			MaxAge:        time.Duration(c.MaxAge),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	}, slices.Concat([]pl.Option{
+		{Name: "schema", Type: pl.OptionString, Required: true,
+			Description: "Avro schema of the records"},
+		{Name: "codec", Type: pl.OptionString, Default: AvroCodecNull,
+			Description: "null, deflate or snappy"},
+		{Name: "path", Type: pl.OptionString,
+			Description: "file path template"},
+		{Name: "max_bytes", Type: pl.OptionInteger, Default: 128 << 20,
+			Description: "file size after which a new file is started"},
+		{Name: "max_age", Type: pl.OptionDuration, Default: "1h0m0s",
+			Description: "file age after which a new file is started"},
+	}, batchOptions(1000, "10s"))...)
+
+	Register("opensearch", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Addresses     []string        `json:"addresses"`
+			Index         string          `json:"index"`
+			DataStream    bool            `json:"data_stream"`
+			Username      string          `json:"username"`
+			Password      string          `json:"password"`
+			AWSRegion     string          `json:"aws_region"`
+			AWSService    string          `json:"aws_service"`
+			Workers       int             `json:"workers"`
+			FlushBytes    int             `json:"flush_bytes"`
+			FlushInterval pl.Duration     `json:"flush_interval"`
+			TLS           *tlsconf.Config `json:"tls"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		tlsConf, err := c.TLS.Client()
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewOpenSearch(OpenSearchConfig{
+			Addresses:     c.Addresses,
+			Index:         c.Index,
+			DataStream:    c.DataStream,
+			Username:      c.Username,
+			Password:      c.Password,
+			AWSRegion:     c.AWSRegion,
+			AWSService:    c.AWSService,
+			Workers:       c.Workers,
+			FlushBytes:    c.FlushBytes,
+			FlushInterval: time.Duration(c.FlushInterval),
+			TLS:           tlsConf,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	},
+		pl.Option{Name: "addresses", Type: pl.OptionArray, Required: true,
+			Description: "OpenSearch node URLs"},
+		pl.Option{Name: "index", Type: pl.OptionString, Required: true,
+			Description: "target index or data stream name"},
+		pl.Option{Name: "data_stream", Type: pl.OptionBoolean,
+			Description: "write with the create action required by data streams"},
+		pl.Option{Name: "username", Type: pl.OptionString},
+		pl.Option{Name: "password", Type: pl.OptionString},
+		pl.Option{Name: "aws_region", Type: pl.OptionString,
+			Description: "enables AWS SigV4 request signing when set"},
+		pl.Option{Name: "aws_service", Type: pl.OptionString,
+			Description: "SigV4 service name, es for managed domains or aoss for serverless"},
+		pl.Option{Name: "workers", Type: pl.OptionInteger, Default: 1,
+			Description: "bulk indexer workers"},
+		pl.Option{Name: "flush_bytes", Type: pl.OptionInteger, Default: 5000000,
+			Description: "flush threshold in bytes"},
+		pl.Option{Name: "flush_interval", Type: pl.OptionDuration, Default: "5s",
+			Description: "flush threshold as duration"},
+		tlsOption,
+	)
+
+	Register("postgres", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			ConnString    string         `json:"conn_string"`
+			Table         string         `json:"table"`
+			PayloadColumn string         `json:"payload_column"`
+			Columns       []columnOption `json:"columns"`
+			UseCopy       bool           `json:"use_copy"`
+			BatchSize     int            `json:"batch_size"`
+			FlushInterval pl.Duration    `json:"flush_interval"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		s, err := NewPostgres(PostgresConfig{
+			ConnString:    c.ConnString,
+			Table:         c.Table,
+			PayloadColumn: c.PayloadColumn,
+			Columns:       columns(c.Columns),
+			UseCopy:       c.UseCopy,
+			BatchSize:     c.BatchSize,
+			FlushInterval: time.Duration(c.FlushInterval),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	}, slices.Concat([]pl.Option{
+		{Name: "conn_string", Type: pl.OptionString, Required: true,
+			Description: "libpq style connection string or URL"},
+		{Name: "table", Type: pl.OptionString, Required: true,
+			Description: "target table, optionally schema qualified"},
+		{Name: "payload_column", Type: pl.OptionString, Default: "payload",
+			Description: "JSONB column holding the full payload"},
+		{Name: "columns", Type: pl.OptionArray,
+			Description: "metadata columns extracted from the payload, as objects of a name and a field path"},
+		{Name: "use_copy", Type: pl.OptionBoolean,
+			Description: "load batches with COPY instead of batched INSERTs"},
+	}, batchOptions(1000, "1s"))...)
+
+	Register("timescale", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			ConnString    string         `json:"conn_string"`
+			Table         string         `json:"table"`
+			TimeColumn    string         `json:"time_column"`
+			TimeField     string         `json:"time_field"`
+			PayloadColumn string         `json:"payload_column"`
+			Columns       []columnOption `json:"columns"`
+			ChunkInterval pl.Duration    `json:"chunk_interval"`
+			BatchSize     int            `json:"batch_size"`
+			FlushInterval pl.Duration    `json:"flush_interval"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		s, err := NewTimescale(TimescaleConfig{
+			ConnString:    c.ConnString,
+			Table:         c.Table,
+			TimeColumn:    c.TimeColumn,
+			TimeField:     c.TimeField,
+			PayloadColumn: c.PayloadColumn,
+			Columns:       columns(c.Columns),
+			ChunkInterval: time.Duration(c.ChunkInterval),
+			BatchSize:     c.BatchSize,
+			FlushInterval: time.Duration(c.FlushInterval),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	}, slices.Concat([]pl.Option{
+		{Name: "conn_string", Type: pl.OptionString, Required: true,
+			Description: "libpq style connection string or URL"},
+		{Name: "table", Type: pl.OptionString, Required: true,
+			Description: "target hypertable, optionally schema qualified"},
+		{Name: "time_column", Type: pl.OptionString, Default: "time",
+			Description: "time partitioning column of the hypertable"},
+		{Name: "time_field", Type: pl.OptionString, Default: "timestamp",
+			Description: "dot separated path of the record time in the payload"},
+		{Name: "payload_column", Type: pl.OptionString, Default: "payload",
+			Description: "JSONB column holding the full payload"},
+		{Name: "columns", Type: pl.OptionArray,
+			Description: "metadata columns extracted from the payload, as objects of a name and a field path"},
+		{Name: "chunk_interval", Type: pl.OptionDuration, Default: "168h0m0s",
+			Description: "chunk time interval of the hypertable"},
+	}, batchOptions(5000, "1s"))...)
+
+	Register("clickhouse", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Addr          []string        `json:"addr"`
+			Database      string          `json:"database"`
+			Username      string          `json:"username"`
+			Password      string          `json:"password"`
+			Table         string          `json:"table"`
+			Columns       []columnOption  `json:"columns"`
+			AsyncInsert   bool            `json:"async_insert"`
+			BatchSize     int             `json:"batch_size"`
+			FlushInterval pl.Duration     `json:"flush_interval"`
+			MaxRetries    int             `json:"max_retries"`
+			RetryBackoff  pl.Duration     `json:"retry_backoff"`
+			TLS           *tlsconf.Config `json:"tls"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		tlsConf, err := c.TLS.Client()
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewClickHouse(ClickHouseConfig{
+			Addr:          c.Addr,
+			Database:      c.Database,
+			Username:      c.Username,
+			Password:      c.Password,
+			Table:         c.Table,
+			Columns:       columns(c.Columns),
+			AsyncInsert:   c.AsyncInsert,
+			BatchSize:     c.BatchSize,
+			FlushInterval: time.Duration(c.FlushInterval),
+			MaxRetries:    c.MaxRetries,
+			RetryBackoff:  time.Duration(c.RetryBackoff),
+			TLS:           tlsConf,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	}, slices.Concat([]pl.Option{
+		{Name: "addr", Type: pl.OptionArray, Required: true,
+			Description: "native protocol addresses"},
+		{Name: "database", Type: pl.OptionString},
+		{Name: "username", Type: pl.OptionString},
+		{Name: "password", Type: pl.OptionString},
+		{Name: "table", Type: pl.OptionString, Required: true,
+			Description: "target table"},
+		{Name: "columns", Type: pl.OptionArray, Required: true,
+			Description: "column mapping in insert order, as objects of a name and a field path"},
+		{Name: "async_insert", Type: pl.OptionBoolean,
+			Description: "use server side async inserts"},
+		tlsOption,
+	}, batchOptions(1000, "1s"), retryOptions(3, "100ms"))...)
+
+	Register("fluentd", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Addr          string          `json:"addr"`
+			SharedKey     string          `json:"shared_key"`
+			Username      string          `json:"username"`
+			Password      string          `json:"password"`
+			Hostname      string          `json:"hostname"`
+			Tag           string          `json:"tag"`
+			TimeField     string          `json:"time_field"`
+			RequireAck    bool            `json:"require_ack"`
+			BatchSize     int             `json:"batch_size"`
+			FlushInterval pl.Duration     `json:"flush_interval"`
+			Timeout       pl.Duration     `json:"timeout"`
+			MaxRetries    int             `json:"max_retries"`
+			RetryBackoff  pl.Duration     `json:"retry_backoff"`
+			TLS           *tlsconf.Config `json:"tls"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		tlsConf, err := c.TLS.Client()
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewFluentd(FluentdConfig{
+			Addr:          c.Addr,
+			SharedKey:     c.SharedKey,
+			Username:      c.Username,
+			Password:      c.Password,
+			Hostname:      c.Hostname,
+			Tag:           c.Tag,
+			TimeField:     c.TimeField,
+			RequireAck:    c.RequireAck,
+			BatchSize:     c.BatchSize,
+			FlushInterval: time.Duration(c.FlushInterval),
+			Timeout:       time.Duration(c.Timeout),
+			MaxRetries:    c.MaxRetries,
+			RetryBackoff:  time.Duration(c.RetryBackoff),
+			TLS:           tlsConf,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	}, slices.Concat([]pl.Option{
+		{Name: "addr", Type: pl.OptionString, Required: true,
+			Description: "forward input address as host:port"},
+		{Name: "shared_key", Type: pl.OptionString,
+			Description: "shared key of the forward input, enables the authentication handshake"},
+		{Name: "username", Type: pl.OptionString},
+		{Name: "password", Type: pl.OptionString},
+		{Name: "hostname", Type: pl.OptionString,
+			Description: "client hostname sent in the handshake, defaults to the host name"},
+		{Name: "tag", Type: pl.OptionString, Required: true,
+			Description: "tag template"},
+		{Name: "time_field", Type: pl.OptionString,
+			Description: "dot separated path of the event time"},
+		{Name: "require_ack", Type: pl.OptionBoolean,
+			Description: "wait for the server to acknowledge each chunk"},
+		{Name: "timeout", Type: pl.OptionDuration, Default: "30s",
+			Description: "timeout for connecting, writing a message and reading its ack"},
+		tlsOption,
+	}, batchOptions(1000, "1s"), retryOptions(3, "1s"))...)
+
+	Register("grpc", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Target        string          `json:"target"`
+			Token         string          `json:"token"`
+			WindowSize    int             `json:"window_size"`
+			FlushInterval pl.Duration     `json:"flush_interval"`
+			Timeout       pl.Duration     `json:"timeout"`
+			TLS           *tlsconf.Config `json:"tls"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		tlsConf, err := c.TLS.Client()
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewGRPC(GRPCConfig{
+			Target:        c.Target,
+			Token:         c.Token,
+			WindowSize:    c.WindowSize,
+			FlushInterval: time.Duration(c.FlushInterval),
+			Timeout:       time.Duration(c.Timeout),
+			TLS:           tlsConf,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	},
+		pl.Option{Name: "target", Type: pl.OptionString, Required: true,
+			Description: "receiver address"},
+		pl.Option{Name: "token", Type: pl.OptionString,
+			Description: "bearer token sent with every window"},
+		pl.Option{Name: "window_size", Type: pl.OptionInteger, Default: 500,
+			Description: "records per acknowledgment window"},
+		pl.Option{Name: "flush_interval", Type: pl.OptionDuration, Default: "1s",
+			Description: "max time a partial window is held"},
+		pl.Option{Name: "timeout", Type: pl.OptionDuration, Default: "30s",
+			Description: "deadline for sending and acknowledging a window"},
+		tlsOption,
+	)
+
+	Register("mqtt", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Brokers        []string        `json:"brokers"`
+			ClientID       string          `json:"client_id"`
+			Username       string          `json:"username"`
+			Password       string          `json:"password"`
+			Topic          string          `json:"topic"`
+			QoS            byte            `json:"qos"`
+			Retained       bool            `json:"retained"`
+			BufferSize     int             `json:"buffer_size"`
+			ConnectTimeout pl.Duration     `json:"connect_timeout"`
+			PublishTimeout pl.Duration     `json:"publish_timeout"`
+			TLS            *tlsconf.Config `json:"tls"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		tlsConf, err := c.TLS.Client()
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewMQTT(MQTTConfig{
+			Brokers:        c.Brokers,
+			ClientID:       c.ClientID,
+			Username:       c.Username,
+			Password:       c.Password,
+			Topic:          c.Topic,
+			QoS:            c.QoS,
+			Retained:       c.Retained,
+			BufferSize:     c.BufferSize,
+			ConnectTimeout: time.Duration(c.ConnectTimeout),
+			PublishTimeout: time.Duration(c.PublishTimeout),
+			TLS:            tlsConf,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySink[pl.DataRawReadable](s), nil
+	},
+		pl.Option{Name: "brokers", Type: pl.OptionArray, Required: true,
+			Description: "broker URLs"},
+		pl.Option{Name: "client_id", Type: pl.OptionString,
+			Description: "client id, generated by the broker when empty"},
+		pl.Option{Name: "username", Type: pl.OptionString},
+		pl.Option{Name: "password", Type: pl.OptionString},
+		pl.Option{Name: "topic", Type: pl.OptionString, Required: true,
+			Description: "topic template rendered against each JSON record"},
+		pl.Option{Name: "qos", Type: pl.OptionInteger,
+			Description: "quality of service 0, 1 or 2"},
+		pl.Option{Name: "retained", Type: pl.OptionBoolean,
+			Description: "publish with the retained flag"},
+		pl.Option{Name: "buffer_size", Type: pl.OptionInteger, Default: 1000,
+			Description: "publishes held while waiting for delivery or reconnect"},
+		pl.Option{Name: "connect_timeout", Type: pl.OptionDuration, Default: "10s",
+			Description: "timeout for the initial connection"},
+		pl.Option{Name: "publish_timeout", Type: pl.OptionDuration, Default: "1m0s",
+			Description: "max wait for delivery of a buffered publish"},
+		tlsOption,
+	)
+
+	Register("alert", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Provider        string            `json:"provider"`
+			Key             string            `json:"key"`
+			URL             string            `json:"url"`
+			Summary         string            `json:"summary"`
+			DedupKey        string            `json:"dedup_key"`
+			Source          string            `json:"source"`
+			SeverityField   string            `json:"severity_field"`
+			SeverityMap     map[string]string `json:"severity_map"`
+			DefaultSeverity string            `json:"default_severity"`
+			Timeout         pl.Duration       `json:"timeout"`
+			MaxRetries      int               `json:"max_retries"`
+			RetryBackoff    pl.Duration       `json:"retry_backoff"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		provider, err := enumOption("provider", c.Provider, map[string]AlertProvider{
+			"pagerduty": AlertPagerDuty, "opsgenie": AlertOpsgenie,
+		})
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewAlert[any](AlertConfig{
+			Provider:        provider,
+			Key:             c.Key,
+			URL:             c.URL,
+			Summary:         c.Summary,
+			DedupKey:        c.DedupKey,
+			Source:          c.Source,
+			SeverityField:   c.SeverityField,
+			SeverityMap:     c.SeverityMap,
+			DefaultSeverity: c.DefaultSeverity,
+			Timeout:         time.Duration(c.Timeout),
+			MaxRetries:      c.MaxRetries,
+			RetryBackoff:    time.Duration(c.RetryBackoff),
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}, slices.Concat([]pl.Option{
+		{Name: "provider", Type: pl.OptionString, Default: "pagerduty",
+			Description: "pagerduty or opsgenie"},
+		{Name: "key", Type: pl.OptionString, Required: true,
+			Description: "PagerDuty integration routing key or Opsgenie API key"},
+		{Name: "url", Type: pl.OptionString,
+			Description: "endpoint, defaults to the provider endpoint"},
+		{Name: "summary", Type: pl.OptionString, Default: "{{json .}}",
+			Description: "summary template"},
+		{Name: "dedup_key", Type: pl.OptionString,
+			Description: "dedup key template, alerts with the same key are grouped by the provider"},
+		{Name: "source", Type: pl.OptionString, Default: "krapht",
+			Description: "source template of the alert"},
+		{Name: "severity_field", Type: pl.OptionString,
+			Description: "dot separated path of the record field holding the severity"},
+		{Name: "severity_map", Type: pl.OptionObject,
+			Description: "severities by field value"},
+		{Name: "default_severity", Type: pl.OptionString, Default: SeverityError,
+			Description: "severity when the field is missing or unmapped"},
+		{Name: "timeout", Type: pl.OptionDuration, Default: "10s",
+			Description: "request timeout"},
+	}, retryOptions(3, "1s"))...)
+
+	Register("chat", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			URL          string      `json:"url"`
+			Platform     string      `json:"platform"`
+			Title        string      `json:"title"`
+			Template     string      `json:"template"`
+			BurstWindow  pl.Duration `json:"burst_window"`
+			MinInterval  pl.Duration `json:"min_interval"`
+			MaxRecords   int         `json:"max_records"`
+			Timeout      pl.Duration `json:"timeout"`
+			MaxRetries   int         `json:"max_retries"`
+			RetryBackoff pl.Duration `json:"retry_backoff"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		platform, err := enumOption("platform", c.Platform, map[string]ChatPlatform{
+			"slack": ChatSlack, "teams": ChatTeams,
+		})
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewChat[any](ChatConfig{
+			URL:          c.URL,
+			Platform:     platform,
+			Title:        c.Title,
+			Template:     c.Template,
+			BurstWindow:  time.Duration(c.BurstWindow),
+			MinInterval:  time.Duration(c.MinInterval),
+			MaxRecords:   c.MaxRecords,
+			Timeout:      time.Duration(c.Timeout),
+			MaxRetries:   c.MaxRetries,
+			RetryBackoff: time.Duration(c.RetryBackoff),
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}, slices.Concat([]pl.Option{
+		{Name: "url", Type: pl.OptionString, Required: true,
+			Description: "incoming webhook URL"},
+		{Name: "platform", Type: pl.OptionString, Default: "slack",
+			Description: "slack or teams"},
+		{Name: "title", Type: pl.OptionString, Default: "{{.Count}} krapht alerts",
+			Description: "summary title template"},
+		{Name: "template", Type: pl.OptionString, Default: "{{json .}}",
+			Description: "template each record is rendered with"},
+		{Name: "burst_window", Type: pl.OptionDuration, Default: "5s",
+			Description: "time a burst of records is coalesced into one message"},
+		{Name: "min_interval", Type: pl.OptionDuration, Default: "1s",
+			Description: "minimum time between messages"},
+		{Name: "max_records", Type: pl.OptionInteger, Default: 20,
+			Description: "records rendered per summary message"},
+		{Name: "timeout", Type: pl.OptionDuration, Default: "10s",
+			Description: "request timeout"},
+	}, retryOptions(3, "1s"))...)
+
+	Register("smtp", func(conf json.RawMessage) (pl.Sink[any], error) {
+		var c struct {
+			Addr         string          `json:"addr"`
+			Username     string          `json:"username"`
+			Password     string          `json:"password"`
+			ImplicitTLS  bool            `json:"implicit_tls"`
+			From         string          `json:"from"`
+			To           []string        `json:"to"`
+			Subject      string          `json:"subject"`
+			Template     string          `json:"template"`
+			DigestWindow pl.Duration     `json:"digest_window"`
+			MinInterval  pl.Duration     `json:"min_interval"`
+			MaxRecords   int             `json:"max_records"`
+			Timeout      pl.Duration     `json:"timeout"`
+			MaxRetries   int             `json:"max_retries"`
+			RetryBackoff pl.Duration     `json:"retry_backoff"`
+			TLS          *tlsconf.Config `json:"tls"`
+		}
+		if err := pl.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		tlsConf, err := c.TLS.Client()
+		if err != nil {
+			return nil, err
+		}
+		s, err := NewSMTP[any](SMTPConfig{
+			Addr:         c.Addr,
+			Username:     c.Username,
+			Password:     c.Password,
+			ImplicitTLS:  c.ImplicitTLS,
+			From:         c.From,
+			To:           c.To,
+			Subject:      c.Subject,
+			Template:     c.Template,
+			DigestWindow: time.Duration(c.DigestWindow),
+			MinInterval:  time.Duration(c.MinInterval),
+			MaxRecords:   c.MaxRecords,
+			Timeout:      time.Duration(c.Timeout),
+			MaxRetries:   c.MaxRetries,
+			RetryBackoff: time.Duration(c.RetryBackoff),
+			TLS:          tlsConf,
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		return s, nil
+	}, slices.Concat([]pl.Option{
+		{Name: "addr", Type: pl.OptionString, Required: true,
+			Description: "server address as host:port"},
+		{Name: "username", Type: pl.OptionString},
+		{Name: "password", Type: pl.OptionString},
+		{Name: "implicit_tls", Type: pl.OptionBoolean,
+			Description: "connect with TLS instead of upgrading with STARTTLS"},
+		{Name: "from", Type: pl.OptionString, Required: true,
+			Description: "sender address"},
+		{Name: "to", Type: pl.OptionArray, Required: true,
+			Description: "recipient addresses"},
+		{Name: "subject", Type: pl.OptionString, Default: "{{.Count}} krapht alerts",
+			Description: "subject template"},
+		{Name: "template", Type: pl.OptionString, Default: "{{json .}}",
+			Description: "template each record is rendered with"},
+		{Name: "digest_window", Type: pl.OptionDuration, Default: "1m0s",
+			Description: "time records are collected into a digest"},
+		{Name: "min_interval", Type: pl.OptionDuration, Default: "5m0s",
+			Description: "minimum time between emails"},
+		{Name: "max_records", Type: pl.OptionInteger, Default: 100,
+			Description: "records rendered per email"},
+		{Name: "timeout", Type: pl.OptionDuration, Default: "30s",
+			Description: "timeout of a single delivery"},
+		tlsOption,
+	}, retryOptions(3, "1s"))...)
+}
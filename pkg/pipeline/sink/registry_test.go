@@ -0,0 +1,59 @@
+package sink_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+func TestNewRegistered(t *testing.T) {
+	assert.Subset(t, sink.Registered(), []string{"blackhole", "kafka", "noop", "postgres", "webhook", "writer"})
+
+	s, err := sink.NewRegistered("blackhole", json.RawMessage(`{"rate": 0}`))
+	assert.NoError(t, err)
+
+	in := make(chan any, 2)
+	in <- 1
+	in <- "two"
+	close(in)
+	s.Load(in, nil)
+
+	_, err = sink.NewRegistered("writer", json.RawMessage(`{"encoding": "xml"}`))
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+	assert.ErrorContains(t, err, "encoding must be one of json, logfmt, ndjson, pretty, raw")
+
+	_, err = sink.NewRegistered("webhook", json.RawMessage(`{"url": "http://localhost", "timeout": "soon"}`))
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+
+	_, err = sink.NewRegistered("missing", nil)
+	assert.ErrorIs(t, err, sink.ErrUnknownSink)
+}
+
+func TestAnySink(t *testing.T) {
+	var got []string
+	s := sink.NewAnySink[string](sinkFunc[string](func(in <-chan string) {
+		for v := range in {
+			got = append(got, v)
+		}
+	}))
+
+	in := make(chan any, 3)
+	in <- "a"
+	in <- 42 // not a string
+	in <- "b"
+	close(in)
+
+	eventC := make(chan pipeline.Event, 1)
+	s.Load(in, eventC)
+	assert.Equal(t, []string{"a", "b"}, got)
+	assert.Equal(t, 42, (<-eventC).(pipeline.ErrorEvent).Record())
+}
+
+// sinkFunc is a sink loading its input with a function
+type sinkFunc[I any] func(in <-chan I)
+
+func (f sinkFunc[I]) Load(in <-chan I, _ chan<- pipeline.Event) { f(in) }
@@ -0,0 +1,69 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that ReleaseOnAck implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*ReleaseOnAck[pl.DataRawReadable])(nil)
+
+// ErrReleaseOnAckSink is the error returned by the release on ack sink
+var ErrReleaseOnAckSink = errors.New("sink error")
+
+// ReleaseOnAckSinkPrefix is the prefix for the release on ack sink error
+const ReleaseOnAckSinkPrefix = "release on ack sink"
+
+// ReleaseOnAck is a sink decorator that releases the pooled buffers of records once the wrapped sink
+// acks them, after it has written them to their destination. Records are handed to the wrapped sink as
+// pl.Record values with an ack token that acks the record as before and then releases it; other items
+// are passed unchanged. Records the wrapped sink fails to write are not acked, so their buffers are left
+// to the garbage collector unless a dead-letter sink acks them.
+//
+// The decorator must only wrap the last consumer of the records: a sink behind a tee or fan-out would
+// release them while other branches still read them.
+type ReleaseOnAck[I any] struct {
+	sink pl.Sink[I]
+}
+
+// NewReleaseOnAck creates a new release on ack sink wrapping s.
+func NewReleaseOnAck[I any](s pl.Sink[I]) (*ReleaseOnAck[I], error) {
+	if s == nil {
+		return nil, fmt.Errorf("%s: %w: wrapped sink is required", ReleaseOnAckSinkPrefix, ErrReleaseOnAckSink)
+	}
+
+	return &ReleaseOnAck[I]{sink: s}, nil
+}
+
+// Load loads records into the wrapped sink, releasing each once the wrapped sink acks it.
+// It blocks until the wrapped sink has returned.
+func (r *ReleaseOnAck[I]) Load(in <-chan I, eventC chan<- pl.Event) {
+	out := make(chan I)
+	go func() {
+		defer close(out)
+		for v := range in {
+			if record, ok := any(v).(pl.Record); ok {
+				if w, ok := any(record.WithAck(releaseAck{record: record})).(I); ok {
+					v = w
+				}
+			}
+			out <- v
+		}
+	}()
+
+	r.sink.Load(out, eventC)
+}
+
+// releaseAck is the ack token of a record acking it and then releasing its buffers
+type releaseAck struct {
+	record pl.Record
+}
+
+// Ack acks the record and releases it
+func (a releaseAck) Ack() error {
+	err := a.record.Ack()
+	pl.Release(a.record)
+	return err
+}
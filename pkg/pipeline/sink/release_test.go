@@ -0,0 +1,49 @@
+package sink_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+func TestReleaseOnAck(t *testing.T) {
+	pool := pipeline.NewBufferPool(1 << 10)
+	pooled := func(s string) *pipeline.PooledBytes {
+		buf := pool.Get()
+		buf.WriteString(s)
+		return pool.Bytes(buf)
+	}
+
+	var data, raw []*pipeline.PooledBytes
+	var records []pipeline.Record
+	for _, p := range []string{`{"n":1}`, `{"n":2}`} {
+		data, raw = append(data, pooled(p)), append(raw, pooled(p))
+		records = append(records, pipeline.NewRecord(data[len(data)-1], raw[len(raw)-1]))
+	}
+
+	var out bytes.Buffer
+	s, err := sink.NewReleaseOnAck[pipeline.Record](sink.NewWriter[pipeline.Record](&out, nil))
+	assert.NoError(t, err)
+
+	r := pipeline.NewRunner("test", mock.NewChannelSourceOf(records...))
+	assert.NoError(t, pipeline.SetSink[pipeline.Record](r, "out", s))
+	for range r.Run(context.Background()) {
+	}
+
+	// the records are written before the sink acks them, which returns their buffers to the pool
+	assert.Equal(t, "{\"n\":1}\n{\"n\":2}\n", out.String())
+	for i := range records {
+		_, err := data[i].Read()
+		assert.ErrorIs(t, err, pipeline.ErrReleased)
+		_, err = raw[i].Read()
+		assert.ErrorIs(t, err, pipeline.ErrReleased)
+	}
+
+	_, err = sink.NewReleaseOnAck[pipeline.Record](nil)
+	assert.ErrorIs(t, err, sink.ErrReleaseOnAckSink)
+}
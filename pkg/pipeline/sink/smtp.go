@@ -0,0 +1,318 @@
+package sink
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"text/template"
+	"time"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that SMTP implements the sink interface
+var _ pl.Sink[any] = (*SMTP[any])(nil)
+
+// ErrSMTPSink is the error returned by the SMTP sink
+var ErrSMTPSink = errors.New("sink error")
+
+// SMTPSinkPrefix is the prefix for the SMTP sink error
+const SMTPSinkPrefix = "smtp sink"
+
+// SMTPConfig is the configuration for the SMTP sink.
+type SMTPConfig struct {
+	Addr        string      // server address as host:port
+	Username    string      // optional username for PLAIN authentication
+	Password    string      // password for PLAIN authentication
+	TLS         *tls.Config // TLS configuration for STARTTLS or implicit TLS
+	ImplicitTLS bool        // connect with TLS instead of upgrading with STARTTLS, e.g. on port 465
+
+	From string   // sender address
+	To   []string // recipient addresses
+
+	Subject  string // subject template rendered with DigestData, defaults to "{{.Count}} krapht alerts"
+	Template string // template each record is rendered with, defaults to "{{json .}}"
+
+	DigestWindow time.Duration // time records are collected into a digest, defaults to 1 minute
+	MinInterval  time.Duration // minimum time between emails, defaults to 5 minutes
+	MaxRecords   int           // records rendered per email, the rest are counted, defaults to 100
+
+	Timeout      time.Duration // timeout of a single delivery, defaults to 30 seconds
+	MaxRetries   int           // attempts for temporary failures, defaults to 3
+	RetryBackoff time.Duration // initial backoff between attempts, defaults to 1 second
+}
+
+// mailSender is the subset of SMTP operations used by the SMTP sink
+type mailSender interface {
+	Send(from string, to []string, msg []byte) error
+}
+
+// SMTP is a sink that emails digests of records for low-volume alerting pipelines.
+//
+// Records received within the digest window are rendered through the template and sent
+// as a single email, and emails are sent at most once per MinInterval; records arriving
+// in between are coalesced into the next digest. Payload records are rendered with their
+// decoded JSON payload and other values, such as events, with the value itself, so a
+// Filter can be used to alert on error events. Upstream messages of RawReadable records
+// are acked after the email is accepted by the server.
+type SMTP[I any] struct {
+	sender       mailSender
+	from         string
+	to           []string
+	subject      *template.Template
+	body         *template.Template
+	filter       func(I) bool
+	digestWindow time.Duration
+	minInterval  time.Duration
+	maxRecords   int
+	maxRetries   int
+	retryBackoff time.Duration
+}
+
+// NewSMTP creates a new SMTP sink with the given configuration.
+// A nil filter sends every record.
+func NewSMTP[I any](conf SMTPConfig, filter func(I) bool) (*SMTP[I], error) {
+	if conf.Addr == "" {
+		return nil, fmt.Errorf("%s: %w: address is empty", SMTPSinkPrefix, ErrSMTPSink)
+	}
+
+	host, _, err := net.SplitHostPort(conf.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", SMTPSinkPrefix, ErrSMTPSink, err)
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 30 * time.Second
+	}
+
+	tlsConf := conf.TLS
+	if tlsConf == nil {
+		tlsConf = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if tlsConf.ServerName == "" {
+		tlsConf = tlsConf.Clone()
+		tlsConf.ServerName = host
+	}
+
+	sender := &smtpSender{
+		addr:     conf.Addr,
+		tls:      tlsConf,
+		implicit: conf.ImplicitTLS,
+		timeout:  conf.Timeout,
+	}
+	if conf.Username != "" {
+		sender.auth = smtp.PlainAuth("", conf.Username, conf.Password, host)
+	}
+
+	return newSMTP(sender, conf, filter)
+}
+
+// newSMTP creates an SMTP sink using an existing sender
+func newSMTP[I any](sender mailSender, conf SMTPConfig, filter func(I) bool) (*SMTP[I], error) {
+	if conf.From == "" {
+		return nil, fmt.Errorf("%s: %w: sender is empty", SMTPSinkPrefix, ErrSMTPSink)
+	}
+
+	if len(conf.To) == 0 {
+		return nil, fmt.Errorf("%s: %w: recipients are empty", SMTPSinkPrefix, ErrSMTPSink)
+	}
+
+	if conf.Subject == "" {
+		conf.Subject = "{{.Count}} krapht alerts"
+	}
+
+	if conf.Template == "" {
+		conf.Template = "{{json .}}"
+	}
+
+	if conf.DigestWindow <= 0 {
+		conf.DigestWindow = time.Minute
+	}
+
+	if conf.MinInterval <= 0 {
+		conf.MinInterval = 5 * time.Minute
+	}
+
+	if conf.MaxRecords <= 0 {
+		conf.MaxRecords = 100
+	}
+
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 3
+	}
+
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = time.Second
+	}
+
+	subject, err := newNotifyTemplate("subject", conf.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid subject template: %w", SMTPSinkPrefix, ErrSMTPSink, err)
+	}
+
+	body, err := newNotifyTemplate("body", conf.Template)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid template: %w", SMTPSinkPrefix, ErrSMTPSink, err)
+	}
+
+	return &SMTP[I]{
+		sender:       sender,
+		from:         conf.From,
+		to:           conf.To,
+		subject:      subject,
+		body:         body,
+		filter:       filter,
+		digestWindow: conf.DigestWindow,
+		minInterval:  conf.MinInterval,
+		maxRecords:   conf.MaxRecords,
+		maxRetries:   conf.MaxRetries,
+		retryBackoff: conf.RetryBackoff,
+	}, nil
+}
+
+// Load collects records from the in channel into digests and emails them.
+// It blocks until the in channel is closed and the final digest is sent.
+func (s *SMTP[I]) Load(in <-chan I, eventC chan<- pl.Event) {
+	collectDigests(filterRecords(in, s.filter, eventC), s.digestWindow, s.minInterval, func(digest []I) {
+		s.send(digest, eventC)
+	})
+}
+
+// send renders a digest as an email, delivers it and acks its records
+func (s *SMTP[I]) send(digest []I, eventC chan<- pl.Event) {
+	msg, err := s.message(digest)
+	if err != nil {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to render email",
+			err,
+			false,
+			digest))
+		return
+	}
+
+	err = withRetry(s.maxRetries, s.retryBackoff, isSMTPTemporary, func() error {
+		return s.sender.Send(s.from, s.to, msg)
+	})
+	if err != nil {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to send email",
+			err,
+			isSMTPTemporary(err),
+			digest))
+		return
+	}
+
+	// ack the messages once the server accepted the email
+	ackRecords(digest, eventC)
+}
+
+// message renders the headers and body of a digest email
+func (s *SMTP[I]) message(digest []I) ([]byte, error) {
+	now := time.Now().UTC()
+
+	var subject strings.Builder
+	if err := s.subject.Execute(&subject, DigestData{Count: len(digest), Time: now}); err != nil {
+		return nil, err
+	}
+
+	body, err := renderDigest(s.body, digest, s.maxRecords)
+	if err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject.String()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	return msg.Bytes(), nil
+}
+
+// isSMTPTemporary reports whether an error is worth retrying.
+// Permanent 5xx replies of the server are not; transient 4xx replies and network errors are.
+func isSMTPTemporary(err error) bool {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code < 500
+	}
+	return true
+}
+
+// smtpSender delivers emails with the net/smtp client
+type smtpSender struct {
+	addr     string
+	auth     smtp.Auth
+	tls      *tls.Config
+	implicit bool
+	timeout  time.Duration
+}
+
+// Send delivers a message to the recipients in a single SMTP session
+func (s *smtpSender) Send(from string, to []string, msg []byte) error {
+	dialer := &net.Dialer{Timeout: s.timeout}
+
+	var conn net.Conn
+	var err error
+	if s.implicit {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.addr, s.tls)
+	} else {
+		conn, err = dialer.Dial("tcp", s.addr)
+	}
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		_ = conn.Close()
+		return err
+	}
+
+	c, err := smtp.NewClient(conn, s.tls.ServerName)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok && !s.implicit {
+		if err := c.StartTLS(s.tls); err != nil {
+			return err
+		}
+	}
+
+	if s.auth != nil {
+		if err := c.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
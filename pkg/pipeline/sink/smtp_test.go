@@ -0,0 +1,140 @@
+package sink
+
+import (
+	"errors"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// fakeMailSender records the messages sent
+type fakeMailSender struct {
+	err  error
+	sent []string
+	to   [][]string
+}
+
+func (f *fakeMailSender) Send(_ string, to []string, msg []byte) error {
+	f.to = append(f.to, to)
+	f.sent = append(f.sent, string(msg))
+	return f.err
+}
+
+func TestSMTP_Load(t *testing.T) {
+	conf := SMTPConfig{
+		From:         "krapht@example.com",
+		To:           []string{"soc@example.com"},
+		Subject:      "{{.Count}} blocked hosts",
+		Template:     "blocked {{.host}}",
+		DigestWindow: time.Hour,
+		MaxRecords:   2,
+	}
+
+	t.Run("sends records as a digest", func(t *testing.T) {
+		sender := &fakeMailSender{}
+		s, err := newSMTP[pipeline.DataRawReadable](sender, conf, nil)
+		assert.NoError(t, err)
+
+		records := []ackableRecord{
+			newAckableRecord(`{"host":"a"}`),
+			newAckableRecord(`{"host":"b"}`),
+			newAckableRecord(`{"host":"c"}`),
+		}
+		in := make(chan pipeline.DataRawReadable, len(records))
+		for _, r := range records {
+			in <- r
+		}
+		close(in)
+
+		s.Load(in, nil)
+
+		assert.Len(t, sender.sent, 1)
+		assert.Equal(t, [][]string{{"soc@example.com"}}, sender.to)
+		msg := sender.sent[0]
+		assert.Contains(t, msg, "Subject: 3 blocked hosts\r\n")
+		assert.Contains(t, msg, "\r\n\r\nblocked a\r\nblocked b\r\n... and 1 more\r\n")
+		for _, r := range records {
+			assert.True(t, r.raw.acked)
+		}
+	})
+
+	t.Run("alerts on filtered error events", func(t *testing.T) {
+		sender := &fakeMailSender{}
+		eventConf := conf
+		eventConf.Template = "{{.Error}}"
+		s, err := newSMTP[pipeline.Event](sender, eventConf, func(e pipeline.Event) bool {
+			return e.Type() == pipeline.EventError
+		})
+		assert.NoError(t, err)
+
+		in := make(chan pipeline.Event, 2)
+		in <- pipeline.NewLogEvent("kafka", pipeline.LevelInfo, "connected")
+		in <- pipeline.NewErrorEvent("failed to write", errors.New("broker down"), true)
+		close(in)
+
+		s.Load(in, nil)
+
+		assert.Len(t, sender.sent, 1)
+		assert.Contains(t, sender.sent[0], "Subject: 1 blocked hosts\r\n")
+		assert.Contains(t, sender.sent[0], "failed to write: broker down\r\n")
+	})
+
+	t.Run("does not ack rejected emails", func(t *testing.T) {
+		sender := &fakeMailSender{err: &textproto.Error{Code: 550, Msg: "mailbox unavailable"}}
+		s, err := newSMTP[pipeline.DataRawReadable](sender, conf, nil)
+		assert.NoError(t, err)
+
+		record := newAckableRecord(`{"host":"a"}`)
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		s.Load(in, eventC)
+
+		// permanent replies are not retried
+		assert.Len(t, sender.sent, 1)
+		errEvent, ok := (<-eventC).(pipeline.ErrorEvent)
+		assert.True(t, ok)
+		assert.False(t, errEvent.IsTemporary())
+		assert.False(t, record.raw.acked)
+	})
+}
+
+func TestSMTP_message(t *testing.T) {
+	s, err := newSMTP[[]byte](&fakeMailSender{}, SMTPConfig{
+		From:    "krapht@example.com",
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "Überwachung",
+	}, nil)
+	assert.NoError(t, err)
+
+	msg, err := s.message([][]byte{[]byte("plain text")})
+	assert.NoError(t, err)
+
+	headers, body, _ := strings.Cut(string(msg), "\r\n\r\n")
+	assert.Contains(t, headers, "To: a@example.com, b@example.com")
+	assert.Contains(t, headers, "Subject: =?utf-8?q?=C3=9Cberwachung?=")
+	assert.Equal(t, "\"plain text\"\r\n", body)
+}
+
+func TestIsSMTPTemporary(t *testing.T) {
+	assert.True(t, isSMTPTemporary(&textproto.Error{Code: 421}))
+	assert.False(t, isSMTPTemporary(&textproto.Error{Code: 554}))
+	assert.True(t, isSMTPTemporary(errors.New("connection reset")))
+}
+
+func TestNewSMTP_Validation(t *testing.T) {
+	_, err := NewSMTP[[]byte](SMTPConfig{}, nil)
+	assert.ErrorIs(t, err, ErrSMTPSink)
+
+	_, err = NewSMTP[[]byte](SMTPConfig{Addr: "localhost:25", To: []string{"a@example.com"}}, nil)
+	assert.ErrorIs(t, err, ErrSMTPSink)
+
+	_, err = NewSMTP[[]byte](SMTPConfig{Addr: "localhost:25", From: "b@example.com"}, nil)
+	assert.ErrorIs(t, err, ErrSMTPSink)
+}
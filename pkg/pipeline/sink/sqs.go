@@ -0,0 +1,276 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that SQS implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*SQS)(nil)
+
+// ErrSQSSink is the error returned by the SQS sink
+var ErrSQSSink = errors.New("sink error")
+
+// SQSSinkPrefix is the prefix for the SQS sink error
+const SQSSinkPrefix = "sqs sink"
+
+// sqsMaxBatchEntries is the max number of entries per SendMessageBatch call
+const sqsMaxBatchEntries = 10
+
+// sqsMaxBatchBytes is the max total payload size of a SendMessageBatch call
+const sqsMaxBatchBytes = 256 * 1024
+
+// SQSConfig is the configuration for the SQS sink.
+type SQSConfig struct {
+	QueueURL string // queue URL, queues ending in ".fifo" are sent as FIFO
+	Region   string // optional region, the default AWS config chain is used when empty
+	Endpoint string // optional endpoint override, e.g. for local testing
+
+	MessageGroupID  string // message group id template rendered against each JSON record, required for FIFO queues
+	DeduplicationID string // optional deduplication id template for FIFO queues without content based deduplication
+
+	BatchSize     int           // records per SendMessageBatch call, at most 10 and defaults to 10
+	FlushInterval time.Duration // max time a partial batch is held, defaults to 1 second
+	MaxRetries    int           // attempts for failed entries and server errors, defaults to 3
+	RetryBackoff  time.Duration // initial backoff between attempts, defaults to 100ms
+}
+
+// sqsAPI is the subset of the SQS client used by the SQS sink
+type sqsAPI interface {
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+// SQS is a sink that sends records to an SQS queue in batches.
+// Upstream messages are acked once SQS reports the entry holding them as successful.
+type SQS struct {
+	client          sqsAPI
+	queueURL        string
+	fifo            bool
+	messageGroupID  *recordTemplate
+	deduplicationID *recordTemplate
+	batchSize       int
+	flushInterval   time.Duration
+	maxRetries      int
+	retryBackoff    time.Duration
+}
+
+// NewSQS creates a new SQS sink with the given configuration.
+func NewSQS(conf SQSConfig) (*SQS, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if conf.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(conf.Region))
+	}
+
+	awsConf, err := awsconfig.LoadDefaultConfig(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: failed to load aws config: %w", SQSSinkPrefix, ErrSQSSink, err)
+	}
+
+	client := sqs.NewFromConfig(awsConf, func(o *sqs.Options) {
+		if conf.Endpoint != "" {
+			o.BaseEndpoint = aws.String(conf.Endpoint)
+		}
+	})
+	return newSQS(client, conf)
+}
+
+// newSQS creates an SQS sink using an existing client
+func newSQS(client sqsAPI, conf SQSConfig) (*SQS, error) {
+	if conf.QueueURL == "" {
+		return nil, fmt.Errorf("%s: %w: queue url is empty", SQSSinkPrefix, ErrSQSSink)
+	}
+
+	fifo := strings.HasSuffix(conf.QueueURL, ".fifo")
+	if fifo && conf.MessageGroupID == "" {
+		return nil, fmt.Errorf("%s: %w: message group id is required for fifo queues", SQSSinkPrefix, ErrSQSSink)
+	}
+
+	if conf.BatchSize <= 0 || conf.BatchSize > sqsMaxBatchEntries {
+		conf.BatchSize = sqsMaxBatchEntries
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 3
+	}
+
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = 100 * time.Millisecond
+	}
+
+	s := &SQS{
+		client:        client,
+		queueURL:      conf.QueueURL,
+		fifo:          fifo,
+		batchSize:     conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		maxRetries:    conf.MaxRetries,
+		retryBackoff:  conf.RetryBackoff,
+	}
+
+	if !fifo {
+		return s, nil
+	}
+
+	var err error
+	if s.messageGroupID, err = newRecordTemplate("group", conf.MessageGroupID); err != nil {
+		return nil, fmt.Errorf("%s: %w: invalid message group id template: %w", SQSSinkPrefix, ErrSQSSink, err)
+	}
+
+	if conf.DeduplicationID != "" {
+		if s.deduplicationID, err = newRecordTemplate("dedup", conf.DeduplicationID); err != nil {
+			return nil, fmt.Errorf("%s: %w: invalid deduplication id template: %w", SQSSinkPrefix, ErrSQSSink, err)
+		}
+	}
+	return s, nil
+}
+
+// Load sends records from the in channel to the queue in batches.
+// It blocks until the in channel is closed and the final batch is sent.
+func (s *SQS) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	collectBatches(in, s.batchSize, s.flushInterval, func(batch []pl.DataRawReadable) {
+		var entries []types.SendMessageBatchRequestEntry
+		records := make(map[string]pl.DataRawReadable, len(batch))
+		size := 0
+
+		for i, drr := range batch {
+			entry, err := s.entry(strconv.Itoa(i), drr)
+			if err != nil {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"failed to build sqs message",
+					err,
+					false,
+					drr))
+				continue
+			}
+
+			// keep each call under the batch payload limit
+			n := len(aws.ToString(entry.MessageBody))
+			if len(entries) > 0 && size+n > sqsMaxBatchBytes {
+				s.send(entries, records, eventC)
+				entries, size = nil, 0
+			}
+
+			entries = append(entries, entry)
+			records[aws.ToString(entry.Id)] = drr
+			size += n
+		}
+
+		if len(entries) > 0 {
+			s.send(entries, records, eventC)
+		}
+	})
+}
+
+// entry builds the batch request entry for a record
+func (s *SQS) entry(id string, drr pl.DataRawReadable) (types.SendMessageBatchRequestEntry, error) {
+	p, err := drr.Data().Read()
+	if err != nil {
+		return types.SendMessageBatchRequestEntry{}, err
+	}
+
+	entry := types.SendMessageBatchRequestEntry{
+		Id:          aws.String(id),
+		MessageBody: aws.String(string(p)),
+	}
+
+	if !s.fifo {
+		return entry, nil
+	}
+
+	group, err := s.messageGroupID.render(p)
+	if err != nil {
+		return entry, err
+	}
+	entry.MessageGroupId = aws.String(group)
+
+	if s.deduplicationID != nil {
+		dedup, err := s.deduplicationID.render(p)
+		if err != nil {
+			return entry, err
+		}
+		entry.MessageDeduplicationId = aws.String(dedup)
+	}
+	return entry, nil
+}
+
+// send sends a batch of entries, retrying entries that failed on the SQS side
+func (s *SQS) send(entries []types.SendMessageBatchRequestEntry, records map[string]pl.DataRawReadable, eventC chan<- pl.Event) {
+	remaining := entries
+
+	err := withRetry(s.maxRetries, s.retryBackoff, isSQSTemporary, func() error {
+		out, err := s.client.SendMessageBatch(context.Background(), &sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(s.queueURL),
+			Entries:  remaining,
+		})
+		if err != nil {
+			return err
+		}
+
+		// ack the messages once SQS accepted them
+		for _, ok := range out.Successful {
+			ackMessage(records[aws.ToString(ok.Id)].Raw(), eventC)
+		}
+
+		failed := make(map[string]bool, len(out.Failed))
+		for _, f := range out.Failed {
+			if f.SenderFault {
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					"sqs rejected message",
+					fmt.Errorf("%s: %s", aws.ToString(f.Code), aws.ToString(f.Message)),
+					false,
+					records[aws.ToString(f.Id)]))
+				continue
+			}
+			failed[aws.ToString(f.Id)] = true
+		}
+
+		var retry []types.SendMessageBatchRequestEntry
+		for _, entry := range remaining {
+			if failed[aws.ToString(entry.Id)] {
+				retry = append(retry, entry)
+			}
+		}
+		remaining = retry
+
+		if len(remaining) > 0 {
+			return fmt.Errorf("%d entries failed", len(remaining))
+		}
+		return nil
+	})
+	if err != nil {
+		failed := make([]pl.DataRawReadable, len(remaining))
+		for i, entry := range remaining {
+			failed[i] = records[aws.ToString(entry.Id)]
+		}
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to send messages to sqs",
+			err,
+			isSQSTemporary(err),
+			failed))
+	}
+}
+
+// isSQSTemporary reports whether an SQS error is worth retrying.
+// API errors are temporary only when they are server faults.
+func isSQSTemporary(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorFault() == smithy.FaultServer
+	}
+	return true
+}
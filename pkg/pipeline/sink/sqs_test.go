@@ -0,0 +1,149 @@
+package sink
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// fakeSQS records batch calls, failing entries in failOnce once and rejecting entries in reject
+type fakeSQS struct {
+	calls    [][]types.SendMessageBatchRequestEntry
+	failOnce map[string]bool
+	reject   map[string]bool
+	err      error
+}
+
+func (f *fakeSQS) SendMessageBatch(_ context.Context, params *sqs.SendMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	f.calls = append(f.calls, params.Entries)
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	out := &sqs.SendMessageBatchOutput{}
+	for _, e := range params.Entries {
+		body := aws.ToString(e.MessageBody)
+		switch {
+		case f.reject[body]:
+			out.Failed = append(out.Failed, types.BatchResultErrorEntry{Id: e.Id, SenderFault: true, Code: aws.String("InvalidMessageContents")})
+		case f.failOnce[body]:
+			delete(f.failOnce, body)
+			out.Failed = append(out.Failed, types.BatchResultErrorEntry{Id: e.Id, Code: aws.String("InternalError")})
+		default:
+			out.Successful = append(out.Successful, types.SendMessageBatchResultEntry{Id: e.Id})
+		}
+	}
+	return out, nil
+}
+
+// loadSQS loads the records into the sink and returns the collected events
+func loadSQS(s *SQS, records ...ackableRecord) []pipeline.Event {
+	in := make(chan pipeline.DataRawReadable, len(records))
+	for _, r := range records {
+		in <- r
+	}
+	close(in)
+
+	eventC := make(chan pipeline.Event, 10)
+	s.Load(in, eventC)
+	close(eventC)
+
+	var events []pipeline.Event
+	for e := range eventC {
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestSQS_Load(t *testing.T) {
+	t.Run("sends batches of at most ten entries", func(t *testing.T) {
+		client := &fakeSQS{}
+		s, err := newSQS(client, SQSConfig{QueueURL: "https://sqs/queue", BatchSize: 20})
+		assert.NoError(t, err)
+
+		var records []ackableRecord
+		for range 12 {
+			records = append(records, newAckableRecord("data"))
+		}
+
+		assert.Empty(t, loadSQS(s, records...))
+		assert.Len(t, client.calls, 2)
+		assert.Len(t, client.calls[0], 10)
+		assert.Len(t, client.calls[1], 2)
+		for _, r := range records {
+			assert.True(t, r.raw.acked)
+		}
+	})
+
+	t.Run("renders fifo group and deduplication ids", func(t *testing.T) {
+		client := &fakeSQS{}
+		s, err := newSQS(client, SQSConfig{
+			QueueURL:        "https://sqs/queue.fifo",
+			MessageGroupID:  "{{.host}}",
+			DeduplicationID: "{{.id}}",
+		})
+		assert.NoError(t, err)
+
+		assert.Empty(t, loadSQS(s, newAckableRecord(`{"host":"a","id":"1"}`)))
+		assert.Equal(t, "a", aws.ToString(client.calls[0][0].MessageGroupId))
+		assert.Equal(t, "1", aws.ToString(client.calls[0][0].MessageDeduplicationId))
+	})
+
+	t.Run("retries failed entries and drops rejected ones", func(t *testing.T) {
+		client := &fakeSQS{failOnce: map[string]bool{"b": true}, reject: map[string]bool{"c": true}}
+		s, err := newSQS(client, SQSConfig{QueueURL: "https://sqs/queue", RetryBackoff: time.Millisecond})
+		assert.NoError(t, err)
+
+		a, b, c := newAckableRecord("a"), newAckableRecord("b"), newAckableRecord("c")
+		events := loadSQS(s, a, b, c)
+
+		assert.Len(t, client.calls, 2)
+		assert.Len(t, client.calls[1], 1)
+		assert.True(t, a.raw.acked)
+		assert.True(t, b.raw.acked)
+		assert.False(t, c.raw.acked)
+		assert.Len(t, events, 1)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		client := &fakeSQS{err: &smithy.GenericAPIError{Code: "QueueDoesNotExist", Fault: smithy.FaultClient}}
+		s, err := newSQS(client, SQSConfig{QueueURL: "https://sqs/queue", RetryBackoff: time.Millisecond})
+		assert.NoError(t, err)
+
+		record := newAckableRecord("a")
+		events := loadSQS(s, record)
+
+		assert.Len(t, client.calls, 1)
+		assert.False(t, record.raw.acked)
+		assert.False(t, events[0].(pipeline.ErrorEvent).IsTemporary())
+	})
+
+	t.Run("splits batches over the payload limit", func(t *testing.T) {
+		client := &fakeSQS{}
+		s, err := newSQS(client, SQSConfig{QueueURL: "https://sqs/queue"})
+		assert.NoError(t, err)
+
+		big := strings.Repeat("x", 200*1024)
+		assert.Empty(t, loadSQS(s, newAckableRecord(big), newAckableRecord(big)))
+		assert.Len(t, client.calls, 2)
+	})
+}
+
+func TestNewSQS_Validation(t *testing.T) {
+	_, err := newSQS(&fakeSQS{}, SQSConfig{})
+	assert.ErrorIs(t, err, ErrSQSSink)
+
+	_, err = newSQS(&fakeSQS{}, SQSConfig{QueueURL: "https://sqs/queue.fifo"})
+	assert.ErrorIs(t, err, ErrSQSSink)
+
+	_, err = newSQS(&fakeSQS{}, SQSConfig{QueueURL: "https://sqs/queue.fifo", MessageGroupID: "{{.host"})
+	assert.ErrorIs(t, err, ErrSQSSink)
+}
@@ -0,0 +1,189 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Tee implements the sink interface
+var _ pl.Sink[any] = (*Tee[any])(nil)
+
+// ErrTeeSink is the error returned by the tee sink
+var ErrTeeSink = errors.New("sink error")
+
+// TeeSinkPrefix is the prefix for the tee sink error
+const TeeSinkPrefix = "tee sink"
+
+// errTeeQueueFull is reported when a record is dropped for a branch whose queue is full
+var errTeeQueueFull = errors.New("queue is full")
+
+// TeeBranch is a named sink records are duplicated to.
+// The name is used to attribute the events of the sink.
+type TeeBranch[I any] struct {
+	Name string
+	Sink pl.Sink[I]
+}
+
+// TeeConfig is the configuration for the tee sink.
+type TeeConfig struct {
+	QueueSize    int  // records queued per branch, defaults to 1000
+	DropWhenFull bool // drop records for a branch whose queue is full instead of waiting for it
+}
+
+// Tee is a sink that duplicates each record to several wrapped sinks.
+//
+// Every branch runs concurrently with its own queue, so a slow destination only
+// stalls the others once its queue is full, or never when DropWhenFull is set.
+// Error events of a branch are wrapped with the branch name and metric events get a
+// "sink" label. Records that carry an ackable message are acked by each branch.
+type Tee[I any] struct {
+	branches     []TeeBranch[I]
+	queueSize    int
+	dropWhenFull bool
+}
+
+// NewTee creates a new tee sink writing to the given branches.
+func NewTee[I any](conf TeeConfig, branches ...TeeBranch[I]) (*Tee[I], error) {
+	if len(branches) == 0 {
+		return nil, fmt.Errorf("%s: %w: no branches", TeeSinkPrefix, ErrTeeSink)
+	}
+
+	names := make(map[string]bool, len(branches))
+	for _, b := range branches {
+		if b.Name == "" || b.Sink == nil {
+			return nil, fmt.Errorf("%s: %w: branches require a name and a sink", TeeSinkPrefix, ErrTeeSink)
+		}
+		if names[b.Name] {
+			return nil, fmt.Errorf("%s: %w: duplicate branch %q", TeeSinkPrefix, ErrTeeSink, b.Name)
+		}
+		names[b.Name] = true
+	}
+
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = 1000
+	}
+
+	return &Tee[I]{
+		branches:     branches,
+		queueSize:    conf.QueueSize,
+		dropWhenFull: conf.DropWhenFull,
+	}, nil
+}
+
+// Load duplicates records from the in channel to every branch.
+// It blocks until the in channel is closed and every branch has returned. Events a branch sends after
+// its Load returned are dropped.
+func (t *Tee[I]) Load(in <-chan I, eventC chan<- pl.Event) {
+	var wg sync.WaitGroup
+	queues := make([]chan I, len(t.branches))
+
+	for i, b := range t.branches {
+		queues[i] = make(chan I, t.queueSize)
+		relay := relayEvents(b.Name, eventC)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.Sink.Load(queues[i], relay.C)
+			relay.Stop()
+		}()
+	}
+
+	for record := range in {
+		for i, q := range queues {
+			if !t.dropWhenFull {
+				q <- record
+				continue
+			}
+
+			select {
+			case q <- record:
+			default:
+				pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+					fmt.Sprintf("%s %s: record dropped", TeeSinkPrefix, t.branches[i].Name),
+					errTeeQueueFull,
+					true,
+					record))
+			}
+		}
+	}
+
+	for _, q := range queues {
+		close(q)
+	}
+	wg.Wait()
+}
+
+// eventRelay passes the events a wrapped sink sends on its event channel to a function. The channel is
+// never closed, so a sink sending events after its Load returned does not panic: the events are dropped
+// once the relay is stopped, or block a sink sending them without SendEvent.
+type eventRelay struct {
+	C    chan pl.Event
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newEventRelay starts a relay queueing up to size events and passing them to forward.
+func newEventRelay(size int, forward func(pl.Event)) *eventRelay {
+	r := &eventRelay{
+		C:    make(chan pl.Event, size),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(r.done)
+		for {
+			select {
+			case e := <-r.C:
+				forward(e)
+			case <-r.stop:
+				for {
+					select {
+					case e := <-r.C:
+						forward(e)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+	return r
+}
+
+// Stop passes the events already queued and stops the relay, returning once the last of them was passed.
+func (r *eventRelay) Stop() {
+	close(r.stop)
+	<-r.done
+}
+
+// relayEvents returns a relay of the events of a wrapped sink forwarding them to eventC, attributed to
+// the named sink.
+func relayEvents(name string, eventC chan<- pl.Event) *eventRelay {
+	return newEventRelay(100, func(e pl.Event) {
+		pl.SendEvent(eventC, attributeEvent(name, e))
+	})
+}
+
+// attributeEvent wraps error events with the sink name, keeping their record, and adds a "sink"
+// label to metric events. Other events are returned unchanged.
+func attributeEvent(name string, e pl.Event) pl.Event {
+	switch e := e.(type) {
+	case pl.ErrorEvent:
+		return pl.NewRecordErrorEvent("sink "+name, e, e.IsTemporary(), e.Record())
+	case pl.MetricEvent:
+		labels := maps.Clone(e.Labels())
+		if labels == nil {
+			labels = make(map[string]string, 1)
+		}
+		labels["sink"] = name
+		return pl.NewMetricEvent(e.Name(), e.Value(), labels, pl.MetricType(e.MetricType()))
+	default:
+		return e
+	}
+}
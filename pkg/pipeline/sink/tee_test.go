@@ -0,0 +1,160 @@
+package sink_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+// collectSink records every value it loads
+type collectSink struct {
+	mu      sync.Mutex
+	records []string
+	block   chan struct{} // when set, Load waits for it to be closed before reading
+	fail    bool          // send an error event for each record
+}
+
+func (c *collectSink) Load(in <-chan string, eventC chan<- pipeline.Event) {
+	if c.block != nil {
+		<-c.block
+	}
+
+	for v := range in {
+		c.mu.Lock()
+		c.records = append(c.records, v)
+		fail := c.fail
+		c.mu.Unlock()
+
+		if fail {
+			pipeline.SendEvent(eventC, pipeline.NewErrorEvent("failed to write", errors.New("boom"), true))
+			pipeline.SendEvent(eventC, pipeline.NewMetricEvent("errors", 1, nil, pipeline.MetricTypeCounter))
+		}
+	}
+}
+
+// lateSink is a sink whose Load returns at once, loading its records and sending an error event for
+// each of them in the background, breaking the contract of Sink
+type lateSink[T any] struct {
+	done chan struct{}
+}
+
+func (l lateSink[T]) Load(in <-chan T, eventC chan<- pipeline.Event) {
+	go func() {
+		defer close(l.done)
+		for v := range in {
+			pipeline.SendEvent(eventC, pipeline.NewRecordErrorEvent("failed to write", errors.New("boom"), true, v))
+		}
+	}()
+}
+
+func TestTee_Load(t *testing.T) {
+	t.Run("duplicates records to every branch", func(t *testing.T) {
+		a, b := &collectSink{}, &collectSink{}
+		tee, err := sink.NewTee(sink.TeeConfig{},
+			sink.TeeBranch[string]{Name: "a", Sink: a},
+			sink.TeeBranch[string]{Name: "b", Sink: b},
+		)
+		assert.NoError(t, err)
+
+		in := make(chan string, 3)
+		in <- "1"
+		in <- "2"
+		in <- "3"
+		close(in)
+
+		tee.Load(in, nil)
+
+		assert.Equal(t, []string{"1", "2", "3"}, a.records)
+		assert.Equal(t, []string{"1", "2", "3"}, b.records)
+	})
+
+	t.Run("attributes branch events", func(t *testing.T) {
+		tee, err := sink.NewTee(sink.TeeConfig{},
+			sink.TeeBranch[string]{Name: "a", Sink: &collectSink{}},
+			sink.TeeBranch[string]{Name: "kafka", Sink: &collectSink{fail: true}},
+		)
+		assert.NoError(t, err)
+
+		in := make(chan string, 1)
+		in <- "1"
+		close(in)
+
+		eventC := make(chan pipeline.Event, 2)
+		tee.Load(in, eventC)
+
+		errEvent := (<-eventC).(pipeline.ErrorEvent)
+		assert.True(t, strings.HasPrefix(errEvent.String(), "sink kafka: failed to write"))
+		assert.True(t, errEvent.IsTemporary())
+
+		metric := (<-eventC).(pipeline.MetricEvent)
+		assert.Equal(t, "kafka", metric.Labels()["sink"])
+	})
+
+	t.Run("drops records for a full branch without stalling the others", func(t *testing.T) {
+		fast := &collectSink{}
+		slow := &collectSink{block: make(chan struct{})}
+		tee, err := sink.NewTee(sink.TeeConfig{QueueSize: 1, DropWhenFull: true},
+			sink.TeeBranch[string]{Name: "fast", Sink: fast},
+			sink.TeeBranch[string]{Name: "slow", Sink: slow},
+		)
+		assert.NoError(t, err)
+
+		in := make(chan string)
+		eventC := make(chan pipeline.Event, 10)
+		done := make(chan struct{})
+		go func() {
+			tee.Load(in, eventC)
+			close(done)
+		}()
+
+		for i, v := range []string{"1", "2", "3"} {
+			in <- v
+			// wait for the fast branch so only the slow branch queue fills up
+			assert.Eventually(t, func() bool {
+				fast.mu.Lock()
+				defer fast.mu.Unlock()
+				return len(fast.records) == i+1
+			}, time.Second, time.Millisecond)
+		}
+		close(in)
+		close(slow.block)
+		<-done
+
+		assert.Equal(t, []string{"1", "2", "3"}, fast.records)
+		assert.Equal(t, []string{"1"}, slow.records)
+		assert.Len(t, eventC, 2)
+	})
+
+	t.Run("does not close the events of a branch that returned early", func(t *testing.T) {
+		late := lateSink[string]{done: make(chan struct{})}
+		tee, err := sink.NewTee(sink.TeeConfig{}, sink.TeeBranch[string]{Name: "late", Sink: late})
+		assert.NoError(t, err)
+
+		in := make(chan string, 1)
+		in <- "1"
+		close(in)
+
+		tee.Load(in, make(chan pipeline.Event, 10))
+		<-late.done
+	})
+}
+
+func TestNewTee_Validation(t *testing.T) {
+	_, err := sink.NewTee[string](sink.TeeConfig{})
+	assert.ErrorIs(t, err, sink.ErrTeeSink)
+
+	_, err = sink.NewTee(sink.TeeConfig{}, sink.TeeBranch[string]{Name: "a"})
+	assert.ErrorIs(t, err, sink.ErrTeeSink)
+
+	_, err = sink.NewTee(sink.TeeConfig{},
+		sink.TeeBranch[string]{Name: "a", Sink: &collectSink{}},
+		sink.TeeBranch[string]{Name: "a", Sink: &collectSink{}},
+	)
+	assert.ErrorIs(t, err, sink.ErrTeeSink)
+}
@@ -0,0 +1,46 @@
+package sink
+
+import (
+	"encoding/json"
+	"strings"
+	"text/template"
+)
+
+// recordTemplate renders a string per record from a text/template.
+// The template is executed against the decoded JSON payload, so fields are referenced as {{.host}}.
+// Templates without actions are returned as is without decoding the payload.
+type recordTemplate struct {
+	text string
+	tmpl *template.Template
+}
+
+// newRecordTemplate parses a record template
+func newRecordTemplate(name, text string) (*recordTemplate, error) {
+	if !strings.Contains(text, "{{") {
+		return &recordTemplate{text: text}, nil
+	}
+
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &recordTemplate{text: text, tmpl: tmpl}, nil
+}
+
+// render executes the template against the payload
+func (t *recordTemplate) render(p []byte) (string, error) {
+	if t.tmpl == nil {
+		return t.text, nil
+	}
+
+	var data any
+	if err := json.Unmarshal(p, &data); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
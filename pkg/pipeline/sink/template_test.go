@@ -0,0 +1,43 @@
+package sink
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordTemplate_Render(t *testing.T) {
+	t.Run("static templates skip decoding", func(t *testing.T) {
+		tmpl, err := newRecordTemplate("topic", "events")
+		assert.NoError(t, err)
+
+		got, err := tmpl.render([]byte("not json"))
+		assert.NoError(t, err)
+		assert.Equal(t, "events", got)
+	})
+
+	t.Run("renders fields of the payload", func(t *testing.T) {
+		tmpl, err := newRecordTemplate("topic", "sites/{{.site}}/{{.device.id}}")
+		assert.NoError(t, err)
+
+		got, err := tmpl.render([]byte(`{"site":"plant1","device":{"id":"plc7"}}`))
+		assert.NoError(t, err)
+		assert.Equal(t, "sites/plant1/plc7", got)
+	})
+
+	t.Run("fails on missing fields and invalid payloads", func(t *testing.T) {
+		tmpl, err := newRecordTemplate("topic", "{{.site}}")
+		assert.NoError(t, err)
+
+		_, err = tmpl.render([]byte(`{}`))
+		assert.Error(t, err)
+
+		_, err = tmpl.render([]byte("not json"))
+		assert.Error(t, err)
+	})
+
+	t.Run("rejects invalid templates", func(t *testing.T) {
+		_, err := newRecordTemplate("topic", "{{.site")
+		assert.Error(t, err)
+	})
+}
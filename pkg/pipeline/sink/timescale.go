@@ -0,0 +1,267 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Timescale implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*Timescale)(nil)
+
+// ErrTimescaleSink is the error returned by the Timescale sink
+var ErrTimescaleSink = errors.New("sink error")
+
+// TimescaleSinkPrefix is the prefix for the Timescale sink error
+const TimescaleSinkPrefix = "timescale sink"
+
+// TimescaleConfig is the configuration for the Timescale sink.
+type TimescaleConfig struct {
+	ConnString string // libpq style connection string or URL
+
+	Table         string   // target hypertable, optionally schema qualified
+	TimeColumn    string   // time partitioning column of the hypertable, defaults to "time"
+	TimeField     string   // dot separated path of the record time in the payload, defaults to "timestamp"
+	PayloadColumn string   // JSONB column holding the full payload, defaults to "payload"
+	Columns       []Column // metadata columns extracted from the payload
+
+	ChunkInterval time.Duration // chunk time interval of the hypertable, defaults to 7 days
+	BatchSize     int           // rows per transaction, defaults to 5000
+	FlushInterval time.Duration // max time a partial batch is held, defaults to 1 second
+}
+
+// Timescale is a sink that writes time-series records into a TimescaleDB hypertable with COPY.
+//
+// The record time is read from TimeField as an RFC 3339 string or as Unix seconds. Each batch
+// is written in a single transaction, with one COPY per hypertable chunk in time order, so
+// every COPY writes into a single chunk. Upstream messages are acked after the transaction
+// commits.
+type Timescale struct {
+	pool          postgresPool
+	table         pgx.Identifier
+	timeField     string
+	columns       []Column
+	columnNames   []string
+	chunkInterval time.Duration
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// NewTimescale creates a new Timescale sink with the given configuration.
+func NewTimescale(conf TimescaleConfig) (*Timescale, error) {
+	if conf.ConnString == "" {
+		return nil, fmt.Errorf("%s: %w: connection string is empty", TimescaleSinkPrefix, ErrTimescaleSink)
+	}
+
+	poolConf, err := pgxpool.ParseConfig(conf.ConnString)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", TimescaleSinkPrefix, ErrTimescaleSink, err)
+	}
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConf)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w: %w", TimescaleSinkPrefix, ErrTimescaleSink, err)
+	}
+
+	t, err := newTimescale(pool, conf)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// newTimescale creates a Timescale sink using an existing pool
+func newTimescale(pool postgresPool, conf TimescaleConfig) (*Timescale, error) {
+	if conf.Table == "" {
+		return nil, fmt.Errorf("%s: %w: table is empty", TimescaleSinkPrefix, ErrTimescaleSink)
+	}
+
+	if conf.TimeColumn == "" {
+		conf.TimeColumn = "time"
+	}
+
+	if conf.TimeField == "" {
+		conf.TimeField = "timestamp"
+	}
+
+	if conf.PayloadColumn == "" {
+		conf.PayloadColumn = "payload"
+	}
+
+	if conf.ChunkInterval <= 0 {
+		conf.ChunkInterval = 7 * 24 * time.Hour
+	}
+
+	if conf.BatchSize <= 0 {
+		conf.BatchSize = 5000
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+
+	names := []string{conf.TimeColumn, conf.PayloadColumn}
+	for _, col := range conf.Columns {
+		names = append(names, col.Name)
+	}
+
+	return &Timescale{
+		pool:          pool,
+		table:         pgx.Identifier(strings.Split(conf.Table, ".")),
+		timeField:     conf.TimeField,
+		columns:       conf.Columns,
+		columnNames:   names,
+		chunkInterval: conf.ChunkInterval,
+		batchSize:     conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+	}, nil
+}
+
+// Load batches records from the in channel and copies each batch in a single transaction.
+// It blocks until the in channel is closed and the final batch is committed.
+func (t *Timescale) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	collectBatches(in, t.batchSize, t.flushInterval, func(batch []pl.DataRawReadable) {
+		t.flush(batch, eventC)
+	})
+}
+
+// timescaleRow is a row of a batch with the time of its record
+type timescaleRow struct {
+	time   time.Time
+	values []any
+}
+
+// flush copies a batch of records chunk by chunk in a transaction and acks them after commit
+func (t *Timescale) flush(batch []pl.DataRawReadable, eventC chan<- pl.Event) {
+	rows := make([]timescaleRow, 0, len(batch))
+	written := make([]pl.DataRawReadable, 0, len(batch))
+
+	for _, drr := range batch {
+		row, err := t.row(drr)
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to map record to timescale columns",
+				err,
+				false,
+				drr))
+			continue
+		}
+		rows = append(rows, row)
+		written = append(written, drr)
+	}
+
+	if len(rows) == 0 {
+		return
+	}
+
+	if err := t.write(context.Background(), rows); err != nil {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to write batch to timescale",
+			err,
+			isPostgresTemporary(err),
+			written))
+		return
+	}
+
+	// ack the messages once the transaction is committed
+	for _, drr := range written {
+		ackMessage(drr.Raw(), eventC)
+	}
+}
+
+// write copies rows in a single transaction, one COPY per chunk in time order
+func (t *Timescale) write(ctx context.Context, rows []timescaleRow) error {
+	slices.SortStableFunc(rows, func(a, b timescaleRow) int { return a.time.Compare(b.time) })
+
+	tx, err := t.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	// rollback is a no-op once the transaction is committed
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	for start := 0; start < len(rows); {
+		chunk := t.chunk(rows[start].time)
+		end := start + 1
+		for end < len(rows) && t.chunk(rows[end].time) == chunk {
+			end++
+		}
+
+		values := make([][]any, 0, end-start)
+		for _, row := range rows[start:end] {
+			values = append(values, row.values)
+		}
+		if _, err := tx.CopyFrom(ctx, t.table, t.columnNames, pgx.CopyFromRows(values)); err != nil {
+			return err
+		}
+		start = end
+	}
+
+	return tx.Commit(ctx)
+}
+
+// chunk returns the index of the chunk holding a time, counted from the Unix epoch
+func (t *Timescale) chunk(ts time.Time) int64 {
+	n := ts.UnixMicro()
+	interval := t.chunkInterval.Microseconds()
+	if n < 0 {
+		// round down for times before the epoch
+		n -= interval - 1
+	}
+	return n / interval
+}
+
+// row builds the time, payload and metadata column values for a record
+func (t *Timescale) row(drr pl.DataRawReadable) (timescaleRow, error) {
+	payload, err := drr.Data().Read()
+	if err != nil {
+		return timescaleRow{}, err
+	}
+
+	var record map[string]any
+	if err := json.Unmarshal(payload, &record); err != nil {
+		return timescaleRow{}, err
+	}
+
+	ts, err := recordTime(lookupField(record, t.timeField))
+	if err != nil {
+		return timescaleRow{}, fmt.Errorf("field %s: %w", t.timeField, err)
+	}
+
+	values := make([]any, 0, len(t.columns)+2)
+	values = append(values, ts, string(payload))
+	for _, col := range t.columns {
+		values = append(values, lookupField(record, col.Field))
+	}
+	return timescaleRow{time: ts, values: values}, nil
+}
+
+// recordTime parses a JSON time value given as an RFC 3339 string or as Unix seconds
+func recordTime(v any) (time.Time, error) {
+	switch ts := v.(type) {
+	case string:
+		return time.Parse(time.RFC3339Nano, ts)
+	case float64:
+		sec, frac := math.Modf(ts)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+	case nil:
+		return time.Time{}, errors.New("time is missing")
+	default:
+		return time.Time{}, fmt.Errorf("unsupported time value %v", v)
+	}
+}
+
+// Close closes the Timescale connection pool.
+func (t *Timescale) Close() {
+	t.pool.Close()
+}
@@ -0,0 +1,109 @@
+package sink
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+func TestTimescale_Load(t *testing.T) {
+	conf := TimescaleConfig{
+		Table:         "metrics.cpu",
+		Columns:       []Column{{Name: "host", Field: "host"}},
+		ChunkInterval: time.Hour,
+	}
+
+	t.Run("copies rows chunk by chunk in time order", func(t *testing.T) {
+		pool := &fakePostgresPool{}
+		ts, err := newTimescale(pool, conf)
+		assert.NoError(t, err)
+
+		records := []ackableRecord{
+			newAckableRecord(`{"timestamp":"2026-01-01T01:30:00Z","host":"b"}`),
+			newAckableRecord(`{"timestamp":"2026-01-01T00:10:00Z","host":"a"}`),
+			newAckableRecord(`{"timestamp":1767229200,"host":"c"}`), // 2026-01-01T01:00:00Z
+		}
+		in := make(chan pipeline.DataRawReadable, len(records))
+		for _, r := range records {
+			in <- r
+		}
+		close(in)
+
+		ts.Load(in, nil)
+
+		assert.Equal(t, pgx.Identifier{"metrics", "cpu"}, pool.table)
+		assert.Equal(t, []string{"time", "payload", "host"}, pool.columns)
+		assert.Equal(t, 2, pool.copies)
+		assert.True(t, pool.committed)
+
+		var hosts []any
+		for _, row := range pool.rows {
+			hosts = append(hosts, row[2])
+		}
+		assert.Equal(t, []any{"a", "c", "b"}, hosts)
+		assert.Equal(t, time.Date(2026, 1, 1, 0, 10, 0, 0, time.UTC), pool.rows[0][0])
+
+		for _, r := range records {
+			assert.True(t, r.raw.acked)
+		}
+	})
+
+	t.Run("reports records without a time", func(t *testing.T) {
+		pool := &fakePostgresPool{}
+		ts, err := newTimescale(pool, conf)
+		assert.NoError(t, err)
+
+		record := newAckableRecord(`{"host":"a"}`)
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		ts.Load(in, eventC)
+
+		errEvent, ok := (<-eventC).(pipeline.ErrorEvent)
+		assert.True(t, ok)
+		assert.False(t, errEvent.IsTemporary())
+		assert.Zero(t, pool.copies)
+		assert.False(t, record.raw.acked)
+	})
+
+	t.Run("does not ack when the copy fails", func(t *testing.T) {
+		pool := &fakePostgresPool{err: errors.New("connection reset")}
+		ts, err := newTimescale(pool, conf)
+		assert.NoError(t, err)
+
+		record := newAckableRecord(`{"timestamp":"2026-01-01T00:00:00Z"}`)
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		ts.Load(in, eventC)
+
+		errEvent, ok := (<-eventC).(pipeline.ErrorEvent)
+		assert.True(t, ok)
+		assert.True(t, errEvent.IsTemporary())
+		assert.False(t, pool.committed)
+		assert.False(t, record.raw.acked)
+	})
+}
+
+func TestTimescale_chunk(t *testing.T) {
+	ts := &Timescale{chunkInterval: time.Hour}
+	assert.Equal(t, int64(0), ts.chunk(time.Unix(3599, 0)))
+	assert.Equal(t, int64(1), ts.chunk(time.Unix(3600, 0)))
+	assert.Equal(t, int64(-1), ts.chunk(time.Unix(-1, 0)))
+}
+
+func TestNewTimescale_Validation(t *testing.T) {
+	_, err := NewTimescale(TimescaleConfig{Table: "cpu"})
+	assert.ErrorIs(t, err, ErrTimescaleSink)
+
+	_, err = newTimescale(&fakePostgresPool{}, TimescaleConfig{})
+	assert.ErrorIs(t, err, ErrTimescaleSink)
+}
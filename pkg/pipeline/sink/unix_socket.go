@@ -0,0 +1,148 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that UnixSocket implements the sink interface
+var _ pl.Sink[any] = (*UnixSocket[any])(nil)
+
+// ErrUnixSocketSink is the error returned by the Unix socket sink
+var ErrUnixSocketSink = errors.New("sink error")
+
+// UnixSocketSinkPrefix is the prefix for the Unix socket sink error
+const UnixSocketSinkPrefix = "unix socket sink"
+
+// UnixSocketConfig is the configuration for the Unix socket sink.
+type UnixSocketConfig struct {
+	Path     string // path of the socket
+	Datagram bool   // write to a datagram socket instead of a stream socket
+
+	WriteTimeout time.Duration // timeout of a single write, defaults to 10 seconds
+	MaxRetries   int           // attempts to reconnect and write a record, defaults to 3
+	RetryBackoff time.Duration // initial backoff between attempts, defaults to 500ms
+}
+
+// UnixSocket is a sink that writes records to a Unix domain socket, for handing data to
+// co-located daemons.
+//
+// On a stream socket records are written newline delimited; on a datagram socket each record
+// is sent as its own datagram. The payload of a record is determined like in the Writer sink.
+// The sink reconnects when a write fails, and upstream messages of RawReadable records are
+// acked after the record is written to the socket.
+type UnixSocket[I any] struct {
+	path         string
+	network      string
+	writeTimeout time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+
+	conn net.Conn
+}
+
+// NewUnixSocket creates a new Unix socket sink with the given configuration.
+// The socket is connected when the first record is written.
+func NewUnixSocket[I any](conf UnixSocketConfig) (*UnixSocket[I], error) {
+	if conf.Path == "" {
+		return nil, fmt.Errorf("%s: %w: path is empty", UnixSocketSinkPrefix, ErrUnixSocketSink)
+	}
+
+	network := "unix"
+	if conf.Datagram {
+		network = "unixgram"
+	}
+
+	if conf.WriteTimeout <= 0 {
+		conf.WriteTimeout = 10 * time.Second
+	}
+
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 3
+	}
+
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = 500 * time.Millisecond
+	}
+
+	return &UnixSocket[I]{
+		path:         conf.Path,
+		network:      network,
+		writeTimeout: conf.WriteTimeout,
+		maxRetries:   conf.MaxRetries,
+		retryBackoff: conf.RetryBackoff,
+	}, nil
+}
+
+// Load writes records from the in channel to the socket.
+// It blocks until the in channel is closed and closes the connection.
+func (u *UnixSocket[I]) Load(in <-chan I, eventC chan<- pl.Event) {
+	defer u.close()
+
+	for record := range in {
+		p, err := writerPayload(record)
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to read data",
+				err,
+				true,
+				record))
+			continue
+		}
+
+		if u.network == "unix" && (len(p) == 0 || p[len(p)-1] != '\n') {
+			p = append(p[:len(p):len(p)], '\n')
+		}
+
+		err = withRetry(u.maxRetries, u.retryBackoff, func(error) bool { return true }, func() error {
+			return u.write(p)
+		})
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to write to socket",
+				err,
+				true,
+				record))
+			continue
+		}
+
+		if raw, ok := any(record).(pl.RawReadable); ok {
+			ackMessage(raw.Raw(), eventC)
+		}
+	}
+}
+
+// write writes p to the socket, connecting first when there is no connection.
+// The connection is dropped after a failed write so the next attempt reconnects.
+func (u *UnixSocket[I]) write(p []byte) error {
+	if u.conn == nil {
+		conn, err := net.DialTimeout(u.network, u.path, u.writeTimeout)
+		if err != nil {
+			return err
+		}
+		u.conn = conn
+	}
+
+	if err := u.conn.SetWriteDeadline(time.Now().Add(u.writeTimeout)); err != nil {
+		u.close()
+		return err
+	}
+
+	if _, err := u.conn.Write(p); err != nil {
+		u.close()
+		return err
+	}
+	return nil
+}
+
+// close closes the connection, if any
+func (u *UnixSocket[I]) close() {
+	if u.conn != nil {
+		_ = u.conn.Close()
+		u.conn = nil
+	}
+}
@@ -0,0 +1,102 @@
+package sink
+
+import (
+	"bufio"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+func TestUnixSocket_Load(t *testing.T) {
+	t.Run("writes newline delimited records to a stream socket", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "daemon.sock")
+		l, err := net.Listen("unix", path)
+		assert.NoError(t, err)
+		defer l.Close()
+
+		lines := make(chan string, 2)
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			scanner := bufio.NewScanner(conn)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+		}()
+
+		s, err := NewUnixSocket[pipeline.DataRawReadable](UnixSocketConfig{Path: path})
+		assert.NoError(t, err)
+
+		first, second := newAckableRecord(`{"n":1}`), newAckableRecord("with newline\n")
+		in := make(chan pipeline.DataRawReadable, 2)
+		in <- first
+		in <- second
+		close(in)
+
+		s.Load(in, nil)
+
+		assert.Equal(t, `{"n":1}`, <-lines)
+		assert.Equal(t, "with newline", <-lines)
+		assert.True(t, first.raw.acked)
+		assert.True(t, second.raw.acked)
+	})
+
+	t.Run("sends records as datagrams", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "daemon.sock")
+		conn, err := net.ListenPacket("unixgram", path)
+		assert.NoError(t, err)
+		defer conn.Close()
+
+		s, err := NewUnixSocket[[]byte](UnixSocketConfig{Path: path, Datagram: true})
+		assert.NoError(t, err)
+
+		in := make(chan []byte, 2)
+		in <- []byte("first")
+		in <- []byte("second")
+		close(in)
+
+		s.Load(in, nil)
+
+		buf := make([]byte, 64)
+		for _, want := range []string{"first", "second"} {
+			assert.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+			n, _, err := conn.ReadFrom(buf)
+			assert.NoError(t, err)
+			assert.Equal(t, want, string(buf[:n]))
+		}
+	})
+
+	t.Run("reports records when the socket is unavailable", func(t *testing.T) {
+		s, err := NewUnixSocket[pipeline.DataRawReadable](UnixSocketConfig{
+			Path:         filepath.Join(t.TempDir(), "missing.sock"),
+			MaxRetries:   2,
+			RetryBackoff: time.Millisecond,
+		})
+		assert.NoError(t, err)
+
+		record := newAckableRecord("data")
+		in := make(chan pipeline.DataRawReadable, 1)
+		in <- record
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		s.Load(in, eventC)
+
+		errEvent, ok := (<-eventC).(pipeline.ErrorEvent)
+		assert.True(t, ok)
+		assert.Equal(t, record, errEvent.Record())
+		assert.False(t, record.raw.acked)
+	})
+}
+
+func TestNewUnixSocket_Validation(t *testing.T) {
+	_, err := NewUnixSocket[[]byte](UnixSocketConfig{})
+	assert.ErrorIs(t, err, ErrUnixSocketSink)
+}
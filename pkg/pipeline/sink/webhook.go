@@ -0,0 +1,256 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Webhook implements the sink interface
+var _ pl.Sink[pl.DataRawReadable] = (*Webhook)(nil)
+
+// ErrWebhookSink is the error returned by the webhook sink
+var ErrWebhookSink = errors.New("sink error")
+
+// WebhookSinkPrefix is the prefix for the webhook sink error
+const WebhookSinkPrefix = "webhook sink"
+
+// WebhookFormat is the request body format used by the webhook sink
+type WebhookFormat uint8
+
+const (
+	// WebhookSingle posts each record as its own request
+	WebhookSingle WebhookFormat = iota
+	// WebhookJSONArray posts batches of JSON records as a JSON array
+	WebhookJSONArray
+	// WebhookNDJSON posts batches of records as newline delimited JSON
+	WebhookNDJSON
+)
+
+// WebhookConfig is the configuration for the webhook sink.
+type WebhookConfig struct {
+	URL     string            // endpoint records are posted to
+	Headers map[string]string // custom request headers
+	Format  WebhookFormat     // request body format, defaults to WebhookSingle
+
+	BatchSize     int           // records per request for batched formats, defaults to 100
+	FlushInterval time.Duration // max time a partial batch is held, defaults to 1 second
+
+	Timeout      time.Duration // request timeout, defaults to 10 seconds
+	MaxRetries   int           // attempts for 5xx, 429 and network errors, defaults to 3
+	RetryBackoff time.Duration // initial backoff between attempts, defaults to 500ms
+
+	BreakerThreshold int           // consecutive failed requests before the circuit opens, 0 disables
+	BreakerCooldown  time.Duration // time the circuit stays open before a trial request, defaults to 30 seconds
+
+	TLS *tls.Config // optional TLS configuration
+}
+
+// webhookStatusError is returned when the endpoint responds with a non 2xx status
+type webhookStatusError struct {
+	status int
+}
+
+// Error implements the error interface
+func (e webhookStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.status)
+}
+
+// Webhook is a sink that posts records to an HTTP endpoint.
+// Upstream messages are acked after the endpoint accepts the request containing them.
+type Webhook struct {
+	client        *http.Client
+	url           string
+	headers       map[string]string
+	format        WebhookFormat
+	batchSize     int
+	flushInterval time.Duration
+	maxRetries    int
+	retryBackoff  time.Duration
+	breaker       *circuitBreaker
+}
+
+// NewWebhook creates a new webhook sink with the given configuration.
+func NewWebhook(conf WebhookConfig) (*Webhook, error) {
+	if conf.URL == "" {
+		return nil, fmt.Errorf("%s: %w: url is empty", WebhookSinkPrefix, ErrWebhookSink)
+	}
+
+	if conf.Format > WebhookNDJSON {
+		return nil, fmt.Errorf("%s: %w: unknown format %d", WebhookSinkPrefix, ErrWebhookSink, conf.Format)
+	}
+
+	// single requests carry exactly one record
+	if conf.Format == WebhookSingle {
+		conf.BatchSize = 1
+	} else if conf.BatchSize <= 0 {
+		conf.BatchSize = 100
+	}
+
+	if conf.FlushInterval <= 0 {
+		conf.FlushInterval = time.Second
+	}
+
+	if conf.Timeout <= 0 {
+		conf.Timeout = 10 * time.Second
+	}
+
+	if conf.MaxRetries <= 0 {
+		conf.MaxRetries = 3
+	}
+
+	if conf.RetryBackoff <= 0 {
+		conf.RetryBackoff = 500 * time.Millisecond
+	}
+
+	if conf.BreakerCooldown <= 0 {
+		conf.BreakerCooldown = 30 * time.Second
+	}
+
+	client := &http.Client{Timeout: conf.Timeout}
+	if conf.TLS != nil {
+		client.Transport = &http.Transport{TLSClientConfig: conf.TLS}
+	}
+
+	return &Webhook{
+		client:        client,
+		url:           conf.URL,
+		headers:       conf.Headers,
+		format:        conf.Format,
+		batchSize:     conf.BatchSize,
+		flushInterval: conf.FlushInterval,
+		maxRetries:    conf.MaxRetries,
+		retryBackoff:  conf.RetryBackoff,
+		breaker:       newCircuitBreaker(conf.BreakerThreshold, conf.BreakerCooldown),
+	}, nil
+}
+
+// Load posts records from the in channel to the endpoint.
+// It blocks until the in channel is closed and the final request completes.
+func (w *Webhook) Load(in <-chan pl.DataRawReadable, eventC chan<- pl.Event) {
+	collectBatches(in, w.batchSize, w.flushInterval, func(batch []pl.DataRawReadable) {
+		w.flush(batch, eventC)
+	})
+}
+
+// flush encodes a batch into a request body and posts it with retries
+func (w *Webhook) flush(batch []pl.DataRawReadable, eventC chan<- pl.Event) {
+	var body bytes.Buffer
+	posted := make([]pl.DataRawReadable, 0, len(batch))
+
+	if w.format == WebhookJSONArray {
+		body.WriteByte('[')
+	}
+
+	for _, drr := range batch {
+		p, err := drr.Data().Read()
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to read data",
+				err,
+				true,
+				drr))
+			continue
+		}
+
+		switch w.format {
+		case WebhookJSONArray:
+			if len(posted) > 0 {
+				body.WriteByte(',')
+			}
+			body.Write(bytes.TrimSpace(p))
+		case WebhookNDJSON:
+			body.Write(bytes.TrimRight(p, "\n"))
+			body.WriteByte('\n')
+		default:
+			body.Write(p)
+		}
+		posted = append(posted, drr)
+	}
+
+	if len(posted) == 0 {
+		return
+	}
+
+	if w.format == WebhookJSONArray {
+		body.WriteByte(']')
+	}
+
+	if !w.breaker.allow() {
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"webhook request rejected",
+			errCircuitOpen,
+			true,
+			posted))
+		return
+	}
+
+	err := withRetry(w.maxRetries, w.retryBackoff, isWebhookTemporary, func() error {
+		return w.post(body.Bytes())
+	})
+	if err != nil {
+		w.breaker.failure()
+		pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+			"failed to post records to webhook",
+			err,
+			isWebhookTemporary(err),
+			posted))
+		return
+	}
+	w.breaker.success()
+
+	// ack the messages once the endpoint accepted them
+	for _, drr := range posted {
+		ackMessage(drr.Raw(), eventC)
+	}
+}
+
+// post sends a single request to the endpoint
+func (w *Webhook) post(body []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	switch w.format {
+	case WebhookNDJSON:
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	default:
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return webhookStatusError{status: resp.StatusCode}
+	}
+	return nil
+}
+
+// isWebhookTemporary reports whether a webhook error is worth retrying.
+// Server errors, rate limiting and transport errors are temporary; other statuses are permanent.
+func isWebhookTemporary(err error) bool {
+	var statusErr webhookStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status == http.StatusTooManyRequests || statusErr.status >= http.StatusInternalServerError
+	}
+	return true
+}
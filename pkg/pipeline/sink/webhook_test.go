@@ -0,0 +1,147 @@
+package sink_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+	"github.com/witfoo/krapht/pkg/pipeline/sink"
+)
+
+// newWebhookInput returns a closed channel holding records with the given payloads
+func newWebhookInput(payloads ...string) <-chan pipeline.DataRawReadable {
+	in := make(chan pipeline.DataRawReadable, len(payloads))
+	for _, p := range payloads {
+		in <- mock.NewDataRawReadableImpl(
+			mock.NewReadableImpl([]byte(p)),
+			mock.NewReadableImpl([]byte(p)),
+		)
+	}
+	close(in)
+	return in
+}
+
+func TestWebhook_Load(t *testing.T) {
+	t.Run("posts batches as a JSON array with custom headers", func(t *testing.T) {
+		var mu sync.Mutex
+		var bodies []string
+		var auth string
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			bodies = append(bodies, string(b))
+			auth = r.Header.Get("Authorization")
+			mu.Unlock()
+		}))
+		defer server.Close()
+
+		webhook, err := sink.NewWebhook(sink.WebhookConfig{
+			URL:       server.URL,
+			Headers:   map[string]string{"Authorization": "Bearer token"},
+			Format:    sink.WebhookJSONArray,
+			BatchSize: 2,
+		})
+		assert.NoError(t, err)
+
+		webhook.Load(newWebhookInput(`{"n":1}`, `{"n":2}`, `{"n":3}`), nil)
+
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, []string{`[{"n":1},{"n":2}]`, `[{"n":3}]`}, bodies)
+		assert.Equal(t, "Bearer token", auth)
+	})
+
+	t.Run("posts batches as NDJSON", func(t *testing.T) {
+		var body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			body = string(b)
+			assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		}))
+		defer server.Close()
+
+		webhook, err := sink.NewWebhook(sink.WebhookConfig{URL: server.URL, Format: sink.WebhookNDJSON})
+		assert.NoError(t, err)
+
+		webhook.Load(newWebhookInput(`{"n":1}`, "{\"n\":2}\n"), nil)
+		assert.Equal(t, "{\"n\":1}\n{\"n\":2}\n", body)
+	})
+
+	t.Run("retries server errors", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		}))
+		defer server.Close()
+
+		webhook, err := sink.NewWebhook(sink.WebhookConfig{URL: server.URL, RetryBackoff: time.Millisecond})
+		assert.NoError(t, err)
+
+		eventC := make(chan pipeline.Event, 1)
+		webhook.Load(newWebhookInput(`{"n":1}`), eventC)
+
+		assert.Equal(t, int32(2), calls.Load())
+		assert.Empty(t, eventC)
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		webhook, err := sink.NewWebhook(sink.WebhookConfig{URL: server.URL, RetryBackoff: time.Millisecond})
+		assert.NoError(t, err)
+
+		eventC := make(chan pipeline.Event, 1)
+		webhook.Load(newWebhookInput(`{"n":1}`), eventC)
+
+		assert.Equal(t, int32(1), calls.Load())
+		event := (<-eventC).(pipeline.ErrorEvent)
+		assert.False(t, event.IsTemporary())
+	})
+
+	t.Run("opens the circuit after consecutive failures", func(t *testing.T) {
+		var calls atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		webhook, err := sink.NewWebhook(sink.WebhookConfig{
+			URL:              server.URL,
+			MaxRetries:       1,
+			BreakerThreshold: 2,
+			BreakerCooldown:  time.Hour,
+		})
+		assert.NoError(t, err)
+
+		eventC := make(chan pipeline.Event, 4)
+		webhook.Load(newWebhookInput(`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`), eventC)
+
+		// the last two records are rejected without calling the endpoint
+		assert.Equal(t, int32(2), calls.Load())
+		assert.Len(t, eventC, 4)
+	})
+}
+
+func TestNewWebhook_Validation(t *testing.T) {
+	_, err := sink.NewWebhook(sink.WebhookConfig{})
+	assert.ErrorIs(t, err, sink.ErrWebhookSink)
+
+	_, err = sink.NewWebhook(sink.WebhookConfig{URL: "http://localhost", Format: 10})
+	assert.ErrorIs(t, err, sink.ErrWebhookSink)
+}
@@ -0,0 +1,90 @@
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	pl "github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Writer implements the sink interface
+var _ pl.Sink[any] = (*Writer[any])(nil)
+
+// Writer is a sink that encodes records onto an io.Writer such as stdout.
+//
+// The payload of a record is its structured data for DataReadable records, the
+// bytes read for Readable records, the value itself for []byte and the JSON
+// encoding of any other value. Upstream messages of RawReadable records are acked
+// after they are written.
+type Writer[I any] struct {
+	w   io.Writer
+	enc Encoder
+}
+
+// NewWriter creates a new Writer sink.
+// A nil writer defaults to stdout and a nil encoder to NDJSON.
+func NewWriter[I any](w io.Writer, enc Encoder) *Writer[I] {
+	if w == nil {
+		w = os.Stdout
+	}
+
+	if enc == nil {
+		enc = NewNDJSONEncoder()
+	}
+
+	return &Writer[I]{
+		w:   w,
+		enc: enc,
+	}
+}
+
+// Load encodes records from the in channel onto the writer.
+// It blocks until the in channel is closed and the encoder trailer is written.
+func (wr *Writer[I]) Load(in <-chan I, eventC chan<- pl.Event) {
+	for record := range in {
+		p, err := writerPayload(record)
+		if err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to read data",
+				err,
+				true,
+				record))
+			continue
+		}
+
+		if err := wr.enc.Encode(wr.w, p); err != nil {
+			pl.SendEvent(eventC, pl.NewRecordErrorEvent(
+				"failed to encode record",
+				err,
+				false,
+				record))
+			continue
+		}
+
+		if raw, ok := any(record).(pl.RawReadable); ok {
+			ackMessage(raw.Raw(), eventC)
+		}
+	}
+
+	if err := wr.enc.Close(wr.w); err != nil {
+		pl.SendEvent(eventC, pl.NewErrorEvent(
+			"failed to close encoder",
+			err,
+			false))
+	}
+}
+
+// writerPayload returns the bytes to encode for a record
+func writerPayload(record any) ([]byte, error) {
+	switch r := record.(type) {
+	case pl.DataReadable:
+		return r.Data().Read()
+	case pl.Readable:
+		return r.Read()
+	case []byte:
+		return r, nil
+	default:
+		return json.Marshal(r)
+	}
+}
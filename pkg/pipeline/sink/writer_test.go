@@ -0,0 +1,62 @@
+package sink
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+)
+
+func TestWriter_Load(t *testing.T) {
+	t.Run("writes structured data and acks records", func(t *testing.T) {
+		var b bytes.Buffer
+		w := NewWriter[pipeline.DataRawReadable](&b, NewJSONEncoder())
+
+		records := []ackableRecord{newAckableRecord(`{"n":1}`), newAckableRecord(`{"n":2}`)}
+		in := make(chan pipeline.DataRawReadable, len(records))
+		for _, r := range records {
+			in <- r
+		}
+		close(in)
+
+		w.Load(in, nil)
+
+		assert.Equal(t, "[{\"n\":1},{\"n\":2}]\n", b.String())
+		for _, r := range records {
+			assert.True(t, r.raw.acked)
+		}
+	})
+
+	t.Run("writes readables, bytes and other values", func(t *testing.T) {
+		var b bytes.Buffer
+		w := NewWriter[any](&b, nil)
+
+		in := make(chan any, 3)
+		in <- mock.NewReadableImpl([]byte(`{"n":1}`))
+		in <- []byte(`{"n":2}`)
+		in <- map[string]int{"n": 3}
+		close(in)
+
+		w.Load(in, nil)
+
+		assert.Equal(t, "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n", b.String())
+	})
+
+	t.Run("reports records the encoder rejects", func(t *testing.T) {
+		var b bytes.Buffer
+		w := NewWriter[[]byte](&b, NewNDJSONEncoder())
+
+		in := make(chan []byte, 2)
+		in <- []byte("not json")
+		in <- []byte(`{"n":1}`)
+		close(in)
+
+		eventC := make(chan pipeline.Event, 1)
+		w.Load(in, eventC)
+
+		assert.Equal(t, "{\"n\":1}\n", b.String())
+		assert.False(t, (<-eventC).(pipeline.ErrorEvent).IsTemporary())
+	})
+}
@@ -0,0 +1,124 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Static check that Generator implements the source interface
+var _ pipeline.Source[pipeline.Readable] = (*Generator)(nil)
+
+// ErrGeneratorSource is the error returned by the generator source
+var ErrGeneratorSource = errors.New("source error")
+
+// GeneratorSourcePrefix is the prefix for the generator source error
+const GeneratorSourcePrefix = "generator source"
+
+// generatorWords are the words of the messages of generated records
+var generatorWords = []string{
+	"connection", "accepted", "denied", "user", "session", "opened", "closed", "request", "timeout",
+	"disk", "usage", "high", "service", "restarted", "packet", "dropped", "login", "failed", "from",
+}
+
+// generatorLevels are the levels of generated records
+var generatorLevels = []string{"debug", "info", "info", "info", "warn", "error"}
+
+// GeneratorConfig is the configuration for the generator source.
+type GeneratorConfig struct {
+	Count int     // records generated before the channel is closed, 0 generates until the context is done
+	Rate  float64 // max records generated per second, 0 generates as fast as possible
+	Size  int     // minimum payload size in bytes, the message is padded to reach it
+	Hosts int     // distinct host names, defaults to 16
+	Seed  uint64  // seed of the content, so runs with the same seed generate the same records
+}
+
+// Generator is a source of synthetic JSON log records, for benchmarks and load tests. Each record has a
+// sequence number, timestamp, host, level and message, such as
+//
+//	{"seq":1,"ts":"2025-01-02T03:04:05.000000006Z","host":"host-03","level":"info","message":"user login failed"}
+type Generator struct {
+	conf     GeneratorConfig
+	interval time.Duration
+}
+
+// NewGenerator creates a new generator source with the given configuration.
+func NewGenerator(conf GeneratorConfig) (*Generator, error) {
+	if conf.Count < 0 {
+		return nil, fmt.Errorf("%s: %w: count must not be negative", GeneratorSourcePrefix, ErrGeneratorSource)
+	}
+
+	if conf.Rate < 0 {
+		return nil, fmt.Errorf("%s: %w: rate must not be negative", GeneratorSourcePrefix, ErrGeneratorSource)
+	}
+
+	if conf.Hosts <= 0 {
+		conf.Hosts = 16
+	}
+
+	var interval time.Duration
+	if conf.Rate > 0 {
+		interval = time.Duration(float64(time.Second) / conf.Rate)
+	}
+
+	return &Generator{
+		conf:     conf,
+		interval: interval,
+	}, nil
+}
+
+// Extract generates records until the count is reached or the context is done.
+func (g *Generator) Extract(ctx context.Context, _ chan<- pipeline.Event) <-chan pipeline.Readable {
+	out := make(chan pipeline.Readable)
+	go func() {
+		defer close(out)
+
+		rng := rand.New(rand.NewPCG(g.conf.Seed, g.conf.Seed))
+		// schedule records against a fixed clock so sleep overshoot does not lower the rate
+		next := time.Now()
+		for seq := 1; g.conf.Count == 0 || seq <= g.conf.Count; seq++ {
+			if g.interval > 0 {
+				next = next.Add(g.interval)
+				time.Sleep(time.Until(next))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case out <- pipeline.Bytes(g.record(rng, seq)):
+			}
+		}
+	}()
+	return out
+}
+
+// record generates the record of the sequence number
+func (g *Generator) record(rng *rand.Rand, seq int) []byte {
+	b := make([]byte, 0, max(g.conf.Size, 128))
+	b = append(b, `{"seq":`...)
+	b = strconv.AppendInt(b, int64(seq), 10)
+	b = append(b, `,"ts":"`...)
+	b = time.Now().UTC().AppendFormat(b, time.RFC3339Nano)
+	b = append(b, `","host":"host-`...)
+	host := rng.IntN(g.conf.Hosts)
+	if host < 10 {
+		b = append(b, '0')
+	}
+	b = strconv.AppendInt(b, int64(host), 10)
+	b = append(b, `","level":"`...)
+	b = append(b, generatorLevels[rng.IntN(len(generatorLevels))]...)
+	b = append(b, `","message":"`...)
+
+	for i, n := 0, 3+rng.IntN(5); i < n || len(b)+2 < g.conf.Size; i++ {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = append(b, generatorWords[rng.IntN(len(generatorWords))]...)
+	}
+	return append(b, `"}`...)
+}
@@ -0,0 +1,60 @@
+package source_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline/source"
+)
+
+func TestGenerator(t *testing.T) {
+	g, err := source.NewGenerator(source.GeneratorConfig{Count: 5, Size: 256, Seed: 1})
+	assert.NoError(t, err)
+
+	var seq int
+	for r := range g.Extract(context.Background(), nil) {
+		b, err := r.Read()
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, len(b), 256)
+
+		var record struct {
+			Seq     int    `json:"seq"`
+			Host    string `json:"host"`
+			Level   string `json:"level"`
+			Message string `json:"message"`
+		}
+		assert.NoError(t, json.Unmarshal(b, &record))
+		seq++
+		assert.Equal(t, seq, record.Seq)
+		assert.Regexp(t, `^host-\d\d$`, record.Host)
+		assert.NotEmpty(t, record.Level)
+		assert.NotEmpty(t, record.Message)
+	}
+	assert.Equal(t, 5, seq)
+}
+
+func TestGeneratorRate(t *testing.T) {
+	g, err := source.NewGenerator(source.GeneratorConfig{Rate: 200})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var n int
+	for range g.Extract(ctx, nil) {
+		n++
+	}
+	assert.InDelta(t, 20, n, 10)
+}
+
+func TestNewGeneratorErrors(t *testing.T) {
+	_, err := source.NewGenerator(source.GeneratorConfig{Count: -1})
+	assert.ErrorIs(t, err, source.ErrGeneratorSource)
+
+	_, err = source.NewGenerator(source.GeneratorConfig{Rate: -1})
+	assert.ErrorIs(t, err, source.ErrGeneratorSource)
+}
@@ -2,23 +2,33 @@ package source
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
-	"io"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/tlsconf"
 )
 
-// Ensure that HTTPLog implements the Readable interface.
-var _ pipeline.Readable = (*HTTPLog)(nil)
+// Ensure that HTTPLog implements the Readable and Releasable interfaces.
+var (
+	_ pipeline.Readable   = (*HTTPLog)(nil)
+	_ pipeline.Releasable = (*HTTPLog)(nil)
+)
 
 // HTTPLog is a simple struct that implements the Readable interface.
+// The logs received by the HTTP source are read into pooled buffers, which are reused once the log is
+// released.
 type HTTPLog struct {
-	addr string
-	log  []byte
-	id   uuid.UUID
+	addr     string
+	log      []byte
+	id       uuid.UUID
+	received time.Time
+	buf      *pipeline.PooledBytes // pooled buffer of the log, if it has one
 }
 
 // NewHTTPLog creates a new HTTPLog with the given log and address.
@@ -37,17 +47,28 @@ func NewHTTPLog(log []byte, addr string) (HTTPLog, error) {
 	}
 
 	return HTTPLog{
-		addr: addr,
-		log:  log,
-		id:   id,
+		addr:     addr,
+		log:      log,
+		id:       id,
+		received: time.Now(),
 	}, err
 }
 
-// Read returns the log and nil error.
+// Read returns the log and nil error, or ErrReleased after a pooled log was released.
 func (h HTTPLog) Read() ([]byte, error) {
+	if h.buf != nil {
+		return h.buf.Read()
+	}
 	return h.log, nil
 }
 
+// Release returns the pooled buffer of the log to its pool, the log must not be used after.
+func (h HTTPLog) Release() {
+	if h.buf != nil {
+		h.buf.Release()
+	}
+}
+
 // Addr returns the address of the HTTPLog.
 func (h HTTPLog) Addr() string {
 	return h.addr
@@ -58,16 +79,37 @@ func (h HTTPLog) ID() uuid.UUID {
 	return h.id
 }
 
+// Received returns the time the HTTPLog was received.
+func (h HTTPLog) Received() time.Time {
+	return h.received
+}
+
+// NewHTTPRecord wraps the HTTPLog in a record of the log with the metadata, its receive time and address
+// filling those left empty.
+func NewHTTPRecord(log HTTPLog, meta pipeline.Metadata) pipeline.Record {
+	if meta.Received.IsZero() {
+		meta.Received = log.received
+	}
+	if meta.Addr == "" {
+		meta.Addr = log.addr
+	}
+	return pipeline.NewRecord(log, log).WithMetadata(meta)
+}
+
 // HTTPConfig is the configuration for the HTTP source.
 type HTTPConfig struct {
 	Addr         string
 	Endpoint     string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
+	TLS          *tlsconf.Config // optional TLS configuration, plaintext when nil
 }
 
-// Ensure that HTTP implements Source interface.
-var _ pipeline.Source[HTTPLog] = (*HTTPServer)(nil)
+// Ensure that HTTP implements the Source and Health interfaces.
+var (
+	_ pipeline.Source[HTTPLog] = (*HTTPServer)(nil)
+	_ pipeline.Health          = (*HTTPServer)(nil)
+)
 
 // HTTPServer is a struct that represents an HTTP source.
 type HTTPServer struct {
@@ -75,6 +117,14 @@ type HTTPServer struct {
 	endpoint     string
 	readTimeout  time.Duration
 	writeTimeout time.Duration
+	tls          *tls.Config
+	state        *httpState // shared by the copies of the source
+}
+
+// httpState is the state of the listener of an HTTP source reported by its health
+type httpState struct {
+	bound    atomic.Bool
+	received atomic.Int64 // time of the last log received, in unix nanoseconds
 }
 
 // NewHTTPServer creates a new HTTP source with the given configuration.
@@ -95,21 +145,47 @@ func NewHTTPServer(conf HTTPConfig) (HTTPServer, error) {
 		conf.WriteTimeout = 5 * time.Second
 	}
 
+	tlsConf, err := conf.TLS.Server()
+	if err != nil {
+		return HTTPServer{}, err
+	}
+
 	return HTTPServer{
 		addr:         conf.Addr,
 		endpoint:     conf.Endpoint,
 		readTimeout:  conf.ReadTimeout,
 		writeTimeout: conf.WriteTimeout,
+		tls:          tlsConf,
+		state:        &httpState{},
 	}, nil
 }
 
+// Health reports whether the listener is bound, and when the last log was received.
+func (h HTTPServer) Health(context.Context) pipeline.HealthStatus {
+	if h.state == nil || !h.state.bound.Load() {
+		return pipeline.HealthStatus{State: pipeline.HealthUnhealthy, Message: "not listening on " + h.addr}
+	}
+
+	status := pipeline.HealthStatus{State: pipeline.HealthHealthy}
+	if received := h.state.received.Load(); received != 0 {
+		status.LastSuccess = time.Unix(0, received)
+	}
+	return status
+}
+
 // Extract creates a new HTTP server and returns a channel of RawReadable.
 func (h HTTPServer) Extract(ctx context.Context, eventC chan<- pipeline.Event) <-chan HTTPLog {
 	out := make(chan HTTPLog)
 
+	state := h.state
+	if state == nil {
+		state = &httpState{}
+	}
+
 	handler := &logHandler{
 		outC:   out,
 		eventC: eventC,
+		state:  state,
 	}
 
 	mux := http.NewServeMux()
@@ -135,7 +211,21 @@ func (h HTTPServer) Extract(ctx context.Context, eventC chan<- pipeline.Event) <
 			}
 		}()
 
-		if err := server.ListenAndServe(); err != nil {
+		ln, err := net.Listen("tcp", h.addr)
+		if err != nil {
+			eventC <- pipeline.NewErrorEvent(
+				"HTTP source server error",
+				err,
+				true)
+			return
+		}
+		if h.tls != nil {
+			ln = tls.NewListener(ln, h.tls)
+		}
+		state.bound.Store(true)
+		defer state.bound.Store(false)
+
+		if err := server.Serve(ln); err != nil {
 			if err != http.ErrServerClosed {
 				eventC <- pipeline.NewErrorEvent(
 					"HTTP source server error",
@@ -151,20 +241,23 @@ func (h HTTPServer) Extract(ctx context.Context, eventC chan<- pipeline.Event) <
 type logHandler struct {
 	outC   chan<- HTTPLog
 	eventC chan<- pipeline.Event
+	state  *httpState
 }
 
 // ServeHTTP handles the incoming HTTP request and sends the log to the output channel.
 func (h *logHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
+	buf := pipeline.DefaultBufferPool.Get()
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		pipeline.DefaultBufferPool.Put(buf)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 	defer r.Body.Close()
 
 	// make sure body is not empty
-	if len(body) == 0 {
+	if buf.Len() == 0 {
+		pipeline.DefaultBufferPool.Put(buf)
 		http.Error(w, "Bad Request", http.StatusBadRequest)
 		return
 	}
@@ -172,16 +265,19 @@ func (h *logHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	newlineByte := byte('\n')
 
 	// make sure body ends with newline
-	if body[len(body)-1] != newlineByte {
-		body = append(body, newlineByte)
+	if body := buf.Bytes(); body[len(body)-1] != newlineByte {
+		buf.WriteByte(newlineByte)
 	}
 
 	// wrap body and send HTTPLog to output channel
-	h.outC <- HTTPLog{log: body, addr: r.RemoteAddr}
+	pooled := pipeline.DefaultBufferPool.Bytes(buf)
+	received := time.Now()
+	h.outC <- HTTPLog{log: buf.Bytes(), addr: r.RemoteAddr, received: received, buf: pooled}
+	h.state.received.Store(received.UnixNano())
 
 	// send OK status
 	w.WriteHeader(http.StatusOK)
-	_, err = w.Write([]byte("OK"))
+	_, err := w.Write([]byte("OK"))
 	if err != nil {
 		h.eventC <- pipeline.NewErrorEvent(
 			"failed to write response",
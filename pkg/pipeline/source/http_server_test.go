@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/witfoo/krapht/pkg/pipeline"
 	"github.com/witfoo/krapht/pkg/pipeline/source"
+	"github.com/witfoo/krapht/pkg/tlsconf"
 )
 
 func TestHTTP_Extract(t *testing.T) {
@@ -35,6 +36,8 @@ func TestHTTP_Extract(t *testing.T) {
 		}
 	}()
 
+	assert.Equal(t, pipeline.HealthUnhealthy, httpInstance.Health(ctx).State)
+
 	out := httpInstance.Extract(ctx, eventC)
 
 	go func() {
@@ -42,6 +45,11 @@ func TestHTTP_Extract(t *testing.T) {
 			logP, err := log.Read()
 			assert.NoError(t, err)
 			assert.Equal(t, []byte("test log\n"), logP)
+
+			// the pooled buffer of a released log is not read
+			log.Release()
+			_, err = log.Read()
+			assert.ErrorIs(t, err, pipeline.ErrReleased)
 		}
 	}()
 
@@ -62,4 +70,30 @@ func TestHTTP_Extract(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 
+	health := httpInstance.Health(ctx)
+	assert.Equal(t, pipeline.HealthHealthy, health.State)
+	assert.False(t, health.LastSuccess.IsZero())
+
+}
+
+func TestNewHTTPServer_TLS(t *testing.T) {
+	_, err := source.NewHTTPServer(source.HTTPConfig{TLS: &tlsconf.Config{}})
+	assert.ErrorIs(t, err, tlsconf.ErrTLS)
+
+	_, err = source.NewHTTPServer(source.HTTPConfig{TLS: &tlsconf.Config{CertFile: "missing.pem", KeyFile: "missing-key.pem"}})
+	assert.ErrorIs(t, err, tlsconf.ErrTLS)
+}
+
+func TestNewHTTPRecord(t *testing.T) {
+	log, err := source.NewHTTPLog([]byte("message\n"), "10.0.0.1:5000")
+	assert.NoError(t, err)
+
+	record := source.NewHTTPRecord(log, pipeline.Metadata{Source: "http", Tenant: "acme"})
+	meta := record.Metadata()
+	assert.Equal(t, pipeline.Metadata{Received: log.Received(), Source: "http", Addr: "10.0.0.1:5000", Tenant: "acme"}, meta)
+	assert.False(t, meta.Received.IsZero())
+
+	data, err := record.Data().Read()
+	assert.NoError(t, err)
+	assert.Equal(t, "message\n", string(data))
 }
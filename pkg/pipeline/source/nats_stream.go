@@ -5,13 +5,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/nats-io/nats.go/jetstream"
 	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/natsconn"
 )
 
-// Static check that Broker implements the source interface
-var _ pipeline.Source[JSMsg] = (*BrokerStream)(nil)
+// Static check that Broker implements the source and health interfaces
+var (
+	_ pipeline.Source[JSMsg] = (*BrokerStream)(nil)
+	_ pipeline.Health        = (*BrokerStream)(nil)
+)
 
 // ErrBrokerSource is the error returned by the broker source
 var ErrBrokerSource = errors.New("source error")
@@ -44,6 +49,11 @@ func NewBrokerStream(js jetstream.JetStream, stream string, conf jetstream.Consu
 	}, nil
 }
 
+// Health reports the status of the nats connection
+func (b BrokerStream) Health(context.Context) pipeline.HealthStatus {
+	return natsconn.Health(b.js.Conn())
+}
+
 // Extract connects to the nats stream and returns a channel of messages
 func (b BrokerStream) Extract(ctx context.Context, eventC chan<- pipeline.Event) <-chan JSMsg {
 	out := make(chan JSMsg)
@@ -128,3 +138,19 @@ func NewJSMsg(jsMsg jetstream.Msg) (JSMsg, error) {
 func (j JSMsg) Read() ([]byte, error) {
 	return j.Data(), nil
 }
+
+// NewJSRecord wraps the JSMsg in a record of the message with the metadata, which is acknowledged with
+// the message. The time the message was stored in the stream and its subject fill the receive time and
+// address left empty.
+func NewJSRecord(msg JSMsg, meta pipeline.Metadata) pipeline.Record {
+	if meta.Received.IsZero() {
+		meta.Received = time.Now()
+		if m, err := msg.Metadata(); err == nil {
+			meta.Received = m.Timestamp
+		}
+	}
+	if meta.Addr == "" {
+		meta.Addr = msg.Subject()
+	}
+	return pipeline.NewRecord(msg, msg).WithMetadata(meta)
+}
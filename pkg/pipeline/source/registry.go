@@ -0,0 +1,226 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/natsconn"
+	"github.com/witfoo/krapht/pkg/tlsconf"
+)
+
+// Ensure that AnySource implements the Source interface.
+var _ pipeline.Source[any] = (*AnySource[string])(nil)
+
+// ErrUnknownSource is returned when no factory is registered under a name.
+var ErrUnknownSource = errors.New("unknown source")
+
+// Factory creates a source from its configuration, a JSON document that is empty when none is given.
+type Factory func(conf json.RawMessage) (pipeline.Source[any], error)
+
+// the registry of the source factories
+var registry = pipeline.NewRegistry[Factory](pipeline.StageSource)
+
+// Register makes a source factory available by name to NewRegistered. It is meant to be called from the
+// init function of the package providing the source, which is linked in by a blank import or in a plugin
+// loaded with flow.LoadPlugin. The options
+// describe the configuration of the source, which NewRegistered checks against them. Register panics
+// when the factory is nil or the name is already registered.
+func Register(name string, factory Factory, options ...pipeline.Option) {
+	registry.Register(name, factory, options...)
+}
+
+// Registered returns the sorted names of the registered sources.
+func Registered() []string {
+	return registry.Names()
+}
+
+// Components returns the descriptions of the registered sources, sorted by name.
+func Components() []pipeline.Component {
+	return registry.Components()
+}
+
+// NewRegistered creates the source registered under the name with its configuration.
+func NewRegistered(name string, conf json.RawMessage) (pipeline.Source[any], error) {
+	factory, ok, err := registry.Factory(name, conf)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSource, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not create source %s: %w", name, err)
+	}
+
+	s, err := factory(conf)
+	if err != nil {
+		return nil, fmt.Errorf("could not create source %s: %w", name, err)
+	}
+	return s, nil
+}
+
+// AnySource is a struct that represents a typed source adapted to a data stream of any items, as
+// created by factories.
+type AnySource[T any] struct {
+	source pipeline.Source[T]
+}
+
+// NewAnySource creates a new AnySource adapting the source.
+func NewAnySource[T any](source pipeline.Source[T]) *AnySource[T] {
+	return &AnySource[T]{
+		source: source,
+	}
+}
+
+// Extract returns the output channel of the items extracted by the source.
+func (a AnySource[T]) Extract(ctx context.Context, eventC chan<- pipeline.Event) <-chan any {
+	out := make(chan any)
+	go func() {
+		defer close(out)
+		for v := range a.source.Extract(ctx, eventC) {
+			out <- v
+		}
+	}()
+	return out
+}
+
+// Health reports the health of the source when it reports one, and healthy otherwise.
+func (a AnySource[T]) Health(ctx context.Context) pipeline.HealthStatus {
+	if h, ok := a.source.(pipeline.Health); ok {
+		return h.Health(ctx)
+	}
+	return pipeline.HealthStatus{State: pipeline.HealthHealthy}
+}
+
+// the built-in sources are registered by default
+func init() {
+	Register("generator", func(conf json.RawMessage) (pipeline.Source[any], error) {
+		var c struct {
+			Count int     `json:"count"`
+			Rate  float64 `json:"rate"`
+			Size  int     `json:"size"`
+			Hosts int     `json:"hosts"`
+			Seed  uint64  `json:"seed"`
+		}
+		if err := pipeline.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		g, err := NewGenerator(GeneratorConfig{Count: c.Count, Rate: c.Rate, Size: c.Size, Hosts: c.Hosts, Seed: c.Seed})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySource[pipeline.Readable](g), nil
+	},
+		pipeline.Option{Name: "count", Type: pipeline.OptionInteger,
+			Description: "records generated before the source is done, 0 generates until the pipeline stops"},
+		pipeline.Option{Name: "rate", Type: pipeline.OptionNumber,
+			Description: "max records generated per second, 0 generates as fast as possible"},
+		pipeline.Option{Name: "size", Type: pipeline.OptionInteger,
+			Description: "minimum payload size in bytes"},
+		pipeline.Option{Name: "hosts", Type: pipeline.OptionInteger, Default: 16,
+			Description: "distinct host names"},
+		pipeline.Option{Name: "seed", Type: pipeline.OptionInteger,
+			Description: "seed of the content, so runs with the same seed generate the same records"},
+	)
+
+	Register("http", func(conf json.RawMessage) (pipeline.Source[any], error) {
+		var c struct {
+			Addr         string            `json:"addr"`
+			Endpoint     string            `json:"endpoint"`
+			ReadTimeout  pipeline.Duration `json:"read_timeout"`
+			WriteTimeout pipeline.Duration `json:"write_timeout"`
+			TLS          *tlsconf.Config   `json:"tls"`
+		}
+		if err := pipeline.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		h, err := NewHTTPServer(HTTPConfig{
+			Addr:         c.Addr,
+			Endpoint:     c.Endpoint,
+			ReadTimeout:  time.Duration(c.ReadTimeout),
+			WriteTimeout: time.Duration(c.WriteTimeout),
+			TLS:          c.TLS,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySource[HTTPLog](h), nil
+	},
+		pipeline.Option{Name: "addr", Type: pipeline.OptionString, Default: ":8008",
+			Description: "listen address"},
+		pipeline.Option{Name: "endpoint", Type: pipeline.OptionString, Default: "/log",
+			Description: "path logs are posted to"},
+		pipeline.Option{Name: "read_timeout", Type: pipeline.OptionDuration, Default: "5s",
+			Description: "timeout for reading a request"},
+		pipeline.Option{Name: "write_timeout", Type: pipeline.OptionDuration, Default: "5s",
+			Description: "timeout for writing a response"},
+		pipeline.Option{Name: "tls", Type: pipeline.OptionObject,
+			Description: "server TLS configuration of cert_file, key_file, ca_file, server_name, insecure_skip_verify, min_version and reload, plaintext when unset"},
+	)
+
+	Register("nats_stream", func(conf json.RawMessage) (pipeline.Source[any], error) {
+		var c struct {
+			URL           string          `json:"url"`
+			Stream        string          `json:"stream"`
+			Durable       string          `json:"durable"`
+			FilterSubject string          `json:"filter_subject"`
+			TLS           *tlsconf.Config `json:"tls"`
+		}
+		if err := pipeline.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		js, err := natsconn.ConnectJetStream(c.URL, c.TLS)
+		if err != nil {
+			return nil, err
+		}
+		b, err := NewBrokerStream(js, c.Stream, jetstream.ConsumerConfig{
+			Durable:       c.Durable,
+			FilterSubject: c.FilterSubject,
+		})
+		if err != nil {
+			js.Conn().Close()
+			return nil, err
+		}
+		return NewAnySource[JSMsg](b), nil
+	},
+		pipeline.Option{Name: "url", Type: pipeline.OptionString, Default: nats.DefaultURL,
+			Description: "nats server URL"},
+		pipeline.Option{Name: "stream", Type: pipeline.OptionString, Required: true,
+			Description: "jetstream stream name"},
+		pipeline.Option{Name: "durable", Type: pipeline.OptionString,
+			Description: "durable consumer name, an ephemeral consumer is created when empty"},
+		pipeline.Option{Name: "filter_subject", Type: pipeline.OptionString,
+			Description: "subject the consumer is filtered on"},
+		pipeline.Option{Name: "tls", Type: pipeline.OptionObject,
+			Description: "client TLS configuration of cert_file, key_file, ca_file, server_name, insecure_skip_verify, min_version and reload"},
+	)
+
+	Register("replay", func(conf json.RawMessage) (pipeline.Source[any], error) {
+		var c struct {
+			Path  string  `json:"path"`
+			Speed float64 `json:"speed"`
+			Fast  bool    `json:"fast"`
+			Loop  bool    `json:"loop"`
+		}
+		if err := pipeline.DecodeOptions(conf, &c); err != nil {
+			return nil, err
+		}
+		r, err := NewReplay(ReplayConfig{Path: c.Path, Speed: c.Speed, Fast: c.Fast, Loop: c.Loop})
+		if err != nil {
+			return nil, err
+		}
+		return NewAnySource[pipeline.Readable](r), nil
+	},
+		pipeline.Option{Name: "path", Type: pipeline.OptionString, Required: true,
+			Description: "path of the capture file written by a capture tap"},
+		pipeline.Option{Name: "speed", Type: pipeline.OptionNumber, Default: 1,
+			Description: "multiplier of the recorded pace"},
+		pipeline.Option{Name: "fast", Type: pipeline.OptionBoolean,
+			Description: "replay as fast as possible, ignoring the recorded timing"},
+		pipeline.Option{Name: "loop", Type: pipeline.OptionBoolean,
+			Description: "replay the capture again from the start until the pipeline stops"},
+	)
+}
@@ -0,0 +1,42 @@
+package source_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/source"
+)
+
+func TestNewRegistered(t *testing.T) {
+	assert.Subset(t, source.Registered(), []string{"generator", "http", "nats_stream", "replay"})
+
+	s, err := source.NewRegistered("generator", json.RawMessage(`{"count": 3, "seed": 1}`))
+	assert.NoError(t, err)
+
+	var got []any
+	for v := range s.Extract(context.Background(), nil) {
+		got = append(got, v)
+	}
+	assert.Len(t, got, 3)
+	assert.Implements(t, (*pipeline.Readable)(nil), got[0])
+
+	_, err = source.NewRegistered("generator", json.RawMessage(`{"count": -1}`))
+	assert.ErrorIs(t, err, source.ErrGeneratorSource)
+
+	_, err = source.NewRegistered("replay", json.RawMessage(`{"speed": 2}`))
+	assert.ErrorIs(t, err, pipeline.ErrOptions)
+
+	_, err = source.NewRegistered("missing", nil)
+	assert.ErrorIs(t, err, source.ErrUnknownSource)
+}
+
+func TestComponents(t *testing.T) {
+	for _, c := range source.Components() {
+		assert.Equal(t, pipeline.StageSource, c.Kind)
+		assert.NotEmpty(t, c.Options, c.Name)
+	}
+}
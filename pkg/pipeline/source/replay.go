@@ -0,0 +1,116 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/capture"
+)
+
+// Static check that Replay implements the source interface
+var _ pipeline.Source[pipeline.Readable] = (*Replay)(nil)
+
+// ErrReplaySource is the error returned by the replay source
+var ErrReplaySource = errors.New("source error")
+
+// ReplaySourcePrefix is the prefix for the replay source error
+const ReplaySourcePrefix = "replay source"
+
+// ReplayConfig is the configuration for the replay source.
+type ReplayConfig struct {
+	Path  string  // path of the capture file written by a capture tap
+	Speed float64 // multiplier of the recorded pace, 2 replays twice as fast, defaults to 1
+	Fast  bool    // replay as fast as possible, ignoring the recorded timing
+	Loop  bool    // replay the capture again from the start after its last item until the context is done
+}
+
+// Replay is a source that replays the items of a capture file with their recorded timing. Items
+// recorded with a raw message are replayed as records of the payload and raw message, which read as
+// the payload, and other items as their payload.
+type Replay struct {
+	conf ReplayConfig
+}
+
+// NewReplay creates a new replay source with the given configuration.
+func NewReplay(conf ReplayConfig) (*Replay, error) {
+	if conf.Path == "" {
+		return nil, fmt.Errorf("%s: %w: path is empty", ReplaySourcePrefix, ErrReplaySource)
+	}
+
+	if conf.Speed < 0 {
+		return nil, fmt.Errorf("%s: %w: speed must not be negative", ReplaySourcePrefix, ErrReplaySource)
+	}
+
+	if conf.Speed == 0 {
+		conf.Speed = 1
+	}
+
+	return &Replay{
+		conf: conf,
+	}, nil
+}
+
+// Extract replays the capture file until its last item, or until the context is done when looping.
+func (r *Replay) Extract(ctx context.Context, eventC chan<- pipeline.Event) <-chan pipeline.Readable {
+	out := make(chan pipeline.Readable)
+	go func() {
+		defer close(out)
+		for {
+			if err := r.replay(ctx, out); err != nil {
+				pipeline.SendEvent(eventC, pipeline.NewErrorEvent(ReplaySourcePrefix+" error", err, false))
+				return
+			}
+			if !r.conf.Loop || ctx.Err() != nil {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// replay replays the capture file once
+func (r *Replay) replay(ctx context.Context, out chan<- pipeline.Readable) error {
+	f, err := os.Open(r.conf.Path)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", ReplaySourcePrefix, ErrReplaySource, err)
+	}
+	defer f.Close()
+
+	cr, err := capture.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", ReplaySourcePrefix, ErrReplaySource, err)
+	}
+
+	start := time.Now()
+	for {
+		e, err := cr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w: %w", ReplaySourcePrefix, ErrReplaySource, err)
+		}
+
+		if !r.conf.Fast {
+			at := start.Add(time.Duration(float64(e.Offset) / r.conf.Speed))
+			timer := time.NewTimer(time.Until(at))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case out <- e.Readable():
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package source_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/capture"
+	"github.com/witfoo/krapht/pkg/pipeline/source"
+)
+
+// writeCapture writes a capture file of the entries
+func writeCapture(t *testing.T, entries ...capture.Entry) string {
+	path := filepath.Join(t.TempDir(), "capture.ndjson")
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	w, err := capture.NewWriter(f, time.Now())
+	assert.NoError(t, err)
+	for _, e := range entries {
+		assert.NoError(t, w.Write(e))
+	}
+	assert.NoError(t, w.Flush())
+	return path
+}
+
+func TestReplay(t *testing.T) {
+	path := writeCapture(t,
+		capture.Entry{Data: []byte("one")},
+		capture.Entry{Offset: 40 * time.Millisecond, Data: []byte(`{"n":2}`), Raw: []byte("two")},
+	)
+
+	r, err := source.NewReplay(source.ReplayConfig{Path: path, Speed: 2})
+	assert.NoError(t, err)
+
+	start := time.Now()
+	var got []pipeline.Readable
+	for v := range r.Extract(context.Background(), nil) {
+		got = append(got, v)
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond, "the recorded pace is kept")
+	assert.Len(t, got, 2)
+
+	b, err := got[0].Read()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("one"), b)
+
+	record, ok := got[1].(pipeline.DataRawReadable)
+	assert.True(t, ok)
+	raw, _ := record.Raw().Read()
+	assert.Equal(t, []byte("two"), raw)
+	b, _ = got[1].Read()
+	assert.Equal(t, []byte(`{"n":2}`), b)
+}
+
+func TestReplayLoop(t *testing.T) {
+	path := writeCapture(t, capture.Entry{Data: []byte("one")})
+
+	r, err := source.NewReplay(source.ReplayConfig{Path: path, Fast: true, Loop: true})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var n int
+	for range r.Extract(ctx, nil) {
+		if n++; n == 5 {
+			cancel()
+		}
+	}
+	assert.GreaterOrEqual(t, n, 5)
+}
+
+func TestReplayErrors(t *testing.T) {
+	_, err := source.NewReplay(source.ReplayConfig{})
+	assert.ErrorIs(t, err, source.ErrReplaySource)
+
+	_, err = source.NewReplay(source.ReplayConfig{Path: "capture.ndjson", Speed: -1})
+	assert.ErrorIs(t, err, source.ErrReplaySource)
+
+	r, err := source.NewReplay(source.ReplayConfig{Path: filepath.Join(t.TempDir(), "missing")})
+	assert.NoError(t, err)
+	eventC := make(chan pipeline.Event, 1)
+	for range r.Extract(context.Background(), eventC) {
+	}
+	assert.Equal(t, pipeline.EventError, (<-eventC).Type())
+}
@@ -0,0 +1,195 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Ensure that Supervisor implements the Runnable and Health interfaces.
+var (
+	_ Runnable = (*Supervisor)(nil)
+	_ Health   = (*Supervisor)(nil)
+)
+
+// ErrSupervisor is returned when a Supervisor gives up restarting its pipeline.
+var ErrSupervisor = errors.New("supervisor error")
+
+// waiter is implemented by runnables that cannot run again until their last run has stopped, such as
+// a Runner whose stages were abandoned past its drain timeout
+type waiter interface {
+	Wait(ctx context.Context) error
+}
+
+// RestartPolicy is how a Supervisor restarts its pipeline.
+type RestartPolicy struct {
+	Backoff     time.Duration           // wait before the first restart, doubled for each restart after it, defaults to 1s
+	MaxBackoff  time.Duration           // cap on the wait between restarts, zero means no cap
+	MaxRestarts int                     // restarts before giving up, zero means no limit
+	Stable      time.Duration           // a run lasting this long resets the backoff and the restarts, zero never resets them
+	Restart     func(e ErrorEvent) bool // reports whether an error calls for a restart, nil restarts on permanent errors not specific to a record
+}
+
+// Supervisor is a Runnable restarting a pipeline that fails with exponential backoff. A run failing with
+// an error event the policy restarts on is cancelled, and run again once it has drained and the backoff
+// has passed, unless the policy has run out of restarts. A Runner is run again once it is no longer
+// running, after the stages of a run abandoned past its drain timeout returned. A run ending without such an error, or once the
+// context of the supervisor is done, ends the supervisor.
+type Supervisor struct {
+	name   string
+	r      Runnable
+	policy RestartPolicy
+
+	mu       sync.Mutex
+	restarts int   // restarts since the supervisor started
+	failure  error // error of the last failed run while restarting, or after giving up
+	gaveUp   bool
+}
+
+// NewSupervisor creates a new Supervisor named name restarting r under the policy.
+func NewSupervisor(name string, r Runnable, policy RestartPolicy) (*Supervisor, error) {
+	if r == nil {
+		return nil, fmt.Errorf("%w: runnable is nil", ErrSupervisor)
+	}
+
+	if policy.Backoff <= 0 {
+		policy.Backoff = time.Second
+	}
+
+	if policy.Restart == nil {
+		policy.Restart = func(e ErrorEvent) bool {
+			return !e.IsTemporary() && e.Record() == nil
+		}
+	}
+
+	return &Supervisor{
+		name:   name,
+		r:      r,
+		policy: policy,
+	}, nil
+}
+
+// Name returns the name of the supervisor.
+func (s *Supervisor) Name() string {
+	return s.name
+}
+
+// Restarts returns the number of times the pipeline was restarted by the current or last run.
+func (s *Supervisor) Restarts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restarts
+}
+
+// Health reports the supervisor degraded while it waits to restart the pipeline and unhealthy once it
+// gave up, and the health of the pipeline otherwise when it reports its health.
+func (s *Supervisor) Health(ctx context.Context) HealthStatus {
+	s.mu.Lock()
+	failure, gaveUp := s.failure, s.gaveUp
+	s.mu.Unlock()
+
+	switch {
+	case gaveUp:
+		return HealthStatus{State: HealthUnhealthy, Message: "gave up restarting: " + failure.Error()}
+	case failure != nil:
+		return HealthStatus{State: HealthDegraded, Message: "restarting: " + failure.Error()}
+	}
+	if h, ok := s.r.(Health); ok {
+		return h.Health(ctx)
+	}
+	return HealthStatus{State: HealthHealthy}
+}
+
+// Run runs the pipeline until it ends without failing, the policy gives up on it or the context is done,
+// and returns the event channel of the runs, which is closed when the supervisor ends. The events of the
+// runs are sent on it with a warning log event before each restart, and an ErrSupervisor error event
+// when the supervisor gives up.
+func (s *Supervisor) Run(ctx context.Context) <-chan Event {
+	out := make(chan Event, 1)
+
+	s.mu.Lock()
+	s.restarts, s.failure, s.gaveUp = 0, nil, false
+	s.mu.Unlock()
+
+	go func() {
+		defer close(out)
+
+		backoff, restarts := s.policy.Backoff, 0
+		for {
+			started := time.Now()
+			failure := s.run(ctx, out)
+			if failure == nil || ctx.Err() != nil {
+				s.setFailure(nil, false)
+				return
+			}
+
+			if s.policy.Stable > 0 && time.Since(started) >= s.policy.Stable {
+				backoff, restarts = s.policy.Backoff, 0
+			}
+			if s.policy.MaxRestarts > 0 && restarts >= s.policy.MaxRestarts {
+				s.setFailure(failure, true)
+				err := fmt.Errorf("%w: %s gave up after %d restarts: %w", ErrSupervisor, s.name, restarts, failure)
+				out <- NewErrorEvent("supervisor: pipeline failed", err, false)
+				return
+			}
+
+			s.setFailure(failure, false)
+			out <- NewLogEvent("supervisor", LevelWarn, fmt.Sprintf("restarting %s in %s after: %v", s.name, backoff, failure))
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				s.setFailure(nil, false)
+				return
+			case <-timer.C:
+			}
+
+			// an abandoned run keeps the pipeline running until its stages return
+			if w, ok := s.r.(waiter); ok {
+				if err := w.Wait(ctx); err != nil {
+					s.setFailure(nil, false)
+					return
+				}
+			}
+
+			restarts++
+			s.mu.Lock()
+			s.restarts++
+			s.failure = nil
+			s.mu.Unlock()
+
+			backoff *= 2
+			if s.policy.MaxBackoff > 0 {
+				backoff = min(backoff, s.policy.MaxBackoff)
+			}
+		}
+	}()
+	return out
+}
+
+// run runs the pipeline once, forwarding its events, and returns the error event it is restarted on,
+// cancelling the run on the first of them, or nil when it ends without one
+func (s *Supervisor) run(ctx context.Context, out chan<- Event) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var failure error
+	for e := range s.r.Run(ctx) {
+		if ee, ok := e.(ErrorEvent); ok && failure == nil && s.policy.Restart(ee) {
+			failure = ee
+			cancel()
+		}
+		out <- e
+	}
+	return failure
+}
+
+// setFailure sets the failure reported by the health of the supervisor
+func (s *Supervisor) setFailure(err error, gaveUp bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failure, s.gaveUp = err, gaveUp
+}
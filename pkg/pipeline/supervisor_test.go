@@ -0,0 +1,177 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// countedRunnable is a Runnable sending the events of a function, called with the count of its runs,
+// on the event channel of each run
+type countedRunnable struct {
+	f func(ctx context.Context, run int, eventC chan<- pipeline.Event)
+	n atomic.Int32
+}
+
+func (r *countedRunnable) Run(ctx context.Context) <-chan pipeline.Event {
+	out := make(chan pipeline.Event, 10)
+	run := int(r.n.Add(1))
+	go func() {
+		defer close(out)
+		r.f(ctx, run, out)
+	}()
+	return out
+}
+
+var errOutage = errors.New("connection lost")
+
+func TestSupervisorRestarts(t *testing.T) {
+	r := &countedRunnable{f: func(ctx context.Context, run int, eventC chan<- pipeline.Event) {
+		if run < 3 {
+			eventC <- pipeline.NewErrorEvent("sink error", errOutage, false)
+			<-ctx.Done() // the failed run is cancelled
+		}
+	}}
+	s, err := pipeline.NewSupervisor("ingest", r, pipeline.RestartPolicy{Backoff: time.Millisecond})
+	require.NoError(t, err)
+
+	var errs, logs int
+	for e := range s.Run(context.Background()) {
+		switch e.Type() {
+		case pipeline.EventError:
+			errs++
+		case pipeline.EventLog:
+			logs++
+			assert.Equal(t, pipeline.LevelWarn, e.(pipeline.LogEvent).Level())
+		}
+	}
+	assert.Equal(t, int32(3), r.n.Load(), "the run ending without failing ends the supervisor")
+	assert.Equal(t, 2, errs)
+	assert.Equal(t, 2, logs, "a lifecycle event per restart")
+	assert.Equal(t, 2, s.Restarts())
+	assert.Equal(t, pipeline.HealthHealthy, s.Health(context.Background()).State)
+}
+
+func TestSupervisorIgnoresRecordErrors(t *testing.T) {
+	r := &countedRunnable{f: func(_ context.Context, _ int, eventC chan<- pipeline.Event) {
+		eventC <- pipeline.NewErrorEvent("parse error", errOutage, true)
+		eventC <- pipeline.NewRecordErrorEvent("parse error", errOutage, false, "item")
+	}}
+	s, err := pipeline.NewSupervisor("ingest", r, pipeline.RestartPolicy{Backoff: time.Millisecond})
+	require.NoError(t, err)
+
+	for range s.Run(context.Background()) {
+	}
+	assert.Equal(t, int32(1), r.n.Load())
+	assert.Zero(t, s.Restarts())
+}
+
+func TestSupervisorGivesUp(t *testing.T) {
+	r := &countedRunnable{f: func(_ context.Context, _ int, eventC chan<- pipeline.Event) {
+		eventC <- pipeline.NewErrorEvent("source error", errOutage, false)
+	}}
+	s, err := pipeline.NewSupervisor("ingest", r, pipeline.RestartPolicy{Backoff: time.Millisecond, MaxRestarts: 2})
+	require.NoError(t, err)
+
+	var last pipeline.Event
+	for e := range s.Run(context.Background()) {
+		last = e
+	}
+	assert.Equal(t, int32(3), r.n.Load())
+	if assert.IsType(t, pipeline.ErrorEvent{}, last) {
+		assert.ErrorIs(t, last.(pipeline.ErrorEvent), pipeline.ErrSupervisor)
+		assert.ErrorIs(t, last.(pipeline.ErrorEvent), errOutage)
+		assert.False(t, last.(pipeline.ErrorEvent).IsTemporary())
+	}
+	assert.Equal(t, pipeline.HealthUnhealthy, s.Health(context.Background()).State)
+}
+
+func TestSupervisorCancelDuringBackoff(t *testing.T) {
+	r := &countedRunnable{f: func(_ context.Context, _ int, eventC chan<- pipeline.Event) {
+		eventC <- pipeline.NewErrorEvent("source error", errOutage, false)
+	}}
+	s, err := pipeline.NewSupervisor("ingest", r, pipeline.RestartPolicy{Backoff: time.Hour})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for e := range s.Run(ctx) {
+		if e.Type() == pipeline.EventLog {
+			assert.Equal(t, pipeline.HealthDegraded, s.Health(context.Background()).State)
+			cancel()
+		}
+	}
+	assert.Zero(t, s.Restarts())
+}
+
+// failingSource fails its first run with a permanent error and emits a single item afterwards
+type failingSource struct {
+	runs atomic.Int32
+}
+
+func (f *failingSource) Extract(ctx context.Context, eventC chan<- pipeline.Event) <-chan int {
+	out := make(chan int)
+	run := f.runs.Add(1)
+	go func() {
+		defer close(out)
+		if run == 1 {
+			eventC <- pipeline.NewErrorEvent("source error", errOutage, false)
+			<-ctx.Done()
+			return
+		}
+		out <- 1
+	}()
+	return out
+}
+
+// hungSink does not read its input until released
+type hungSink struct {
+	release chan struct{}
+}
+
+func (h hungSink) Load(in <-chan int, _ chan<- pipeline.Event) {
+	<-h.release
+	for range in {
+	}
+}
+
+func TestSupervisorAbandonedRun(t *testing.T) {
+	r := pipeline.NewRunner("ingest", &failingSource{}, pipeline.WithDrainTimeout(10*time.Millisecond))
+	release := make(chan struct{})
+	require.NoError(t, pipeline.SetSink[int](r, "hung", hungSink{release: release}))
+
+	s, err := pipeline.NewSupervisor("ingest", r, pipeline.RestartPolicy{Backoff: time.Millisecond, MaxRestarts: 1})
+	require.NoError(t, err)
+
+	var errs []string
+	for e := range s.Run(context.Background()) {
+		if e.Type() != pipeline.EventError {
+			continue
+		}
+		errs = append(errs, e.String())
+		if strings.Contains(e.String(), "abandoned") {
+			// the restart waits for the abandoned sink to return
+			go func() {
+				time.Sleep(20 * time.Millisecond)
+				close(release)
+			}()
+		}
+	}
+
+	assert.Len(t, errs, 2, "the source error and the abandon, but no failed restart: %v", errs)
+	assert.Equal(t, 1, s.Restarts())
+	assert.Equal(t, pipeline.HealthHealthy, s.Health(context.Background()).State)
+}
+
+func TestNewSupervisor(t *testing.T) {
+	_, err := pipeline.NewSupervisor("ingest", nil, pipeline.RestartPolicy{})
+	assert.ErrorIs(t, err, pipeline.ErrSupervisor)
+}
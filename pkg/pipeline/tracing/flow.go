@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Flow implements the Flow interface.
+var _ pipeline.Flow[pipeline.Readable, pipeline.DataRawReadable] = (*Flow[pipeline.Readable, pipeline.DataRawReadable])(nil)
+
+// Flow is a flow decorator that creates a span per batch of items passed to the wrapped flow, linking to
+// the spans of the items, and wraps the items it emits with the span context of the current batch.
+type Flow[I, O any] struct {
+	flow pipeline.Flow[I, O]
+	conf Config
+}
+
+// NewFlow creates a new traced flow wrapping f.
+func NewFlow[I, O any](f pipeline.Flow[I, O], conf Config) (*Flow[I, O], error) {
+	if f == nil {
+		return nil, fmt.Errorf("%w: flow is nil", ErrTracing)
+	}
+
+	conf, err := conf.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Flow[I, O]{
+		flow: f,
+		conf: conf,
+	}, nil
+}
+
+// Transform passes the unwrapped items from the input channel to the wrapped flow and returns the output
+// channel of its wrapped items.
+func (f Flow[I, O]) Transform(in <-chan I, eventC chan<- pipeline.Event) <-chan O {
+	var current atomic.Pointer[trace.SpanContext]
+
+	inner := make(chan I)
+	go func() {
+		defer close(inner)
+		b := &batch{conf: f.conf, kind: string(pipeline.StageFlow)}
+		defer b.end()
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				sc := b.add(SpanContextOf(v))
+				current.Store(&sc)
+				inner <- unwrap(v)
+			case <-b.expired():
+				b.end()
+			}
+		}
+	}()
+
+	out := make(chan O)
+	go func() {
+		defer close(out)
+		for v := range f.flow.Transform(inner, eventC) {
+			if sc := current.Load(); sc != nil {
+				v = Wrap(v, *sc)
+			}
+			out <- v
+		}
+	}()
+	return out
+}
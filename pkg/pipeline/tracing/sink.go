@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"fmt"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Sink implements the Sink interface.
+var _ pipeline.Sink[pipeline.Readable] = (*Sink[pipeline.Readable])(nil)
+
+// Sink is a sink decorator that creates a span per batch of items handed to the wrapped sink, linking to
+// the spans of the items.
+type Sink[I any] struct {
+	sink pipeline.Sink[I]
+	conf Config
+}
+
+// NewSink creates a new traced sink wrapping s.
+func NewSink[I any](s pipeline.Sink[I], conf Config) (*Sink[I], error) {
+	if s == nil {
+		return nil, fmt.Errorf("%w: sink is nil", ErrTracing)
+	}
+
+	conf, err := conf.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sink[I]{
+		sink: s,
+		conf: conf,
+	}, nil
+}
+
+// Load hands the unwrapped items from the input channel to the wrapped sink. It blocks until the
+// wrapped sink returns.
+func (s Sink[I]) Load(in <-chan I, eventC chan<- pipeline.Event) {
+	out := make(chan I)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.sink.Load(out, eventC)
+	}()
+
+	b := &batch{conf: s.conf, kind: string(pipeline.StageSink)}
+	func() {
+		defer b.end()
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					return
+				}
+				b.add(SpanContextOf(v))
+				out <- unwrap(v)
+			case <-b.expired():
+				b.end()
+			}
+		}
+	}()
+
+	close(out)
+	<-done
+}
@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// Ensure that Source implements the Source interface.
+var _ pipeline.Source[pipeline.Readable] = (*Source[pipeline.Readable])(nil)
+
+// Source is a source decorator that creates a span per extracted item, from the time the previous item
+// was emitted until the item is, and wraps the item with the span context.
+type Source[T any] struct {
+	src  pipeline.Source[T]
+	conf Config
+}
+
+// NewSource creates a new traced source wrapping src.
+func NewSource[T any](src pipeline.Source[T], conf Config) (*Source[T], error) {
+	if src == nil {
+		return nil, fmt.Errorf("%w: source is nil", ErrTracing)
+	}
+
+	conf, err := conf.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Source[T]{
+		src:  src,
+		conf: conf,
+	}, nil
+}
+
+// Extract extracts the items of the wrapped source, which are children of the span of the context when it
+// has one, and returns the output channel of the wrapped items.
+func (s Source[T]) Extract(ctx context.Context, eventC chan<- pipeline.Event) <-chan T {
+	out := make(chan T)
+	go func() {
+		defer close(out)
+		last := time.Now()
+		for v := range s.src.Extract(ctx, eventC) {
+			_, span := s.conf.Tracer.Start(ctx, "extract "+s.conf.Name, trace.WithTimestamp(last),
+				trace.WithAttributes(AttributeStage.String(s.conf.Name), AttributeKind.String(string(pipeline.StageSource))))
+			span.End()
+			out <- Wrap(v, span.SpanContext())
+			last = time.Now()
+		}
+	}()
+	return out
+}
@@ -0,0 +1,240 @@
+// Package tracing instruments pipeline stages with OpenTelemetry spans, so the latency of items can be
+// traced from the source to the sink.
+//
+// Sources emit a span per extracted item, flows a span per batch of transformed items and sinks a span per
+// batch of loaded items. The span context of an item travels with it in an envelope, and the spans of the
+// following stages link to the spans of the items they handle. Spans are created with the tracer of the
+// configuration, by default from the global tracer provider, and exported by the OpenTelemetry SDK it is
+// set up with.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+)
+
+// ScopeName is the instrumentation scope of the default tracer.
+const ScopeName = "github.com/witfoo/krapht/pkg/pipeline/tracing"
+
+// Attribute keys set on the spans
+const (
+	AttributeStage = attribute.Key("krapht.stage")
+	AttributeKind  = attribute.Key("krapht.stage.kind")
+	AttributeItems = attribute.Key("krapht.batch.items")
+)
+
+// ErrTracing is returned when a stage cannot be instrumented.
+var ErrTracing = errors.New("tracing error")
+
+// Config is the configuration of an instrumented stage.
+type Config struct {
+	Name   string       // stage name used in the span names and attributes, required
+	Tracer trace.Tracer // tracer creating the spans, defaults to the tracer of the global provider
+
+	// BatchSize and BatchInterval bound the batch of items of a flow or sink span, which ends when it
+	// holds BatchSize items or BatchInterval after it started. They default to 100 items and 1 second.
+	BatchSize     int
+	BatchInterval time.Duration
+}
+
+// withDefaults validates the configuration and applies its defaults
+func (c Config) withDefaults() (Config, error) {
+	if c.Name == "" {
+		return c, fmt.Errorf("%w: name is empty", ErrTracing)
+	}
+
+	if c.Tracer == nil {
+		c.Tracer = otel.Tracer(ScopeName)
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = 100
+	}
+
+	if c.BatchInterval <= 0 {
+		c.BatchInterval = time.Second
+	}
+	return c, nil
+}
+
+// Carrier is implemented by items carrying the span context of the last stage that handled them.
+type Carrier interface {
+	SpanContext() trace.SpanContext
+}
+
+// SpanContextOf returns the span context carried by the item, or an invalid span context.
+func SpanContextOf(v any) trace.SpanContext {
+	if c, ok := v.(Carrier); ok {
+		return c.SpanContext()
+	}
+	return trace.SpanContext{}
+}
+
+// Wrap returns the item in an envelope carrying the span context. Readable and DataRawReadable items are
+// wrapped, replacing the envelope of items already wrapped; other items, or those whose envelope is not
+// assignable to I, are returned unchanged.
+func Wrap[I any](v I, sc trace.SpanContext) I {
+	var wrapped any
+	switch r := any(v).(type) {
+	case tracedRecord:
+		wrapped = tracedRecord{DataRawReadable: r.DataRawReadable, sc: sc}
+	case tracedReadable:
+		wrapped = tracedReadable{Readable: r.Readable, sc: sc}
+	case pipeline.DataRawReadable:
+		wrapped = tracedRecord{DataRawReadable: r, sc: sc}
+	case pipeline.Readable:
+		wrapped = tracedReadable{Readable: r, sc: sc}
+	default:
+		return v
+	}
+
+	if w, ok := wrapped.(I); ok {
+		return w
+	}
+	return v
+}
+
+// Unwrap returns the item of an envelope, or the item itself when it is not wrapped.
+func Unwrap(v any) any {
+	switch r := v.(type) {
+	case tracedRecord:
+		return r.DataRawReadable
+	case tracedReadable:
+		return r.Readable
+	default:
+		return v
+	}
+}
+
+// unwrap returns the item of an envelope when it is of the item type, or the item itself
+func unwrap[I any](v I) I {
+	if u, ok := Unwrap(v).(I); ok {
+		return u
+	}
+	return v
+}
+
+// Ensure that the envelopes keep the record features of the items they wrap.
+var (
+	_ pipeline.Envelope         = tracedRecord{}
+	_ pipeline.MetadataReadable = tracedRecord{}
+	_ pipeline.Ackable          = tracedRecord{}
+	_ pipeline.Releasable       = tracedRecord{}
+	_ pipeline.Envelope         = tracedReadable{}
+	_ pipeline.MetadataReadable = tracedReadable{}
+	_ pipeline.Releasable       = tracedReadable{}
+)
+
+// tracedRecord is a DataRawReadable carrying a span context
+type tracedRecord struct {
+	pipeline.DataRawReadable
+	sc trace.SpanContext
+}
+
+// SpanContext returns the span context of the record
+func (r tracedRecord) SpanContext() trace.SpanContext { return r.sc }
+
+// Unwrap returns the wrapped record
+func (r tracedRecord) Unwrap() any { return r.DataRawReadable }
+
+// Metadata returns the metadata of the wrapped record, which is zero when it has none
+func (r tracedRecord) Metadata() pipeline.Metadata {
+	m, _ := pipeline.MetadataOf(r.DataRawReadable)
+	return m
+}
+
+// Ack acks the wrapped record when it can be acked
+func (r tracedRecord) Ack() error {
+	if a, ok := r.DataRawReadable.(pipeline.Ackable); ok {
+		return a.Ack()
+	}
+	return nil
+}
+
+// Release releases the wrapped record when it is Releasable
+func (r tracedRecord) Release() { pipeline.Release(r.DataRawReadable) }
+
+// tracedReadable is a Readable carrying a span context
+type tracedReadable struct {
+	pipeline.Readable
+	sc trace.SpanContext
+}
+
+// SpanContext returns the span context of the readable
+func (r tracedReadable) SpanContext() trace.SpanContext { return r.sc }
+
+// Unwrap returns the wrapped readable
+func (r tracedReadable) Unwrap() any { return r.Readable }
+
+// Metadata returns the metadata of the wrapped readable, which is zero when it has none
+func (r tracedReadable) Metadata() pipeline.Metadata {
+	m, _ := pipeline.MetadataOf(r.Readable)
+	return m
+}
+
+// Ack acks the wrapped readable when it is a message that can be acked
+func (r tracedReadable) Ack() error {
+	if a, ok := r.Readable.(interface{ Ack() error }); ok {
+		return a.Ack()
+	}
+	return nil
+}
+
+// Release releases the wrapped readable when it is Releasable
+func (r tracedReadable) Release() { pipeline.Release(r.Readable) }
+
+// batch is the span of a batch of items, a child of the span of its first item linking to the others
+type batch struct {
+	conf  Config
+	kind  string
+	span  trace.Span
+	items int
+	timer *time.Timer
+}
+
+// add adds an item carrying the span context to the batch, starting its span on the first item,
+// and returns the span context of the batch
+func (b *batch) add(sc trace.SpanContext) trace.SpanContext {
+	if b.span == nil {
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), sc)
+		_, b.span = b.conf.Tracer.Start(ctx, b.kind+" "+b.conf.Name,
+			trace.WithAttributes(AttributeStage.String(b.conf.Name), AttributeKind.String(b.kind)))
+		b.timer = time.NewTimer(b.conf.BatchInterval)
+	} else if sc.IsValid() {
+		b.span.AddLink(trace.Link{SpanContext: sc})
+	}
+
+	b.items++
+	sc = b.span.SpanContext()
+	if b.items >= b.conf.BatchSize {
+		b.end()
+	}
+	return sc
+}
+
+// expired returns the channel of the batch interval, or nil when no batch is open
+func (b *batch) expired() <-chan time.Time {
+	if b.timer == nil {
+		return nil
+	}
+	return b.timer.C
+}
+
+// end ends the span of the open batch
+func (b *batch) end() {
+	if b.span == nil {
+		return
+	}
+	b.timer.Stop()
+	b.span.SetAttributes(AttributeItems.Int(b.items))
+	b.span.End()
+	b.span, b.timer, b.items = nil, nil, 0
+}
@@ -0,0 +1,189 @@
+package tracing_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/witfoo/krapht/pkg/pipeline"
+	"github.com/witfoo/krapht/pkg/pipeline/flow"
+	"github.com/witfoo/krapht/pkg/pipeline/mock"
+	"github.com/witfoo/krapht/pkg/pipeline/tracing"
+)
+
+// readableSource emits the payloads as Readable items
+type readableSource []string
+
+func (s readableSource) Extract(_ context.Context, _ chan<- pipeline.Event) <-chan pipeline.Readable {
+	out := make(chan pipeline.Readable)
+	go func() {
+		defer close(out)
+		for _, p := range s {
+			out <- mock.NewReadableImpl([]byte(p))
+		}
+	}()
+	return out
+}
+
+// collectSink collects the payloads of the items and whether they were unwrapped
+type collectSink struct {
+	payloads  []string
+	unwrapped bool
+}
+
+func (c *collectSink) Load(in <-chan pipeline.DataRawReadable, _ chan<- pipeline.Event) {
+	c.unwrapped = true
+	for v := range in {
+		if _, ok := v.(tracing.Carrier); ok {
+			c.unwrapped = false
+		}
+		b, _ := v.Data().Read()
+		c.payloads = append(c.payloads, string(b))
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	src, err := tracing.NewSource[pipeline.Readable](readableSource{"a", "b", "c"}, tracing.Config{Name: "mock", Tracer: tracer})
+	assert.NoError(t, err)
+
+	upper, err := flow.NewMap(func(in pipeline.Readable) (pipeline.DataRawReadable, error) {
+		_, carried := in.(tracing.Carrier)
+		assert.False(t, carried, "flow receives the unwrapped item")
+		b, err := in.Read()
+		return pipeline.NewRecord(pipeline.Bytes(strings.ToUpper(string(b))), in), err
+	})
+	assert.NoError(t, err)
+	f, err := tracing.NewFlow[pipeline.Readable, pipeline.DataRawReadable](upper, tracing.Config{Name: "upper", Tracer: tracer, BatchSize: 2})
+	assert.NoError(t, err)
+
+	collect := &collectSink{}
+	snk, err := tracing.NewSink[pipeline.DataRawReadable](collect, tracing.Config{Name: "collect", Tracer: tracer})
+	assert.NoError(t, err)
+
+	eventC := make(chan pipeline.Event, 10)
+	snk.Load(f.Transform(src.Extract(context.Background(), eventC), eventC), eventC)
+
+	assert.Equal(t, []string{"A", "B", "C"}, collect.payloads)
+	assert.True(t, collect.unwrapped)
+
+	spans := recorder.Ended()
+	names := make(map[string]int)
+	for _, s := range spans {
+		names[s.Name()]++
+	}
+	assert.Equal(t, map[string]int{"extract mock": 3, "flow upper": 2, "sink collect": 1}, names)
+
+	var extract []sdktrace.ReadOnlySpan
+	for _, s := range spans {
+		if s.Name() == "extract mock" {
+			extract = append(extract, s)
+		}
+	}
+	for _, s := range spans {
+		switch s.Name() {
+		case "flow upper":
+			// the batch is a child of its first item and links to the others
+			assert.Contains(t, []string{extract[0].SpanContext().SpanID().String(), extract[2].SpanContext().SpanID().String()},
+				s.Parent().SpanID().String())
+			assert.Equal(t, s.Parent().TraceID(), s.SpanContext().TraceID())
+		case "sink collect":
+			assert.Len(t, s.Links(), 2)
+		}
+	}
+}
+
+func TestTracingBatchInterval(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	collect := &collectSink{}
+	snk, err := tracing.NewSink[pipeline.DataRawReadable](collect, tracing.Config{Name: "collect", Tracer: tracer, BatchInterval: 10 * time.Millisecond})
+	assert.NoError(t, err)
+
+	in := make(chan pipeline.DataRawReadable)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		snk.Load(in, nil)
+	}()
+
+	in <- pipeline.NewRecord(pipeline.Bytes("a"), pipeline.Bytes("a"))
+	assert.Eventually(t, func() bool { return len(recorder.Ended()) == 1 }, time.Second, 5*time.Millisecond)
+	in <- pipeline.NewRecord(pipeline.Bytes("b"), pipeline.Bytes("b"))
+	close(in)
+	<-done
+
+	assert.Len(t, recorder.Ended(), 2)
+}
+
+func TestWrap(t *testing.T) {
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	_, span := tracer.Start(context.Background(), "test")
+	sc := span.SpanContext()
+
+	r := tracing.Wrap[pipeline.Readable](pipeline.Bytes("a"), sc)
+	assert.Equal(t, sc, tracing.SpanContextOf(r))
+	assert.Equal(t, pipeline.Bytes("a"), tracing.Unwrap(r))
+	assert.Equal(t, pipeline.Bytes("a"), tracing.Unwrap(tracing.Wrap(r, sc)), "envelopes are not nested")
+
+	// a concrete item type cannot hold the envelope
+	b := tracing.Wrap(pipeline.Bytes("b"), sc)
+	assert.False(t, tracing.SpanContextOf(b).IsValid())
+
+	_, err := tracing.NewSink[any](nil, tracing.Config{Name: "nil"})
+	assert.ErrorIs(t, err, tracing.ErrTracing)
+	_, err = tracing.NewFlow[any, any](flow.NewPassthrough[any](), tracing.Config{})
+	assert.ErrorIs(t, err, tracing.ErrTracing)
+}
+
+// ackToken counts the acks of a record
+type ackToken struct{ acks *int }
+
+func (a ackToken) Ack() error {
+	*a.acks++
+	return nil
+}
+
+func TestWrapRecord(t *testing.T) {
+	tracer := sdktrace.NewTracerProvider().Tracer("test")
+	traced, err := tracing.NewFlow[pipeline.DataRawReadable, pipeline.DataRawReadable](
+		flow.NewPassthrough[pipeline.DataRawReadable](), tracing.Config{Name: "pass", Tracer: tracer})
+	assert.NoError(t, err)
+
+	var acks int
+	meta := pipeline.Metadata{Source: "syslog", Tenant: "acme"}
+	pool := pipeline.NewBufferPool(1 << 10)
+	buf := pool.Get()
+	buf.WriteString("raw")
+	data := pool.Bytes(buf)
+	record := pipeline.NewRecord(data, pipeline.Bytes("raw")).WithMetadata(meta).WithAck(ackToken{acks: &acks})
+
+	in := make(chan pipeline.DataRawReadable, 1)
+	in <- record
+	close(in)
+	wrapped := <-traced.Transform(in, nil)
+	assert.True(t, tracing.SpanContextOf(wrapped).IsValid())
+
+	// an untraced stage after the traced one keeps the metadata and ack token of the record
+	got, ok := pipeline.MetadataOf(wrapped)
+	assert.True(t, ok)
+	assert.Equal(t, meta, got)
+
+	derived := pipeline.DeriveRecord(pipeline.Bytes("parsed"), wrapped.Raw(), wrapped)
+	assert.Equal(t, meta, derived.Metadata())
+	assert.NoError(t, derived.Ack())
+	assert.NoError(t, wrapped.(pipeline.Ackable).Ack())
+	assert.Equal(t, 2, acks)
+
+	pipeline.Release(wrapped)
+	_, err = data.Read()
+	assert.ErrorIs(t, err, pipeline.ErrReleased)
+}
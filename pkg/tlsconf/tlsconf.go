@@ -0,0 +1,224 @@
+// Package tlsconf creates TLS configurations from a declarative configuration of certificate, key and
+// CA files, shared by the network sources and sinks.
+package tlsconf
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrTLS is returned when a TLS configuration cannot be created.
+var ErrTLS = errors.New("tls config error")
+
+// reloadInterval is the minimum time between checks of the files of a reloaded key pair
+var reloadInterval = time.Second
+
+// versions are the TLS versions by name
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// Config is the declarative configuration of TLS for a client or a server.
+type Config struct {
+	CertFile string `json:"cert_file"` // PEM certificate file, of the client certificate for clients
+	KeyFile  string `json:"key_file"`  // PEM private key file of the certificate
+
+	// CAFile is a PEM file of the CA certificates verifying the peer, the server certificate for clients
+	// and the required client certificates for servers. Clients use the system pool when it is empty.
+	CAFile string `json:"ca_file"`
+
+	ServerName         string `json:"server_name"`          // name verified in the server certificate, defaults to the host dialed
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"` // accept any server certificate, for testing only
+	MinVersion         string `json:"min_version"`          // minimum version, one of 1.0, 1.1, 1.2 or 1.3, defaults to 1.2
+
+	// Reload reloads the certificate and key when their files change, checking them for changes at most
+	// once a second on handshakes, so certificates can be rotated without a restart.
+	Reload bool `json:"reload"`
+}
+
+// Client returns the TLS configuration of a client, or nil for a nil configuration.
+func (c *Config) Client() (*tls.Config, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	conf, err := c.base()
+	if err != nil {
+		return nil, err
+	}
+	conf.ServerName = c.ServerName
+	conf.InsecureSkipVerify = c.InsecureSkipVerify
+
+	if c.CAFile != "" {
+		pool, err := loadPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	}
+
+	if c.CertFile != "" {
+		pair, err := c.keyPair()
+		if err != nil {
+			return nil, err
+		}
+		if c.Reload {
+			conf.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+				return pair.certificate()
+			}
+		} else {
+			cert, err := pair.certificate()
+			if err != nil {
+				return nil, err
+			}
+			conf.Certificates = []tls.Certificate{*cert}
+		}
+	}
+	return conf, nil
+}
+
+// Server returns the TLS configuration of a server, which requires a certificate and key, or nil for a
+// nil configuration. Clients must present a certificate signed by the CA when a CA file is set.
+func (c *Config) Server() (*tls.Config, error) {
+	if c == nil {
+		return nil, nil
+	}
+	if c.CertFile == "" {
+		return nil, fmt.Errorf("%w: server certificate file is empty", ErrTLS)
+	}
+
+	conf, err := c.base()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.CAFile != "" {
+		pool, err := loadPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	pair, err := c.keyPair()
+	if err != nil {
+		return nil, err
+	}
+	if c.Reload {
+		conf.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return pair.certificate()
+		}
+	} else {
+		cert, err := pair.certificate()
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{*cert}
+	}
+	return conf, nil
+}
+
+// base returns the configuration shared by clients and servers
+func (c *Config) base() (*tls.Config, error) {
+	version := uint16(tls.VersionTLS12)
+	if c.MinVersion != "" {
+		v, ok := versions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown min version %s", ErrTLS, c.MinVersion)
+		}
+		version = v
+	}
+	return &tls.Config{MinVersion: version}, nil
+}
+
+// keyPair returns the key pair of the certificate and key files, loading it once to check it
+func (c *Config) keyPair() (*keyPair, error) {
+	if c.KeyFile == "" {
+		return nil, fmt.Errorf("%w: key file of certificate %s is empty", ErrTLS, c.CertFile)
+	}
+
+	pair := &keyPair{certFile: c.CertFile, keyFile: c.KeyFile}
+	if _, err := pair.certificate(); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// loadPool loads a pool of the PEM certificates of a file
+func loadPool(file string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTLS, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("%w: no certificates in CA file %s", ErrTLS, file)
+	}
+	return pool, nil
+}
+
+// keyPair is a certificate loaded from files, reloaded when they change
+type keyPair struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time // latest modification time of the files of the certificate
+	checked  time.Time // last time the files were checked for changes
+}
+
+// certificate returns the certificate, reloading it when the files changed since it was loaded. The
+// last certificate is kept when the files cannot be loaded, such as while they are written.
+func (k *keyPair) certificate() (*tls.Certificate, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	if k.cert != nil && now.Sub(k.checked) < reloadInterval {
+		return k.cert, nil
+	}
+	k.checked = now
+
+	modified, err := latestModTime(k.certFile, k.keyFile)
+	if err == nil && k.cert != nil && !modified.After(k.modified) {
+		return k.cert, nil
+	}
+
+	var cert tls.Certificate
+	if err == nil {
+		cert, err = tls.LoadX509KeyPair(k.certFile, k.keyFile)
+	}
+	if err != nil {
+		if k.cert != nil {
+			return k.cert, nil
+		}
+		return nil, fmt.Errorf("%w: %w", ErrTLS, err)
+	}
+	k.cert, k.modified = &cert, modified
+	return k.cert, nil
+}
+
+// latestModTime returns the latest modification time of the files
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
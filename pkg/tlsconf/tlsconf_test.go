@@ -0,0 +1,217 @@
+package tlsconf
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA is a certificate authority issuing certificates to files for tests
+type testCA struct {
+	dir  string
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCA creates a CA and writes its certificate to ca.pem
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	ca := &testCA{dir: t.TempDir(), cert: cert, key: key}
+	ca.write(t, "ca.pem", "CERTIFICATE", der)
+	return ca
+}
+
+// issue writes a certificate of the common name and its key to name.pem and name-key.pem
+func (ca *testCA) issue(t *testing.T, name, commonName string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return ca.write(t, name+".pem", "CERTIFICATE", der), ca.write(t, name+"-key.pem", "EC PRIVATE KEY", keyDER)
+}
+
+// write writes a PEM block to a file of the CA directory and returns its path
+func (ca *testCA) write(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+
+	path := filepath.Join(ca.dir, name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+	return path
+}
+
+// handshake connects a client to a server and returns the common name of the server certificate
+func handshake(t *testing.T, server, client *tls.Config) (string, error) {
+	t.Helper()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", server)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.(*tls.Conn).Handshake()
+		_, _ = conn.Write([]byte("ok"))
+	}()
+
+	conn, err := tls.Dial("tcp", ln.Addr().String(), client)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	// the client certificate is verified after the client handshake completes
+	if _, err := conn.Read(make([]byte, 2)); err != nil {
+		return "", err
+	}
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+}
+
+func TestConfig(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "server", "server")
+	clientCert, clientKey := ca.issue(t, "client", "client")
+	caFile := filepath.Join(ca.dir, "ca.pem")
+
+	server, err := (&Config{CertFile: serverCert, KeyFile: serverKey}).Server()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), server.MinVersion)
+
+	client, err := (&Config{CAFile: caFile, MinVersion: "1.3"}).Client()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), client.MinVersion)
+
+	name, err := handshake(t, server, client)
+	assert.NoError(t, err)
+	assert.Equal(t, "server", name)
+
+	// the server certificate is not trusted without the CA
+	untrusted, err := (&Config{}).Client()
+	require.NoError(t, err)
+	_, err = handshake(t, server, untrusted)
+	assert.Error(t, err)
+
+	insecure, err := (&Config{InsecureSkipVerify: true}).Client()
+	require.NoError(t, err)
+	_, err = handshake(t, server, insecure)
+	assert.NoError(t, err)
+
+	// servers with a CA require client certificates signed by it
+	mutual, err := (&Config{CertFile: serverCert, KeyFile: serverKey, CAFile: caFile}).Server()
+	require.NoError(t, err)
+	_, err = handshake(t, mutual, client)
+	assert.Error(t, err)
+
+	withCert, err := (&Config{CAFile: caFile, CertFile: clientCert, KeyFile: clientKey}).Client()
+	require.NoError(t, err)
+	_, err = handshake(t, mutual, withCert)
+	assert.NoError(t, err)
+
+	var nilConf *Config
+	c, err := nilConf.Client()
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+}
+
+func TestConfig_Errors(t *testing.T) {
+	ca := newTestCA(t)
+	cert, key := ca.issue(t, "server", "server")
+
+	tests := []Config{
+		{},
+		{CertFile: cert},
+		{CertFile: cert, KeyFile: filepath.Join(ca.dir, "missing.pem")},
+		{CertFile: cert, KeyFile: key, CAFile: cert + ".missing"},
+		{CertFile: cert, KeyFile: key, CAFile: key},
+		{CertFile: cert, KeyFile: key, MinVersion: "1.4"},
+	}
+	for _, conf := range tests {
+		_, err := conf.Server()
+		assert.ErrorIs(t, err, ErrTLS, conf)
+	}
+}
+
+func TestConfig_Reload(t *testing.T) {
+	interval := reloadInterval
+	reloadInterval = 0
+	defer func() { reloadInterval = interval }()
+
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, "server", "first")
+	caFile := filepath.Join(ca.dir, "ca.pem")
+
+	server, err := (&Config{CertFile: certFile, KeyFile: keyFile, Reload: true}).Server()
+	require.NoError(t, err)
+	client, err := (&Config{CAFile: caFile}).Client()
+	require.NoError(t, err)
+
+	name, err := handshake(t, server, client)
+	assert.NoError(t, err)
+	assert.Equal(t, "first", name)
+
+	// the rotated certificate is served on the next handshake
+	ca.issue(t, "server", "second")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	name, err = handshake(t, server, client)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", name)
+
+	// the last certificate is kept while the files are invalid
+	require.NoError(t, os.WriteFile(keyFile, nil, 0o600))
+	later := future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(keyFile, later, later))
+	name, err = handshake(t, server, client)
+	assert.NoError(t, err)
+	assert.Equal(t, "second", name)
+}